@@ -49,7 +49,8 @@ python "${BOB_DIR}/config_system/generate_config_json.py" \
 
 # Get a hash of the environment so we can detect if we need to
 # regenerate the build.ninja
-python "${BOB_DIR}/scripts/env_hash.py" "${BUILDDIR}/.env.hash"
+python "${BOB_DIR}/scripts/env_hash.py" "${BUILDDIR}/.env.hash" \
+       --config-json "${CONFIG_JSON}"
 
 # If enabled, the following environment variables optimize the performance
 # of ccache. Otherwise they have no effect.
@@ -0,0 +1,49 @@
+#!/bin/bash
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+set -e
+
+# Example usage
+# ./bob_vscode
+#
+# To write the configuration somewhere other than the source directory
+# ./bob_vscode --vscode-out=/path/to/workspace/.vscode
+
+# Switch to the build directory
+cd "$(dirname "${BASH_SOURCE[0]}")"
+
+# Read settings written by bootstrap.bash
+source ".bob.bootstrap"
+
+# Switch to the working directory
+cd -P "${WORKDIR}"
+
+BOB_BUILDER_TARGET=".bootstrap/bin/bob"
+BOB_BUILDER="${BUILDDIR}/${BOB_BUILDER_TARGET}"
+BOB_BUILDER_NINJA="${BUILDDIR}/.bootstrap/build.ninja"
+
+if [ ! -f "${BOB_BUILDER_NINJA}" ]; then
+	echo "Missing ${BOB_BUILDER_NINJA}"
+	echo "Please build your project first"
+	exit 1
+fi
+
+# Make sure Bob is built
+ninja -f "${BOB_BUILDER_NINJA}" "${BOB_BUILDER_TARGET}"
+
+"${BOB_BUILDER}" -l "${BLUEPRINT_LIST_FILE}" -b "${BUILDDIR}" \
+	--vscode-out="${SRCDIR}/.vscode" "$@" "${SRCDIR}/${TOPNAME}"
@@ -24,6 +24,13 @@
 # BOB_CONFIG_OPTS - Configuration options to be used when calling the
 #                   configuration system.
 # BOB_CONFIG_PLUGINS - Configuration system plugins to use
+#
+# Options:
+# --event-log=FILE - Write an event per line to FILE each time the build is
+#                    generated. A relative path is relative to PWD.
+# --config-overrides=FILE - Replace the values of configuration options with
+#                           those in the JSON file FILE each time the build
+#                           is generated. A relative path is relative to PWD.
 
 # The location that this script is called from determines the working
 # directory of the build.
@@ -35,6 +42,23 @@ SCRIPT_DIR=$(dirname "${BASH_SOURCE[0]}")
 source "${SCRIPT_DIR}/pathtools.bash"
 source "${SCRIPT_DIR}/bootstrap/utils.bash"
 
+BOB_EVENT_LOG=""
+BOB_CONFIG_OVERRIDES=""
+for ARG in "$@"; do
+    case "${ARG}" in
+        --event-log=*)
+            BOB_EVENT_LOG="${ARG#--event-log=}"
+            ;;
+        --config-overrides=*)
+            BOB_CONFIG_OVERRIDES="${ARG#--config-overrides=}"
+            ;;
+        *)
+            echo "Unknown option ${ARG}" >&2
+            exit 1
+            ;;
+    esac
+done
+
 # Use defaults where we can. Generally the caller should set these.
 if [ -z "${SRCDIR}" ] ; then
     # If not specified, assume the current directory
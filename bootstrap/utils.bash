@@ -36,6 +36,8 @@ function write_bootstrap() {
         -e "s|@@ConfigJson@@|${CONFIG_JSON}|" \
         -e "s|@@BobConfigOpts@@|${BOB_CONFIG_OPTS}|" \
         -e "s|@@BobConfigPluginOpts@@|${BOB_CONFIG_PLUGIN_OPTS}|" \
+        -e "s|@@BobEventLog@@|${BOB_EVENT_LOG}|" \
+        -e "s|@@BobConfigOverrides@@|${BOB_CONFIG_OVERRIDES}|" \
         -e "s|@@BobBootstrapVersion@@|${BOB_VERSION}|" \
         "${BOB_DIR}/bob.bootstrap.in" > "${BUILDDIR}/.bob.bootstrap.tmp"
     rsync -c "${BUILDDIR}/.bob.bootstrap.tmp" "${BUILDDIR}/.bob.bootstrap"
@@ -55,5 +57,9 @@ function create_bob_symlinks() {
     local BOB_DIR="${1}" BUILDDIR="${2}"
 
     ln -sf "${BOB_DIR}/bob.bash" "${BUILDDIR}/bob"
+    ln -sf "${BOB_DIR}/bob_explain.bash" "${BUILDDIR}/bob_explain"
     ln -sf "${BOB_DIR}/bob_graph.bash" "${BUILDDIR}/bob_graph"
+    ln -sf "${BOB_DIR}/bob_lock.bash" "${BUILDDIR}/bob_lock"
+    ln -sf "${BOB_DIR}/bob_stats.bash" "${BUILDDIR}/bob_stats"
+    ln -sf "${BOB_DIR}/bob_vscode.bash" "${BUILDDIR}/bob_vscode"
 }
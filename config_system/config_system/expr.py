@@ -208,19 +208,21 @@ OPERATOR_FORMAT_MAP = {
 }
 
 
-def format_dependency_list(depends, skip_parens=False):
+def format_dependency_list(depends, skip_parens=False, with_values=True):
+    """Format an expression for display. Unless `with_values` is False,
+    identifiers are followed by their current value."""
     assert depends, "Empty dependency list"
     assert type(depends) == tuple
 
     if len(depends) == 3:
-        left = format_dependency_list(depends[1])
-        right = format_dependency_list(depends[2])
+        left = format_dependency_list(depends[1], with_values=with_values)
+        right = format_dependency_list(depends[2], with_values=with_values)
 
         operator = OPERATOR_FORMAT_MAP.get(depends[0], depends[0])
         result = left + " " + operator + " " + right
         return result if skip_parens else "(" + result + ")"
     elif depends[0] == 'not':
-        return "!" + format_dependency_list(depends[1])
+        return "!" + format_dependency_list(depends[1], with_values=with_values)
     elif depends[0] == 'string':
         return '"' + depends[1] + '"'
     elif depends[0] == 'number':
@@ -228,6 +230,8 @@ def format_dependency_list(depends, skip_parens=False):
     elif depends[0] == 'boolean':
         return 'y' if depends[1] else 'n'
     elif depends[0] == 'identifier':
+        if not with_values:
+            return depends[1]
         config = get_config(depends[1])
         value = config['value']
         if config['datatype'] == 'bool':
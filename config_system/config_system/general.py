@@ -13,6 +13,7 @@
 # See the License for the specific language governing permissions and
 # limitations under the License.
 
+import json
 import logging
 import os
 import re
@@ -171,6 +172,80 @@ def read_profile_file(profile_filename):
         logger.warning("Failed to load non-existing \"%s\" profile" % profile_filename)
 
 
+# Prefix of the lines in a configuration file which record the definition
+# each option had in the Mconfig when the file was written
+DEFINITION_PREFIX = "#~ "
+
+
+def get_definition(c):
+    """
+    Return the parts of an option's Mconfig definition that decide its
+    value when it has not been set by the user: its defaults and its
+    dependencies.
+    """
+    def fmt(e):
+        return expr.format_dependency_list(e, skip_parens=True, with_values=False)
+
+    defaults = [fmt(i['expr']) + " if " + fmt(i['cond']) for i in c.get('default_cond', [])]
+    if "default" in c:
+        defaults.append(fmt(c['default']))
+
+    depends = c.get('depends')
+
+    return {
+        "default": ", ".join(defaults),
+        "depends": fmt(depends) if depends else "",
+    }
+
+
+def read_definition_line(line):
+    """Record the definition an option had when the configuration was written"""
+    m = re.match(r"([A-Za-z0-9_]+) (.*)", line[len(DEFINITION_PREFIX):])
+    if not m:
+        logger.warning("Ignoring invalid option definition '%s'" % line)
+        return
+    try:
+        c = data.get_config(m.group(1))
+    except KeyError:
+        # The option has been removed from the Mconfig
+        return
+    try:
+        c['saved_definition'] = json.loads(m.group(2))
+    except ValueError:
+        logger.warning("Ignoring invalid option definition '%s'" % line)
+
+
+def check_saved_definitions(config_filename):
+    """
+    Warn about options whose defaults or dependencies have changed since
+    the configuration file was written. The value read from the file is
+    kept, so the option may now differ from what a new configuration would
+    use. The old definition is remembered until the option is set again,
+    so that the warning is repeated until the option has been reviewed.
+    """
+    for i in data.get_config_list():
+        c = data.get_config(i)
+        saved = c.pop('saved_definition', None)
+        if saved is None:
+            continue
+
+        current = get_definition(c)
+        changes = []
+        for part in ["default", "depends"]:
+            old = saved.get(part, "")
+            if old != current[part]:
+                changes.append("%s changed from '%s' to '%s'" %
+                               (part, old or "none", current[part] or "none"))
+
+        if changes:
+            c['stale_definition'] = saved
+            value = c['value']
+            if c['datatype'] == "bool":
+                value = "y" if value else "n"
+            logger.warning("%s: %s since %s was written. Review the value '%s' kept from it" %
+                           (i, ", ".join(changes), config_filename, value))
+
+
 def read_config_file(config_filename):
     try:
         with open(config_filename, "rt") as f:
@@ -180,6 +255,8 @@ def read_config_file(config_filename):
                 line = line.strip()
                 if line == "":
                     continue  # Ignore blank lines
+                elif line.startswith(DEFINITION_PREFIX):
+                    read_definition_line(line)
                 elif line.startswith("#"):
                     # match config name together with optional '[by user]' and '(source)' parts
                     # eg. "# CONFIG_RELEASE is not set [by user] (source)"
@@ -217,10 +294,12 @@ def read_config(options_filename, config_filename, ignore_missing):
     init_config(options_filename, ignore_missing)
     read_config_file(config_filename)
     enforce_dependent_values("Inconsistent input, correcting: ", fix_bools=True)
+    check_saved_definitions(config_filename)
 
 
 def write_config(config_filename):
     with utils.open_and_write_if_changed(config_filename) as f:
+        written = []
         for (i_type, i_symbol) in data.iter_symbols_menuorder():
             if i_type in ["config", "menuconfig"]:
                 c = data.get_config(i_symbol)
@@ -228,7 +307,8 @@ def write_config(config_filename):
                 if not can_enable(c):
                     # Don't output this option because it cannot be enabled
                     continue
-                elif c['datatype'] == "bool":
+                written.append(i_symbol)
+                if c['datatype'] == "bool":
                     if c['value'] is True:
                         f.write("CONFIG_%s=y" % i_symbol)
                     else:
@@ -248,6 +328,14 @@ def write_config(config_filename):
             elif i_type == "menu":
                 f.write("\n#\n# %s\n#\n" %
                         data.get_menu_title(i_symbol))
+
+        if written:
+            f.write("\n#\n# Option definitions, used to detect Mconfig changes\n#\n")
+        for i_symbol in written:
+            c = data.get_config(i_symbol)
+            definition = c.get('stale_definition') or get_definition(c)
+            f.write("%s%s %s\n" % (DEFINITION_PREFIX, i_symbol,
+                                    json.dumps(definition, sort_keys=True)))
     logger.info("Written configuration to '%s'" % config_filename)
 
 
@@ -526,6 +614,8 @@ def set_config(key, value, is_user_set=True):
     # Record user specified value even if it is (currently) impossible
     c['is_user_set'] |= is_user_set
     if is_user_set:
+        # Setting the option counts as reviewing any change to its definition
+        c.pop('stale_definition', None)
         if "choice_group" in c:
             group = c['choice_group']
             if value is True:
@@ -30,6 +30,14 @@ root_logger.setLevel(logging.WARNING)
 counter = config_system.log_handlers.ErrorCounterHandler()
 root_logger.addHandler(counter)
 
+# Report problems, such as options whose Mconfig definition has changed
+# since the configuration was written
+stream = logging.StreamHandler()
+formatter = config_system.log_handlers.ColorFormatter("%(levelname)s: %(message)s",
+                                                      stream.stream.isatty())
+stream.setFormatter(formatter)
+root_logger.addHandler(stream)
+
 
 def parse_args():
     parser = argparse.ArgumentParser()
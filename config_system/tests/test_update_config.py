@@ -362,3 +362,95 @@ def test_option_depends_on_plugin(caplog, mocker, tmpdir, plugin, mconfig, args)
 
     assert returncode == 0
     assert len(errors) == 0
+
+
+def run_update_config(mocker, config_fname, mconfig_fname, new, args):
+    """Run update_config's `main()` with the given options, checking that
+    no errors are reported"""
+    mocker.patch("update_config.parse_args", new=lambda: argparse.Namespace(
+        config=str(config_fname),
+        database=str(mconfig_fname),
+        json=None,
+        new=new,
+        plugin=[],
+        depfile=None,
+        ignore_missing=False,
+        args=args,
+    ))
+
+    update_config.counter.reset()
+    assert update_config.main() == 0
+
+
+def test_changed_definition_is_reported(caplog, mocker, tmpdir):
+    """Write a configuration, then change the default and dependencies of
+    its options in the Mconfig. Reading the configuration back must warn
+    about each changed option until it has been set again.
+    """
+
+    config_fname = tmpdir.join("bob.config")
+    mconfig_fname = tmpdir.join("Mconfig")
+
+    mconfig_fname.write("""
+config GATE
+    bool "gating"
+    default y
+
+config DEFAULT_CHANGES
+    bool "default changes"
+    default n
+
+config DEPENDS_CHANGES
+    string "depends changes"
+    default "value"
+
+config UNCHANGED
+    int "unchanged"
+    default 3
+""", "wt")
+    run_update_config(mocker, config_fname, mconfig_fname, True, [])
+
+    mconfig_fname.write("""
+config GATE
+    bool "gating"
+    default y
+
+config DEFAULT_CHANGES
+    bool "default changes"
+    default y
+
+config DEPENDS_CHANGES
+    string "depends changes"
+    depends on GATE
+    default "value"
+
+config UNCHANGED
+    int "unchanged"
+    default 3
+""", "wt")
+
+    expected_warnings = [
+        "DEFAULT_CHANGES: default changed from 'n' to 'y' since %s was written. "
+        "Review the value 'n' kept from it" % config_fname,
+        "DEPENDS_CHANGES: depends changed from 'none' to 'GATE' since %s was written. "
+        "Review the value 'value' kept from it" % config_fname,
+    ]
+
+    def warnings():
+        result = [r.message for r in caplog.records if r.levelno == logging.WARNING]
+        caplog.clear()
+        return result
+
+    caplog.clear()
+    run_update_config(mocker, config_fname, mconfig_fname, False, [])
+    assert warnings() == expected_warnings
+
+    # The warnings are repeated until the options are reviewed
+    run_update_config(mocker, config_fname, mconfig_fname, False, [])
+    assert warnings() == expected_warnings
+
+    run_update_config(mocker, config_fname, mconfig_fname, False,
+                      ["DEFAULT_CHANGES=n", "DEPENDS_CHANGES=value"])
+    warnings()
+    run_update_config(mocker, config_fname, mconfig_fname, False, [])
+    assert warnings() == []
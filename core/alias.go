@@ -70,6 +70,8 @@ func (m *alias) getAliasList() []string {
 // Called by Blueprint to generate the rules associated with the alias.
 // This is forwarded to the backend to handle.
 func (m *alias) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
 	getBackend(ctx).aliasActions(m, ctx)
 }
 
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// The allocators which can be selected with the `allocator` property.
+// "default" keeps the allocator of the C library.
+var allocators = []string{"default", "jemalloc", "tcmalloc", "scudo"}
+
+// allocatorLdlibs returns the configured flags which link 'allocator' for
+// the given target type. Android builds select the allocator for the
+// whole platform, so don't have these options.
+func allocatorLdlibs(config *bobConfig, tgt tgtType, allocator string) []string {
+	key := string(tgt) + "_" + allocator + "_ldlibs"
+	if _, ok := config.Properties.properties[key]; !ok {
+		return []string{}
+	}
+	return utils.NewStringSlice(strings.Fields(config.Properties.GetString(key)))
+}
+
+// getAllocator returns the allocator selected by a binary, or "" if the
+// C library's allocator is used.
+func (b *binary) getAllocator() string {
+	if b.Properties.Allocator == nil || *b.Properties.Allocator == "default" {
+		return ""
+	}
+	return *b.Properties.Allocator
+}
+
+// checkAllocatorMutator validates the allocator of each binary, and
+// ensures that no allocator is linked by other means. A second allocator,
+// e.g. pulled in through the ldlibs of a static library, would leave the
+// binary with two heaps. This runs after the flags of static libraries
+// have been propagated to the binaries which use them.
+func checkAllocatorMutator(mctx blueprint.BottomUpMutatorContext) {
+	b, ok := mctx.Module().(*binary)
+	if !ok || !isEnabled(b) {
+		return
+	}
+
+	config := getConfig(mctx)
+	tgt := b.getTarget()
+
+	if b.Properties.Allocator != nil && !utils.Contains(allocators, *b.Properties.Allocator) {
+		mctx.PropertyErrorf("allocator", "unknown allocator %s, expected one of %s",
+			*b.Properties.Allocator, strings.Join(allocators, ", "))
+		return
+	}
+
+	if !config.Properties.GetBool("builder_ninja") {
+		// The platform's allocator is always used on Android
+		return
+	}
+
+	allocator := b.getAllocator()
+	if allocator != "" && len(allocatorLdlibs(config, tgt, allocator)) == 0 {
+		mctx.PropertyErrorf("allocator", "%s is not available for %s binaries, "+
+			"as %s_%s_LDLIBS is empty", allocator, tgt,
+			strings.ToUpper(string(tgt)), strings.ToUpper(allocator))
+		return
+	}
+
+	linked := utils.NewStringSlice(b.Properties.Ldlibs, b.Properties.Ldflags)
+	for _, other := range allocators[1:] {
+		for _, flag := range allocatorLdlibs(config, tgt, other) {
+			if utils.Contains(linked, flag) {
+				mctx.PropertyErrorf("ldlibs", "links %s with %s, which must be selected "+
+					"with the allocator property, so that only one allocator is used",
+					other, flag)
+				return
+			}
+		}
+	}
+}
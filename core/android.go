@@ -46,6 +46,37 @@ func enabledAndRequired(m blueprint.Module) bool {
 	return true
 }
 
+// The Android.mk variable and Soong property installing a module to each
+// partition other than system
+var androidPartitions = map[string]struct{ androidMk, soong string }{
+	"vendor":     {"LOCAL_VENDOR_MODULE", "vendor"},
+	"odm":        {"LOCAL_ODM_MODULE", "device_specific"},
+	"product":    {"LOCAL_PRODUCT_MODULE", "product_specific"},
+	"system_ext": {"LOCAL_SYSTEM_EXT_MODULE", "system_ext_specific"},
+}
+
+// androidPartition returns the partition a module is installed to, or
+// an empty string for the system partition.
+func androidPartition(ctx blueprint.BaseModuleContext, props *AndroidProps) string {
+	if props.Partition == nil {
+		if props.isProprietary() {
+			return "vendor"
+		}
+		return ""
+	}
+
+	partition := *props.Partition
+	if partition == "system" {
+		return ""
+	}
+	if _, ok := androidPartitions[partition]; !ok {
+		ctx.PropertyErrorf("partition", "must be one of system, vendor, odm, product or system_ext, not %s",
+			partition)
+		return ""
+	}
+	return partition
+}
+
 // Map of path prefixes and where to split the path into "base" and "rel" sections, roughly
 // corresponding to LOCAL_PATH and LOCAL_MODULE_RELATIVE_PATH/relative_install_path.
 var androidInstallLocationSplits = map[string]int{
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+// The rules building modules with an AUX toolchain, by module type.
+// There are no AUX shared libraries, as AUX processors don't load them.
+var auxRuleSuffix = map[binType]string{
+	binTypeStatic:     "AUX_STATIC_LIBRARY",
+	binTypeExecutable: "AUX_EXECUTABLE",
+}
+
+func (a *AndroidAuxProps) isAux() bool {
+	return a.Aux.Os != nil
+}
+
+// androidMkAuxVariables returns the assignments selecting the AUX
+// toolchain of a module
+func (a *AndroidAuxProps) androidMkAuxVariables() string {
+	sb := &strings.Builder{}
+	variables := []struct {
+		name  string
+		value *string
+	}{
+		{"LOCAL_AUX_OS", a.Aux.Os},
+		{"LOCAL_AUX_OS_VARIANT", a.Aux.Os_variant},
+		{"LOCAL_AUX_ARCH", a.Aux.Arch},
+		{"LOCAL_AUX_SUBARCH", a.Aux.Subarch},
+		{"LOCAL_AUX_CPU", a.Aux.Cpu},
+	}
+	for _, v := range variables {
+		if v.value != nil {
+			sb.WriteString(v.name + ":=" + proptools.String(v.value) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// androidAuxMutator checks the modules built with an AUX toolchain. It
+// runs after target specific properties are applied, as the toolchain
+// is normally only selected for the target.
+func androidAuxMutator(mctx blueprint.BottomUpMutatorContext) {
+	l, ok := getLibrary(mctx.Module())
+	if !ok || !isEnabled(l) || !l.Properties.isAux() {
+		return
+	}
+
+	if _, ok := mctx.Module().(*sharedLibrary); ok {
+		mctx.PropertyErrorf("aux", "is only supported by binaries and static libraries")
+	} else if !getConfig(mctx).Properties.GetBool("builder_android_make") {
+		mctx.PropertyErrorf("aux", "is only supported by the Android.mk backend")
+	} else if l.Properties.TargetType != tgtTypeTarget {
+		mctx.PropertyErrorf("aux", "is only supported on target modules")
+	} else if len(l.Properties.Shared_libs) > 0 {
+		mctx.PropertyErrorf("shared_libs", "can't be used with aux, as AUX processors "+
+			"don't load shared libraries: %s", strings.Join(l.Properties.Shared_libs, ", "))
+	}
+}
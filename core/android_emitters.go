@@ -0,0 +1,316 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+// The parts of the Android.mk and Android.bp definitions of libraries
+// and binaries. The values are worked out from the module first, so
+// that each part can be written on its own.
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/ARM-software/bob-build/internal/bpwriter"
+	"github.com/ARM-software/bob-build/internal/ccflags"
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// androidMkEmitter writes a group of variables to the Android make
+// fragment of a module.
+type androidMkEmitter interface {
+	androidMk(sb *strings.Builder)
+}
+
+// androidBpEmitter adds a group of properties to the Android.bp
+// definition of a module.
+type androidBpEmitter interface {
+	androidBp(m bpwriter.Module)
+}
+
+// androidCompileFlags holds the compiler flags of a module. The
+// Android build systems select the C and C++ standards and the
+// instruction set themselves, so these are taken out of the flags.
+type androidCompileFlags struct {
+	cflags     []string
+	conlyflags []string
+	cxxflags   []string
+	cStd       string
+	cxxStd     string
+	armMode    string
+}
+
+var _ androidMkEmitter = (*androidCompileFlags)(nil)
+var _ androidBpEmitter = (*androidCompileFlags)(nil)
+
+func newAndroidCompileFlags(audit *ccflags.Audit,
+	cflags, conlyflags, cxxflags []string) (androidCompileFlags, error) {
+	armMode, err := ccflags.GetArmMode(cflags, conlyflags, cxxflags)
+	if err != nil {
+		return androidCompileFlags{}, err
+	}
+
+	// The flags are audited in the order they are written
+	return androidCompileFlags{
+		cflags:     audit.Filter("cflags", ccflags.AndroidCompileFlagDropReason, cflags),
+		cxxflags:   audit.Filter("cxxflags", ccflags.AndroidCompileFlagDropReason, cxxflags),
+		conlyflags: audit.Filter("conlyflags", ccflags.AndroidCompileFlagDropReason, conlyflags),
+		cStd:       ccflags.GetCompilerStandard(cflags, conlyflags),
+		cxxStd:     ccflags.GetCompilerStandard(cflags, cxxflags),
+		armMode:    armMode,
+	}, nil
+}
+
+func (e *androidCompileFlags) androidMk(sb *strings.Builder) {
+	writeListAssignment(sb, "LOCAL_CFLAGS", e.cflags)
+	writeListAssignment(sb, "LOCAL_CPPFLAGS", e.cxxflags)
+	writeListAssignment(sb, "LOCAL_CONLYFLAGS", e.conlyflags)
+
+	// Note that the standard is only used by Android O and later
+	if e.cStd != "" {
+		sb.WriteString("LOCAL_C_STD:=" + e.cStd + "\n")
+	}
+	if e.cxxStd != "" {
+		sb.WriteString("LOCAL_CPP_STD:=" + e.cxxStd + "\n")
+	}
+	if e.armMode != "" {
+		sb.WriteString("LOCAL_ARM_MODE:=" + e.armMode + "\n")
+	}
+}
+
+func (e *androidCompileFlags) androidBp(m bpwriter.Module) {
+	if e.cStd != "" {
+		m.AddString("c_std", e.cStd)
+	}
+	if e.cxxStd != "" {
+		m.AddString("cpp_std", e.cxxStd)
+	}
+	if e.armMode != "" {
+		m.AddString("instruction_set", e.armMode)
+	}
+
+	m.AddStringList("cflags", e.cflags)
+	m.AddStringList("conlyflags", e.conlyflags)
+	m.AddStringList("cppflags", e.cxxflags)
+}
+
+// androidLibs holds the libraries used by a module, using the names
+// of the modules in the Android build.
+type androidLibs struct {
+	shared      []string
+	static      []string
+	wholeStatic []string
+	header      []string
+	// Libraries whose headers are exported to users of the module
+	exportShared []string
+	exportStatic []string
+	exportHeader []string
+	// Whether the static libraries are linked as a group
+	group bool
+}
+
+var _ androidMkEmitter = (*androidLibs)(nil)
+var _ androidBpEmitter = (*androidLibs)(nil)
+
+// newAndroidLibs converts the libraries of a module to the names used
+// in the Android build with 'names'. The header libraries are passed
+// separately, as not all of them are available to every backend.
+func newAndroidLibs(props *BuildProps, names func([]string) []string,
+	headerLibs, exportHeaderLibs []string) androidLibs {
+	e := androidLibs{
+		shared:      names(props.Shared_libs),
+		static:      names(props.ResolvedStaticLibs),
+		wholeStatic: names(props.Whole_static_libs),
+		group:       len(props.Link_group) > 0,
+	}
+
+	// Exported header libraries must be in the header libraries as
+	// well - we can't export a header library which isn't used.
+	e.exportHeader = names(exportHeaderLibs)
+	e.header = utils.NewStringSlice(names(headerLibs), e.exportHeader)
+
+	for _, lib := range names(props.Reexport_libs) {
+		if utils.Contains(e.shared, lib) {
+			e.exportShared = append(e.exportShared, lib)
+		} else if utils.Contains(e.static, lib) {
+			e.exportStatic = append(e.exportStatic, lib)
+		} else if utils.Contains(e.header, lib) {
+			e.exportHeader = append(e.exportHeader, lib)
+		}
+	}
+	return e
+}
+
+func (e *androidLibs) androidMk(sb *strings.Builder) {
+	writeListAssignment(sb, "LOCAL_SHARED_LIBRARIES", e.shared)
+	writeListAssignment(sb, "LOCAL_STATIC_LIBRARIES", e.static)
+	writeListAssignment(sb, "LOCAL_WHOLE_STATIC_LIBRARIES", e.wholeStatic)
+	writeListAssignment(sb, "LOCAL_HEADER_LIBRARIES", e.header)
+	if e.group {
+		// Android can only group all the static libraries
+		sb.WriteString("LOCAL_GROUP_STATIC_LIBRARIES := true\n")
+	}
+
+	writeListAssignment(sb, "LOCAL_EXPORT_SHARED_LIBRARY_HEADERS", e.exportShared)
+	writeListAssignment(sb, "LOCAL_EXPORT_STATIC_LIBRARY_HEADERS", e.exportStatic)
+	writeListAssignment(sb, "LOCAL_EXPORT_HEADER_LIBRARY_HEADERS", e.exportHeader)
+}
+
+func (e *androidLibs) androidBp(m bpwriter.Module) {
+	m.AddStringList("shared_libs", e.shared)
+	m.AddStringList("static_libs", e.static)
+	m.AddStringList("whole_static_libs", e.wholeStatic)
+	if e.group {
+		// Soong can only group all the static libraries
+		m.AddBool("group_static_libs", true)
+	}
+	m.AddStringList("header_libs", e.header)
+	m.AddStringList("export_shared_lib_headers", e.exportShared)
+	m.AddStringList("export_static_lib_headers", e.exportStatic)
+	m.AddStringList("export_header_lib_headers", e.exportHeader)
+}
+
+// androidMkIncludes holds the include directories of a module. These
+// are ordered local_include_dirs, export_local_include_dirs,
+// include_dirs then export_include_dirs, as the latter two should be
+// system headers.
+type androidMkIncludes struct {
+	includes       []string
+	exportIncludes []string
+	// The assignments of the include directories of the headers
+	// generated for each architecture
+	archIncludes string
+}
+
+var _ androidMkEmitter = (*androidMkIncludes)(nil)
+
+// androidMk writes the include directories used to compile the module.
+// The exported directories are written separately by androidMkExports.
+func (e *androidMkIncludes) androidMk(sb *strings.Builder) {
+	writeListAssignment(sb, "LOCAL_C_INCLUDES", e.includes)
+	sb.WriteString(e.archIncludes)
+}
+
+// androidMkExports writes the include directories exported to users of
+// the module. These follow LOCAL_MODULE_TAGS in the Android make
+// fragments of libraries and binaries.
+func (e *androidMkIncludes) androidMkExports(sb *strings.Builder) {
+	writeListAssignment(sb, "LOCAL_EXPORT_C_INCLUDE_DIRS", e.exportIncludes)
+}
+
+// androidMkInstall describes the installation of a library or binary.
+type androidMkInstall struct {
+	bt          binType
+	tgt         tgtType
+	multilib    bool
+	installable bool
+	base        string
+	rel         string
+	// LOCAL_POST_INSTALL_CMD, with all variables expanded
+	postInstallCmd string
+	// The modules installed alongside this one
+	required []string
+}
+
+var _ androidMkEmitter = (*androidMkInstall)(nil)
+
+func (e *androidMkInstall) androidMk(sb *strings.Builder) {
+	if !e.installable {
+		// Only disable installation on the target, because host
+		// libraries need to be installed to be used by the build.
+		//
+		// Target shared libraries do not need an explicit installation
+		// location, but cannot be uninstallable, or the multilib paths
+		// will conflict, resulting in the same location being used for
+		// both 32 and 64-bit versions.
+		if e.tgt == tgtTypeTarget && e.bt != binTypeShared {
+			sb.WriteString("LOCAL_UNINSTALLABLE_MODULE:=true\n")
+		}
+		return
+	}
+
+	if e.postInstallCmd != "" {
+		// Intentionally using a recursively expanded variable. We
+		// don't want LOCAL_INSTALLED_MODULE expanded now, but
+		// when it is used in base_rules.mk
+		sb.WriteString("LOCAL_POST_INSTALL_CMD=" + e.postInstallCmd + "\n")
+	}
+
+	if e.bt == binTypeExecutable {
+		if e.multilib {
+			// For executables we need to be clear about where to
+			// install both 32 and 64 bit versions of the
+			// binaries. This is done by appending `64` to the install dir.
+			// However, there are not separate variables for the 32 and 64-bit
+			// relative paths, so ignore LOCAL_MODULE_RELATIVE_PATH and put the
+			// relative_install_path in LOCAL_MODULE_PATH_[32|64] instead.
+			//
+			// LOCAL_UNSTRIPPED_PATH does not need to be set
+			fullInstallPath := filepath.Join(e.base, e.rel)
+			sb.WriteString("LOCAL_MODULE_PATH_32:=" + fullInstallPath + "\n")
+			sb.WriteString("LOCAL_MODULE_PATH_64:=" + fullInstallPath + "64\n")
+		} else {
+			sb.WriteString("LOCAL_MODULE_PATH:=" + e.base + "\n")
+			sb.WriteString("LOCAL_MODULE_RELATIVE_PATH:=" + e.rel + "\n")
+
+			// When LOCAL_MODULE_PATH is specified, you need to
+			// specify LOCAL_UNSTRIPPED_PATH too
+			if e.tgt == tgtTypeTarget {
+				// Unstripped executables only generated for target
+				sb.WriteString("LOCAL_UNSTRIPPED_PATH:=$(TARGET_OUT_EXECUTABLES_UNSTRIPPED)\n")
+			}
+		}
+	} else {
+		// You can't specify an explicit install dir for
+		// libraries, so we can only control LOCAL_MODULE_RELATIVE_PATH
+		sb.WriteString("LOCAL_MODULE_RELATIVE_PATH:=" + e.rel + "\n")
+	}
+
+	if len(e.required) > 0 {
+		sb.WriteString("LOCAL_REQUIRED_MODULES:=" + newlineSeparatedList(e.required))
+	}
+}
+
+// androidMkMultilib selects the architectures a module is built for,
+// and the linker flags used for each of them.
+type androidMkMultilib struct {
+	// The value of LOCAL_MULTILIB, if any
+	multilib string
+	ldflags  []string
+	ldlibs   []string
+	tgt      tgtType
+}
+
+var _ androidMkEmitter = (*androidMkMultilib)(nil)
+
+func (e *androidMkMultilib) androidMk(sb *strings.Builder) {
+	if e.multilib != "" {
+		sb.WriteString("LOCAL_MULTILIB:=" + e.multilib + "\n")
+	}
+	if e.multilib == "both" {
+		writeListAssignment(sb, "LOCAL_LDFLAGS_32", e.ldflags)
+		writeListAssignment(sb, "LOCAL_LDFLAGS_64", e.ldflags)
+	} else {
+		writeListAssignment(sb, "LOCAL_LDFLAGS", e.ldflags)
+	}
+
+	if e.tgt == tgtTypeTarget {
+		writeListAssignment(sb, "LOCAL_LDLIBS", e.ldlibs)
+	} else {
+		writeListAssignment(sb, "LOCAL_LDLIBS_$(HOST_OS)", e.ldlibs)
+	}
+}
@@ -0,0 +1,244 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ARM-software/bob-build/internal/bpwriter"
+	"github.com/ARM-software/bob-build/internal/ccflags"
+)
+
+func androidMkOf(e androidMkEmitter) string {
+	sb := &strings.Builder{}
+	e.androidMk(sb)
+	return sb.String()
+}
+
+func androidBpOf(t *testing.T, e androidBpEmitter) string {
+	f := bpwriter.FileFactory()
+	m, err := f.NewModule("cc_library", "libfoo")
+	assert.Nil(t, err)
+	e.androidBp(m)
+
+	sb := &strings.Builder{}
+	f.Render(sb)
+	return sb.String()
+}
+
+// Name libraries as the Android.mk backend does for target modules
+func testAndroidNames(names []string) (ret []string) {
+	for _, name := range names {
+		ret = append(ret, name+"__target")
+	}
+	return
+}
+
+func Test_androidCompileFlags(t *testing.T) {
+	flags, err := newAndroidCompileFlags(nil,
+		[]string{"-marm", "-DFOO", "-mcpu=cortex-a53"},
+		[]string{"-std=c11"}, []string{"-std=c++14", "-fno-rtti"})
+	assert.Nil(t, err)
+
+	assert.Equal(t,
+		"LOCAL_CFLAGS := -DFOO\n"+
+			"LOCAL_CPPFLAGS := -fno-rtti\n"+
+			"LOCAL_C_STD:=c11\n"+
+			"LOCAL_CPP_STD:=c++14\n"+
+			"LOCAL_ARM_MODE:=arm\n",
+		androidMkOf(&flags))
+
+	assert.Equal(t,
+		"cc_library {\n"+
+			"    name: \"libfoo\",\n"+
+			"    c_std: \"c11\",\n"+
+			"    cpp_std: \"c++14\",\n"+
+			"    instruction_set: \"arm\",\n"+
+			"    cflags: [\"-DFOO\"],\n"+
+			"    cppflags: [\"-fno-rtti\"],\n"+
+			"}\n\n",
+		androidBpOf(t, &flags))
+}
+
+func Test_androidCompileFlags_audit(t *testing.T) {
+	audit := &ccflags.Audit{}
+	_, err := newAndroidCompileFlags(audit,
+		[]string{"-mcpu=cortex-a53"}, []string{"-std=c11"}, []string{"-std=c++14"})
+	assert.Nil(t, err)
+
+	properties := []string{}
+	for _, d := range audit.Dropped {
+		properties = append(properties, d.Property)
+	}
+	assert.Equal(t, []string{"cflags", "cxxflags", "conlyflags"}, properties)
+}
+
+func Test_androidCompileFlags_conflicting_arm_mode(t *testing.T) {
+	_, err := newAndroidCompileFlags(nil, []string{"-marm"}, nil, []string{"-mthumb"})
+	assert.NotNil(t, err)
+}
+
+func Test_androidLibs(t *testing.T) {
+	props := BuildProps{
+		Shared_libs:        []string{"libshared"},
+		ResolvedStaticLibs: []string{"libstatic", "libother"},
+		Whole_static_libs:  []string{"libwhole"},
+		Reexport_libs:      []string{"libshared", "libstatic", "libheader"},
+		Link_group:         []string{"libstatic", "libother"},
+	}
+
+	libs := newAndroidLibs(&props, testAndroidNames, []string{"libheader"}, []string{"libexported"})
+
+	assert.Equal(t,
+		"LOCAL_SHARED_LIBRARIES := libshared__target\n"+
+			"LOCAL_STATIC_LIBRARIES := libstatic__target libother__target\n"+
+			"LOCAL_WHOLE_STATIC_LIBRARIES := libwhole__target\n"+
+			"LOCAL_HEADER_LIBRARIES := libheader__target libexported__target\n"+
+			"LOCAL_GROUP_STATIC_LIBRARIES := true\n"+
+			"LOCAL_EXPORT_SHARED_LIBRARY_HEADERS := libshared__target\n"+
+			"LOCAL_EXPORT_STATIC_LIBRARY_HEADERS := libstatic__target\n"+
+			"LOCAL_EXPORT_HEADER_LIBRARY_HEADERS := libexported__target libheader__target\n",
+		androidMkOf(&libs))
+
+	bp := androidBpOf(t, &libs)
+	assert.Contains(t, bp, "    group_static_libs: true,\n")
+	assert.Contains(t, bp, "    export_shared_lib_headers: [\"libshared__target\"],\n")
+	assert.Contains(t, bp, "    export_header_lib_headers: [\n"+
+		"        \"libexported__target\",\n"+
+		"        \"libheader__target\",\n"+
+		"    ],\n")
+}
+
+func Test_androidLibs_without_exports(t *testing.T) {
+	props := BuildProps{Shared_libs: []string{"libshared"}}
+
+	libs := newAndroidLibs(&props, testAndroidNames, nil, nil)
+
+	assert.Equal(t, "LOCAL_SHARED_LIBRARIES := libshared__target\n", androidMkOf(&libs))
+	assert.Equal(t,
+		"cc_library {\n"+
+			"    name: \"libfoo\",\n"+
+			"    shared_libs: [\"libshared__target\"],\n"+
+			"}\n\n",
+		androidBpOf(t, &libs))
+}
+
+func Test_androidMkIncludes(t *testing.T) {
+	includes := androidMkIncludes{
+		includes:       []string{"$(LOCAL_PATH)/include", "/usr/include/foo"},
+		exportIncludes: []string{"$(LOCAL_PATH)/include"},
+		archIncludes:   "LOCAL_C_INCLUDES_arm:=gen/arm\n",
+	}
+
+	assert.Equal(t,
+		"LOCAL_C_INCLUDES := $(LOCAL_PATH)/include /usr/include/foo\n"+
+			"LOCAL_C_INCLUDES_arm:=gen/arm\n",
+		androidMkOf(&includes))
+
+	sb := &strings.Builder{}
+	includes.androidMkExports(sb)
+	assert.Equal(t, "LOCAL_EXPORT_C_INCLUDE_DIRS := $(LOCAL_PATH)/include\n", sb.String())
+}
+
+func Test_androidMkInstall_uninstallable(t *testing.T) {
+	install := androidMkInstall{bt: binTypeStatic, tgt: tgtTypeTarget}
+	assert.Equal(t, "LOCAL_UNINSTALLABLE_MODULE:=true\n", androidMkOf(&install))
+
+	// Shared libraries and host modules are always installed
+	install = androidMkInstall{bt: binTypeShared, tgt: tgtTypeTarget}
+	assert.Equal(t, "", androidMkOf(&install))
+	install = androidMkInstall{bt: binTypeExecutable, tgt: tgtTypeHost}
+	assert.Equal(t, "", androidMkOf(&install))
+}
+
+func Test_androidMkInstall_executables(t *testing.T) {
+	install := androidMkInstall{
+		bt:             binTypeExecutable,
+		tgt:            tgtTypeTarget,
+		installable:    true,
+		base:           "$(TARGET_OUT_VENDOR)",
+		rel:            "bin",
+		postInstallCmd: "strip $(LOCAL_INSTALLED_MODULE)",
+		required:       []string{"libfoo"},
+	}
+	assert.Equal(t,
+		"LOCAL_POST_INSTALL_CMD=strip $(LOCAL_INSTALLED_MODULE)\n"+
+			"LOCAL_MODULE_PATH:=$(TARGET_OUT_VENDOR)\n"+
+			"LOCAL_MODULE_RELATIVE_PATH:=bin\n"+
+			"LOCAL_UNSTRIPPED_PATH:=$(TARGET_OUT_EXECUTABLES_UNSTRIPPED)\n"+
+			"LOCAL_REQUIRED_MODULES:= \\\n    libfoo\n",
+		androidMkOf(&install))
+
+	install = androidMkInstall{
+		bt:          binTypeExecutable,
+		tgt:         tgtTypeTarget,
+		multilib:    true,
+		installable: true,
+		base:        "$(TARGET_OUT_VENDOR)",
+		rel:         "bin",
+	}
+	assert.Equal(t,
+		"LOCAL_MODULE_PATH_32:=$(TARGET_OUT_VENDOR)/bin\n"+
+			"LOCAL_MODULE_PATH_64:=$(TARGET_OUT_VENDOR)/bin64\n",
+		androidMkOf(&install))
+}
+
+func Test_androidMkInstall_libraries(t *testing.T) {
+	install := androidMkInstall{
+		bt:          binTypeShared,
+		tgt:         tgtTypeTarget,
+		installable: true,
+		base:        "$(TARGET_OUT_VENDOR)",
+		rel:         "egl",
+	}
+	assert.Equal(t, "LOCAL_MODULE_RELATIVE_PATH:=egl\n", androidMkOf(&install))
+}
+
+func Test_androidMkMultilib(t *testing.T) {
+	multilib := androidMkMultilib{
+		multilib: "both",
+		ldflags:  []string{"-Wl,--no-undefined"},
+		ldlibs:   []string{"-llog"},
+		tgt:      tgtTypeTarget,
+	}
+	assert.Equal(t,
+		"LOCAL_MULTILIB:=both\n"+
+			"LOCAL_LDFLAGS_32 := -Wl,--no-undefined\n"+
+			"LOCAL_LDFLAGS_64 := -Wl,--no-undefined\n"+
+			"LOCAL_LDLIBS := -llog\n",
+		androidMkOf(&multilib))
+
+	multilib = androidMkMultilib{
+		multilib: "first",
+		ldflags:  []string{"-Wl,--no-undefined"},
+		tgt:      tgtTypeTarget,
+	}
+	assert.Equal(t,
+		"LOCAL_MULTILIB:=first\n"+
+			"LOCAL_LDFLAGS := -Wl,--no-undefined\n",
+		androidMkOf(&multilib))
+
+	multilib = androidMkMultilib{
+		ldlibs: []string{"-lpthread"},
+		tgt:    tgtTypeHost,
+	}
+	assert.Equal(t, "LOCAL_LDLIBS_$(HOST_OS) := -lpthread\n", androidMkOf(&multilib))
+}
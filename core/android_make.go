@@ -18,6 +18,7 @@
 package core
 
 import (
+	"container/heap"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -28,7 +29,6 @@ import (
 
 	"github.com/ARM-software/bob-build/internal/ccflags"
 	"github.com/ARM-software/bob-build/internal/escape"
-	"github.com/ARM-software/bob-build/internal/fileutils"
 	"github.com/ARM-software/bob-build/internal/utils"
 )
 
@@ -57,7 +57,13 @@ func (g *androidMkGenerator) escapeFlag(s string) string {
 
 func androidMkWriteString(ctx blueprint.ModuleContext, name string, sb *strings.Builder) {
 	filename := getPathInBuildDir(name + ".inc")
-	err := fileutils.WriteIfChanged(filename, sb)
+	if provenanceEnabled(getConfig(ctx)) {
+		annotated := &strings.Builder{}
+		annotated.WriteString(provenanceComment(ctx))
+		annotated.WriteString(sb.String())
+		sb = annotated
+	}
+	err := getBackendOutput(getConfig(ctx)).writeFile(filename, sb)
 	if err != nil {
 		utils.Die("%v", err.Error())
 	}
@@ -92,232 +98,137 @@ var (
 	}
 )
 
-func specifyCompilerStandard(varname string, flags ...[]string) (line string) {
-	if std := ccflags.GetCompilerStandard(flags...); std != "" {
-		line = varname + ":=" + std + "\n"
+// writeAndroidMkPartition selects the partition the module is installed
+// to. Modules with an owner have always been marked proprietary, which
+// is the same as LOCAL_VENDOR_MODULE.
+func writeAndroidMkPartition(sb *strings.Builder, ctx blueprint.ModuleContext, props *AndroidProps) {
+	if props.isProprietary() {
+		sb.WriteString("LOCAL_MODULE_OWNER := " + proptools.String(props.Owner) + "\n")
 	}
-	return
-}
 
-func specifyArmMode(flags ...[]string) (line string) {
-	armMode, err := ccflags.GetArmMode(flags...)
-	if err != nil {
-		panic(err)
+	partition := androidPartition(ctx, props)
+	if partition == "" {
+		return
 	}
-
-	if armMode != "" {
-		line = "LOCAL_ARM_MODE:=" + armMode + "\n"
+	if props.Partition == nil {
+		sb.WriteString("LOCAL_PROPRIETARY_MODULE := true\n")
+	} else {
+		sb.WriteString(androidPartitions[partition].androidMk + " := true\n")
 	}
-	return
 }
 
-// Identifies if a module links to a generated library. Generated
-// libraries only support a single architecture
-func linksToGeneratedLibrary(ctx blueprint.ModuleContext) bool {
-	seenGeneratedLib := false
-
-	ctx.WalkDeps(func(dep, parent blueprint.Module) bool {
-
-		// Only consider dependencies that get linked
-		tag := ctx.OtherModuleDependencyTag(dep)
-		if tag == staticDepTag ||
-			tag == sharedDepTag ||
-			tag == wholeStaticDepTag {
-
-			_, staticLib := dep.(*generateStaticLibrary)
-			_, sharedLib := dep.(*generateSharedLibrary)
-			if sharedLib || staticLib {
-				// We depend on a generated library
-				seenGeneratedLib = true
-
-				// No need to continue walking
-				return false
-			}
-
-			// Keep walking this part of the tree
-			return true
-		}
-
-		return false
-	})
-
-	return seenGeneratedLib
-}
-
-// This function generates the Android make fragment to build static
-// libraries, shared libraries and executables. It's evolved over time
-// and needs to be refactored to use interfaces better.
-func androidLibraryBuildAction(sb *strings.Builder, mod blueprint.Module, ctx blueprint.ModuleContext, tcs toolchainSet) {
-	var bt binType
-	var m *library
-	var libname string
-
-	switch real := mod.(type) {
-	case *staticLibrary:
-		bt = binTypeStatic
-		m = &real.library
-		libname = real.outputFileName()
-	case *sharedLibrary:
-		bt = binTypeShared
-		m = &real.library
-		libname = real.outputFileName()
-	case *binary:
-		bt = binTypeExecutable
-		m = &real.library
-		libname = real.outputFileName()
-	default:
-		utils.Die("Unexpected module type %T", real)
+// writeGeneratedSourcesCopy adds the sources generated by 'module' to
+// the library. When 'perArch' is set, the sources generated for 'arch'
+// are only compiled for that architecture.
+func writeGeneratedSourcesCopy(sb *strings.Builder, m *library, module string, arch androidMkArch, perArch bool) {
+	// LOCAL_GENERATED_SOURCES is used to name target generated as
+	// part of this module which we also link into a library. The
+	// generated sources are automatically added to the
+	// library. Unfortunately, we've generated the sources in a
+	// separate module...
+	//
+	// To compile a file generated in another module we could try
+	// and explicitly list the file in that modules directory in
+	// LOCAL_SRCS. However Android make won't work with LOCAL_SRCS
+	// outside the source tree, so we can't do that.
+	//
+	// Therefore we use LOCAL_GENERATED_SOURCES and copy the files
+	// generated in the other module into this module.
+	//
+	// An alternative would be to avoid the separate source
+	// generation module and do it as part of this module.
+
+	sources := "$(" + module + "_OUTPUTS" + arch.varSuffix + ")"
+	sourcesDir := "$(" + module + "_GEN_DIR" + arch.varSuffix + ")"
+	localDir := "$(local-generated-sources-dir)"
+	localVar := m.altName() + "_" + module + "_SRCS"
+	generatedVar := "LOCAL_GENERATED_SOURCES"
+	if perArch {
+		localDir = filepath.Join(localDir, arch.name)
+		localVar += arch.varSuffix
+		generatedVar += "_" + arch.name
 	}
 
-	if m.Properties.Build_wrapper != nil {
-		utils.Die("build_wrapper not supported on Android")
-	}
+	localSourceExpr := "$(subst " + sourcesDir + ", " + localDir + ", " + sources + ")"
+	localSources := "$(" + localVar + ")"
 
-	// Calculate and record outputs
-	m.outs = []string{filepath.Join(m.outputDir(), libname)}
+	sb.WriteString(localVar + ":=" + localSourceExpr + "\n")
+	sb.WriteString(generatedVar + "+=" + localSources + "\n")
 
-	sb.WriteString("##########################\ninclude $(CLEAR_VARS)\n\n")
-	sb.WriteString("LOCAL_MODULE:=" + m.altName() + "\n")
-	sb.WriteString("LOCAL_MODULE_CLASS:=" + classes[bt] + "\n\n")
+	// Copy rule. Use a static pattern to avoid running the command for each file
+	sb.WriteString(localSources + ": " + localDir + "/%: " + sourcesDir + "/%\n")
+	sb.WriteString("\tcp $< $@\n\n")
+}
 
-	// The order we want is  local_include_dirs, export_local_include_dirs,
-	//                       include_dirs, export_include_dirs
-	// This is because include and export include should be system headers
+// androidMkIncludes returns the include directories of the module,
+// along with the generated headers it must wait for. 'headerIncludes'
+// and 'exportHeaderIncludes' are the directories of the header
+// libraries which Android make can't provide.
+func (g *androidMkGenerator) androidMkIncludes(ctx blueprint.ModuleContext, m *library, isMultiLib bool,
+	headerIncludes, exportHeaderIncludes []string) (androidMkIncludes, []string) {
 	includes := utils.PrefixDirs(m.Properties.Local_include_dirs, "$(LOCAL_PATH)")
 	includes = append(includes, utils.PrefixDirs(m.Properties.Export_local_include_dirs, "$(LOCAL_PATH)")...)
 	includes = append(includes, m.Properties.Include_dirs...)
 	includes = append(includes, m.Properties.Export_include_dirs...)
+	includes = append(includes, utils.PrefixDirs(m.exported.externalLocalIncludeDirs, "$(LOCAL_PATH)")...)
+	includes = append(includes, m.exported.externalIncludeDirs...)
 
-	exportIncludeDirs := utils.NewStringSlice(m.Properties.Export_include_dirs, utils.PrefixDirs(m.Properties.Export_local_include_dirs, "$(LOCAL_PATH)"))
+	exportIncludes := utils.NewStringSlice(m.Properties.Export_include_dirs,
+		utils.PrefixDirs(m.Properties.Export_local_include_dirs, "$(LOCAL_PATH)"))
 
-	// Handle generated headers
-	headerDirs, headerOutputs := m.GetGeneratedHeaders(ctx)
-	includes = append(includes, headerDirs...)
-	additionalDeps := headerOutputs
-
-	// Handle generated sources
-	for _, module := range m.getAllGeneratedSourceModules(ctx) {
-		// LOCAL_GENERATED_SOURCES is used to name target generated as
-		// part of this module which we also link into a library. The
-		// generated sources are automatically added to the
-		// library. Unfortunately, we've generated the sources in a
-		// separate module...
-		//
-		// To compile a file generated in another module we could try
-		// and explicitly list the file in that modules directory in
-		// LOCAL_SRCS. However Android make won't work with LOCAL_SRCS
-		// outside the source tree, so we can't do that.
-		//
-		// Therefore we use LOCAL_GENERATED_SOURCES and copy the files
-		// generated in the other module into this module.
-		//
-		// An alternative would be to avoid the separate source
-		// generation module and do it as part of this module.
-
-		sources := "$(" + module + "_OUTPUTS)"
-		sourcesDir := "$(" + module + "_GEN_DIR)"
-
-		localSourceExpr := "$(subst " + sourcesDir + ", $(local-generated-sources-dir), " + sources + ")"
-		localSources := "$(" + m.altName() + "_" + module + "_SRCS)"
-
-		sb.WriteString(m.altName() + "_" + module + "_SRCS:=" + localSourceExpr + "\n")
-		sb.WriteString("LOCAL_GENERATED_SOURCES+=" + localSources + "\n")
-
-		// Copy rule. Use a static pattern to avoid running the command for each file
-		sb.WriteString(localSources + ": $(local-generated-sources-dir)" + "/%: " + sourcesDir + "/%\n")
-		sb.WriteString("\tcp $< $@\n\n")
-	}
-
-	if getConfig(ctx).Properties.GetBool("target_toolchain_clang") {
-		sb.WriteString("LOCAL_CLANG := true\n")
-	} else {
-		sb.WriteString("LOCAL_CLANG := false\n")
+	if m.Properties.TargetType == tgtTypeHost {
+		includes = utils.MergeUnique(includes, headerIncludes, exportHeaderIncludes)
+		exportIncludes = append(exportIncludes, exportHeaderIncludes...)
 	}
-	srcs := m.Properties.getSources(ctx)
 
-	// Remove sources which are not compiled
-	nonCompiledDeps := utils.Filter(utils.IsNotCompilableSource, srcs)
-	srcs = utils.Filter(utils.IsCompilableSource, srcs)
-
-	writeListAssignment(sb, "LOCAL_SRC_FILES", srcs)
-
-	versionScript := m.getVersionScript(ctx)
-	if bt == binTypeShared || bt == binTypeExecutable {
-		if versionScript != nil {
-			additionalDeps = append(additionalDeps, *versionScript)
+	// Handle generated headers. Multilib modules use the headers each
+	// arch variant generates for the architecture being compiled.
+	headerDirs, headerOutputs := m.GetGeneratedHeaders(ctx)
+	archIncludes := &strings.Builder{}
+	if isMultiLib {
+		archHeaderModules := archVariantHeaderModules(m)
+		for _, mod := range archHeaderModules {
+			gc, _ := getGenerateCommon(mod)
+			headerDirs = utils.Difference(headerDirs, gc.genIncludeDirs())
 		}
+		g.writeArchHeaderIncludes(archIncludes, archHeaderModules)
 	}
 
-	additionalDeps = append(additionalDeps, utils.PrefixDirs(nonCompiledDeps, "$(LOCAL_PATH)")...)
-	writeListAssignment(sb, "LOCAL_ADDITIONAL_DEPENDENCIES", additionalDeps)
-	writeListAssignment(sb, "LOCAL_C_INCLUDES", includes)
+	return androidMkIncludes{
+		includes:       append(includes, headerDirs...),
+		exportIncludes: exportIncludes,
+		archIncludes:   archIncludes.String(),
+	}, headerOutputs
+}
 
-	cflagsList := utils.NewStringSlice(m.Properties.Cflags, m.Properties.Export_cflags)
-	_, _, exportedCflags := m.GetExportedVariables(ctx)
-	cflagsList = append(cflagsList, exportedCflags...)
-	writeListAssignment(sb, "LOCAL_CFLAGS",
-		utils.Filter(ccflags.AndroidCompileFlags, cflagsList))
-	writeListAssignment(sb, "LOCAL_CPPFLAGS",
-		utils.Filter(ccflags.AndroidCompileFlags, m.Properties.Cxxflags))
-	writeListAssignment(sb, "LOCAL_CONLYFLAGS",
-		utils.Filter(ccflags.AndroidCompileFlags, m.Properties.Conlyflags))
-
-	// Setup module C/C++ standard if requested. Note that this only affects Android O and later.
-	sb.WriteString(specifyCompilerStandard("LOCAL_C_STD", cflagsList, m.Properties.Conlyflags))
-	sb.WriteString(specifyCompilerStandard("LOCAL_CPP_STD", cflagsList, m.Properties.Cxxflags))
-
-	// Setup ARM mode if needed
-	sb.WriteString(specifyArmMode(cflagsList, m.Properties.Conlyflags, m.Properties.Cxxflags))
-
-	// convert Shared_libs, Resolved_static_libs, and Whole_static_libs
-	// to Android module names rather than Bob module names
-	sharedLibs := androidModuleNames(m.Properties.Shared_libs)
-	staticLibs := androidModuleNames(m.Properties.ResolvedStaticLibs)
-	wholeStaticLibs := androidModuleNames(m.Properties.Whole_static_libs)
-	exportHeaderLibs := androidModuleNames(m.Properties.Export_header_libs)
-	headerLibs := append(androidModuleNames(m.Properties.Header_libs), exportHeaderLibs...)
-
-	writeListAssignment(sb, "LOCAL_SHARED_LIBRARIES", sharedLibs)
-	writeListAssignment(sb, "LOCAL_STATIC_LIBRARIES", staticLibs)
-	writeListAssignment(sb, "LOCAL_WHOLE_STATIC_LIBRARIES", wholeStaticLibs)
-	writeListAssignment(sb, "LOCAL_HEADER_LIBRARIES", headerLibs)
-
-	reexportShared := []string{}
-	reexportStatic := []string{}
-	reexportHeaders := exportHeaderLibs
-	for _, lib := range androidModuleNames(m.Properties.Reexport_libs) {
-		if utils.Contains(sharedLibs, lib) {
-			reexportShared = append(reexportShared, lib)
-		} else if utils.Contains(staticLibs, lib) {
-			reexportStatic = append(reexportStatic, lib)
-		} else if utils.Contains(headerLibs, lib) {
-			reexportHeaders = append(reexportHeaders, lib)
-		}
+// androidMkPostInstallCmd returns the post install command of the
+// module, with its variables expanded.
+func androidMkPostInstallCmd(props *InstallableProps) string {
+	if props.Post_install_cmd == nil {
+		return ""
 	}
 
-	writeListAssignment(sb, "LOCAL_EXPORT_SHARED_LIBRARY_HEADERS", reexportShared)
-	writeListAssignment(sb, "LOCAL_EXPORT_STATIC_LIBRARY_HEADERS", reexportStatic)
-	writeListAssignment(sb, "LOCAL_EXPORT_HEADER_LIBRARY_HEADERS", reexportHeaders)
-
-	writeListAssignment(sb, "LOCAL_MODULE_TAGS", m.Properties.Tags)
-	writeListAssignment(sb, "LOCAL_EXPORT_C_INCLUDE_DIRS", exportIncludeDirs)
-	if m.Properties.isProprietary() {
-		sb.WriteString("LOCAL_MODULE_OWNER := " + proptools.String(m.Properties.Owner) + "\n")
-		sb.WriteString("LOCAL_PROPRIETARY_MODULE := true\n")
+	// Setup args like we do for bob_generated_*
+	args := map[string]string{}
+	if props.Post_install_tool != nil {
+		args["tool"] = *props.Post_install_tool
 	}
-	if strlib, ok := mod.(stripable); ok && strlib.strip() {
-		sb.WriteString("LOCAL_STRIP_MODULE := true\n")
-	}
-
-	tgt := m.Properties.TargetType
-
-	var tc toolchain
-	if tgt == tgtTypeTarget {
-		tc = tcs.target
-	} else {
-		tc = tcs.host
+	args["out"] = "$(LOCAL_INSTALLED_MODULE)"
+
+	// We can't use target specific variables in make due to
+	// the way LOCAL_POST_INSTALL_CMD is
+	// implemented. Therefore expand all variable use here.
+	cmd := strings.Replace(*props.Post_install_cmd, "${args}",
+		strings.Join(props.Post_install_args, " "), -1)
+	for key, value := range args {
+		cmd = strings.Replace(cmd, "${"+key+"}", value, -1)
 	}
+	return cmd
+}
 
+// androidMkLdflags returns the linker flags of the module.
+func androidMkLdflags(ctx blueprint.ModuleContext, m *library, bt binType,
+	tc toolchain, versionScript *string) []string {
 	// Can't see a way to wrap a particular library in -Wl in link flags on android, so specify
 	// -Wl,--copy-dt-needed-entries across the lot
 	hasForwardingLib := false
@@ -339,10 +250,63 @@ func androidLibraryBuildAction(sb *strings.Builder, mod blueprint.Module, ctx bl
 			}
 		})
 	if hasForwardingLib {
-		copydtneeded = "-fuse-ld=bfd " + tc.getLinker().keepSharedLibraryTransitivity()
+		copydtneeded = utils.Join([]string{tc.getLinker().getForwardingLibFlags(),
+			tc.getLinker().keepSharedLibraryTransitivity()})
 	}
 
-	// Handle installation
+	ldflags := getFlagAudit(ctx).Filter("ldflags", ccflags.AndroidLinkFlagDropReason, m.Properties.Ldflags)
+	ldflags = append(ldflags, copydtneeded)
+
+	if bt == binTypeShared || bt == binTypeExecutable {
+		if versionScript != nil {
+			ldflags = append(ldflags, tc.getLinker().setVersionScript(*versionScript))
+		}
+		ldflags = append(ldflags, m.gcSectionsLdflags(tc)...)
+	}
+	return ldflags
+}
+
+// androidLibraryBuildAction generates the Android make fragment to
+// build static libraries, shared libraries and executables.
+func androidLibraryBuildAction(sb *strings.Builder, mod blueprint.Module, ctx blueprint.ModuleContext, g *androidMkGenerator) {
+	var bt binType
+	var m *library
+	var libname string
+
+	switch real := mod.(type) {
+	case *staticLibrary:
+		bt = binTypeStatic
+		m = &real.library
+		libname = real.outputFileName()
+	case *sharedLibrary:
+		bt = binTypeShared
+		m = &real.library
+		libname = real.outputFileName()
+	case *binary:
+		bt = binTypeExecutable
+		m = &real.library
+		libname = real.outputFileName()
+	default:
+		utils.Die("Unexpected module type %T", real)
+	}
+
+	if len(m.Properties.Env) > 0 {
+		utils.Die("env not supported on Android libraries")
+	}
+	if m.Properties.Post_build_cmd != nil {
+		utils.Die("post_build_cmd not supported on Android")
+	}
+	if len(m.Properties.Post_install_outs) > 0 {
+		utils.Die("post_install_outs not supported on Android")
+	}
+	if len(m.Properties.Objcopy_formats) > 0 {
+		utils.Die("objcopy_formats not supported on Android")
+	}
+
+	// Calculate and record outputs
+	m.outs = []string{filepath.Join(m.outputDir(), libname)}
+
+	tgt := m.Properties.TargetType
 	installBase, installRel, ok := getAndroidInstallPath(&m.Properties.InstallableProps)
 
 	// Only setup multilib for target modules.
@@ -350,119 +314,173 @@ func androidLibraryBuildAction(sb *strings.Builder, mod blueprint.Module, ctx bl
 	// also do multilib target binaries to allow creation of test
 	// binaries in both modes.
 	// All test binaries will be installable.
-	isMultiLib := (tgt == tgtTypeTarget) &&
+	// AUX modules are built for a single processor.
+	isMultiLib := (tgt == tgtTypeTarget) && !m.Properties.isAux() &&
 		((bt == binTypeShared) || (bt == binTypeStatic) || ok)
 
-	// Disable multilib if this module depends on generated libraries
-	// (which can't support multilib)
-	if isMultiLib && linksToGeneratedLibrary(ctx) {
-		isMultiLib = false
+	// Restrict the module to the primary architecture if it links to
+	// a generated library that is only built for that architecture
+	singleArchLib := ""
+	if isMultiLib {
+		singleArchLib = singleArchGeneratedLibrary(ctx)
+		if singleArchLib != "" {
+			utils.Warnf(ctx.ModuleName(), "only built for the primary architecture, as it links to %s, "+
+				"whose command does not use ${arch}", singleArchLib)
+			isMultiLib = false
+		}
 	}
 
-	if ok {
-		if m.Properties.Post_install_cmd != nil {
-			// Setup args like we do for bob_generated_*
-			args := map[string]string{}
-			if m.Properties.Post_install_tool != nil {
-				args["tool"] = *m.Properties.Post_install_tool
-			}
-			args["out"] = "$(LOCAL_INSTALLED_MODULE)"
-
-			// We can't use target specific variables in make due to
-			// the way LOCAL_POST_INSTALL_CMD is
-			// implemented. Therefore expand all variable use here.
-			cmd := strings.Replace(*m.Properties.Post_install_cmd, "${args}",
-				strings.Join(m.Properties.Post_install_args, " "), -1)
-			for key, value := range args {
-				cmd = strings.Replace(cmd, "${"+key+"}", value, -1)
-			}
+	// Android make has no host header libraries
+	headerLibNames := m.Properties.Header_libs
+	exportHeaderLibNames := m.Properties.Export_header_libs
+	var headerIncludes, exportHeaderIncludes []string
+	if tgt == tgtTypeHost {
+		headerLibNames, headerIncludes = hostHeaderLibraries(ctx, headerLibNames)
+		exportHeaderLibNames, exportHeaderIncludes = hostHeaderLibraries(ctx, exportHeaderLibNames)
+	}
+
+	includes, additionalDeps := g.androidMkIncludes(ctx, m, isMultiLib, headerIncludes, exportHeaderIncludes)
+
+	_, _, exportedCflags := m.GetExportedVariables(ctx)
+	flags, err := newAndroidCompileFlags(getFlagAudit(ctx),
+		utils.MergeFlags(m.Properties.Cflags, m.Properties.Export_cflags, exportedCflags),
+		m.Properties.Conlyflags, m.Properties.getCxxflags())
+	if err != nil {
+		panicInProperty("cflags", err)
+	}
+
+	libs := newAndroidLibs(&m.Properties.Build.BuildProps, androidModuleNames,
+		headerLibNames, exportHeaderLibNames)
+
+	var tc toolchain
+	if tgt == tgtTypeTarget {
+		tc = g.target
+	} else {
+		tc = g.host
+	}
+	versionScript := m.getVersionScript(ctx)
 
-			// Intentionally using a recursively expanded variable. We
-			// don't want LOCAL_INSTALLED_MODULE expanded now, but
-			// when it is used in base_rules.mk
-			sb.WriteString("LOCAL_POST_INSTALL_CMD=" + cmd + "\n")
+	install := androidMkInstall{
+		bt:          bt,
+		tgt:         tgt,
+		multilib:    isMultiLib,
+		installable: ok,
+		base:        installBase,
+		rel:         installRel,
+	}
+	if ok {
+		install.postInstallCmd = androidMkPostInstallCmd(&m.Properties.InstallableProps)
+		for _, name := range m.getInstallDepPhonyNames(ctx) {
+			install.required = append(install.required, androidModuleName(name))
 		}
+	}
 
-		if bt == binTypeExecutable {
-			if isMultiLib {
-				// For executables we need to be clear about where to
-				// install both 32 and 64 bit versions of the
-				// binaries. This is done by appending `64` to the install dir.
-				// However, there are not separate variables for the 32 and 64-bit
-				// relative paths, so ignore LOCAL_MODULE_RELATIVE_PATH and put the
-				// relative_install_path in LOCAL_MODULE_PATH_[32|64] instead.
-				//
-				// LOCAL_UNSTRIPPED_PATH does not need to be set
-				fullInstallPath := filepath.Join(installBase, installRel)
-				sb.WriteString("LOCAL_MODULE_PATH_32:=" + fullInstallPath + "\n")
-				sb.WriteString("LOCAL_MODULE_PATH_64:=" + fullInstallPath + "64\n")
-			} else {
-				sb.WriteString("LOCAL_MODULE_PATH:=" + installBase + "\n")
-				sb.WriteString("LOCAL_MODULE_RELATIVE_PATH:=" + installRel + "\n")
-
-				// When LOCAL_MODULE_PATH is specified, you need to
-				// specify LOCAL_UNSTRIPPED_PATH too
-				if tgt == tgtTypeTarget {
-					// Unstripped executables only generated for target
-					sb.WriteString("LOCAL_UNSTRIPPED_PATH:=$(TARGET_OUT_EXECUTABLES_UNSTRIPPED)\n")
-				}
-			}
+	multilib := androidMkMultilib{
+		ldflags: androidMkLdflags(ctx, m, bt, tc, versionScript),
+		ldlibs:  m.Properties.Ldlibs,
+		tgt:     tgt,
+	}
+	if isMultiLib {
+		multilib.multilib = "both"
+	} else if singleArchLib != "" {
+		multilib.multilib = "first"
+	}
+
+	sb.WriteString("##########################\ninclude $(CLEAR_VARS)\n\n")
+	sb.WriteString("LOCAL_MODULE:=" + m.altName() + "\n")
+	sb.WriteString("LOCAL_MODULE_CLASS:=" + classes[bt] + "\n\n")
+
+	// Handle generated sources
+	for _, module := range m.getAllGeneratedSourceModules(ctx) {
+		if isMultiLib && isArchVariant(generatedModule(ctx, module)) {
+			writeArchBlock(sb, androidMkDeviceArches, func(arch androidMkArch) {
+				writeGeneratedSourcesCopy(sb, m, module, arch, true)
+			})
 		} else {
-			// You can't specify an explicit install dir for
-			// libraries, so we can only control LOCAL_MODULE_RELATIVE_PATH
-			sb.WriteString("LOCAL_MODULE_RELATIVE_PATH:=" + installRel + "\n")
+			writeGeneratedSourcesCopy(sb, m, module, androidMkPrimaryArch, false)
 		}
+	}
 
-		requiredModuleNames := m.getInstallDepPhonyNames(ctx)
-		if len(requiredModuleNames) > 0 {
-			for i, v := range requiredModuleNames {
-				requiredModuleNames[i] = androidModuleName(v)
-			}
-			sb.WriteString("LOCAL_REQUIRED_MODULES:=" + newlineSeparatedList(requiredModuleNames))
-		}
+	clang := getConfig(ctx).Properties.GetBool("target_toolchain_clang")
+	if clang {
+		sb.WriteString("LOCAL_CLANG := true\n")
 	} else {
-		// Only disable installation on the target, because host
-		// libraries need to be installed to be used by the build.
-		//
-		// Target shared libraries do not need an explicit installation
-		// location, but cannot be uninstallable, or the multilib paths
-		// will conflict, resulting in the same location being used for
-		// both 32 and 64-bit versions.
-		if tgt == tgtTypeTarget && bt != binTypeShared {
-			sb.WriteString("LOCAL_UNINSTALLABLE_MODULE:=true\n")
-		}
+		sb.WriteString("LOCAL_CLANG := false\n")
 	}
+	if buildWrapper, deps := m.Properties.getBuildWrapperAndDeps(ctx); buildWrapper != "" {
+		sb.WriteString(androidMkCompilerWrapper(buildWrapper, clang))
+		additionalDeps = append(additionalDeps, deps...)
+	}
+	srcs := m.Properties.getSources(ctx)
 
-	ldflags := utils.Filter(ccflags.AndroidLinkFlags, m.Properties.Ldflags)
-	ldflags = append(ldflags, copydtneeded)
+	// Remove sources which are not compiled
+	nonCompiledDeps := utils.Filter(utils.IsNotCompilableSource, srcs)
+	srcs = utils.Filter(utils.IsCompilableSource, srcs)
+
+	writeListAssignment(sb, "LOCAL_SRC_FILES", srcs)
 
 	if (bt == binTypeShared || bt == binTypeExecutable) && versionScript != nil {
-		ldflags = append(ldflags, tc.getLinker().setVersionScript(*versionScript))
+		additionalDeps = append(additionalDeps, *versionScript)
 	}
+	additionalDeps = append(additionalDeps, utils.PrefixDirs(nonCompiledDeps, "$(LOCAL_PATH)")...)
+	writeListAssignment(sb, "LOCAL_ADDITIONAL_DEPENDENCIES", additionalDeps)
 
-	if isMultiLib {
-		sb.WriteString("LOCAL_MULTILIB:=both\n")
-		writeListAssignment(sb, "LOCAL_LDFLAGS_32", ldflags)
-		writeListAssignment(sb, "LOCAL_LDFLAGS_64", ldflags)
-	} else {
-		writeListAssignment(sb, "LOCAL_LDFLAGS", ldflags)
+	for _, e := range []androidMkEmitter{&includes, &flags, &libs} {
+		e.androidMk(sb)
 	}
 
-	if tgt == tgtTypeTarget {
-		writeListAssignment(sb, "LOCAL_LDLIBS", m.Properties.Ldlibs)
-	} else {
-		writeListAssignment(sb, "LOCAL_LDLIBS_$(HOST_OS)", m.Properties.Ldlibs)
+	writeListAssignment(sb, "LOCAL_MODULE_TAGS", m.Properties.Tags)
+	includes.androidMkExports(sb)
+	writeAndroidMkPartition(sb, ctx, &m.Properties.AndroidProps)
+	if strlib, ok := mod.(stripable); ok && strlib.strip() {
+		sb.WriteString("LOCAL_STRIP_MODULE := true\n")
 	}
-	sb.WriteString("\ninclude $(" + rulePrefix[tgt] + ruleSuffix[bt] + ")\n")
+
+	for _, e := range []androidMkEmitter{&install, &multilib} {
+		e.androidMk(sb)
+	}
+
+	if bt == binTypeExecutable && proptools.Bool(m.Properties.Static_executable) {
+		sb.WriteString("LOCAL_FORCE_STATIC_EXECUTABLE:=true\n")
+	}
+
+	rule := rulePrefix[tgt] + ruleSuffix[bt]
+	if m.Properties.isAux() {
+		sb.WriteString(m.Properties.androidMkAuxVariables())
+		rule = "BUILD_" + auxRuleSuffix[bt]
+	}
+	sb.WriteString("\ninclude $(" + rule + ")\n")
 
 	androidMkWriteString(ctx, m.altShortName(), sb)
 }
 
+// androidMkCompilerWrapper returns the assignments which make the
+// Android build system run the compilers through a build wrapper.
+// There is no wrapper variable for each module, so the compilers are
+// replaced by the wrapper followed by the compiler that would otherwise
+// be used. LOCAL_CXX also replaces the compiler used to link.
+//
+// These are intentionally recursively expanded, as my_prefix is only
+// set when binary.mk is included for each architecture.
+func androidMkCompilerWrapper(buildWrapper string, clang bool) string {
+	cc, cxx := "$(CLANG)", "$(CLANG_CXX)"
+	if !clang {
+		cc = "$($(LOCAL_2ND_ARCH_VAR_PREFIX)$(my_prefix)CC)"
+		cxx = "$($(LOCAL_2ND_ARCH_VAR_PREFIX)$(my_prefix)CXX)"
+	}
+	return "LOCAL_CC = " + buildWrapper + " " + cc + "\n" +
+		"LOCAL_CXX = " + buildWrapper + " " + cxx + "\n"
+}
+
 func (g *androidMkGenerator) staticActions(m *staticLibrary, ctx blueprint.ModuleContext) {
 	if enabledAndRequired(m) {
+		if m.relocatable {
+			ctx.ModuleErrorf("bob_relocatable_object is not supported by the Android make backend")
+			return
+		}
 		sb := &strings.Builder{}
 		m.outputdir = g.staticLibOutputDir(m)
-		androidLibraryBuildAction(sb, m, ctx, g.toolchainSet)
+		androidLibraryBuildAction(sb, m, ctx, g)
 	}
 }
 
@@ -470,7 +488,7 @@ func (g *androidMkGenerator) sharedActions(m *sharedLibrary, ctx blueprint.Modul
 	if enabledAndRequired(m) {
 		sb := &strings.Builder{}
 		m.outputdir = g.sharedLibOutputDir(m)
-		androidLibraryBuildAction(sb, m, ctx, g.toolchainSet)
+		androidLibraryBuildAction(sb, m, ctx, g)
 	}
 }
 
@@ -478,7 +496,7 @@ func (g *androidMkGenerator) binaryActions(m *binary, ctx blueprint.ModuleContex
 	if enabledAndRequired(m) {
 		sb := &strings.Builder{}
 		m.outputdir = g.binaryOutputDir(m)
-		androidLibraryBuildAction(sb, m, ctx, g.toolchainSet)
+		androidLibraryBuildAction(sb, m, ctx, g)
 	}
 }
 
@@ -500,6 +518,28 @@ func (g *androidMkGenerator) aliasActions(m *alias, ctx blueprint.ModuleContext)
 	androidMkWriteString(ctx, m.Name(), sb)
 }
 
+// Python and Go tools are only built by the Linux backend. They are
+// only reported when something built in the Android build needs them.
+func (g *androidMkGenerator) pythonBinaryActions(m *pythonBinary, ctx blueprint.ModuleContext) {
+	if enabledAndRequired(m) {
+		ctx.ModuleErrorf("bob_python_binary is not supported by the Android make backend")
+	}
+}
+
+func (g *androidMkGenerator) goBinaryActions(m *goBinary, ctx blueprint.ModuleContext) {
+	if enabledAndRequired(m) {
+		ctx.ModuleErrorf("bob_go_binary is not supported by the Android make backend")
+	}
+}
+
+// Android builds device trees and overlays with its own tools, as part
+// of the kernel and boot images.
+func (g *androidMkGenerator) deviceTreeActions(m *deviceTree, ctx blueprint.ModuleContext) {
+	if enabledAndRequired(m) {
+		ctx.ModuleErrorf("%s is not supported by the Android make backend", ctx.ModuleType())
+	}
+}
+
 func pathToModuleName(path string) string {
 	path = strings.Replace(path, "/", "__", -1)
 	path = strings.Replace(path, ".", "_", -1)
@@ -509,6 +549,48 @@ func pathToModuleName(path string) string {
 	return path
 }
 
+func (g *androidMkGenerator) headerLibraryActions(m *headerLibrary, ctx blueprint.ModuleContext) {
+	// Android make only has target header libraries. Host modules use
+	// the include directories directly, see hostHeaderLibraries().
+	if m.Properties.TargetType != tgtTypeTarget || !enabledAndRequired(m) {
+		return
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString("##########################\ninclude $(CLEAR_VARS)\n\n")
+	sb.WriteString("LOCAL_MODULE:=" + m.altName() + "\n")
+	writeListAssignment(sb, "LOCAL_EXPORT_C_INCLUDE_DIRS", utils.NewStringSlice(m.Properties.Export_include_dirs,
+		utils.PrefixDirs(m.Properties.Export_local_include_dirs, "$(LOCAL_PATH)")))
+	sb.WriteString("\ninclude $(BUILD_HEADER_LIBRARY)\n")
+
+	androidMkWriteString(ctx, m.altShortName(), sb)
+}
+
+// hostHeaderLibraries removes the bob_header_library modules from the
+// header libraries 'names' used by a host module, returning their
+// include directories instead.
+func hostHeaderLibraries(ctx blueprint.ModuleContext, names []string) (remaining []string, includeDirs []string) {
+	headerLibs := map[string]*headerLibrary{}
+	ctx.VisitDirectDepsIf(
+		func(dep blueprint.Module) bool { return ctx.OtherModuleDependencyTag(dep) == headerDepTag },
+		func(dep blueprint.Module) {
+			if hl, ok := dep.(*headerLibrary); ok {
+				headerLibs[dep.Name()] = hl
+			}
+		})
+
+	for _, name := range names {
+		if hl, ok := headerLibs[name]; ok {
+			includeDirs = append(includeDirs, hl.Properties.Export_include_dirs...)
+			includeDirs = append(includeDirs,
+				utils.PrefixDirs(hl.Properties.Export_local_include_dirs, "$(LOCAL_PATH)")...)
+		} else {
+			remaining = append(remaining, name)
+		}
+	}
+	return
+}
+
 func (g *androidMkGenerator) resourceActions(m *resource, ctx blueprint.ModuleContext) {
 	if !enabledAndRequired(m) {
 		return
@@ -530,16 +612,14 @@ func (g *androidMkGenerator) resourceActions(m *resource, ctx blueprint.ModuleCo
 
 		sb.WriteString("\ninclude $(CLEAR_VARS)\n\n")
 		sb.WriteString("LOCAL_MODULE := " + moduleName + "\n")
-		sb.WriteString("LOCAL_INSTALLED_MODULE_STEM := " + filepath.Base(file) + "\n")
+		rel := m.installRelPath(file)
+		sb.WriteString("LOCAL_INSTALLED_MODULE_STEM := " + filepath.Base(rel) + "\n")
 		sb.WriteString("LOCAL_MODULE_CLASS := ETC\n")
 		sb.WriteString("LOCAL_MODULE_PATH := " + installBase + "\n")
-		sb.WriteString("LOCAL_MODULE_RELATIVE_PATH := " + installRel + "\n")
+		sb.WriteString("LOCAL_MODULE_RELATIVE_PATH := " + m.installRelDir(installRel, file) + "\n")
 		writeListAssignment(sb, "LOCAL_MODULE_TAGS", m.Properties.Tags)
 		sb.WriteString("LOCAL_SRC_FILES := " + file + "\n")
-		if m.Properties.isProprietary() {
-			sb.WriteString("LOCAL_MODULE_OWNER := " + proptools.String(m.Properties.Owner) + "\n")
-			sb.WriteString("LOCAL_PROPRIETARY_MODULE := true\n")
-		}
+		writeAndroidMkPartition(sb, ctx, &m.Properties.AndroidProps)
 		sb.WriteString("\ninclude $(BUILD_PREBUILT)\n")
 	}
 
@@ -576,10 +656,6 @@ func outputsVarName(m *generateCommon) string {
 	return m.Name() + "_OUTPUTS"
 }
 
-func (g *androidMkGenerator) outputs(m *generateCommon) string {
-	return "$(" + outputsVarName(m) + ")"
-}
-
 // Setup rule to create export_includes
 func writeExportIncludeMkText(sb *strings.Builder, moduleName string) {
 	// The following makefile snippets are based on Android makefiles from AOSP
@@ -638,13 +714,23 @@ const libraryLinkTypeMkText string = "ifeq ($(PLATFORM_SDK_VERSION),25)\n" +
 	"  include $(BUILD_SYSTEM)/link_type.mk\n" +
 	"endif\n"
 
-func declarePrebuiltStaticLib(sb *strings.Builder, moduleName, path, includePaths string, target bool) {
+// declarePrebuiltArch selects the architecture of a target prebuilt.
+// The secondary architecture is selected by its variable prefix, in the
+// same way as the multilib support in the Android build system.
+func declarePrebuiltArch(sb *strings.Builder, archVarPrefix string) {
+	if archVarPrefix != "" {
+		sb.WriteString("LOCAL_2ND_ARCH_VAR_PREFIX:=" + archVarPrefix + "\n")
+	}
+}
+
+func declarePrebuiltStaticLib(sb *strings.Builder, moduleName, path, includePaths, archVarPrefix string, target bool) {
 	sb.WriteString("\ninclude $(CLEAR_VARS)\n")
 	sb.WriteString("LOCAL_MODULE:=" + moduleName + "\n")
 	sb.WriteString("LOCAL_SRC_FILES:=" + path + "\n")
 	if !target {
 		sb.WriteString("LOCAL_IS_HOST_MODULE:=true\n")
 	}
+	declarePrebuiltArch(sb, archVarPrefix)
 
 	// We would like to just have the following line, but it looks like it is NDK only
 	// Therefore all the following is needed.
@@ -664,13 +750,14 @@ func declarePrebuiltStaticLib(sb *strings.Builder, moduleName, path, includePath
 	sb.WriteString(libraryLinkTypeMkText)
 }
 
-func declarePrebuiltSharedLib(sb *strings.Builder, moduleName, path, includePaths string, target bool) {
+func declarePrebuiltSharedLib(sb *strings.Builder, moduleName, path, includePaths, archVarPrefix string, target bool) {
 	sb.WriteString("\ninclude $(CLEAR_VARS)\n")
 	sb.WriteString("LOCAL_MODULE:=" + moduleName + "\n")
 	sb.WriteString("LOCAL_SRC_FILES:=" + path + "\n")
 	if !target {
 		sb.WriteString("LOCAL_IS_HOST_MODULE:=true\n")
 	}
+	declarePrebuiltArch(sb, archVarPrefix)
 	// We would like to just have the following line, but it looks like it is NDK only
 	// Therefore all the following is needed.
 	//sb.WriteString("include $(PREBUILT_SHARED_LIBRARY)\n")
@@ -685,9 +772,8 @@ func declarePrebuiltSharedLib(sb *strings.Builder, moduleName, path, includePath
 	//  Put shared libraries in common path to simplify link line.
 	//  see shared_library_internal.mk and host_shared_library_internal.mk
 	if target {
-		// Only supporting the primary target architecture.
-		// Others are TARGET_2ND_x
-		sb.WriteString("OVERRIDE_BUILT_MODULE_PATH:=$(TARGET_OUT_INTERMEDIATE_LIBRARIES)\n\n")
+		// The secondary architecture's directory is TARGET_2ND_x
+		sb.WriteString("OVERRIDE_BUILT_MODULE_PATH:=$(" + archVarPrefix + "TARGET_OUT_INTERMEDIATE_LIBRARIES)\n\n")
 	} else {
 		// Only supporting the primary host architecture.
 		// Others are HOST_2ND_x, HOST_CROSS_x, HOST_CROSS_2ND_x
@@ -718,15 +804,23 @@ func declarePrebuiltBinary(sb *strings.Builder, moduleName, path string, target
 	sb.WriteString("\tcp $< $@\n")
 }
 
-func installGeneratedFiles(sb *strings.Builder, m installable, ctx blueprint.ModuleContext, tags []string) {
+// installGeneratedFiles installs the outputs a generator writes for
+// 'arch'. The outputs of the secondary device architecture are
+// installed in a subdirectory named after the architecture.
+func (g *androidMkGenerator) installGeneratedFiles(sb *strings.Builder, m installable, gc *generateCommon,
+	ctx blueprint.ModuleContext, arch androidMkArch) {
+
 	/* Install generated files one by one, if required */
 	installBase, installRel, ok := getAndroidInstallPath(m.getInstallableProps())
 
 	if !ok {
 		return
 	}
+	if arch == androidMkSecondaryArch {
+		installRel = filepath.Join(installRel, arch.name)
+	}
 	sb.WriteString("\n")
-	filesToInstall := m.filesToInstall(ctx)
+	filesToInstall := g.archPaths(gc, arch, m.filesToInstall(ctx))
 
 	for _, file := range filesToInstall {
 		moduleName := pathToModuleName(file)
@@ -738,37 +832,93 @@ func installGeneratedFiles(sb *strings.Builder, m installable, ctx blueprint.Mod
 		sb.WriteString("LOCAL_MODULE_CLASS := ETC\n")
 		sb.WriteString("LOCAL_MODULE_PATH := " + installBase + "\n")
 		sb.WriteString("LOCAL_MODULE_RELATIVE_PATH := " + installRel + "\n")
-		writeListAssignment(sb, "LOCAL_MODULE_TAGS", tags)
+		writeListAssignment(sb, "LOCAL_MODULE_TAGS", gc.Properties.Tags)
 		sb.WriteString("LOCAL_PREBUILT_MODULE_FILE := " + file + "\n\n")
 
 		sb.WriteString("include $(BUILD_PREBUILT)\n")
 	}
 }
 
+// writeToolStamp writes the rule hashing the tools run by a generator
+// into 'stamp'. Like the outputs of generators, the stamp is only
+// updated when its contents change.
+func (g *androidMkGenerator) writeToolStamp(sb *strings.Builder, stamp string, tools []string) {
+	script := filepath.Join(g.bobScriptsDir(), "tool_stamp.py")
+
+	sb.WriteString(stamp + ": LOCAL_PATH := $(LOCAL_PATH)\n")
+	sb.WriteString(stamp + ": tools := " + strings.Join(tools, " ") + "\n")
+	sb.WriteString(stamp + ": tool_stamp := " + script + "\n")
+	sb.WriteString(stamp + ": " + strings.Join(tools, " ") + " " + script + "\n")
+	sb.WriteString("\tpython $(tool_stamp) --output $@ --depfile $@.d --root $(LOCAL_PATH) $(tools)\n")
+	sb.WriteString(g.transformDepFile("$@.d"))
+	sb.WriteString(g.includeDepFile(stamp, stamp+".d"))
+	sb.WriteString(".KATI_RESTAT: " + stamp + "\n\n")
+}
+
+// generateCommonActions writes the rules running the module's command
+// once, for the host or the primary device architecture.
 func (g *androidMkGenerator) generateCommonActions(sb *strings.Builder, m *generateCommon, ctx blueprint.ModuleContext, inouts []inout) {
-	m.outputdir = g.sourceOutputDir(m)
-	prefixInoutsWithOutputDir(inouts, m.outputDir())
-	// Calculate and record outputs and include dirs
-	m.recordOutputsFromInout(inouts)
-	m.includeDirs = utils.PrefixDirs(m.Properties.Export_gen_include_dirs, m.outputDir())
+	arch := androidMkHostArch
+	if m.Properties.Target != tgtTypeHost {
+		arch = androidMkPrimaryArch
+	}
+	g.generateArchActions(sb, m, ctx, inouts, arch, g.sourceOutputDir(m))
+}
+
+// generateArchActions writes the rules running the module's command
+// for a single architecture, with outputs placed in outputDir.
+// Outputs and include directories are only recorded for the primary
+// architecture.
+func (g *androidMkGenerator) generateArchActions(sb *strings.Builder, m *generateCommon, ctx blueprint.ModuleContext,
+	inouts []inout, arch androidMkArch, outputDir string) {
+
+	prefixInoutsWithOutputDir(inouts, outputDir)
+	if arch.varSuffix == "" {
+		// Calculate and record outputs and include dirs
+		m.outputdir = outputDir
+		m.recordOutputsFromInout(inouts)
+		m.includeDirs = utils.PrefixDirs(m.Properties.Export_gen_include_dirs, m.outputDir())
+	}
+
+	outputsVar := outputsVarName(m) + arch.varSuffix
+	outputs := "$(" + outputsVar + ")"
 
 	sb.WriteString("##########################\ninclude $(CLEAR_VARS)\n\n")
 
 	// This is required to have $(local-generated-sources-dir) work as expected
 	sb.WriteString("LOCAL_MODULE := " + m.Name() + "\n")
 	sb.WriteString("LOCAL_MODULE_CLASS := STATIC_LIBRARIES\n")
-	sb.WriteString(outputsVarName(m) + " := \n")
-	sb.WriteString(outputDirVarName(m) + " := " + g.sourceOutputDir(m) + "\n")
+	sb.WriteString(outputsVar + " := \n")
+	sb.WriteString(outputDirVarName(m) + arch.varSuffix + " := " + outputDir + "\n")
 	sb.WriteString("\n")
 
-	cmd, args, implicits, _ := m.getArgs(ctx)
+	cmd, args, implicits, hostBinLibDirs := m.getArgs(ctx)
+	args["arch"] = arch.name
+	args["gen_dir"] = outputDir
+
+	orderOnly := []string{}
+	if tools := stampedTools(args); len(tools) > 0 && toolStampsEnabled(getConfig(ctx)) {
+		stamp := outputDir + ".tool_stamp"
+		g.writeToolStamp(sb, stamp, tools)
+		implicits = toolStampDeps(implicits, tools, stamp)
+		orderOnly = tools
+	}
 	utils.StripUnusedArgs(args, cmd)
 
+	// Set the variables from env, and let host_bin find its shared
+	// libraries where they were built, without relying on them having
+	// been installed.
+	cmd = m.Properties.envExportPrefix() + ldLibraryPathPrefix(hostBinLibDirs) + cmd
+
+	fileArgs := utils.ContainsArg(cmd, "file_args")
+
 	for _, inout := range inouts {
 		ins := strings.Join(inout.in, " ")
 
 		// Make does not cleanly support multiple out-files
-		// To handle that, we output the rule only on the first file, and let every other output
+		// To handle that, we output the rule only on the first file. Kati
+		// adds the other outputs to the same ninja action. Older versions,
+		// which don't support .KATI_IMPLICIT_OUTPUTS, let every other output
 		// depend on the first.
 		// This is not 100 % safe, since if the secondary file is removed, it will not be rebuilt.
 		// It is assumed that this will not be a big issue, since removing individual files from a generated
@@ -777,6 +927,9 @@ func (g *androidMkGenerator) generateCommonActions(sb *strings.Builder, m *gener
 		for _, key := range utils.SortedKeys(args) {
 			sb.WriteString(outs + ": " + key + ":= " + args[key] + "\n")
 		}
+		if fileArgs {
+			sb.WriteString(outs + ": file_args := " + strings.Join(inout.fileArgs, " ") + "\n")
+		}
 
 		sb.WriteString(outs + ": in := " + ins + "\n")
 		sb.WriteString(outs + ": out := " + strings.Join(inout.out, " ") + "\n")
@@ -804,26 +957,40 @@ func (g *androidMkGenerator) generateCommonActions(sb *strings.Builder, m *gener
 			// ...and include it outside the rule.
 			sb.WriteString(g.includeDepFile(outs, inout.depfile))
 		}
-		sb.WriteString(outputsVarName(m) + " += " + outs + "\n")
+		sb.WriteString(outputsVar + " += " + outs + "\n")
 
-		for _, out := range append(inout.out[1:], inout.implicitOuts...) {
-			sb.WriteString(out + ": " + outs + "\n")
-			sb.WriteString(outputsVarName(m) + " += " + out + "\n")
+		extraOuts := utils.NewStringSlice(inout.out[1:], inout.implicitOuts)
+		if len(extraOuts) > 0 {
+			sb.WriteString("ifneq ($(filter 25 26 27,$(PLATFORM_SDK_VERSION)),)\n")
+			for _, out := range extraOuts {
+				sb.WriteString(out + ": " + outs + "\n")
+			}
+			sb.WriteString("else\n")
+			sb.WriteString(outs + ": .KATI_IMPLICIT_OUTPUTS := " + strings.Join(extraOuts, " ") + "\n")
+			sb.WriteString("endif\n")
+		}
+		for _, out := range extraOuts {
+			sb.WriteString(outputsVar + " += " + out + "\n")
 		}
 		sb.WriteString("\n")
 	}
-	sb.WriteString(g.outputs(m) + ": " + strings.Join(implicits, " ") + "\n")
+	sb.WriteString(outputs + ": " + strings.Join(implicits, " ") + "\n")
+	if len(orderOnly) > 0 {
+		sb.WriteString(outputs + ": | " + strings.Join(orderOnly, " ") + "\n")
+	}
 
 	/* This will ensure that any dependencies will not be rebuilt in the case of no change */
-	sb.WriteString(".KATI_RESTAT: " + g.outputs(m) + "\n")
+	sb.WriteString(".KATI_RESTAT: " + outputs + "\n")
 }
 
 func (g *androidMkGenerator) generateSourceActions(m *generateSource, ctx blueprint.ModuleContext) {
 	if enabledAndRequired(m) {
 		sb := &strings.Builder{}
-		inouts := m.generateInouts(ctx, g)
-		g.generateCommonActions(sb, &m.generateCommon, ctx, inouts)
-		installGeneratedFiles(sb, m, ctx, m.generateCommon.Properties.Tags)
+		inouts := func() []inout { return m.generateInouts(ctx, g) }
+		g.generateArchVariants(sb, &m.generateCommon, ctx, inouts,
+			func(arch androidMkArch) {
+				g.installGeneratedFiles(sb, m, &m.generateCommon, ctx, arch)
+			})
 		androidMkWriteString(ctx, m.altShortName(), sb)
 	}
 }
@@ -831,9 +998,11 @@ func (g *androidMkGenerator) generateSourceActions(m *generateSource, ctx bluepr
 func (g *androidMkGenerator) transformSourceActions(m *transformSource, ctx blueprint.ModuleContext) {
 	if enabledAndRequired(m) {
 		sb := &strings.Builder{}
-		inouts := m.generateInouts(ctx, g)
-		g.generateCommonActions(sb, &m.generateCommon, ctx, inouts)
-		installGeneratedFiles(sb, m, ctx, m.generateCommon.Properties.Tags)
+		inouts := func() []inout { return m.generateInouts(ctx, g) }
+		g.generateArchVariants(sb, &m.generateCommon, ctx, inouts,
+			func(arch androidMkArch) {
+				g.installGeneratedFiles(sb, m, &m.generateCommon, ctx, arch)
+			})
 		androidMkWriteString(ctx, m.altShortName(), sb)
 	}
 }
@@ -841,18 +1010,18 @@ func (g *androidMkGenerator) transformSourceActions(m *transformSource, ctx blue
 func (g *androidMkGenerator) genStaticActions(m *generateStaticLibrary, ctx blueprint.ModuleContext) {
 	if enabledAndRequired(m) {
 		sb := &strings.Builder{}
-		inouts := m.generateInouts(ctx, g)
-		g.generateCommonActions(sb, &m.generateCommon, ctx, inouts)
-
-		// Add prebuilt outputs
-		outputs := m.outputs()
-		if len(outputs) != 1 {
-			utils.Die("outputs() returned %d objects for bob_generate_static_lib %s", len(outputs), m.Name())
-		}
-		library := outputs[0]
-		declarePrebuiltStaticLib(sb, m.altShortName(), library,
-			strings.Join(m.genIncludeDirs(), " "),
-			m.generateCommon.Properties.Target != tgtTypeHost)
+		gc := &m.generateCommon
+		inouts := func() []inout { return m.generateInouts(ctx, g) }
+		g.generateArchVariants(sb, gc, ctx, inouts,
+			func(arch androidMkArch) {
+				outputs := m.outputs()
+				if len(outputs) != 1 {
+					utils.Die("outputs() returned %d objects for %s %s", len(outputs), ctx.ModuleType(), m.Name())
+				}
+				declarePrebuiltStaticLib(sb, m.altShortName(), g.archPath(gc, arch, outputs[0]),
+					strings.Join(g.archPaths(gc, arch, m.genIncludeDirs()), " "), arch.varPrefix,
+					gc.Properties.Target != tgtTypeHost)
+			})
 
 		androidMkWriteString(ctx, m.altShortName(), sb)
 	}
@@ -861,18 +1030,18 @@ func (g *androidMkGenerator) genStaticActions(m *generateStaticLibrary, ctx blue
 func (g *androidMkGenerator) genSharedActions(m *generateSharedLibrary, ctx blueprint.ModuleContext) {
 	if enabledAndRequired(m) {
 		sb := &strings.Builder{}
-		inouts := m.generateInouts(ctx, g)
-		g.generateCommonActions(sb, &m.generateCommon, ctx, inouts)
-
-		// Add prebuilt outputs
-		outputs := m.outputs()
-		if len(outputs) != 1 {
-			utils.Die("outputs() returned %d objects for bob_generate_shared_lib %s", len(outputs), m.Name())
-		}
-		library := outputs[0]
-		declarePrebuiltSharedLib(sb, m.altShortName(), library,
-			strings.Join(m.genIncludeDirs(), " "),
-			m.generateCommon.Properties.Target != tgtTypeHost)
+		gc := &m.generateCommon
+		inouts := func() []inout { return m.generateInouts(ctx, g) }
+		g.generateArchVariants(sb, gc, ctx, inouts,
+			func(arch androidMkArch) {
+				outputs := m.outputs()
+				if len(outputs) != 1 {
+					utils.Die("outputs() returned %d objects for %s %s", len(outputs), ctx.ModuleType(), m.Name())
+				}
+				declarePrebuiltSharedLib(sb, m.altShortName(), g.archPath(gc, arch, outputs[0]),
+					strings.Join(g.archPaths(gc, arch, m.genIncludeDirs()), " "), arch.varPrefix,
+					gc.Properties.Target != tgtTypeHost)
+			})
 
 		androidMkWriteString(ctx, m.altShortName(), sb)
 	}
@@ -924,66 +1093,177 @@ func generatesAndroidIncFile(m blueprint.Module) bool {
 		return false
 	} else if _, ok := m.(*externalLib); ok {
 		return false
+	} else if hl, ok := m.(*headerLibrary); ok {
+		return hl.Properties.TargetType == tgtTypeTarget
 	}
 	return true
 }
 
-func (s *androidMkOrderer) GenerateBuildActions(ctx blueprint.SingletonContext) {
-	sb := &strings.Builder{}
-	var order androidMkFileSlice
-	ctx.VisitAllModules(func(m blueprint.Module) {
-		di, ok := m.(androidNaming)
-		if ok && enabledAndRequired(m) {
-			deps := []string{}
-			ctx.VisitDepsDepthFirst(m, func(child blueprint.Module) {
-				childdi, ok := child.(androidNaming)
-				if ok && generatesAndroidIncFile(child) && enabledAndRequired(m) {
-					deps = append(deps, childdi.altShortName())
-				}
-			})
-			if generatesAndroidIncFile(m) {
-				order = append(order, androidMkFile{di.altShortName(), deps})
-			}
+// androidMkDeps returns the names of the .inc files which must be included
+// before the one for module m. Only direct dependencies are needed to get the
+// ordering right, but modules which don't generate .inc files are looked
+// through, so that their dependencies are still honoured. The results for
+// those modules are memoized in passThrough, which is shared between the
+// workers of the orderer and guarded by androidLock. 'visiting' holds the
+// modules being looked through by this worker, to terminate on cycles.
+func androidMkDeps(ctx blueprint.SingletonContext, m blueprint.Module,
+	passThrough map[blueprint.Module][]string, visiting map[blueprint.Module]bool) (deps []string) {
+
+	ctx.VisitDirectDeps(m, func(child blueprint.Module) {
+		if childdi, ok := child.(androidNaming); ok && generatesAndroidIncFile(child) {
+			deps = utils.AppendIfUnique(deps, childdi.altShortName())
+			return
+		}
+		if visiting[child] {
+			return
 		}
+
+		androidLock.Lock()
+		childDeps, ok := passThrough[child]
+		androidLock.Unlock()
+		if !ok {
+			// Another worker may look through the same module at
+			// the same time. Both get the same result.
+			visiting[child] = true
+			childDeps = androidMkDeps(ctx, child, passThrough, visiting)
+			delete(visiting, child)
+
+			androidLock.Lock()
+			passThrough[child] = childDeps
+			androidLock.Unlock()
+		}
+		deps = utils.AppendUnique(deps, childDeps)
 	})
 
-	// Quick and dirty alphabetical topological sort
-	for len(order) > 0 {
-		lowindex := -1
-		for i, val := range order {
-			if (lowindex == -1 || val.Name < order[lowindex].Name) && len(val.Deps) == 0 {
-				lowindex = i
+	return
+}
+
+// androidMkFileHeap is a min-heap of indices into an androidMkFileSlice,
+// ordered by name, and then position in the slice.
+type androidMkFileHeap struct {
+	files   androidMkFileSlice
+	indices []int
+}
+
+func (h *androidMkFileHeap) Len() int { return len(h.indices) }
+func (h *androidMkFileHeap) Less(i, j int) bool {
+	a, b := h.indices[i], h.indices[j]
+	if h.files[a].Name != h.files[b].Name {
+		return h.files[a].Name < h.files[b].Name
+	}
+	return a < b
+}
+func (h *androidMkFileHeap) Swap(i, j int)      { h.indices[i], h.indices[j] = h.indices[j], h.indices[i] }
+func (h *androidMkFileHeap) Push(x interface{}) { h.indices = append(h.indices, x.(int)) }
+func (h *androidMkFileHeap) Pop() interface{} {
+	last := h.indices[len(h.indices)-1]
+	h.indices = h.indices[:len(h.indices)-1]
+	return last
+}
+
+// Sort the .inc files so that each one is included after those it
+// depends on. Where there is a choice, the alphabetically lowest
+// name is output first, keeping the output stable.
+func sortAndroidMkFiles(order androidMkFileSlice) ([]string, error) {
+	// Number of unmet dependencies for each file, and which files
+	// depend on each name
+	remaining := make([]int, len(order))
+	dependents := map[string][]int{}
+
+	ready := &androidMkFileHeap{files: order}
+	for i, f := range order {
+		remaining[i] = len(f.Deps)
+		for _, dep := range f.Deps {
+			dependents[dep] = append(dependents[dep], i)
+		}
+		if remaining[i] == 0 {
+			ready.indices = append(ready.indices, i)
+		}
+	}
+	heap.Init(ready)
+
+	names := []string{}
+	done := make([]bool, len(order))
+	seen := map[string]bool{}
+	for ready.Len() > 0 {
+		i := heap.Pop(ready).(int)
+		done[i] = true
+		name := order[i].Name
+		names = append(names, name)
+
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		for _, d := range dependents[name] {
+			remaining[d]--
+			if remaining[d] == 0 {
+				heap.Push(ready, d)
 			}
 		}
-		if lowindex == -1 {
+	}
 
-			/* Generate a list of remaining modules and their dependencies */
-			deps := ""
-			for _, o := range order {
-				deps += fmt.Sprintf("%s depends on\n", o.Name)
-				for _, d := range o.Deps {
+	if len(names) < len(order) {
+		/* Generate a list of remaining modules and their dependencies */
+		deps := ""
+		for i, o := range order {
+			if done[i] {
+				continue
+			}
+			deps += fmt.Sprintf("%s depends on\n", o.Name)
+			for _, d := range o.Deps {
+				if !seen[d] {
 					deps += fmt.Sprintf("\t%s\n", d)
 				}
 			}
+		}
 
-			utils.Die("unmet or circular dependency. %d remaining.\n%s", len(order), deps)
+		graph := map[string][]string{}
+		for i, o := range order {
+			if !done[i] {
+				graph[o.Name] = append(graph[o.Name], o.Deps...)
+			}
+		}
+		for _, cycle := range dependencyCycles(graph) {
+			deps += fmt.Sprintf("cycle: %s\n", strings.Join(cycle, " -> "))
 		}
 
-		sb.WriteString("include $(BOB_ANDROIDMK_DIR)/" + order[lowindex].Name + ".inc\n")
+		return nil, fmt.Errorf("unmet or circular dependency. %d remaining.\n%s",
+			len(order)-len(names), deps)
+	}
 
-		for i := range order {
-			newdeps := []string{}
-			for _, dep := range order[i].Deps {
-				if dep != order[lowindex].Name {
-					newdeps = append(newdeps, dep)
-				}
-			}
-			order[i].Deps = newdeps
+	return names, nil
+}
+
+func (s *androidMkOrderer) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	sb := &strings.Builder{}
+	var modules []blueprint.Module
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if _, ok := m.(androidNaming); ok && enabledAndRequired(m) && generatesAndroidIncFile(m) {
+			modules = append(modules, m)
 		}
-		order = append(order[:lowindex], order[lowindex+1:]...)
+	})
+
+	// Looking through the dependencies of each module is independent,
+	// so is spread over a pool of workers. Each writes its own entry.
+	order := make(androidMkFileSlice, len(modules))
+	passThrough := map[blueprint.Module][]string{}
+	utils.ParallelFor(len(modules), func(i int) {
+		m := modules[i]
+		deps := androidMkDeps(ctx, m, passThrough, map[blueprint.Module]bool{})
+		order[i] = androidMkFile{m.(androidNaming).altShortName(), deps}
+	})
+
+	names, err := sortAndroidMkFiles(order)
+	if err != nil {
+		utils.Die("%v", err)
 	}
+	for _, name := range names {
+		sb.WriteString("include $(BOB_ANDROIDMK_DIR)/" + name + ".inc\n")
+	}
+
 	androidmkFile := getPathInBuildDir("Android.inc")
-	err := fileutils.WriteIfChanged(androidmkFile, sb)
+	err = getBackendOutput(getConfig(ctx)).writeFile(androidmkFile, sb)
 	if err != nil {
 		utils.Die("%v", err.Error())
 	}
@@ -1088,10 +1368,7 @@ func (g *androidMkGenerator) kernelModuleActions(m *kernelModule, ctx blueprint.
 		sb.WriteString("LOCAL_UNINSTALLABLE_MODULE := true\n")
 	}
 	sb.WriteString("LOCAL_MODULE_SUFFIX := .ko\n")
-	if m.Properties.isProprietary() {
-		sb.WriteString("LOCAL_MODULE_OWNER := " + proptools.String(m.Properties.Owner) + "\n")
-		sb.WriteString("LOCAL_PROPRIETARY_MODULE := true\n")
-	}
+	writeAndroidMkPartition(sb, ctx, &m.Properties.AndroidProps)
 	sb.WriteString("include $(BUILD_SYSTEM)/base_rules.mk\n\n")
 
 	args := m.generateKbuildArgs(ctx).toDict()
@@ -1179,7 +1456,7 @@ func mapAndroidNames(ctx blueprint.BottomUpMutatorContext) {
 func (g *androidMkGenerator) init(ctx *blueprint.Context, config *bobConfig) {
 	ctx.RegisterBottomUpMutator("modulemapper", mapAndroidNames).Parallel()
 
-	ctx.RegisterSingletonType("androidmk_orderer", androidMkOrdererFactory)
+	registerSingletonType(ctx, "androidmk_orderer", androidMkOrdererFactory)
 
 	g.toolchainSet.parseConfig(config)
 }
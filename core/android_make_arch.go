@@ -0,0 +1,201 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// androidMkArch describes one of the architectures a generator is run
+// for on Android make.
+type androidMkArch struct {
+	// Value of ${arch}
+	name string
+	// Value of LOCAL_2ND_ARCH_VAR_PREFIX
+	varPrefix string
+	// Suffix distinguishing the make variables of this architecture
+	varSuffix string
+}
+
+var (
+	androidMkHostArch      = androidMkArch{"$(HOST_ARCH)", "", ""}
+	androidMkPrimaryArch   = androidMkArch{"$(TARGET_ARCH)", "", ""}
+	androidMkSecondaryArch = androidMkArch{"$(TARGET_2ND_ARCH)", "$(TARGET_2ND_ARCH_VAR_PREFIX)", "_2ND"}
+
+	// The architectures of multilib device modules
+	androidMkDeviceArches = []androidMkArch{androidMkPrimaryArch, androidMkSecondaryArch}
+)
+
+// isArchVariant reports whether a generator is run once for each
+// device architecture. This is the case for target generators of any
+// type whose command uses ${arch}, so that their outputs can be used
+// by multilib modules.
+//
+// Resources are never arch variants. They only copy files from the
+// source tree, which are the same for every architecture, so they are
+// installed once.
+func isArchVariant(m *generateCommon) bool {
+	return m.Properties.Target != tgtTypeHost && m.usesArch()
+}
+
+// androidMkArchVariants returns the architectures a generator is run for
+func androidMkArchVariants(m *generateCommon) []androidMkArch {
+	if m.Properties.Target == tgtTypeHost {
+		return []androidMkArch{androidMkHostArch}
+	} else if isArchVariant(m) {
+		return androidMkDeviceArches
+	}
+	return []androidMkArch{androidMkPrimaryArch}
+}
+
+// archOutputDir returns the directory holding the outputs of a
+// generator for one architecture. Each architecture of an arch variant
+// has its own directory, which ${gen_dir} refers to.
+func (g *androidMkGenerator) archOutputDir(m *generateCommon, arch androidMkArch) string {
+	if !isArchVariant(m) {
+		return g.sourceOutputDir(m)
+	}
+	return filepath.Join(g.sourceOutputDir(m), arch.name)
+}
+
+// archPath moves one of the recorded outputs or include directories of
+// a generator, which are those of the primary architecture, to the
+// output directory of 'arch'.
+func (g *androidMkGenerator) archPath(m *generateCommon, arch androidMkArch, path string) string {
+	primaryDir := g.archOutputDir(m, androidMkPrimaryArch)
+	return g.archOutputDir(m, arch) + strings.TrimPrefix(path, primaryDir)
+}
+
+func (g *androidMkGenerator) archPaths(m *generateCommon, arch androidMkArch, paths []string) []string {
+	ret := []string{}
+	for _, path := range paths {
+		ret = append(ret, g.archPath(m, arch, path))
+	}
+	return ret
+}
+
+// writeArchBlock calls 'write' for each architecture in 'arches'. The
+// secondary device architecture is only used on devices that have one.
+func writeArchBlock(sb *strings.Builder, arches []androidMkArch, write func(arch androidMkArch)) {
+	for _, arch := range arches {
+		if arch == androidMkSecondaryArch {
+			sb.WriteString("\nifdef TARGET_2ND_ARCH\n")
+			write(arch)
+			sb.WriteString("endif\n")
+		} else {
+			write(arch)
+		}
+	}
+}
+
+// generateArchVariants writes the rules running a generator for each of
+// its architectures. 'each' is called after the rules of an
+// architecture are written, to declare or install its outputs.
+func (g *androidMkGenerator) generateArchVariants(sb *strings.Builder, m *generateCommon, ctx blueprint.ModuleContext,
+	inouts func() []inout, each func(arch androidMkArch)) {
+
+	writeArchBlock(sb, androidMkArchVariants(m), func(arch androidMkArch) {
+		g.generateArchActions(sb, m, ctx, inouts(), arch, g.archOutputDir(m, arch))
+		each(arch)
+	})
+}
+
+// singleArchGeneratedLibrary returns the name of a generated library,
+// linked by the module, which is only built for the primary device
+// architecture. Modules linking to one cannot be built for both
+// architectures. Returns an empty string when there is none.
+func singleArchGeneratedLibrary(ctx blueprint.ModuleContext) (name string) {
+	ctx.WalkDeps(func(dep, parent blueprint.Module) bool {
+		if name != "" {
+			return false
+		}
+
+		// Only consider dependencies that get linked
+		tag := ctx.OtherModuleDependencyTag(dep)
+		if tag != staticDepTag && tag != sharedDepTag && tag != wholeStaticDepTag {
+			return false
+		}
+
+		var gc *generateCommon
+		if lib, ok := dep.(*generateStaticLibrary); ok {
+			gc = &lib.generateCommon
+		} else if lib, ok := dep.(*generateSharedLibrary); ok {
+			gc = &lib.generateCommon
+		}
+		if gc == nil {
+			// Keep walking this part of the tree
+			return true
+		}
+		if !isArchVariant(gc) {
+			name = dep.Name()
+		}
+		return false
+	})
+
+	return
+}
+
+// archVariantHeaderModules returns the arch variants among the
+// generators whose headers a library uses
+func archVariantHeaderModules(l *library) (ret []blueprint.Module) {
+	for _, m := range l.exported.generatedHeaderModules {
+		if gc, ok := getGenerateCommon(m); ok && isArchVariant(gc) {
+			ret = append(ret, m)
+		}
+	}
+	return
+}
+
+// writeArchHeaderIncludes adds the include directories of headers
+// generated per architecture to the include paths of that
+// architecture, so that each architecture of a multilib module is
+// compiled against its own headers. The headers of the primary
+// architecture are already dependencies of the module.
+func (g *androidMkGenerator) writeArchHeaderIncludes(sb *strings.Builder, variants []blueprint.Module) {
+	if len(variants) == 0 {
+		return
+	}
+	writeArchBlock(sb, androidMkDeviceArches, func(arch androidMkArch) {
+		dirs := []string{}
+		headers := []string{}
+		for _, m := range variants {
+			gc, _ := getGenerateCommon(m)
+			dirs = append(dirs, g.archPaths(gc, arch, gc.genIncludeDirs())...)
+			headers = append(headers, g.archPaths(gc, arch, getHeadersGenerated(m.(dependentInterface)))...)
+		}
+		writeListAssignment(sb, "LOCAL_C_INCLUDES_"+arch.name, dirs)
+		if arch == androidMkSecondaryArch {
+			sb.WriteString("LOCAL_ADDITIONAL_DEPENDENCIES += " + strings.Join(headers, " ") + "\n")
+		}
+	})
+}
+
+// generatedModule returns the generator of the named dependency
+func generatedModule(ctx blueprint.ModuleContext, name string) *generateCommon {
+	dep, _ := ctx.GetDirectDep(name)
+	gc, ok := getGenerateCommon(dep)
+	if !ok {
+		utils.Die("%s is not a generated module", name)
+	}
+	return gc
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_androidMkArchVariants(t *testing.T) {
+	g := &androidMkGenerator{}
+	m := &generateCommon{}
+	m.SimpleName.Properties.Name = "gen_blob"
+	m.Properties.Target = tgtTypeTarget
+	m.Properties.Cmd = proptools.StringPtr("${tool} -o ${out} ${in}")
+
+	assert.Equal(t, []androidMkArch{androidMkPrimaryArch}, androidMkArchVariants(m))
+	assert.Equal(t, "$(TARGET_OUT_GEN)/STATIC_LIBRARIES/gen_blob",
+		g.archOutputDir(m, androidMkPrimaryArch))
+
+	m.Properties.Cmd = proptools.StringPtr("${tool} ${args} -o ${out} ${in}")
+	m.Properties.Args = []string{"--arch", "${arch}"}
+	assert.Equal(t, androidMkDeviceArches, androidMkArchVariants(m))
+
+	out := "$(TARGET_OUT_GEN)/STATIC_LIBRARIES/gen_blob/$(TARGET_ARCH)/blob.c"
+	assert.Equal(t, out, g.archPath(m, androidMkPrimaryArch, out))
+	assert.Equal(t, "$(TARGET_OUT_GEN)/STATIC_LIBRARIES/gen_blob/$(TARGET_2ND_ARCH)/blob.c",
+		g.archPath(m, androidMkSecondaryArch, out))
+
+	m.Properties.Target = tgtTypeHost
+	assert.Equal(t, []androidMkArch{androidMkHostArch}, androidMkArchVariants(m))
+}
+
+func Test_writeArchBlock(t *testing.T) {
+	sb := &strings.Builder{}
+	writeArchBlock(sb, androidMkDeviceArches, func(arch androidMkArch) {
+		sb.WriteString("SRCS_" + arch.name + " := blob.c\n")
+	})
+	assert.Equal(t, "SRCS_$(TARGET_ARCH) := blob.c\n"+
+		"\nifdef TARGET_2ND_ARCH\n"+
+		"SRCS_$(TARGET_2ND_ARCH) := blob.c\n"+
+		"endif\n", sb.String())
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sortAndroidMkFiles_orders_dependencies_first(t *testing.T) {
+	order := androidMkFileSlice{
+		{"libc", []string{"liba", "libb"}},
+		{"liba", []string{}},
+		{"bin", []string{"libc", "libd"}},
+		{"libd", []string{"liba"}},
+		{"libb", []string{}},
+	}
+
+	names, err := sortAndroidMkFiles(order)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"liba", "libb", "libc", "libd", "bin"}, names)
+}
+
+func Test_sortAndroidMkFiles_reports_cycles(t *testing.T) {
+	order := androidMkFileSlice{
+		{"liba", []string{"libb"}},
+		{"libb", []string{"liba"}},
+		{"libc", []string{}},
+	}
+
+	names, err := sortAndroidMkFiles(order)
+
+	assert.Nil(t, names)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "2 remaining")
+	assert.Contains(t, err.Error(), "liba depends on\n\tlibb\n")
+	assert.Contains(t, err.Error(), "cycle: liba -> libb -> liba\n")
+}
+
+func Test_sortAndroidMkFiles_reports_unmet_dependencies(t *testing.T) {
+	order := androidMkFileSlice{
+		{"liba", []string{"libmissing"}},
+	}
+
+	_, err := sortAndroidMkFiles(order)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "1 remaining")
+}
+
+func Test_androidMkCompilerWrapper(t *testing.T) {
+	assert.Equal(t,
+		"LOCAL_CC = ccache $(CLANG)\n"+
+			"LOCAL_CXX = ccache $(CLANG_CXX)\n",
+		androidMkCompilerWrapper("ccache", true))
+
+	assert.Equal(t,
+		"LOCAL_CC = $(LOCAL_PATH)/wrap.py $($(LOCAL_2ND_ARCH_VAR_PREFIX)$(my_prefix)CC)\n"+
+			"LOCAL_CXX = $(LOCAL_PATH)/wrap.py $($(LOCAL_2ND_ARCH_VAR_PREFIX)$(my_prefix)CXX)\n",
+		androidMkCompilerWrapper("$(LOCAL_PATH)/wrap.py", false))
+}
+
+func Test_androidMkAuxVariables(t *testing.T) {
+	props := AndroidAuxProps{}
+	assert.False(t, props.isAux())
+	assert.Equal(t, "", props.androidMkAuxVariables())
+
+	props.Aux.Os = proptools.StringPtr("freertos")
+	props.Aux.Arch = proptools.StringPtr("arm")
+	props.Aux.Cpu = proptools.StringPtr("cortex-m4")
+	assert.True(t, props.isAux())
+	assert.Equal(t, "LOCAL_AUX_OS:=freertos\nLOCAL_AUX_ARCH:=arm\nLOCAL_AUX_CPU:=cortex-m4\n",
+		props.androidMkAuxVariables())
+}
@@ -21,7 +21,6 @@ import (
 	"bufio"
 	"crypto/sha1"
 	"encoding/hex"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -32,7 +31,6 @@ import (
 	"github.com/google/blueprint/proptools"
 
 	"github.com/ARM-software/bob-build/internal/bpwriter"
-	"github.com/ARM-software/bob-build/internal/fileutils"
 	"github.com/ARM-software/bob-build/internal/utils"
 )
 
@@ -55,6 +53,24 @@ func AndroidBpFile() bpwriter.File {
 
 func (g *androidBpGenerator) aliasActions(*alias, blueprint.ModuleContext) {}
 
+func (g *androidBpGenerator) pythonBinaryActions(m *pythonBinary, ctx blueprint.ModuleContext) {
+	if enabledAndRequired(m) {
+		ctx.ModuleErrorf("bob_python_binary is not supported by the Android.bp backend")
+	}
+}
+
+func (g *androidBpGenerator) goBinaryActions(m *goBinary, ctx blueprint.ModuleContext) {
+	if enabledAndRequired(m) {
+		ctx.ModuleErrorf("bob_go_binary is not supported by the Android.bp backend")
+	}
+}
+
+func (g *androidBpGenerator) deviceTreeActions(m *deviceTree, ctx blueprint.ModuleContext) {
+	if enabledAndRequired(m) {
+		ctx.ModuleErrorf("%s is not supported by the Android.bp backend", ctx.ModuleType())
+	}
+}
+
 func (g *androidBpGenerator) buildDir() string {
 	// The androidbp backend writes an Android.bp file, which should
 	// never reference an actual output directory (which will be
@@ -96,12 +112,21 @@ func (g *androidBpGenerator) escapeFlag(s string) string {
 	return s
 }
 
-func addProvenanceProps(m bpwriter.Module, props AndroidProps) {
+func addProvenanceProps(mctx blueprint.BaseModuleContext, m bpwriter.Module, props AndroidProps) {
 	if props.isProprietary() {
 		m.AddString("owner", proptools.String(props.Owner))
+	}
+
+	partition := androidPartition(mctx, &props)
+	if partition == "" {
+		return
+	}
+	if props.Partition == nil {
 		m.AddBool("vendor", true)
 		m.AddBool("proprietary", true)
 		m.AddBool("soc_specific", true)
+	} else {
+		m.AddBool(androidPartitions[partition].soong, true)
 	}
 }
 
@@ -265,10 +290,9 @@ func (cm *codeMatcher) match() bool {
 	if err == nil {
 		matched = strings.Contains(string(contents), cm.text)
 	} else {
-		fmt.Fprintf(os.Stderr,
-			"WARNING: Could not open file %s to determine Soong "+
-				"compatibility layer: %s\n"+
-				"Compilation of Bob plugins may fail!\n",
+		utils.Warnf("", "Could not open file %s to determine Soong "+
+			"compatibility layer: %s\n"+
+			"Compilation of Bob plugins may fail!",
 			err.Error(), cm.filename)
 	}
 
@@ -334,9 +358,9 @@ func getSoongCompatFile(config *bobConfig) string {
 	if len(soongCompats) == 1 {
 		return soongCompats[0].src
 	} else if len(soongCompats) == 0 {
-		fmt.Fprintf(os.Stderr, "WARNING: No available Soong compatibility "+
+		utils.Warnf("", "No available Soong compatibility "+
 			"layers found for ANDROID_PLATFORM_VERSION = %d.\n"+
-			"Attempting text-based detection.\n",
+			"Attempting text-based detection.",
 			android_platform_version)
 		soongCompats = allSoongCompats
 	}
@@ -359,10 +383,10 @@ func getSoongCompatFile(config *bobConfig) string {
 		}
 	}
 
-	fmt.Fprintf(os.Stderr, "WARNING: Could not find an appropriate Soong "+
+	utils.Warnf("", "Could not find an appropriate Soong "+
 		"compatibility layer based on code in build/soong.\n"+
 		"WARNING: Falling back to default for this Android version. "+
-		"Compilation of Bob plugins may fail!\n")
+		"Compilation of Bob plugins may fail!")
 	return soongCompats[len(soongCompats)-1].src
 }
 
@@ -399,7 +423,7 @@ func (s *androidBpSingleton) GenerateBuildActions(ctx blueprint.SingletonContext
 	AndroidBpFile().Render(sb)
 
 	androidbpFile := getPathInSourceDir("Android.bp")
-	err = fileutils.WriteIfChanged(androidbpFile, sb)
+	err = getBackendOutput(getConfig(ctx)).writeFile(androidbpFile, sb)
 	if err != nil {
 		utils.Die("%v", err.Error())
 	}
@@ -427,7 +451,7 @@ func (g *androidBpGenerator) init(ctx *blueprint.Context, config *bobConfig) {
 	// Do not run in parallel to avoid locking issues on the map
 	ctx.RegisterBottomUpMutator("collect_buildbp", collectBuildBpFilesMutator)
 
-	ctx.RegisterSingletonType("androidbp_singleton", androidBpSingletonFactory)
+	registerSingletonType(ctx, "androidbp_singleton", androidBpSingletonFactory)
 
 	g.toolchainSet.parseConfig(config)
 }
@@ -21,6 +21,9 @@ package core
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
@@ -67,6 +70,10 @@ func bpModuleNamesForDep(mctx blueprint.BaseModuleContext, name string) []string
 		return []string{l.shortName()}
 	}
 
+	if hl, ok := dep.(*headerLibrary); ok {
+		return []string{hl.shortName()}
+	}
+
 	// Most cases should match the getLibrary() check above, but generated libraries,
 	// etc, do not, and they also do not require using shortName() (because of not
 	// being target-specific), so just use the original build.bp name.
@@ -167,34 +174,105 @@ func addMTEProps(m bpwriter.Module, props AndroidMTEProps) {
 	}
 }
 
-func addRequiredModules(m bpwriter.Module, l library, mctx blueprint.ModuleContext) {
-	if _, _, ok := getSoongInstallPath(l.getInstallableProps()); ok {
-		requiredModuleNames := l.getInstallDepPhonyNames(mctx)
-		m.AddStringList("required", bpModuleNamesForDeps(mctx, requiredModuleNames))
+// The values Soong accepts for stl. The libc++ variants are the
+// platform's own STL, which is not in the NDK.
+var (
+	ndkStls      = []string{"c++_shared", "c++_static", "none", "system"}
+	platformStls = []string{"libc++", "libc++_static"}
+)
+
+var apexNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)*(\.\*)?$`)
+
+// isAPILevel returns whether a value names an API level, as Soong
+// expects for sdk_version and min_sdk_version
+func isAPILevel(value string, extra ...string) bool {
+	if value == "current" || utils.Contains(extra, value) {
+		return true
 	}
+	level, err := strconv.Atoi(value)
+	return err == nil && level > 0
 }
 
-func addCFlags(m bpwriter.Module, cflags []string, conlyFlags []string, cxxFlags []string) error {
-	if std := ccflags.GetCompilerStandard(cflags, conlyFlags); std != "" {
-		m.AddString("c_std", std)
+// checkAndroidSdkProps validates the SDK, STL and APEX properties
+// before they are passed to Soong, which would otherwise fail with an
+// error about the generated module rather than the Bob module.
+func checkAndroidSdkProps(props *AndroidSdkProps, tgt tgtType) error {
+	if tgt == tgtTypeHost {
+		prop := ""
+		if props.Sdk_version != nil {
+			prop = "sdk_version"
+		} else if props.Min_sdk_version != nil {
+			prop = "min_sdk_version"
+		} else if len(props.Apex_available) > 0 {
+			prop = "apex_available"
+		}
+		if prop != "" {
+			return fmt.Errorf("%s is only supported for target modules, "+
+				"set it in the target: block", prop)
+		}
 	}
 
-	if std := ccflags.GetCompilerStandard(cflags, cxxFlags); std != "" {
-		m.AddString("cpp_std", std)
+	if props.Sdk_version != nil && !isAPILevel(*props.Sdk_version) {
+		return fmt.Errorf("sdk_version must be an API level or current, not %s",
+			*props.Sdk_version)
+	}
+	if props.Min_sdk_version != nil && !isAPILevel(*props.Min_sdk_version, "apex_inherit") {
+		return fmt.Errorf("min_sdk_version must be an API level, current or apex_inherit, not %s",
+			*props.Min_sdk_version)
 	}
 
-	armMode, err := ccflags.GetArmMode(cflags, conlyFlags, cxxFlags)
-	if err != nil {
-		return err
+	if props.Stl != nil {
+		stl := *props.Stl
+		if !utils.Contains(ndkStls, stl) && !utils.Contains(platformStls, stl) {
+			return fmt.Errorf("stl must be one of %s, not %s",
+				strings.Join(append(append([]string{}, ndkStls...), platformStls...), ", "), stl)
+		}
+		if props.Sdk_version != nil && utils.Contains(platformStls, stl) {
+			return fmt.Errorf("stl %s is not in the NDK, so can't be used with sdk_version, "+
+				"use one of %s", stl, strings.Join(ndkStls, ", "))
+		}
+	}
+
+	for _, apex := range props.Apex_available {
+		if strings.HasPrefix(apex, "//apex_available:") {
+			if apex != "//apex_available:platform" && apex != "//apex_available:anyapex" {
+				return fmt.Errorf("apex_available entry %s must be "+
+					"//apex_available:platform or //apex_available:anyapex", apex)
+			}
+		} else if !apexNameRegexp.MatchString(apex) {
+			return fmt.Errorf("apex_available entry %s is not an APEX name", apex)
+		}
 	}
+	return nil
+}
 
-	if armMode != "" {
-		m.AddString("instruction_set", armMode)
+func addSdkProps(m bpwriter.Module, props AndroidSdkProps) {
+	if props.Sdk_version != nil {
+		m.AddString("sdk_version", *props.Sdk_version)
 	}
+	if props.Stl != nil {
+		m.AddString("stl", *props.Stl)
+	}
+	m.AddStringList("apex_available", props.Apex_available)
+	if props.Min_sdk_version != nil {
+		m.AddString("min_sdk_version", *props.Min_sdk_version)
+	}
+}
 
-	m.AddStringList("cflags", utils.Filter(ccflags.AndroidCompileFlags, cflags))
-	m.AddStringList("conlyflags", utils.Filter(ccflags.AndroidCompileFlags, conlyFlags))
-	m.AddStringList("cppflags", utils.Filter(ccflags.AndroidCompileFlags, cxxFlags))
+func addRequiredModules(m bpwriter.Module, l library, mctx blueprint.ModuleContext) {
+	if _, _, ok := getSoongInstallPath(l.getInstallableProps()); ok {
+		requiredModuleNames := l.getInstallDepPhonyNames(mctx)
+		m.AddStringList("required", bpModuleNamesForDeps(mctx, requiredModuleNames))
+	}
+}
+
+func addCFlags(m bpwriter.Module, audit *ccflags.Audit,
+	cflags []string, conlyFlags []string, cxxFlags []string) error {
+	flags, err := newAndroidCompileFlags(audit, cflags, conlyFlags, cxxFlags)
+	if err != nil {
+		return err
+	}
+	flags.androidBp(m)
 	return nil
 }
 
@@ -216,27 +294,11 @@ func addCcLibraryProps(m bpwriter.Module, l library, mctx blueprint.ModuleContex
 	// modules, but it doesn't export cflags.
 	_, _, exported_cflags := l.GetExportedVariables(mctx)
 
-	cflags := utils.NewStringSlice(l.Properties.Cflags, l.Properties.Export_cflags, exported_cflags)
-
-	sharedLibs := bpModuleNamesForDeps(mctx, l.Properties.Shared_libs)
-	staticLibs := bpModuleNamesForDeps(mctx, l.Properties.ResolvedStaticLibs)
-	// Exported header libraries must be mentioned in both header_libs
-	// *and* export_header_lib_headers - i.e., we can't export a header
-	// library which isn't actually being used.
-	headerLibs := bpModuleNamesForDeps(mctx, l.Properties.Header_libs, l.Properties.Export_header_libs)
-
-	reexportShared := []string{}
-	reexportStatic := []string{}
-	reexportHeaders := bpModuleNamesForDeps(mctx, l.Properties.Export_header_libs)
-	for _, lib := range bpModuleNamesForDeps(mctx, l.Properties.Reexport_libs) {
-		if utils.Contains(sharedLibs, lib) {
-			reexportShared = append(reexportShared, lib)
-		} else if utils.Contains(staticLibs, lib) {
-			reexportStatic = append(reexportStatic, lib)
-		} else if utils.Contains(headerLibs, lib) {
-			reexportHeaders = append(reexportHeaders, lib)
-		}
-	}
+	cflags := utils.MergeFlags(l.Properties.Cflags, l.Properties.Export_cflags, exported_cflags)
+
+	libs := newAndroidLibs(&l.Properties.Build.BuildProps,
+		func(names []string) []string { return bpModuleNamesForDeps(mctx, names) },
+		l.Properties.Header_libs, l.Properties.Export_header_libs)
 
 	if l.shortName() != l.outputName() {
 		m.AddString("stem", l.outputName())
@@ -247,36 +309,62 @@ func addCcLibraryProps(m bpwriter.Module, l library, mctx blueprint.ModuleContex
 	m.AddStringList("generated_headers", append(genHeaderModules, exportGenHeaderModules...))
 	m.AddStringList("export_generated_headers", exportGenHeaderModules)
 	m.AddStringList("exclude_srcs", l.Properties.Exclude_srcs)
-	err := addCFlags(m, cflags, l.Properties.Conlyflags, l.Properties.Cxxflags)
+	audit := getFlagAudit(mctx)
+	err := addCFlags(m, audit, cflags, l.Properties.Conlyflags, l.Properties.getCxxflags())
 	if err != nil {
 		utils.Die("Module %s: %s", mctx.ModuleName(), err.Error())
 	}
-	m.AddStringList("include_dirs", l.Properties.Include_dirs)
-	m.AddStringList("local_include_dirs", l.Properties.Local_include_dirs)
-	m.AddStringList("shared_libs", bpModuleNamesForDeps(mctx, l.Properties.Shared_libs))
-	m.AddStringList("static_libs", staticLibs)
-	m.AddStringList("whole_static_libs", bpModuleNamesForDeps(mctx, l.Properties.Whole_static_libs))
-	m.AddStringList("header_libs", headerLibs)
-	m.AddStringList("export_shared_lib_headers", reexportShared)
-	m.AddStringList("export_static_lib_headers", reexportStatic)
-	m.AddStringList("export_header_lib_headers", reexportHeaders)
-	m.AddStringList("ldflags", utils.Filter(ccflags.AndroidLinkFlags, l.Properties.Ldflags))
+	// Soong doesn't know about the include directories of
+	// bob_external_library modules, so add them here.
+	m.AddStringList("include_dirs",
+		utils.NewStringSlice(l.Properties.Include_dirs, l.exported.externalIncludeDirs))
+	m.AddStringList("local_include_dirs",
+		utils.NewStringSlice(l.Properties.Local_include_dirs, l.exported.externalLocalIncludeDirs))
+	libs.androidBp(m)
+	tc := getBackend(mctx).getToolchain(l.Properties.TargetType)
+	m.AddStringList("ldflags", utils.NewStringSlice(
+		audit.Filter("ldflags", ccflags.AndroidLinkFlagDropReason, l.Properties.Ldflags),
+		l.gcSectionsLdflags(tc)))
 
 	_, installRel, ok := getSoongInstallPath(l.getInstallableProps())
 	if ok && installRel != "" {
 		m.AddString("relative_install_path", installRel)
 	}
 
-	addProvenanceProps(m, l.Properties.Build.AndroidProps)
+	addProvenanceProps(mctx, m, l.Properties.Build.AndroidProps)
 	addPGOProps(m, l.Properties.Build.AndroidPGOProps)
+	if err := checkAndroidSdkProps(&l.Properties.Build.AndroidSdkProps, l.Properties.TargetType); err != nil {
+		utils.Die("Module %s: %s", mctx.ModuleName(), err.Error())
+	}
+	addSdkProps(m, l.Properties.Build.AndroidSdkProps)
 	addRequiredModules(m, l, mctx)
 
+	if l.Properties.Build_wrapper != nil {
+		utils.Die("Module %s has a build_wrapper - this is not supported on Android.bp, "+
+			"as Soong runs the compiler itself. Unset it in a builder_android_bp: block",
+			mctx.ModuleName())
+	}
+	if len(l.Properties.Env) > 0 {
+		utils.Die("Module %s sets env - this is not supported on Android.bp libraries, "+
+			"as Soong runs the compiler itself. Unset it in a builder_android_bp: block",
+			mctx.ModuleName())
+	}
+
 	if l.Properties.Post_install_cmd != nil ||
 		l.Properties.Post_install_args != nil ||
-		l.Properties.Post_install_tool != nil {
+		l.Properties.Post_install_tool != nil ||
+		l.Properties.Post_install_outs != nil {
 		utils.Die("Module %s has post install actions - this is not supported on Android.bp",
 			mctx.ModuleName())
 	}
+	if l.Properties.Post_build_cmd != nil {
+		utils.Die("Module %s has post build actions - this is not supported on Android.bp",
+			mctx.ModuleName())
+	}
+	if len(l.Properties.Objcopy_formats) > 0 {
+		utils.Die("Module %s has objcopy_formats - this is not supported on Android.bp",
+			mctx.ModuleName())
+	}
 }
 
 func addBinaryProps(m bpwriter.Module, l binary, mctx blueprint.ModuleContext) {
@@ -287,7 +375,7 @@ func addBinaryProps(m bpwriter.Module, l binary, mctx blueprint.ModuleContext) {
 		// binaries in both modes.
 		// We disable multilib if this module depends on generated libraries
 		// (which can't support multilib).
-		if l.Properties.TargetType == tgtTypeTarget && !linksToGeneratedLibrary(mctx) {
+		if l.Properties.TargetType == tgtTypeTarget && singleArchGeneratedLibrary(mctx) == "" {
 			m.AddString("compile_multilib", "both")
 
 			// For executables we need to be clear about where to
@@ -299,6 +387,10 @@ func addBinaryProps(m bpwriter.Module, l binary, mctx blueprint.ModuleContext) {
 		}
 	}
 
+	if proptools.Bool(l.Properties.Static_executable) {
+		m.AddBool("static_executable", true)
+	}
+
 	addMTEProps(m, l.Properties.Build.AndroidMTEProps)
 }
 
@@ -313,7 +405,7 @@ func addStaticOrSharedLibraryProps(m bpwriter.Module, l library, mctx blueprint.
 	// This part handles the target libraries.
 	// We disable multilib if this module depends on generated libraries
 	// (which can't support multilib).
-	if l.Properties.TargetType == tgtTypeTarget && !linksToGeneratedLibrary(mctx) {
+	if l.Properties.TargetType == tgtTypeTarget && singleArchGeneratedLibrary(mctx) == "" {
 		m.AddString("compile_multilib", "both")
 	}
 }
@@ -418,12 +510,22 @@ func (g *androidBpGenerator) sharedActions(l *sharedLibrary, mctx blueprint.Modu
 	if versionScript != nil {
 		m.AddString("version_script", *versionScript)
 	}
+
+	if l.hasStubs() {
+		stubs := m.NewGroup("stubs")
+		stubs.AddString("symbol_file", *l.Properties.Stubs.Symbol_file)
+		stubs.AddStringList("versions", l.Properties.Stubs.Versions)
+	}
 }
 
 func (g *androidBpGenerator) staticActions(l *staticLibrary, mctx blueprint.ModuleContext) {
 	if !enabledAndRequired(l) {
 		return
 	}
+	if l.relocatable {
+		mctx.ModuleErrorf("bob_relocatable_object is not supported by the Android.bp backend")
+		return
+	}
 
 	// Calculate and record outputs
 	l.outs = []string{l.outputName()}
@@ -444,3 +546,27 @@ func (g *androidBpGenerator) staticActions(l *staticLibrary, mctx blueprint.Modu
 	addCcLibraryProps(m, l.library, mctx)
 	addStaticOrSharedLibraryProps(m, l.library, mctx)
 }
+
+func (g *androidBpGenerator) headerLibraryActions(m *headerLibrary, mctx blueprint.ModuleContext) {
+	if !enabledAndRequired(m) {
+		return
+	}
+
+	if len(m.Properties.Export_include_dirs) > 0 {
+		utils.Die("Module %s exports non-local include dirs %v - this is not supported",
+			mctx.ModuleName(), m.Properties.Export_include_dirs)
+	}
+
+	bpmod, err := AndroidBpFile().NewModule("cc_library_headers", m.shortName())
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Soong doesn't export cflags, so they are added to the flags of
+	// each user of the library.
+	bpmod.AddStringList("export_include_dirs", m.Properties.Export_local_include_dirs)
+	if m.Properties.TargetType == tgtTypeHost {
+		bpmod.AddBool("host_supported", true)
+		bpmod.AddBool("device_supported", false)
+	}
+}
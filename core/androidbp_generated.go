@@ -86,12 +86,15 @@ func populateCommonProps(gc *generateCommon, mctx blueprint.ModuleContext, m bpw
 	// Replace ${args} immediately
 	cmd := strings.Replace(proptools.String(gc.Properties.Cmd), "${args}",
 		strings.Join(gc.Properties.Args, " "), -1)
-	cmd = expandCmd(gc, cmd, mctx.ModuleDir())
+	cmd = gc.Properties.envExportPrefix() + expandCmd(gc, cmd, mctx.ModuleDir())
 	m.AddString("cmd", cmd)
 
 	if gc.Properties.Tool != nil {
 		m.AddString("tool", *gc.Properties.Tool)
 	}
+	if gc.Properties.License_header != nil {
+		mctx.PropertyErrorf("license_header", "is not supported by the Android.bp backend")
+	}
 	if gc.Properties.Rsp_content != nil {
 		m.AddString("rsp_content", *gc.Properties.Rsp_content)
 	}
@@ -159,6 +162,7 @@ func (g *androidBpGenerator) transformSourceActions(ts *transformSource, mctx bl
 	gr.AddStringList("replace", ts.Properties.TransformSourceProps.Out.Replace)
 	gr.AddStringList("implicit_srcs", ts.Properties.TransformSourceProps.Out.Implicit_srcs)
 	gr.AddStringList("implicit_outs", ts.Properties.TransformSourceProps.Out.Implicit_outs)
+	gr.AddStringList("file_args", ts.Properties.TransformSourceProps.Out.File_args)
 
 	populateCommonProps(&ts.generateCommon, mctx, m)
 
@@ -72,7 +72,7 @@ func (g *androidBpGenerator) kernelModuleActions(l *kernelModule, mctx blueprint
 		kdir = getPathInSourceDir(kdir)
 	}
 
-	addProvenanceProps(bpmod, l.Properties.AndroidProps)
+	addProvenanceProps(mctx, bpmod, l.Properties.AndroidProps)
 	bpmod.AddStringList("srcs", l.Properties.getSources(mctx))
 	bpmod.AddStringList("generated_deps", generated_deps)
 	bpmod.AddStringList("out", l.outs)
@@ -106,5 +106,6 @@ func (g *androidBpGenerator) kernelModuleActions(l *kernelModule, mctx blueprint
 		l.Properties.Make_args,
 	)
 
-	addInstallProps(bpmod, l.getInstallableProps(), l.Properties.isProprietary())
+	addInstallProps(bpmod, l.getInstallableProps(),
+		androidPartition(mctx, &l.Properties.AndroidProps) == "vendor")
 }
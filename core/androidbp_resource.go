@@ -77,7 +77,7 @@ func (g *androidBpGenerator) resourceActions(r *resource, mctx blueprint.ModuleC
 		// So place resources in /data/nativetest to align with cc_test.
 		//modType = "prebuilt_testcase_bob"
 		modType = "prebuilt_data_bob"
-		if r.Properties.isProprietary() {
+		if androidPartition(mctx, &r.Properties.AndroidProps) == "vendor" {
 			// Vendor modules need an additional path element to match cc_test
 			installRel = filepath.Join("nativetest", "vendor", installRel)
 		} else {
@@ -89,15 +89,15 @@ func (g *androidBpGenerator) resourceActions(r *resource, mctx blueprint.ModuleC
 	}
 
 	// as prebuilt_etc module supports only single src, we have to split into N modules
-	for _, src := range r.Properties.getSources(mctx) {
+	for _, src := range r.filesToInstall(mctx) {
 		// keep module name unique, remove slashes
 		m, err := AndroidBpFile().NewModule(modType, r.getAndroidbpResourceName(src))
 		if err != nil {
 			utils.Die(err.Error())
 		}
 
-		addProvenanceProps(m, r.Properties.AndroidProps)
+		addProvenanceProps(mctx, m, r.Properties.AndroidProps)
 
-		write(m, src, installRel)
+		write(m, src, r.installRelDir(installRel, src))
 	}
 }
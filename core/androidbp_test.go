@@ -53,7 +53,7 @@ func Test_addCFlags(t *testing.T) {
 	conlyFlags := []string{"-std=c11"}
 	cxxFlags := []string{"-std=c++11"}
 
-	addCFlags(m, cflags, conlyFlags, cxxFlags)
+	addCFlags(m, nil, cflags, conlyFlags, cxxFlags)
 
 	m.AssertExpectations(t)
 }
@@ -72,7 +72,7 @@ func Test_addCFlags2(t *testing.T) {
 	conlyFlags := []string{"-std=c17"}
 	cxxFlags := []string{"-std=c++17"}
 
-	addCFlags(m, cflags, conlyFlags, cxxFlags)
+	addCFlags(m, nil, cflags, conlyFlags, cxxFlags)
 
 	m.AssertExpectations(t)
 }
@@ -89,7 +89,7 @@ func Test_addCFlags3(t *testing.T) {
 	conlyFlags := []string{}
 	cxxFlags := []string{"-std=c++17"}
 
-	addCFlags(m, cflags, conlyFlags, cxxFlags)
+	addCFlags(m, nil, cflags, conlyFlags, cxxFlags)
 
 	m.AssertExpectations(t)
 }
@@ -101,7 +101,61 @@ func Test_addCFlags4(t *testing.T) {
 	conlyFlags := []string{}
 	cxxFlags := []string{}
 
-	err := addCFlags(m, cflags, conlyFlags, cxxFlags)
+	err := addCFlags(m, nil, cflags, conlyFlags, cxxFlags)
 
 	assert.Equal(t, err.Error(), "Both thumb and no thumb (arm) options are specified")
 }
+
+func Test_addSdkProps(t *testing.T) {
+	m := new(MockModule)
+
+	m.On("AddString", "sdk_version", "29")
+	m.On("AddString", "stl", "c++_shared")
+	m.On("AddStringList", "apex_available", []string{"//apex_available:platform", "com.android.foo"})
+
+	sdkVersion := "29"
+	stl := "c++_shared"
+	addSdkProps(m, AndroidSdkProps{
+		Sdk_version:    &sdkVersion,
+		Stl:            &stl,
+		Apex_available: []string{"//apex_available:platform", "com.android.foo"},
+	})
+
+	m.AssertExpectations(t)
+}
+
+func Test_checkAndroidSdkProps(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	valid := []AndroidSdkProps{
+		{},
+		{Sdk_version: str("current"), Stl: str("c++_static")},
+		{Sdk_version: str("29"), Min_sdk_version: str("apex_inherit")},
+		{Stl: str("libc++")},
+		{Apex_available: []string{"//apex_available:anyapex", "com.android.*", "com.vendor.foo"}},
+	}
+	for _, props := range valid {
+		assert.NoError(t, checkAndroidSdkProps(&props, tgtTypeTarget))
+	}
+	assert.NoError(t, checkAndroidSdkProps(&AndroidSdkProps{Stl: str("libc++")}, tgtTypeHost))
+
+	invalid := map[string]AndroidSdkProps{
+		"sdk_version must be":       {Sdk_version: str("Q")},
+		"min_sdk_version must be":   {Min_sdk_version: str("0")},
+		"stl must be one of":        {Stl: str("gnustl")},
+		"is not in the NDK":         {Sdk_version: str("29"), Stl: str("libc++")},
+		"//apex_available:platform": {Apex_available: []string{"//apex_available:vendor"}},
+		"is not an APEX name":       {Apex_available: []string{"com android"}},
+	}
+	for message, props := range invalid {
+		err := checkAndroidSdkProps(&props, tgtTypeTarget)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), message)
+		}
+	}
+
+	err := checkAndroidSdkProps(&AndroidSdkProps{Sdk_version: str("29")}, tgtTypeHost)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "only supported for target modules")
+	}
+}
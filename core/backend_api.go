@@ -0,0 +1,291 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// Versions of the interface between the module types and the backends.
+//
+// Version 1 is generatorBackend. Version 2 is generatorBackendV2, which
+// is experimental, and only used when the
+// experimental_backend_api_v2 configuration option is set. Backends
+// and module types can move to version 2 one at a time, using the
+// adapters below to talk to each other.
+const (
+	backendAPIV1 = 1
+	backendAPIV2 = 2
+)
+
+// generatorBackendV2 is the next version of generatorBackend. Build
+// actions and initialisation return errors, rather than reporting them
+// on the module context or dying, so that the caller decides how they
+// are reported.
+type generatorBackendV2 interface {
+	// Module build actions
+	aliasActions(*alias, blueprint.ModuleContext) error
+	binaryActions(*binary, blueprint.ModuleContext) error
+	generateSourceActions(*generateSource, blueprint.ModuleContext) error
+	transformSourceActions(*transformSource, blueprint.ModuleContext) error
+	genSharedActions(*generateSharedLibrary, blueprint.ModuleContext) error
+	genStaticActions(*generateStaticLibrary, blueprint.ModuleContext) error
+	genBinaryActions(*generateBinary, blueprint.ModuleContext) error
+	kernelModuleActions(*kernelModule, blueprint.ModuleContext) error
+	sharedActions(*sharedLibrary, blueprint.ModuleContext) error
+	staticActions(*staticLibrary, blueprint.ModuleContext) error
+	resourceActions(*resource, blueprint.ModuleContext) error
+	headerLibraryActions(*headerLibrary, blueprint.ModuleContext) error
+	pythonBinaryActions(*pythonBinary, blueprint.ModuleContext) error
+	goBinaryActions(*goBinary, blueprint.ModuleContext) error
+	deviceTreeActions(*deviceTree, blueprint.ModuleContext) error
+
+	// Backend specific info for module types
+	buildDir() string
+	sourceDir() string
+	bobScriptsDir() string
+	sharedLibsDir(tgt tgtType) string
+
+	// Backend flag escaping
+	escapeFlag(string) string
+
+	// Backend initialisation
+	init(*blueprint.Context, *bobConfig) error
+
+	// Access to backend configuration
+	getToolchain(tgt tgtType) toolchain
+}
+
+// backendAPIVersion returns the version of the backend interface
+// selected by the configuration.
+func backendAPIVersion(props *configProperties) int {
+	if _, ok := props.properties["experimental_backend_api_v2"]; ok &&
+		props.GetBool("experimental_backend_api_v2") {
+		return backendAPIV2
+	}
+	return backendAPIV1
+}
+
+// backendV2Adapter presents a version 1 backend as a version 2 backend.
+// Version 1 backends report their own errors, so none are returned.
+type backendV2Adapter struct {
+	generatorBackend
+}
+
+func (a *backendV2Adapter) aliasActions(m *alias, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.aliasActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) binaryActions(m *binary, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.binaryActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) generateSourceActions(m *generateSource, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.generateSourceActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) transformSourceActions(m *transformSource, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.transformSourceActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) genSharedActions(m *generateSharedLibrary, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.genSharedActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) genStaticActions(m *generateStaticLibrary, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.genStaticActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) genBinaryActions(m *generateBinary, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.genBinaryActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) kernelModuleActions(m *kernelModule, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.kernelModuleActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) sharedActions(m *sharedLibrary, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.sharedActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) staticActions(m *staticLibrary, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.staticActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) resourceActions(m *resource, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.resourceActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) headerLibraryActions(m *headerLibrary, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.headerLibraryActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) pythonBinaryActions(m *pythonBinary, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.pythonBinaryActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) goBinaryActions(m *goBinary, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.goBinaryActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) deviceTreeActions(m *deviceTree, ctx blueprint.ModuleContext) error {
+	a.generatorBackend.deviceTreeActions(m, ctx)
+	return nil
+}
+
+func (a *backendV2Adapter) init(ctx *blueprint.Context, config *bobConfig) error {
+	a.generatorBackend.init(ctx, config)
+	return nil
+}
+
+// backendV1Adapter presents a version 2 backend to module types which
+// still use the version 1 interface. Errors from the build actions are
+// reported against the module, and initialisation errors are fatal.
+type backendV1Adapter struct {
+	generatorBackendV2
+}
+
+func reportBackendError(ctx blueprint.ModuleContext, err error) {
+	if err != nil {
+		ctx.ModuleErrorf("%v", err)
+	}
+}
+
+func (a *backendV1Adapter) aliasActions(m *alias, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.aliasActions(m, ctx))
+}
+
+func (a *backendV1Adapter) binaryActions(m *binary, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.binaryActions(m, ctx))
+}
+
+func (a *backendV1Adapter) generateSourceActions(m *generateSource, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.generateSourceActions(m, ctx))
+}
+
+func (a *backendV1Adapter) transformSourceActions(m *transformSource, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.transformSourceActions(m, ctx))
+}
+
+func (a *backendV1Adapter) genSharedActions(m *generateSharedLibrary, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.genSharedActions(m, ctx))
+}
+
+func (a *backendV1Adapter) genStaticActions(m *generateStaticLibrary, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.genStaticActions(m, ctx))
+}
+
+func (a *backendV1Adapter) genBinaryActions(m *generateBinary, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.genBinaryActions(m, ctx))
+}
+
+func (a *backendV1Adapter) kernelModuleActions(m *kernelModule, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.kernelModuleActions(m, ctx))
+}
+
+func (a *backendV1Adapter) sharedActions(m *sharedLibrary, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.sharedActions(m, ctx))
+}
+
+func (a *backendV1Adapter) staticActions(m *staticLibrary, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.staticActions(m, ctx))
+}
+
+func (a *backendV1Adapter) resourceActions(m *resource, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.resourceActions(m, ctx))
+}
+
+func (a *backendV1Adapter) headerLibraryActions(m *headerLibrary, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.headerLibraryActions(m, ctx))
+}
+
+func (a *backendV1Adapter) pythonBinaryActions(m *pythonBinary, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.pythonBinaryActions(m, ctx))
+}
+
+func (a *backendV1Adapter) goBinaryActions(m *goBinary, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.goBinaryActions(m, ctx))
+}
+
+func (a *backendV1Adapter) deviceTreeActions(m *deviceTree, ctx blueprint.ModuleContext) {
+	reportBackendError(ctx, a.generatorBackendV2.deviceTreeActions(m, ctx))
+}
+
+func (a *backendV1Adapter) init(ctx *blueprint.Context, config *bobConfig) {
+	if err := a.generatorBackendV2.init(ctx, config); err != nil {
+		utils.Die("Failed to initialise the backend: %v", err)
+	}
+}
+
+// toBackendV2 returns the version 2 interface of a backend, so that
+// callers which have moved to it can use any backend.
+func toBackendV2(g generatorBackend) generatorBackendV2 {
+	if a, ok := g.(*backendV1Adapter); ok {
+		return a.generatorBackendV2
+	}
+	return &backendV2Adapter{g}
+}
+
+// toBackendV1 returns the version 1 interface of a backend
+func toBackendV1(g generatorBackendV2) generatorBackend {
+	if a, ok := g.(*backendV2Adapter); ok {
+		return a.generatorBackend
+	}
+	return &backendV1Adapter{g}
+}
+
+// selectBackendAPI returns the backend used by the module types. With
+// version 2, calls go through the version 2 interface, so that a
+// version 1 backend behaves as it will once it has been converted.
+func selectBackendAPI(version int, g generatorBackend) generatorBackend {
+	if version == backendAPIV2 {
+		return &backendV1Adapter{&backendV2Adapter{g}}
+	}
+	return g
+}
+
+// unwrapBackend returns the backend behind any adapters, for code which
+// only supports specific backends.
+func unwrapBackend(g interface{}) interface{} {
+	for {
+		switch a := g.(type) {
+		case *backendV1Adapter:
+			g = a.generatorBackendV2
+		case *backendV2Adapter:
+			g = a.generatorBackend
+		default:
+			return g
+		}
+	}
+}
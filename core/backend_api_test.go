@@ -0,0 +1,58 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_backendAPIVersion(t *testing.T) {
+	props := &configProperties{properties: map[string]interface{}{}}
+	assert.Equal(t, backendAPIV1, backendAPIVersion(props))
+
+	props.properties["experimental_backend_api_v2"] = false
+	assert.Equal(t, backendAPIV1, backendAPIVersion(props))
+
+	props.properties["experimental_backend_api_v2"] = true
+	assert.Equal(t, backendAPIV2, backendAPIVersion(props))
+}
+
+func Test_selectBackendAPI(t *testing.T) {
+	g := &linuxGenerator{}
+
+	assert.Equal(t, g, selectBackendAPI(backendAPIV1, g))
+
+	v2 := selectBackendAPI(backendAPIV2, g)
+	assert.IsType(t, &backendV1Adapter{}, v2)
+	assert.Equal(t, g, unwrapBackend(v2))
+}
+
+func Test_backend_adapters_unwrap(t *testing.T) {
+	g := &linuxGenerator{}
+
+	// Converting back and forth doesn't stack adapters
+	v2 := toBackendV2(g)
+	assert.IsType(t, &backendV2Adapter{}, v2)
+	assert.Equal(t, g, toBackendV1(v2))
+
+	v1 := toBackendV1(v2)
+	assert.Equal(t, v2, toBackendV2(&backendV1Adapter{v2}))
+	assert.Equal(t, g, unwrapBackend(v1))
+}
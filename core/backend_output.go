@@ -0,0 +1,63 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+	"sync"
+)
+
+// backendOutput receives the files which backends write themselves,
+// rather than through the Ninja file written by Blueprint, so that
+// they can be captured when testing a backend.
+type backendOutput interface {
+	writeFile(filename string, content *strings.Builder) error
+}
+
+// fileOutput writes files to disk, leaving unchanged files untouched so
+// that their timestamps don't trigger rebuilds.
+type fileOutput struct{}
+
+func (fileOutput) writeFile(filename string, content *strings.Builder) error {
+	return writeFileIfChanged(filename, content)
+}
+
+// memoryOutput keeps the files written in memory, keyed by filename
+type memoryOutput struct {
+	lock  sync.Mutex
+	files map[string]string
+}
+
+func newMemoryOutput() *memoryOutput {
+	return &memoryOutput{files: map[string]string{}}
+}
+
+func (o *memoryOutput) writeFile(filename string, content *strings.Builder) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.files[filename] = content.String()
+	return nil
+}
+
+// getBackendOutput returns where the backend writes its files
+func getBackendOutput(config *bobConfig) backendOutput {
+	if config.Output != nil {
+		return config.Output
+	}
+	return fileOutput{}
+}
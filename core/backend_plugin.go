@@ -0,0 +1,390 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// Backend is a generator backend defined outside of Bob, e.g. one
+// writing build files for a proprietary build system. A backend is
+// linked into a Bob binary whose main package imports the package
+// defining it, which calls RegisterBackend from an init function. It is
+// selected with the BUILDER_PLUGIN and BACKEND_PLUGIN options.
+//
+// Each module type has its own method, called when an enabled module
+// of that type generates its build actions. Errors are reported
+// against the module. Embed BackendBase to implement only the module
+// types the backend supports.
+type Backend interface {
+	// Init is called once the configuration is read, before any
+	// module is parsed, to register mutators and singletons.
+	Init(ctx *blueprint.Context, config Config) error
+
+	// Directories as they appear in the build definitions
+	BuildDir() string
+	SourceDir() string
+	BobScriptsDir() string
+	SharedLibsDir(target string) string
+
+	// EscapeFlag escapes a compiler or linker flag for the build
+	// definitions
+	EscapeFlag(flag string) string
+
+	AliasActions(Module, blueprint.ModuleContext) error
+	BinaryActions(Module, blueprint.ModuleContext) error
+	GenerateSourceActions(Module, blueprint.ModuleContext) error
+	TransformSourceActions(Module, blueprint.ModuleContext) error
+	GenSharedActions(Module, blueprint.ModuleContext) error
+	GenStaticActions(Module, blueprint.ModuleContext) error
+	GenBinaryActions(Module, blueprint.ModuleContext) error
+	KernelModuleActions(Module, blueprint.ModuleContext) error
+	SharedActions(Module, blueprint.ModuleContext) error
+	StaticActions(Module, blueprint.ModuleContext) error
+	ResourceActions(Module, blueprint.ModuleContext) error
+	HeaderLibraryActions(Module, blueprint.ModuleContext) error
+	PythonBinaryActions(Module, blueprint.ModuleContext) error
+	GoBinaryActions(Module, blueprint.ModuleContext) error
+	DeviceTreeActions(Module, blueprint.ModuleContext) error
+}
+
+// Module is the view of a module given to a Backend
+type Module interface {
+	Name() string
+	// Type is the module type, e.g. bob_binary
+	Type() string
+	// Target is "host", "target" or "host_cross" for modules built
+	// for one of them, and empty for other modules
+	Target() string
+	// Properties are the property structures of the module, once
+	// features, defaults and templates have been applied
+	Properties() []interface{}
+	// Sources are the files listed in srcs, as paths in the build
+	// definitions
+	Sources() []string
+	// Outputs are the outputs recorded with SetOutputs
+	Outputs() []string
+	// SetOutputs records the files a module writes, so that modules
+	// using it, e.g. through generated_sources or host_bin, refer to
+	// them. It has no effect on module types without outputs.
+	SetOutputs(outputDir string, outputs, implicitOutputs []string)
+	// Unwrap returns the Blueprint module
+	Unwrap() blueprint.Module
+}
+
+// Config gives a Backend access to the configuration and toolchains
+type Config interface {
+	// IsSet returns whether a configuration option exists. The
+	// other methods die when the option is missing or has another type.
+	IsSet(name string) bool
+	GetBool(name string) bool
+	GetInt(name string) int
+	GetString(name string) string
+	// Toolchain returns the "host", "target" or "host_cross" toolchain
+	Toolchain(target string) Toolchain
+}
+
+// Toolchain describes the tools of the host or target toolchain, with
+// the flags Bob passes to each for the configured target.
+type Toolchain struct {
+	Archiver    string
+	Assembler   string
+	CCompiler   string
+	CXXCompiler string
+	Linker      string
+
+	ArchiverFlags    []string
+	AssemblerFlags   []string
+	CCompilerFlags   []string
+	CXXCompilerFlags []string
+	LinkerFlags      []string
+	LinkerLibs       []string
+}
+
+// BackendBase implements the module type methods of Backend, reporting
+// each module type as unsupported.
+type BackendBase struct{}
+
+func unsupportedModule(m Module) error {
+	return fmt.Errorf("%s is not supported by the backend plugin", m.Type())
+}
+
+func (BackendBase) AliasActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) BinaryActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) GenerateSourceActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) TransformSourceActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) GenSharedActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) GenStaticActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) GenBinaryActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) KernelModuleActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) SharedActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) StaticActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) ResourceActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) HeaderLibraryActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) PythonBinaryActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) GoBinaryActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+func (BackendBase) DeviceTreeActions(m Module, ctx blueprint.ModuleContext) error {
+	return unsupportedModule(m)
+}
+
+var backendPlugins = map[string]func() Backend{}
+
+// RegisterBackend makes a backend available under a name. It must be
+// called before Main, usually from an init function.
+func RegisterBackend(name string, factory func() Backend) {
+	if _, ok := backendPlugins[name]; ok {
+		utils.Die("Backend plugin %s is registered twice", name)
+	}
+	backendPlugins[name] = factory
+}
+
+func lookupBackendPlugin(name string) (Backend, error) {
+	factory, ok := backendPlugins[name]
+	if !ok {
+		names := []string{}
+		for n := range backendPlugins {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("backend plugin '%s' is not registered. Registered plugins: %v",
+			name, names)
+	}
+	return factory(), nil
+}
+
+// pluginModule is the Module given to a backend plugin
+type pluginModule struct {
+	m   blueprint.Module
+	ctx blueprint.ModuleContext
+	g   generatorBackend
+}
+
+func (pm *pluginModule) Name() string             { return pm.ctx.ModuleName() }
+func (pm *pluginModule) Type() string             { return pm.ctx.ModuleType() }
+func (pm *pluginModule) Unwrap() blueprint.Module { return pm.m }
+
+func (pm *pluginModule) Target() string {
+	if t, ok := pm.m.(interface{ getTarget() tgtType }); ok {
+		return string(t.getTarget())
+	}
+	return ""
+}
+
+func (pm *pluginModule) Properties() []interface{} {
+	if f, ok := pm.m.(featurable); ok {
+		return f.featurableProperties()
+	}
+	return nil
+}
+
+func (pm *pluginModule) Sources() []string {
+	for _, p := range pm.Properties() {
+		if f, ok := findPropertyField(reflect.ValueOf(p), "SourceProps"); ok {
+			srcs := f.Addr().Interface().(*SourceProps).getSources(pm.ctx)
+			return getBackendPathsInSourceDir(pm.g, srcs)
+		}
+	}
+	return []string{}
+}
+
+func (pm *pluginModule) Outputs() []string {
+	if op, ok := pm.m.(interface{ outputs() []string }); ok {
+		return op.outputs()
+	}
+	return []string{}
+}
+
+func (pm *pluginModule) SetOutputs(outputDir string, outputs, implicitOutputs []string) {
+	if op, ok := pm.m.(outputSetter); ok {
+		op.setOutputs(outputDir, outputs, implicitOutputs)
+	}
+}
+
+// pluginConfig is the Config given to a backend plugin
+type pluginConfig struct {
+	config *bobConfig
+	tcs    *toolchainSet
+}
+
+func (c *pluginConfig) IsSet(name string) bool {
+	_, ok := c.config.Properties.properties[name]
+	return ok
+}
+
+func (c *pluginConfig) GetBool(name string) bool     { return c.config.Properties.GetBool(name) }
+func (c *pluginConfig) GetInt(name string) int       { return c.config.Properties.GetInt(name) }
+func (c *pluginConfig) GetString(name string) string { return c.config.Properties.GetString(name) }
+
+func (c *pluginConfig) Toolchain(target string) Toolchain {
+	tc := c.tcs.getToolchain(tgtType(target))
+	t := Toolchain{}
+	t.Archiver, t.ArchiverFlags = tc.getArchiver()
+	t.Assembler, t.AssemblerFlags = tc.getAssembler()
+	t.CCompiler, t.CCompilerFlags = tc.getCCompiler()
+	t.CXXCompiler, t.CXXCompilerFlags = tc.getCXXCompiler()
+	t.Linker = tc.getLinker().getTool()
+	t.LinkerFlags = tc.getLinker().getFlags()
+	t.LinkerLibs = tc.getLinker().getLibs()
+	return t
+}
+
+// pluginBackend presents a backend plugin as a version 2 backend
+type pluginBackend struct {
+	toolchainSet
+	backend Backend
+}
+
+var _ generatorBackendV2 = (*pluginBackend)(nil)
+
+func newPluginBackend(config *bobConfig) (*pluginBackend, error) {
+	props := config.Properties
+	name := ""
+	if _, ok := props.properties["backend_plugin"]; ok {
+		name = props.GetString("backend_plugin")
+	}
+	backend, err := lookupBackendPlugin(name)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginBackend{backend: backend}, nil
+}
+
+func (g *pluginBackend) module(m blueprint.Module, ctx blueprint.ModuleContext) Module {
+	return &pluginModule{m, ctx, getBackend(ctx)}
+}
+
+func (g *pluginBackend) aliasActions(m *alias, ctx blueprint.ModuleContext) error {
+	return g.backend.AliasActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) binaryActions(m *binary, ctx blueprint.ModuleContext) error {
+	return g.backend.BinaryActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) generateSourceActions(m *generateSource, ctx blueprint.ModuleContext) error {
+	return g.backend.GenerateSourceActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) transformSourceActions(m *transformSource, ctx blueprint.ModuleContext) error {
+	return g.backend.TransformSourceActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) genSharedActions(m *generateSharedLibrary, ctx blueprint.ModuleContext) error {
+	return g.backend.GenSharedActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) genStaticActions(m *generateStaticLibrary, ctx blueprint.ModuleContext) error {
+	return g.backend.GenStaticActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) genBinaryActions(m *generateBinary, ctx blueprint.ModuleContext) error {
+	return g.backend.GenBinaryActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) kernelModuleActions(m *kernelModule, ctx blueprint.ModuleContext) error {
+	return g.backend.KernelModuleActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) sharedActions(m *sharedLibrary, ctx blueprint.ModuleContext) error {
+	return g.backend.SharedActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) staticActions(m *staticLibrary, ctx blueprint.ModuleContext) error {
+	return g.backend.StaticActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) resourceActions(m *resource, ctx blueprint.ModuleContext) error {
+	return g.backend.ResourceActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) headerLibraryActions(m *headerLibrary, ctx blueprint.ModuleContext) error {
+	return g.backend.HeaderLibraryActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) pythonBinaryActions(m *pythonBinary, ctx blueprint.ModuleContext) error {
+	return g.backend.PythonBinaryActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) goBinaryActions(m *goBinary, ctx blueprint.ModuleContext) error {
+	return g.backend.GoBinaryActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) deviceTreeActions(m *deviceTree, ctx blueprint.ModuleContext) error {
+	return g.backend.DeviceTreeActions(g.module(m, ctx), ctx)
+}
+
+func (g *pluginBackend) buildDir() string      { return g.backend.BuildDir() }
+func (g *pluginBackend) sourceDir() string     { return g.backend.SourceDir() }
+func (g *pluginBackend) bobScriptsDir() string { return g.backend.BobScriptsDir() }
+func (g *pluginBackend) sharedLibsDir(tgt tgtType) string {
+	return g.backend.SharedLibsDir(string(tgt))
+}
+func (g *pluginBackend) escapeFlag(s string) string { return g.backend.EscapeFlag(s) }
+
+func (g *pluginBackend) init(ctx *blueprint.Context, config *bobConfig) error {
+	g.toolchainSet.parseConfig(config)
+	return g.backend.Init(ctx, &pluginConfig{config, &g.toolchainSet})
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/google/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+type testBackend struct {
+	BackendBase
+	initialised bool
+}
+
+func (b *testBackend) Init(ctx *blueprint.Context, config Config) error {
+	b.initialised = config.IsSet("test_option") && config.GetBool("test_option")
+	return nil
+}
+
+func (b *testBackend) BuildDir() string                { return "out" }
+func (b *testBackend) SourceDir() string               { return "src" }
+func (b *testBackend) BobScriptsDir() string           { return "src/bob/scripts" }
+func (b *testBackend) SharedLibsDir(tgt string) string { return "out/" + tgt + "/lib" }
+func (b *testBackend) EscapeFlag(s string) string      { return s }
+
+// testModule implements the parts of Module used by BackendBase
+type testModule struct {
+	Module
+}
+
+func (m testModule) Type() string { return "bob_test" }
+
+func Test_backendPlugin(t *testing.T) {
+	RegisterBackend("test_backend", func() Backend { return &testBackend{} })
+	defer delete(backendPlugins, "test_backend")
+
+	_, err := lookupBackendPlugin("missing")
+	assert.EqualError(t, err,
+		"backend plugin 'missing' is not registered. Registered plugins: [test_backend]")
+
+	config := &bobConfig{}
+	config.Properties.properties = map[string]interface{}{
+		"backend_plugin": "test_backend",
+		"test_option":    true,
+	}
+	g, err := newPluginBackend(config)
+	assert.NoError(t, err)
+	assert.Equal(t, "out/host/lib", g.sharedLibsDir(tgtTypeHost))
+
+	pc := &pluginConfig{config, &g.toolchainSet}
+	assert.NoError(t, g.backend.Init(nil, pc))
+	assert.True(t, g.backend.(*testBackend).initialised)
+	assert.False(t, pc.IsSet("missing_option"))
+}
+
+func Test_BackendBase(t *testing.T) {
+	b := &testBackend{}
+	assert.EqualError(t, b.BinaryActions(testModule{}, nil),
+		"bob_test is not supported by the backend plugin")
+}
+
+func Test_setOutputs(t *testing.T) {
+	m := &kernelModule{}
+	var op outputSetter = m
+	op.setOutputs("out/res", []string{"out/res/a"}, []string{"out/res/a.sym"})
+	assert.Equal(t, []string{"out/res/a"}, m.outputs())
+}
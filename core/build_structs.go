@@ -21,8 +21,10 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/google/blueprint"
+	"github.com/google/blueprint/pathtools"
 	"github.com/google/blueprint/proptools"
 
 	"github.com/ARM-software/bob-build/internal/utils"
@@ -104,6 +106,10 @@ type generatorBackend interface {
 	sharedActions(*sharedLibrary, blueprint.ModuleContext)
 	staticActions(*staticLibrary, blueprint.ModuleContext)
 	resourceActions(*resource, blueprint.ModuleContext)
+	headerLibraryActions(*headerLibrary, blueprint.ModuleContext)
+	pythonBinaryActions(*pythonBinary, blueprint.ModuleContext)
+	goBinaryActions(*goBinary, blueprint.ModuleContext)
+	deviceTreeActions(*deviceTree, blueprint.ModuleContext)
 
 	// Backend specific info for module types
 	buildDir() string
@@ -126,6 +132,10 @@ type generatorBackend interface {
 type bobConfig struct {
 	Generator  generatorBackend
 	Properties configProperties
+
+	// Where backends write files other than the Ninja file. When
+	// nil, files are written to disk.
+	Output backendOutput
 }
 
 // AndroidProps defines module properties used by Android backends
@@ -134,6 +144,9 @@ type AndroidProps struct {
 	Tags []string
 	// Value to use on Android for LOCAL_MODULE_OWNER
 	Owner *string
+	// The Android partition the module is installed to: system, vendor,
+	// odm, product or system_ext. Modules with an owner default to vendor.
+	Partition *string
 }
 
 func (p *AndroidProps) isProprietary() bool {
@@ -158,6 +171,38 @@ type AndroidMTEProps struct {
 	}
 }
 
+// AndroidSdkProps defines properties selecting what a module is built
+// against on Android, and the APEXes it can be part of. They are only
+// used by the Android.bp backend, except for Stl.
+type AndroidSdkProps struct {
+	// Build against the NDK at this API level, rather than the platform
+	Sdk_version *string
+	// The C++ standard library to use. The Linux backend accepts
+	// different values to Soong.
+	Stl *string
+	// The APEXes the module can be included in
+	Apex_available []string
+	// The lowest API level the module supports when it is part of an APEX
+	Min_sdk_version *string
+}
+
+// AndroidAuxProps selects one of the auxiliary (AUX) toolchains of the
+// Android make build, which build firmware for processors other than
+// the application processor. They are only used by the Android.mk
+// backend, on binaries and static libraries.
+type AndroidAuxProps struct {
+	Aux struct {
+		// The OS of the AUX toolchain, which enables it
+		Os *string
+		// The architecture, sub-architecture and CPU of the processor
+		Arch    *string
+		Subarch *string
+		Cpu     *string
+		// The variant of the AUX OS
+		Os_variant *string
+	}
+}
+
 func getBobScriptsDir() string {
 	return filepath.Join(getBobDir(), "scripts")
 }
@@ -210,6 +255,58 @@ func getBackendPathInBobScriptsDir(g generatorBackend, elems ...string) string {
 	return filepath.Join(append([]string{g.bobScriptsDir()}, elems...)...)
 }
 
+// What to do when a glob matches no files
+const (
+	emptyGlobAllow = "allow"
+	emptyGlobWarn  = "warn"
+	emptyGlobError = "error"
+)
+
+func emptyGlobPolicy(config *bobConfig) string {
+	props := config.Properties
+	for _, policy := range []string{emptyGlobError, emptyGlobWarn} {
+		key := "empty_glob_" + policy
+		if _, ok := props.properties[key]; ok && props.GetBool(key) {
+			return policy
+		}
+	}
+	return emptyGlobAllow
+}
+
+// The sources of a module are listed many times during generation, so
+// remember which empty globs have been reported.
+var emptyGlobsReported sync.Map
+
+func reportEmptyGlob(ctx blueprint.BaseModuleContext, pattern string) {
+	policy := emptyGlobPolicy(getConfig(ctx))
+	if policy == emptyGlobAllow {
+		return
+	}
+
+	key := ctx.ModuleDir() + ":" + ctx.ModuleName() + ":" + pattern
+	if _, reported := emptyGlobsReported.LoadOrStore(key, true); reported {
+		return
+	}
+
+	if policy == emptyGlobError {
+		ctx.ModuleErrorf("glob %s matched no files", pattern)
+	} else {
+		utils.Warnf(ctx.BlueprintsFile(), "glob %s in module %s matched no files",
+			pattern, ctx.ModuleName())
+	}
+}
+
+// isExcluded returns whether a file matches one of the excludes, which
+// may contain wildcards.
+func isExcluded(file string, excludes []string) bool {
+	for _, exclude := range excludes {
+		if matched, err := pathtools.Match(exclude, file); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
 func glob(ctx blueprint.BaseModuleContext, globs []string, excludes []string) []string {
 	var files []string
 
@@ -222,11 +319,12 @@ func glob(ctx blueprint.BaseModuleContext, globs []string, excludes []string) []
 			// Globs need to be calculated relative to the source
 			// directory (not the working directory), so add it
 			// here, and remove it afterwards.
-			file = getPathInSourceDir(file)
-			matches, err := ctx.GlobWithDeps(file, excludesFromSrcDir)
+			matches, err := ctx.GlobWithDeps(getPathInSourceDir(file), excludesFromSrcDir)
 
 			if err != nil {
 				ctx.ModuleErrorf("glob failed with: %s", err)
+			} else if len(matches) == 0 {
+				reportEmptyGlob(ctx, file)
 			}
 
 			for _, match := range matches {
@@ -236,7 +334,7 @@ func glob(ctx blueprint.BaseModuleContext, globs []string, excludes []string) []
 				}
 				files = append(files, rel)
 			}
-		} else if !utils.Contains(excludes, file) {
+		} else if !isExcluded(file, excludes) {
 			files = append(files, file)
 		}
 	}
@@ -281,11 +379,16 @@ func (s *SourceProps) processPaths(ctx blueprint.BaseModuleContext, g generatorB
 type tgtType string
 
 const (
-	tgtTypeHost    tgtType = "host"
-	tgtTypeTarget  tgtType = "target"
-	tgtTypeUnknown tgtType = ""
+	tgtTypeHost      tgtType = "host"
+	tgtTypeTarget    tgtType = "target"
+	tgtTypeHostCross tgtType = "host_cross"
+	tgtTypeUnknown   tgtType = ""
 )
 
+// The target types which have a `host: {}`, `target: {}` or
+// `host_cross: {}` block in target specific modules.
+var targetSpecificTypes = []tgtType{tgtTypeHost, tgtTypeTarget, tgtTypeHostCross}
+
 func stripEmptyComponents(list []string) []string {
 	var emptyStrFilter = func(s string) bool { return s != "" }
 
@@ -320,7 +423,7 @@ func stripEmptyComponentsMutator(mctx blueprint.BottomUpMutatorContext) {
 	strippableProps := f.featurableProperties()
 
 	if t, ok := mctx.Module().(targetSpecificLibrary); ok {
-		for _, tgt := range []tgtType{tgtTypeHost, tgtTypeTarget} {
+		for _, tgt := range targetSpecificTypes {
 			tgtSpecific := t.getTargetSpecific(tgt)
 			tgtSpecificData := tgtSpecific.getTargetSpecificProps()
 			strippableProps = append(strippableProps, tgtSpecificData)
@@ -354,6 +457,7 @@ func parseAndAddVariationDeps(mctx blueprint.BottomUpMutatorContext,
 
 	hostVariation := []blueprint.Variation{blueprint.Variation{Mutator: splitterMutatorName, Variation: string(tgtTypeHost)}}
 	targetVariation := []blueprint.Variation{blueprint.Variation{Mutator: splitterMutatorName, Variation: string(tgtTypeTarget)}}
+	hostCrossVariation := []blueprint.Variation{blueprint.Variation{Mutator: splitterMutatorName, Variation: string(tgtTypeHostCross)}}
 
 	for _, dep := range deps {
 		var variations []blueprint.Variation
@@ -366,6 +470,8 @@ func parseAndAddVariationDeps(mctx blueprint.BottomUpMutatorContext,
 					variations = append(variations, hostVariation...)
 				} else if vn == "target" {
 					variations = append(variations, targetVariation...)
+				} else if vn == "host_cross" {
+					variations = append(variations, hostCrossVariation...)
 				} else {
 					utils.Die("Invalid variation: %s in module name %s", vn, dep)
 				}
@@ -435,7 +541,7 @@ func targetMutator(mctx blueprint.TopDownMutatorContext) {
 
 		tgt := t.getTarget()
 
-		if tgt != tgtTypeHost && tgt != tgtTypeTarget {
+		if tgt != tgtTypeHost && tgt != tgtTypeTarget && tgt != tgtTypeHostCross {
 			// This is fine if target is neither host or target,
 			// it can happen if the target is the default
 			return
@@ -533,6 +639,7 @@ func findRequiredModulesMutator(mctx blueprint.TopDownMutatorContext) {
 		// need its dependencies so return.
 		if isEnabled(e) && isBuiltByDefault(e) {
 			markAsRequired(e)
+			e.getEnableableProps().Required_chain = []string{mctx.ModuleName()}
 		} else {
 			return
 		}
@@ -542,7 +649,12 @@ func findRequiredModulesMutator(mctx blueprint.TopDownMutatorContext) {
 		return
 	}
 
+	// The modules through which each dependency is required, starting
+	// from this module
+	chains := map[blueprint.Module][]string{mctx.Module(): []string{mctx.ModuleName()}}
+
 	mctx.WalkDeps(func(dep blueprint.Module, parent blueprint.Module) bool {
+		chain := append(append([]string{}, chains[parent]...), dep.Name())
 		e, ok := dep.(enableable)
 		if ok {
 			// Stop traversing if we've already visited this while
@@ -556,7 +668,9 @@ func findRequiredModulesMutator(mctx blueprint.TopDownMutatorContext) {
 				return false
 			}
 			markAsRequired(e)
+			e.getEnableableProps().Required_chain = chain
 		}
+		chains[dep] = chain
 		return true
 	})
 }
@@ -587,20 +701,28 @@ func checkDisabledMutator(mctx blueprint.BottomUpMutatorContext) {
 		}
 		if e, ok := dep.(enableable); ok {
 			if !isEnabled(e) {
-				disabledDeps = utils.AppendIfUnique(disabledDeps, dep.Name())
+				disabledDeps = utils.AppendIfUnique(disabledDeps, disabledModuleDescription(dep))
 			}
 		}
 	})
 
-	// disable current module if dependency is disabled, or panic if it's required
-	if len(disabledDeps) > 0 {
-		if isRequired(ep) {
-			utils.Die("Module %s is required but depends on disabled modules %s", module.Name(), strings.Join(disabledDeps, ", "))
-		} else {
-			ep.getEnableableProps().Enabled = proptools.BoolPtr(false)
+	if len(disabledDeps) == 0 {
+		return
+	}
+	reason := "depends on disabled module " + strings.Join(disabledDeps, "; and on ")
+
+	// disable current module if dependency is disabled, or fail if it's required
+	props := ep.getEnableableProps()
+	if isRequired(ep) {
+		chain := strings.Join(props.Required_chain, " -> ")
+		if !autoDisableDependents(getConfig(mctx)) {
+			mctx.ModuleErrorf("is required (%s), but %s", chain, reason)
 			return
 		}
+		utils.Warnf(mctx.ModuleName(), "disabled, as it %s. It was required by %s", reason, chain)
 	}
+	props.Enabled = proptools.BoolPtr(false)
+	props.Disabled_reason = reason
 }
 
 type factoryWithConfig func(*bobConfig) (blueprint.Module, []interface{})
@@ -609,6 +731,8 @@ func registerModuleTypes(register func(string, factoryWithConfig)) {
 	register("bob_binary", binaryFactory)
 	register("bob_static_library", staticLibraryFactory)
 	register("bob_shared_library", sharedLibraryFactory)
+	register("bob_header_library", headerLibraryFactory)
+	register("bob_relocatable_object", relocatableObjectFactory)
 
 	register("bob_defaults", defaultsFactory)
 
@@ -626,4 +750,9 @@ func registerModuleTypes(register func(string, factoryWithConfig)) {
 	register("bob_kernel_module", kernelModuleFactory)
 	register("bob_resource", resourceFactory)
 	register("bob_install_group", installGroupFactory)
+	register("bob_python_binary", pythonBinaryFactory)
+	register("bob_go_binary", goBinaryFactory)
+	register("bob_dtb", deviceTreeFactory)
+	register("bob_dtbo", deviceTreeOverlayFactory)
+	register("bob_warning_budget", warningBudgetFactory)
 }
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// compilerID identifies the compiler of a toolchain
+type compilerID struct {
+	// One of gcc, clang, appleclang or armclang
+	vendor string
+	major  int
+	minor  int
+}
+
+func (id compilerID) version() string {
+	return fmt.Sprintf("%d.%d", id.major, id.minor)
+}
+
+// The range of major versions with a <vendor>_ge_<version> feature.
+// Features are defined for every vendor, whichever compiler is used, so
+// that modules can refer to them with any toolchain. The range is
+// extended to newer compilers when they are used.
+var compilerFeatureVersions = map[string][2]int{
+	"gcc":        {7, 16},
+	"clang":      {8, 22},
+	"appleclang": {11, 18},
+}
+
+// parseCompilerID identifies a compiler from its predefined macros, as
+// listed by `-dM -E`. The checks are ordered because armclang and Apple
+// clang also define the macros of clang, which defines those of GCC.
+func parseCompilerID(macros string) (compilerID, error) {
+	defines := map[string]string{}
+	for _, line := range strings.Split(macros, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "#define" {
+			defines[fields[1]] = fields[2]
+		}
+	}
+
+	number := func(name string) int {
+		value, _ := strconv.Atoi(defines[name])
+		return value
+	}
+
+	if _, ok := defines["__ARMCC_VERSION"]; ok {
+		// Mmmuuxx, e.g. 6180001 for 6.18
+		version := number("__ARMCC_VERSION")
+		return compilerID{"armclang", version / 1000000, version / 10000 % 100}, nil
+	} else if _, ok := defines["__apple_build_version__"]; ok {
+		return compilerID{"appleclang", number("__clang_major__"), number("__clang_minor__")}, nil
+	} else if _, ok := defines["__clang__"]; ok {
+		return compilerID{"clang", number("__clang_major__"), number("__clang_minor__")}, nil
+	} else if _, ok := defines["__GNUC__"]; ok {
+		return compilerID{"gcc", number("__GNUC__"), number("__GNUC_MINOR__")}, nil
+	}
+	return compilerID{}, fmt.Errorf("unknown compiler")
+}
+
+// identifyCompiler runs the C compiler of a toolchain to list its
+// predefined macros.
+func identifyCompiler(tc toolchain) (compilerID, error) {
+	cc, flags := tc.getCCompiler()
+	args := utils.NewStringSlice(flags, []string{"-dM", "-E", "-x", "c", "-"})
+	cmd := exec.Command(cc, utils.Filter(func(s string) bool { return s != "" }, args)...)
+	cmd.Stdin = &bytes.Buffer{}
+	out, err := cmd.Output()
+	if err != nil {
+		return compilerID{}, fmt.Errorf("%s failed: %v", cc, err)
+	}
+	return parseCompilerID(string(out))
+}
+
+// compilerFeaturePrefix returns the prefix of the compiler properties
+// and features of a target type. Those of the target are unprefixed,
+// as most modules are built for it.
+func compilerFeaturePrefix(tgt tgtType) string {
+	if tgt == tgtTypeTarget {
+		return ""
+	}
+	return string(tgt) + "_"
+}
+
+// addCompilerID makes the compiler of a target type available to
+// templates, as {{.<prefix>compiler_vendor}} and
+// {{.<prefix>compiler_version}}, and to features, as
+// <prefix><vendor>_ge_<major>. An unidentified compiler has an empty
+// vendor, and none of the features are enabled.
+func (properties *configProperties) addCompilerID(tgt tgtType, id compilerID) {
+	prefix := compilerFeaturePrefix(tgt)
+
+	version := ""
+	if id.vendor != "" {
+		version = id.version()
+	}
+	properties.properties[prefix+"compiler_vendor"] = id.vendor
+	properties.stringMap[prefix+"compiler_vendor"] = id.vendor
+	properties.properties[prefix+"compiler_version"] = version
+	properties.stringMap[prefix+"compiler_version"] = version
+
+	for vendor, versions := range compilerFeatureVersions {
+		last := versions[1]
+		if vendor == id.vendor && id.major > last {
+			last = id.major
+		}
+		for major := versions[0]; major <= last; major++ {
+			name := fmt.Sprintf("%s%s_ge_%d", prefix, vendor, major)
+			properties.features[name] = vendor == id.vendor && id.major >= major
+		}
+	}
+
+	properties.featureList = utils.SortedKeysBoolMap(properties.features)
+}
+
+// identifyCompilers adds the compiler properties and features of each
+// target type. This must be done after the toolchains are created by
+// the backend, and before any module is created, as modules have
+// a property for each feature.
+func identifyCompilers(config *bobConfig) {
+	for _, tgt := range availableTargetTypes(&config.Properties) {
+		id, err := identifyCompiler(config.Generator.getToolchain(tgt))
+		if err != nil {
+			utils.Warnf("", "Unable to identify the %s compiler: %v", tgt, err)
+		}
+		config.Properties.addCompilerID(tgt, id)
+		utils.Logf(utils.LogInfo, "", "The %s compiler is %s %s", tgt, id.vendor, id.version())
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseCompilerID(t *testing.T) {
+	gcc := "#define __GNUC__ 12\n#define __GNUC_MINOR__ 2\n#define __GNUC_PATCHLEVEL__ 0\n"
+	id, err := parseCompilerID(gcc)
+	assert.NoError(t, err)
+	assert.Equal(t, compilerID{"gcc", 12, 2}, id)
+	assert.Equal(t, "12.2", id.version())
+
+	// Clang also defines the GNU macros
+	clang := gcc + "#define __clang__ 1\n#define __clang_major__ 15\n#define __clang_minor__ 0\n"
+	id, err = parseCompilerID(clang)
+	assert.NoError(t, err)
+	assert.Equal(t, compilerID{"clang", 15, 0}, id)
+
+	id, err = parseCompilerID(clang + "#define __apple_build_version__ 14000029\n")
+	assert.NoError(t, err)
+	assert.Equal(t, compilerID{"appleclang", 15, 0}, id)
+
+	id, err = parseCompilerID(clang + "#define __ARMCC_VERSION 6180001\n")
+	assert.NoError(t, err)
+	assert.Equal(t, compilerID{"armclang", 6, 18}, id)
+
+	_, err = parseCompilerID("#define __STDC__ 1\n")
+	assert.Error(t, err)
+}
+
+func Test_addCompilerID(t *testing.T) {
+	properties := configProperties{
+		features:   map[string]bool{},
+		properties: map[string]interface{}{},
+		stringMap:  map[string]string{},
+	}
+
+	properties.addCompilerID(tgtTypeTarget, compilerID{"gcc", 12, 2})
+	properties.addCompilerID(tgtTypeHost, compilerID{"clang", 30, 1})
+
+	assert.Equal(t, "gcc", properties.stringMap["compiler_vendor"])
+	assert.Equal(t, "12.2", properties.stringMap["compiler_version"])
+	assert.Equal(t, "clang", properties.stringMap["host_compiler_vendor"])
+
+	assert.True(t, properties.features["gcc_ge_12"])
+	assert.False(t, properties.features["gcc_ge_13"])
+	assert.False(t, properties.features["clang_ge_15"])
+	assert.False(t, properties.features["host_gcc_ge_12"])
+	assert.True(t, properties.features["host_clang_ge_15"])
+
+	// Newer compilers than the range of features extend it
+	assert.True(t, properties.features["host_clang_ge_30"])
+	assert.Contains(t, properties.featureList, "host_clang_ge_30")
+	assert.NotContains(t, properties.featureList, "clang_ge_30")
+}
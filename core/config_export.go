@@ -0,0 +1,171 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+const configExportFile = "config_export.json"
+
+// The version of the format of config_export.json and of the overrides
+// file, described by docs/config_schema.json
+const configFileVersion = 1
+
+// configFileContent is the content of config_export.json, and of the file
+// given to --config-overrides.
+type configFileContent struct {
+	Version int                    `json:"version"`
+	Options map[string]interface{} `json:"options"`
+}
+
+func configExportEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["config_export"]
+	return ok && props.GetBool("config_export")
+}
+
+// configExportContent returns config_export.json, holding the value of
+// each option Bob uses, including the host_ and target_ options it
+// derives from shared ones.
+func configExportContent(properties *configProperties) (string, error) {
+	// Maps are written with sorted keys, so the file is stable
+	data, err := json.MarshalIndent(configFileContent{
+		Version: configFileVersion,
+		Options: properties.properties,
+	}, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// applyOverrides replaces the values of the options in the overrides
+// file. Only options which are set in the configuration can be
+// overridden, with a value of the same type, as the dependencies
+// between options are not checked again.
+func (properties *configProperties) applyOverrides(filename string) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("Unable to read configuration overrides: %s", err.Error())
+	}
+	d := json.NewDecoder(bytes.NewReader(content))
+	d.UseNumber()
+
+	var overrides configFileContent
+	err = d.Decode(&overrides)
+	if err != nil {
+		return fmt.Errorf("Unable to decode configuration overrides %s: %s", filename, err.Error())
+	}
+	if overrides.Version != configFileVersion {
+		return fmt.Errorf("Configuration overrides %s have version %d, expected %d",
+			filename, overrides.Version, configFileVersion)
+	}
+
+	keys := []string{}
+	for key := range overrides.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errs := []string{}
+	for _, key := range keys {
+		value := overrides.Options[key]
+		current, ok := properties.properties[key]
+		if !ok {
+			// Namespaced options derived from shared ones can be
+			// overridden, as they are in config_export.json.
+			current, ok = properties.inheritedValue(key)
+		}
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s is not a configuration option, or is disabled", key))
+			continue
+		}
+		if configValueType(value) != configValueType(current) {
+			errs = append(errs, fmt.Sprintf("%s must be a %s, like its value %s",
+				key, configValueType(current), describeConfigValue(current)))
+			continue
+		}
+
+		properties.properties[key] = value
+		properties.stringMap[key] = convertToString(value)
+		if v, ok := boolValue(value); ok {
+			properties.features[key] = v
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Invalid configuration overrides in %s:\n\t%s",
+			filename, strings.Join(errs, "\n\t"))
+	}
+	return nil
+}
+
+// configValueType returns the JSON type of an option's value
+func configValueType(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func describeConfigValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// configExportSingleton writes the configuration to config_export.json
+// in the build directory.
+type configExportSingleton struct{}
+
+func (s *configExportSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	config := getConfig(ctx)
+	content, err := configExportContent(&config.Properties)
+	if err != nil {
+		utils.Die("Failed to generate %s: %v", configExportFile, err)
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString(content)
+	err = getBackendOutput(config).writeFile(getPathInBuildDir(configExportFile), sb)
+	if err != nil {
+		utils.Die("Failed to write %s: %v", configExportFile, err)
+	}
+}
+
+func configExportSingletonFactory() blueprint.Singleton {
+	return &configExportSingleton{}
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfigJSON = `{
+    "as_binary": {"ignore": false, "value": "as"},
+    "builder_ninja": {"ignore": false, "value": true},
+    "link_parallelism": {"ignore": false, "value": 4},
+    "target_toolchain_clang": {"ignore": true, "value": false}
+}`
+
+// loadTestConfig loads testConfigJSON with the given overrides, if any
+func loadTestConfig(t *testing.T, overrides string) (*configProperties, error) {
+	dir, err := ioutil.TempDir("", "bob_config_export")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	configJSON := filepath.Join(dir, "config.json")
+	assert.Nil(t, ioutil.WriteFile(configJSON, []byte(testConfigJSON), 0644))
+
+	overridesFile := ""
+	if overrides != "" {
+		overridesFile = filepath.Join(dir, "overrides.json")
+		assert.Nil(t, ioutil.WriteFile(overridesFile, []byte(overrides), 0644))
+	}
+
+	props := &configProperties{}
+	return props, props.LoadConfig(configJSON, overridesFile)
+}
+
+func Test_configExportContent(t *testing.T) {
+	props, err := loadTestConfig(t, "")
+	assert.Nil(t, err)
+
+	content, err := configExportContent(props)
+	assert.Nil(t, err)
+	assert.Equal(t, `{
+    "version": 1,
+    "options": {
+        "as_binary": "as",
+        "builder_ninja": true,
+        "host_as_binary": "as",
+        "link_parallelism": 4,
+        "target_as_binary": "as"
+    }
+}
+`, content)
+}
+
+func Test_applyOverrides(t *testing.T) {
+	props, err := loadTestConfig(t, `{
+    "version": 1,
+    "options": {
+        "as_binary": "arm-linux-gnueabihf-as",
+        "builder_ninja": false,
+        "link_parallelism": 8
+    }
+}`)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "arm-linux-gnueabihf-as", props.GetString("as_binary"))
+	assert.Equal(t, 8, props.GetInt("link_parallelism"))
+	assert.Equal(t, "8", props.StringMap()["link_parallelism"])
+	assert.False(t, props.GetBool("builder_ninja"))
+	assert.False(t, props.features["builder_ninja"])
+
+	// The derived options follow the overridden value
+	assert.Equal(t, "arm-linux-gnueabihf-as", props.GetTargetString(tgtTypeTarget, "as_binary"))
+	assert.Equal(t, "arm-linux-gnueabihf-as", props.GetTargetString(tgtTypeHost, "as_binary"))
+}
+
+func Test_applyOverrides_derived_option(t *testing.T) {
+	props, err := loadTestConfig(t, `{"version": 1, "options": {"target_as_binary": "aarch64-linux-gnu-as"}}`)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "aarch64-linux-gnu-as", props.GetTargetString(tgtTypeTarget, "as_binary"))
+	assert.Equal(t, "as", props.GetTargetString(tgtTypeHost, "as_binary"))
+}
+
+func Test_applyOverrides_round_trip(t *testing.T) {
+	props, err := loadTestConfig(t, "")
+	assert.Nil(t, err)
+	exported, err := configExportContent(props)
+	assert.Nil(t, err)
+
+	reloaded, err := loadTestConfig(t, exported)
+	assert.Nil(t, err)
+	assert.Equal(t, props.properties, reloaded.properties)
+	assert.Equal(t, props.stringMap, reloaded.stringMap)
+}
+
+func Test_applyOverrides_errors(t *testing.T) {
+	_, err := loadTestConfig(t, `{
+    "version": 1,
+    "options": {
+        "builder_ninja": "y",
+        "link_parallelism": "8",
+        "no_such_option": true,
+        "target_toolchain_clang": true
+    }
+}`)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "builder_ninja must be a boolean, like its value true")
+	assert.Contains(t, err.Error(), "link_parallelism must be a number, like its value 4")
+	assert.Contains(t, err.Error(), "no_such_option is not a configuration option")
+	assert.Contains(t, err.Error(), "target_toolchain_clang is not a configuration option, or is disabled")
+
+	_, err = loadTestConfig(t, `{"options": {"builder_ninja": false}}`)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "have version 0, expected 1")
+
+	_, err = loadTestConfig(t, `{"version": 1, "options": {`)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Unable to decode configuration overrides")
+}
@@ -91,6 +91,16 @@ func (properties configProperties) GetString(name string) string {
 	return ""
 }
 
+// GetTargetString returns the value of an option for the toolchain of
+// one target type. Options shared by both target types can be
+// overridden for one of them with a host_ or target_ prefix.
+func (properties configProperties) GetTargetString(tgt tgtType, name string) string {
+	if _, ok := properties.properties[string(tgt)+"_"+name]; ok {
+		return properties.GetString(string(tgt) + "_" + name)
+	}
+	return properties.GetString(name)
+}
+
 func (properties configProperties) StringMap() map[string]string {
 	return properties.stringMap
 }
@@ -142,6 +152,53 @@ func convertToString(thing interface{}) string {
 	return value
 }
 
+// Options which apply to the toolchains of both target types, unless
+// overridden in the host or target namespace, i.e. by an option with a
+// host_ or target_ prefix (e.g. target_as_binary).
+var sharedToolchainOptions = []string{
+	"as_binary",
+	"armclang_ar_binary",
+	"armclang_as_binary",
+	"linker",
+}
+
+// inheritTargetOptions sets the host and target namespaced options which
+// have not been set to the value of the shared option, so that both sets
+// can be used in templates and features.
+func (properties *configProperties) inheritTargetOptions() {
+	for _, name := range sharedToolchainOptions {
+		value, ok := properties.properties[name]
+		if !ok {
+			continue
+		}
+		for _, tgt := range availableTargetTypes(properties) {
+			key := string(tgt) + "_" + name
+			if _, ok := properties.properties[key]; ok {
+				continue
+			}
+			properties.properties[key] = value
+			properties.stringMap[key] = properties.stringMap[name]
+			if v, ok := properties.features[name]; ok {
+				properties.features[key] = v
+			}
+		}
+	}
+}
+
+// inheritedValue returns the value that a host or target namespaced
+// option which has not been set takes from the shared option.
+func (properties *configProperties) inheritedValue(key string) (interface{}, bool) {
+	for _, name := range sharedToolchainOptions {
+		for _, tgt := range availableTargetTypes(properties) {
+			if key == string(tgt)+"_"+name {
+				value, ok := properties.properties[name]
+				return value, ok
+			}
+		}
+	}
+	return nil, false
+}
+
 // Identify if the input is a boolean and return its value
 func boolValue(thing interface{}) (value, isBool bool) {
 	field := reflect.ValueOf(thing)
@@ -158,7 +215,11 @@ func boolValue(thing interface{}) (value, isBool bool) {
 	return
 }
 
-func (properties *configProperties) LoadConfig(filename string) error {
+// LoadConfig reads the configuration from the JSON file written by the
+// configuration system. When 'overridesFile' is not empty, the values
+// it holds replace those of the configuration, before the derived
+// options are set.
+func (properties *configProperties) LoadConfig(filename, overridesFile string) error {
 	content, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("Unable to read configuration file: %s", err.Error())
@@ -201,6 +262,15 @@ func (properties *configProperties) LoadConfig(filename string) error {
 		}
 	}
 
+	if overridesFile != "" {
+		err = properties.applyOverrides(overridesFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	properties.inheritTargetOptions()
+
 	// Calculate the plain list of features once.
 	properties.featureList = utils.SortedKeysBoolMap(properties.features)
 
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_inheritTargetOptions(t *testing.T) {
+	properties := configProperties{
+		properties: map[string]interface{}{
+			"as_binary":        "as",
+			"target_as_binary": "aarch64-as",
+		},
+		stringMap: map[string]string{
+			"as_binary":        "as",
+			"target_as_binary": "aarch64-as",
+		},
+		features: map[string]bool{},
+	}
+	properties.inheritTargetOptions()
+
+	// The host inherits the shared value, while the target keeps its own
+	assert.Equal(t, "as", properties.GetTargetString(tgtTypeHost, "as_binary"))
+	assert.Equal(t, "as", properties.StringMap()["host_as_binary"])
+	assert.Equal(t, "aarch64-as", properties.GetTargetString(tgtTypeTarget, "as_binary"))
+
+	// Options which are not set are not inherited
+	_, ok := properties.properties["host_armclang_as_binary"]
+	assert.False(t, ok)
+}
+
+func Test_GetTargetString_without_namespaced_option(t *testing.T) {
+	properties := configProperties{
+		properties: map[string]interface{}{"armclang_ar_binary": "armar"},
+	}
+	assert.Equal(t, "armar", properties.GetTargetString(tgtTypeTarget, "armclang_ar_binary"))
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+func cxxFeatureFlag(setting *bool, enable, disable string) []string {
+	if setting == nil {
+		return []string{}
+	} else if *setting {
+		return []string{enable}
+	}
+	return []string{disable}
+}
+
+// getCxxflags returns the flags for C++ compilation, with the flags
+// selecting the exception and RTTI settings first, so that they can still
+// be overridden by cxxflags.
+func (props *BuildProps) getCxxflags() []string {
+	return utils.NewStringSlice(
+		cxxFeatureFlag(props.Exceptions, "-fexceptions", "-fno-exceptions"),
+		cxxFeatureFlag(props.Rtti, "-frtti", "-fno-rtti"),
+		props.Cxxflags)
+}
+
+// cxxFeatureEnabled returns whether C++ sources are compiled with a
+// feature, given the flags enabling and disabling it. As on the command
+// line, the last of these in cflags and cxxflags wins, so this takes
+// both the property and flags passed directly into account. When none
+// is given, the compiler's default applies.
+func (props *Build) cxxFeatureEnabled(enable, disable string, byDefault bool) bool {
+	enabled := byDefault
+	for _, flag := range utils.NewStringSlice(props.Cflags, props.getCxxflags()) {
+		if flag == enable {
+			enabled = true
+		} else if flag == disable {
+			enabled = false
+		}
+	}
+	return enabled
+}
+
+// cxxFeatureProperty returns the property to report a problem with a
+// feature against: the property itself if it is set, or else cxxflags.
+func cxxFeatureProperty(setting *bool, name string) string {
+	if setting != nil {
+		return name
+	}
+	return "cxxflags"
+}
+
+// checkCxxFeaturesMutator ensures that a binary or shared library built
+// without exceptions or RTTI does not link static libraries which are
+// built with them. This must run after the static libraries have been
+// resolved, so that indirect dependencies are checked too.
+//
+// GCC and Clang enable both features by default, so on Linux a static
+// library which doesn't disable them is treated as using them. Android
+// builds C++ without them unless asked to.
+func checkCxxFeaturesMutator(mctx blueprint.BottomUpMutatorContext) {
+	var props *Build
+	switch m := mctx.Module().(type) {
+	case *binary:
+		props = &m.Properties.Build
+	case *sharedLibrary:
+		props = &m.Properties.Build
+	default:
+		return
+	}
+
+	if e, ok := mctx.Module().(enableable); ok && !isEnabled(e) {
+		return
+	}
+
+	byDefault := getConfig(mctx).Properties.GetBool("builder_ninja")
+	exceptions := props.cxxFeatureEnabled("-fexceptions", "-fno-exceptions", byDefault)
+	rtti := props.cxxFeatureEnabled("-frtti", "-fno-rtti", byDefault)
+	if exceptions && rtti {
+		return
+	}
+
+	mctx.VisitDirectDeps(func(dep blueprint.Module) {
+		tag := mctx.OtherModuleDependencyTag(dep)
+		if tag != staticDepTag && tag != wholeStaticDepTag {
+			return
+		}
+		lib, ok := dep.(*staticLibrary)
+		if !ok {
+			return
+		}
+		depProps := &lib.Properties.Build
+
+		if !exceptions && depProps.cxxFeatureEnabled("-fexceptions", "-fno-exceptions", byDefault) {
+			mctx.PropertyErrorf(cxxFeatureProperty(props.Exceptions, "exceptions"),
+				"disables exceptions, but links %s, which is built with exceptions",
+				mctx.OtherModuleName(dep))
+		}
+		if !rtti && depProps.cxxFeatureEnabled("-frtti", "-fno-rtti", byDefault) {
+			mctx.PropertyErrorf(cxxFeatureProperty(props.Rtti, "rtti"),
+				"disables RTTI, but links %s, which is built with RTTI",
+				mctx.OtherModuleName(dep))
+		}
+	})
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_cxxFeatureEnabled(t *testing.T) {
+	props := &Build{}
+	assert.True(t, props.cxxFeatureEnabled("-fexceptions", "-fno-exceptions", true))
+	assert.False(t, props.cxxFeatureEnabled("-fexceptions", "-fno-exceptions", false))
+
+	props.Exceptions = proptools.BoolPtr(false)
+	assert.False(t, props.cxxFeatureEnabled("-fexceptions", "-fno-exceptions", true))
+
+	// cxxflags come after the property, so take precedence
+	props.Cxxflags = []string{"-fexceptions"}
+	assert.True(t, props.cxxFeatureEnabled("-fexceptions", "-fno-exceptions", false))
+
+	props = &Build{}
+	props.Cflags = []string{"-fno-rtti"}
+	assert.False(t, props.cxxFeatureEnabled("-frtti", "-fno-rtti", true))
+	assert.True(t, props.cxxFeatureEnabled("-fexceptions", "-fno-exceptions", true))
+}
+
+func Test_cxxFeatureProperty(t *testing.T) {
+	assert.Equal(t, "rtti", cxxFeatureProperty(proptools.BoolPtr(false), "rtti"))
+	assert.Equal(t, "cxxflags", cxxFeatureProperty(nil, "rtti"))
+}
@@ -18,6 +18,8 @@
 package core
 
 import (
+	"reflect"
+	"strings"
 	"sync"
 
 	"github.com/google/blueprint"
@@ -28,17 +30,23 @@ import (
 type defaults struct {
 	moduleBase
 
+	// The target types the defaults are split into
+	variants []tgtType
+
 	Properties struct {
 		Features
 		Build
 		KernelProps
 		// The list of default properties that should prepended to all configuration
 		Defaults []string
+		// Properties which modules using these defaults, and defaults
+		// applied after these, must not set
+		Final []string
 	}
 }
 
 func (m *defaults) supportedVariants() []tgtType {
-	return []tgtType{tgtTypeHost, tgtTypeTarget}
+	return m.variants
 }
 
 func (m *defaults) disable() {
@@ -106,6 +114,13 @@ func (m *defaults) processPaths(ctx blueprint.BaseModuleContext, g generatorBack
 }
 
 func (m *defaults) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
+	for _, name := range m.Properties.Final {
+		if _, ok := propertyValue(m.defaultableProperties(), featurePropertyName(name)); !ok {
+			ctx.PropertyErrorf("final", "%s is not a property of bob_defaults", name)
+		}
+	}
 }
 
 func (m *defaults) getEscapeProperties() []*[]string {
@@ -132,11 +147,12 @@ func (m *defaults) getMatchSourcePropNames() []string {
 }
 
 func defaultsFactory(config *bobConfig) (blueprint.Module, []interface{}) {
-	module := &defaults{}
+	module := &defaults{variants: availableTargetTypes(&config.Properties)}
 
 	module.Properties.Features.Init(&config.Properties, CommonProps{}, BuildProps{}, KernelProps{}, SplittableProps{})
 	module.Properties.Host.init(&config.Properties, CommonProps{}, BuildProps{}, KernelProps{})
 	module.Properties.Target.init(&config.Properties, CommonProps{}, BuildProps{}, KernelProps{})
+	module.Properties.Host_cross.init(&config.Properties, CommonProps{}, BuildProps{}, KernelProps{})
 
 	return module, []interface{}{&module.Properties, &module.SimpleName.Properties}
 }
@@ -199,7 +215,7 @@ func defaultDepsStage1Mutator(mctx blueprint.BottomUpMutatorContext) {
 	if gsc, ok := getGenerateCommon(mctx.Module()); ok {
 		if len(gsc.Properties.Flag_defaults) > 0 {
 			tgt := gsc.Properties.Target
-			if !(tgt == tgtTypeHost || tgt == tgtTypeTarget) {
+			if !(tgt == tgtTypeHost || tgt == tgtTypeTarget || tgt == tgtTypeHostCross) {
 				utils.Die("Module %s uses flag_defaults '%v' but has invalid target type '%s'",
 					mctx.ModuleName(), gsc.Properties.Flag_defaults, tgt)
 			}
@@ -227,7 +243,8 @@ func expandDefault(d string, visited []string) []string {
 	if len(defaultsMap[d]) > 0 {
 		for _, def := range defaultsMap[d] {
 			if utils.Find(visited, def) >= 0 {
-				utils.Die("Defaults module %s depends upon itself", def)
+				utils.Die("Defaults module %s depends upon itself: %s", def,
+					strings.Join(append(visited, def), " -> "))
 			}
 			defaults = append(defaults, expandDefault(def, append(visited, def))...)
 			defaults = append(defaults, def)
@@ -253,7 +270,7 @@ func defaultDepsStage2Mutator(mctx blueprint.BottomUpMutatorContext) {
 	if _, ok := mctx.Module().(defaultable); ok {
 
 		// Get a flattened list of the default hierarchy
-		flattenedDefaults := expandDefault(mctx.ModuleName(), []string{})
+		flattenedDefaults := expandDefault(mctx.ModuleName(), []string{mctx.ModuleName()})
 
 		var defaults []string
 
@@ -269,3 +286,56 @@ func defaultDepsStage2Mutator(mctx blueprint.BottomUpMutatorContext) {
 		mctx.AddDependency(mctx.Module(), defaultDepTag, defaults...)
 	}
 }
+
+// findDefaultsChain searches the defaults included by 'from' for the
+// defaults module 'd', returning the chain of defaults leading to it.
+func findDefaultsChain(from, d string, chain []string) []string {
+	for _, def := range defaultsMap[from] {
+		next := append(append([]string{}, chain...), def)
+		if def == d {
+			return next
+		}
+		if utils.Contains(chain, def) {
+			continue
+		}
+		if found := findDefaultsChain(def, d, next); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// defaultsChain describes how a module includes a defaults module, e.g.
+// "libfoo -> common -> project_wide".
+func defaultsChain(module, d string) string {
+	if chain := findDefaultsChain(module, d, []string{module}); chain != nil {
+		return strings.Join(chain, " -> ")
+	}
+	return module + " -> " + d
+}
+
+// propertyIsSet returns whether a property has been given a value
+func propertyIsSet(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return !v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() > 0
+	}
+	return !reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// finalPropertiesSet returns the properties in 'final' which are set in
+// a module's properties.
+func finalPropertiesSet(props []interface{}, final map[string]string) []string {
+	set := []string{}
+	for _, name := range utils.SortedKeys(final) {
+		for _, p := range props {
+			if v, ok := findPropertyField(reflect.ValueOf(p), featurePropertyName(name)); ok && propertyIsSet(v) {
+				set = append(set, name)
+				break
+			}
+		}
+	}
+	return set
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_defaultsChain(t *testing.T) {
+	defaultsMap = map[string][]string{
+		"libfoo":       {"common", "test_defaults"},
+		"common":       {"project_wide"},
+		"project_wide": {},
+		// A cycle is not followed forever
+		"test_defaults": {"test_defaults", "strict"},
+		"strict":        {},
+	}
+	defer func() { defaultsMap = map[string][]string{} }()
+
+	assert.Equal(t, "libfoo -> common -> project_wide", defaultsChain("libfoo", "project_wide"))
+	assert.Equal(t, "libfoo -> test_defaults -> strict", defaultsChain("libfoo", "strict"))
+}
+
+func Test_finalPropertiesSet(t *testing.T) {
+	enabled := false
+	common := &CommonProps{Cflags: []string{"-O0"}}
+	common.Enabled = &enabled
+	props := &BuildProps{}
+	final := map[string]string{
+		"cflags":  "strict",
+		"ldflags": "strict",
+		"enabled": "project_wide",
+	}
+
+	assert.Equal(t, []string{"cflags", "enabled"},
+		finalPropertiesSet([]interface{}{props, common}, final))
+	assert.False(t, propertyIsSet(reflect.ValueOf(props.Ldflags)))
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+func defineMismatchIsError(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["define_mismatch_error"]
+	return ok && props.GetBool("define_mismatch_error")
+}
+
+func describeMacro(macros map[string]string, name string) string {
+	if value, ok := macros[name]; ok {
+		return name + "=" + value
+	}
+	return name + " undefined"
+}
+
+// compileMacros returns the macros a library is compiled with, for C
+// and for C++ sources. The flags are in the order CompileObjs uses.
+func (l *library) compileMacros() []map[string]string {
+	cflags := utils.MergeFlags(l.Properties.Cflags, l.Properties.Export_cflags, l.exported.cflags)
+	return []map[string]string{
+		utils.MacroDefinitions(utils.NewStringSlice(cflags, l.Properties.Conlyflags)),
+		utils.MacroDefinitions(utils.NewStringSlice(cflags, l.Properties.getCxxflags())),
+	}
+}
+
+// macroMismatches compares the macros a library exports with the
+// macros a module is compiled with, describing each macro whose value
+// differs.
+func macroMismatches(exporter, user string, exported map[string]string,
+	compiled []map[string]string) []string {
+	mismatches := []string{}
+	for _, name := range utils.SortedKeys(exported) {
+		for _, macros := range compiled {
+			if value, ok := macros[name]; !ok || value != exported[name] {
+				mismatches = append(mismatches, fmt.Sprintf("%s exports %s, but %s is compiled with %s",
+					exporter, describeMacro(exported, name), user, describeMacro(macros, name)))
+				break
+			}
+		}
+	}
+	return mismatches
+}
+
+// checkDefinesMutator reports libraries which are compiled with a
+// different value for a macro than they, or one of the libraries they
+// use, export in export_cflags. Code built with different values can
+// disagree on the layout of structures, or the functions available,
+// and fail at runtime rather than at link time.
+func checkDefinesMutator(mctx blueprint.BottomUpMutatorContext) {
+	l, ok := getLibrary(mctx.Module())
+	if !ok || !isEnabled(l) {
+		return
+	}
+	if len(l.Properties.getSources(mctx)) == 0 {
+		// Nothing is compiled
+		return
+	}
+
+	compiled := l.compileMacros()
+
+	reports := macroMismatches(mctx.ModuleName(), "it",
+		utils.MacroDefinitions(l.Properties.Export_cflags), compiled)
+
+	visited := map[string]bool{}
+	mctx.VisitDirectDeps(func(dep blueprint.Module) {
+		switch mctx.OtherModuleDependencyTag(dep) {
+		case headerDepTag, wholeStaticDepTag, staticDepTag, sharedDepTag, reexportLibsTag:
+		default:
+			return
+		}
+		pe, ok := dep.(propertyExporter)
+		if !ok || visited[dep.Name()] {
+			return
+		}
+		visited[dep.Name()] = true
+
+		reports = append(reports, macroMismatches(dep.Name(), mctx.ModuleName(),
+			utils.MacroDefinitions(pe.exportCflags()), compiled)...)
+	})
+
+	if len(reports) == 0 {
+		return
+	}
+	sort.Strings(reports)
+
+	for _, report := range reports {
+		if defineMismatchIsError(getConfig(mctx)) {
+			mctx.ModuleErrorf("inconsistent define: %s", report)
+		} else {
+			utils.Warnf(mctx.BlueprintsFile(), "inconsistent define: %s", report)
+		}
+	}
+}
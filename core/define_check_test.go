@@ -0,0 +1,39 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_macroMismatches(t *testing.T) {
+	exported := map[string]string{"ABI": "2", "DEBUG": "1", "SAME": "x"}
+	compiled := []map[string]string{
+		{"ABI": "2", "DEBUG": "1", "SAME": "x"},
+		{"ABI": "1", "SAME": "x"},
+	}
+
+	assert.Equal(t, []string{
+		"libfoo exports ABI=2, but libbar is compiled with ABI=1",
+		"libfoo exports DEBUG=1, but libbar is compiled with DEBUG undefined",
+	}, macroMismatches("libfoo", "libbar", exported, compiled))
+
+	assert.Empty(t, macroMismatches("libfoo", "libbar", exported, compiled[:1]))
+}
@@ -0,0 +1,305 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// dependencyCycles returns the shortest cycle through the lowest named
+// node of each strongly connected component of a graph that contains a
+// cycle. Each cycle starts and ends with the same node. Nodes and edges
+// are visited in sorted order, so the result is stable.
+func dependencyCycles(graph map[string][]string) [][]string {
+	nodes := []string{}
+	sorted := map[string][]string{}
+	for node, deps := range graph {
+		nodes = append(nodes, node)
+		sorted[node] = append([]string{}, deps...)
+		sort.Strings(sorted[node])
+	}
+	sort.Strings(nodes)
+	graph = sorted
+
+	// Tarjan's algorithm
+	index := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	components := [][]string{}
+
+	var connect func(string)
+	connect = func(node string) {
+		index[node] = len(index)
+		lowlink[node] = index[node]
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, dep := range graph[node] {
+			if _, visited := index[dep]; !visited {
+				connect(dep)
+				if lowlink[dep] < lowlink[node] {
+					lowlink[node] = lowlink[dep]
+				}
+			} else if onStack[dep] && index[dep] < lowlink[node] {
+				lowlink[node] = index[dep]
+			}
+		}
+
+		if lowlink[node] == index[node] {
+			component := []string{}
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == node {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for _, node := range nodes {
+		if _, visited := index[node]; !visited {
+			connect(node)
+		}
+	}
+
+	cycles := [][]string{}
+	for _, component := range components {
+		sort.Strings(component)
+		start := component[0]
+		if len(component) == 1 && !utils.Contains(graph[start], start) {
+			continue
+		}
+		cycles = append(cycles, shortestCycle(graph, start, component))
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// shortestCycle finds the shortest path from start back to itself,
+// using a breadth first search limited to the nodes in its component.
+func shortestCycle(graph map[string][]string, start string, component []string) []string {
+	parent := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, dep := range graph[node] {
+			if dep == start {
+				cycle := []string{start}
+				for n := node; n != start; n = parent[n] {
+					cycle = append([]string{n}, cycle...)
+				}
+				return append([]string{start}, cycle...)
+			}
+			if _, seen := parent[dep]; seen || !utils.Contains(component, dep) {
+				continue
+			}
+			parent[dep] = node
+			queue = append(queue, dep)
+		}
+	}
+	return nil
+}
+
+// dependencyEdge is a dependency which a property of a module will
+// add, named as written in the property.
+type dependencyEdge struct {
+	dep      string
+	tag      dependencyTag
+	property string
+}
+
+// dependencyCycleHandler finds dependency cycles before the
+// dependencies are added, so that they can be reported with the
+// property creating each dependency. Blueprint would otherwise only
+// report the modules involved.
+type dependencyCycleHandler struct {
+	lock    sync.Mutex
+	edges   map[string][]dependencyEdge
+	targets map[string]tgtType
+	once    sync.Once
+	// The description of each cycle, keyed by its first module
+	cycles map[string]string
+}
+
+func newDependencyCycleHandler() *dependencyCycleHandler {
+	return &dependencyCycleHandler{
+		edges:   map[string][]dependencyEdge{},
+		targets: map[string]tgtType{},
+	}
+}
+
+// dependencyNodeName names a variant of a module in the graph
+func dependencyNodeName(name string, target tgtType) string {
+	if target == "" {
+		return name
+	}
+	return name + " (" + string(target) + ")"
+}
+
+func dependencyModuleTarget(m blueprint.Module) tgtType {
+	if s, ok := m.(splittable); ok {
+		return s.getTarget()
+	}
+	return ""
+}
+
+// moduleDependencyEdges returns the dependencies that the depender
+// mutators will add for a module
+func moduleDependencyEdges(m blueprint.Module) []dependencyEdge {
+	edges := []dependencyEdge{}
+	add := func(tag dependencyTag, property string, deps ...string) {
+		for _, dep := range deps {
+			edges = append(edges, dependencyEdge{dep, tag, property})
+		}
+	}
+
+	if l, ok := getLibrary(m); ok {
+		props := &l.Properties
+		add(wholeStaticDepTag, "whole_static_libs", props.Whole_static_libs...)
+		add(staticDepTag, "static_libs", props.Static_libs...)
+		add(headerDepTag, "header_libs", props.Header_libs...)
+		add(headerDepTag, "export_header_libs", props.Export_header_libs...)
+		add(sharedDepTag, "shared_libs", props.Shared_libs...)
+		add(generatedSourceTag, "generated_sources", props.Generated_sources...)
+		add(generatedHeaderTag, "generated_headers", props.Generated_headers...)
+		add(exportGeneratedHeaderTag, "export_generated_headers", props.Export_generated_headers...)
+		add(generatedDepTag, "generated_deps", props.Generated_deps...)
+	}
+	if km, ok := m.(*kernelModule); ok {
+		add(kernelModuleDepTag, "extra_symbols", km.Properties.Extra_symbols...)
+	}
+	if gsc, ok := getGenerateCommon(m); ok {
+		if gsc.Properties.Host_bin != nil {
+			add(hostToolBinTag, "host_bin", proptools.String(gsc.Properties.Host_bin))
+		}
+		add(generatedDepTag, "generated_deps", gsc.Properties.Generated_deps...)
+		add(generatedSourceTag, "generated_sources", gsc.Properties.Generated_sources...)
+	}
+	if ins, ok := m.(installable); ok {
+		add(installDepTag, "install_deps", ins.getInstallableProps().Install_deps...)
+	}
+	if a, ok := m.(*alias); ok {
+		add(aliasTag, "srcs", a.Properties.Srcs...)
+	}
+	return edges
+}
+
+// collectDependenciesMutator records the dependencies of each enabled
+// module. It must run after all properties which add dependencies have
+// been finalized, and before any of the dependencies are added.
+func (h *dependencyCycleHandler) collectDependenciesMutator(mctx blueprint.BottomUpMutatorContext) {
+	m := mctx.Module()
+	if _, ok := m.(*defaults); ok {
+		return
+	}
+	if e, ok := m.(enableable); ok && !isEnabled(e) {
+		return
+	}
+
+	target := dependencyModuleTarget(m)
+	node := dependencyNodeName(mctx.ModuleName(), target)
+	edges := moduleDependencyEdges(m)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.edges[node] = edges
+	h.targets[node] = target
+}
+
+// resolve returns the variants of a module that a dependency refers
+// to. As when the dependency is added, this is the variant matching
+// the depending module, unless variants are given after a ':'.
+func (h *dependencyCycleHandler) resolve(target tgtType, dep string) []string {
+	targets := []tgtType{target}
+	if idx := strings.LastIndex(dep, ":"); idx > 0 {
+		targets = []tgtType{}
+		for _, t := range strings.Split(dep[idx+1:], ",") {
+			targets = append(targets, tgtType(t))
+		}
+		dep = dep[:idx]
+	}
+
+	nodes := []string{}
+	for _, t := range targets {
+		if _, ok := h.edges[dependencyNodeName(dep, t)]; ok {
+			nodes = append(nodes, dependencyNodeName(dep, t))
+		} else if _, ok := h.edges[dep]; ok {
+			nodes = append(nodes, dep)
+		}
+	}
+	return nodes
+}
+
+// findCycles builds the dependency graph once every module has been
+// recorded, and describes the cycles in it.
+func (h *dependencyCycleHandler) findCycles() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	graph := map[string][]string{}
+	via := map[[2]string]dependencyEdge{}
+	for node, edges := range h.edges {
+		graph[node] = []string{}
+		for _, edge := range edges {
+			for _, dep := range h.resolve(h.targets[node], edge.dep) {
+				key := [2]string{node, dep}
+				if _, ok := via[key]; !ok {
+					graph[node] = append(graph[node], dep)
+					via[key] = edge
+				}
+			}
+		}
+	}
+
+	h.cycles = map[string]string{}
+	for _, cycle := range dependencyCycles(graph) {
+		lines := []string{}
+		for i := 0; i+1 < len(cycle); i++ {
+			edge := via[[2]string{cycle[i], cycle[i+1]}]
+			lines = append(lines, fmt.Sprintf("    %s -> %s %s, from %s",
+				cycle[i], edge.tag.name, cycle[i+1], edge.property))
+		}
+		h.cycles[cycle[0]] = strings.Join(lines, "\n")
+	}
+}
+
+// checkCyclesMutator reports each cycle against its first module.
+// The graph is only complete once all modules have been recorded, so
+// this must be a separate mutator.
+func (h *dependencyCycleHandler) checkCyclesMutator(mctx blueprint.BottomUpMutatorContext) {
+	h.once.Do(h.findCycles)
+
+	node := dependencyNodeName(mctx.ModuleName(), dependencyModuleTarget(mctx.Module()))
+	if cycle, ok := h.cycles[node]; ok {
+		mctx.ModuleErrorf("is part of a dependency cycle:\n%s", cycle)
+	}
+}
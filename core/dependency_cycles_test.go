@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dependencyCycles(t *testing.T) {
+	graph := map[string][]string{
+		// a -> b -> c -> a, with the shorter a -> c -> a
+		"a":    {"b", "c"},
+		"b":    {"c"},
+		"c":    {"a", "d"},
+		"d":    {"e"},
+		"e":    {},
+		"self": {"self"},
+		"x":    {"y", "missing"},
+		"y":    {"x"},
+	}
+
+	assert.Equal(t, [][]string{
+		{"a", "c", "a"},
+		{"self", "self"},
+		{"x", "y", "x"},
+	}, dependencyCycles(graph))
+}
+
+func Test_dependencyCycles_acyclic(t *testing.T) {
+	assert.Empty(t, dependencyCycles(map[string][]string{
+		"a": {"b", "c"},
+		"b": {"c"},
+		"c": {},
+	}))
+}
+
+func Test_dependencyCycleHandler_findCycles(t *testing.T) {
+	h := newDependencyCycleHandler()
+	record := func(name string, target tgtType, edges ...dependencyEdge) {
+		node := dependencyNodeName(name, target)
+		h.edges[node] = edges
+		h.targets[node] = target
+	}
+
+	record("libfoo", tgtTypeTarget,
+		dependencyEdge{"libbar", staticDepTag, "static_libs"})
+	record("libbar", tgtTypeTarget,
+		dependencyEdge{"gen_bar", generatedHeaderTag, "generated_headers"})
+	record("gen_bar", "",
+		dependencyEdge{"libfoo:target", generatedDepTag, "generated_deps"})
+	// The host variants don't form a cycle, as gen_bar uses the target
+	// variant of libfoo
+	record("libfoo", tgtTypeHost,
+		dependencyEdge{"libbar", staticDepTag, "static_libs"})
+	record("libbar", tgtTypeHost)
+
+	h.findCycles()
+
+	assert.Equal(t, map[string]string{
+		"gen_bar": "    gen_bar -> generated_dep libfoo (target), from generated_deps\n" +
+			"    libfoo (target) -> static libbar (target), from static_libs\n" +
+			"    libbar (target) -> generated_headers gen_bar, from generated_headers",
+	}, h.cycles)
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// DeviceTreeProps describes the properties of the bob_dtb and bob_dtbo
+// modules
+type DeviceTreeProps struct {
+	SourceProps
+	IncludeDirsProps
+	InstallableProps
+	EnableableProps
+	AliasableProps
+
+	// Flags passed to the C preprocessor, such as defines
+	Cpp_flags []string
+	// Flags passed to dtc
+	Dtc_flags []string
+	// Whether the sources are run through the C preprocessor before dtc,
+	// so that they can #include headers. Defaults to true.
+	Preprocess *bool
+}
+
+// Type representing each bob_dtb and bob_dtbo module. Each source is
+// compiled to a device tree blob, or to an overlay for bob_dtbo.
+type deviceTree struct {
+	moduleBase
+	simpleOutputProducer
+	Properties struct {
+		DeviceTreeProps
+		Features
+	}
+
+	// Whether the outputs are overlays, applied on top of a base
+	// device tree by the bootloader
+	overlay bool
+}
+
+var _ featurable = (*deviceTree)(nil)
+var _ enableable = (*deviceTree)(nil)
+var _ aliasable = (*deviceTree)(nil)
+var _ installable = (*deviceTree)(nil)
+var _ pathProcessor = (*deviceTree)(nil)
+
+func (m *deviceTree) features() *Features {
+	return &m.Properties.Features
+}
+
+func (m *deviceTree) featurableProperties() []interface{} {
+	return []interface{}{&m.Properties.DeviceTreeProps}
+}
+
+func (m *deviceTree) getEnableableProps() *EnableableProps {
+	return &m.Properties.EnableableProps
+}
+
+func (m *deviceTree) getAliasList() []string {
+	return m.Properties.getAliasList()
+}
+
+func (m *deviceTree) getInstallableProps() *InstallableProps {
+	return &m.Properties.InstallableProps
+}
+
+func (m *deviceTree) getInstallDepPhonyNames(ctx blueprint.ModuleContext) []string {
+	return getShortNamesForDirectDepsWithTags(ctx, installDepTag)
+}
+
+func (m *deviceTree) filesToInstall(ctx blueprint.BaseModuleContext) []string {
+	return m.outputs()
+}
+
+func (m *deviceTree) shortName() string {
+	return m.Name()
+}
+
+func (m *deviceTree) altName() string {
+	return m.Name()
+}
+
+func (m *deviceTree) altShortName() string {
+	return m.shortName()
+}
+
+func (m *deviceTree) processPaths(ctx blueprint.BaseModuleContext, g generatorBackend) {
+	prefix := projectModuleDir(ctx)
+
+	m.Properties.SourceProps.processPaths(ctx, g)
+	m.Properties.InstallableProps.processPaths(ctx, g)
+	m.Properties.IncludeDirsProps.Local_include_dirs =
+		utils.PrefixDirs(m.Properties.IncludeDirsProps.Local_include_dirs, prefix)
+}
+
+func (m *deviceTree) preprocess() bool {
+	return proptools.BoolDefault(m.Properties.Preprocess, true)
+}
+
+// outputExt returns the extension of the compiled device trees
+func (m *deviceTree) outputExt() string {
+	if m.overlay {
+		return ".dtbo"
+	}
+	return ".dtb"
+}
+
+// outputFileNames returns the name of the blob compiled from each
+// source, reporting sources which aren't device tree sources, and
+// sources whose blobs would have the same name.
+func (m *deviceTree) outputFileNames(ctx blueprint.ModuleContext, srcs []string) []string {
+	names := []string{}
+	sources := map[string]string{}
+
+	for _, src := range srcs {
+		ext := filepath.Ext(src)
+		if ext != ".dts" && ext != ".dtso" {
+			ctx.PropertyErrorf("srcs", "%s is not a .dts or .dtso file", src)
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(src), ext) + m.outputExt()
+		if other, ok := sources[name]; ok {
+			ctx.PropertyErrorf("srcs", "%s and %s both compile to %s", other, src, name)
+			continue
+		}
+		sources[name] = src
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// dtcTool returns the device tree compiler, set by DTC_BINARY
+func dtcTool(config *bobConfig) string {
+	if _, ok := config.Properties.properties["dtc_binary"]; ok {
+		if dtc := config.Properties.GetString("dtc_binary"); dtc != "" {
+			return dtc
+		}
+	}
+	return "dtc"
+}
+
+// Called by Blueprint to generate the rules associated with the module.
+// This is forwarded to the backend to handle.
+func (m *deviceTree) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
+	if isEnabled(m) {
+		getBackend(ctx).deviceTreeActions(m, ctx)
+	}
+}
+
+// Create the structure representing the bob_dtb
+func deviceTreeFactory(config *bobConfig) (blueprint.Module, []interface{}) {
+	module := &deviceTree{}
+	module.Properties.Features.Init(&config.Properties, DeviceTreeProps{})
+	return module, []interface{}{&module.Properties, &module.SimpleName.Properties}
+}
+
+// Create the structure representing the bob_dtbo
+func deviceTreeOverlayFactory(config *bobConfig) (blueprint.Module, []interface{}) {
+	module, props := deviceTreeFactory(config)
+	module.(*deviceTree).overlay = true
+	return module, props
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_deviceTree_outputExt(t *testing.T) {
+	m := &deviceTree{}
+	assert.Equal(t, ".dtb", m.outputExt())
+
+	m.overlay = true
+	assert.Equal(t, ".dtbo", m.outputExt())
+}
+
+func Test_deviceTree_preprocess(t *testing.T) {
+	m := &deviceTree{}
+	assert.True(t, m.preprocess())
+
+	disabled := false
+	m.Properties.Preprocess = &disabled
+	assert.False(t, m.preprocess())
+}
+
+func Test_dtcTool(t *testing.T) {
+	config := &bobConfig{}
+	config.Properties.properties = map[string]interface{}{}
+	assert.Equal(t, "dtc", dtcTool(config))
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+// disablingFeature returns the name of the last enabled feature which
+// sets `enabled: false`, as that is the value the module ends up with,
+// or "" if no feature does.
+func disablingFeature(f *Features, props *configProperties) string {
+	if f.BlueprintEmbed == nil {
+		return ""
+	}
+	featuresData := reflect.ValueOf(f.BlueprintEmbed).Elem()
+
+	disabledBy := ""
+	for _, featureKey := range props.featureList {
+		if !props.features[featureKey] {
+			continue
+		}
+		featureStruct := featuresData.FieldByName(featurePropertyName(featureKey))
+		if !featureStruct.IsValid() {
+			continue
+		}
+		enabled, ok := findPropertyField(featureStruct.FieldByName("BlueprintEmbed"), "Enabled")
+		if !ok || enabled.IsNil() {
+			continue
+		}
+		if enabled.Elem().Bool() {
+			disabledBy = ""
+		} else {
+			disabledBy = strings.ToUpper(featureKey)
+		}
+	}
+	return disabledBy
+}
+
+// recordDisablingFeature records why a module which is disabled once
+// features are applied was disabled, when a feature did it.
+func recordDisablingFeature(m featurable, props *configProperties) {
+	e, ok := m.(enableable)
+	if !ok || isEnabled(e) {
+		return
+	}
+	if feature := disablingFeature(m.features(), props); feature != "" {
+		e.getEnableableProps().Disabled_reason = "is disabled by the " + feature + " feature"
+	}
+}
+
+// disabledModuleDescription names a disabled module, with the reason
+// it is disabled.
+func disabledModuleDescription(m blueprint.Module) string {
+	reason := "sets enabled: false"
+	if e, ok := m.(enableable); ok && e.getEnableableProps().Disabled_reason != "" {
+		reason = e.getEnableableProps().Disabled_reason
+	}
+	return fmt.Sprintf("%s, which %s", m.Name(), reason)
+}
+
+// autoDisableDependents returns whether modules which are required, but
+// depend on disabled modules, are disabled rather than failing
+// generation, as set by AUTO_DISABLE_DEPENDENTS.
+func autoDisableDependents(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["auto_disable_dependents"]
+	return ok && props.GetBool("auto_disable_dependents")
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEnableableProps struct {
+	EnableableProps
+}
+
+func Test_disablingFeature(t *testing.T) {
+	properties := enabledFeatures("debug", "no_gpu", "simulator")
+	var features Features
+	features.Init(&properties, testEnableableProps{})
+	assert.Equal(t, "", disablingFeature(&features, &properties))
+
+	features.injectData("No_gpu", "EnableableProps.Enabled", proptools.BoolPtr(false))
+	assert.Equal(t, "NO_GPU", disablingFeature(&features, &properties))
+
+	// A later feature enabling the module again takes precedence
+	features.injectData("Simulator", "EnableableProps.Enabled", proptools.BoolPtr(true))
+	assert.Equal(t, "", disablingFeature(&features, &properties))
+}
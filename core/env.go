@@ -0,0 +1,99 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// EnvProps sets environment variables for the commands of a module
+type EnvProps struct {
+	// Environment variables set when running the module's commands,
+	// as NAME=value. The value is used as is, without expanding
+	// variables or splitting it into words.
+	Env []string
+}
+
+// parseEnvEntry splits an entry of an env property into the name and
+// value of the variable
+func parseEnvEntry(entry string) (name, value string, err error) {
+	parts := strings.SplitN(entry, "=", 2)
+	if len(parts) != 2 || !envNameRegexp.MatchString(parts[0]) {
+		return "", "", fmt.Errorf("'%s' is not NAME=value", entry)
+	}
+	if strings.ContainsAny(parts[1], "\n\r") {
+		return "", "", fmt.Errorf("the value of %s contains a newline", parts[0])
+	}
+	return parts[0], parts[1], nil
+}
+
+// checkEnv reports the entries which are not NAME=value. It is called
+// once per module, so that the backends can ignore them.
+func (e *EnvProps) checkEnv(ctx blueprint.BaseModuleContext) {
+	for _, entry := range e.Env {
+		if _, _, err := parseEnvEntry(entry); err != nil {
+			ctx.PropertyErrorf("env", "%v", err)
+		}
+	}
+}
+
+// shellQuote quotes a word for the shell, and escapes $, which Ninja,
+// Make and Soong all expand in commands.
+func shellQuote(s string) string {
+	s = "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+	return strings.Replace(s, "$", "$$", -1)
+}
+
+// envAssignments returns the NAME='value' words setting the variables
+func (e *EnvProps) envAssignments() []string {
+	words := []string{}
+	for _, entry := range e.Env {
+		if name, value, err := parseEnvEntry(entry); err == nil {
+			words = append(words, name+"="+shellQuote(value))
+		}
+	}
+	return words
+}
+
+// envExportPrefix returns the prefix of a shell command line which
+// exports the variables to every command on the line, or an empty
+// string when no variables are set.
+func (e *EnvProps) envExportPrefix() string {
+	words := e.envAssignments()
+	if len(words) == 0 {
+		return ""
+	}
+	return "export " + strings.Join(words, " ") + "; "
+}
+
+// envCommandPrefix returns the prefix of a single command which runs
+// it with the variables set. As it is a command, it can follow other
+// wrappers, which then run with the variables set too.
+func (e *EnvProps) envCommandPrefix() string {
+	words := e.envAssignments()
+	if len(words) == 0 {
+		return ""
+	}
+	return "env " + strings.Join(words, " ")
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseEnvEntry(t *testing.T) {
+	name, value, err := parseEnvEntry("LC_ALL=C")
+	assert.NoError(t, err)
+	assert.Equal(t, "LC_ALL", name)
+	assert.Equal(t, "C", value)
+
+	name, value, err = parseEnvEntry("FLAGS=-a=b -c")
+	assert.NoError(t, err)
+	assert.Equal(t, "FLAGS", name)
+	assert.Equal(t, "-a=b -c", value)
+
+	_, _, err = parseEnvEntry("FOO")
+	assert.EqualError(t, err, "'FOO' is not NAME=value")
+	_, _, err = parseEnvEntry("1FOO=bar")
+	assert.Error(t, err)
+	_, _, err = parseEnvEntry("FOO=a\nb")
+	assert.EqualError(t, err, "the value of FOO contains a newline")
+}
+
+func Test_envPrefixes(t *testing.T) {
+	e := EnvProps{}
+	assert.Equal(t, "", e.envExportPrefix())
+	assert.Equal(t, "", e.envCommandPrefix())
+
+	e.Env = []string{"A=it's", "B=$HOME", "bad"}
+	assert.Equal(t, `export A='it'\''s' B='$$HOME'; `, e.envExportPrefix())
+	assert.Equal(t, `env A='it'\''s' B='$$HOME'`, e.envCommandPrefix())
+}
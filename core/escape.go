@@ -18,15 +18,29 @@
 package core
 
 import (
+	"strings"
+
 	"github.com/google/blueprint"
 
 	"github.com/ARM-software/bob-build/internal/escape"
+	"github.com/ARM-software/bob-build/internal/utils"
 )
 
 type propertyEscapeInterface interface {
 	getEscapeProperties() []*[]string
 }
 
+// auditEscapedValues warns about values containing characters which are
+// escaped, but are unlikely to be intended
+func auditEscapedValues(moduleName string, values []string) {
+	for _, value := range values {
+		if found := escape.Suspicious(value); len(found) > 0 {
+			utils.Warnf(moduleName, "suspicious characters %s in %q",
+				strings.Join(found, " "), value)
+		}
+	}
+}
+
 func escapeMutator(mctx blueprint.TopDownMutatorContext) {
 	// This mutator is not registered on the androidbp backend, as it
 	// doesn't need escaping
@@ -49,8 +63,12 @@ func escapeMutator(mctx blueprint.TopDownMutatorContext) {
 	// Escape libraries as well as generator modules
 	if m, ok := module.(propertyEscapeInterface); ok {
 		escapeProps := m.getEscapeProperties()
+		audit := strictPathsEnabled(&getConfig(mctx).Properties)
 
 		for _, prop := range escapeProps {
+			if audit {
+				auditEscapedValues(mctx.ModuleName(), *prop)
+			}
 			// If the flags contain template sequences, we avoid escaping those
 			*prop = escape.EscapeTemplatedStringList(*prop, g.escapeFlag)
 		}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// eventLogHandler writes a JSON object per line describing what
+// happens during generation, for CI systems to ingest. Each event is
+// written as soon as it happens, so that the events leading to a
+// failure are kept.
+type eventLogHandler struct {
+	start time.Time
+
+	lock            sync.Mutex
+	file            *os.File
+	configLoaded    time.Time
+	lastModule      time.Time
+	modules         int
+	disabledModules int
+	warnings        int
+}
+
+// initEventLog starts the event log, when BOB_EVENT_LOG, set by the
+// --event-log option of bootstrap, names a file.
+func initEventLog(start time.Time) *eventLogHandler {
+	out, present := os.LookupEnv("BOB_EVENT_LOG")
+	if !present || out == "" {
+		return nil
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		utils.Warnf("", "Unable to write event log %s: %v", out, err)
+		return nil
+	}
+
+	h := &eventLogHandler{start: start, file: file}
+	h.emit("start", map[string]interface{}{"config": configJSONFile})
+	utils.ObserveLog(h.logObserver)
+	return h
+}
+
+func millisecondsSince(start, end time.Time) int64 {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start).Nanoseconds() / 1000000
+}
+
+// emit writes an event. Must not be called with the lock held.
+func (h *eventLogHandler) emit(event string, fields map[string]interface{}) {
+	now := time.Now()
+	fields["event"] = event
+	fields["time"] = now.Format(time.RFC3339Nano)
+	fields["elapsed_ms"] = millisecondsSince(h.start, now)
+
+	content, err := json.Marshal(fields)
+	if err != nil {
+		utils.Die("%v", err)
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.file != nil {
+		h.file.Write(append(content, '\n'))
+	}
+}
+
+func (h *eventLogHandler) logObserver(level utils.LogLevel, context, msg string) {
+	event := "warning"
+	if level == utils.LogError {
+		event = "error"
+	} else {
+		h.lock.Lock()
+		h.warnings++
+		h.lock.Unlock()
+	}
+
+	fields := map[string]interface{}{"message": msg}
+	if context != "" {
+		fields["context"] = context
+	}
+	h.emit(event, fields)
+}
+
+func (h *eventLogHandler) setConfigLoaded() {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	h.configLoaded = time.Now()
+	h.lock.Unlock()
+	h.emit("config_loaded", map[string]interface{}{})
+}
+
+// eventLogMutator records each module variant once all other mutators
+// have processed it, and whether it is disabled, in which case no build
+// actions are generated for it.
+func (h *eventLogHandler) eventLogMutator(mctx blueprint.BottomUpMutatorContext) {
+	m := mctx.Module()
+	if _, ok := m.(*defaults); ok {
+		return
+	}
+
+	enabled := true
+	if e, ok := m.(enableable); ok {
+		enabled = isEnabled(e)
+	}
+
+	fields := map[string]interface{}{
+		"name":    mctx.ModuleName(),
+		"type":    mctx.ModuleType(),
+		"file":    mctx.BlueprintsFile(),
+		"enabled": enabled,
+	}
+	if s, ok := m.(splittable); ok {
+		fields["variant"] = string(s.getTarget())
+	}
+
+	h.lock.Lock()
+	h.modules++
+	if !enabled {
+		h.disabledModules++
+	}
+	h.lastModule = time.Now()
+	h.lock.Unlock()
+
+	h.emit("module", fields)
+}
+
+// finish writes the totals and the time taken by each phase, once
+// Blueprint has written the build files. A generation which fails ends
+// with an error event instead.
+func (h *eventLogHandler) finish() {
+	if h == nil {
+		return
+	}
+	now := time.Now()
+
+	h.lock.Lock()
+	fields := map[string]interface{}{
+		"modules":          h.modules,
+		"disabled_modules": h.disabledModules,
+		"warnings":         h.warnings,
+		"phases_ms": map[string]int64{
+			"load_config":       millisecondsSince(h.start, h.configLoaded),
+			"process_modules":   millisecondsSince(h.configLoaded, h.lastModule),
+			"write_build_files": millisecondsSince(h.lastModule, now),
+		},
+	}
+	h.lock.Unlock()
+	h.emit("finish", fields)
+
+	utils.ObserveLog(nil)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if err := h.file.Close(); err != nil {
+		utils.Warnf("", "Failed to write event log: %v", err)
+	}
+	h.file = nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+func Test_eventLogHandler_writes_a_line_per_event(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bob_events")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "events.jsonl")
+
+	file, err := os.Create(filename)
+	assert.Nil(t, err)
+	h := &eventLogHandler{start: time.Now(), file: file}
+	utils.ObserveLog(h.logObserver)
+
+	h.setConfigLoaded()
+	utils.Warnf("libfoo", "glob %s matched no files", "*.S")
+	h.modules = 2
+	h.disabledModules = 1
+	h.finish()
+
+	// Nothing is written once the log is finished
+	utils.Warnf("", "after finish")
+
+	content, err := ioutil.ReadFile(filename)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Equal(t, 3, len(lines))
+
+	events := []map[string]interface{}{}
+	for _, line := range lines {
+		event := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &event), line)
+		events = append(events, event)
+	}
+
+	assert.Equal(t, "config_loaded", events[0]["event"])
+	assert.Equal(t, "warning", events[1]["event"])
+	assert.Equal(t, "libfoo", events[1]["context"])
+	assert.Equal(t, "glob *.S matched no files", events[1]["message"])
+	assert.Equal(t, "finish", events[2]["event"])
+	assert.Equal(t, float64(2), events[2]["modules"])
+	assert.Equal(t, float64(1), events[2]["disabled_modules"])
+	assert.Equal(t, float64(1), events[2]["warnings"])
+	assert.Contains(t, events[2], "phases_ms")
+}
@@ -0,0 +1,427 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+var (
+	explainModule   string
+	explainProperty string
+)
+
+func init() {
+	flag.StringVar(&explainModule, "explain-module", "",
+		"Module whose property is explained. No build files are written")
+	flag.StringVar(&explainProperty, "explain-property", "",
+		"Property to explain, e.g. cflags")
+}
+
+// Descriptions of the mutators which change property values, used to
+// say how an element was changed. Other mutators are named as they are.
+var explainStages = map[string]string{
+	"features_applier":      "feature",
+	"template_applier":      "template",
+	"target":                "host, target or host_cross block",
+	"process_paths":         "module directory prefix",
+	"default_applier":       "defaults",
+	"escape_mutator":        "escaping",
+	"late_template_mutator": "late template",
+}
+
+// explainElement is an element of a property's value, and the steps
+// which produced it, starting with where it was first set.
+type explainElement struct {
+	value   string
+	origins []string
+}
+
+// explainHandler follows the value of one property of a module, and of
+// every defaults module, through the mutators, recording where each
+// element of the value came from.
+type explainHandler struct {
+	module   string
+	property string
+	field    string
+
+	lock sync.Mutex
+	// The elements of the value, for each variant of each module,
+	// keyed by name and variant
+	values map[string][]explainElement
+}
+
+func initExplainHandler() *explainHandler {
+	if explainModule == "" && explainProperty == "" {
+		return nil
+	}
+	if explainModule == "" || explainProperty == "" {
+		utils.Die("--explain-module and --explain-property must be used together")
+	}
+
+	return &explainHandler{
+		module:   explainModule,
+		property: explainProperty,
+		field:    featurePropertyName(explainProperty),
+		values:   map[string][]explainElement{},
+	}
+}
+
+// findPropertyField looks for a property in a property structure and
+// the structures embedded in it. Feature blocks and host and target
+// blocks are not searched, as they are applied by mutators.
+func findPropertyField(v reflect.Value, field string) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Name == "BlueprintEmbed" {
+			continue
+		}
+		if f.Name == field {
+			return v.Field(i), true
+		}
+		switch f.Type {
+		case reflect.TypeOf(Features{}), reflect.TypeOf(TargetSpecific{}):
+			continue
+		}
+		if f.Type.Kind() == reflect.Struct {
+			if found, ok := findPropertyField(v.Field(i), field); ok {
+				return found, true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// explainValue returns the value of a property as a list, holding a
+// single element for properties which are not lists.
+func explainValue(v reflect.Value) []string {
+	switch v.Kind() {
+	case reflect.Slice:
+		if s, ok := v.Interface().([]string); ok {
+			return append([]string{}, s...)
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return []string{}
+		}
+		return explainValue(v.Elem())
+	case reflect.String:
+		return []string{v.String()}
+	}
+	return []string{fmt.Sprint(v.Interface())}
+}
+
+// propertyValue returns the value of a property of a module, and
+// whether the module has it.
+func propertyValue(props []interface{}, field string) ([]string, bool) {
+	for _, p := range props {
+		if f, ok := findPropertyField(reflect.ValueOf(p), field); ok {
+			return explainValue(f), true
+		}
+	}
+	return nil, false
+}
+
+func explainVariant(m blueprint.Module) string {
+	if s, ok := m.(splittable); ok {
+		return string(s.getTarget())
+	}
+	return ""
+}
+
+func (h *explainHandler) key(name string, m blueprint.Module) string {
+	return name + ":" + explainVariant(m)
+}
+
+func (h *explainHandler) moduleValue(mctx blueprint.BaseModuleContext) ([]string, bool) {
+	m := mctx.Module()
+	_, isDefaults := m.(*defaults)
+	if mctx.ModuleName() != h.module && !isDefaults {
+		return nil, false
+	}
+	if f, ok := m.(featurable); ok {
+		return propertyValue(f.featurableProperties(), h.field)
+	}
+	return nil, false
+}
+
+// previous returns the elements last recorded for a module. Once a
+// module has been split, its variants start from the elements of the
+// module before the split.
+func (h *explainHandler) previous(mctx blueprint.BaseModuleContext) ([]explainElement, bool) {
+	if prev, ok := h.values[h.key(mctx.ModuleName(), mctx.Module())]; ok {
+		return prev, true
+	}
+	prev, ok := h.values[mctx.ModuleName()+":"]
+	return prev, ok
+}
+
+// featureOrigin returns the enabled feature whose block sets an element
+func (h *explainHandler) featureOrigin(mctx blueprint.BaseModuleContext, value string) string {
+	f, ok := mctx.Module().(featurable)
+	if !ok || f.features().BlueprintEmbed == nil {
+		return ""
+	}
+	props := &getConfig(mctx).Properties
+	blocks := reflect.ValueOf(f.features().BlueprintEmbed).Elem()
+	for _, name := range props.featureList {
+		if !props.features[name] {
+			continue
+		}
+		block := blocks.FieldByName(featurePropertyName(name))
+		if !block.IsValid() {
+			continue
+		}
+		embed := block.FieldByName("BlueprintEmbed")
+		if v, ok := findPropertyField(embed, h.field); ok &&
+			utils.Contains(explainValue(v), value) {
+			return "feature " + name
+		}
+	}
+	return ""
+}
+
+// newElementOrigins describes where an element added by a mutator came
+// from. Elements added by defaults carry the origins they have in the
+// defaults module.
+func (h *explainHandler) newElementOrigins(stage string, mctx blueprint.BaseModuleContext,
+	value string) []string {
+	name := mctx.ModuleName()
+
+	switch stage {
+	case "features_applier":
+		if feature := h.featureOrigin(mctx, value); feature != "" {
+			return []string{fmt.Sprintf("set in %s of %s", feature, name)}
+		}
+	case "target":
+		tgt := explainVariant(mctx.Module())
+		return []string{fmt.Sprintf("set in %s: block of %s", tgt, name)}
+	case "default_applier":
+		origins := []string{}
+		mctx.VisitDirectDeps(func(dep blueprint.Module) {
+			if len(origins) > 0 || mctx.OtherModuleDependencyTag(dep) != defaultDepTag {
+				return
+			}
+			for _, e := range h.values[h.key(mctx.OtherModuleName(dep), dep)] {
+				if e.value == value {
+					origins = append(append(origins, e.origins...),
+						"inherited from defaults "+mctx.OtherModuleName(dep))
+					return
+				}
+			}
+		})
+		if len(origins) > 0 {
+			return origins
+		}
+	}
+
+	return []string{fmt.Sprintf("added by %s in %s", stage, name)}
+}
+
+// update records the value of the property once a mutator has run on a
+// module. Elements still present keep their origins, elements changed in
+// place gain the mutator as an origin, and new elements are attributed
+// to what the mutator applied.
+func (h *explainHandler) update(stage string, mctx blueprint.BaseModuleContext) {
+	value, ok := h.moduleValue(mctx)
+	if !ok {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	prev, _ := h.previous(mctx)
+	description := stage
+	if d, ok := explainStages[stage]; ok {
+		description = d
+	}
+
+	elements := []explainElement{}
+	if len(prev) == len(value) {
+		for i, v := range value {
+			e := explainElement{v, prev[i].origins}
+			if v != prev[i].value {
+				e.origins = append(append([]string{}, e.origins...),
+					fmt.Sprintf("changed by %s from %s", description, prev[i].value))
+			}
+			elements = append(elements, e)
+		}
+	} else {
+		next := 0
+		for _, v := range value {
+			found := -1
+			for j := next; j < len(prev); j++ {
+				if prev[j].value == v {
+					found = j
+					break
+				}
+			}
+			if found >= 0 {
+				elements = append(elements, prev[found])
+				next = found + 1
+			} else {
+				elements = append(elements,
+					explainElement{v, h.newElementOrigins(stage, mctx, v)})
+			}
+		}
+	}
+
+	h.values[h.key(mctx.ModuleName(), mctx.Module())] = elements
+}
+
+// start records the value set in the build definition, the first time
+// a mutator runs on a module.
+func (h *explainHandler) start(mctx blueprint.BaseModuleContext) {
+	value, ok := h.moduleValue(mctx)
+	if !ok {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if _, seen := h.previous(mctx); seen {
+		return
+	}
+	elements := []explainElement{}
+	for _, v := range value {
+		elements = append(elements, explainElement{v,
+			[]string{fmt.Sprintf("set in %s (%s)", mctx.ModuleName(), mctx.BlueprintsFile())}})
+	}
+	h.values[h.key(mctx.ModuleName(), mctx.Module())] = elements
+}
+
+// explainBottomUpMutator wraps a mutator so that the property is
+// followed through it. When nothing is explained, the mutator is
+// returned as is.
+func (h *explainHandler) explainBottomUpMutator(name string,
+	mutator blueprint.BottomUpMutator) blueprint.BottomUpMutator {
+	if h == nil {
+		return mutator
+	}
+	return func(mctx blueprint.BottomUpMutatorContext) {
+		h.start(mctx)
+		mutator(mctx)
+		h.update(name, mctx)
+	}
+}
+
+// explainTopDownMutator is the top down equivalent of explainBottomUpMutator
+func (h *explainHandler) explainTopDownMutator(name string,
+	mutator blueprint.TopDownMutator) blueprint.TopDownMutator {
+	if h == nil {
+		return mutator
+	}
+	return func(mctx blueprint.TopDownMutatorContext) {
+		h.start(mctx)
+		mutator(mctx)
+		h.update(name, mctx)
+	}
+}
+
+// report describes the final value of the property for each variant
+// of the module.
+func (h *explainHandler) report() string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	keys := []string{}
+	for key := range h.values {
+		if strings.HasPrefix(key, h.module+":") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	// The module before splitting is only reported when it was not split
+	if len(keys) > 1 && keys[0] == h.module+":" {
+		keys = keys[1:]
+	}
+
+	sb := strings.Builder{}
+	for _, key := range keys {
+		name := h.module
+		if variant := strings.TrimPrefix(key, h.module+":"); variant != "" {
+			name += " (" + variant + ")"
+		}
+		elements := h.values[key]
+		fmt.Fprintf(&sb, "%s of %s: %d element(s)\n", h.property, name, len(elements))
+		for _, e := range elements {
+			fmt.Fprintf(&sb, "  %s\n", e.value)
+			for _, origin := range e.origins {
+				fmt.Fprintf(&sb, "      %s\n", origin)
+			}
+		}
+	}
+	return sb.String()
+}
+
+type explainSingleton struct {
+	handler *explainHandler
+}
+
+func (s *explainSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	h := s.handler
+	found := false
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if ctx.ModuleName(m) != h.module {
+			return
+		}
+		found = true
+		if f, ok := m.(featurable); ok {
+			if _, ok := propertyValue(f.featurableProperties(), h.field); ok {
+				return
+			}
+		}
+		utils.Die("%s has no property %s", h.module, h.property)
+	})
+	if !found {
+		utils.Die("Module %s not found", h.module)
+	}
+
+	fmt.Print(h.report())
+
+	// As with the statistics, don't overwrite the build files
+	os.Exit(0)
+}
+
+func (h *explainHandler) explainSingletonFactory() blueprint.Singleton {
+	return &explainSingleton{h}
+}
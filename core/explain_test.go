@@ -0,0 +1,71 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_propertyValue_finds_embedded_properties(t *testing.T) {
+	b := Build{}
+	b.Cflags = []string{"-DA", "-DB"}
+	b.Out = proptools.StringPtr("libfoo")
+	b.Target.BlueprintEmbed = &BuildProps{Ldflags: []string{"-lm"}}
+
+	value, ok := propertyValue([]interface{}{&b}, "Cflags")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"-DA", "-DB"}, value)
+
+	// host and target blocks are applied later, so are not searched
+	value, ok = propertyValue([]interface{}{&b}, "Ldflags")
+	assert.True(t, ok)
+	assert.Empty(t, value)
+
+	value, ok = propertyValue([]interface{}{&b}, "Out")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"libfoo"}, value)
+
+	value, ok = propertyValue([]interface{}{&b}, "Strip")
+	assert.True(t, ok)
+	assert.Empty(t, value)
+
+	_, ok = propertyValue([]interface{}{&b}, "No_such_property")
+	assert.False(t, ok)
+}
+
+func Test_explainHandler_report(t *testing.T) {
+	h := &explainHandler{module: "libfoo", property: "cflags", values: map[string][]explainElement{
+		"libfoo:": {{"-DA", []string{"set in libfoo (build.bp)"}}},
+		"libfoo:target": {
+			{"-DD", []string{"set in libfoo_defaults (build.bp)",
+				"inherited from defaults libfoo_defaults"}},
+			{"-DA", []string{"set in libfoo (build.bp)"}},
+		},
+		"libfoo_defaults:target": {{"-DD", []string{"set in libfoo_defaults (build.bp)"}}},
+	}}
+
+	assert.Equal(t, "cflags of libfoo (target): 2 element(s)\n"+
+		"  -DD\n"+
+		"      set in libfoo_defaults (build.bp)\n"+
+		"      inherited from defaults libfoo_defaults\n"+
+		"  -DA\n"+
+		"      set in libfoo (build.bp)\n", h.report())
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// exportedVariables holds what a library inherits from its
+// dependencies. It is collected once per module by
+// exportedVariablesMutator, so that the backends do not need to walk
+// the dependencies again each time they are needed.
+type exportedVariables struct {
+	localIncludeDirs []string
+	includeDirs      []string
+	cflags           []string
+
+	// Include directories of bob_external_library modules. The
+	// Android build system handles the exports of other libraries, but
+	// knows nothing of these.
+	externalLocalIncludeDirs []string
+	externalIncludeDirs      []string
+
+	// Generated modules whose headers are used by this library, in
+	// dependency order
+	generatedHeaderModules []blueprint.Module
+
+	// Generated modules listed in export_generated_headers, whose
+	// headers are also used by users of this library
+	exportedGeneratedHeaderModules []blueprint.Module
+
+	// Header libraries listed in export_header_libs, whose include
+	// directories and flags are also used by users of this library
+	exportedHeaderLibs []*headerLibrary
+
+	// Headers written by bison for the libraries used
+	parserHeaders []string
+
+	// Export macro headers of the libraries used
+	exportHeaders []string
+}
+
+// exportedVariablesMutator collects the include directories, flags and
+// generated headers each library gets from its dependencies. This runs
+// after all dependencies are added and properties are final, and since
+// it is bottom up, the generated headers exported by dependencies have
+// already been collected.
+func exportedVariablesMutator(mctx blueprint.BottomUpMutatorContext) {
+	l, ok := getLibrary(mctx.Module())
+	if !ok || !isEnabled(l) {
+		return
+	}
+
+	exported := exportedVariables{}
+	visitedLibs := map[string]bool{}
+	visitedGenerated := map[string]bool{}
+
+	addExports := func(pe propertyExporter) {
+		exported.localIncludeDirs = append(exported.localIncludeDirs,
+			pe.exportLocalIncludeDirs()...)
+		exported.includeDirs = append(exported.includeDirs, pe.exportIncludeDirs()...)
+		exported.cflags = append(exported.cflags, pe.exportCflags()...)
+	}
+
+	addExternal := func(el *externalLib) {
+		exported.externalLocalIncludeDirs = append(exported.externalLocalIncludeDirs,
+			el.exportLocalIncludeDirs()...)
+		exported.externalIncludeDirs = append(exported.externalIncludeDirs,
+			el.exportIncludeDirs()...)
+	}
+
+	addGenerated := func(m blueprint.Module) {
+		// A module may be reached more than once, through different
+		// dependency tags or libraries. Only list its headers once.
+		if !visitedGenerated[m.Name()] {
+			visitedGenerated[m.Name()] = true
+			exported.generatedHeaderModules = append(exported.generatedHeaderModules, m)
+		}
+	}
+
+	mctx.VisitDirectDeps(func(dep blueprint.Module) {
+		tag := mctx.OtherModuleDependencyTag(dep)
+
+		switch tag {
+		case generatedHeaderTag, exportGeneratedHeaderTag:
+			if _, ok := getGenerateCommon(dep); !ok {
+				utils.Die("%s dependency on non-generated module %s",
+					tag.(dependencyTag).name, dep.Name())
+			}
+			addGenerated(dep)
+			if tag == exportGeneratedHeaderTag {
+				exported.exportedGeneratedHeaderModules =
+					append(exported.exportedGeneratedHeaderModules, dep)
+			}
+
+		case headerDepTag:
+			if visitedLibs[dep.Name()] {
+				return
+			}
+			visitedLibs[dep.Name()] = true
+			if el, ok := dep.(*externalLib); ok {
+				addExports(el)
+				addExternal(el)
+				return
+			}
+			hl, ok := dep.(*headerLibrary)
+			if !ok {
+				return
+			}
+			addExports(hl)
+			if utils.Contains(l.Properties.Export_header_libs, dep.Name()) {
+				exported.exportedHeaderLibs = append(exported.exportedHeaderLibs, hl)
+			}
+
+		case wholeStaticDepTag, staticDepTag, sharedDepTag, reexportLibsTag:
+			if tag != wholeStaticDepTag {
+				// The library could be a bob_generate_shared_library
+				// or bob_generate_static_library, exporting its
+				// generated header directories, or a library
+				// exporting generated headers of its own.
+				if _, ok := getGenerateCommon(dep); ok {
+					addGenerated(dep)
+				}
+				if lib, ok := getLibrary(dep); ok {
+					for _, m := range lib.exported.exportedGeneratedHeaderModules {
+						addGenerated(m)
+					}
+				}
+			}
+
+			if visitedLibs[dep.Name()] {
+				// Dependencies are visited once for each tag.
+				return
+			}
+			visitedLibs[dep.Name()] = true
+
+			if pe, ok := dep.(propertyExporter); ok {
+				addExports(pe)
+			}
+			if el, ok := dep.(*externalLib); ok {
+				addExternal(el)
+			}
+			if lib, ok := getLibrary(dep); ok {
+				exported.parserHeaders = append(exported.parserHeaders, lib.parserHeaders()...)
+				exported.exportHeaders = append(exported.exportHeaders, lib.exportHeaders()...)
+				for _, hl := range lib.exported.exportedHeaderLibs {
+					if !visitedLibs[hl.Name()] {
+						visitedLibs[hl.Name()] = true
+						addExports(hl)
+					}
+				}
+			}
+		}
+	})
+
+	// Libraries deep in the dependency graph are reached through many
+	// paths, and each path repeats their exports. The direct
+	// dependencies are visited in the order they were added, with
+	// reexported libraries last, so keeping the first occurrence of
+	// each entry puts parents before children.
+	exported.localIncludeDirs = utils.MergeUnique(exported.localIncludeDirs)
+	exported.includeDirs = utils.MergeUnique(exported.includeDirs)
+	exported.cflags = utils.MergeFlags(exported.cflags)
+	exported.externalLocalIncludeDirs = utils.MergeUnique(exported.externalLocalIncludeDirs)
+	exported.externalIncludeDirs = utils.MergeUnique(exported.externalIncludeDirs)
+
+	l.exported = exported
+}
@@ -19,32 +19,74 @@ package core
 
 import (
 	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
 )
 
+// ExternalLibProps describes how to use a library built outside of Bob.
+// These are passed on to the modules using the library, in the same way
+// as the exported properties of Bob libraries.
 type ExternalLibProps struct {
 	Export_cflags  []string
 	Export_ldflags []string
 	Ldlibs         []string
 
-	TargetType tgtType `blueprint:"mutated"`
+	// Include directories relative to the root of the source tree
+	Export_include_dirs []string
+	// Include directories relative to the build.bp file
+	Export_local_include_dirs []string
 }
 
 type externalLib struct {
 	moduleBase
+
+	// The target types the library is available for
+	variants []tgtType
+
 	Properties struct {
 		ExternalLibProps
+		ExternalPackageProps
 		Features
+
+		Target     TargetSpecific
+		Host       TargetSpecific
+		Host_cross TargetSpecific
+
+		TargetType tgtType `blueprint:"mutated"`
 	}
 }
 
 func (m *externalLib) featurableProperties() []interface{} {
-	return []interface{}{&m.Properties.ExternalLibProps}
+	return []interface{}{&m.Properties.ExternalLibProps, &m.Properties.ExternalPackageProps}
 }
 
 func (m *externalLib) features() *Features {
 	return &m.Properties.Features
 }
 
+func (m *externalLib) targetableProperties() []interface{} {
+	return []interface{}{&m.Properties.ExternalLibProps, &m.Properties.ExternalPackageProps}
+}
+
+func (m *externalLib) getTargetSpecific(tgt tgtType) *TargetSpecific {
+	if tgt == tgtTypeHost {
+		return &m.Properties.Host
+	} else if tgt == tgtTypeTarget {
+		return &m.Properties.Target
+	} else if tgt == tgtTypeHostCross {
+		return &m.Properties.Host_cross
+	} else {
+		utils.Die("Unsupported target type: %s", tgt)
+	}
+	return nil
+}
+
+func (m *externalLib) processPaths(ctx blueprint.BaseModuleContext, g generatorBackend) {
+	m.Properties.Export_local_include_dirs = utils.PrefixDirs(m.Properties.Export_local_include_dirs,
+		projectModuleDir(ctx))
+	m.processPackage(ctx)
+}
+
 func (m *externalLib) outputName() string   { return m.Name() }
 func (m *externalLib) altName() string      { return m.outputName() }
 func (m *externalLib) altShortName() string { return m.altName() }
@@ -55,7 +97,7 @@ func (m *externalLib) outputs() []string         { return []string{} }
 func (m *externalLib) implicitOutputs() []string { return []string{} }
 
 // Implement the splittable interface so "normal" libraries can depend on external ones.
-func (m *externalLib) supportedVariants() []tgtType         { return []tgtType{tgtTypeHost, tgtTypeTarget} }
+func (m *externalLib) supportedVariants() []tgtType         { return m.variants }
 func (m *externalLib) disable()                             {}
 func (m *externalLib) setVariant(tgt tgtType)               { m.Properties.TargetType = tgt }
 func (m *externalLib) getTarget() tgtType                   { return m.Properties.TargetType }
@@ -64,21 +106,27 @@ func (m *externalLib) getSplittableProps() *SplittableProps { return &Splittable
 // Implement the propertyExporter interface so that external libraries can pass
 // on properties e.g. from pkg-config
 
-func (m *externalLib) exportCflags() []string           { return m.Properties.Export_cflags }
-func (m *externalLib) exportIncludeDirs() []string      { return []string{} }
-func (m *externalLib) exportLocalIncludeDirs() []string { return []string{} }
-func (m *externalLib) exportLdflags() []string          { return m.Properties.Export_ldflags }
-func (m *externalLib) exportLdlibs() []string           { return m.Properties.Ldlibs }
-func (m *externalLib) exportSharedLibs() []string       { return []string{} }
+func (m *externalLib) exportCflags() []string      { return m.Properties.Export_cflags }
+func (m *externalLib) exportIncludeDirs() []string { return m.Properties.Export_include_dirs }
+func (m *externalLib) exportLocalIncludeDirs() []string {
+	return m.Properties.Export_local_include_dirs
+}
+func (m *externalLib) exportLdflags() []string    { return m.Properties.Export_ldflags }
+func (m *externalLib) exportLdlibs() []string     { return m.Properties.Ldlibs }
+func (m *externalLib) exportSharedLibs() []string { return []string{} }
 
 var _ propertyExporter = (*externalLib)(nil)
-var _ splittable = (*externalLib)(nil)
+var _ targetSpecificLibrary = (*externalLib)(nil)
+var _ pathProcessor = (*externalLib)(nil)
 
 // External libraries have no actions - they are already built.
 func (m *externalLib) GenerateBuildActions(ctx blueprint.ModuleContext) {}
 
 func externalLibFactory(config *bobConfig) (blueprint.Module, []interface{}) {
-	module := &externalLib{}
-	module.Properties.Features.Init(&config.Properties, ExternalLibProps{})
+	module := &externalLib{variants: availableTargetTypes(&config.Properties)}
+	module.Properties.Features.Init(&config.Properties, ExternalLibProps{}, ExternalPackageProps{})
+	module.Properties.Host.init(&config.Properties, ExternalLibProps{}, ExternalPackageProps{})
+	module.Properties.Target.init(&config.Properties, ExternalLibProps{}, ExternalPackageProps{})
+	module.Properties.Host_cross.init(&config.Properties, ExternalLibProps{}, ExternalPackageProps{})
 	return module, []interface{}{&module.Properties, &module.SimpleName.Properties}
 }
@@ -0,0 +1,231 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// ExternalPackageProps selects a package installed by a C++ package
+// manager. The include directories, flags and libraries of the package
+// are read from the package manager's output, and added to the
+// ExternalLibProps of the library.
+type ExternalPackageProps struct {
+	// The package manager which installed the package: conan or vcpkg
+	Package_manager *string
+	// The name of the package. Defaults to the module name.
+	Package *string
+	// The package manager output, relative to the build.bp file.
+	// For conan, the conanbuildinfo.json written by the json generator.
+	// For vcpkg, the installed directory of a triplet, such as
+	// vcpkg_installed/x64-linux.
+	Package_info *string
+}
+
+// packageInfo holds what a library from a package manager needs to be
+// used by other modules
+type packageInfo struct {
+	includeDirs []string
+	cflags      []string
+	libDirs     []string
+	libs        []string
+}
+
+// conanDependency is the subset of a dependency in conanbuildinfo.json
+// which Bob uses
+type conanDependency struct {
+	Name          string   `json:"name"`
+	Include_paths []string `json:"include_paths"`
+	Lib_paths     []string `json:"lib_paths"`
+	Libs          []string `json:"libs"`
+	System_libs   []string `json:"system_libs"`
+	Defines       []string `json:"defines"`
+	Cflags        []string `json:"cflags"`
+}
+
+// parseConanBuildInfo extracts package 'pkg' from the contents of a
+// conanbuildinfo.json file
+func parseConanBuildInfo(content []byte, pkg string) (packageInfo, error) {
+	var buildInfo struct {
+		Dependencies []conanDependency `json:"dependencies"`
+	}
+	if err := json.Unmarshal(content, &buildInfo); err != nil {
+		return packageInfo{}, err
+	}
+
+	for _, dep := range buildInfo.Dependencies {
+		if dep.Name != pkg {
+			continue
+		}
+		info := packageInfo{
+			includeDirs: dep.Include_paths,
+			cflags:      append(utils.PrefixAll(dep.Defines, "-D"), dep.Cflags...),
+			libDirs:     dep.Lib_paths,
+			libs:        append(dep.Libs, dep.System_libs...),
+		}
+		return info, nil
+	}
+	return packageInfo{}, fmt.Errorf("package %s is not a dependency", pkg)
+}
+
+// vcpkgLibraryName returns the name of the library a file installed by
+// vcpkg provides, if it is a static or shared library
+func vcpkgLibraryName(file string) (string, bool) {
+	base := filepath.Base(file)
+	if !strings.HasPrefix(base, "lib") {
+		return "", false
+	}
+	base = strings.TrimPrefix(base, "lib")
+	if strings.HasSuffix(base, ".a") {
+		return strings.TrimSuffix(base, ".a"), true
+	}
+	if i := strings.Index(base, ".so"); i > 0 {
+		if rest := base[i+len(".so"):]; rest == "" || strings.HasPrefix(rest, ".") {
+			return base[:i], true
+		}
+	}
+	return "", false
+}
+
+// parseVcpkgList extracts a package from the list of files vcpkg
+// installed for it. 'installedDir' is the installed directory of the
+// triplet the list belongs to.
+func parseVcpkgList(list io.Reader, installedDir string) (packageInfo, error) {
+	triplet := filepath.Base(installedDir)
+	libPrefix := triplet + "/lib/"
+	includePrefix := triplet + "/include/"
+
+	info := packageInfo{}
+	scanner := bufio.NewScanner(list)
+	for scanner.Scan() {
+		file := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(file, includePrefix) {
+			info.includeDirs = utils.AppendIfUnique(info.includeDirs,
+				filepath.Join(installedDir, "include"))
+		} else if strings.HasPrefix(file, libPrefix) &&
+			!strings.Contains(strings.TrimPrefix(file, libPrefix), "/") {
+			if lib, ok := vcpkgLibraryName(file); ok {
+				info.libDirs = utils.AppendIfUnique(info.libDirs,
+					filepath.Join(installedDir, "lib"))
+				info.libs = utils.AppendIfUnique(info.libs, lib)
+			}
+		}
+	}
+	return info, scanner.Err()
+}
+
+// readVcpkgPackage finds the list of files vcpkg installed for package
+// 'pkg' in 'installedDir', and extracts the package from it
+func readVcpkgPackage(installedDir, pkg string) (packageInfo, string, error) {
+	triplet := filepath.Base(installedDir)
+	pattern := filepath.Join(filepath.Dir(installedDir), "vcpkg", "info",
+		pkg+"_*_"+triplet+".list")
+
+	lists, err := filepath.Glob(pattern)
+	if err != nil {
+		return packageInfo{}, "", err
+	}
+	if len(lists) != 1 {
+		return packageInfo{}, "", fmt.Errorf("expected one match for %s, found %d",
+			pattern, len(lists))
+	}
+
+	file, err := os.Open(lists[0])
+	if err != nil {
+		return packageInfo{}, "", err
+	}
+	defer file.Close()
+
+	info, err := parseVcpkgList(file, installedDir)
+	return info, lists[0], err
+}
+
+// readPackage reads the package selected by the package properties of
+// an external library
+func (m *externalLib) readPackage(ctx blueprint.BaseModuleContext) (info packageInfo, err error) {
+	props := &m.Properties.ExternalPackageProps
+	pkg := proptools.StringDefault(props.Package, m.Name())
+
+	if props.Package_info == nil {
+		ctx.PropertyErrorf("package_info", "must be set with package_manager")
+		return
+	}
+	path := *props.Package_info
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(getSourceDir(), projectModuleDir(ctx), path)
+	}
+
+	switch *props.Package_manager {
+	case "conan":
+		var content []byte
+		ctx.AddNinjaFileDeps(path)
+		content, err = ioutil.ReadFile(path)
+		if err == nil {
+			info, err = parseConanBuildInfo(content, pkg)
+		}
+	case "vcpkg":
+		var list string
+		info, list, err = readVcpkgPackage(path, pkg)
+		if list != "" {
+			ctx.AddNinjaFileDeps(list)
+		}
+	default:
+		ctx.PropertyErrorf("package_manager", "must be conan or vcpkg, not %s",
+			*props.Package_manager)
+	}
+	if err != nil {
+		ctx.PropertyErrorf("package_info", "%s", err.Error())
+	}
+	return
+}
+
+// processPackage adds the include directories, flags and libraries of
+// the package selected by the package properties to the library
+func (m *externalLib) processPackage(ctx blueprint.BaseModuleContext) {
+	if m.Properties.Package_manager == nil {
+		return
+	}
+	if !getConfig(ctx).Properties.GetBool("builder_ninja") {
+		ctx.PropertyErrorf("package_manager", "is only supported on the Linux backend")
+		return
+	}
+
+	info, err := m.readPackage(ctx)
+	if err != nil {
+		return
+	}
+
+	props := &m.Properties.ExternalLibProps
+	props.Export_include_dirs = append(props.Export_include_dirs, info.includeDirs...)
+	props.Export_cflags = append(props.Export_cflags, info.cflags...)
+	props.Export_ldflags = append(props.Export_ldflags, utils.PrefixAll(info.libDirs, "-L")...)
+	props.Ldlibs = append(props.Ldlibs, utils.PrefixAll(info.libs, "-l")...)
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseConanBuildInfo(t *testing.T) {
+	content := []byte(`{
+		"dependencies": [
+			{
+				"name": "zlib",
+				"include_paths": ["/conan/zlib/include"],
+				"lib_paths": ["/conan/zlib/lib"],
+				"libs": ["z"]
+			},
+			{
+				"name": "fmt",
+				"include_paths": ["/conan/fmt/include"],
+				"lib_paths": ["/conan/fmt/lib"],
+				"libs": ["fmt"],
+				"system_libs": ["m"],
+				"defines": ["FMT_HEADER_ONLY=0"],
+				"cflags": ["-pthread"]
+			}
+		]
+	}`)
+
+	info, err := parseConanBuildInfo(content, "fmt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/conan/fmt/include"}, info.includeDirs)
+	assert.Equal(t, []string{"-DFMT_HEADER_ONLY=0", "-pthread"}, info.cflags)
+	assert.Equal(t, []string{"/conan/fmt/lib"}, info.libDirs)
+	assert.Equal(t, []string{"fmt", "m"}, info.libs)
+
+	_, err = parseConanBuildInfo(content, "boost")
+	assert.Error(t, err)
+
+	_, err = parseConanBuildInfo([]byte("not json"), "fmt")
+	assert.Error(t, err)
+}
+
+func Test_vcpkgLibraryName(t *testing.T) {
+	tests := map[string]string{
+		"x64-linux/lib/libfmt.a":        "fmt",
+		"x64-linux/lib/libz.so":         "z",
+		"x64-linux/lib/libz.so.1.2.11":  "z",
+		"x64-linux/lib/libsomething.la": "",
+		"x64-linux/lib/fmt.lib":         "",
+		"x64-linux/lib/libfoo.sofa":     "",
+	}
+
+	for file, expected := range tests {
+		lib, ok := vcpkgLibraryName(file)
+		assert.Equal(t, expected != "", ok, file)
+		assert.Equal(t, expected, lib, file)
+	}
+}
+
+func Test_parseVcpkgList(t *testing.T) {
+	list := strings.Join([]string{
+		"x64-linux/",
+		"x64-linux/include/",
+		"x64-linux/include/fmt/core.h",
+		"x64-linux/include/fmt/format.h",
+		"x64-linux/debug/lib/libfmtd.a",
+		"x64-linux/lib/libfmt.a",
+		"x64-linux/lib/pkgconfig/fmt.pc",
+		"x64-linux/share/fmt/copyright",
+	}, "\n")
+
+	info, err := parseVcpkgList(strings.NewReader(list), "/vcpkg_installed/x64-linux")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/vcpkg_installed/x64-linux/include"}, info.includeDirs)
+	assert.Equal(t, []string{"/vcpkg_installed/x64-linux/lib"}, info.libDirs)
+	assert.Equal(t, []string{"fmt"}, info.libs)
+	assert.Empty(t, info.cflags)
+}
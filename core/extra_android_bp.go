@@ -0,0 +1,99 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+func extraAndroidBpEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["extra_android_bp"]
+	return ok && props.GetBool("extra_android_bp")
+}
+
+// androidBpConfig returns a copy of the config which selects the
+// Android.bp builder instead of Ninja, so that features and templates
+// see the builder the files are written for.
+func androidBpConfig(config *bobConfig) *bobConfig {
+	bpConfig := &bobConfig{Properties: config.Properties}
+	props := &bpConfig.Properties
+
+	props.properties = map[string]interface{}{}
+	for k, v := range config.Properties.properties {
+		props.properties[k] = v
+	}
+	props.stringMap = map[string]string{}
+	for k, v := range config.Properties.stringMap {
+		props.stringMap[k] = v
+	}
+	props.features = map[string]bool{}
+	for k, v := range config.Properties.features {
+		props.features[k] = v
+	}
+
+	builders := map[string]bool{
+		"builder_ninja":        false,
+		"builder_android_make": false,
+		"builder_android_bp":   true,
+	}
+	for k, v := range builders {
+		props.properties[k] = v
+		props.stringMap[k] = convertToString(v)
+		props.features[k] = v
+	}
+
+	return bpConfig
+}
+
+func dieOnErrors(errs []error) {
+	if len(errs) == 0 {
+		return
+	}
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	utils.Die("Unable to generate Android.bp alongside build.ninja")
+}
+
+// generateExtraAndroidBp writes the Android.bp file from the same build
+// definitions and config as the Ninja build. The backends change module
+// properties differently (paths and escaping), so the module graph is
+// resolved again in a separate context, and no Ninja file is written
+// for it. The lint, config export and flag audit reports are only
+// written by the Ninja generation. The Android.bp is regenerated whenever
+// build.ninja is, as both depend on the same files.
+func generateExtraAndroidBp(config *bobConfig) {
+	bpConfig := androidBpConfig(config)
+	ctx := newContext(bpConfig, &generationTools{secondary: true})
+
+	// The Ninja generation has already handled the bootstrap module
+	// types used to build Bob itself.
+	ctx.SetIgnoreUnknownModuleTypes(true)
+
+	_, errs := ctx.ParseBlueprintsFiles(flag.Arg(0), bpConfig)
+	dieOnErrors(errs)
+	_, errs = ctx.ResolveDependencies(bpConfig)
+	dieOnErrors(errs)
+	_, errs = ctx.PrepareBuildActions(bpConfig)
+	dieOnErrors(errs)
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_androidBpConfig_selects_builder(t *testing.T) {
+	config := &bobConfig{}
+	config.Properties.properties = map[string]interface{}{
+		"builder_ninja":        true,
+		"builder_android_make": false,
+		"builder_android_bp":   false,
+		"extra_android_bp":     true,
+	}
+	config.Properties.stringMap = map[string]string{"builder_ninja": "1"}
+	config.Properties.features = map[string]bool{"builder_ninja": true}
+
+	bpConfig := androidBpConfig(config)
+	assert.False(t, bpConfig.Properties.GetBool("builder_ninja"))
+	assert.True(t, bpConfig.Properties.GetBool("builder_android_bp"))
+	assert.False(t, bpConfig.Properties.features["builder_ninja"])
+	assert.Equal(t, "1", bpConfig.Properties.StringMap()["builder_android_bp"])
+
+	// The Ninja generation's config is unchanged
+	assert.True(t, config.Properties.GetBool("builder_ninja"))
+	assert.True(t, config.Properties.features["builder_ninja"])
+	assert.True(t, extraAndroidBpEnabled(config))
+}
@@ -128,3 +128,16 @@ func newSpecialFilePath(path string) filePath {
 	// Module dir should be the directory.
 	return generatedFilePath{path, filepath.Base(path), filepath.Dir(path)}
 }
+
+// absolutePath resolves the directory variables used in the build
+// files, and makes the path absolute, so that it can be used by tools
+// outside the build.
+func absolutePath(path string) string {
+	path = strings.Replace(path, "${SrcDir}", getSourceDir(), -1)
+	path = strings.Replace(path, "${BuildDir}", getBuildDir(), -1)
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		utils.Die("%v", err)
+	}
+	return abs
+}
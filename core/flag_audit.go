@@ -0,0 +1,113 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/ccflags"
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+const flagAuditFile = "flag_audit.json"
+
+var (
+	// The flags dropped from each module, keyed by the module name
+	// and variant.
+	//
+	// Flags are dropped while templates are applied, and while the
+	// Android backends generate build actions, so the audits can't be
+	// kept in the modules' properties.
+	flagAudits     = map[string]*ccflags.Audit{}
+	flagAuditsLock sync.Mutex
+)
+
+func flagAuditEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["flag_audit"]
+	return ok && props.GetBool("flag_audit")
+}
+
+// getFlagAudit returns the audit to record the flags dropped from the
+// current module in. It is nil when FLAG_AUDIT is disabled.
+func getFlagAudit(ctx blueprint.BaseModuleContext) *ccflags.Audit {
+	if !flagAuditEnabled(getConfig(ctx)) {
+		return nil
+	}
+
+	name := ctx.ModuleName()
+	if s, ok := ctx.Module().(splittable); ok {
+		name += " (" + string(s.getTarget()) + ")"
+	}
+
+	flagAuditsLock.Lock()
+	defer flagAuditsLock.Unlock()
+
+	audit, ok := flagAudits[name]
+	if !ok {
+		audit = &ccflags.Audit{}
+		flagAudits[name] = audit
+	}
+	return audit
+}
+
+// flagAuditContent returns flag_audit.json. Modules which kept all
+// their flags are left out.
+func flagAuditContent(audits map[string]*ccflags.Audit) (string, error) {
+	modules := map[string][]ccflags.DroppedFlag{}
+	for name, audit := range audits {
+		if len(audit.Dropped) > 0 {
+			modules[name] = audit.Dropped
+		}
+	}
+
+	// Maps are written with sorted keys, so the file is stable
+	data, err := json.MarshalIndent(modules, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// flagAuditSingleton writes the flags dropped from each module to
+// flag_audit.json in the build directory.
+type flagAuditSingleton struct{}
+
+func (s *flagAuditSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	flagAuditsLock.Lock()
+	content, err := flagAuditContent(flagAudits)
+	flagAuditsLock.Unlock()
+	if err != nil {
+		utils.Die("Failed to generate %s: %v", flagAuditFile, err)
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString(content)
+	err = getBackendOutput(getConfig(ctx)).writeFile(getPathInBuildDir(flagAuditFile), sb)
+	if err != nil {
+		utils.Die("Failed to write %s: %v", flagAuditFile, err)
+	}
+}
+
+func flagAuditSingletonFactory() blueprint.Singleton {
+	return &flagAuditSingleton{}
+}
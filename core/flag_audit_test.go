@@ -0,0 +1,48 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ARM-software/bob-build/internal/ccflags"
+)
+
+func Test_flagAuditContent(t *testing.T) {
+	libfoo := &ccflags.Audit{}
+	libfoo.Drop("cflags", "-march=armv8-a", "machine specific flags are set by Android")
+	audits := map[string]*ccflags.Audit{
+		"libfoo (target)": libfoo,
+		"libbar (host)":   {},
+	}
+
+	content, err := flagAuditContent(audits)
+	assert.Nil(t, err)
+	assert.Equal(t, `{
+    "libfoo (target)": [
+        {
+            "property": "cflags",
+            "flag": "-march=armv8-a",
+            "reason": "machine specific flags are set by Android"
+        }
+    ]
+}
+`, content)
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// The name of the file, within the build directory, used to keep the
+// results of compiler flag probes between generation runs.
+const flagCacheFileName = ".bob.flag_cache.json"
+
+// persistentFlagCache stores the results of compiler flag probes on disk,
+// so that flags don't need to be probed again on every generation.
+//
+// Results are grouped by a fingerprint of the compiler, made from its
+// path, the size and modification time of the binary, and the output of
+// `--version`. Changing the compiler therefore invalidates its results.
+// Only the results for compilers used in the current run are written
+// back, so results for old compilers get dropped.
+type persistentFlagCache struct {
+	filename string
+	lock     sync.Mutex
+
+	// Results loaded from the previous run, keyed by compiler fingerprint
+	previous map[string]map[string]bool
+
+	// Results used in this run, keyed by compiler fingerprint
+	current map[string]map[string]bool
+
+	// Fingerprints of the compilers seen in this run, keyed by compiler path
+	fingerprints map[string]string
+}
+
+var flagCacheOnce sync.Once
+var flagCacheInstance *persistentFlagCache
+
+// getPersistentFlagCache returns the flag cache shared by all toolchains,
+// loading it from the build directory the first time it is used.
+func getPersistentFlagCache() *persistentFlagCache {
+	flagCacheOnce.Do(func() {
+		flagCacheInstance = newPersistentFlagCache(getPathInBuildDir(flagCacheFileName))
+	})
+	return flagCacheInstance
+}
+
+func newPersistentFlagCache(filename string) *persistentFlagCache {
+	cache := &persistentFlagCache{
+		filename:     filename,
+		previous:     map[string]map[string]bool{},
+		current:      map[string]map[string]bool{},
+		fingerprints: map[string]string{},
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		// No cache yet
+		return cache
+	}
+
+	err = json.Unmarshal(content, &cache.previous)
+	if err != nil {
+		// Ignore a corrupt cache, it will be rewritten
+		utils.Warnf("", "Ignoring invalid flag cache %s: %v", filename, err)
+		cache.previous = map[string]map[string]bool{}
+	}
+
+	return cache
+}
+
+// Identify the compiler, so that results are not reused after it changes
+func compilerFingerprint(compiler string) string {
+	path := compiler
+	if p, err := exec.LookPath(compiler); err == nil {
+		path = p
+	}
+
+	fingerprint := path
+	if fi, err := os.Stat(path); err == nil {
+		fingerprint += fmt.Sprintf(":%d:%d", fi.Size(), fi.ModTime().UnixNano())
+	}
+
+	out, err := exec.Command(path, "--version").Output()
+	if err == nil {
+		version := strings.SplitN(strings.TrimSpace(string(out)), "\n", 2)[0]
+		fingerprint += ":" + version
+	}
+
+	return fingerprint
+}
+
+func (cache *persistentFlagCache) getFingerprint(compiler string) string {
+	// Must be called with the lock held
+	fingerprint, ok := cache.fingerprints[compiler]
+	if !ok {
+		fingerprint = compilerFingerprint(compiler)
+		cache.fingerprints[compiler] = fingerprint
+		if _, ok := cache.current[fingerprint]; !ok {
+			cache.current[fingerprint] = map[string]bool{}
+		}
+	}
+	return fingerprint
+}
+
+// lookup returns the recorded result of probing 'key' on 'compiler', and
+// whether there was one.
+func (cache *persistentFlagCache) lookup(compiler, key string) (supported bool, ok bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	fingerprint := cache.getFingerprint(compiler)
+	if supported, ok = cache.current[fingerprint][key]; ok {
+		return
+	}
+	if supported, ok = cache.previous[fingerprint][key]; ok {
+		cache.current[fingerprint][key] = supported
+	}
+	return
+}
+
+// store records the result of probing 'key' on 'compiler'.
+func (cache *persistentFlagCache) store(compiler, key string, supported bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	fingerprint := cache.getFingerprint(compiler)
+	cache.current[fingerprint][key] = supported
+}
+
+// save writes the results used in this run to the cache file.
+func (cache *persistentFlagCache) save() error {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	content, err := json.MarshalIndent(cache.current, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	sb := strings.Builder{}
+	sb.Write(content)
+	sb.WriteString("\n")
+
+	return writeFileIfChanged(cache.filename, &sb)
+}
+
+// saveFlagCache writes out the flag cache, if any flags have been probed.
+func saveFlagCache() {
+	if flagCacheInstance == nil {
+		return
+	}
+	if err := flagCacheInstance.save(); err != nil {
+		utils.Warnf("", "Failed to write flag cache: %v", err)
+	}
+}
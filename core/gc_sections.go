@@ -0,0 +1,65 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+// gcSectionsMutator marks the static libraries linked into a binary or
+// shared library using gc_sections, so that their objects are also
+// compiled with a section per function and data object. Otherwise the
+// linker could only discard whole objects from them.
+func gcSectionsMutator(mctx blueprint.TopDownMutatorContext) {
+	l, ok := getBinaryOrSharedLib(mctx.Module())
+	if !ok || !proptools.Bool(l.Properties.Gc_sections) {
+		return
+	}
+
+	for dep := range getLinkableModules(mctx) {
+		if sl, ok := dep.(*staticLibrary); ok {
+			sl.Properties.GcSectionsObjects = true
+		}
+	}
+}
+
+// gcSectionsCflags returns the compiler flags placing each function and
+// data object in its own section.
+func (l *library) gcSectionsCflags() []string {
+	if proptools.Bool(l.Properties.Gc_sections) || l.Properties.GcSectionsObjects {
+		return []string{"-ffunction-sections", "-fdata-sections"}
+	}
+	return []string{}
+}
+
+// gcSectionsLdflags returns the linker flags discarding unused sections.
+// Symbols exported from a shared library are always kept by the linker,
+// but other symbols only used at runtime, e.g. through dlsym(), must be
+// listed in keep_symbols.
+func (l *library) gcSectionsLdflags(tc toolchain) []string {
+	if !proptools.Bool(l.Properties.Gc_sections) {
+		return []string{}
+	}
+
+	flags := []string{tc.getLinker().gcSections()}
+	for _, symbol := range l.Properties.Keep_symbols {
+		flags = append(flags, tc.getLinker().keepSymbol(symbol))
+	}
+	return flags
+}
@@ -50,7 +50,10 @@ func (m *generateBinary) outputFileName() string {
 //// Support blueprint.Module
 
 func (m *generateBinary) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
 	if isEnabled(m) {
+		applyDepOutputs(ctx)
 		g := getBackend(ctx)
 		g.genBinaryActions(m, ctx)
 	}
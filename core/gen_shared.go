@@ -46,7 +46,10 @@ func (m *generateSharedLibrary) libExtension() string {
 //// Support blueprint.Module
 
 func (m *generateSharedLibrary) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
 	if isEnabled(m) {
+		applyDepOutputs(ctx)
 		g := getBackend(ctx)
 		g.genSharedActions(m, ctx)
 	}
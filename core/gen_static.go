@@ -44,7 +44,10 @@ func (m *generateStaticLibrary) libExtension() string {
 //// Support blueprint.Module
 
 func (m *generateStaticLibrary) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
 	if isEnabled(m) {
+		applyDepOutputs(ctx)
 		g := getBackend(ctx)
 		g.genStaticActions(m, ctx)
 	}
@@ -18,6 +18,8 @@
 package core
 
 import (
+	"fmt"
+	"hash/fnv"
 	"path/filepath"
 	"reflect"
 	"regexp"
@@ -52,6 +54,8 @@ type inout struct {
 	implicitSrcs []string
 	implicitOuts []string
 	rspfile      string
+	// Arguments for this command only, substituted for ${file_args}
+	fileArgs []string
 }
 
 // Add a prefix to all output paths
@@ -75,6 +79,7 @@ type GenerateProps struct {
 	AliasableProps
 	EnableableProps
 	InstallableProps
+	EnvProps
 
 	/* The command that is to be run for this source generation.
 	 * Substitutions can be made in the command, by using $name_of_var. A list of substitutions that can be used:
@@ -116,6 +121,10 @@ type GenerateProps struct {
 	// Used to indicate that the console should be used.
 	Console *bool
 
+	// Name of a pool defined in GENERATOR_POOLS, used to limit how many
+	// commands run in parallel. Only supported on the Linux backend.
+	Pool *string
+
 	// A list of source modules that this bob_generated_source will encapsulate.
 	// When this module is used with generated_headers, the named modules' export_gen_include_dirs will be forwarded.
 	// When this module is used with generated_sources, the named modules' outputs will be supplied as sources.
@@ -128,7 +137,7 @@ type GenerateProps struct {
 	// The defaults used to retrieve cflags
 	Flag_defaults []string
 
-	// The target type - must be either "host" or "target"
+	// The target type - must be "host", "target" or "host_cross"
 	Target tgtType
 
 	// If true, depfile name will be generated and can be used as ${depfile} reference in 'cmd'
@@ -138,6 +147,14 @@ type GenerateProps struct {
 	// before executing the command. This can be used to e.g. contain ${in},
 	// in cases where the command line length is a limiting factor.
 	Rsp_content *string
+
+	// A text file holding a license header, which is prepended as a
+	// comment to the source files written by the command
+	License_header *string
+
+	// If true, check that the source files written by the command
+	// start with license_header, rather than adding it
+	License_header_check *bool
 }
 
 type generateCommon struct {
@@ -186,6 +203,7 @@ func (m *generateCommon) init(properties *configProperties, list ...interface{})
 	m.Properties.Features.Init(properties, list...)
 	m.Properties.FlagArgsBuild.Host.init(properties, CommonProps{}, BuildProps{})
 	m.Properties.FlagArgsBuild.Target.init(properties, CommonProps{}, BuildProps{})
+	m.Properties.FlagArgsBuild.Host_cross.init(properties, CommonProps{}, BuildProps{})
 }
 
 func (m *generateCommon) shortName() string {
@@ -365,7 +383,10 @@ type generateSource struct {
 var _ installable = (*generateSource)(nil)
 
 func (m *generateSource) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
 	if isEnabled(m) {
+		applyDepOutputs(ctx)
 		g := getBackend(ctx)
 		g.generateSourceActions(m, ctx)
 	}
@@ -383,10 +404,12 @@ func (m *generateCommon) hostBinName(mctx blueprint.ModuleContext) (name string)
 		func(module blueprint.Module) {
 			_, bin_ok := module.(*binary)
 			_, genbin_ok := module.(*generateBinary)
-			if bin_ok || genbin_ok {
+			_, pybin_ok := module.(*pythonBinary)
+			_, gobin_ok := module.(*goBinary)
+			if bin_ok || genbin_ok || pybin_ok || gobin_ok {
 				name = module.Name()
 			} else {
-				mctx.PropertyErrorf("host_bin", "%s is not a `bob_binary` nor `bob_generate_binary`", module.Name())
+				mctx.PropertyErrorf("host_bin", "%s is not a `bob_binary`, `bob_generate_binary`, `bob_python_binary` nor `bob_go_binary`", module.Name())
 			}
 		})
 
@@ -397,6 +420,11 @@ func (m *generateCommon) hostBinName(mctx blueprint.ModuleContext) (name string)
 // target type and shared library dependencies for a generator module.
 // This is different from the "tool" in that it used to depend on
 // a bob_binary module.
+//
+// The shared libraries are the transitive closure of the binary's
+// shared_libs, including any generated shared libraries and the symlinks
+// needed to load versioned libraries, so that the generator can not run
+// before everything the tool loads at runtime has been built.
 func (m *generateCommon) hostBinOuts(mctx blueprint.ModuleContext) (string, []string, tgtType) {
 	// No host_bin provided
 	if m.Properties.Host_bin == nil {
@@ -420,8 +448,14 @@ func (m *generateCommon) hostBinOuts(mctx blueprint.ModuleContext) (string, []st
 				hostBinTarget = b.getTarget()
 			} else if gb, ok := child.(*generateBinary); ok {
 				outputs = gb.outputs()
+			} else if pb, ok := child.(*pythonBinary); ok {
+				outputs = pb.outputs()
+				hostBinTarget = tgtTypeHost
+			} else if gob, ok := child.(*goBinary); ok {
+				outputs = gob.outputs()
+				hostBinTarget = tgtTypeHost
 			} else {
-				mctx.PropertyErrorf("host_bin", "%s is not a `bob_binary` nor `bob_generate_binary`", parent.Name())
+				mctx.PropertyErrorf("host_bin", "%s is not a `bob_binary`, `bob_generate_binary`, `bob_python_binary` nor `bob_go_binary`", parent.Name())
 				return false
 			}
 
@@ -434,7 +468,11 @@ func (m *generateCommon) hostBinOuts(mctx blueprint.ModuleContext) (string, []st
 			return true // keep visiting
 		} else if parent != mctx.Module() && depTag == sharedDepTag {
 			if l, ok := child.(*sharedLibrary); ok {
-				hostBinSharedLibsDeps = append(hostBinSharedLibsDeps, l.outputs()...)
+				hostBinSharedLibsDeps = utils.AppendUnique(hostBinSharedLibsDeps,
+					utils.NewStringSlice(l.outputs(), l.implicitOutputs()))
+			} else if l, ok := child.(*generateSharedLibrary); ok {
+				hostBinSharedLibsDeps = utils.AppendUnique(hostBinSharedLibsDeps,
+					utils.NewStringSlice(l.outputs(), l.implicitOutputs()))
 			}
 
 			return true // keep visiting
@@ -481,7 +519,52 @@ func getDependentArgsAndFiles(ctx blueprint.ModuleContext, args map[string]strin
 	return
 }
 
-func (m *generateCommon) getArgs(ctx blueprint.ModuleContext) (string, map[string]string, []string, tgtType) {
+// hostBinLibraryDirs returns the directories holding the shared libraries
+// of a host_bin, starting with the backend's shared library directory.
+func hostBinLibraryDirs(g generatorBackend, hostTarget tgtType, sharedLibs []string) []string {
+	dirs := []string{g.sharedLibsDir(hostTarget)}
+	for _, lib := range sharedLibs {
+		dirs = utils.AppendIfUnique(dirs, filepath.Dir(lib))
+	}
+	return dirs
+}
+
+// ldLibraryPathPrefix returns the environment assignment to prefix a
+// command with so that it finds shared libraries in 'dirs'. Both ninja
+// and make need '$' to be escaped as '$$'.
+func ldLibraryPathPrefix(dirs []string) string {
+	if len(dirs) == 0 {
+		return ""
+	}
+	return "LD_LIBRARY_PATH=" + strings.Join(dirs, ":") + ":$$LD_LIBRARY_PATH "
+}
+
+// usesArch reports whether the module's command is parameterised by
+// the architecture through ${arch}.
+func (m *generateCommon) usesArch() bool {
+	cmd := strings.Replace(proptools.String(m.Properties.Cmd), "${args}",
+		strings.Join(m.Properties.Args, " "), -1)
+	return utils.ContainsArg(cmd, "arch")
+}
+
+// generatorArchName returns the value of ${arch} for a generator built
+// for 'tgt'. Generators without a target are not built for a particular
+// architecture, so this is empty for them.
+func generatorArchName(config *bobConfig, tgt tgtType) string {
+	switch tgt {
+	case tgtTypeHost, tgtTypeTarget, tgtTypeHostCross:
+		key := string(tgt) + "_arch_name"
+		if _, ok := config.Properties.properties[key]; ok {
+			return config.Properties.GetString(key)
+		}
+	}
+	return ""
+}
+
+// getArgs returns the command, its arguments and implicit dependencies for
+// a generator module. When a host_bin is used, the directories it loads
+// shared libraries from are also returned.
+func (m *generateCommon) getArgs(ctx blueprint.ModuleContext) (string, map[string]string, []string, []string) {
 	g := getBackend(ctx)
 
 	tc := g.getToolchain(m.Properties.Target)
@@ -497,6 +580,7 @@ func (m *generateCommon) getArgs(ctx blueprint.ModuleContext) (string, map[strin
 
 	args := map[string]string{
 		"ar":              arBinary,
+		"arch":            generatorArchName(getConfig(ctx), m.Properties.Target),
 		"as":              asBinary,
 		"asflags":         utils.Join(astargetflags, props.Asflags),
 		"bob_config":      configFile,
@@ -526,16 +610,25 @@ func (m *generateCommon) getArgs(ctx blueprint.ModuleContext) (string, map[strin
 		dependents = append(dependents, toolPath)
 	}
 
+	var hostBinLibDirs []string
 	hostBin, hostBinSharedLibs, hostTarget := m.hostBinOuts(ctx)
 	if hostBin != "" {
 		args["host_bin"] = hostBin
 		dependents = append(dependents, hostBin)
 		dependents = append(dependents, hostBinSharedLibs...)
+		hostBinLibDirs = hostBinLibraryDirs(g, hostTarget, hostBinSharedLibs)
 	}
 
 	// Args can contain other parameters, so replace that immediately
 	cmd := strings.Replace(proptools.String(m.Properties.Cmd), "${args}", strings.Join(m.Properties.Args, " "), -1)
 
+	if m.Properties.License_header != nil {
+		args["license_header"] = getBackendPathInSourceDir(g, *m.Properties.License_header)
+		args["license_header_tool"] = getBackendPathInBobScriptsDir(g, "license_header.py")
+		dependents = append(dependents, args["license_header"], args["license_header_tool"])
+		cmd = licenseHeaderCommand(cmd, proptools.Bool(m.Properties.License_header_check))
+	}
+
 	if proptools.Bool(m.Properties.Depfile) && !utils.ContainsArg(cmd, "depfile") {
 		utils.Die("%s depfile is true, but ${depfile} not used in cmd", m.Name())
 	}
@@ -546,7 +639,7 @@ func (m *generateCommon) getArgs(ctx blueprint.ModuleContext) (string, map[strin
 		}
 	}
 
-	return cmd, args, dependents, hostTarget
+	return cmd, args, dependents, hostBinLibDirs
 }
 
 func (m *generateCommon) getSources(ctx blueprint.BaseModuleContext) []string {
@@ -554,17 +647,61 @@ func (m *generateCommon) getSources(ctx blueprint.BaseModuleContext) []string {
 }
 
 func (m *generateCommon) processPaths(ctx blueprint.BaseModuleContext, g generatorBackend) {
+	m.Properties.checkEnv(ctx)
 	m.Properties.SourceProps.processPaths(ctx, g)
 	m.Properties.InstallableProps.processPaths(ctx, g)
 	if m.Properties.Tool != nil {
 		*m.Properties.Tool = filepath.Join(projectModuleDir(ctx), *m.Properties.Tool)
 	}
+	if m.Properties.License_header != nil {
+		*m.Properties.License_header = filepath.Join(projectModuleDir(ctx), *m.Properties.License_header)
+	} else if m.Properties.License_header_check != nil {
+		ctx.PropertyErrorf("license_header_check", "requires license_header")
+	}
+}
+
+// licenseHeaderCommand returns cmd followed by the step adding the
+// license header to the files it writes, or checking they have it.
+// Files which aren't source files are left alone by the script.
+func licenseHeaderCommand(cmd string, check bool) string {
+	step := "python ${license_header_tool} --header ${license_header}"
+	if check {
+		step += " --check"
+	}
+	return cmd + " && " + step + " ${out}"
 }
 
 func (m *generateCommon) getAliasList() []string {
 	return m.Properties.getAliasList()
 }
 
+// toolStampsEnabled returns whether GENERATOR_TOOL_STAMPS is set
+func toolStampsEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["generator_tool_stamps"]
+	return ok && props.GetBool("generator_tool_stamps")
+}
+
+// stampedTools returns the tool and host_bin run by a generator, given
+// the arguments of its command, before unused arguments are removed.
+func stampedTools(args map[string]string) []string {
+	tools := []string{}
+	for _, key := range []string{"tool", "host_bin"} {
+		if tool, ok := args[key]; ok && tool != "" {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// toolStampDeps returns the implicit dependencies of a generator whose
+// tools are covered by 'stamp'. The tools themselves are no longer
+// implicit dependencies, so the generator only runs again when their
+// contents change, but must still be order-only dependencies.
+func toolStampDeps(implicits, tools []string, stamp string) []string {
+	return append(utils.Difference(implicits, tools), stamp)
+}
+
 func getDepfileName(s string) string {
 	return utils.FlattenPath(s) + ".d"
 }
@@ -627,28 +764,38 @@ type TransformSourceProps struct {
 		// List of implicit outputs, which can use capture groups from match.
 		// Implicit outputs are output files that do not get mentioned on the command line.
 		Implicit_outs []string
+		// Arguments for the command of each source, which can use capture
+		// groups from match. They are substituted for ${file_args} in cmd.
+		File_args []string
 	}
+	// The number of commands to split the sources between, each run
+	// on all the sources of its shard, instead of one command per source
+	Shards *int64
 }
 
 func (tsp *TransformSourceProps) inoutForSrc(re *regexp.Regexp, source filePath, depfile *bool, rspfile bool) (io inout) {
 	io.in = []string{source.buildPath()}
 
 	for _, rep := range tsp.Out.Replace {
-		out := filepath.Join(re.ReplaceAllString(source.localPath(), rep))
+		out := filepath.Join(utils.TransformPath(re, source.localPath(), rep))
 		io.out = append(io.out, out)
 	}
 
 	for _, implOut := range tsp.Out.Implicit_outs {
-		implOut = filepath.Join(re.ReplaceAllString(source.localPath(), implOut))
+		implOut = filepath.Join(utils.TransformPath(re, source.localPath(), implOut))
 		io.implicitOuts = append(io.implicitOuts, implOut)
 	}
 
+	for _, arg := range tsp.Out.File_args {
+		io.fileArgs = append(io.fileArgs, utils.TransformPath(re, source.localPath(), arg))
+	}
+
 	if proptools.Bool(depfile) {
 		io.depfile = getDepfileName(source.localPath())
 	}
 
 	for _, implSrc := range tsp.Out.Implicit_srcs {
-		implSrc = re.ReplaceAllString(source.localPath(), implSrc)
+		implSrc = utils.TransformPath(re, source.localPath(), implSrc)
 		io.implicitSrcs = append(io.implicitSrcs, filepath.Join(source.moduleDir(), implSrc))
 	}
 
@@ -660,7 +807,10 @@ func (tsp *TransformSourceProps) inoutForSrc(re *regexp.Regexp, source filePath,
 }
 
 func (m *transformSource) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
 	if isEnabled(m) {
+		applyDepOutputs(ctx)
 		g := getBackend(ctx)
 		g.transformSourceActions(m, ctx)
 	}
@@ -694,15 +844,70 @@ func (m *transformSource) generateInouts(ctx blueprint.ModuleContext, g generato
 	var inouts []inout
 	re := regexp.MustCompile(m.Properties.Out.Match)
 
+	cmd := strings.Replace(proptools.String(m.generateCommon.Properties.Cmd), "${args}",
+		strings.Join(m.generateCommon.Properties.Args, " "), -1)
+	if len(m.Properties.Out.File_args) > 0 && !utils.ContainsArg(cmd, "file_args") {
+		ctx.PropertyErrorf("out", "file_args is set, but ${file_args} not used in cmd")
+	}
+
+	if m.Properties.Shards != nil && *m.Properties.Shards < 1 {
+		ctx.PropertyErrorf("shards", "must be at least 1, not %d", *m.Properties.Shards)
+	}
+
 	for _, source := range m.sourceInfo(ctx, g) {
 		io := m.Properties.inoutForSrc(re, source, m.generateCommon.Properties.Depfile,
 			m.generateCommon.Properties.Rsp_content != nil)
+		for _, out := range append(io.out, io.implicitOuts...) {
+			if filepath.IsAbs(out) || out == ".." || strings.HasPrefix(out, "../") {
+				ctx.PropertyErrorf("out", "%s is outside the output directory (from %s)",
+					out, source.localPath())
+			}
+		}
 		inouts = append(inouts, io)
 	}
 
 	return inouts
 }
 
+// shardInouts merges the commands of the sources into the number of
+// shards set by the shards property. A source is assigned to a shard
+// by a hash of its path, so that adding or removing a source only
+// changes the commands of its own shard. Empty shards are dropped.
+func (m *transformSource) shardInouts(inouts []inout) []inout {
+	if m.Properties.Shards == nil || *m.Properties.Shards <= 1 {
+		return inouts
+	}
+
+	shards := make([]inout, *m.Properties.Shards)
+	for _, io := range inouts {
+		h := fnv.New32a()
+		h.Write([]byte(io.in[0]))
+		shard := &shards[h.Sum32()%uint32(len(shards))]
+
+		shard.in = append(shard.in, io.in...)
+		shard.out = append(shard.out, io.out...)
+		shard.implicitSrcs = utils.AppendUnique(shard.implicitSrcs, io.implicitSrcs)
+		shard.implicitOuts = append(shard.implicitOuts, io.implicitOuts...)
+		shard.fileArgs = append(shard.fileArgs, io.fileArgs...)
+	}
+
+	sharded := []inout{}
+	for i, shard := range shards {
+		if len(shard.in) == 0 {
+			continue
+		}
+		name := fmt.Sprintf("shard_%d", i)
+		if proptools.Bool(m.generateCommon.Properties.Depfile) {
+			shard.depfile = getDepfileName(name)
+		}
+		if m.generateCommon.Properties.Rsp_content != nil {
+			shard.rspfile = getRspfileName(name)
+		}
+		sharded = append(sharded, shard)
+	}
+	return sharded
+}
+
 func (m *transformSource) filesToInstall(ctx blueprint.BaseModuleContext) []string {
 	// Install everything that we generate
 	return m.outputs()
@@ -711,7 +916,8 @@ func (m *transformSource) filesToInstall(ctx blueprint.BaseModuleContext) []stri
 // The module that can generate sources using a multiple execution
 // The command will be run once per src file- with $in being the path in "srcs" and $out being the path transformed
 // through the regexp defined by out.match and out.replace. The regular expression that is used is
-// in regexp.compiled(out.Match).ReplaceAllString(src[i], out.Replace). See https://golang.org/pkg/regexp/ for more
+// in regexp.compiled(out.Match).ReplaceAllString(src[i], out.Replace), with the ${dir}, ${stem} and ${ext}
+// of src[i] also available. See utils.TransformPath, and https://golang.org/pkg/regexp/ for more
 // information.
 // The working directory will be the source directory, and all paths will be relative to the source directory
 // if not else noted
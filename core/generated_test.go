@@ -0,0 +1,126 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_stampedTools(t *testing.T) {
+	assert.Equal(t, []string{}, stampedTools(map[string]string{"gen_dir": "out/gen/foo"}))
+	assert.Equal(t, []string{"src/gen.py", "out/host/executable/mkdata"},
+		stampedTools(map[string]string{
+			"host_bin": "out/host/executable/mkdata",
+			"tool":     "src/gen.py",
+		}))
+}
+
+func Test_toolStampDeps(t *testing.T) {
+	implicits := []string{"src/gen.py", "out/host/shared/libdata.so"}
+	assert.Equal(t, []string{"out/host/shared/libdata.so", "out/gen/foo.tool_stamp"},
+		toolStampDeps(implicits, []string{"src/gen.py"}, "out/gen/foo.tool_stamp"))
+}
+
+func Test_licenseHeaderCommand(t *testing.T) {
+	assert.Equal(t,
+		"${tool} ${in} ${out} && python ${license_header_tool} --header ${license_header} ${out}",
+		licenseHeaderCommand("${tool} ${in} ${out}", false))
+	assert.Equal(t,
+		"${tool} -o ${gen_dir} && python ${license_header_tool} --header ${license_header} --check ${out}",
+		licenseHeaderCommand("${tool} -o ${gen_dir}", true))
+}
+
+func Test_inoutForSrc(t *testing.T) {
+	tsp := &TransformSourceProps{}
+	tsp.Out.Replace = []string{"gen/${dir}/${stem}.pb.c", "${stem}.pb.h"}
+	tsp.Out.File_args = []string{"--package=$1"}
+
+	re := regexp.MustCompile(`([^/]+)/.*\.proto$`)
+	source := sourceFilePath{"api/v1/msg.proto", "api", "${SrcDir}"}
+	io := tsp.inoutForSrc(re, source, nil, false)
+
+	assert.Equal(t, []string{"${SrcDir}/api/v1/msg.proto"}, io.in)
+	assert.Equal(t, []string{"gen/api/v1/msg.pb.c", "msg.pb.h"}, io.out)
+	assert.Equal(t, []string{"--package=api"}, io.fileArgs)
+	assert.Equal(t, "", io.depfile)
+}
+
+func Test_shardInouts(t *testing.T) {
+	inouts := []inout{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		inouts = append(inouts, inout{
+			in:       []string{"src/" + name + ".idl"},
+			out:      []string{name + ".c"},
+			fileArgs: []string{"--name=" + name},
+		})
+	}
+
+	m := &transformSource{}
+	assert.Equal(t, inouts, m.shardInouts(inouts))
+
+	m.Properties.Shards = proptools.Int64Ptr(3)
+	m.generateCommon.Properties.Depfile = proptools.BoolPtr(true)
+	sharded := m.shardInouts(inouts)
+	assert.True(t, len(sharded) <= 3)
+
+	shardOf := map[string]string{}
+	total := 0
+	for _, shard := range sharded {
+		assert.Equal(t, len(shard.in), len(shard.out))
+		assert.Equal(t, len(shard.in), len(shard.fileArgs))
+		assert.Regexp(t, `^shard_[0-2]\.d$`, shard.depfile)
+		for _, in := range shard.in {
+			shardOf[in] = shard.depfile
+		}
+		total += len(shard.in)
+	}
+	assert.Equal(t, len(inouts), total)
+
+	// Adding a source does not move the others between shards
+	more := append(inouts, inout{in: []string{"src/i.idl"}, out: []string{"i.c"}})
+	for _, shard := range m.shardInouts(more) {
+		for _, in := range shard.in {
+			if depfile, ok := shardOf[in]; ok {
+				assert.Equal(t, depfile, shard.depfile, in)
+			}
+		}
+	}
+}
+
+func Test_generatorArchName(t *testing.T) {
+	config := &bobConfig{}
+	config.Properties.properties = map[string]interface{}{
+		"host_arch_name":   "x86_64",
+		"target_arch_name": "aarch64",
+	}
+
+	assert.Equal(t, "x86_64", generatorArchName(config, tgtTypeHost))
+	assert.Equal(t, "aarch64", generatorArchName(config, tgtTypeTarget))
+
+	// Generators without a target have no architecture, and the host
+	// cross architecture is only set with a host cross toolchain.
+	assert.Equal(t, "", generatorArchName(config, tgtTypeUnknown))
+	assert.Equal(t, "", generatorArchName(config, tgtTypeHostCross))
+
+	config.Properties.properties["host_cross_arch_name"] = "x86_64-w64"
+	assert.Equal(t, "x86_64-w64", generatorArchName(config, tgtTypeHostCross))
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// gitMetadata describes the commit the source tree is checked out at,
+// for the git_describe, git_commit and git_dirty template functions. It
+// is read once per generation, the first time one of them is used.
+type gitMetadata struct {
+	once sync.Once
+	err  error
+
+	describe string
+	commit   string
+	dirty    bool
+
+	// The files git updates when the values change. The build is
+	// regenerated when they do, and commands using the values only
+	// rerun when they change. Editing a tracked file doesn't update
+	// any of them, so dirty is only refreshed once the index or HEAD
+	// changes.
+	deps []string
+}
+
+var sourceGitMetadata gitMetadata
+
+// gitOutput runs git in 'dir', returning its output without the
+// trailing newline
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		err = fmt.Errorf("git %s: %s", strings.Join(args, " "),
+			strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return strings.TrimSpace(string(out)), err
+}
+
+// gitTrackedFiles returns the files which change when the commit or the
+// dirty state of a checkout does. 'gitDir' holds HEAD and the index,
+// 'commonDir' the refs, and 'ref' is the ref HEAD points at, if any.
+// A ref is either a loose file, or in packed-refs after git gc, so
+// whichever of these exist are listed. A file which doesn't exist can't
+// be a dependency, or the build would be regenerated every time.
+func gitTrackedFiles(gitDir, commonDir, ref string) []string {
+	deps := []string{
+		filepath.Join(gitDir, "HEAD"),
+		filepath.Join(gitDir, "index"),
+	}
+	if strings.HasPrefix(ref, "refs/") {
+		for _, path := range []string{
+			filepath.Join(commonDir, filepath.FromSlash(ref)),
+			filepath.Join(commonDir, "packed-refs"),
+		} {
+			if _, err := os.Stat(path); err == nil {
+				deps = append(deps, path)
+			}
+		}
+	}
+	return deps
+}
+
+func (g *gitMetadata) load(dir string) {
+	absDir := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	gitDir, err := gitOutput(dir, "rev-parse", "--git-dir")
+	if err != nil {
+		g.err = err
+		return
+	}
+	commonDir, err := gitOutput(dir, "rev-parse", "--git-common-dir")
+	if err != nil {
+		g.err = err
+		return
+	}
+	// Fails on a detached HEAD, which is then the only ref to track
+	ref, _ := gitOutput(dir, "symbolic-ref", "-q", "HEAD")
+	g.deps = gitTrackedFiles(absDir(gitDir), absDir(commonDir), ref)
+
+	if g.commit, g.err = gitOutput(dir, "rev-parse", "HEAD"); g.err != nil {
+		return
+	}
+	if g.describe, g.err = gitOutput(dir, "describe", "--tags", "--always"); g.err != nil {
+		return
+	}
+
+	status, err := gitOutput(dir, "status", "--porcelain", "--untracked-files=no")
+	g.dirty = status != ""
+	g.err = err
+}
+
+// get returns the metadata of the source tree, reading it on first use
+func (g *gitMetadata) get() (*gitMetadata, error) {
+	g.once.Do(func() { g.load(getSourceDir()) })
+	return g, g.err
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_gitTrackedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bob_git_tracked_files")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	gitDir := filepath.Join(dir, ".git")
+	ref := filepath.Join(gitDir, "refs", "heads", "master")
+	packedRefs := filepath.Join(gitDir, "packed-refs")
+	assert.Nil(t, os.MkdirAll(filepath.Dir(ref), 0755))
+
+	// Refs which don't exist are not listed
+	assert.Equal(t, []string{
+		filepath.Join(gitDir, "HEAD"),
+		filepath.Join(gitDir, "index"),
+	}, gitTrackedFiles(gitDir, gitDir, "refs/heads/master"))
+
+	assert.Nil(t, ioutil.WriteFile(ref, []byte{}, 0644))
+	assert.Equal(t, []string{
+		filepath.Join(gitDir, "HEAD"),
+		filepath.Join(gitDir, "index"),
+		ref,
+	}, gitTrackedFiles(gitDir, gitDir, "refs/heads/master"))
+
+	assert.Nil(t, ioutil.WriteFile(packedRefs, []byte{}, 0644))
+	assert.Equal(t, []string{
+		filepath.Join(gitDir, "HEAD"),
+		filepath.Join(gitDir, "index"),
+		ref,
+		packedRefs,
+	}, gitTrackedFiles(gitDir, gitDir, "refs/heads/master"))
+
+	// A detached HEAD holds the commit itself
+	assert.Equal(t, []string{
+		"/wt/.git/worktrees/wt/HEAD",
+		"/wt/.git/worktrees/wt/index",
+	}, gitTrackedFiles("/wt/.git/worktrees/wt", "/wt/.git", ""))
+}
+
+func Test_gitMetadataLoad(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	dir, err := ioutil.TempDir("", "bob_git_metadata")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	git := func(args ...string) {
+		args = append([]string{"-c", "user.name=bob", "-c", "user.email=bob@example.com"}, args...)
+		if _, err := gitOutput(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file := filepath.Join(dir, "file.txt")
+
+	g := gitMetadata{}
+	g.load(dir)
+	assert.Error(t, g.err)
+
+	git("init", "-q")
+	git("symbolic-ref", "HEAD", "refs/heads/master")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("one\n"), 0644))
+	git("add", "file.txt")
+	git("commit", "-q", "-m", "one")
+	git("tag", "v1.0")
+
+	g = gitMetadata{}
+	g.load(dir)
+	assert.NoError(t, g.err)
+	assert.Equal(t, "v1.0", g.describe)
+	assert.Len(t, g.commit, 40)
+	assert.False(t, g.dirty)
+	assert.Contains(t, g.deps, filepath.Join(dir, ".git", "refs", "heads", "master"))
+	assert.NotContains(t, g.deps, filepath.Join(dir, ".git", "packed-refs"))
+
+	// After packing, the ref is only in packed-refs
+	git("pack-refs", "--all")
+	g = gitMetadata{}
+	g.load(dir)
+	assert.NoError(t, g.err)
+	assert.NotContains(t, g.deps, filepath.Join(dir, ".git", "refs", "heads", "master"))
+	assert.Contains(t, g.deps, filepath.Join(dir, ".git", "packed-refs"))
+
+	assert.Nil(t, ioutil.WriteFile(file, []byte("two\n"), 0644))
+	g = gitMetadata{}
+	g.load(dir)
+	assert.NoError(t, g.err)
+	assert.True(t, g.dirty)
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+// GoBinaryProps describes the properties of the bob_go_binary module
+type GoBinaryProps struct {
+	SourceProps
+	AliasableProps
+	EnableableProps
+
+	// The package to build, relative to the build.bp, which must be in
+	// a Go module. Defaults to the package in the build.bp directory.
+	Package *string
+	// Build tags
+	Tags []string
+	// Flags passed to the Go linker
+	Ldflags []string
+}
+
+// Type representing each bob_go_binary module. The module builds a Go
+// host tool, so that it can be used as the host_bin of generator
+// modules.
+type goBinary struct {
+	moduleBase
+	simpleOutputProducer
+	Properties struct {
+		GoBinaryProps
+		Features
+	}
+}
+
+var _ featurable = (*goBinary)(nil)
+var _ enableable = (*goBinary)(nil)
+var _ aliasable = (*goBinary)(nil)
+var _ pathProcessor = (*goBinary)(nil)
+
+func (m *goBinary) features() *Features {
+	return &m.Properties.Features
+}
+
+func (m *goBinary) featurableProperties() []interface{} {
+	return []interface{}{&m.Properties.GoBinaryProps}
+}
+
+func (m *goBinary) getEnableableProps() *EnableableProps {
+	return &m.Properties.EnableableProps
+}
+
+func (m *goBinary) getAliasList() []string {
+	return m.Properties.getAliasList()
+}
+
+func (m *goBinary) shortName() string {
+	return m.Name()
+}
+
+func (m *goBinary) altName() string {
+	return m.Name()
+}
+
+func (m *goBinary) altShortName() string {
+	return m.shortName()
+}
+
+// The package is left relative to the build.bp, as that is where the
+// go command is run.
+func (m *goBinary) processPaths(ctx blueprint.BaseModuleContext, g generatorBackend) {
+	m.Properties.SourceProps.processPaths(ctx, g)
+}
+
+func (m *goBinary) goPackage() string {
+	return proptools.StringDefault(m.Properties.Package, ".")
+}
+
+// goTool returns the go command, and the flags set in GOFLAGS for
+// every Go tool built.
+func goTool(config *bobConfig) (string, string) {
+	tool, flags := "go", ""
+	if _, ok := config.Properties.properties["go_binary"]; ok {
+		if binary := config.Properties.GetString("go_binary"); binary != "" {
+			tool = binary
+		}
+	}
+	if _, ok := config.Properties.properties["go_flags"]; ok {
+		flags = config.Properties.GetString("go_flags")
+	}
+	return tool, flags
+}
+
+// Called by Blueprint to generate the rules associated with the module.
+// This is forwarded to the backend to handle.
+func (m *goBinary) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
+	if isEnabled(m) {
+		getBackend(ctx).goBinaryActions(m, ctx)
+	}
+}
+
+// Create the structure representing the bob_go_binary
+func goBinaryFactory(config *bobConfig) (blueprint.Module, []interface{}) {
+	module := &goBinary{}
+	module.Properties.Features.Init(&config.Properties, GoBinaryProps{})
+	return module, []interface{}{&module.Properties, &module.SimpleName.Properties}
+}
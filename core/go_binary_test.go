@@ -0,0 +1,38 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_goTool(t *testing.T) {
+	config := &bobConfig{}
+	config.Properties.properties = map[string]interface{}{}
+	tool, flags := goTool(config)
+	assert.Equal(t, "go", tool)
+	assert.Equal(t, "", flags)
+
+	config.Properties.properties["go_binary"] = "/opt/go/bin/go"
+	config.Properties.properties["go_flags"] = "-mod=vendor"
+	tool, flags = goTool(config)
+	assert.Equal(t, "/opt/go/bin/go", tool)
+	assert.Equal(t, "-mod=vendor", flags)
+}
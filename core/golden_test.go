@@ -0,0 +1,195 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/blueprint/bootstrap"
+	"github.com/stretchr/testify/assert"
+)
+
+// The golden tests run each backend over the build.bp in each directory
+// of testdata/golden, and compare the files it writes with those kept
+// in a subdirectory named after the backend. Rerun with -update-golden
+// to write the expected files, and to remove those the backend no
+// longer writes. The test never writes to testdata otherwise.
+//
+// Only the files Bob writes itself are compared. The build.ninja of the
+// Linux backend is written by Blueprint, and changes with it.
+var updateGolden = flag.Bool("update-golden", false,
+	"Rewrite the expected output of the backend golden tests")
+
+const (
+	goldenDir      = "testdata/golden"
+	goldenBuildDir = "build"
+)
+
+// The backends tested, with the option selecting each of them
+var goldenBackends = []struct {
+	name    string
+	builder string
+}{
+	{"linux", "builder_ninja"},
+	{"android_make", "builder_android_make"},
+}
+
+// loadGoldenConfig reads the configuration shared by the golden tests,
+// selecting one backend, and capturing the files it writes.
+func loadGoldenConfig(t *testing.T, builder string) *bobConfig {
+	config := &bobConfig{}
+	err := config.Properties.LoadConfig(filepath.Join(goldenDir, "config.json"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	props := &config.Properties
+	for _, b := range goldenBackends {
+		enabled := b.builder == builder
+		props.properties[b.builder] = enabled
+		props.stringMap[b.builder] = convertToString(enabled)
+		props.features[b.builder] = enabled
+	}
+
+	config.Output = newMemoryOutput()
+	return config
+}
+
+func checkGoldenErrors(t *testing.T, stage string, errs []error) {
+	for _, err := range errs {
+		t.Errorf("%s: %v", stage, err)
+	}
+	if len(errs) > 0 {
+		t.FailNow()
+	}
+}
+
+// generateGolden runs the backend selected by the config over a build
+// definition, returning the files written, relative to the build
+// directory.
+func generateGolden(t *testing.T, config *bobConfig, bp []byte) map[string]string {
+	ctx := newContext(config, &generationTools{})
+	ctx.MockFileSystem(map[string][]byte{"build.bp": bp})
+
+	_, errs := ctx.ParseBlueprintsFiles("build.bp", config)
+	checkGoldenErrors(t, "parsing", errs)
+	_, errs = ctx.ResolveDependencies(config)
+	checkGoldenErrors(t, "resolving dependencies", errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	checkGoldenErrors(t, "preparing build actions", errs)
+
+	files := map[string]string{}
+	for name, content := range config.Output.(*memoryOutput).files {
+		rel, err := filepath.Rel(goldenBuildDir, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[rel] = content
+	}
+	return files
+}
+
+// compareGolden compares the files written by a backend with the
+// expected files in dir. Missing expected files, and those which the
+// backend no longer writes, are reported, or fixed with -update-golden.
+func compareGolden(t *testing.T, dir string, files map[string]string) {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if _, ok := files[rel]; ok {
+			return nil
+		}
+		if *updateGolden {
+			t.Logf("Removed %s", path)
+			return os.Remove(path)
+		}
+		t.Errorf("%s is no longer written. Rerun with -update-golden if this is expected", path)
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		expectedFile := filepath.Join(dir, name)
+		expected, err := ioutil.ReadFile(expectedFile)
+
+		if *updateGolden {
+			if err := os.MkdirAll(filepath.Dir(expectedFile), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(expectedFile, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			t.Logf("Wrote %s", expectedFile)
+			continue
+		} else if os.IsNotExist(err) {
+			t.Errorf("%s does not exist. Rerun with -update-golden to write it", expectedFile)
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, string(expected), content,
+			"%s differs. Rerun with -update-golden if this is expected", expectedFile)
+	}
+}
+
+func TestBackendGolden(t *testing.T) {
+	// The configuration uses the GNU toolchain, which is looked up when
+	// the backend is initialised.
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc is not installed")
+	}
+
+	buildDir := bootstrap.BuildDir
+	bootstrap.BuildDir = goldenBuildDir
+	defer func() { bootstrap.BuildDir = buildDir }()
+
+	cases, err := filepath.Glob(filepath.Join(goldenDir, "*", "build.bp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bpFile := range cases {
+		dir := filepath.Dir(bpFile)
+		bp, err := ioutil.ReadFile(bpFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, backend := range goldenBackends {
+			builder := backend.builder
+			expectedDir := filepath.Join(dir, backend.name)
+			t.Run(filepath.Base(dir)+"/"+backend.name, func(t *testing.T) {
+				config := loadGoldenConfig(t, builder)
+				compareGolden(t, expectedDir, generateGolden(t, config, bp))
+			})
+		}
+	}
+}
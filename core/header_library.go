@@ -0,0 +1,133 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// HeaderLibProps are the properties of a header library. The sources
+// are the headers themselves, which are not compiled but tell IDEs and
+// other tools which files belong to the library.
+type HeaderLibProps struct {
+	SourceProps
+	EnableableProps
+
+	// Include dirs (path relative to the build.bp file) exported to users
+	Export_local_include_dirs []string
+
+	// Include dirs (path relative to root) exported to users
+	Export_include_dirs []string
+
+	// Flags exported to users
+	Export_cflags []string
+
+	// Do not check the formatting of the headers of this library
+	Format_check_disabled *bool
+
+	TargetType tgtType `blueprint:"mutated"`
+}
+
+type headerLibrary struct {
+	moduleBase
+	Properties struct {
+		HeaderLibProps
+		Features
+	}
+
+	// The results of checking the formatting of the headers
+	formatCheckOuts []string
+
+	// The target types the library is available for
+	variants []tgtType
+}
+
+func (m *headerLibrary) featurableProperties() []interface{} {
+	return []interface{}{&m.Properties.HeaderLibProps}
+}
+
+func (m *headerLibrary) features() *Features {
+	return &m.Properties.Features
+}
+
+func (m *headerLibrary) getEnableableProps() *EnableableProps {
+	return &m.Properties.EnableableProps
+}
+
+func (m *headerLibrary) processPaths(ctx blueprint.BaseModuleContext, g generatorBackend) {
+	m.Properties.SourceProps.processPaths(ctx, g)
+	m.Properties.Export_local_include_dirs = utils.PrefixDirs(m.Properties.Export_local_include_dirs,
+		projectModuleDir(ctx))
+}
+
+// Header libraries are available for every target type, so that any
+// library can use them.
+func (m *headerLibrary) supportedVariants() []tgtType { return m.variants }
+func (m *headerLibrary) disable()                     {}
+func (m *headerLibrary) setVariant(tgt tgtType)       { m.Properties.TargetType = tgt }
+func (m *headerLibrary) getTarget() tgtType           { return m.Properties.TargetType }
+
+func (m *headerLibrary) getSplittableProps() *SplittableProps { return &SplittableProps{} }
+
+func (m *headerLibrary) shortName() string {
+	return m.Name() + "__" + string(m.Properties.TargetType)
+}
+
+func (m *headerLibrary) altName() string      { return m.Name() }
+func (m *headerLibrary) altShortName() string { return m.shortName() }
+
+// Header libraries have no outputs - the headers are used in place.
+func (m *headerLibrary) outputs() []string         { return []string{} }
+func (m *headerLibrary) implicitOutputs() []string { return []string{} }
+
+func (m *headerLibrary) exportCflags() []string {
+	return m.Properties.Export_cflags
+}
+
+func (m *headerLibrary) exportIncludeDirs() []string {
+	return m.Properties.Export_include_dirs
+}
+
+func (m *headerLibrary) exportLocalIncludeDirs() []string {
+	return m.Properties.Export_local_include_dirs
+}
+
+// Header libraries don't link anything
+func (m *headerLibrary) exportLdflags() []string    { return []string{} }
+func (m *headerLibrary) exportLdlibs() []string     { return []string{} }
+func (m *headerLibrary) exportSharedLibs() []string { return []string{} }
+
+var _ propertyExporter = (*headerLibrary)(nil)
+var _ splittable = (*headerLibrary)(nil)
+var _ pathProcessor = (*headerLibrary)(nil)
+
+func (m *headerLibrary) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
+	if isEnabled(m) {
+		getBackend(ctx).headerLibraryActions(m, ctx)
+	}
+}
+
+func headerLibraryFactory(config *bobConfig) (blueprint.Module, []interface{}) {
+	module := &headerLibrary{variants: availableTargetTypes(&config.Properties)}
+	module.Properties.Features.Init(&config.Properties, HeaderLibProps{})
+	return module, []interface{}{&module.Properties, &module.SimpleName.Properties}
+}
@@ -0,0 +1,36 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+// hostCrossEnabled returns whether a third toolchain is configured,
+// building host tools for an OS other than the one running the build.
+func hostCrossEnabled(properties *configProperties) bool {
+	_, ok := properties.properties["host_cross_toolchain"]
+	return ok && properties.GetBool("host_cross_toolchain")
+}
+
+// availableTargetTypes returns the target types there is a toolchain
+// for. Modules which can be used by any other module, like header
+// libraries and defaults, are split into all of these.
+func availableTargetTypes(properties *configProperties) []tgtType {
+	tgts := []tgtType{tgtTypeHost, tgtTypeTarget}
+	if hostCrossEnabled(properties) {
+		tgts = append(tgts, tgtTypeHostCross)
+	}
+	return tgts
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_availableTargetTypes(t *testing.T) {
+	props := &configProperties{properties: map[string]interface{}{}}
+	assert.Equal(t, []tgtType{tgtTypeHost, tgtTypeTarget}, availableTargetTypes(props))
+
+	props.properties["host_cross_toolchain"] = false
+	assert.Equal(t, []tgtType{tgtTypeHost, tgtTypeTarget}, availableTargetTypes(props))
+
+	props.properties["host_cross_toolchain"] = true
+	assert.Equal(t, []tgtType{tgtTypeHost, tgtTypeTarget, tgtTypeHostCross},
+		availableTargetTypes(props))
+}
+
+func Test_library_supportedVariants_host_cross(t *testing.T) {
+	yes := true
+	l := &library{}
+	l.Properties.Host_cross_supported = &yes
+	assert.Equal(t, []tgtType{tgtTypeTarget, tgtTypeHostCross}, l.supportedVariants())
+	assert.Equal(t, &l.Properties.Host_cross, l.getTargetSpecific(tgtTypeHostCross))
+}
+
+func Test_newToolchainGnuHostCross(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bob_host_cross")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	prefix := filepath.Join(dir, "x86_64-w64-mingw32-")
+	assert.Nil(t, ioutil.WriteFile(prefix+"gcc", []byte{}, 0755))
+
+	config := &bobConfig{}
+	config.Properties.properties = map[string]interface{}{
+		"as_binary":                 "as",
+		"host_cross_gnu_prefix":     prefix,
+		"host_cross_gnu_cc_binary":  "gcc",
+		"host_cross_gnu_cxx_binary": "g++",
+		"host_cross_gnu_flags":      "-static",
+		"host_cross_sysroot":        "",
+		"host_cross_ar_binary":      "x86_64-w64-mingw32-ar",
+		"host_cross_objcopy_binary": "x86_64-w64-mingw32-objcopy",
+		"host_cross_objdump_binary": "x86_64-w64-mingw32-objdump",
+		"host_cross_nm_binary":      "x86_64-w64-mingw32-nm",
+		"host_cross_size_binary":    "x86_64-w64-mingw32-size",
+	}
+
+	tc := newToolchainGnuHostCross(config)
+	cc, flags := tc.getCCompiler()
+	assert.Equal(t, prefix+"gcc", cc)
+	assert.Contains(t, flags, "-static")
+	ar, _ := tc.getArchiver()
+	assert.Equal(t, "x86_64-w64-mingw32-ar", ar)
+	nm, size := tc.getSizeTools()
+	assert.Equal(t, "x86_64-w64-mingw32-nm", nm)
+	assert.Equal(t, "x86_64-w64-mingw32-size", size)
+
+	// The rpath options are specific to ELF
+	assert.Equal(t, "", tc.getLinker().setRpath([]string{"/usr/lib"}))
+	assert.Equal(t, "", tc.getLinker().setRpathLink("/usr/lib"))
+	assert.Equal(t, "-Wl,--gc-sections", tc.getLinker().gcSections())
+}
+
+func Test_binary_outputFileName_host_cross(t *testing.T) {
+	b := &binary{hostCrossExtension: ".exe"}
+	b.Properties.TargetType = tgtTypeHost
+	b.Properties.Out = proptools.StringPtr("tool")
+	assert.Equal(t, "tool", b.outputFileName())
+
+	b.Properties.TargetType = tgtTypeHostCross
+	assert.Equal(t, "tool.exe", b.outputFileName())
+}
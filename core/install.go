@@ -18,7 +18,10 @@
 package core
 
 import (
+	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/google/blueprint"
 
@@ -32,6 +35,22 @@ type EnableableProps struct {
 	// Whether it is built by default in a build with no targets requested.
 	// Nothing to do with 'defaults'.
 	Build_by_default *bool
+	// If set, generation fails when the module does not end up with this
+	// enabled state. Modules are also disabled when they depend on a
+	// disabled module, so this catches configurations that unexpectedly
+	// enable or disable a module.
+	Expect_enabled *bool
+	// Configuration options the module needs, each given as `OPTION=value`,
+	// or as `OPTION` for a boolean option which must be enabled.
+	Required_config []string
+	// What happens when required_config is not met: `error`, the
+	// default, fails generation, and `disable` disables the module.
+	Required_config_action *string
+	// Why the module was disabled by Bob, if it was
+	Disabled_reason string `blueprint:"mutated"`
+	// The modules through which a built by default module requires
+	// this one, starting with the module built by default
+	Required_chain []string `blueprint:"mutated"`
 	// Is this module depended on by a module which is built by default?
 	// Used to prune unused modules from Android builds, where we can't
 	// control exactly what gets built.
@@ -64,10 +83,42 @@ func isBuiltByDefault(e enableable) bool {
 		}
 	case *kernelModule:
 		return true
+	case *deviceTree:
+		return true
 	}
 	return false
 }
 
+// Check that modules with expect_enabled set have ended up in the
+// expected state. This must run after disabled dependencies have been
+// propagated by checkDisabledMutator.
+func checkExpectedEnabledMutator(mctx blueprint.BottomUpMutatorContext) {
+	if _, ok := mctx.Module().(*defaults); ok {
+		return
+	}
+
+	e, ok := mctx.Module().(enableable)
+	if !ok {
+		return
+	}
+
+	expected := e.getEnableableProps().Expect_enabled
+	if expected == nil {
+		return
+	}
+
+	if *expected && !isEnabled(e) {
+		if reason := e.getEnableableProps().Disabled_reason; reason != "" {
+			mctx.ModuleErrorf("is expected to be enabled, but is disabled, as it %s", reason)
+			return
+		}
+		mctx.ModuleErrorf("is expected to be enabled, but is disabled " +
+			"(either directly, or because it depends on a disabled module)")
+	} else if !*expected && isEnabled(e) {
+		mctx.ModuleErrorf("is expected to be disabled, but is enabled")
+	}
+}
+
 func isRequired(e enableable) bool {
 	return e.getEnableableProps().Required
 }
@@ -91,11 +142,36 @@ type InstallableProps struct {
 	Post_install_cmd *string
 	// Arguments to post install command
 	Post_install_args []string
+	// Files written by the post install command, relative to the install path
+	Post_install_outs []string
 	// The path retrieved from the install group so we don't need to walk dependencies to get it
 	InstallGroupPath *string `blueprint:"mutated"`
+	// Installed files, as `install_path=file` entries, for the checksum manifest
+	InstalledEntries []string `blueprint:"mutated"`
+
+	DistProps
+}
+
+// DistProps select the installed files which are included in the
+// distribution package
+type DistProps struct {
+	// Whether to include this module's installed files in the package
+	Dist *bool
+	// Names to give files in the package, each given as `pattern=name`.
+	// The pattern is matched against the name of the installed file.
+	Dist_renames []string
+	// Licence files to include in the package alongside this module
+	Dist_licenses []string
+	// Files to package, as `archive_path=file` entries
+	DistEntries []string `blueprint:"mutated"`
+}
+
+func (props *DistProps) isDist() bool {
+	return props.Dist != nil && *props.Dist
 }
 
 func (props *InstallableProps) processPaths(ctx blueprint.BaseModuleContext, g generatorBackend) {
+	props.Dist_licenses = utils.PrefixDirs(props.Dist_licenses, projectModuleDir(ctx))
 	if props.Post_install_tool != nil {
 		*props.Post_install_tool = getBackendPathInSourceDir(g, projectModuleDir(ctx), *props.Post_install_tool)
 	}
@@ -193,6 +269,18 @@ type ResourceProps struct {
 	InstallableProps
 	EnableableProps
 	AndroidProps
+
+	// Directories to install with all their contents. Files keep their
+	// path relative to the directory. Exclude_srcs also applies to them.
+	Src_dirs []string
+
+	// Unix modes of installed files, each given as `pattern=mode`, where
+	// mode is in octal. The pattern is matched against the path of the
+	// file relative to the install path. The last matching entry is used.
+	Install_modes []string
+
+	// Owners of installed files, each given as `pattern=user[:group]`.
+	Install_owners []string
 }
 
 type resource struct {
@@ -204,7 +292,10 @@ type resource struct {
 }
 
 func (m *resource) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
 	if isEnabled(m) {
+		m.checkInstallSettings(ctx)
 		getBackend(ctx).resourceActions(m, ctx)
 	}
 }
@@ -249,7 +340,82 @@ func (m *resource) implicitOutputs() []string {
 }
 
 func (m *resource) filesToInstall(ctx blueprint.BaseModuleContext) []string {
-	return m.Properties.SourceProps.getSources(ctx)
+	files := m.Properties.SourceProps.getSources(ctx)
+
+	for _, dir := range m.Properties.Src_dirs {
+		for _, file := range glob(ctx, []string{filepath.Join(dir, "**", "*")},
+			m.Properties.Exclude_srcs) {
+			if fi, err := os.Stat(getPathInSourceDir(file)); err == nil && !fi.IsDir() {
+				files = append(files, file)
+			}
+		}
+	}
+
+	return files
+}
+
+// installRelPath returns where a file is installed, relative to the
+// install path. Files from src_dirs keep their place in the tree.
+func (m *resource) installRelPath(src string) string {
+	for _, dir := range m.Properties.Src_dirs {
+		if rel, err := filepath.Rel(dir, src); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return filepath.Base(src)
+}
+
+// installRelDir returns the directory 'src' is installed to, given the
+// install path relative to the partition on Android.
+func (m *resource) installRelDir(installRel, src string) string {
+	dir := filepath.Dir(m.installRelPath(src))
+	if dir == "." {
+		return installRel
+	}
+	return filepath.Join(installRel, dir)
+}
+
+// matchInstallSetting returns the value of the last `pattern=value` entry
+// whose pattern matches 'rel', or "" if none do.
+func matchInstallSetting(settings []string, rel string) string {
+	value := ""
+	for _, setting := range settings {
+		parts := strings.SplitN(setting, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if ok, _ := filepath.Match(parts[0], rel); ok {
+			value = parts[1]
+		}
+	}
+	return value
+}
+
+var installModeRegexp = regexp.MustCompile("^[0-7]{3,4}$")
+
+// checkInstallSettings validates the install_modes and install_owners
+// properties of a resource.
+func (m *resource) checkInstallSettings(ctx blueprint.BaseModuleContext) {
+	for _, setting := range m.Properties.Install_modes {
+		parts := strings.SplitN(setting, "=", 2)
+		if len(parts) != 2 || !installModeRegexp.MatchString(parts[1]) {
+			ctx.PropertyErrorf("install_modes",
+				"%s is not in the form pattern=mode, with an octal mode", setting)
+		}
+	}
+	for _, setting := range m.Properties.Install_owners {
+		parts := strings.SplitN(setting, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			ctx.PropertyErrorf("install_owners",
+				"%s is not in the form pattern=user[:group]", setting)
+		}
+	}
+}
+
+// installPermissions returns the mode and owner to give an installed file
+func (m *resource) installPermissions(rel string) (mode, owner string) {
+	return matchInstallSetting(m.Properties.Install_modes, rel),
+		matchInstallSetting(m.Properties.Install_owners, rel)
 }
 
 func (m *resource) getInstallableProps() *InstallableProps {
@@ -257,6 +423,7 @@ func (m *resource) getInstallableProps() *InstallableProps {
 }
 
 func (m *resource) processPaths(ctx blueprint.BaseModuleContext, g generatorBackend) {
+	m.Properties.Src_dirs = utils.PrefixDirs(m.Properties.Src_dirs, projectModuleDir(ctx))
 	m.Properties.SourceProps.processPaths(ctx, g)
 	m.Properties.InstallableProps.processPaths(ctx, g)
 }
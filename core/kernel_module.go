@@ -261,6 +261,8 @@ func (m *kernelModule) generateKbuildArgs(ctx blueprint.BaseModuleContext) kbuil
 }
 
 func (m *kernelModule) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
 	if isEnabled(m) {
 		getBackend(ctx).kernelModuleActions(m, ctx)
 	}
@@ -18,6 +18,8 @@
 package core
 
 import (
+	"fmt"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"text/template"
@@ -106,8 +108,12 @@ func (s *SourceProps) initializeNonCompiledSourceMap(mctx blueprint.BaseModuleCo
 	// Unused non-compiled sources are not allowed, so create
 	// a map to mark whether a non-compiled source is matched.
 	nonCompiledSources := make(map[string]bool)
-	if _, ok := getLibrary(mctx.Module()); ok {
+	if l, ok := getLibrary(mctx.Module()); ok {
 		for _, src := range s.getSources(mctx) {
+			if (l.qtEnabled() && isQtInput(src)) || isParserSource(src) {
+				// Used by the Qt tools, flex or bison instead
+				continue
+			}
 			if utils.IsNotCompilableSource(src) {
 				nonCompiledSources[src] = false
 			}
@@ -142,31 +148,80 @@ func setupMatchSources(mctx blueprint.BaseModuleContext,
 
 	nonCompiledSources := sourceProps.initializeNonCompiledSourceMap(mctx)
 	addtoFuncmap(propfnmap, matchSrcProps, "match_srcs",
-		func(arg string) string {
-			return sourceProps.matchSources(mctx, arg, nonCompiledSources)
+		func(arg string, opts ...string) string {
+			return sourceProps.matchSources(mctx, arg, opts, nonCompiledSources)
 		})
 
 	return nonCompiledSources
 }
 
+// Options accepted by {{match_srcs}} after the patterns
+type matchSourcesOptions struct {
+	// Return an empty string rather than failing when nothing matches
+	optional bool
+	// Directory, relative to the module directory, which the returned
+	// paths are relative to. When not set, the paths are in the source
+	// directory of the backend.
+	root *string
+}
+
+func parseMatchSourcesOptions(ctx blueprint.BaseModuleContext, opts []string) (o matchSourcesOptions) {
+	for _, opt := range opts {
+		if opt == "optional" {
+			o.optional = true
+		} else if strings.HasPrefix(opt, "root=") {
+			root := filepath.Join(ctx.ModuleDir(), strings.TrimPrefix(opt, "root="))
+			o.root = &root
+		} else {
+			utils.Die("Unknown match_srcs option '%s' for module '%s', expected 'optional' or 'root=<dir>'",
+				opt, ctx.ModuleName())
+		}
+	}
+	return
+}
+
 // Callback function implementing {{match_srcs}}
-func (s *SourceProps) matchSources(ctx blueprint.BaseModuleContext, arg string,
+//
+// arg holds one or more space separated glob patterns, each of which
+// may use brace expansion. Sources are returned in the order of the
+// source list, whichever pattern they match.
+func (s *SourceProps) matchSources(ctx blueprint.BaseModuleContext, arg string, opts []string,
 	matchedNonCompiledSources map[string]bool) string {
 
 	g := getBackend(ctx)
+	options := parseMatchSourcesOptions(ctx, opts)
+
+	patterns := []string{}
+	for _, pattern := range strings.Fields(arg) {
+		patterns = append(patterns, utils.ExpandBraces(pattern)...)
+	}
 
 	matchedSources := []string{}
 	for _, src := range s.getSources(ctx) {
-		matched, err := pathtools.Match("**/"+arg, src)
-		if err != nil {
-			utils.Die("Error during matching filepath pattern")
-		}
-		if matched {
+		for _, pattern := range patterns {
+			matched, err := pathtools.Match("**/"+pattern, src)
+			if err != nil {
+				utils.Die("Error during matching filepath pattern")
+			}
+			if !matched {
+				continue
+			}
+
 			matchedNonCompiledSources[src] = true
-			matchedSources = append(matchedSources, getBackendPathInSourceDir(g, src))
+			if options.root != nil {
+				rel, err := filepath.Rel(*options.root, src)
+				if err != nil {
+					utils.Die("Could not make '%s' relative to '%s' for module '%s'",
+						src, *options.root, ctx.ModuleName())
+				}
+				matchedSources = append(matchedSources, rel)
+			} else {
+				matchedSources = append(matchedSources, getBackendPathInSourceDir(g, src))
+			}
+			break
 		}
 	}
-	if len(matchedSources) == 0 {
+	if len(matchedSources) == 0 && !options.optional {
 		utils.Die("Could not match '%s' for module '%s'", arg, ctx.ModuleName())
 	}
 
@@ -202,19 +257,94 @@ func setupAddIfSupported(mctx blueprint.BaseModuleContext,
 	if t, ok := mctx.Module().(moduleWithBuildProps); ok {
 		build := t.build()
 		tc := getBackend(mctx).getToolchain(build.TargetType)
+		audit := getFlagAudit(mctx)
+
+		addIfSupported := func(prop string, langs []string) {
+			addtoFuncmap(propfnmap, []string{prop}, "add_if_supported",
+				func(s string) string {
+					flag := checkCompilerFlag(s, langs, tc)
+					if flag == "" {
+						audit.Drop(strings.ToLower(prop), s,
+							"not supported by the "+string(build.TargetType)+" compiler")
+					}
+					return flag
+				})
+		}
+		addIfSupported("Cflags", []string{"c++", "c"})
+		addIfSupported("Export_cflags", []string{"c++", "c"})
+		addIfSupported("Cxxflags", []string{"c++"})
+		addIfSupported("Conlyflags", []string{"c"})
+	}
+}
+
+// Properties in which {{dep_outputs}} can be used
+func depOutputsPropNames(m blueprint.Module) []string {
+	if _, ok := getGenerateCommon(m); ok {
+		return []string{"Cmd", "Args"}
+	} else if _, ok := getLibrary(m); ok {
+		return []string{"Ldflags"}
+	}
+	return []string{}
+}
 
-		addtoFuncmap(propfnmap, []string{"Cflags", "Export_cflags"}, "add_if_supported",
-			func(s string) string {
-				return checkCompilerFlag(s, []string{"c++", "c"}, tc)
-			})
-		addtoFuncmap(propfnmap, []string{"Cxxflags"}, "add_if_supported",
-			func(s string) string {
-				return checkCompilerFlag(s, []string{"c++"}, tc)
-			})
-		addtoFuncmap(propfnmap, []string{"Conlyflags"}, "add_if_supported",
-			func(s string) string {
-				return checkCompilerFlag(s, []string{"c"}, tc)
-			})
+func depOutputsTemplate(name string) string {
+	return "{{dep_outputs \"" + name + "\"}}"
+}
+
+// findDirectDep returns the direct dependency of a module with the given name
+func findDirectDep(ctx blueprint.BaseModuleContext, name string) (found blueprint.Module) {
+	ctx.VisitDirectDeps(func(dep blueprint.Module) {
+		if found == nil && ctx.OtherModuleName(dep) == name {
+			found = dep
+		}
+	})
+	return
+}
+
+// Check uses of {{dep_outputs}}
+//
+// {{dep_outputs}} returns the outputs of a dependency of the module.
+// These are only known once the dependency's build actions have been
+// generated, so the late template only checks that the module depends
+// on the named module, and keeps the template to be expanded by
+// applyDepOutputs.
+func setupDepOutputs(mctx blueprint.BaseModuleContext,
+	propfnmap map[string]template.FuncMap) {
+
+	addtoFuncmap(propfnmap, depOutputsPropNames(mctx.Module()), "dep_outputs",
+		func(name string) (string, error) {
+			if findDirectDep(mctx, name) == nil {
+				return "", fmt.Errorf("dep_outputs: %s is not a dependency of %s",
+					name, mctx.ModuleName())
+			}
+			return depOutputsTemplate(name), nil
+		})
+}
+
+// applyDepOutputs expands {{dep_outputs}} to the outputs of the named
+// dependency. It must be called when generating the module's build
+// actions, before the properties using it are read.
+func applyDepOutputs(ctx blueprint.ModuleContext) {
+	m, ok := ctx.Module().(featurable)
+	if !ok {
+		return
+	}
+
+	propfnmap := make(map[string]template.FuncMap)
+	addtoFuncmap(propfnmap, depOutputsPropNames(ctx.Module()), "dep_outputs",
+		func(name string) (string, error) {
+			dep, ok := findDirectDep(ctx, name).(phonyInterface)
+			if !ok {
+				return "", fmt.Errorf("dep_outputs: %s does not have outputs", name)
+			}
+			if gc, ok := getGenerateCommon(dep); ok {
+				return strings.Join(gc.outputs(), " "), nil
+			}
+			return strings.Join(dep.outputs(), " "), nil
+		})
+
+	for _, p := range m.featurableProperties() {
+		applyLateTemplateRecursive(reflect.Indirect(reflect.ValueOf(p)), nil, propfnmap)
 	}
 }
 
@@ -232,6 +362,7 @@ func applyLateTemplates(mctx blueprint.BaseModuleContext) {
 	// Set up {{match_srcs}} and {{add_if_supported}} handling
 	nonCompiledSources := setupMatchSources(mctx, propfnmap)
 	setupAddIfSupported(mctx, propfnmap)
+	setupDepOutputs(mctx, propfnmap)
 
 	// Add more late templates above this line
 
@@ -69,17 +69,106 @@ type BuildProps struct {
 	Conlyflags []string
 	// Flags used for C++ compilation
 	Cxxflags []string
+	// Whether C++ sources are compiled with exceptions. When unset, the
+	// compiler's default is used.
+	Exceptions *bool
+	// Whether C++ sources are compiled with RTTI. When unset, the
+	// compiler's default is used.
+	Rtti *bool
 	// Flags used for assembly compilation
 	Asflags []string
 	// Flags used for linking
 	Ldflags []string
+	// The memory allocator linked into a binary, replacing the one in
+	// the C library
+	Allocator *string
+	// Link a binary statically, without shared libraries or a dynamic
+	// loader. Static libraries are used for its ldlibs.
+	Static_executable *bool
+	// Link a binary statically as a position independent executable,
+	// which relocates itself when it starts. Only supported on the
+	// Linux backend.
+	Static_pie *bool
 	// Same as ldflags, but specified on static libraries and propagated to
 	// the top-level build object.
 	Export_ldflags []string
+	// Remove unused functions and data when linking. The objects of
+	// the module, and of the static libraries it links, are compiled
+	// with a section per function and data object.
+	Gc_sections *bool
+	// Symbols which gc_sections must keep, although nothing in the
+	// link refers to them
+	Keep_symbols []string
+	// Symbols left global in a bob_relocatable_object. All other
+	// symbols are made local.
+	Global_symbols []string
+	// Set on static libraries linked by a module using gc_sections
+	GcSectionsObjects bool `blueprint:"mutated"`
 	// Shared library version
 	Library_version string
 	// Shared library version script
 	Version_script *string
+	// Generate the version script, instead of using version_script
+	Version_script_from struct {
+		// Headers whose declarations annotated with macro are exported
+		Headers []string
+		// The macro annotating exported declarations. Defaults to the
+		// export macro of hidden_visibility, e.g. LIBFOO_EXPORT.
+		Macro *string
+		// File listing exported symbols, one per line, in version nodes
+		// started by [NODE] lines
+		Symbol_list *string
+		// Version node of the symbols from headers, and of symbols
+		// before the first [NODE] line. Defaults to the export macro
+		// without _EXPORT, e.g. LIBFOO.
+		Node *string
+	}
+	// Version script written from version_script_from
+	GeneratedVersionScript *string `blueprint:"mutated"`
+	// Stub libraries built from the API of a bob_shared_library. Its
+	// users link against the stub of the last version, so they are only
+	// relinked when the API changes, and can only use symbols the API
+	// declares.
+	Stubs struct {
+		// Version script listing the symbols of the API
+		Symbol_file *string
+		// Versions of the API, oldest first
+		Versions []string
+	}
+	// Name of a pool defined in GENERATOR_POOLS or NINJA_POOLS, used to
+	// limit how many modules link in parallel, instead of the default
+	// link pool. Only supported on the Linux backend.
+	Link_pool *string
+	// Whether to compile a shared library with hidden symbol visibility,
+	// exporting only the symbols annotated with its export macro.
+	// Defaults to the HIDDEN_VISIBILITY option. Only supported on the
+	// Linux backend.
+	Hidden_visibility *bool
+	// Script or binary used by post_build_cmd
+	Post_build_tool *string
+	// Command run on the linked output before it is installed. ${in} is
+	// the linked output, and ${out} the file to install instead.
+	Post_build_cmd *string
+	// Arguments to the post build command
+	Post_build_args []string
+	// Name of the file written by post_build_cmd. Defaults to the name
+	// of the linked output.
+	Post_build_out *string
+	// Formats to convert a binary to with objcopy, in addition to the
+	// executable: binary, ihex or srec
+	Objcopy_formats []string
+	// Run Qt's moc on the headers in srcs which use Q_OBJECT or
+	// Q_GADGET, uic on .ui files and rcc on .qrc files, compiling the
+	// generated sources with the module
+	Qt *bool
+	// Flags passed to moc
+	Qt_moc_flags []string
+	// Flags passed to flex for each .l or .ll file in srcs
+	Flex_flags []string
+	// Flags passed to bison for each .y or .yy file in srcs
+	Bison_flags []string
+	// Lexers and grammars in srcs, run through flex and bison
+	ParserSrcs []string `blueprint:"mutated"`
 
 	// The list of shared lib modules that this library depends on.
 	// These are propagated to the closest linking object when specified on static libraries.
@@ -110,6 +199,11 @@ type BuildProps struct {
 	// from dependent libraries
 	Whole_static_libs []string `bob:"first_overrides"`
 
+	// Static libraries which depend on each other. They are linked as a
+	// group, which the linker searches repeatedly, rather than once in
+	// order. Each must be one of the static libraries of the module.
+	Link_group []string
+
 	// List of libraries to import headers from, but not link to
 	Header_libs []string `bob:"first_overrides"`
 
@@ -147,6 +241,25 @@ type BuildProps struct {
 	// their dependencies at runtime.
 	Add_lib_dirs_to_rpath *bool
 
+	// Run-time search paths. Relative paths are relative to the
+	// directory the module is installed in.
+	Rpath []string
+
+	// Adds the install directories of the shared libraries the module
+	// links with to its run-time search path, relative to its own, and
+	// checks that they are installed
+	Use_origin_rpath *bool
+
+	// clang-tidy checks to enable or disable for this module, in addition
+	// to CLANG_TIDY_CHECKS. Only used when CLANG_TIDY is enabled.
+	Tidy_checks []string
+
+	// Do not run clang-tidy on the sources of this module
+	Tidy_disabled *bool
+
+	// Do not check the formatting of the sources of this module
+	Format_check_disabled *bool
+
 	// This is a shared library that pulls in one or more shared
 	// libraries to resolve symbols that the binary needs. This is
 	// useful where a named library is the standard library to link
@@ -160,9 +273,15 @@ type BuildProps struct {
 	// requires the BFD linker.
 	Forwarding_shlib *bool
 
+	// Environment variables set when compiling, archiving and
+	// linking. Only supported on Linux.
+	EnvProps
+
 	StripProps
 	AndroidPGOProps
 	AndroidMTEProps
+	AndroidSdkProps
+	AndroidAuxProps
 
 	TargetType tgtType `blueprint:"mutated"`
 }
@@ -193,8 +312,9 @@ func (b *BuildProps) processBuildWrapper(ctx blueprint.BaseModuleContext) {
 type Build struct {
 	CommonProps
 	BuildProps
-	Target TargetSpecific
-	Host   TargetSpecific
+	Target     TargetSpecific
+	Host       TargetSpecific
+	Host_cross TargetSpecific
 	SplittableProps
 }
 
@@ -203,6 +323,8 @@ func (l *Build) getTargetSpecific(tgt tgtType) *TargetSpecific {
 		return &l.Host
 	} else if tgt == tgtTypeTarget {
 		return &l.Target
+	} else if tgt == tgtTypeHostCross {
+		return &l.Host_cross
 	} else {
 		utils.Die("Unsupported target type: %s", tgt)
 	}
@@ -226,6 +348,13 @@ func (l *Build) isTargetSupported() bool {
 	return *l.Target_supported
 }
 
+func (l *Build) isHostCrossSupported() bool {
+	if l.Host_cross_supported == nil {
+		return false
+	}
+	return *l.Host_cross_supported
+}
+
 func (l *Build) isForwardingSharedLibrary() bool {
 	if l.Forwarding_shlib == nil {
 		return false
@@ -233,6 +362,10 @@ func (l *Build) isForwardingSharedLibrary() bool {
 	return *l.Forwarding_shlib
 }
 
+func (b *BuildProps) hasStubs() bool {
+	return b.Stubs.Symbol_file != nil || len(b.Stubs.Versions) > 0
+}
+
 func (l *Build) isRpathWanted() bool {
 	if l.Add_lib_dirs_to_rpath == nil {
 		return false
@@ -257,13 +390,24 @@ func (l *Build) getBuildWrapperAndDeps(ctx blueprint.ModuleContext) (string, []s
 	return "", []string{}
 }
 
-// Add module paths to srcs, exclude_srcs, local_include_dirs, export_local_include_dirs
-// and post_install_tool
+// getCommandWrapperAndDeps returns the wrappers run before the compiler,
+// archiver and linker: the env property, followed by build_wrapper.
+func (l *Build) getCommandWrapperAndDeps(ctx blueprint.ModuleContext) (string, []string) {
+	buildWrapper, deps := l.getBuildWrapperAndDeps(ctx)
+	return utils.Join([]string{l.envCommandPrefix(), buildWrapper}), deps
+}
+
+// Add module paths to srcs, exclude_srcs, local_include_dirs, export_local_include_dirs,
+// post_install_tool and post_build_tool
 func (l *BuildProps) processPaths(ctx blueprint.BaseModuleContext, g generatorBackend) {
 	prefix := projectModuleDir(ctx)
 
 	l.Export_local_include_dirs = utils.PrefixDirs(l.Export_local_include_dirs, prefix)
 	l.processBuildWrapper(ctx)
+	l.checkEnv(ctx)
+	if l.Post_build_tool != nil {
+		*l.Post_build_tool = getBackendPathInSourceDir(g, prefix, *l.Post_build_tool)
+	}
 }
 
 func (l *Build) processPaths(ctx blueprint.BaseModuleContext, g generatorBackend) {
@@ -284,6 +428,32 @@ type library struct {
 	moduleBase
 	simpleOutputProducer
 
+	// Directory of the objects compiled from the library's sources
+	objDir string
+
+	// Stamp files of the clang-tidy runs on this library's sources
+	tidyOuts []string
+
+	// The results of checking the formatting of the sources
+	formatCheckOuts []string
+
+	// Link map written when linking this library, if any
+	linkMap string
+
+	// The output of nm and size on the linked file, for the code size
+	// report, if the toolchain has them
+	symbolSizes  string
+	sectionSizes string
+
+	// Files written by post_build_cmd, installed instead of the outputs
+	postBuildOuts []string
+
+	// Warnings of each compilation, when a warning budget covers the library
+	warningLogOuts []string
+
+	// What the library inherits from its dependencies
+	exported exportedVariables
+
 	Properties struct {
 		Features
 		Build
@@ -393,6 +563,9 @@ func (l *library) supportedVariants() (tgts []tgtType) {
 	if l.Properties.isTargetSupported() {
 		tgts = append(tgts, tgtTypeTarget)
 	}
+	if l.Properties.isHostCrossSupported() {
+		tgts = append(tgts, tgtTypeHostCross)
+	}
 	return
 }
 
@@ -480,83 +653,28 @@ func (l *library) shortName() string {
 	return l.Name()
 }
 
+// GetGeneratedHeaders returns the include directories and outputs of the
+// generated headers used by the library. These come from
+// generated_headers and export_generated_headers, generated libraries in
+// static_libs and shared_libs, and the export_generated_headers of other
+// libraries in static_libs and shared_libs.
 func (l *library) GetGeneratedHeaders(ctx blueprint.ModuleContext) (includeDirs []string, orderOnly []string) {
-	visited := map[string]bool{}
-
-	mainModule := ctx.Module()
-
-	ctx.WalkDeps(func(child, parent blueprint.Module) bool {
-
-		tag := ctx.OtherModuleDependencyTag(child)
-
-		/* We want all the export_gen_include_dirs from generated modules mentioned by the
-		 * main module, primarily from generated_headers, but also static_libs and
-		 * shared_libs where they refer to a bob_generated_[static|shared]_library.
-		 *
-		 * We also want all the export_generated_headers from libraries mentioned by the main
-		 * module, i.e. from static_libs and shared_libs, as well as
-		 * export_generated_headers from the main module itself.
-		 *
-		 * Note that generated_header and export_generated_header tags can't have child
-		 * generated_header, export_generated_header, static_libs or shared_libs tags,
-		 * because these are only added by libraries.
-		 */
-		importHeaderDirs := false
-		visitChildren := false
-		childMustBeGenerated := true
-		if parent == mainModule {
-			if tag == generatedHeaderTag || tag == exportGeneratedHeaderTag {
-				importHeaderDirs = true
-				visitChildren = false
-			} else if tag == staticDepTag || tag == sharedDepTag || tag == reexportLibsTag {
-				/* Try to import generated header dirs from static|shared_libs too:
-				 * - The library could be a bob_generate_shared_library or
-				 *   bob_generate_static_library, in which case we need to import
-				 *   any generated header dirs it exports.
-				 * - If it's a bob_static_library or bob_shared_library, it may
-				 *   export generated header dirs, so it's children need visiting.
-				 */
-				importHeaderDirs = true
-				visitChildren = true
-				// We don't know the module type so disable the check
-				childMustBeGenerated = false
-			}
-		} else {
-			if tag == exportGeneratedHeaderTag {
-				importHeaderDirs = true
-				visitChildren = false
-			}
-		}
-
-		if importHeaderDirs {
-			// Add include directories for any generated modules
-			if gs, ok := getGenerateCommon(child); ok {
-				// WalkDeps will visit a module once for each
-				// dependency tag. Only list the headers once.
-				if _, seen := visited[child.Name()]; !seen {
-					visited[child.Name()] = true
-
-					includeDirs = append(includeDirs, gs.genIncludeDirs()...)
-
-					// Generated headers are "order-only". That means that a source file does not need to rebuild
-					// if a generated header changes, just that it must be built after a generated header.
-					// The source file _will_ be rebuilt if it uses the header (since that is registered in the
-					// depfile). Note that this means that generated headers cannot change which headers are used
-					// (by aliasing another header).
-					ds, ok := child.(dependentInterface)
-					if !ok {
-						utils.Die("generated_headers %s must have outputs()", child.Name())
-					}
-
-					orderOnly = append(orderOnly, getHeadersGenerated(ds)...)
-				}
-			} else if childMustBeGenerated {
-				utils.Die("%s dependency on non-generated module %s", tag.(dependencyTag).name, child.Name())
-			}
+	for _, m := range l.exported.generatedHeaderModules {
+		gs, _ := getGenerateCommon(m)
+		includeDirs = append(includeDirs, gs.genIncludeDirs()...)
+
+		// Generated headers are "order-only". That means that a source file does not need to rebuild
+		// if a generated header changes, just that it must be built after a generated header.
+		// The source file _will_ be rebuilt if it uses the header (since that is registered in the
+		// depfile). Note that this means that generated headers cannot change which headers are used
+		// (by aliasing another header).
+		ds, ok := m.(dependentInterface)
+		if !ok {
+			utils.Die("generated_headers %s must have outputs()", m.Name())
 		}
 
-		return visitChildren
-	})
+		orderOnly = append(orderOnly, getHeadersGenerated(ds)...)
+	}
 	return
 }
 
@@ -572,30 +690,10 @@ func (l *library) getAllGeneratedSourceModules(ctx blueprint.ModuleContext) (mod
 	return
 }
 
+// GetExportedVariables returns the include directories and flags
+// exported to the library by the libraries it uses.
 func (l *library) GetExportedVariables(ctx blueprint.ModuleContext) (expLocalIncludes, expIncludes, expCflags []string) {
-	visited := map[string]bool{}
-	ctx.VisitDirectDeps(func(dep blueprint.Module) {
-
-		if !(ctx.OtherModuleDependencyTag(dep) == wholeStaticDepTag ||
-			ctx.OtherModuleDependencyTag(dep) == staticDepTag ||
-			ctx.OtherModuleDependencyTag(dep) == sharedDepTag ||
-			ctx.OtherModuleDependencyTag(dep) == reexportLibsTag) {
-			return
-		} else if _, ok := visited[dep.Name()]; ok {
-			// VisitDirectDeps will visit a module once for each
-			// dependency. We've already done this module.
-			return
-		}
-		visited[dep.Name()] = true
-
-		if pe, ok := dep.(propertyExporter); ok {
-			expLocalIncludes = append(expLocalIncludes, pe.exportLocalIncludeDirs()...)
-			expIncludes = append(expIncludes, pe.exportIncludeDirs()...)
-			expCflags = append(expCflags, pe.exportCflags()...)
-		}
-	})
-
-	return
+	return l.exported.localIncludeDirs, l.exported.includeDirs, l.exported.cflags
 }
 
 func (l *library) getVersionScript(ctx blueprint.ModuleContext) *string {
@@ -613,7 +711,7 @@ func (l *library) getVersionScript(ctx blueprint.ModuleContext) *string {
 		return &path
 	}
 
-	return nil
+	return l.Properties.GeneratedVersionScript
 }
 
 func (l *library) processPaths(ctx blueprint.BaseModuleContext, g generatorBackend) {
@@ -628,9 +726,23 @@ func (l *library) processPaths(ctx blueprint.BaseModuleContext, g generatorBacke
 			*versionScript = filepath.Join(projectModuleDir(ctx), *versionScript)
 		}
 	}
+
+	symbolFile := l.Properties.Build.Stubs.Symbol_file
+	if symbolFile != nil {
+		*symbolFile = filepath.Join(projectModuleDir(ctx), *symbolFile)
+	}
+
+	from := &l.Properties.Build.Version_script_from
+	from.Headers = utils.PrefixDirs(from.Headers, projectModuleDir(ctx))
+	if from.Symbol_list != nil {
+		*from.Symbol_list = filepath.Join(projectModuleDir(ctx), *from.Symbol_list)
+	}
 }
 
 func (m *library) filesToInstall(ctx blueprint.BaseModuleContext) []string {
+	if len(m.postBuildOuts) > 0 {
+		return m.postBuildOuts
+	}
 	return m.outputs()
 }
 
@@ -642,18 +754,32 @@ func (l *library) checkField(cond bool, fieldName string) {
 
 // All libraries must implement `propertyExporter`
 func (l *library) exportCflags() []string           { return l.Properties.Export_cflags }
-func (l *library) exportIncludeDirs() []string      { return l.Properties.Export_include_dirs }
 func (l *library) exportLocalIncludeDirs() []string { return l.Properties.Export_local_include_dirs }
 func (l *library) exportLdflags() []string          { return l.Properties.Export_ldflags }
 func (l *library) exportLdlibs() []string           { return l.Properties.Ldlibs }
 func (l *library) exportSharedLibs() []string       { return l.Properties.Shared_libs }
 
+// The headers written by bison, and the export header of libraries
+// compiled with hidden visibility, are exported with the include
+// directories
+func (l *library) exportIncludeDirs() []string {
+	return utils.NewStringSlice(l.Properties.Export_include_dirs, l.parserExportIncludeDirs(),
+		l.exportHeaderIncludeDirs())
+}
+
 type staticLibrary struct {
 	library
+
+	// Set for bob_relocatable_object, which links its objects and
+	// whole_static_libs into a single object rather than an archive
+	relocatable bool
 }
 
 func (m *staticLibrary) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
 	if isEnabled(m) {
+		applyDepOutputs(ctx)
 		getBackend(ctx).staticActions(m, ctx)
 	}
 }
@@ -661,6 +787,9 @@ func (m *staticLibrary) GenerateBuildActions(ctx blueprint.ModuleContext) {
 //// Support singleOutputModule
 
 func (m *staticLibrary) outputFileName() string {
+	if m.relocatable {
+		return m.outputName() + ".o"
+	}
 	return m.outputName() + ".a"
 }
 
@@ -723,7 +852,10 @@ func (m *sharedLibrary) librarySymlinks(ctx blueprint.ModuleContext) map[string]
 }
 
 func (m *sharedLibrary) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
 	if isEnabled(m) {
+		applyDepOutputs(ctx)
 		getBackend(ctx).sharedActions(m, ctx)
 	}
 }
@@ -745,6 +877,12 @@ func (m *sharedLibrary) getTocName() string {
 
 type binary struct {
 	library
+
+	// Files converted to other formats by objcopy_formats
+	objcopyOuts []string
+	// The extension of the host_cross variant, from
+	// HOST_CROSS_EXECUTABLE_EXTENSION
+	hostCrossExtension string
 }
 
 // binary supports:
@@ -758,7 +896,10 @@ func (l *binary) strip() bool {
 }
 
 func (m *binary) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
 	if isEnabled(m) {
+		applyDepOutputs(ctx)
 		getBackend(ctx).binaryActions(m, ctx)
 	}
 }
@@ -766,6 +907,9 @@ func (m *binary) GenerateBuildActions(ctx blueprint.ModuleContext) {
 //// Support singleOutputModule
 
 func (m *binary) outputFileName() string {
+	if m.Properties.TargetType == tgtTypeHostCross {
+		return m.outputName() + m.hostCrossExtension
+	}
 	return m.outputName()
 }
 
@@ -773,6 +917,7 @@ func (l *library) LibraryFactory(config *bobConfig, module blueprint.Module) (bl
 	l.Properties.Features.Init(&config.Properties, CommonProps{}, BuildProps{}, SplittableProps{})
 	l.Properties.Host.init(&config.Properties, CommonProps{}, BuildProps{})
 	l.Properties.Target.init(&config.Properties, CommonProps{}, BuildProps{})
+	l.Properties.Host_cross.init(&config.Properties, CommonProps{}, BuildProps{})
 
 	return module, []interface{}{&l.Properties, &l.SimpleName.Properties}
 }
@@ -782,6 +927,11 @@ func staticLibraryFactory(config *bobConfig) (blueprint.Module, []interface{}) {
 	return module.LibraryFactory(config, module)
 }
 
+func relocatableObjectFactory(config *bobConfig) (blueprint.Module, []interface{}) {
+	module := &staticLibrary{relocatable: true}
+	return module.LibraryFactory(config, module)
+}
+
 func sharedLibraryFactory(config *bobConfig) (blueprint.Module, []interface{}) {
 	module := &sharedLibrary{}
 	if config.Properties.GetBool("osx") {
@@ -794,6 +944,9 @@ func sharedLibraryFactory(config *bobConfig) (blueprint.Module, []interface{}) {
 
 func binaryFactory(config *bobConfig) (blueprint.Module, []interface{}) {
 	module := &binary{}
+	if hostCrossEnabled(&config.Properties) {
+		module.hostCrossExtension = config.Properties.GetString("host_cross_executable_extension")
+	}
 	return module.LibraryFactory(config, module)
 }
 
@@ -819,6 +972,10 @@ func getLibrary(m blueprint.Module) (*library, bool) {
 
 func checkLibraryFieldsMutator(mctx blueprint.BottomUpMutatorContext) {
 	m := mctx.Module()
+	if l, ok := getLibrary(m); ok && l.qtEnabled() &&
+		!getConfig(mctx).Properties.GetBool("builder_ninja") {
+		mctx.PropertyErrorf("qt", "is only supported by the Linux backend")
+	}
 	if b, ok := m.(*binary); ok {
 		props := b.Properties
 		b.checkField(len(props.Export_cflags) == 0, "export_cflags")
@@ -827,17 +984,39 @@ func checkLibraryFieldsMutator(mctx blueprint.BottomUpMutatorContext) {
 		b.checkField(len(props.Export_local_include_dirs) == 0, "export_local_include_dirs")
 		b.checkField(len(props.Reexport_libs) == 0, "reexport_libs")
 		b.checkField(props.Forwarding_shlib == nil, "forwarding_shlib")
+		b.checkField(len(props.Global_symbols) == 0, "global_symbols")
+		b.checkField(!props.hasStubs(), "stubs")
 	} else if sl, ok := m.(*sharedLibrary); ok {
 		props := sl.Properties
+		if len(props.Stubs.Versions) > 0 && props.Stubs.Symbol_file == nil {
+			mctx.PropertyErrorf("stubs", "versions requires a symbol_file")
+		}
+		sl.checkField(len(props.Global_symbols) == 0, "global_symbols")
 		sl.checkField(len(props.Export_ldflags) == 0, "export_ldflags")
 		sl.checkField(props.Mte.Memtag_heap == nil, "memtag_heap")
 		sl.checkField(props.Mte.Diag_memtag_heap == nil, "memtag_heap")
+		sl.checkField(props.Allocator == nil, "allocator")
+		sl.checkField(props.Static_executable == nil, "static_executable")
+		sl.checkField(props.Static_pie == nil, "static_pie")
+		sl.checkField(len(props.Objcopy_formats) == 0, "objcopy_formats")
 	} else if sl, ok := m.(*staticLibrary); ok {
 		props := sl.Properties
 		sl.checkField(props.Forwarding_shlib == nil, "forwarding_shlib")
 		sl.checkField(props.Version_script == nil, "version_script")
+		sl.checkField(!props.versionScriptGenerated(), "version_script_from")
+		sl.checkField(!props.hasStubs(), "stubs")
+		sl.checkField(props.Link_pool == nil, "link_pool")
+		sl.checkField(len(props.Link_group) == 0, "link_group")
+		sl.checkField(len(props.Keep_symbols) == 0, "keep_symbols")
+		sl.checkField(len(props.Objcopy_formats) == 0, "objcopy_formats")
 		sl.checkField(props.Mte.Memtag_heap == nil, "memtag_heap")
 		sl.checkField(props.Mte.Diag_memtag_heap == nil, "memtag_heap")
+		sl.checkField(props.Allocator == nil, "allocator")
+		sl.checkField(props.Static_executable == nil, "static_executable")
+		sl.checkField(props.Static_pie == nil, "static_pie")
+		if !sl.relocatable {
+			sl.checkField(len(props.Global_symbols) == 0, "global_symbols")
+		}
 	}
 }
 
@@ -1014,7 +1193,8 @@ func (handler *graphMutatorHandler) ResolveDependencySortMutator(mctx blueprint.
 	}
 
 	// This mutator is run after host/target splitting, so TargetType should have been set.
-	if !(mainBuild.TargetType == tgtTypeTarget || mainBuild.TargetType == tgtTypeHost) {
+	if !(mainBuild.TargetType == tgtTypeTarget || mainBuild.TargetType == tgtTypeHost ||
+		mainBuild.TargetType == tgtTypeHostCross) {
 		utils.Die("Cannot process dependencies on module '%s' with target type '%s'", mainModuleName, mainBuild.TargetType)
 	}
 
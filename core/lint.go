@@ -0,0 +1,338 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// The version of the lint.json format. As with build_graph.json, this
+// is only incremented when a field is removed or changes meaning.
+const lintVersion = 1
+
+const lintFile = "lint.json"
+
+const (
+	lintWarning = "warning"
+	lintError   = "error"
+)
+
+// lintDiagnostic is a single problem found in a module definition
+type lintDiagnostic struct {
+	File     string `json:"file"`
+	Module   string `json:"module"`
+	Property string `json:"property"`
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// lintReport is the content of lint.json
+type lintReport struct {
+	Version     int              `json:"version"`
+	Diagnostics []lintDiagnostic `json:"diagnostics"`
+}
+
+// lintProblem is a problem reported by a check, before the module
+// and severity are known.
+type lintProblem struct {
+	property string
+	message  string
+}
+
+type lintCheck struct {
+	name string
+	run  func(dir string, m blueprint.Module) []lintProblem
+}
+
+var lintChecks = []lintCheck{
+	{"export_include_dir_outside_module", lintExportIncludeDirs},
+	{"linker_flag_in_cflags", lintLinkerFlags},
+	{"compile_flag_in_ldflags", lintCompileFlags},
+	{"conflicting_properties", lintConflictingProperties},
+}
+
+func lintCheckNames() []string {
+	names := []string{}
+	for _, c := range lintChecks {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+func lintEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["lint"]
+	return ok && props.GetBool("lint")
+}
+
+// lintConfigChecks returns the checks listed in a config option, and
+// dies if any of them do not exist.
+func lintConfigChecks(config *bobConfig, key string) []string {
+	value, ok := config.Properties.properties[key]
+	if !ok {
+		return []string{}
+	}
+	checks := strings.Fields(value.(string))
+	known := lintCheckNames()
+	for _, check := range checks {
+		if !utils.Contains(known, check) {
+			utils.Die("%s: unknown check %s. The checks are: %s",
+				strings.ToUpper(key), check, strings.Join(known, ", "))
+		}
+	}
+	return checks
+}
+
+// lintExportIncludeDirs reports export_local_include_dirs which are
+// outside the module's directory. Modules using the library can then
+// see headers belonging to another module, without depending on it.
+func lintExportIncludeDirs(dir string, m blueprint.Module) []lintProblem {
+	pe, ok := m.(propertyExporter)
+	if !ok {
+		return nil
+	}
+	problems := []lintProblem{}
+	for _, include := range pe.exportLocalIncludeDirs() {
+		rel, err := filepath.Rel(dir, filepath.Clean(include))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			problems = append(problems, lintProblem{"export_local_include_dirs",
+				fmt.Sprintf("%s is outside the module directory", rel)})
+		}
+	}
+	return problems
+}
+
+func isLinkerFlag(flag string) bool {
+	return strings.HasPrefix(flag, "-Wl,") || strings.HasPrefix(flag, "-L") ||
+		(strings.HasPrefix(flag, "-l") && len(flag) > 2) || flag == "-rdynamic"
+}
+
+func isCompileFlag(flag string) bool {
+	for _, prefix := range []string{"-D", "-U", "-I", "-isystem", "-std="} {
+		if strings.HasPrefix(flag, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// lintProperty is the value of a list property checked by lint
+type lintProperty struct {
+	name   string
+	values []string
+}
+
+// lintFlags reports the flags in each property which match a predicate
+func lintFlags(props []lintProperty, match func(string) bool, message string) []lintProblem {
+	problems := []lintProblem{}
+	for _, prop := range props {
+		for _, flag := range prop.values {
+			if match(flag) {
+				problems = append(problems, lintProblem{prop.name, fmt.Sprintf(message, flag)})
+			}
+		}
+	}
+	return problems
+}
+
+// lintLinkerFlags reports linker flags passed to the compiler, where
+// they are either ignored or only cause a warning.
+func lintLinkerFlags(dir string, m blueprint.Module) []lintProblem {
+	l, ok := getLibrary(m)
+	if !ok {
+		return nil
+	}
+	return lintFlags([]lintProperty{
+		{"cflags", l.Properties.Cflags},
+		{"conlyflags", l.Properties.Conlyflags},
+		{"cxxflags", l.Properties.Cxxflags},
+		{"export_cflags", l.Properties.Export_cflags},
+	}, isLinkerFlag, "%s is a linker flag, and belongs in ldflags or ldlibs")
+}
+
+// lintCompileFlags reports compiler flags passed to the linker, which
+// has no effect as the sources have already been compiled.
+func lintCompileFlags(dir string, m blueprint.Module) []lintProblem {
+	l, ok := getLibrary(m)
+	if !ok {
+		return nil
+	}
+	return lintFlags([]lintProperty{
+		{"ldflags", l.Properties.Ldflags},
+		{"export_ldflags", l.Properties.Export_ldflags},
+	}, isCompileFlag, "%s is a compiler flag, and belongs in cflags")
+}
+
+// lintConflictingProperties reports properties which contradict each
+// other, so that one of them is ignored.
+func lintConflictingProperties(dir string, m blueprint.Module) []lintProblem {
+	problems := []lintProblem{}
+
+	if l, ok := getLibrary(m); ok {
+		props := &l.Properties
+		seen := map[string]string{}
+		for _, list := range []lintProperty{
+			{"shared_libs", props.Shared_libs},
+			{"static_libs", props.Static_libs},
+			{"whole_static_libs", props.Whole_static_libs},
+		} {
+			for _, lib := range list.values {
+				if other, ok := seen[lib]; ok && other != list.name {
+					problems = append(problems, lintProblem{list.name,
+						fmt.Sprintf("%s is also in %s", lib, other)})
+				} else {
+					seen[lib] = list.name
+				}
+			}
+		}
+		if props.Tidy_disabled != nil && *props.Tidy_disabled && len(props.Tidy_checks) > 0 {
+			problems = append(problems, lintProblem{"tidy_checks",
+				"is ignored, as tidy_disabled is set"})
+		}
+	}
+
+	if i, ok := m.(installable); ok {
+		props := i.getInstallableProps()
+		if props.Install_group == nil && props.Relative_install_path != nil {
+			problems = append(problems, lintProblem{"relative_install_path",
+				"is ignored, as there is no install_group"})
+		}
+	}
+
+	return problems
+}
+
+// lintModule runs the enabled checks on a module
+func lintModule(file, name, dir string, m blueprint.Module,
+	disabled, errors []string) []lintDiagnostic {
+	diagnostics := []lintDiagnostic{}
+	for _, check := range lintChecks {
+		if utils.Contains(disabled, check.name) {
+			continue
+		}
+		severity := lintWarning
+		if utils.Contains(errors, check.name) {
+			severity = lintError
+		}
+		for _, p := range check.run(dir, m) {
+			diagnostics = append(diagnostics, lintDiagnostic{
+				File:     file,
+				Module:   name,
+				Property: p.property,
+				Check:    check.name,
+				Severity: severity,
+				Message:  p.message,
+			})
+		}
+	}
+	return diagnostics
+}
+
+// lintUnique sorts diagnostics, and removes those repeated by the
+// host and target variants of a module.
+func lintUnique(diagnostics []lintDiagnostic) []lintDiagnostic {
+	sort.Slice(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		for _, cmp := range [][2]string{
+			{a.File, b.File}, {a.Module, b.Module}, {a.Property, b.Property},
+			{a.Check, b.Check}, {a.Message, b.Message},
+		} {
+			if cmp[0] != cmp[1] {
+				return cmp[0] < cmp[1]
+			}
+		}
+		return false
+	})
+
+	unique := []lintDiagnostic{}
+	for i, d := range diagnostics {
+		if i == 0 || d != diagnostics[i-1] {
+			unique = append(unique, d)
+		}
+	}
+	return unique
+}
+
+// lintContent returns lint.json
+func lintContent(diagnostics []lintDiagnostic) (string, error) {
+	report := lintReport{Version: lintVersion, Diagnostics: diagnostics}
+	data, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// lintSingleton checks every enabled module, and writes lint.json
+// before reporting anything, so that the file is available to tools
+// even when one of the checks makes generation fail.
+type lintSingleton struct{}
+
+func (s *lintSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	config := getConfig(ctx)
+	disabled := lintConfigChecks(config, "lint_disabled_checks")
+	errors := lintConfigChecks(config, "lint_error_checks")
+
+	diagnostics := []lintDiagnostic{}
+	modules := map[string]blueprint.Module{}
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if _, ok := m.(*defaults); ok {
+			return
+		}
+		if e, ok := m.(enableable); ok && !isEnabled(e) {
+			return
+		}
+		name := ctx.ModuleName(m)
+		modules[name] = m
+		diagnostics = append(diagnostics, lintModule(ctx.BlueprintFile(m), name,
+			ctx.ModuleDir(m), m, disabled, errors)...)
+	})
+
+	diagnostics = lintUnique(diagnostics)
+	content, err := lintContent(diagnostics)
+	if err != nil {
+		utils.Die("%v", err)
+	}
+	sb := &strings.Builder{}
+	sb.WriteString(content)
+	err = getBackendOutput(config).writeFile(getPathInBuildDir(lintFile), sb)
+	if err != nil {
+		utils.Die("%v", err)
+	}
+
+	for _, d := range diagnostics {
+		if d.Severity == lintError {
+			ctx.ModuleErrorf(modules[d.Module], "%s: %s [%s]", d.Property, d.Message, d.Check)
+		} else {
+			utils.Warnf(d.File, "%s: %s: %s [%s]", d.Module, d.Property, d.Message, d.Check)
+		}
+	}
+}
+
+func lintSingletonFactory() blueprint.Singleton {
+	return &lintSingleton{}
+}
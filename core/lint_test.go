@@ -0,0 +1,93 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lintTestLibrary() *staticLibrary {
+	m := &staticLibrary{}
+	m.Properties.Export_local_include_dirs = []string{"foo/include", "foo/../bar/include"}
+	m.Properties.Cflags = []string{"-O2", "-lm", "-Wl,--gc-sections"}
+	m.Properties.Ldflags = []string{"-DFOO=1", "-Lfoo/lib"}
+	m.Properties.Shared_libs = []string{"libbar"}
+	m.Properties.Static_libs = []string{"libbar", "libbaz"}
+	return m
+}
+
+func Test_lintModule(t *testing.T) {
+	diagnostics := lintModule("foo/build.bp", "libfoo", "foo", lintTestLibrary(),
+		[]string{}, []string{"conflicting_properties"})
+
+	assert.Equal(t, []lintDiagnostic{
+		{"foo/build.bp", "libfoo", "export_local_include_dirs",
+			"export_include_dir_outside_module", lintWarning, "../bar/include is outside the module directory"},
+		{"foo/build.bp", "libfoo", "cflags",
+			"linker_flag_in_cflags", lintWarning, "-lm is a linker flag, and belongs in ldflags or ldlibs"},
+		{"foo/build.bp", "libfoo", "cflags",
+			"linker_flag_in_cflags", lintWarning, "-Wl,--gc-sections is a linker flag, and belongs in ldflags or ldlibs"},
+		{"foo/build.bp", "libfoo", "ldflags",
+			"compile_flag_in_ldflags", lintWarning, "-DFOO=1 is a compiler flag, and belongs in cflags"},
+		{"foo/build.bp", "libfoo", "static_libs",
+			"conflicting_properties", lintError, "libbar is also in shared_libs"},
+	}, diagnostics)
+}
+
+func Test_lintModule_disabled_checks(t *testing.T) {
+	disabled := lintCheckNames()
+	assert.Empty(t, lintModule("foo/build.bp", "libfoo", "foo", lintTestLibrary(),
+		disabled, []string{}))
+}
+
+func Test_lintConflictingProperties_install_and_tidy(t *testing.T) {
+	m := &staticLibrary{}
+	m.Properties.Relative_install_path = new(string)
+	m.Properties.Tidy_disabled = new(bool)
+	m.Properties.Tidy_checks = []string{"-*"}
+
+	assert.Equal(t, []lintProblem{
+		{"relative_install_path", "is ignored, as there is no install_group"},
+	}, lintConflictingProperties("foo", m))
+
+	*m.Properties.Tidy_disabled = true
+	assert.Equal(t, []lintProblem{
+		{"tidy_checks", "is ignored, as tidy_disabled is set"},
+		{"relative_install_path", "is ignored, as there is no install_group"},
+	}, lintConflictingProperties("foo", m))
+}
+
+func Test_lintContent_removes_repeated_variants(t *testing.T) {
+	d := lintDiagnostic{"foo/build.bp", "libfoo", "cflags", "linker_flag_in_cflags", lintWarning, "-lm"}
+	other := lintDiagnostic{"bar/build.bp", "libbar", "ldflags", "compile_flag_in_ldflags", lintWarning, "-DX"}
+
+	diagnostics := lintUnique([]lintDiagnostic{d, other, d})
+	assert.Equal(t, []lintDiagnostic{other, d}, diagnostics)
+
+	content, err := lintContent(diagnostics)
+	assert.Nil(t, err)
+
+	report := lintReport{}
+	assert.Nil(t, json.Unmarshal([]byte(content), &report))
+	assert.Equal(t, lintVersion, report.Version)
+	assert.Equal(t, diagnostics, report.Diagnostics)
+	assert.Contains(t, content, `"severity": "warning"`)
+}
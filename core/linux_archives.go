@@ -0,0 +1,72 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+func thinArchivesEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["static_lib_thin_archives"]
+	return ok && props.GetBool("static_lib_thin_archives")
+}
+
+func deterministicArchivesEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["deterministic_archives"]
+	return ok && props.GetBool("deterministic_archives")
+}
+
+// archiverModifiers returns the operation and modifiers used to write
+// static libraries with a toolchain's archiver.
+func archiverModifiers(config *bobConfig, tc toolchain) string {
+	modifiers := "rcs"
+	thin, deterministic := tc.getArchiverModifiers()
+	if thinArchivesEnabled(config) {
+		modifiers += thin
+	}
+	if deterministicArchivesEnabled(config) {
+		modifiers += deterministic
+	}
+	return modifiers
+}
+
+// archiverEnv returns the environment the archiver runs in. For
+// deterministic archives, tools which read SOURCE_DATE_EPOCH use it
+// rather than the current time, unless the build sets it.
+func archiverEnv(config *bobConfig) string {
+	if deterministicArchivesEnabled(config) {
+		return "SOURCE_DATE_EPOCH=$${SOURCE_DATE_EPOCH:-0}"
+	}
+	return ""
+}
+
+// checkArchiverModifiers reports archive options that the archiver of
+// any of the toolchains does not support.
+func (tcs *toolchainSet) checkArchiverModifiers(config *bobConfig) {
+	for _, tgt := range availableTargetTypes(&config.Properties) {
+		thin, deterministic := tcs.getToolchain(tgt).getArchiverModifiers()
+		if thinArchivesEnabled(config) && thin == "" {
+			utils.Die("STATIC_LIB_THIN_ARCHIVES is not supported by the %s archiver", tgt)
+		}
+		if deterministicArchivesEnabled(config) && deterministic == "" {
+			utils.Die("DETERMINISTIC_ARCHIVES is not supported by the %s archiver", tgt)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// archiverStub only implements the part of toolchain used to select
+// archiver modifiers.
+type archiverStub struct {
+	toolchain
+	thin, deterministic string
+}
+
+func (tc archiverStub) getArchiverModifiers() (string, string) {
+	return tc.thin, tc.deterministic
+}
+
+func Test_archiverModifiers(t *testing.T) {
+	config := &bobConfig{}
+	config.Properties.properties = map[string]interface{}{}
+	tc := archiverStub{thin: "T", deterministic: "D"}
+
+	assert.Equal(t, "rcs", archiverModifiers(config, tc))
+	assert.Equal(t, "", archiverEnv(config))
+
+	config.Properties.properties["static_lib_thin_archives"] = true
+	assert.Equal(t, "rcsT", archiverModifiers(config, tc))
+
+	config.Properties.properties["deterministic_archives"] = true
+	assert.Equal(t, "rcsTD", archiverModifiers(config, tc))
+	assert.Equal(t, "SOURCE_DATE_EPOCH=$${SOURCE_DATE_EPOCH:-0}", archiverEnv(config))
+
+	config.Properties.properties["static_lib_thin_archives"] = false
+	assert.Equal(t, "rcsD", archiverModifiers(config, tc))
+}
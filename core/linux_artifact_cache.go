@@ -0,0 +1,148 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+var _ = pctx.StaticVariable("artifact_cache_tool", "${BobScriptsDir}/artifact_cache.py")
+
+func artifactCacheDir(config *bobConfig) string {
+	props := config.Properties
+	if _, ok := props.properties["artifact_cache_dir"]; !ok {
+		return ""
+	}
+	return props.GetString("artifact_cache_dir")
+}
+
+func artifactCacheURL(config *bobConfig) string {
+	props := config.Properties
+	if _, ok := props.properties["artifact_cache_url"]; !ok {
+		return ""
+	}
+	return props.GetString("artifact_cache_url")
+}
+
+// artifactCacheAllActions returns whether compiles and generated
+// files are cached, as well as archives and links.
+func artifactCacheAllActions(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["artifact_cache_all_actions"]
+	return ok && props.GetBool("artifact_cache_all_actions")
+}
+
+// artifactCacheArgs returns the arguments locating the cache, or nil
+// when neither a cache directory nor a cache server is set.
+func artifactCacheArgs(config *bobConfig) []string {
+	dir := artifactCacheDir(config)
+	url := artifactCacheURL(config)
+	if dir == "" && url == "" {
+		return nil
+	}
+
+	args := []string{}
+	if dir != "" {
+		args = append(args, "--cache-dir", dir)
+	}
+	if url != "" {
+		args = append(args, "--url", url)
+	}
+	return append(args, "--build-dir", "${BuildDir}")
+}
+
+// artifactCacheWrapper returns the command prefix which runs an archive
+// or link through the artifact cache, or an empty string when the cache
+// is disabled. The prefix is put before the build wrapper, so 'outputs'
+// must list every file the command writes. 'tool' is the archiver or
+// linker, whose contents identify the toolchain in the cache key.
+func artifactCacheWrapper(ctx blueprint.ModuleContext, tool string, outputs []string) string {
+	args := artifactCacheArgs(getConfig(ctx))
+	if args == nil {
+		return ""
+	}
+
+	return utils.Join([]string{"$artifact_cache_tool"}, args,
+		[]string{"--tool", tool},
+		utils.PrefixAll(outputs, "--output "),
+		[]string{"--"})
+}
+
+// artifactCacheCompileWrapper returns the command prefix which runs a
+// C or C++ compile through the artifact cache. The key is made from the
+// preprocessed source, so that it covers the headers the source
+// includes. The object must be the first output.
+func artifactCacheCompileWrapper(ctx blueprint.ModuleContext, compiler string, outputs []string) string {
+	if !artifactCacheAllActions(getConfig(ctx)) {
+		return ""
+	}
+	wrapper := artifactCacheWrapper(ctx, compiler, outputs)
+	if wrapper == "" {
+		return ""
+	}
+	return strings.Replace(wrapper, "$artifact_cache_tool", "$artifact_cache_tool --preprocess", 1)
+}
+
+// generatorActionKey identifies the command a generator runs on one set
+// of inputs, before ninja expands it. It does not depend on the build
+// directory, as ${BuildDir} has not been expanded.
+func generatorActionKey(cmd string, args map[string]string, rspContent string, io inout,
+	implicits []string) string {
+	h := sha256.New()
+	write := func(label string, values ...string) {
+		fmt.Fprintf(h, "%s\x00%d\x00%s\x00", label, len(values), strings.Join(values, "\x00"))
+	}
+	write("cmd", cmd)
+	for _, k := range utils.SortedKeys(args) {
+		write("arg", k, args[k])
+	}
+	write("rsp", rspContent)
+	write("in", io.in...)
+	write("out", io.out...)
+	write("implicit_outs", io.implicitOuts...)
+	write("implicit_srcs", io.implicitSrcs...)
+	write("implicits", implicits...)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// artifactCacheGeneratorCommand wraps a generator's command, so that its
+// outputs are restored from the artifact cache rather than generated,
+// and stored after generating them. The command may be a shell list, so
+// it runs in a subshell. Each build statement sets the cache arguments
+// in 'artifact_cache'.
+func artifactCacheGeneratorCommand(cmd string) string {
+	return "$artifact_cache_tool --restore $artifact_cache || { (" + cmd +
+		") && $artifact_cache_tool --store $artifact_cache; }"
+}
+
+// artifactCacheGeneratorArgs returns the 'artifact_cache' argument of a
+// generator build statement. The cache can only be used when every
+// input is known before running the command, so the caller must not
+// cache generators writing a depfile.
+func artifactCacheGeneratorArgs(config *bobConfig, key string, io inout, implicits []string) string {
+	return utils.Join(artifactCacheArgs(config),
+		[]string{"--action-key", key},
+		utils.PrefixAll(utils.NewStringSlice(io.in, io.implicitSrcs, implicits), "--input "),
+		utils.PrefixAll(utils.NewStringSlice(io.out, io.implicitOuts), "--output "))
+}
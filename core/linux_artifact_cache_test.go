@@ -0,0 +1,59 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_artifactCacheArgs(t *testing.T) {
+	config := &bobConfig{}
+	config.Properties.properties = map[string]interface{}{}
+	assert.Nil(t, artifactCacheArgs(config))
+
+	config.Properties.properties["artifact_cache_url"] = "http://cache/bob"
+	assert.Equal(t, []string{"--url", "http://cache/bob", "--build-dir", "${BuildDir}"},
+		artifactCacheArgs(config))
+
+	config.Properties.properties["artifact_cache_dir"] = "/cache"
+	assert.Equal(t, []string{"--cache-dir", "/cache", "--url", "http://cache/bob",
+		"--build-dir", "${BuildDir}"}, artifactCacheArgs(config))
+}
+
+func Test_generatorActionKey(t *testing.T) {
+	io := inout{
+		in:  []string{"a.in"},
+		out: []string{"${BuildDir}/gen/a.c"},
+	}
+	args := map[string]string{"tool": "gen.py"}
+	key := generatorActionKey("${tool} ${in} ${out}", args, "", io, nil)
+	assert.Equal(t, key, generatorActionKey("${tool} ${in} ${out}", args, "", io, nil))
+
+	// Arguments are hashed separately, so moving text between them changes the key
+	assert.NotEqual(t, key, generatorActionKey("${tool} ${in} ${out}",
+		map[string]string{"tool": "gen.p", "y": ""}, "", io, nil))
+	assert.NotEqual(t, key, generatorActionKey("${tool} ${in} ${out}", args, "rsp", io, nil))
+	assert.NotEqual(t, key, generatorActionKey("${tool} ${in} ${out}", args, "", io,
+		[]string{"gen.py"}))
+
+	other := io
+	other.implicitSrcs = []string{"a.h"}
+	assert.NotEqual(t, key, generatorActionKey("${tool} ${in} ${out}", args, "", other, nil))
+}
@@ -46,6 +46,8 @@ var (
 
 type linuxGenerator struct {
 	toolchainSet
+
+	warningBudgets *warningBudgetHandler
 }
 
 /* Compile time checks for interfaces that must be implemented by linuxGenerator */
@@ -103,8 +105,8 @@ func (g *linuxGenerator) bobScriptsDir() string {
 	return "${BobScriptsDir}"
 }
 
-func (g *linuxGenerator) sourceOutputDir(m *generateCommon) string {
-	return filepath.Join("${BuildDir}", "gen", m.Name())
+func (g *linuxGenerator) sourceOutputDir(ctx blueprint.ModuleContext, m *generateCommon) string {
+	return g.moduleOutputDir(ctx, filepath.Join("${BuildDir}", "gen"), m.Name())
 }
 
 type singleOutputModule interface {
@@ -204,6 +206,13 @@ func (*linuxGenerator) aliasActions(m *alias, ctx blueprint.ModuleContext) {
 			}
 
 			srcs = append(srcs, name)
+
+			// Depend on the installed files directly, so that the
+			// alias always installs the modules it builds, as
+			// declareAlias does on Android.
+			if ins, ok := p.(installable); ok {
+				srcs = utils.AppendUnique(srcs, ins.getInstallableProps().installedFiles())
+			}
 		})
 
 	ctx.Build(pctx,
@@ -223,11 +232,12 @@ var stripRule = pctx.StaticRule("strip",
 		Description: "strip $out",
 	}, "args")
 
+// $perms optionally sets the mode and owner of the installed file
 var installRule = pctx.StaticRule("install",
 	blueprint.RuleParams{
-		Command:     "rm -f $out; cp $in $out",
+		Command:     "rm -f $out; cp $in $out$perms",
 		Description: "$out",
-	})
+	}, "perms")
 
 func (g *linuxGenerator) install(m interface{}, ctx blueprint.ModuleContext) []string {
 	ins := m.(installable)
@@ -235,59 +245,68 @@ func (g *linuxGenerator) install(m interface{}, ctx blueprint.ModuleContext) []s
 	props := ins.getInstallableProps()
 	installPath, ok := props.getInstallPath()
 	if !ok {
+		if props.isDist() {
+			ctx.PropertyErrorf("dist", "requires an install_group")
+		}
 		return []string{}
 	}
+	distPath := installPath
 	installPath = filepath.Join("${BuildDir}", installPath)
+	props.addDistLicenses(ctx, g)
 
 	installedFiles := []string{}
 
 	rule := installRule
 	args := map[string]string{}
 	deps := []string{}
-	if props.Post_install_cmd != nil {
-		rulename := "install"
-
-		cmd := "rm -f $out; cp $in $out ; " + *props.Post_install_cmd
-
-		// Expand args immediately
-		cmd = strings.Replace(cmd, "${args}", strings.Join(props.Post_install_args, " "), -1)
-
-		if props.Post_install_tool != nil {
-			args["tool"] = *props.Post_install_tool
-			deps = append(deps, *props.Post_install_tool)
-		}
-		utils.StripUnusedArgs(args, cmd)
-
-		rule = ctx.Rule(pctx,
-			rulename,
-			blueprint.RuleParams{
-				Command:     cmd,
-				Description: "$out",
-			},
-			utils.SortedKeys(args)...)
+	if props.Post_install_cmd != nil && len(props.Post_install_outs) == 0 {
+		rule, args, deps = postInstallFileRule(ctx, props)
 	}
 
 	// Check if this is a resource
-	_, isResource := ins.(*resource)
+	res, isResource := ins.(*resource)
 
 	for _, src := range ins.filesToInstall(ctx) {
-		dest := filepath.Join(installPath, filepath.Base(src))
+		rel := filepath.Base(src)
+		if isResource {
+			rel = res.installRelPath(src)
+		}
+		dest := filepath.Join(installPath, rel)
+		fileArgs := args
+
 		// Resources always come from the source directory.
 		// All other module types install files from the build directory.
 		if isResource {
 			src = getBackendPathInSourceDir(g, src)
+
+			perms := ""
+			mode, owner := res.installPermissions(rel)
+			if mode != "" {
+				perms += " && chmod " + mode + " " + dest
+			}
+			if owner != "" {
+				perms += " && chown " + owner + " " + dest
+			}
+			if perms != "" {
+				fileArgs = map[string]string{"perms": perms}
+				for k, v := range args {
+					fileArgs[k] = v
+				}
+			}
 		}
 
 		// Interpose strip target
-		if lib, ok := m.(stripable); ok {
-			debugPath := lib.getDebugPath()
-			separateDebugInfo := debugPath != nil
+		if lib, ok := m.(stripable); ok && !isObjcopyOutput(m, src) {
+			// The debug path points at the install group's
+			// property, so must not be modified here.
+			debugDir := ""
+			separateDebugInfo := lib.getDebugPath() != nil
 			if separateDebugInfo {
-				if *debugPath == "" {
+				if *lib.getDebugPath() == "" {
 					// Install next to library by default
-					debugPath = &installPath
+					debugDir = installPath
 				} else {
-					*debugPath = filepath.Join("${BuildDir}", *debugPath)
+					debugDir = filepath.Join("${BuildDir}", *lib.getDebugPath())
 				}
 			}
 
@@ -296,26 +315,30 @@ func (g *linuxGenerator) install(m interface{}, ctx blueprint.ModuleContext) []s
 				basename := filepath.Base(src)
 				strippedSrc := filepath.Join(lib.stripOutputDir(g), basename)
 				stArgs := tc.getStripFlags()
+				debugOuts := []string{}
 				if lib.strip() {
 					stArgs = append(stArgs, "--strip")
 				}
 				if separateDebugInfo {
-					dbgFile := filepath.Join(*debugPath, basename+".dbg")
+					dbgFile := filepath.Join(debugDir, basename+".dbg")
 					stArgs = append(stArgs, "--debug-file")
 					stArgs = append(stArgs, dbgFile)
+					debugOuts = append(debugOuts, dbgFile)
 				}
 				stripArgs := map[string]string{
 					"args": strings.Join(stArgs, " "),
 				}
 				ctx.Build(pctx,
 					blueprint.BuildParams{
-						Rule:     stripRule,
-						Outputs:  []string{strippedSrc},
-						Inputs:   []string{src},
-						Args:     stripArgs,
-						Optional: true,
+						Rule:            stripRule,
+						Outputs:         []string{strippedSrc},
+						ImplicitOutputs: debugOuts,
+						Inputs:          []string{src},
+						Args:            stripArgs,
+						Optional:        true,
 					})
 				src = strippedSrc
+				installedFiles = append(installedFiles, debugOuts...)
 			}
 		}
 
@@ -324,12 +347,14 @@ func (g *linuxGenerator) install(m interface{}, ctx blueprint.ModuleContext) []s
 				Rule:      rule,
 				Outputs:   []string{dest},
 				Inputs:    []string{src},
-				Args:      args,
+				Args:      fileArgs,
 				Implicits: deps,
 				Optional:  true,
 			})
 
 		installedFiles = append(installedFiles, dest)
+		props.addInstalledEntry(filepath.Join(distPath, rel), dest)
+		props.addDistEntry(filepath.Join(distPath, rel), dest)
 	}
 
 	if symlinkIns, ok := m.(symlinkInstaller); ok {
@@ -348,17 +373,64 @@ func (g *linuxGenerator) install(m interface{}, ctx blueprint.ModuleContext) []s
 				})
 
 			installedFiles = append(installedFiles, symlink)
+			props.addInstalledEntry(filepath.Join(distPath, key), symlink)
+			props.addDistEntry(filepath.Join(distPath, key), symlink)
 		}
 	}
 
+	installedFiles = g.addPostInstall(ctx, props, installPath, distPath, installedFiles)
+
 	return append(installedFiles, ins.getInstallDepPhonyNames(ctx)...)
 }
 
+// Header libraries have nothing to build. Building the module checks
+// that its headers exist.
+func (g *linuxGenerator) headerLibraryActions(m *headerLibrary, ctx blueprint.ModuleContext) {
+	srcs := m.Properties.getSources(ctx)
+	if !proptools.Bool(m.Properties.Format_check_disabled) {
+		m.formatCheckOuts = addFormatCheck(ctx, m.Properties.TargetType, srcs)
+	}
+	addPhony(m, ctx, getBackendPathsInSourceDir(g, srcs), true)
+}
+
 func (g *linuxGenerator) resourceActions(m *resource, ctx blueprint.ModuleContext) {
 	installDeps := g.install(m, ctx)
 	addPhony(m, ctx, installDeps, false)
 }
 
 func (g *linuxGenerator) init(ctx *blueprint.Context, config *bobConfig) {
+	if tidyEnabled(config) {
+		registerSingletonType(ctx, "analyze_singleton", analyzeSingletonFactory)
+	}
+	if linkMapEnabled(config) {
+		registerSingletonType(ctx, "size_report_singleton", sizeReportSingletonFactory)
+	}
+	if formatCheckEnabled(config) {
+		registerSingletonType(ctx, "format_check_singleton", formatCheckSingletonFactory)
+	}
+	registerSingletonType(ctx, "phony_group_singleton", phonyGroupSingletonFactory)
+	g.warningBudgets = newWarningBudgetHandler()
+	ctx.RegisterBottomUpMutator("warning_budgets", g.warningBudgets.warningBudgetMutator).Parallel()
+	registerSingletonType(ctx, "warning_budget_singleton", g.warningBudgets.warningBudgetSingletonFactory)
+	registerSingletonType(ctx, "clean_targets_singleton", cleanTargetsSingletonFactory)
+	if distEnabled(config) {
+		registerSingletonType(ctx, "dist_singleton", distSingletonFactory)
+	}
+	if checksumManifestEnabled(config) {
+		registerSingletonType(ctx, "checksum_manifest_singleton", checksumManifestSingletonFactory)
+	}
+	if kernelModuleCompileCommandsEnabled(config) {
+		registerSingletonType(ctx, "compile_commands_singleton", compileCommandsSingletonFactory)
+	}
+	if latestSymlinksEnabled(config) {
+		registerSingletonType(ctx, "latest_symlinks_singleton", latestSymlinksSingletonFactory)
+	}
+	if buildGraphEnabled(config) {
+		h := newBuildGraphHandler()
+		ctx.RegisterBottomUpMutator("collect_build_graph", h.buildGraphMutator).Parallel()
+		registerSingletonType(ctx, "build_graph_singleton", h.buildGraphSingletonFactory)
+	}
+
 	g.toolchainSet.parseConfig(config)
+	g.toolchainSet.checkArchiverModifiers(config)
 }
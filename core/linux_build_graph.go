@@ -0,0 +1,282 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// The version of the build graph format. This must be incremented when
+// a field is removed or its meaning changes, so that tools reading the
+// file can detect it. Adding fields does not change the version.
+const buildGraphVersion = 1
+
+const buildGraphFile = "build_graph.json"
+
+func buildGraphEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["build_graph_json"]
+	return ok && props.GetBool("build_graph_json")
+}
+
+// buildGraphDep is a direct dependency of a module
+type buildGraphDep struct {
+	Name   string `json:"name"`
+	Target string `json:"target,omitempty"`
+	// The kind of dependency, e.g. static or generated_headers
+	Kind string `json:"kind"`
+}
+
+// buildGraphCompile describes how the sources of a library or binary
+// are compiled
+type buildGraphCompile struct {
+	IncludeDirs []string `json:"include_dirs"`
+	Cflags      []string `json:"cflags"`
+	Conlyflags  []string `json:"conlyflags"`
+	Cxxflags    []string `json:"cxxflags"`
+	Ldflags     []string `json:"ldflags"`
+	Ldlibs      []string `json:"ldlibs"`
+}
+
+// buildGraphGlob is a wildcard in the srcs of a module, with the files
+// it matched once exclude_srcs was applied
+type buildGraphGlob struct {
+	Pattern string   `json:"pattern"`
+	Matches []string `json:"matches"`
+}
+
+// buildGraphModule describes one variant of a module. Paths are
+// absolute.
+type buildGraphModule struct {
+	Name            string             `json:"name"`
+	Type            string             `json:"type"`
+	Target          string             `json:"target,omitempty"`
+	Dir             string             `json:"dir"`
+	Srcs            []string           `json:"srcs"`
+	Globs           []buildGraphGlob   `json:"globs"`
+	Outputs         []string           `json:"outputs"`
+	ImplicitOutputs []string           `json:"implicit_outputs"`
+	Deps            []buildGraphDep    `json:"deps"`
+	Compile         *buildGraphCompile `json:"compile,omitempty"`
+}
+
+// buildGraph is the content of build_graph.json
+type buildGraph struct {
+	Version int                `json:"version"`
+	Modules []buildGraphModule `json:"modules"`
+}
+
+// buildGraphHandler collects the modules of the build graph. The
+// sources and dependencies are recorded by a mutator, as a singleton
+// can't see dependency tags, and the outputs are added by the singleton
+// once the build actions have been generated.
+type buildGraphHandler struct {
+	lock    sync.Mutex
+	modules map[string]*buildGraphModule
+}
+
+func newBuildGraphHandler() *buildGraphHandler {
+	return &buildGraphHandler{modules: map[string]*buildGraphModule{}}
+}
+
+func buildGraphKey(name string, m blueprint.Module) string {
+	if s, ok := m.(splittable); ok {
+		return name + ":" + string(s.getTarget())
+	}
+	return name + ":"
+}
+
+func buildGraphTarget(m blueprint.Module) string {
+	if s, ok := m.(splittable); ok {
+		return string(s.getTarget())
+	}
+	return ""
+}
+
+func buildGraphPaths(paths []string) []string {
+	abs := []string{}
+	for _, path := range paths {
+		abs = append(abs, absolutePath(path))
+	}
+	return abs
+}
+
+func (h *buildGraphHandler) buildGraphMutator(mctx blueprint.BottomUpMutatorContext) {
+	m := mctx.Module()
+	if _, ok := m.(*defaults); ok {
+		return
+	}
+	if e, ok := m.(enableable); ok && !isEnabled(e) {
+		return
+	}
+
+	module := &buildGraphModule{
+		Name:   mctx.ModuleName(),
+		Type:   mctx.ModuleType(),
+		Target: buildGraphTarget(m),
+		Dir:    absolutePath("${SrcDir}/" + mctx.ModuleDir()),
+		Srcs:   []string{},
+		Globs:  []buildGraphGlob{},
+		Deps:   []buildGraphDep{},
+	}
+	if ms, ok := m.(matchSourceInterface); ok {
+		props := ms.getSourceProperties()
+		srcs := utils.PrefixDirs(props.getSources(mctx), "${SrcDir}")
+		module.Srcs = buildGraphPaths(srcs)
+		module.Globs = buildGraphGlobs(mctx, props)
+	}
+
+	mctx.VisitDirectDeps(func(dep blueprint.Module) {
+		kind := "other"
+		if tag, ok := mctx.OtherModuleDependencyTag(dep).(dependencyTag); ok {
+			kind = tag.name
+		}
+		if kind == defaultDepTag.name {
+			return
+		}
+		module.Deps = append(module.Deps, buildGraphDep{
+			Name:   mctx.OtherModuleName(dep),
+			Target: buildGraphTarget(dep),
+			Kind:   kind,
+		})
+	})
+	sort.Slice(module.Deps, func(i, j int) bool {
+		a, b := module.Deps[i], module.Deps[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Kind < b.Kind
+	})
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.modules[buildGraphKey(module.Name, m)] = module
+}
+
+// buildGraphGlobs lists the files matched by each wildcard in srcs, so
+// that tools can tell which sources were found by which pattern, and
+// which patterns found nothing.
+func buildGraphGlobs(ctx blueprint.BaseModuleContext, props *SourceProps) []buildGraphGlob {
+	globs := []buildGraphGlob{}
+	for _, pattern := range props.Srcs {
+		if !strings.ContainsAny(pattern, "*?[") {
+			continue
+		}
+		matches := glob(ctx, []string{pattern}, props.Exclude_srcs)
+		globs = append(globs, buildGraphGlob{
+			Pattern: absolutePath("${SrcDir}/" + pattern),
+			Matches: buildGraphPaths(utils.PrefixDirs(matches, "${SrcDir}")),
+		})
+	}
+	return globs
+}
+
+// buildGraphCompileFlags returns how a library or binary is compiled,
+// as the Linux backend does it.
+func buildGraphCompileFlags(l *library) *buildGraphCompile {
+	gendirs := []string{}
+	for _, m := range l.exported.generatedHeaderModules {
+		gs, _ := getGenerateCommon(m)
+		gendirs = append(gendirs, gs.genIncludeDirs()...)
+	}
+
+	return &buildGraphCompile{
+		IncludeDirs: buildGraphPaths(l.compileIncludeDirs(gendirs)),
+		Cflags:      utils.MergeFlags(l.Properties.Cflags, l.Properties.Export_cflags, l.exported.cflags),
+		Conlyflags:  utils.NewStringSlice(l.Properties.Conlyflags),
+		Cxxflags:    utils.NewStringSlice(l.Properties.Cxxflags),
+		Ldflags:     utils.NewStringSlice(l.Properties.Ldflags),
+		Ldlibs:      utils.NewStringSlice(l.Properties.Ldlibs),
+	}
+}
+
+// content returns build_graph.json, with the modules sorted so that it
+// does not depend on the order modules are visited in.
+func (h *buildGraphHandler) content() (string, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	keys := []string{}
+	for key := range h.modules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	graph := buildGraph{Version: buildGraphVersion, Modules: []buildGraphModule{}}
+	for _, key := range keys {
+		graph.Modules = append(graph.Modules, *h.modules[key])
+	}
+
+	data, err := json.MarshalIndent(graph, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+type buildGraphSingleton struct {
+	handler *buildGraphHandler
+}
+
+func (s *buildGraphSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	h := s.handler
+
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		h.lock.Lock()
+		defer h.lock.Unlock()
+
+		module, ok := h.modules[buildGraphKey(ctx.ModuleName(m), m)]
+		if !ok {
+			return
+		}
+		module.Outputs = []string{}
+		module.ImplicitOutputs = []string{}
+		if p, ok := m.(phonyInterface); ok {
+			module.Outputs = buildGraphPaths(p.outputs())
+			module.ImplicitOutputs = buildGraphPaths(p.implicitOutputs())
+		}
+		if l, ok := getLibrary(m); ok {
+			module.Compile = buildGraphCompileFlags(l)
+		}
+	})
+
+	content, err := h.content()
+	if err != nil {
+		utils.Die("%v", err)
+	}
+	sb := &strings.Builder{}
+	sb.WriteString(content)
+	err = getBackendOutput(getConfig(ctx)).writeFile(getPathInBuildDir(buildGraphFile), sb)
+	if err != nil {
+		utils.Die("%v", err)
+	}
+}
+
+func (h *buildGraphHandler) buildGraphSingletonFactory() blueprint.Singleton {
+	return &buildGraphSingleton{h}
+}
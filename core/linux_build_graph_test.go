@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_buildGraphHandler_content_sorts_modules(t *testing.T) {
+	h := newBuildGraphHandler()
+	h.modules["libfoo:target"] = &buildGraphModule{Name: "libfoo", Target: "target"}
+	h.modules["gen_foo:"] = &buildGraphModule{Name: "gen_foo"}
+	h.modules["libfoo:host"] = &buildGraphModule{Name: "libfoo", Target: "host"}
+
+	content, err := h.content()
+	assert.Nil(t, err)
+
+	graph := buildGraph{}
+	assert.Nil(t, json.Unmarshal([]byte(content), &graph))
+	assert.Equal(t, buildGraphVersion, graph.Version)
+
+	names := []string{}
+	for _, m := range graph.Modules {
+		names = append(names, m.Name+":"+m.Target)
+	}
+	assert.Equal(t, []string{"gen_foo:", "libfoo:host", "libfoo:target"}, names)
+}
+
+func Test_buildGraphHandler_content_is_stable(t *testing.T) {
+	h := newBuildGraphHandler()
+	h.modules["libfoo:target"] = &buildGraphModule{
+		Name: "libfoo",
+		Deps: []buildGraphDep{{Name: "libbar", Target: "target", Kind: "static"}},
+	}
+
+	first, err := h.content()
+	assert.Nil(t, err)
+	second, err := h.content()
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+	assert.Contains(t, first, `"kind": "static"`)
+}
+
+func Test_buildGraphHandler_content_lists_glob_matches(t *testing.T) {
+	h := newBuildGraphHandler()
+	h.modules["libfoo:target"] = &buildGraphModule{
+		Name: "libfoo",
+		Globs: []buildGraphGlob{
+			{Pattern: "/src/foo/*.c", Matches: []string{"/src/foo/a.c", "/src/foo/b.c"}},
+			{Pattern: "/src/foo/gen/*.c", Matches: []string{}},
+		},
+	}
+
+	content, err := h.content()
+	assert.Nil(t, err)
+
+	graph := buildGraph{}
+	assert.Nil(t, json.Unmarshal([]byte(content), &graph))
+	assert.Equal(t, h.modules["libfoo:target"].Globs, graph.Modules[0].Globs)
+	// An empty match list is written as a list, not null
+	assert.Contains(t, content, `"matches": []`)
+}
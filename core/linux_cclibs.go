@@ -34,36 +34,41 @@ var asRule = pctx.StaticRule("as",
 	blueprint.RuleParams{
 		Depfile:     "$out.d",
 		Deps:        blueprint.DepsGCC,
-		Command:     "$build_wrapper $ascompiler $asflags $in -MD $depfile -o $out",
+		Command:     "$object_store $warning_log $build_wrapper $ascompiler $asflags $in -MD $depfile -o $out",
 		Description: "$out",
-	}, "ascompiler", "asflags", "build_wrapper", "depfile")
+	}, "ascompiler", "asflags", "build_wrapper", "depfile", "object_store", "warning_log")
 
 var ccRule = pctx.StaticRule("cc",
 	blueprint.RuleParams{
 		Depfile:     "$out.d",
 		Deps:        blueprint.DepsGCC,
-		Command:     "$build_wrapper $ccompiler -c $cflags $conlyflags -MMD -MF $depfile $in -o $out",
+		Command:     "$object_store $artifact_cache $warning_log $build_wrapper $ccompiler -c $cflags $conlyflags -MMD -MF $depfile $in -o $out",
 		Description: "$out",
-	}, "ccompiler", "cflags", "conlyflags", "build_wrapper", "depfile")
+	}, "artifact_cache", "ccompiler", "cflags", "conlyflags", "build_wrapper", "depfile", "object_store", "warning_log")
 
 var cxxRule = pctx.StaticRule("cxx",
 	blueprint.RuleParams{
 		Depfile:     "$out.d",
 		Deps:        blueprint.DepsGCC,
-		Command:     "$build_wrapper $cxxcompiler -c $cflags $cxxflags -MMD -MF $depfile $in -o $out",
+		Command:     "$object_store $artifact_cache $warning_log $build_wrapper $cxxcompiler -c $cflags $cxxflags -MMD -MF $depfile $in -o $out",
 		Description: "$out",
-	}, "cxxcompiler", "cflags", "cxxflags", "build_wrapper", "depfile")
+	}, "artifact_cache", "cxxcompiler", "cflags", "cxxflags", "build_wrapper", "depfile", "object_store", "warning_log")
 
 func (l *library) ObjDir() string {
-	return filepath.Join("${BuildDir}", string(l.Properties.TargetType), "objects", l.outputName()) + string(os.PathSeparator)
+	return l.objDir
 }
 
-// This function has common support to compile objs for static libs, shared libs and binaries.
-func (l *library) CompileObjs(ctx blueprint.ModuleContext) ([]string, []string) {
-	g := getBackend(ctx)
-	srcs := l.GetSrcs(ctx)
+// linuxObjDir returns the directory holding the objects of the library,
+// in the output layout 'layout'.
+func linuxObjDir(ctx blueprint.ModuleContext, l *library, layout string) string {
+	base := filepath.Join("${BuildDir}", string(l.Properties.TargetType), "objects")
+	return moduleOutputDir(layout, base, ctx.ModuleDir(), l.outputName()) + string(os.PathSeparator)
+}
 
-	expLocalIncludes, expIncludes, exportedCflags := l.GetExportedVariables(ctx)
+// compileIncludeDirs returns the include directories used to compile
+// the library, in search order. 'gendirs' are the include directories
+// of the generated headers it uses.
+func (l *library) compileIncludeDirs(gendirs []string) []string {
 	// There are 2 sets of include dirs - "global" and "local".
 	// Local acts on the root source directory.
 
@@ -74,34 +79,67 @@ func (l *library) CompileObjs(ctx blueprint.ModuleContext) ([]string, []string)
 
 	// Prefix all local includes with SrcDir
 	localIncludeDirs = utils.PrefixDirs(localIncludeDirs, "${SrcDir}")
-	expLocalIncludes = utils.PrefixDirs(expLocalIncludes, "${SrcDir}")
+	expLocalIncludes := utils.PrefixDirs(l.exported.localIncludeDirs, "${SrcDir}")
+
+	return utils.MergeUnique(localIncludeDirs, l.Properties.Include_dirs,
+		l.Properties.Export_include_dirs, expLocalIncludes, l.exported.includeDirs, gendirs)
+}
 
-	includeDirs := append(localIncludeDirs, l.Properties.Include_dirs...)
-	includeDirs = append(includeDirs, l.Properties.Export_include_dirs...)
-	includeDirs = append(includeDirs, expLocalIncludes...)
-	includeDirs = append(includeDirs, expIncludes...)
+// This function has common support to compile objs for static libs, shared libs and binaries.
+func (l *library) CompileObjs(ctx blueprint.ModuleContext) ([]string, []string) {
+	g := getBackend(ctx)
+	srcs := l.GetSrcs(ctx)
+	qtSrcs, qtHeaders := l.addQtActions(ctx)
+	srcs = append(srcs, qtSrcs...)
+	srcs = append(srcs, l.addParserActions(ctx)...)
 
+	_, _, exportedCflags := l.GetExportedVariables(ctx)
 	gendirs, orderOnly := l.GetGeneratedHeaders(ctx)
-	includeDirs = append(includeDirs, gendirs...)
-	includeFlags := utils.PrefixAll(includeDirs, "-I")
-	cflagsList := utils.NewStringSlice(l.Properties.Cflags, l.Properties.Export_cflags,
-		exportedCflags, includeFlags)
+	if l.qtEnabled() {
+		gendirs = append(gendirs, l.qtIncludeDir())
+		orderOnly = append(orderOnly, qtHeaders...)
+	}
+	if len(l.Properties.ParserSrcs) > 0 {
+		gendirs = append(gendirs, l.parserIncludeDir())
+	}
+	orderOnly = append(orderOnly, l.parserHeaders()...)
+	orderOnly = append(orderOnly, l.exported.parserHeaders...)
+	gendirs = append(gendirs, l.exportHeaderIncludeDirs()...)
+	orderOnly = append(orderOnly, l.exportHeaders()...)
+	orderOnly = append(orderOnly, l.exported.exportHeaders...)
+	includeFlags := utils.PrefixAll(l.compileIncludeDirs(gendirs), "-I")
+	cflagsList := utils.MergeFlags(l.visibilityCflags(), l.Properties.Cflags, l.Properties.Export_cflags,
+		exportedCflags, l.gcSectionsCflags(), includeFlags)
 
 	tc := g.getToolchain(l.Properties.TargetType)
 	as, astargetflags := tc.getAssembler()
 	cc, cctargetflags := tc.getCCompiler()
 	cxx, cxxtargetflags := tc.getCXXCompiler()
+	stlCxxflags, _, err := l.stlFlags(tc)
+	if err != nil {
+		ctx.PropertyErrorf("stl", "%v", err)
+	}
+	staticCflags, _, err := l.staticExecutableFlags(tc)
+	if err != nil {
+		ctx.PropertyErrorf(l.staticLinkProperty(), "%v", err)
+	}
 
 	ctx.Variable(pctx, "asflags", utils.Join(astargetflags, l.Properties.Asflags))
-	ctx.Variable(pctx, "cflags", utils.Join(cflagsList))
+	ctx.Variable(pctx, "cflags", utils.Join(cflagsList, staticCflags))
 	ctx.Variable(pctx, "conlyflags", utils.Join(cctargetflags, l.Properties.Conlyflags))
-	ctx.Variable(pctx, "cxxflags", utils.Join(cxxtargetflags, l.Properties.Cxxflags))
+	ctx.Variable(pctx, "cxxflags", utils.Join(cxxtargetflags, stlCxxflags, l.Properties.getCxxflags()))
+
+	layout := outputLayout(getConfig(ctx))
+	l.objDir = linuxObjDir(ctx, l, layout)
+	objectSources := map[string]string{}
 
 	objectFiles := []string{}
 	nonCompiledDeps := []string{}
 
 	for _, source := range srcs {
 		var rule blueprint.Rule
+		// Flags for the source language, which clang-tidy also needs
+		langflags := ""
 		args := make(map[string]string)
 		switch path.Ext(source) {
 		case ".s":
@@ -115,6 +153,7 @@ func (l *library) CompileObjs(ctx blueprint.ModuleContext) ([]string, []string)
 			args["ccompiler"] = cc
 			args["cflags"] = "$cflags"
 			args["conlyflags"] = "$conlyflags"
+			langflags = "$conlyflags"
 			rule = ccRule
 		case ".cc":
 			fallthrough
@@ -122,13 +161,14 @@ func (l *library) CompileObjs(ctx blueprint.ModuleContext) ([]string, []string)
 			args["cxxcompiler"] = cxx
 			args["cflags"] = "$cflags"
 			args["cxxflags"] = "$cxxflags"
+			langflags = "$cxxflags"
 			rule = cxxRule
 		default:
 			nonCompiledDeps = append(nonCompiledDeps, getBackendPathInSourceDir(g, source))
 			continue
 		}
 
-		buildWrapper, buildWrapperDeps := l.Properties.Build.getBuildWrapperAndDeps(ctx)
+		buildWrapper, buildWrapperDeps := l.Properties.Build.getCommandWrapperAndDeps(ctx)
 		args["build_wrapper"] = buildWrapper
 
 		var sourceWithoutPrefix string
@@ -138,20 +178,50 @@ func (l *library) CompileObjs(ctx blueprint.ModuleContext) ([]string, []string)
 			sourceWithoutPrefix = source
 			source = getBackendPathInSourceDir(g, source)
 		}
-		output := l.ObjDir() + sourceWithoutPrefix + ".o"
+		output := l.ObjDir() + objectFileName(layout, sourceWithoutPrefix)
+		if other, ok := objectSources[output]; ok {
+			ctx.PropertyErrorf("srcs", "%s and %s compile to the same object file in the %s output layout",
+				other, sourceWithoutPrefix, layout)
+			continue
+		}
+		objectSources[output] = sourceWithoutPrefix
+		args["object_store"] = objectStoreWrapper(ctx, output)
+
+		implicitOuts := []string{}
+		warningLog, warningLogFile := warningLogWrapper(ctx, output)
+		args["warning_log"] = warningLog
+		if warningLogFile != "" {
+			implicitOuts = append(implicitOuts, warningLogFile)
+			l.warningLogOuts = append(l.warningLogOuts, warningLogFile)
+		}
+		// Assembly is not cached, as the files it includes are not
+		// known before it is assembled
+		if rule != asRule {
+			compiler := args["ccompiler"] + args["cxxcompiler"]
+			args["artifact_cache"] = artifactCacheCompileWrapper(ctx, compiler,
+				utils.NewStringSlice([]string{output}, implicitOuts))
+		}
 
 		ctx.Build(pctx,
 			blueprint.BuildParams{
-				Rule:      rule,
-				Outputs:   []string{output},
-				Inputs:    []string{source},
-				Args:      args,
-				OrderOnly: utils.NewStringSlice(orderOnly, buildWrapperDeps),
-				Optional:  true,
+				Rule:            rule,
+				Outputs:         []string{output},
+				ImplicitOutputs: implicitOuts,
+				Inputs:          []string{source},
+				Args:            args,
+				OrderOnly:       utils.NewStringSlice(orderOnly, buildWrapperDeps),
+				Optional:        true,
 			})
 		objectFiles = append(objectFiles, output)
+
+		// clang-tidy only checks C and C++ sources
+		if langflags != "" && path.Ext(source) != ".S" {
+			l.addTidyAction(ctx, source, output, langflags, orderOnly)
+		}
 	}
 
+	l.addFormatCheckAction(ctx)
+
 	return objectFiles, nonCompiledDeps
 }
 
@@ -195,41 +265,87 @@ func (l *library) GetWholeStaticLibs(ctx blueprint.ModuleContext) []string {
 func (l *library) GetStaticLibs(ctx blueprint.ModuleContext) []string {
 	libs := []string{}
 	for _, moduleName := range l.Properties.ResolvedStaticLibs {
-		dep, _ := ctx.GetDirectDep(moduleName)
-		if dep == nil {
-			utils.Die("%s has no dependency on static lib %s", l.Name(), moduleName)
+		libs = append(libs, l.staticLibOutputs(ctx, moduleName)...)
+	}
+
+	return libs
+}
+
+// staticLibOutputs returns the archives to link for one of the static
+// libraries of a module.
+func (l *library) staticLibOutputs(ctx blueprint.ModuleContext, moduleName string) []string {
+	dep, _ := ctx.GetDirectDep(moduleName)
+	if dep == nil {
+		utils.Die("%s has no dependency on static lib %s", l.Name(), moduleName)
+	}
+	if sl, ok := dep.(*staticLibrary); ok {
+		return sl.outputs()
+	} else if sl, ok := dep.(*generateStaticLibrary); ok {
+		return sl.outputs()
+	} else if _, ok := dep.(*externalLib); ok {
+		// External static libraries are added to the link using the flags
+		// exported by their ldlibs and ldflags properties, rather than by
+		// specifying the filename here.
+		return []string{}
+	}
+	utils.Die("%s is not a static library", ctx.OtherModuleName(dep))
+	return nil
+}
+
+// getStaticLibFlags returns the flags linking the static libraries of
+// a module, in the order they were sorted in. The libraries from the
+// first to the last member of link_group are linked as a group, so
+// the members can depend on each other whatever order they end up in.
+func (l *library) getStaticLibFlags(ctx blueprint.ModuleContext, lnk linker) []string {
+	libs := l.Properties.ResolvedStaticLibs
+
+	first, last := -1, -1
+	for _, member := range l.Properties.Link_group {
+		i := utils.Find(libs, member)
+		if i < 0 {
+			ctx.PropertyErrorf("link_group", "%s is not a static library of this module", member)
+			continue
 		}
-		if sl, ok := dep.(*staticLibrary); ok {
-			libs = append(libs, sl.outputs()...)
-		} else if sl, ok := dep.(*generateStaticLibrary); ok {
-			libs = append(libs, sl.outputs()...)
-		} else if _, ok := dep.(*externalLib); ok {
-			// External static libraries are added to the link using the flags
-			// exported by their ldlibs and ldflags properties, rather than by
-			// specifying the filename here.
-		} else {
-			utils.Die("%s is not a static library", ctx.OtherModuleName(dep))
+		if first < 0 || i < first {
+			first = i
+		}
+		if i > last {
+			last = i
 		}
 	}
 
-	return libs
+	flags := []string{}
+	group := []string{}
+	for i, moduleName := range libs {
+		outs := l.staticLibOutputs(ctx, moduleName)
+		if first >= 0 && i >= first && i <= last {
+			group = append(group, outs...)
+			if i == last {
+				flags = append(flags, lnk.linkGroup(group))
+			}
+		} else {
+			flags = append(flags, outs...)
+		}
+	}
+	return flags
 }
 
 // The rule for building a static library
 // Note that we need to remove the old library, else we will not remove the old object files
 var staticLibraryRule = pctx.StaticRule("static_library",
 	blueprint.RuleParams{
-		Command:     "rm -f $out && $build_wrapper $ar -rcs $out $in",
+		Command:     "rm -f $out && $ar_env $artifact_cache $build_wrapper $ar -$ar_modifiers $out $in",
 		Description: "$out",
-	}, "ar", "build_wrapper")
+	}, "ar", "ar_env", "ar_modifiers", "artifact_cache", "build_wrapper")
 
 var _ = pctx.StaticVariable("whole_static_tool", "${BobScriptsDir}/whole_static.py")
 var wholeStaticLibraryRule = pctx.StaticRule("whole_static_library",
 	blueprint.RuleParams{
-		Command:     "$whole_static_tool --build-wrapper \"$build_wrapper\" --ar $ar --out $out $in $whole_static_libs",
+		Command: "$ar_env $artifact_cache $whole_static_tool --build-wrapper \"$build_wrapper\" --ar $ar " +
+			"--modifiers $ar_modifiers --out $out $in $whole_static_libs",
 		CommandDeps: []string{"$whole_static_tool"},
 		Description: "$out",
-	}, "ar", "build_wrapper", "whole_static_libs")
+	}, "ar", "ar_env", "ar_modifiers", "artifact_cache", "build_wrapper", "whole_static_libs")
 
 func (g *linuxGenerator) staticActions(m *staticLibrary, ctx blueprint.ModuleContext) {
 
@@ -237,16 +353,25 @@ func (g *linuxGenerator) staticActions(m *staticLibrary, ctx blueprint.ModuleCon
 	m.outputdir = g.staticLibOutputDir(m)
 	m.outs = []string{filepath.Join(m.outputDir(), m.outputFileName())}
 
+	if m.relocatable {
+		g.relocatableObjectActions(m, ctx)
+		return
+	}
+
 	rule := staticLibraryRule
 
-	buildWrapper, buildWrapperDeps := m.Properties.Build.getBuildWrapperAndDeps(ctx)
+	buildWrapper, buildWrapperDeps := m.Properties.Build.getCommandWrapperAndDeps(ctx)
 
 	tc := g.getToolchain(m.Properties.TargetType)
 	arBinary, _ := tc.getArchiver()
 
+	config := getConfig(ctx)
 	args := map[string]string{
-		"ar":            arBinary,
-		"build_wrapper": buildWrapper,
+		"ar":             arBinary,
+		"ar_env":         archiverEnv(config),
+		"ar_modifiers":   archiverModifiers(config, tc),
+		"artifact_cache": artifactCacheWrapper(ctx, arBinary, m.outputs()),
+		"build_wrapper":  buildWrapper,
 	}
 
 	wholeStaticLibs := m.library.GetWholeStaticLibs(ctx)
@@ -272,7 +397,11 @@ func (g *linuxGenerator) staticActions(m *staticLibrary, ctx blueprint.ModuleCon
 			Args:      args,
 		})
 
-	installDeps := g.install(m, ctx)
+	g.addPostBuild(ctx, &m.library)
+	installDeps := append(g.install(m, ctx), m.postBuildOuts...)
+	installDeps = append(installDeps,
+		g.addLibraryInterface(ctx, &m.library, m.outputs()[0], "static", "")...)
+	installDeps = append(installDeps, g.addXcodeConfig(ctx, &m.library, nil)...)
 	addPhony(m, ctx, installDeps, !isBuiltByDefault(m))
 }
 
@@ -308,12 +437,22 @@ func (g *linuxGenerator) getSharedLibLinkPaths(ctx blueprint.ModuleContext) (lib
 	return
 }
 
-func (g *linuxGenerator) getSharedLibTocPaths(ctx blueprint.ModuleContext) (libs []string) {
+// Returns the files a link depends on for its shared libraries. When
+// useToc is set, these are the tables of contents rather than the
+// libraries. Libraries with stubs are replaced by their stub, so that
+// changes to their implementation don't relink their users.
+func (g *linuxGenerator) getSharedLibLinkDeps(ctx blueprint.ModuleContext, useToc bool) (libs []string) {
 	ctx.VisitDirectDepsIf(
 		func(m blueprint.Module) bool { return ctx.OtherModuleDependencyTag(m) == sharedDepTag },
 		func(m blueprint.Module) {
-			if l, ok := m.(sharedLibProducer); ok {
-				libs = append(libs, g.getSharedLibTocPath(l))
+			if sl, ok := m.(*sharedLibrary); ok && sl.hasStubs() {
+				libs = append(libs, sharedLibStubPath(sl))
+			} else if l, ok := m.(sharedLibProducer); ok {
+				if useToc {
+					libs = append(libs, g.getSharedLibTocPath(l))
+				} else {
+					libs = append(libs, g.getSharedLibLinkPath(l))
+				}
 			} else if _, ok := m.(*externalLib); ok {
 				// Don't try and guess the path to external libraries,
 				// and as they are outside of the build we don't need to
@@ -325,12 +464,35 @@ func (g *linuxGenerator) getSharedLibTocPaths(ctx blueprint.ModuleContext) (libs
 	return
 }
 
+// Returns the shared libraries which must be built before a link, but
+// which don't make it rerun when they change.
+func (g *linuxGenerator) getSharedLibOrderOnlyDeps(ctx blueprint.ModuleContext) (libs []string) {
+	if enableToc {
+		// Add an order only dependecy on the actual libraries to cover
+		// the case where the .so is deleted but the toc is still
+		// present.
+		return g.getSharedLibLinkPaths(ctx)
+	}
+
+	// Users of a library with stubs link against the stub, but still
+	// need the library itself at runtime.
+	ctx.VisitDirectDepsIf(
+		func(m blueprint.Module) bool { return ctx.OtherModuleDependencyTag(m) == sharedDepTag },
+		func(m blueprint.Module) {
+			if sl, ok := m.(*sharedLibrary); ok && sl.hasStubs() {
+				libs = append(libs, g.getSharedLibLinkPath(sl))
+			}
+		})
+	return
+}
+
 func (l *library) getSharedLibFlags(ctx blueprint.ModuleContext) (ldlibs []string, ldflags []string) {
 	// With forwarding shared library we do not have to use
 	// --no-as-needed for dependencies because it is already set
 	useNoAsNeeded := !l.Properties.Build.isForwardingSharedLibrary()
 	hasForwardingLib := false
 	libPaths := []string{}
+	uninstalled := []string{}
 	tc := getBackend(ctx).getToolchain(l.Properties.TargetType)
 
 	ctx.VisitDirectDepsIf(
@@ -346,6 +508,11 @@ func (l *library) getSharedLibFlags(ctx blueprint.ModuleContext) (ldlibs []strin
 					}
 				}
 				ldlibs = append(ldlibs, pathToLibFlag(sl.outputName()))
+				if sl.hasStubs() {
+					// Search the stub's directory before the one
+					// holding the library itself
+					ldflags = append(ldflags, "-L"+filepath.Dir(sharedLibStubPath(sl)))
+				}
 				if b.isForwardingSharedLibrary() {
 					if useNoAsNeeded {
 						ldlibs = append(ldlibs, tc.getLinker().dropUnusedDependencies())
@@ -354,11 +521,15 @@ func (l *library) getSharedLibFlags(ctx blueprint.ModuleContext) (ldlibs []strin
 				}
 				if installPath, ok := sl.Properties.InstallableProps.getInstallPath(); ok {
 					libPaths = utils.AppendIfUnique(libPaths, installPath)
+				} else {
+					uninstalled = append(uninstalled, m.Name())
 				}
 			} else if sl, ok := m.(*generateSharedLibrary); ok {
 				ldlibs = append(ldlibs, pathToLibFlag(sl.outputName()))
 				if installPath, ok := sl.generateCommon.Properties.InstallableProps.getInstallPath(); ok {
 					libPaths = utils.AppendIfUnique(libPaths, installPath)
+				} else {
+					uninstalled = append(uninstalled, m.Name())
 				}
 			} else if el, ok := m.(*externalLib); ok {
 				ldlibs = append(ldlibs, el.exportLdlibs()...)
@@ -369,24 +540,45 @@ func (l *library) getSharedLibFlags(ctx blueprint.ModuleContext) (ldlibs []strin
 		})
 
 	if hasForwardingLib {
-		ldlibs = append(ldlibs, tc.getLinker().getForwardingLibFlags())
-	}
-	if l.Properties.isRpathWanted() {
-		if installPath, ok := l.Properties.InstallableProps.getInstallPath(); ok {
-			var rpaths []string
-			for _, path := range libPaths {
-				out, err := filepath.Rel(installPath, path)
-				if err != nil {
-					utils.Die("Could not find relative path for: %s due to: %s", path, err)
-				}
-				rpaths = append(rpaths, "'$$ORIGIN/"+out+"'")
-			}
-			ldlibs = append(ldlibs, tc.getLinker().setRpath(rpaths))
+		if flags := tc.getLinker().getForwardingLibFlags(); flags != "" {
+			ldlibs = append(ldlibs, flags)
 		}
 	}
+	if rpaths := l.getRpaths(ctx, libPaths, uninstalled); len(rpaths) > 0 {
+		ldlibs = append(ldlibs, tc.getLinker().setRpath(rpaths))
+	}
 	return
 }
 
+// ltoCacheDir returns the directory set by LTO_CACHE_DIR, with relative
+// paths in the build directory, or "" when there is no cache.
+func ltoCacheDir(config *bobConfig) string {
+	props := config.Properties
+	if _, ok := props.properties["lto_cache_dir"]; !ok {
+		return ""
+	}
+	dir := props.GetString("lto_cache_dir")
+	if dir != "" && !filepath.IsAbs(dir) {
+		dir = filepath.Join("${BuildDir}", dir)
+	}
+	return dir
+}
+
+// usesLto returns whether link time optimization is enabled by a list
+// of linker flags. As on the command line, the last -flto or -fno-lto
+// wins.
+func usesLto(flags []string) bool {
+	enabled := false
+	for _, flag := range flags {
+		if flag == "-flto" || strings.HasPrefix(flag, "-flto=") {
+			enabled = true
+		} else if flag == "-fno-lto" {
+			enabled = false
+		}
+	}
+	return enabled
+}
+
 func (g *linuxGenerator) getCommonLibArgs(l *library, ctx blueprint.ModuleContext) map[string]string {
 	tc := g.getToolchain(l.Properties.TargetType)
 
@@ -403,21 +595,41 @@ func (g *linuxGenerator) getCommonLibArgs(l *library, ctx blueprint.ModuleContex
 		ldflags = append(ldflags, tc.getLinker().setVersionScript(*versionScript))
 	}
 
+	if linkMapEnabled(getConfig(ctx)) {
+		ldflags = append(ldflags, tc.getLinker().setMapFile(l.linkMapFile()))
+	}
+
+	ldflags = append(ldflags, l.gcSectionsLdflags(tc)...)
+
+	// Errors were reported when compiling
+	if _, stlLdflags, err := l.stlFlags(tc); err == nil {
+		ldflags = append(ldflags, stlLdflags...)
+	}
+	if _, staticLdflags, err := l.staticExecutableFlags(tc); err == nil {
+		ldflags = append(ldflags, staticLdflags...)
+	}
+
 	sharedLibLdlibs, sharedLibLdflags := l.getSharedLibFlags(ctx)
 
 	linker := tc.getLinker().getTool()
 	tcLdflags := tc.getLinker().getFlags()
+
+	// The cache is only used when link time optimization is enabled
+	if dir := ltoCacheDir(getConfig(ctx)); dir != "" && usesLto(utils.NewStringSlice(tcLdflags, ldflags)) {
+		if flag := tc.getLinker().setLtoCacheDir(dir); flag != "" {
+			ldflags = append(ldflags, flag)
+		}
+	}
 	tcLdlibs := tc.getLinker().getLibs()
-	buildWrapper, _ := l.Properties.Build.getBuildWrapperAndDeps(ctx)
+	buildWrapper, _ := l.Properties.Build.getCommandWrapperAndDeps(ctx)
 
 	wholeStaticLibs := l.GetWholeStaticLibs(ctx)
-	staticLibs := l.GetStaticLibs(ctx)
 	staticLibFlags := []string{}
 	if len(wholeStaticLibs) > 0 {
 		staticLibFlags = append(staticLibFlags, tc.getLinker().linkWholeArchives(
 			wholeStaticLibs))
 	}
-	staticLibFlags = append(staticLibFlags, staticLibs...)
+	staticLibFlags = append(staticLibFlags, l.getStaticLibFlags(ctx, tc.getLinker())...)
 	sharedLibDir := g.sharedLibsDir(l.Properties.TargetType)
 	args := map[string]string{
 		"build_wrapper":   buildWrapper,
@@ -447,18 +659,27 @@ func (g *linuxGenerator) getSharedLibArgs(l *sharedLibrary, ctx blueprint.Module
 }
 
 func (g *linuxGenerator) getBinaryArgs(b *binary, ctx blueprint.ModuleContext) map[string]string {
-	return g.getCommonLibArgs(&b.library, ctx)
+	args := g.getCommonLibArgs(&b.library, ctx)
+
+	// The allocator must be linked before the C library, and after any
+	// other libraries which use it. Make sure it is kept even when the
+	// binary itself doesn't reference it.
+	if allocator := b.getAllocator(); allocator != "" {
+		tc := g.getToolchain(b.Properties.TargetType)
+		args["ldlibs"] = utils.Join([]string{tc.getLinker().keepUnusedDependencies()},
+			allocatorLdlibs(getConfig(ctx), b.Properties.TargetType, allocator),
+			[]string{tc.getLinker().dropUnusedDependencies()},
+			[]string{args["ldlibs"]})
+	}
+
+	return args
 }
 
 // Returns the implicit dependencies for a library
 // When useToc is set, replace shared libraries with their toc files.
 func (g *linuxGenerator) ccLinkImplicits(l linkableModule, ctx blueprint.ModuleContext, useToc bool) []string {
 	implicits := utils.NewStringSlice(l.GetWholeStaticLibs(ctx), l.GetStaticLibs(ctx))
-	if useToc {
-		implicits = append(implicits, g.getSharedLibTocPaths(ctx)...)
-	} else {
-		implicits = append(implicits, g.getSharedLibLinkPaths(ctx)...)
-	}
+	implicits = append(implicits, g.getSharedLibLinkDeps(ctx, useToc)...)
 	versionScript := l.getVersionScript(ctx)
 	if versionScript != nil {
 		implicits = append(implicits, *versionScript)
@@ -486,14 +707,18 @@ var linkPoolParams = blueprint.PoolParams{
 
 var linkPool = pctx.StaticPool("link", linkPoolParams)
 
-var sharedLibraryRule = pctx.StaticRule("shared_library",
-	blueprint.RuleParams{
-		Command: "$build_wrapper $linker -shared $in -o $out $ldflags " +
-			"$static_libs -L$shared_libs_dir $shared_libs_flags $ldlibs",
-		Description: "$out",
-		Pool:        linkPool,
-	}, "build_wrapper", "ldflags", "ldlibs", "linker", "shared_libs_dir", "shared_libs_flags",
-	"static_libs")
+// The arguments of the rules linking shared libraries and executables
+var linkRuleArgs = []string{"artifact_cache", "build_wrapper", "ldflags", "ldlibs", "linker",
+	"shared_libs_dir", "shared_libs_flags", "static_libs"}
+
+var sharedLibraryRuleParams = blueprint.RuleParams{
+	Command: "$artifact_cache $build_wrapper $linker -shared $in -o $out $ldflags " +
+		"$static_libs -L$shared_libs_dir $shared_libs_flags $ldlibs",
+	Description: "$out",
+	Pool:        linkPool,
+}
+
+var sharedLibraryRule = pctx.StaticRule("shared_library", sharedLibraryRuleParams, linkRuleArgs...)
 
 var symlinkRule = pctx.StaticRule("symlink",
 	blueprint.RuleParams{
@@ -506,15 +731,21 @@ func (g *linuxGenerator) sharedActions(m *sharedLibrary, ctx blueprint.ModuleCon
 	m.outputdir = g.sharedLibsDir(m.Properties.TargetType)
 	soFile := filepath.Join(m.outputDir(), m.getRealName())
 	m.outs = []string{soFile}
+	m.implicitOuts = []string{}
 
+	g.addExportHeader(ctx, &m.library)
 	objectFiles, nonCompiledDeps := m.CompileObjs(ctx)
 
 	_, buildWrapperDeps := m.Properties.Build.getBuildWrapperAndDeps(ctx)
 
-	installDeps := g.install(m, ctx)
+	g.addPostBuild(ctx, &m.library)
+	installDeps := append(g.install(m, ctx), m.postBuildOuts...)
 
-	// Create symlinks if needed
-	for name, symlinkTgt := range m.librarySymlinks(ctx) {
+	// Create symlinks if needed. These are recorded as implicit outputs,
+	// as users of the library may need them at runtime.
+	symlinks := m.librarySymlinks(ctx)
+	for _, name := range utils.SortedKeys(symlinks) {
+		symlinkTgt := symlinks[name]
 		symlink := filepath.Join(m.outputDir(), name)
 		lib := filepath.Join(m.outputDir(), symlinkTgt)
 		ctx.Build(pctx,
@@ -525,42 +756,50 @@ func (g *linuxGenerator) sharedActions(m *sharedLibrary, ctx blueprint.ModuleCon
 				Args:     map[string]string{"target": symlinkTgt},
 				Optional: true,
 			})
-		installDeps = append(installDeps, symlink)
+		m.implicitOuts = append(m.implicitOuts, symlink)
 	}
 
-	orderOnly := buildWrapperDeps
-	if enableToc {
-		// Add an order only dependecy on the actual libraries to cover
-		// the case where the .so is deleted but the toc is still
-		// present.
-		orderOnly = append(orderOnly, g.getSharedLibLinkPaths(ctx)...)
-	}
+	orderOnly := append(buildWrapperDeps, g.getSharedLibOrderOnlyDeps(ctx)...)
 
+	linkMap := m.addLinkMap(ctx)
+	linkArgs := g.getSharedLibArgs(m, ctx)
+	linkArgs["artifact_cache"] = artifactCacheWrapper(ctx, linkArgs["linker"],
+		utils.NewStringSlice(m.outputs(), linkMap))
 	ctx.Build(pctx,
 		blueprint.BuildParams{
-			Rule:      sharedLibraryRule,
-			Outputs:   m.outputs(),
-			Inputs:    objectFiles,
-			Implicits: append(g.ccLinkImplicits(m, ctx, enableToc), nonCompiledDeps...),
-			OrderOnly: orderOnly,
-			Optional:  true,
-			Args:      g.getSharedLibArgs(m, ctx),
+			Rule:            linkRule(ctx, &m.library, sharedLibraryRule, sharedLibraryPoolRules),
+			Outputs:         m.outputs(),
+			ImplicitOutputs: linkMap,
+			Inputs:          objectFiles,
+			Implicits:       append(g.ccLinkImplicits(m, ctx, enableToc), nonCompiledDeps...),
+			OrderOnly:       orderOnly,
+			Optional:        true,
+			Args:            linkArgs,
 		})
 
+	g.addSizeToolOutputs(ctx, &m.library)
+
 	tocFile := g.getSharedLibTocPath(m)
 	g.addSharedLibToc(ctx, soFile, tocFile, m.getTarget())
-
+	installDeps = append(installDeps, g.addSharedLibStubs(ctx, m)...)
+	installDeps = append(installDeps, g.addExportCheck(ctx, &m.library, soFile, objectFiles)...)
+
+	// Libraries without a version are linked without -soname, so
+	// their users record the file name, which getSoname returns.
+	installDeps = append(installDeps,
+		g.addLibraryInterface(ctx, &m.library, soFile, "shared", m.getSoname())...)
+	installDeps = append(installDeps, g.addXcodeConfig(ctx, &m.library, linkArgs)...)
 	addPhony(m, ctx, installDeps, !isBuiltByDefault(m))
 }
 
-var executableRule = pctx.StaticRule("executable",
-	blueprint.RuleParams{
-		Command: "$build_wrapper $linker $in -o $out $ldflags $static_libs " +
-			"-L$shared_libs_dir $shared_libs_flags $ldlibs",
-		Description: "$out",
-		Pool:        linkPool,
-	}, "build_wrapper", "ldflags", "ldlibs", "linker", "shared_libs_dir",
-	"shared_libs_flags", "static_libs")
+var executableRuleParams = blueprint.RuleParams{
+	Command: "$artifact_cache $build_wrapper $linker $in -o $out $ldflags $static_libs " +
+		"-L$shared_libs_dir $shared_libs_flags $ldlibs",
+	Description: "$out",
+	Pool:        linkPool,
+}
+
+var executableRule = pctx.StaticRule("executable", executableRuleParams, linkRuleArgs...)
 
 func (g *linuxGenerator) binaryActions(m *binary, ctx blueprint.ModuleContext) {
 	// Calculate and record outputs
@@ -573,24 +812,27 @@ func (g *linuxGenerator) binaryActions(m *binary, ctx blueprint.ModuleContext) {
 
 	_, buildWrapperDeps := m.Properties.Build.getBuildWrapperAndDeps(ctx)
 
-	orderOnly := buildWrapperDeps
-	if enableToc {
-		// Add an order only dependecy on the actual libraries to cover
-		// the case where the .so is deleted but the toc is still
-		// present.
-		orderOnly = append(orderOnly, g.getSharedLibLinkPaths(ctx)...)
-	}
+	orderOnly := append(buildWrapperDeps, g.getSharedLibOrderOnlyDeps(ctx)...)
 
+	linkMap := m.addLinkMap(ctx)
+	linkArgs := g.getBinaryArgs(m, ctx)
+	linkArgs["artifact_cache"] = artifactCacheWrapper(ctx, linkArgs["linker"],
+		utils.NewStringSlice(m.outputs(), linkMap))
 	ctx.Build(pctx,
 		blueprint.BuildParams{
-			Rule:      executableRule,
-			Outputs:   m.outputs(),
-			Inputs:    objectFiles,
-			Implicits: append(g.ccLinkImplicits(m, ctx, enableToc), nonCompiledDeps...),
-			OrderOnly: orderOnly,
-			Optional:  true,
-			Args:      g.getBinaryArgs(m, ctx),
+			Rule:            linkRule(ctx, &m.library, executableRule, executablePoolRules),
+			Outputs:         m.outputs(),
+			ImplicitOutputs: linkMap,
+			Inputs:          objectFiles,
+			Implicits:       append(g.ccLinkImplicits(m, ctx, enableToc), nonCompiledDeps...),
+			OrderOnly:       orderOnly,
+			Optional:        true,
+			Args:            linkArgs,
 		})
-	installDeps := g.install(m, ctx)
+	g.addSizeToolOutputs(ctx, &m.library)
+	g.addObjcopyOutputs(ctx, m)
+	g.addPostBuild(ctx, &m.library)
+	installDeps := append(g.install(m, ctx), m.postBuildOuts...)
+	installDeps = append(installDeps, g.addXcodeConfig(ctx, &m.library, linkArgs)...)
 	addPhony(m, ctx, installDeps, optional)
 }
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// The phony target which writes the checksum manifest
+const checksumManifestTargetName = "checksum_manifest"
+
+var _ = pctx.StaticVariable("checksum_manifest_tool", "${BobScriptsDir}/checksum_manifest.py")
+
+var checksumManifestRule = pctx.StaticRule("checksum_manifest",
+	blueprint.RuleParams{
+		Command:        "$checksum_manifest_tool -o $out --entries ${entries_file} $args",
+		CommandDeps:    []string{"$checksum_manifest_tool"},
+		Rspfile:        "${entries_file}",
+		RspfileContent: "$entries",
+		Description:    "$out",
+	}, "entries_file", "entries", "args")
+
+func checksumManifestEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["checksum_manifest"]
+	return ok && props.GetBool("checksum_manifest")
+}
+
+// addInstalledEntry records that 'file' is installed at 'path',
+// relative to the build directory.
+func (props *InstallableProps) addInstalledEntry(path, file string) {
+	props.InstalledEntries = append(props.InstalledEntries, path+"="+file)
+}
+
+// installedFiles returns the files recorded by addInstalledEntry.
+func (props *InstallableProps) installedFiles() []string {
+	files := []string{}
+	for _, entry := range props.InstalledEntries {
+		files = append(files, strings.SplitN(entry, "=", 2)[1])
+	}
+	return files
+}
+
+type checksumManifestSingleton struct{}
+
+// GenerateBuildActions writes the SHA-256 checksums of the files
+// installed by all modules, and of the distribution package, to a
+// manifest. With CHECKSUM_MANIFEST_KEY, the manifest is also signed.
+func (s *checksumManifestSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	config := getConfig(ctx)
+	files := map[string]string{}
+
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		ins, ok := m.(installable)
+		if !ok {
+			return
+		}
+		if e, ok := m.(enableable); ok && !isEnabled(e) {
+			return
+		}
+		for _, entry := range ins.getInstallableProps().InstalledEntries {
+			parts := strings.SplitN(entry, "=", 2)
+			files[parts[0]] = parts[1]
+		}
+	})
+
+	if distEnabled(config) {
+		_, _, archive := distArchive(config)
+		files[filepath.Join("dist", filepath.Base(archive))] = archive
+	}
+
+	manifest := getPathInBuildDir("checksums.sha256")
+	outputs := []string{manifest}
+	args := []string{}
+	if key, ok := config.Properties.properties["checksum_manifest_key"].(string); ok && key != "" {
+		outputs = append(outputs, manifest+".sig")
+		args = append(args, "--key", key, "--signature", manifest+".sig")
+	}
+
+	inputs := []string{}
+	entries := []string{}
+	for _, path := range utils.SortedKeys(files) {
+		inputs = append(inputs, files[path])
+		entries = append(entries, path+"="+files[path])
+	}
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:    checksumManifestRule,
+			Inputs:  inputs,
+			Outputs: outputs,
+			Args: map[string]string{
+				"entries_file": manifest + ".rsp",
+				"entries":      strings.Join(entries, " "),
+				"args":         strings.Join(args, " "),
+			},
+			Optional: true,
+		})
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     blueprint.Phony,
+			Inputs:   outputs,
+			Outputs:  []string{checksumManifestTargetName},
+			Optional: true,
+		})
+}
+
+func checksumManifestSingletonFactory() blueprint.Singleton {
+	return &checksumManifestSingleton{}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// The suffix of the target removing the outputs of a module
+const cleanTargetSuffix = "_clean"
+
+// The target listing the clean targets of all modules
+const cleanTargetsListName = "clean_targets"
+
+var moduleCleanRule = pctx.StaticRule("module_clean",
+	blueprint.RuleParams{
+		Command:     "rm -rf $paths",
+		Description: "clean $module",
+	}, "paths", "module")
+
+var cleanListRule = pctx.StaticRule("clean_targets_list",
+	blueprint.RuleParams{
+		Command:     "cat $list",
+		Description: "$out",
+	}, "list")
+
+// cleanPaths returns the files and directories which only hold the
+// outputs and intermediates of a module. Directories shared between
+// modules, such as the directory of all static libraries, are never
+// returned.
+func cleanPaths(m blueprint.Module) []string {
+	paths := []string{}
+
+	if l, ok := getLibrary(m); ok {
+		paths = append(paths, l.ObjDir())
+		paths = append(paths, l.postBuildOuts...)
+	} else if gc, ok := getGenerateCommon(m); ok {
+		paths = append(paths, gc.outputDir())
+	} else if km, ok := m.(*kernelModule); ok {
+		paths = append(paths, km.outputDir())
+	} else if dt, ok := m.(*deviceTree); ok {
+		paths = append(paths, dt.outputDir())
+	}
+
+	if p, ok := m.(phonyInterface); ok {
+		paths = append(paths, p.outputs()...)
+		paths = append(paths, p.implicitOutputs()...)
+	}
+
+	return utils.MergeUnique(paths)
+}
+
+type cleanTargetsSingleton struct{}
+
+// GenerateBuildActions adds a `<module>_clean` target for each module,
+// which removes its outputs and intermediate files so that the next
+// build of the module starts from scratch, and a target listing them.
+func (s *cleanTargetsSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	targets := []string{}
+
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if e, ok := m.(enableable); !ok || !isEnabled(e) {
+			return
+		}
+		p, ok := m.(phonyInterface)
+		if !ok {
+			return
+		}
+
+		paths := cleanPaths(m)
+		if len(paths) == 0 {
+			return
+		}
+
+		target := p.shortName() + cleanTargetSuffix
+		targets = append(targets, target)
+
+		ctx.Build(pctx,
+			blueprint.BuildParams{
+				Rule:    moduleCleanRule,
+				Outputs: []string{target},
+				Args: map[string]string{
+					"paths":  utils.Join(paths),
+					"module": p.shortName(),
+				},
+				Optional: true,
+			})
+	})
+
+	sort.Strings(targets)
+	list := filepath.Join(getBuildDir(), cleanTargetsListName+".txt")
+	sb := &strings.Builder{}
+	for _, target := range targets {
+		sb.WriteString(target + "\n")
+	}
+	if err := getBackendOutput(getConfig(ctx)).writeFile(list, sb); err != nil {
+		utils.Die("%v", err)
+	}
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     cleanListRule,
+			Outputs:  []string{cleanTargetsListName},
+			Args:     map[string]string{"list": list},
+			Optional: true,
+		})
+}
+
+func cleanTargetsSingletonFactory() blueprint.Singleton {
+	return &cleanTargetsSingleton{}
+}
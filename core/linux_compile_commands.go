@@ -0,0 +1,87 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+
+	"github.com/google/blueprint"
+)
+
+// The phony target which writes the project's compilation database
+const compileCommandsTargetName = "compile_commands"
+
+var (
+	_                   = pctx.StaticVariable("compile_commands_tool", "${BobScriptsDir}/compile_commands.py")
+	compileCommandsRule = pctx.StaticRule("compile_commands",
+		blueprint.RuleParams{
+			Command:     "python $compile_commands_tool -o $out $in",
+			CommandDeps: []string{"$compile_commands_tool"},
+			Description: "$out",
+		})
+)
+
+func kernelModuleCompileCommandsEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["kernel_module_compile_commands"]
+	return ok && props.GetBool("kernel_module_compile_commands")
+}
+
+// compileCommandsFile is the compilation database written by the
+// Kbuild invocation of a kernel module.
+func (m *kernelModule) compileCommandsFile() string {
+	return filepath.Join(m.outputDir(), m.outputName()+".compile_commands.json")
+}
+
+type compileCommandsSingleton struct{}
+
+// GenerateBuildActions merges the compilation databases of all kernel
+// modules into compile_commands.json in the build directory, so that
+// editors get the flags Kbuild uses for driver sources. Entries for
+// other files in an existing database are kept.
+func (s *compileCommandsSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	databases := []string{}
+
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if km, ok := m.(*kernelModule); ok && isEnabled(km) {
+			databases = append(databases, km.compileCommandsFile())
+		}
+	})
+
+	output := getPathInBuildDir("compile_commands.json")
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     compileCommandsRule,
+			Inputs:   databases,
+			Outputs:  []string{output},
+			Optional: true,
+		})
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     blueprint.Phony,
+			Inputs:   []string{output},
+			Outputs:  []string{compileCommandsTargetName},
+			Optional: true,
+		})
+}
+
+func compileCommandsSingletonFactory() blueprint.Singleton {
+	return &compileCommandsSingleton{}
+}
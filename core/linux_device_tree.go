@@ -0,0 +1,111 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+var dtcRule = pctx.StaticRule("dtc",
+	blueprint.RuleParams{
+		Depfile:     "$out.d",
+		Deps:        blueprint.DepsGCC,
+		Command:     "$dtc -I dts -O dtb -d $out.d $dtcflags -o $out $in",
+		Description: "$out",
+	}, "dtc", "dtcflags")
+
+// The preprocessed source is kept next to the blob, so that dtc errors,
+// which refer to it, can be looked up.
+var dtcCppRule = pctx.StaticRule("dtc_cpp",
+	blueprint.RuleParams{
+		Depfile: "$out.d",
+		Deps:    blueprint.DepsGCC,
+		Command: "$ccompiler -E -nostdinc -undef -D__DTS__ -x assembler-with-cpp $cppflags " +
+			"-MD -MF $out.d -MT $out -o $out.pp.dts $in && " +
+			"$dtc -I dts -O dtb $dtcflags -o $out $out.pp.dts",
+		Description: "$out",
+	}, "ccompiler", "cppflags", "dtc", "dtcflags")
+
+func (g *linuxGenerator) deviceTreeOutputDir(ctx blueprint.ModuleContext, m *deviceTree) string {
+	return g.moduleOutputDir(ctx, filepath.Join("${BuildDir}", string(tgtTypeTarget), "dtb"), m.Name())
+}
+
+// deviceTreeIncludeDirs returns the directories searched for included
+// files, in search order.
+func (g *linuxGenerator) deviceTreeIncludeDirs(m *deviceTree) []string {
+	return utils.NewStringSlice(
+		getBackendPathsInSourceDir(g, m.Properties.Local_include_dirs),
+		m.Properties.Include_dirs)
+}
+
+// deviceTreeActions compiles each source to its own blob. Overlays are
+// compiled with -@, so that they can refer to the labels of the base
+// device tree.
+func (g *linuxGenerator) deviceTreeActions(m *deviceTree, ctx blueprint.ModuleContext) {
+	srcs := m.Properties.getSources(ctx)
+	names := m.outputFileNames(ctx, srcs)
+	if len(names) != len(srcs) {
+		return
+	}
+
+	m.outputdir = g.deviceTreeOutputDir(ctx, m)
+	m.outs = utils.PrefixDirs(names, m.outputDir())
+
+	includeDirs := g.deviceTreeIncludeDirs(m)
+	dtcflags := utils.NewStringSlice(utils.PrefixAll(includeDirs, "-i "), m.Properties.Dtc_flags)
+	if m.overlay {
+		dtcflags = append([]string{"-@"}, dtcflags...)
+	}
+
+	rule := dtcRule
+	args := map[string]string{
+		"dtc":      dtcTool(getConfig(ctx)),
+		"dtcflags": utils.Join(dtcflags),
+	}
+	if m.preprocess() {
+		cc, _ := g.getToolchain(tgtTypeTarget).getCCompiler()
+		rule = dtcCppRule
+		args["ccompiler"] = cc
+		args["cppflags"] = utils.Join(utils.PrefixAll(includeDirs, "-I"), m.Properties.Cpp_flags)
+	}
+
+	for i, src := range srcs {
+		implicitOuts := []string{}
+		if m.preprocess() {
+			implicitOuts = append(implicitOuts, m.outs[i]+".pp.dts")
+		}
+		m.implicitOuts = append(m.implicitOuts, implicitOuts...)
+
+		ctx.Build(pctx,
+			blueprint.BuildParams{
+				Rule:            rule,
+				Inputs:          []string{getBackendPathInSourceDir(g, src)},
+				Outputs:         []string{m.outs[i]},
+				ImplicitOutputs: implicitOuts,
+				Args:            args,
+				Optional:        true,
+			})
+	}
+
+	installDeps := g.install(m, ctx)
+	addPhony(m, ctx, installDeps, !isBuiltByDefault(m))
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+
+	"github.com/google/blueprint"
+)
+
+// The phony target which creates the distribution package
+const distTargetName = "dist"
+
+var _ = pctx.StaticVariable("dist_tool", "${BobScriptsDir}/dist.py")
+
+// Like the size report, the list of files is passed in a response file.
+var distRule = pctx.StaticRule("dist",
+	blueprint.RuleParams{
+		Command: "$dist_tool -o $out --root ${root} --format ${format} " +
+			"--entries ${entries_file}",
+		CommandDeps:    []string{"$dist_tool"},
+		Rspfile:        "${entries_file}",
+		RspfileContent: "$entries",
+		Description:    "$out",
+	}, "root", "format", "entries_file", "entries")
+
+func distEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["dist"]
+	return ok && props.GetBool("dist")
+}
+
+// addDistEntry records that 'file' is packaged at 'path' in the
+// distribution package, if the module is part of it.
+func (props *InstallableProps) addDistEntry(path, file string) {
+	if !props.isDist() {
+		return
+	}
+	if name := matchInstallSetting(props.Dist_renames, filepath.Base(path)); name != "" {
+		path = filepath.Join(filepath.Dir(path), name)
+	}
+	props.DistEntries = append(props.DistEntries, path+"="+file)
+}
+
+// addDistLicenses records the licence files of a module. They are kept
+// in a directory per module, so that identically named files from
+// different modules do not collide.
+func (props *InstallableProps) addDistLicenses(ctx blueprint.ModuleContext, g generatorBackend) {
+	if !props.isDist() {
+		return
+	}
+	for _, license := range props.Dist_licenses {
+		path := filepath.Join("licenses", ctx.ModuleName(), filepath.Base(license))
+		props.DistEntries = append(props.DistEntries,
+			path+"="+getBackendPathInSourceDir(g, license))
+	}
+}
+
+// distArchive returns the name of the top level directory of the
+// distribution package, its format, and its path
+func distArchive(config *bobConfig) (root, format, archive string) {
+	props := config.Properties
+	root = props.GetString("dist_name")
+	if version := props.GetString("dist_version"); version != "" {
+		root += "-" + version
+	}
+	format = "tar.gz"
+	if props.GetBool("dist_zip") {
+		format = "zip"
+	}
+	archive = getPathInBuildDir("dist", root+"."+format)
+	return
+}
+
+type distSingleton struct{}
+
+// GenerateBuildActions packages the installed files of all modules with
+// dist set into a single archive.
+func (s *distSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	// Map of archive path to the file packaged there
+	files := map[string]string{}
+	owners := map[string]string{}
+
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		ins, ok := m.(installable)
+		if !ok {
+			return
+		}
+		for _, entry := range ins.getInstallableProps().DistEntries {
+			parts := strings.SplitN(entry, "=", 2)
+			path, file := parts[0], parts[1]
+			if prev, ok := files[path]; ok && prev != file {
+				ctx.Errorf("%s is packaged from both %s (module %s) and %s (module %s)",
+					path, prev, owners[path], file, ctx.ModuleName(m))
+				continue
+			}
+			files[path] = file
+			owners[path] = ctx.ModuleName(m)
+		}
+	})
+
+	root, format, archive := distArchive(getConfig(ctx))
+
+	inputs := []string{}
+	entries := []string{}
+	for _, path := range utils.SortedKeys(files) {
+		inputs = append(inputs, files[path])
+		entries = append(entries, path+"="+files[path])
+	}
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:    distRule,
+			Inputs:  inputs,
+			Outputs: []string{archive},
+			Args: map[string]string{
+				"root":         root,
+				"format":       format,
+				"entries_file": archive + ".rsp",
+				"entries":      strings.Join(entries, " "),
+			},
+			Optional: true,
+		})
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     blueprint.Phony,
+			Inputs:   []string{archive},
+			Outputs:  []string{distTargetName},
+			Optional: true,
+		})
+}
+
+func distSingletonFactory() blueprint.Singleton {
+	return &distSingleton{}
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// The phony target which checks the formatting of all modules
+const formatCheckTargetName = "format-check"
+
+var _ = pctx.StaticVariable("format_check_tool", "${BobScriptsDir}/format_check.py")
+
+// The check of each module always succeeds, recording the unformatted
+// files, so that the summary can report every module at once.
+var formatCheckRule = pctx.StaticRule("format_check",
+	blueprint.RuleParams{
+		Command:     "$format_check_tool check --clang-format $clang_format --module $module -o $out $in",
+		CommandDeps: []string{"$format_check_tool"},
+		Description: "format-check $module",
+	}, "clang_format", "module")
+
+var formatSummaryRule = pctx.StaticRule("format_summary",
+	blueprint.RuleParams{
+		Command:        "$format_check_tool summary -o $out --results $out.rsp",
+		CommandDeps:    []string{"$format_check_tool"},
+		Rspfile:        "$out.rsp",
+		RspfileContent: "$in",
+		Description:    "$out",
+	})
+
+func formatCheckEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["clang_format_check"]
+	return ok && props.GetBool("clang_format_check")
+}
+
+// clang-format understands C and C++, including headers
+func isFormattable(s string) bool {
+	switch filepath.Ext(s) {
+	case ".c", ".cc", ".cpp", ".h", ".hh", ".hpp":
+		return true
+	}
+	return false
+}
+
+// addFormatCheck checks the formatting of the sources listed in the
+// module's srcs. Generated sources are not checked. 'srcs' are
+// relative to the source directory, as returned by getSources().
+func addFormatCheck(ctx blueprint.ModuleContext, tgt tgtType, srcs []string) []string {
+	if !formatCheckEnabled(getConfig(ctx)) {
+		return []string{}
+	}
+
+	srcs = utils.Filter(isFormattable, srcs)
+	if len(srcs) == 0 {
+		return []string{}
+	}
+
+	output := filepath.Join("${BuildDir}", string(tgt), "format_check", ctx.ModuleName()+".json")
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:    formatCheckRule,
+			Outputs: []string{output},
+			Inputs:  getBackendPathsInSourceDir(getBackend(ctx), srcs),
+			Args: map[string]string{
+				"clang_format": getConfig(ctx).Properties.GetString("clang_format_binary"),
+				"module":       ctx.ModuleName(),
+			},
+			Optional: true,
+		})
+
+	return []string{output}
+}
+
+func (l *library) addFormatCheckAction(ctx blueprint.ModuleContext) {
+	if !proptools.Bool(l.Properties.Format_check_disabled) {
+		l.formatCheckOuts = addFormatCheck(ctx, l.Properties.TargetType,
+			l.Properties.getSources(ctx))
+	}
+}
+
+type formatCheckProducer interface {
+	formatCheckOutputs() []string
+}
+
+func (l *library) formatCheckOutputs() []string {
+	return l.formatCheckOuts
+}
+
+func (m *headerLibrary) formatCheckOutputs() []string {
+	return m.formatCheckOuts
+}
+
+type formatCheckSingleton struct{}
+
+// GenerateBuildActions summarizes the formatting checks of every module
+// under the format-check target.
+func (s *formatCheckSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	results := []string{}
+
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if f, ok := m.(formatCheckProducer); ok {
+			results = append(results, f.formatCheckOutputs()...)
+		}
+	})
+
+	output := filepath.Join("${BuildDir}", "format_check.stamp")
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     formatSummaryRule,
+			Inputs:   results,
+			Outputs:  []string{output},
+			Optional: true,
+		})
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     blueprint.Phony,
+			Inputs:   []string{output},
+			Outputs:  []string{formatCheckTargetName},
+			Optional: true,
+		})
+}
+
+func formatCheckSingletonFactory() blueprint.Singleton {
+	return &formatCheckSingleton{}
+}
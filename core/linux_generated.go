@@ -18,6 +18,9 @@
 package core
 
 import (
+	"regexp"
+	"strings"
+
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
 
@@ -36,30 +39,109 @@ var touchRule = pctx.StaticRule("touch",
 		Description: "touch $out",
 	})
 
+var _ = pctx.StaticVariable("tool_stamp", "${BobScriptsDir}/tool_stamp.py")
+
+// The stamp is only rewritten when the hash of the tools changes, so
+// restat stops generators running again after a tool is rebuilt with
+// the same contents.
+var toolStampRule = pctx.StaticRule("tool_stamp",
+	blueprint.RuleParams{
+		Command:     "python ${tool_stamp} --output $out --depfile $out.d --root ${SrcDir} $in",
+		CommandDeps: []string{"${tool_stamp}"},
+		Depfile:     "$out.d",
+		Deps:        blueprint.DepsGCC,
+		Restat:      true,
+		Description: "$out",
+	})
+
+// Matches references to ninja's ${out} variable in a command, and
+// escaped dollars, so that $$out is not taken to be a reference.
+var outVarRegexp = regexp.MustCompile(`\$\$|\$\{out\}|\$out([^a-zA-Z0-9_-]|$)`)
+
+// ninja does not support depfiles on build statements with multiple
+// outputs. When a module needs this, only the first output is declared on
+// the build statement running the command, and references to ${out} are
+// redirected to the "_outs" argument, which holds the full output list.
+func expandMultiOutRefs(s string) string {
+	return outVarRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$$" {
+			return match
+		}
+		return "${_outs}" + outVarRegexp.FindStringSubmatch(match)[1]
+	})
+}
+
 // Generate the build actions for a generateSource module and populates the outputs.
 func (g *linuxGenerator) generateCommonActions(m *generateCommon, ctx blueprint.ModuleContext, inouts []inout) {
-	m.outputdir = g.sourceOutputDir(m)
+	m.outputdir = g.sourceOutputDir(ctx, m)
 	prefixInoutsWithOutputDir(inouts, m.outputDir())
 	// Calculate and record outputs and include dirs
 	m.recordOutputsFromInout(inouts)
 	m.includeDirs = utils.PrefixDirs(m.Properties.Export_gen_include_dirs, m.outputDir())
 
-	cmd, args, implicits, hostTarget := m.getArgs(ctx)
+	cmd, args, implicits, hostBinLibDirs := m.getArgs(ctx)
 
-	ldLibraryPath := ""
-	if _, ok := args["host_bin"]; ok {
-		ldLibraryPath += "LD_LIBRARY_PATH=" + g.sharedLibsDir(hostTarget) + ":$$LD_LIBRARY_PATH "
+	orderOnly := []string{}
+	if tools := stampedTools(args); len(tools) > 0 && toolStampsEnabled(getConfig(ctx)) {
+		stamp := m.outputDir() + ".tool_stamp"
+		ctx.Build(pctx,
+			blueprint.BuildParams{
+				Rule:     toolStampRule,
+				Inputs:   tools,
+				Outputs:  []string{stamp},
+				Optional: true,
+			})
+		implicits = toolStampDeps(implicits, tools, stamp)
+		orderOnly = tools
 	}
+
+	// Variables from env are exported before setting the library path
+	// of host_bin, which only applies to the first command
+	cmdPrefix := m.Properties.envExportPrefix() + ldLibraryPathPrefix(hostBinLibDirs)
 	utils.StripUnusedArgs(args, cmd)
 
+	multiOutDepfile := false
+	for _, inout := range inouts {
+		if inout.depfile != "" && len(inout.out) > 1 {
+			multiOutDepfile = true
+			break
+		}
+	}
+	if multiOutDepfile {
+		cmd = expandMultiOutRefs(cmd)
+	}
+
+	// Generated files can only be cached when every input is known
+	// before running the command, which is not the case with a depfile
+	config := getConfig(ctx)
+	cached := artifactCacheAllActions(config) && artifactCacheArgs(config) != nil &&
+		!proptools.Bool(m.Properties.Console)
+	for _, inout := range inouts {
+		if inout.depfile != "" {
+			cached = false
+			break
+		}
+	}
+	rspContent := proptools.String(m.Properties.Rsp_content)
+
 	var pool blueprint.Pool
+	if proptools.Bool(m.Properties.Console) && m.Properties.Pool != nil {
+		utils.Die("Module %s sets both console and pool", ctx.ModuleName())
+	}
 	if proptools.Bool(m.Properties.Console) {
 		// Console can be used to run longrunning jobs (even interactive jobs).
 		pool = blueprint.Console
+	} else if m.Properties.Pool != nil {
+		var ok bool
+		pool, ok = ninjaPools[*m.Properties.Pool]
+		if !ok {
+			utils.Die("Module %s uses pool '%s', which is not defined in GENERATOR_POOLS or NINJA_POOLS",
+				ctx.ModuleName(), *m.Properties.Pool)
+		}
 	}
 
 	ruleparams := blueprint.RuleParams{
-		Command: ldLibraryPath + cmd,
+		Command: cmdPrefix + cmd,
 		// Restat is always set to true. This is due to wanting to enable scripts
 		// to only update the outputs if they have changed (keeping the same mtime if it
 		// has not). If there are no updates, the following rules will not have to update
@@ -72,41 +154,76 @@ func (g *linuxGenerator) generateCommonActions(m *generateCommon, ctx blueprint.
 	if m.Properties.Rsp_content != nil {
 		ruleparams.Rspfile = "${rspfile}"
 		ruleparams.RspfileContent = *m.Properties.Rsp_content
+		if multiOutDepfile {
+			ruleparams.RspfileContent = expandMultiOutRefs(ruleparams.RspfileContent)
+		}
+	}
+
+	if cached {
+		ruleparams.Command = artifactCacheGeneratorCommand(ruleparams.Command)
+	}
+
+	// Each command of a bob_transform_source can have its own arguments
+	fileArgs := utils.ContainsArg(cmd, "file_args")
+
+	argNames := append(utils.SortedKeys(args), "depfile", "rspfile")
+	if fileArgs {
+		argNames = append(argNames, "file_args")
+	}
+	if multiOutDepfile {
+		argNames = append(argNames, "_outs")
+	}
+	if cached {
+		argNames = append(argNames, "artifact_cache")
 	}
 
 	//print("Keys:" + strings.Join(argkeys, ",") + "\n")
-	rule := ctx.Rule(pctx, "gen_"+m.Name(), ruleparams,
-		append(utils.SortedKeys(args), "depfile", "rspfile")...)
+	rule := ctx.Rule(pctx, "gen_"+m.Name(), ruleparams, argNames...)
 
 	for _, inout := range inouts {
-		if inout.depfile != "" && len(inout.out) > 1 {
-			utils.Die("Module %s uses a depfile with multiple outputs", ctx.ModuleName())
-		}
-
 		if inout.rspfile != "" {
 			args["rspfile"] = inout.rspfile
 		}
+		if multiOutDepfile {
+			args["_outs"] = strings.Join(inout.out, " ")
+		}
+		if fileArgs {
+			args["file_args"] = strings.Join(inout.fileArgs, " ")
+		}
+		if cached {
+			delete(args, "artifact_cache")
+			key := generatorActionKey(cmdPrefix+cmd, args, rspContent, inout, implicits)
+			args["artifact_cache"] = artifactCacheGeneratorArgs(config, key, inout, implicits)
+		}
 
 		buildparams := blueprint.BuildParams{
 			Rule:      rule,
 			Inputs:    inout.in,
 			Outputs:   inout.out,
 			Implicits: append(inout.implicitSrcs, implicits...),
+			OrderOnly: orderOnly,
 			Args:      args,
 			Optional:  true,
 		}
 
 		// ninja currently does not support case when depfile is defined and
-		// multiple outputs at the same time. For implicit outputs fallback to using a separate rule.
+		// multiple outputs at the same time. Only the first output is
+		// declared on the command, and the other explicit and implicit
+		// outputs fall back to using a separate rule.
 		if inout.depfile != "" {
-			if len(inout.implicitOuts) > 0 {
-				// No-op rule linking implicit outputs to the main output. Touch the implicit
-				// outputs in case the script actually creates the implicit outputs first.
+			extraOuts := inout.implicitOuts
+			if len(inout.out) > 1 {
+				buildparams.Outputs = inout.out[:1]
+				extraOuts = utils.NewStringSlice(inout.out[1:], inout.implicitOuts)
+			}
+			if len(extraOuts) > 0 {
+				// No-op rule linking the other outputs to the main output. Touch the other
+				// outputs in case the script actually creates them first.
 				ctx.Build(pctx,
 					blueprint.BuildParams{
 						Rule:     touchRule,
-						Inputs:   inout.out,
-						Outputs:  inout.implicitOuts,
+						Inputs:   buildparams.Outputs,
+						Outputs:  extraOuts,
 						Optional: true,
 					})
 			}
@@ -129,7 +246,7 @@ func (g *linuxGenerator) generateSourceActions(m *generateSource, ctx blueprint.
 }
 
 func (g *linuxGenerator) transformSourceActions(m *transformSource, ctx blueprint.ModuleContext) {
-	inouts := m.generateInouts(ctx, g)
+	inouts := m.shardInouts(m.generateInouts(ctx, g))
 	g.generateCommonActions(&m.generateCommon, ctx, inouts)
 
 	installDeps := g.install(m, ctx)
@@ -0,0 +1,38 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_expandMultiOutRefs(t *testing.T) {
+	assert.Equal(t, "gen ${in} -o ${_outs} -d ${depfile}",
+		expandMultiOutRefs("gen ${in} -o ${out} -d ${depfile}"))
+	assert.Equal(t, "cp ${in} ${_outs}", expandMultiOutRefs("cp ${in} $out"))
+	assert.Equal(t, "echo ${_outs}; touch ${_outs}",
+		expandMultiOutRefs("echo $out; touch $out"))
+
+	// Other variables and escaped dollars are left alone
+	assert.Equal(t, "gen $outdir $out_dir", expandMultiOutRefs("gen $outdir $out_dir"))
+	assert.Equal(t, "for f in ${_outs}; do echo $$out; done",
+		expandMultiOutRefs("for f in ${out}; do echo $$out; done"))
+	assert.Equal(t, "echo $${out} $$${_outs}", expandMultiOutRefs("echo $${out} $$${out}"))
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+var _ = pctx.StaticVariable("go_binary_tool", "${BobScriptsDir}/go_binary.py")
+
+var goBinaryRule = pctx.StaticRule("go_binary",
+	blueprint.RuleParams{
+		Command: "python ${go_binary_tool} --go $go --goflags '$goflags' " +
+			"--cache-dir $cache_dir --dir $dir --package $package $tags_arg $ldflags_arg " +
+			"--output $out",
+		CommandDeps: []string{"${go_binary_tool}"},
+		Description: "$out",
+	}, "go", "goflags", "cache_dir", "dir", "package", "tags_arg", "ldflags_arg")
+
+func (g *linuxGenerator) goBinaryOutputDir(m *goBinary) string {
+	return filepath.Join("${BuildDir}", string(tgtTypeHost), "go", m.Name())
+}
+
+// goCacheDir is shared by every Go tool, so that packages used by more
+// than one tool are only downloaded and compiled once.
+func (g *linuxGenerator) goCacheDir() string {
+	return filepath.Join("${BuildDir}", "go")
+}
+
+// goBinaryActions builds the tool. Ninja only knows about the sources
+// listed in srcs, and relies on the go command's own cache to make
+// rebuilding the tool cheap when nothing it uses changed.
+func (g *linuxGenerator) goBinaryActions(m *goBinary, ctx blueprint.ModuleContext) {
+	m.outputdir = g.goBinaryOutputDir(m)
+	m.outs = []string{filepath.Join(m.outputDir(), m.Name())}
+
+	tool, goflags := goTool(getConfig(ctx))
+	args := map[string]string{
+		"go":          tool,
+		"goflags":     goflags,
+		"cache_dir":   g.goCacheDir(),
+		"dir":         filepath.Join(g.sourceDir(), projectModuleDir(ctx)),
+		"package":     m.goPackage(),
+		"tags_arg":    "",
+		"ldflags_arg": "",
+	}
+	if len(m.Properties.Tags) > 0 {
+		args["tags_arg"] = "--tags " + strings.Join(m.Properties.Tags, ",")
+	}
+	if len(m.Properties.Ldflags) > 0 {
+		args["ldflags_arg"] = "--ldflags '" + strings.Join(m.Properties.Ldflags, " ") + "'"
+	}
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     goBinaryRule,
+			Inputs:   getBackendPathsInSourceDir(g, m.Properties.getSources(ctx)),
+			Outputs:  m.outputs(),
+			Args:     args,
+			Optional: true,
+		})
+
+	addPhony(m, ctx, []string{}, !isBuiltByDefault(m))
+}
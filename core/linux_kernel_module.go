@@ -35,23 +35,24 @@ var (
 				"--sources $in " +
 				"--kernel $kernel_dir --cross-compile '$kernel_cross_compile' " +
 				"$cc_flag $hostcc_flag $clang_triple_flag $ld_flag " +
-				"$kbuild_options --extra-cflags='$extra_cflags' $make_args",
+				"$compile_commands_flag $kbuild_options --extra-cflags='$extra_cflags' $make_args",
 			CommandDeps: []string{"$kmod_build"},
 			Depfile:     "$out.d",
 			Deps:        blueprint.DepsGCC,
 			Pool:        blueprint.Console,
 			Description: "$out",
 		}, "depfile", "extra_includes", "extra_cflags", "kernel_dir", "kernel_cross_compile",
-		"kbuild_options", "make_args", "output_module_dir", "cc_flag", "hostcc_flag", "clang_triple_flag", "ld_flag")
+		"kbuild_options", "make_args", "output_module_dir", "cc_flag", "hostcc_flag", "clang_triple_flag", "ld_flag",
+		"compile_commands_flag")
 )
 
-func (g *linuxGenerator) kernelModOutputDir(m *kernelModule) string {
-	return filepath.Join("${BuildDir}", "target", "kernel_modules", m.outputName())
+func (g *linuxGenerator) kernelModOutputDir(ctx blueprint.ModuleContext, m *kernelModule) string {
+	return g.moduleOutputDir(ctx, filepath.Join("${BuildDir}", "target", "kernel_modules"), m.outputName())
 }
 
 func (g *linuxGenerator) kernelModuleActions(m *kernelModule, ctx blueprint.ModuleContext) {
 	// Calculate and record outputs
-	m.outputdir = g.kernelModOutputDir(m)
+	m.outputdir = g.kernelModOutputDir(ctx, m)
 	m.outs = []string{filepath.Join(m.outputDir(), m.outputName()+".ko")}
 	optional := !isBuiltByDefault(m)
 
@@ -61,6 +62,12 @@ func (g *linuxGenerator) kernelModuleActions(m *kernelModule, ctx blueprint.Modu
 		getBackendPathsInSourceDir(g, m.Properties.getSources(ctx)),
 		m.extraSymbolsFiles(ctx))
 
+	sideOutputs := []string{filepath.Join(m.outputDir(), "Module.symvers")}
+	if kernelModuleCompileCommandsEnabled(getConfig(ctx)) {
+		args["compile_commands_flag"] = "--compile-commands " + m.compileCommandsFile()
+		sideOutputs = append(sideOutputs, m.compileCommandsFile())
+	}
+
 	ctx.Build(pctx,
 		blueprint.BuildParams{
 			Rule:     kbuildRule,
@@ -70,14 +77,15 @@ func (g *linuxGenerator) kernelModuleActions(m *kernelModule, ctx blueprint.Modu
 			Args:     args,
 		})
 
-	// Add a dependency between Module.symvers and the kernel module. This
-	// should really be added to Outputs or ImplicitOutputs above, but
-	// Ninja doesn't support dependency files with multiple outputs yet.
+	// Add a dependency between Module.symvers (and the compilation
+	// database) and the kernel module. These should really be added to
+	// Outputs or ImplicitOutputs above, but Ninja doesn't support
+	// dependency files with multiple outputs yet.
 	ctx.Build(pctx,
 		blueprint.BuildParams{
 			Rule:     blueprint.Phony,
 			Inputs:   m.outputs(),
-			Outputs:  []string{filepath.Join(m.outputDir(), "Module.symvers")},
+			Outputs:  sideOutputs,
 			Optional: true,
 		})
 
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+var _ = pctx.StaticVariable("library_interface_tool", "${BobScriptsDir}/library_interface.py")
+
+var libraryInterfaceRule = pctx.StaticRule("library_interface",
+	blueprint.RuleParams{
+		Command:     "$library_interface_tool $in -o $out $args",
+		CommandDeps: []string{"$library_interface_tool"},
+		Description: "$out",
+	}, "args")
+
+func libraryInterfaceEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["library_interface_files"]
+	return ok && props.GetBool("library_interface_files")
+}
+
+// addLibraryInterface writes a description of what the library exports
+// next to 'libFile', returning the description's path so that it can be
+// built along with the library. The include directories are recorded as
+// given in the build files, so do not depend on the build directory.
+func (g *linuxGenerator) addLibraryInterface(ctx blueprint.ModuleContext, l *library,
+	libFile, libType, soname string) []string {
+
+	if !libraryInterfaceEnabled(getConfig(ctx)) {
+		return []string{}
+	}
+
+	tc := g.getToolchain(l.Properties.TargetType)
+	output := filepath.Join(filepath.Dir(libFile), l.outputName()+".interface.json")
+
+	args := []string{
+		"--name", ctx.ModuleName(),
+		"--type", libType,
+		"--target", string(l.Properties.TargetType),
+	}
+	if soname != "" {
+		args = append(args, "--soname", soname)
+	}
+	includeDirs := utils.NewStringSlice(l.Properties.Export_local_include_dirs,
+		l.Properties.Export_include_dirs)
+	for _, dir := range includeDirs {
+		args = append(args, "--export-include-dir="+dir)
+	}
+	for _, flag := range l.Properties.Export_cflags {
+		args = append(args, "--export-cflag="+flag)
+	}
+	args = append(args, tc.getLibraryTocFlags()...)
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     libraryInterfaceRule,
+			Outputs:  []string{output},
+			Inputs:   []string{libFile},
+			Args:     map[string]string{"args": utils.Join(args)},
+			Optional: true,
+		})
+
+	return []string{output}
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+
+	"github.com/google/blueprint"
+)
+
+// The phony target which writes the code size report
+const sizeReportTargetName = "size_report"
+
+var _ = pctx.StaticVariable("size_report_tool", "${BobScriptsDir}/size_report.py")
+
+// The list of maps can be long, so pass it in a response file.
+var sizeReportRule = pctx.StaticRule("size_report",
+	blueprint.RuleParams{
+		Command:        "$size_report_tool --json ${json} --csv ${csv} --entries ${json}.rsp",
+		CommandDeps:    []string{"$size_report_tool"},
+		Rspfile:        "${json}.rsp",
+		RspfileContent: "$entries",
+		Description:    "$out",
+	}, "json", "csv", "entries")
+
+// The largest symbols, in the order nm sorts them
+var symbolSizesRule = pctx.StaticRule("symbol_sizes",
+	blueprint.RuleParams{
+		Command:     "$nm --print-size --size-sort $in > $out",
+		Description: "$out",
+	}, "nm")
+
+// The section totals, in the Berkeley format of size
+var sectionSizesRule = pctx.StaticRule("section_sizes",
+	blueprint.RuleParams{
+		Command:     "$size $in > $out",
+		Description: "$out",
+	}, "size")
+
+func linkMapEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["link_map"]
+	return ok && props.GetBool("link_map")
+}
+
+func (l *library) linkMapFile() string {
+	return l.outputs()[0] + ".map"
+}
+
+// addLinkMap records that the link of the library writes a map file,
+// returning the extra outputs of the link.
+func (l *library) addLinkMap(ctx blueprint.ModuleContext) []string {
+	if !linkMapEnabled(getConfig(ctx)) {
+		return []string{}
+	}
+	l.linkMap = l.linkMapFile()
+	return []string{l.linkMap}
+}
+
+// addSizeToolOutputs runs nm and size on the file a library links, so
+// that the code size report can list its largest symbols, and check
+// the totals from the link map. Nothing is run when the toolchain has
+// no nm or size.
+func (g *linuxGenerator) addSizeToolOutputs(ctx blueprint.ModuleContext, l *library) {
+	if l.linkMap == "" {
+		return
+	}
+	nm, size := g.getToolchain(l.Properties.TargetType).getSizeTools()
+	if nm == "" || size == "" {
+		return
+	}
+
+	linked := l.outputs()[0]
+	l.symbolSizes = linked + ".nm"
+	l.sectionSizes = linked + ".size"
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     symbolSizesRule,
+			Inputs:   []string{linked},
+			Outputs:  []string{l.symbolSizes},
+			Args:     map[string]string{"nm": nm},
+			Optional: true,
+		})
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     sectionSizesRule,
+			Inputs:   []string{linked},
+			Outputs:  []string{l.sectionSizes},
+			Args:     map[string]string{"size": size},
+			Optional: true,
+		})
+}
+
+type linkMapProducer interface {
+	linkMapOutput() string
+	// The output of nm and size on the linked file. Empty when they
+	// were not run.
+	sizeToolOutputs() (symbols, sections string)
+}
+
+func (l *library) linkMapOutput() string {
+	return l.linkMap
+}
+
+func (l *library) sizeToolOutputs() (string, string) {
+	return l.symbolSizes, l.sectionSizes
+}
+
+// sizeReportEntry describes the files summarized for one module, as
+// module=map[,nm,size], which is how size_report.py reads it.
+func sizeReportEntry(name, linkMap, symbols, sections string) string {
+	entry := name + "=" + linkMap
+	if symbols != "" && sections != "" {
+		entry += "," + symbols + "," + sections
+	}
+	return entry
+}
+
+type sizeReportSingleton struct{}
+
+// GenerateBuildActions summarizes the link maps of all the binaries and
+// shared libraries which have been linked, with the output of nm and
+// size on them.
+func (s *sizeReportSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	entries := map[string]string{}
+	inputs := map[string][]string{}
+
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		p, ok := m.(linkMapProducer)
+		if !ok || p.linkMapOutput() == "" {
+			return
+		}
+		name := ctx.ModuleName(m)
+		if variant := ctx.ModuleSubDir(m); variant != "" {
+			name += ":" + variant
+		}
+		symbols, sections := p.sizeToolOutputs()
+		entries[name] = sizeReportEntry(name, p.linkMapOutput(), symbols, sections)
+		inputs[name] = utils.Filter(func(s string) bool { return s != "" },
+			[]string{p.linkMapOutput(), symbols, sections})
+	})
+
+	maps := []string{}
+	content := []string{}
+	for _, name := range utils.SortedKeys(entries) {
+		maps = append(maps, inputs[name]...)
+		content = append(content, entries[name])
+	}
+
+	jsonReport := getPathInBuildDir("size_report.json")
+	csvReport := getPathInBuildDir("size_report.csv")
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:    sizeReportRule,
+			Inputs:  maps,
+			Outputs: []string{jsonReport, csvReport},
+			Args: map[string]string{
+				"json":    jsonReport,
+				"csv":     csvReport,
+				"entries": strings.Join(content, " "),
+			},
+			Optional: true,
+		})
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     blueprint.Phony,
+			Inputs:   []string{jsonReport, csvReport},
+			Outputs:  []string{sizeReportTargetName},
+			Optional: true,
+		})
+}
+
+func sizeReportSingletonFactory() blueprint.Singleton {
+	return &sizeReportSingleton{}
+}
@@ -0,0 +1,31 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sizeReportEntry(t *testing.T) {
+	assert.Equal(t, "main:target=build/target/main.map",
+		sizeReportEntry("main:target", "build/target/main.map", "", ""))
+	assert.Equal(t, "main:target=build/target/main.map,build/target/main.nm,build/target/main.size",
+		sizeReportEntry("main:target", "build/target/main.map", "build/target/main.nm", "build/target/main.size"))
+}
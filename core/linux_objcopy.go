@@ -0,0 +1,95 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// The extension of the file written for each objcopy output format
+var objcopyFormats = map[string]string{
+	"binary": ".bin",
+	"ihex":   ".hex",
+	"srec":   ".srec",
+}
+
+var objcopyRule = pctx.StaticRule("objcopy",
+	blueprint.RuleParams{
+		Command:     "$objcopy -O $format $in $out",
+		Description: "$out",
+	}, "objcopy", "format")
+
+// addObjcopyOutputs converts the linked binary to each format listed in
+// objcopy_formats. The files are written next to the binary, adding the
+// format's extension to its name, and are recorded as implicit outputs.
+func (g *linuxGenerator) addObjcopyOutputs(ctx blueprint.ModuleContext, b *binary) {
+	formats := b.Properties.Objcopy_formats
+	if len(formats) == 0 {
+		return
+	}
+
+	objcopy := g.getToolchain(b.Properties.TargetType).getObjcopy()
+	if objcopy == "" {
+		ctx.PropertyErrorf("objcopy_formats", "is not supported by the %s toolchain",
+			b.Properties.TargetType)
+		return
+	}
+
+	linked := b.outputs()[0]
+	for _, format := range utils.MergeUnique(formats) {
+		ext, ok := objcopyFormats[format]
+		if !ok {
+			ctx.PropertyErrorf("objcopy_formats", "unknown format %s, expected one of %s",
+				format, strings.Join(utils.SortedKeys(objcopyFormats), ", "))
+			continue
+		}
+
+		out := linked + ext
+		ctx.Build(pctx,
+			blueprint.BuildParams{
+				Rule:    objcopyRule,
+				Inputs:  []string{linked},
+				Outputs: []string{out},
+				Args: map[string]string{
+					"objcopy": objcopy,
+					"format":  format,
+				},
+				Optional: true,
+			})
+		b.objcopyOuts = append(b.objcopyOuts, out)
+	}
+	b.implicitOuts = append(b.implicitOuts, b.objcopyOuts...)
+}
+
+// isObjcopyOutput returns whether a file is a binary converted by
+// objcopy, which can't be stripped as it is no longer an ELF file.
+func isObjcopyOutput(m interface{}, file string) bool {
+	if b, ok := m.(*binary); ok {
+		return utils.Contains(b.objcopyOuts, file)
+	}
+	return false
+}
+
+// The converted files are installed alongside the binary
+func (b *binary) filesToInstall(ctx blueprint.BaseModuleContext) []string {
+	return append(b.library.filesToInstall(ctx), b.objcopyOuts...)
+}
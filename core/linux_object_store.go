@@ -0,0 +1,45 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+
+	"github.com/google/blueprint"
+)
+
+var _ = pctx.StaticVariable("object_store_tool", "${BobScriptsDir}/object_store.py")
+
+func objectStoreEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["content_addressed_objects"]
+	return ok && props.GetBool("content_addressed_objects")
+}
+
+// objectStoreWrapper returns the command prefix which moves an object
+// file into the content-addressed store of the build directory once it
+// is compiled, leaving a hard link in its place. It is empty unless the
+// experimental CONTENT_ADDRESSED_OBJECTS option is enabled.
+func objectStoreWrapper(ctx blueprint.ModuleContext, output string) string {
+	if !objectStoreEnabled(getConfig(ctx)) {
+		return ""
+	}
+
+	return "$object_store_tool --store " + filepath.Join("${BuildDir}", ".object_store") +
+		" --output " + output + " --"
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+// Ways of arranging the per-module intermediate directories, such as
+// the object and generated source directories, in the build directory
+const (
+	// One directory per module, named after the module
+	outputLayoutByName = "by_name"
+
+	// Per-module directories nested under the path of the directory
+	// defining the module, mirroring the source tree
+	outputLayoutByPath = "by_path"
+
+	// As by_name, but with the object files of a module kept in a
+	// single directory, rather than mirroring the paths of its sources
+	outputLayoutFlat = "flat"
+)
+
+// The directory holding the stable symlinks to module outputs
+const latestSymlinksDir = "latest"
+
+// The target creating all the stable symlinks
+const latestSymlinksTarget = "latest_symlinks"
+
+// outputLayout returns the layout chosen by the OUTPUT_LAYOUT options.
+func outputLayout(config *bobConfig) string {
+	props := config.Properties
+	for _, layout := range []string{outputLayoutByPath, outputLayoutFlat} {
+		key := "output_layout_" + layout
+		if _, ok := props.properties[key]; ok && props.GetBool(key) {
+			return layout
+		}
+	}
+	return outputLayoutByName
+}
+
+// moduleOutputDir returns the directory of a module called 'name',
+// defined in 'moduleDir', below the directory 'base' shared by all
+// modules of its kind.
+func moduleOutputDir(layout, base, moduleDir, name string) string {
+	if layout == outputLayoutByPath {
+		return filepath.Join(base, moduleDir, name)
+	}
+	return filepath.Join(base, name)
+}
+
+// objectFileName returns the path of the object compiled from
+// 'source', relative to the object directory of its module.
+func objectFileName(layout, source string) string {
+	if layout == outputLayoutFlat {
+		source = strings.Replace(strings.TrimPrefix(source, "/"), "/", "_", -1)
+	}
+	return source + ".o"
+}
+
+func (g *linuxGenerator) moduleOutputDir(ctx blueprint.ModuleContext, base, name string) string {
+	return moduleOutputDir(outputLayout(getConfig(ctx)), base, ctx.ModuleDir(), name)
+}
+
+// latestSymlinkTarget returns the relative path from the symlink 'link'
+// to 'output'. Both are in the build directory, so the link remains
+// valid when the build directory is moved.
+func latestSymlinkTarget(link, output string) (string, error) {
+	return filepath.Rel(filepath.Dir(link), output)
+}
+
+type latestSymlinksSingleton struct{}
+
+// GenerateBuildActions adds a symlink at ${BuildDir}/latest/<target>/<file>
+// to the output of each library and binary. The symlinks don't depend
+// on the output layout, or on the way modules are split between
+// directories, so scripts using them don't need updating when either
+// changes.
+func (s *latestSymlinksSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	owners := map[string]string{}
+
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if e, ok := m.(enableable); !ok || !isEnabled(e) {
+			return
+		}
+		tm, ok := m.(targetableModule)
+		if !ok {
+			return
+		}
+		p, ok := m.(phonyInterface)
+		if !ok {
+			return
+		}
+
+		output := ""
+		for _, out := range p.outputs() {
+			if filepath.Base(out) == tm.outputFileName() {
+				output = out
+				break
+			}
+		}
+		if output == "" {
+			return
+		}
+
+		link := filepath.Join("${BuildDir}", latestSymlinksDir,
+			string(tm.getTarget()), tm.outputFileName())
+		if owner, ok := owners[link]; ok {
+			ctx.Errorf("%s and %s both output %s, so have the same symlink in %s",
+				owner, ctx.ModuleName(m), tm.outputFileName(), latestSymlinksDir)
+			return
+		}
+		owners[link] = ctx.ModuleName(m)
+
+		target, err := latestSymlinkTarget(link, output)
+		if err != nil {
+			panic(fmt.Sprintf("%s: %v", ctx.ModuleName(m), err))
+		}
+
+		ctx.Build(pctx,
+			blueprint.BuildParams{
+				Rule:     symlinkRule,
+				Inputs:   []string{output},
+				Outputs:  []string{link},
+				Args:     map[string]string{"target": target},
+				Optional: !isBuiltByDefault(m.(enableable)),
+			})
+	})
+
+	links := make([]string, 0, len(owners))
+	for link := range owners {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     blueprint.Phony,
+			Inputs:   links,
+			Outputs:  []string{latestSymlinksTarget},
+			Optional: true,
+		})
+}
+
+func latestSymlinksSingletonFactory() blueprint.Singleton {
+	return &latestSymlinksSingleton{}
+}
+
+// latestSymlinksEnabled returns whether OUTPUT_LATEST_SYMLINKS is set.
+func latestSymlinksEnabled(config *bobConfig) bool {
+	props := config.Properties
+	if _, ok := props.properties["output_latest_symlinks"]; !ok {
+		return false
+	}
+	return props.GetBool("output_latest_symlinks")
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_moduleOutputDir(t *testing.T) {
+	base := "${BuildDir}/target/objects"
+	assert.Equal(t, "${BuildDir}/target/objects/libfoo",
+		moduleOutputDir(outputLayoutByName, base, "libs/foo", "libfoo"))
+	assert.Equal(t, "${BuildDir}/target/objects/libfoo",
+		moduleOutputDir(outputLayoutFlat, base, "libs/foo", "libfoo"))
+	assert.Equal(t, "${BuildDir}/target/objects/libs/foo/libfoo",
+		moduleOutputDir(outputLayoutByPath, base, "libs/foo", "libfoo"))
+	// Modules in the root directory stay directly below 'base'
+	assert.Equal(t, "${BuildDir}/target/objects/libfoo",
+		moduleOutputDir(outputLayoutByPath, base, ".", "libfoo"))
+}
+
+func Test_objectFileName(t *testing.T) {
+	assert.Equal(t, "libs/foo/src/foo.c.o", objectFileName(outputLayoutByName, "libs/foo/src/foo.c"))
+	assert.Equal(t, "libs/foo/src/foo.c.o", objectFileName(outputLayoutByPath, "libs/foo/src/foo.c"))
+	assert.Equal(t, "libs_foo_src_foo.c.o", objectFileName(outputLayoutFlat, "libs/foo/src/foo.c"))
+	// Generated sources start with a separator once the build directory is removed
+	assert.Equal(t, "gen_foo_foo.c.o", objectFileName(outputLayoutFlat, "/gen/foo/foo.c"))
+}
+
+func Test_latestSymlinkTarget(t *testing.T) {
+	target, err := latestSymlinkTarget("${BuildDir}/latest/host/libfoo.so",
+		"${BuildDir}/host/shared/libfoo.so")
+	assert.NoError(t, err)
+	assert.Equal(t, "../../host/shared/libfoo.so", target)
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+var flexRule = pctx.StaticRule("flex",
+	blueprint.RuleParams{
+		Command:     "$flex $flexflags -o $out $in",
+		Description: "$out",
+	}, "flex", "flexflags")
+
+var bisonRule = pctx.StaticRule("bison",
+	blueprint.RuleParams{
+		Command:     "$bison $bisonflags --defines=$header -o $out $in",
+		Description: "$out",
+	}, "bison", "bisonflags", "header")
+
+// The C or C++ source written for each lexer and parser extension
+var parserSourceExts = map[string]string{
+	".l":  ".c",
+	".ll": ".cpp",
+	".y":  ".c",
+	".yy": ".cpp",
+}
+
+func isParserSource(src string) bool {
+	_, ok := parserSourceExts[filepath.Ext(src)]
+	return ok
+}
+
+func isGrammar(src string) bool {
+	ext := filepath.Ext(src)
+	return ext == ".y" || ext == ".yy"
+}
+
+// parserTool returns the configured binary of flex or bison
+func parserTool(config *bobConfig, tool string) string {
+	key := tool + "_binary"
+	if _, ok := config.Properties.properties[key]; ok {
+		if binary := config.Properties.GetString(key); binary != "" {
+			return binary
+		}
+	}
+	return tool
+}
+
+// parserSourcesMutator records the flex and bison sources of each
+// library, so that the headers written by bison can be exported to
+// the modules using it before the build actions are generated.
+func parserSourcesMutator(mctx blueprint.BottomUpMutatorContext) {
+	l, ok := getLibrary(mctx.Module())
+	if !ok || !isEnabled(l) {
+		return
+	}
+
+	l.Properties.ParserSrcs = utils.Filter(isParserSource, l.Properties.getSources(mctx))
+	if len(l.Properties.ParserSrcs) > 0 && !getConfig(mctx).Properties.GetBool("builder_ninja") {
+		mctx.PropertyErrorf("srcs", "flex and bison sources are only supported by the Linux backend")
+	}
+}
+
+func (l *library) parserOutputDir() string {
+	return filepath.Join("${BuildDir}", string(l.Properties.TargetType), "parsers", l.Name())
+}
+
+// parserIncludeDir holds the headers written by bison
+func (l *library) parserIncludeDir() string {
+	return filepath.Join(l.parserOutputDir(), "include")
+}
+
+// parserHeader returns the header bison writes for a grammar. Headers
+// are named after the grammar, so that users can include them without
+// knowing where the grammar is.
+func (l *library) parserHeader(src string) string {
+	base := strings.TrimSuffix(filepath.Base(src), filepath.Ext(src))
+	ext := ".tab.h"
+	if filepath.Ext(src) == ".yy" {
+		ext = ".tab.hh"
+	}
+	return filepath.Join(l.parserIncludeDir(), base+ext)
+}
+
+// parserHeaders returns the headers written by bison for the library
+func (l *library) parserHeaders() []string {
+	headers := []string{}
+	for _, src := range utils.Filter(isGrammar, l.Properties.ParserSrcs) {
+		headers = append(headers, l.parserHeader(src))
+	}
+	return headers
+}
+
+// parserExportIncludeDirs returns the include directory of the bison
+// headers, which is exported to users of the library.
+func (l *library) parserExportIncludeDirs() []string {
+	if len(utils.Filter(isGrammar, l.Properties.ParserSrcs)) == 0 {
+		return []string{}
+	}
+	return []string{l.parserIncludeDir()}
+}
+
+// addParserActions runs flex and bison on the library's lexers and
+// grammars, returning the sources to compile. Each source is numbered,
+// so that lexers or grammars with the same name in different
+// directories don't write the same file.
+func (l *library) addParserActions(ctx blueprint.ModuleContext) (srcs []string) {
+	g := getBackend(ctx)
+	config := getConfig(ctx)
+	headers := map[string]string{}
+
+	for i, src := range l.Properties.ParserSrcs {
+		ext := filepath.Ext(src)
+		base := strings.TrimSuffix(filepath.Base(src), ext)
+		out := filepath.Join(l.parserOutputDir(), fmt.Sprintf("%d_%s%s", i, base, parserSourceExts[ext]))
+		input := getBackendPathInSourceDir(g, src)
+
+		if isGrammar(src) {
+			header := l.parserHeader(src)
+			if prev, ok := headers[header]; ok {
+				ctx.PropertyErrorf("srcs", "%s and %s both write %s",
+					prev, src, filepath.Base(header))
+				continue
+			}
+			headers[header] = src
+
+			ctx.Build(pctx,
+				blueprint.BuildParams{
+					Rule:            bisonRule,
+					Inputs:          []string{input},
+					Outputs:         []string{out},
+					ImplicitOutputs: []string{header},
+					Args: map[string]string{
+						"bison":      parserTool(config, "bison"),
+						"bisonflags": utils.Join(l.Properties.Bison_flags),
+						"header":     header,
+					},
+					Optional: true,
+				})
+		} else {
+			ctx.Build(pctx,
+				blueprint.BuildParams{
+					Rule:    flexRule,
+					Inputs:  []string{input},
+					Outputs: []string{out},
+					Args: map[string]string{
+						"flex":      parserTool(config, "flex"),
+						"flexflags": utils.Join(l.Properties.Flex_flags),
+					},
+					Optional: true,
+				})
+		}
+		srcs = append(srcs, out)
+	}
+	return
+}
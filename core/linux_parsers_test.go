@@ -0,0 +1,47 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isParserSource(t *testing.T) {
+	assert.True(t, isParserSource("calc/lexer.l"))
+	assert.True(t, isParserSource("calc/lexer.ll"))
+	assert.True(t, isParserSource("calc/parse.y"))
+	assert.True(t, isParserSource("calc/parse.yy"))
+	assert.False(t, isParserSource("calc/main.c"))
+	assert.False(t, isParserSource("calc/parse.h"))
+}
+
+func Test_parserHeaders(t *testing.T) {
+	l := &library{}
+	l.Properties.TargetType = tgtTypeHost
+	l.Properties.ParserSrcs = []string{"calc/lexer.l", "calc/parse.y", "expr/expr.yy"}
+
+	dir := l.parserIncludeDir()
+	assert.Equal(t, []string{dir + "/parse.tab.h", dir + "/expr.tab.hh"}, l.parserHeaders())
+	assert.Equal(t, []string{dir}, l.parserExportIncludeDirs())
+
+	l.Properties.ParserSrcs = []string{"calc/lexer.l"}
+	assert.Equal(t, []string{}, l.parserHeaders())
+	assert.Equal(t, []string{}, l.parserExportIncludeDirs())
+}
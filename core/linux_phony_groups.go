@@ -0,0 +1,117 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+
+	"github.com/google/blueprint"
+)
+
+// The last element of the name of directory group targets
+const phonyGroupName = "all"
+
+// phonyGroupTarget returns the target which builds all the modules
+// defined in 'dir' and its subdirectories.
+func phonyGroupTarget(dir string) string {
+	return filepath.Join(dir, phonyGroupName)
+}
+
+type phonyGroupSingleton struct{}
+
+// GenerateBuildActions adds targets grouping the modules of each
+// directory, and targets naming each alias after the directory it is
+// defined in. For example, an alias `tests` defined in `src/foo/build.bp`
+// can also be built as `src/foo/tests`, and `src/all` builds all the
+// modules defined under `src`.
+func (s *phonyGroupSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	// Map of directory to the targets defined in it
+	targets := map[string][]string{}
+	// Map of directory to the directories immediately below it which
+	// contain modules
+	children := map[string]map[string]bool{}
+	dirs := map[string]bool{}
+	aliases := map[string]string{}
+
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if e, ok := m.(enableable); ok && !isEnabled(e) {
+			return
+		}
+
+		dir := ctx.ModuleDir(m)
+		name := ""
+		switch p := m.(type) {
+		case *alias:
+			name = p.Name()
+			aliases[filepath.Join(dir, name)] = name
+		case *externalLib:
+			// No build rules are created for external libraries
+			return
+		case phonyInterface:
+			name = p.shortName()
+		default:
+			return
+		}
+
+		targets[dir] = utils.AppendIfUnique(targets[dir], name)
+		for ; dir != "." && dir != "/" && !dirs[dir]; dir = filepath.Dir(dir) {
+			dirs[dir] = true
+			parent := filepath.Dir(dir)
+			if children[parent] == nil {
+				children[parent] = map[string]bool{}
+			}
+			children[parent][dir] = true
+		}
+	})
+
+	for _, dir := range utils.SortedKeysBoolMap(dirs) {
+		inputs := append([]string{}, targets[dir]...)
+		sort.Strings(inputs)
+		for _, subdir := range utils.SortedKeysBoolMap(children[dir]) {
+			inputs = append(inputs, phonyGroupTarget(subdir))
+		}
+		s.addPhony(ctx, phonyGroupTarget(dir), inputs)
+	}
+
+	for _, target := range utils.SortedKeys(aliases) {
+		// Aliases in the top level directory already have this name,
+		// and an alias named after the group target is built by the
+		// group.
+		if filepath.Dir(target) == "." || filepath.Base(target) == phonyGroupName {
+			continue
+		}
+		s.addPhony(ctx, target, []string{aliases[target]})
+	}
+}
+
+func (s *phonyGroupSingleton) addPhony(ctx blueprint.SingletonContext, name string, inputs []string) {
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     blueprint.Phony,
+			Inputs:   inputs,
+			Outputs:  []string{name},
+			Optional: true,
+		})
+}
+
+func phonyGroupSingletonFactory() blueprint.Singleton {
+	return &phonyGroupSingleton{}
+}
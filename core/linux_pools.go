@@ -0,0 +1,132 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// Pools defined in GENERATOR_POOLS and NINJA_POOLS, keyed by name.
+// Generator modules select them with the `pool` property.
+var ninjaPools = map[string]blueprint.Pool{}
+
+// Copies of the link rules running in each of the ninjaPools, keyed
+// by pool name. Libraries and binaries select them with the
+// `link_pool` property.
+var (
+	sharedLibraryPoolRules = map[string]blueprint.Rule{}
+	executablePoolRules    = map[string]blueprint.Rule{}
+)
+
+// Parse a space separated list of name:depth pool definitions.
+func parsePoolDefinitions(defs string) (map[string]int, error) {
+	pools := map[string]int{}
+	for _, def := range strings.Fields(defs) {
+		elems := strings.Split(def, ":")
+		if len(elems) != 2 || elems[0] == "" {
+			return nil, fmt.Errorf("Invalid pool definition '%s', expected name:depth", def)
+		}
+		depth, err := strconv.Atoi(elems[1])
+		if err != nil || depth < 1 {
+			return nil, fmt.Errorf("Invalid depth for pool '%s': %s", elems[0], elems[1])
+		}
+		pools[elems[0]] = depth
+	}
+	return pools, nil
+}
+
+// configPoolDefinitions returns the pools defined in GENERATOR_POOLS and
+// NINJA_POOLS. A pool can only be defined once.
+func configPoolDefinitions(config *configProperties) (map[string]int, error) {
+	pools := map[string]int{}
+	for _, key := range []string{"generator_pools", "ninja_pools"} {
+		if _, ok := config.properties[key]; !ok {
+			continue
+		}
+		defs, err := parsePoolDefinitions(config.GetString(key))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", strings.ToUpper(key), err)
+		}
+		for name, depth := range defs {
+			if _, ok := pools[name]; ok {
+				return nil, fmt.Errorf("%s: pool '%s' is already defined", strings.ToUpper(key), name)
+			}
+			pools[name] = depth
+		}
+	}
+	return pools, nil
+}
+
+func withPool(params blueprint.RuleParams, pool blueprint.Pool) blueprint.RuleParams {
+	params.Pool = pool
+	return params
+}
+
+func init() {
+	// Blueprint only allows pools and rules to be declared during
+	// initialisation, which is before Main() has loaded the config.
+	// Read the pool definitions early so that they can be declared
+	// here. Errors loading the config are reported by Main().
+	config := configProperties{}
+	if config.LoadConfig(configJSONFile, configOverridesFile) != nil {
+		return
+	}
+
+	pools, err := configPoolDefinitions(&config)
+	if err != nil {
+		utils.Die("%v", err)
+	}
+	for name, depth := range pools {
+		pool := pctx.StaticPool("pool_"+name,
+			blueprint.PoolParams{
+				Comment: "Pool defined in GENERATOR_POOLS or NINJA_POOLS",
+				Depth:   depth,
+			})
+		ninjaPools[name] = pool
+		sharedLibraryPoolRules[name] = pctx.StaticRule("shared_library_pool_"+name,
+			withPool(sharedLibraryRuleParams, pool), linkRuleArgs...)
+		executablePoolRules[name] = pctx.StaticRule("executable_pool_"+name,
+			withPool(executableRuleParams, pool), linkRuleArgs...)
+	}
+}
+
+// linkRule returns the rule to link a library or binary with. This is
+// 'rule' unless the module selects a pool with `link_pool`, in which case
+// it is the copy of 'rule' in 'poolRules' running in that pool.
+func linkRule(ctx blueprint.ModuleContext, l *library, rule blueprint.Rule,
+	poolRules map[string]blueprint.Rule) blueprint.Rule {
+
+	if l.Properties.Link_pool == nil {
+		return rule
+	}
+
+	name := proptools.String(l.Properties.Link_pool)
+	pooled, ok := poolRules[name]
+	if !ok {
+		ctx.PropertyErrorf("link_pool", "pool '%s' is not defined in GENERATOR_POOLS or NINJA_POOLS", name)
+		return rule
+	}
+	return pooled
+}
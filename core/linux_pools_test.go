@@ -0,0 +1,57 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parsePoolDefinitions(t *testing.T) {
+	pools, err := parsePoolDefinitions("network:1  link_heavy:2")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"network": 1, "link_heavy": 2}, pools)
+
+	_, err = parsePoolDefinitions("network")
+	assert.NotNil(t, err)
+	_, err = parsePoolDefinitions("network:0")
+	assert.NotNil(t, err)
+}
+
+func Test_configPoolDefinitions(t *testing.T) {
+	config := &configProperties{
+		properties: map[string]interface{}{
+			"generator_pools": "network:1",
+			"ninja_pools":     "link_heavy:2",
+		},
+	}
+	pools, err := configPoolDefinitions(config)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"network": 1, "link_heavy": 2}, pools)
+
+	// Both options share the pool names
+	config.properties["ninja_pools"] = "network:4"
+	_, err = configPoolDefinitions(config)
+	assert.EqualError(t, err, "NINJA_POOLS: pool 'network' is already defined")
+
+	// Neither option has to be set
+	pools, err = configPoolDefinitions(&configProperties{properties: map[string]interface{}{}})
+	assert.Nil(t, err)
+	assert.Empty(t, pools)
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+func (l *library) postBuildOutputDir() string {
+	return filepath.Join("${BuildDir}", string(l.Properties.TargetType), "post_build", l.Name())
+}
+
+// addPostBuild runs post_build_cmd on the linked output, so that the
+// file it writes is installed in place of the linked output. This must
+// be called once the outputs are known, and before installing. The
+// file is built with the module, whether or not it is installed.
+func (g *linuxGenerator) addPostBuild(ctx blueprint.ModuleContext, l *library) {
+	props := &l.Properties.Build.BuildProps
+	if props.Post_build_cmd == nil {
+		if props.Post_build_tool != nil || props.Post_build_args != nil ||
+			props.Post_build_out != nil {
+			ctx.PropertyErrorf("post_build_cmd", "is required by the other post_build properties")
+		}
+		return
+	}
+
+	linked := l.outputs()[0]
+	out := filepath.Join(l.postBuildOutputDir(),
+		proptools.StringDefault(props.Post_build_out, filepath.Base(linked)))
+
+	// Expand args immediately, as with post_install_cmd
+	cmd := strings.Replace(*props.Post_build_cmd, "${args}",
+		strings.Join(props.Post_build_args, " "), -1)
+
+	args := map[string]string{}
+	implicits := []string{}
+	if props.Post_build_tool != nil {
+		args["tool"] = *props.Post_build_tool
+		implicits = append(implicits, *props.Post_build_tool)
+	}
+	utils.StripUnusedArgs(args, cmd)
+
+	rule := ctx.Rule(pctx,
+		"post_build",
+		blueprint.RuleParams{
+			Command:     cmd,
+			Description: "$out",
+		},
+		utils.SortedKeys(args)...)
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:      rule,
+			Inputs:    []string{linked},
+			Outputs:   []string{out},
+			Implicits: implicits,
+			Args:      args,
+			Optional:  true,
+		})
+
+	l.postBuildOuts = []string{out}
+}
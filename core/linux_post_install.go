@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// postInstallCommand expands post_install_args into post_install_cmd,
+// returning the command along with the arguments and dependencies
+// needed to run post_install_tool.
+func postInstallCommand(props *InstallableProps) (string, map[string]string, []string) {
+	cmd := strings.Replace(*props.Post_install_cmd, "${args}",
+		strings.Join(props.Post_install_args, " "), -1)
+
+	args := map[string]string{}
+	deps := []string{}
+	if props.Post_install_tool != nil {
+		args["tool"] = *props.Post_install_tool
+		deps = append(deps, *props.Post_install_tool)
+	}
+	utils.StripUnusedArgs(args, cmd)
+
+	return cmd, args, deps
+}
+
+// postInstallFileRule returns a rule which installs a file and then
+// runs post_install_cmd on it. This is used when the command only
+// modifies the installed file, so the installed file is its only output.
+func postInstallFileRule(ctx blueprint.ModuleContext, props *InstallableProps) (blueprint.Rule, map[string]string, []string) {
+	cmd, args, deps := postInstallCommand(props)
+
+	rule := ctx.Rule(pctx,
+		"install",
+		blueprint.RuleParams{
+			Command:     "rm -f $out; cp $in $out$perms ; " + cmd,
+			Description: "$out",
+		},
+		append(utils.SortedKeys(args), "perms")...)
+
+	return rule, args, deps
+}
+
+// addPostInstall runs post_install_cmd once all of a module's files
+// have been installed, when the command writes the files listed in
+// post_install_outs. The outputs depend on every installed file, so
+// caches built from the installed files are regenerated whenever one
+// of them changes. Returns the installed files, including the outputs.
+func (g *linuxGenerator) addPostInstall(ctx blueprint.ModuleContext, props *InstallableProps,
+	installPath, distPath string, installedFiles []string) []string {
+
+	if len(props.Post_install_outs) == 0 {
+		return installedFiles
+	}
+	if props.Post_install_cmd == nil {
+		ctx.PropertyErrorf("post_install_outs", "requires post_install_cmd")
+		return installedFiles
+	}
+
+	outs := []string{}
+	for _, rel := range props.Post_install_outs {
+		if filepath.IsAbs(rel) || strings.HasPrefix(filepath.Clean(rel), "..") {
+			ctx.PropertyErrorf("post_install_outs",
+				"'%s' must be relative to the install path", rel)
+			return installedFiles
+		}
+		out := filepath.Join(installPath, rel)
+		outs = append(outs, out)
+		props.addInstalledEntry(filepath.Join(distPath, rel), out)
+		props.addDistEntry(filepath.Join(distPath, rel), out)
+	}
+
+	cmd, args, deps := postInstallCommand(props)
+
+	rule := ctx.Rule(pctx,
+		"post_install",
+		blueprint.RuleParams{
+			Command:     cmd,
+			Description: "post install " + ctx.ModuleName(),
+		},
+		utils.SortedKeys(args)...)
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:      rule,
+			Inputs:    installedFiles,
+			Outputs:   outs,
+			Implicits: deps,
+			Args:      args,
+			Optional:  true,
+		})
+
+	return append(installedFiles, outs...)
+}
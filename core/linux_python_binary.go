@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+var _ = pctx.StaticVariable("python_binary_tool", "${BobScriptsDir}/python_binary.py")
+
+var pythonBinaryRule = pctx.StaticRule("python_binary",
+	blueprint.RuleParams{
+		Command: "python ${python_binary_tool} --packaging $packaging --python $python " +
+			"--main $main --root $root $requirements_arg --output $out $in",
+		CommandDeps: []string{"${python_binary_tool}"},
+		Description: "$out",
+	}, "packaging", "python", "main", "root", "requirements_arg")
+
+func (g *linuxGenerator) pythonBinaryOutputDir(m *pythonBinary) string {
+	return filepath.Join("${BuildDir}", string(tgtTypeHost), "python", m.Name())
+}
+
+// pythonBinaryActions packages the sources and requirements of the tool.
+// A zipapp is a single file, while a venv is a directory, which is run
+// through a launcher script so that the module still has one output.
+func (g *linuxGenerator) pythonBinaryActions(m *pythonBinary, ctx blueprint.ModuleContext) {
+	packaging := m.packaging(ctx)
+
+	m.outputdir = g.pythonBinaryOutputDir(m)
+	out := filepath.Join(m.outputDir(), m.Name())
+	if packaging == pythonPackagingZipapp {
+		out += ".pyz"
+	}
+	m.outs = []string{out}
+
+	args := map[string]string{
+		"packaging":        packaging,
+		"python":           pythonInterpreter(getConfig(ctx)),
+		"main":             proptools.NinjaAndShellEscape(*m.Properties.Main),
+		"root":             filepath.Join(g.sourceDir(), projectModuleDir(ctx)),
+		"requirements_arg": "",
+	}
+	implicits := []string{}
+	if m.Properties.Requirements != nil {
+		requirements := getBackendPathInSourceDir(g, *m.Properties.Requirements)
+		args["requirements_arg"] = "--requirements " + requirements
+		implicits = append(implicits, requirements)
+	}
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:      pythonBinaryRule,
+			Inputs:    getBackendPathsInSourceDir(g, m.Properties.getSources(ctx)),
+			Outputs:   m.outputs(),
+			Implicits: implicits,
+			Args:      args,
+			Optional:  true,
+		})
+
+	addPhony(m, ctx, []string{}, !isBuiltByDefault(m))
+}
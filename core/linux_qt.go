@@ -0,0 +1,190 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+var qtMocRule = pctx.StaticRule("qt_moc",
+	blueprint.RuleParams{
+		Command:     "$moc $mocflags $in -o $out",
+		Description: "$out",
+	}, "moc", "mocflags")
+
+var qtUicRule = pctx.StaticRule("qt_uic",
+	blueprint.RuleParams{
+		Command:     "$uic $in -o $out",
+		Description: "$out",
+	}, "uic")
+
+var qtRccRule = pctx.StaticRule("qt_rcc",
+	blueprint.RuleParams{
+		Command:     "$rcc --name $name $in -o $out",
+		Description: "$out",
+	}, "rcc", "name")
+
+var (
+	qObjectRegexp = regexp.MustCompile(`\bQ_(OBJECT|GADGET)\b`)
+	qrcFileRegexp = regexp.MustCompile(`<file(\s[^>]*)?>([^<]+)</file>`)
+)
+
+func (l *library) qtEnabled() bool {
+	return proptools.Bool(l.Properties.Qt)
+}
+
+// isQtInput returns whether a source is processed by one of the Qt
+// tools, rather than compiled or used by match_srcs.
+func isQtInput(src string) bool {
+	ext := filepath.Ext(src)
+	return ext == ".ui" || ext == ".qrc" || utils.IsHeader(src)
+}
+
+// qtTool returns the configured binary of a Qt tool
+func qtTool(config *bobConfig, tool string) string {
+	key := "qt_" + tool + "_binary"
+	if _, ok := config.Properties.properties[key]; ok {
+		if binary := config.Properties.GetString(key); binary != "" {
+			return binary
+		}
+	}
+	return tool
+}
+
+// declaresQObject returns whether a header needs to be run through moc
+func declaresQObject(content []byte) bool {
+	return qObjectRegexp.Match(content)
+}
+
+// qrcFiles returns the files listed in a Qt resource collection,
+// relative to the directory of the collection.
+func qrcFiles(content []byte) []string {
+	files := []string{}
+	for _, match := range qrcFileRegexp.FindAllSubmatch(content, -1) {
+		files = append(files, strings.TrimSpace(string(match[2])))
+	}
+	return files
+}
+
+func (l *library) qtOutputDir() string {
+	return filepath.Join("${BuildDir}", string(l.Properties.TargetType), "qt", l.Name())
+}
+
+// qtIncludeDir holds the headers written by uic
+func (l *library) qtIncludeDir() string {
+	return filepath.Join(l.qtOutputDir(), "include")
+}
+
+// addQtActions runs the Qt tools on the module's sources. It returns
+// the sources to compile with the module, and the headers written by
+// uic, which must exist before anything is compiled.
+//
+// The sources are read to find which headers use Q_OBJECT and which
+// files each resource collection embeds, so the build files are
+// regenerated when they change.
+func (l *library) addQtActions(ctx blueprint.ModuleContext) (srcs []string, headers []string) {
+	if !l.qtEnabled() {
+		return
+	}
+
+	g := getBackend(ctx)
+	config := getConfig(ctx)
+	dir := l.qtOutputDir()
+	readFiles := []string{}
+
+	read := func(src string) ([]byte, bool) {
+		file := filepath.Join(getSourceDir(), src)
+		readFiles = append(readFiles, file)
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			ctx.PropertyErrorf("srcs", "%v", err)
+			return nil, false
+		}
+		return content, true
+	}
+
+	for _, src := range l.Properties.getSources(ctx) {
+		base := strings.TrimSuffix(filepath.Base(src), filepath.Ext(src))
+		input := getBackendPathInSourceDir(g, src)
+
+		switch {
+		case filepath.Ext(src) == ".ui":
+			out := filepath.Join(l.qtIncludeDir(), "ui_"+base+".h")
+			ctx.Build(pctx,
+				blueprint.BuildParams{
+					Rule:     qtUicRule,
+					Inputs:   []string{input},
+					Outputs:  []string{out},
+					Args:     map[string]string{"uic": qtTool(config, "uic")},
+					Optional: true,
+				})
+			headers = append(headers, out)
+
+		case filepath.Ext(src) == ".qrc":
+			content, ok := read(src)
+			if !ok {
+				continue
+			}
+			resources := utils.PrefixDirs(qrcFiles(content), filepath.Dir(input))
+			out := filepath.Join(dir, filepath.Dir(src), "qrc_"+base+".cpp")
+			ctx.Build(pctx,
+				blueprint.BuildParams{
+					Rule:      qtRccRule,
+					Inputs:    []string{input},
+					Outputs:   []string{out},
+					Implicits: resources,
+					Args: map[string]string{
+						"rcc":  qtTool(config, "rcc"),
+						"name": base,
+					},
+					Optional: true,
+				})
+			srcs = append(srcs, out)
+
+		case utils.IsHeader(src):
+			content, ok := read(src)
+			if !ok || !declaresQObject(content) {
+				continue
+			}
+			out := filepath.Join(dir, filepath.Dir(src), "moc_"+base+".cpp")
+			ctx.Build(pctx,
+				blueprint.BuildParams{
+					Rule:    qtMocRule,
+					Inputs:  []string{input},
+					Outputs: []string{out},
+					Args: map[string]string{
+						"moc":      qtTool(config, "moc"),
+						"mocflags": utils.Join(l.Properties.Qt_moc_flags),
+					},
+					Optional: true,
+				})
+			srcs = append(srcs, out)
+		}
+	}
+
+	ctx.AddNinjaFileDeps(readFiles...)
+	return
+}
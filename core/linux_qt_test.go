@@ -0,0 +1,50 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_declaresQObject(t *testing.T) {
+	assert.True(t, declaresQObject([]byte("class Window : public QWidget {\n    Q_OBJECT\n};")))
+	assert.True(t, declaresQObject([]byte("struct Point {\n    Q_GADGET\n};")))
+	assert.False(t, declaresQObject([]byte("class Plain {};")))
+	assert.False(t, declaresQObject([]byte("#define MY_Q_OBJECTS 1")))
+}
+
+func Test_qrcFiles(t *testing.T) {
+	qrc := `<!DOCTYPE RCC><RCC version="1.0">
+<qresource prefix="/icons">
+    <file>open.png</file>
+    <file alias="save.png">images/save-24.png</file>
+</qresource>
+</RCC>`
+
+	assert.Equal(t, []string{"open.png", "images/save-24.png"}, qrcFiles([]byte(qrc)))
+}
+
+func Test_isQtInput(t *testing.T) {
+	assert.True(t, isQtInput("src/window.h"))
+	assert.True(t, isQtInput("src/window.ui"))
+	assert.True(t, isQtInput("res/icons.qrc"))
+	assert.False(t, isQtInput("src/window.cpp"))
+	assert.False(t, isQtInput("src/exports.txt"))
+}
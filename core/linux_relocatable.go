@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// The rule for linking objects into a single relocatable object. The
+// C runtime and libraries are left out, as they are added when the
+// object is linked into the final image.
+var relocatableObjectRule = pctx.StaticRule("relocatable_object",
+	blueprint.RuleParams{
+		Command: "$build_wrapper $linker -r -nostdlib $in -o $out $ldflags $whole_static_libs " +
+			"$localize_symbols",
+		Description: "$out",
+	}, "build_wrapper", "ldflags", "linker", "localize_symbols", "whole_static_libs")
+
+// localizeSymbolsCommand returns the command appended to the link to
+// make every symbol in an object local, other than those listed.
+func localizeSymbolsCommand(objcopy, object string, globalSymbols []string) string {
+	if len(globalSymbols) == 0 {
+		return ""
+	}
+	cmd := []string{"&&", objcopy}
+	for _, symbol := range utils.MergeUnique(globalSymbols) {
+		cmd = append(cmd, "--keep-global-symbol="+symbol)
+	}
+	return strings.Join(append(cmd, object), " ")
+}
+
+// relocatableObjectActions links a bob_relocatable_object. Like a
+// static library, its static_libs and shared_libs are linked by the
+// modules using it, while its whole_static_libs are included.
+func (g *linuxGenerator) relocatableObjectActions(m *staticLibrary, ctx blueprint.ModuleContext) {
+	tc := g.getToolchain(m.Properties.TargetType)
+	buildWrapper, buildWrapperDeps := m.Properties.Build.getCommandWrapperAndDeps(ctx)
+
+	localize := ""
+	if len(m.Properties.Global_symbols) > 0 {
+		objcopy := tc.getObjcopy()
+		if objcopy == "" {
+			ctx.PropertyErrorf("global_symbols", "is not supported by the %s toolchain",
+				m.Properties.TargetType)
+			return
+		}
+		localize = localizeSymbolsCommand(objcopy, m.outputs()[0], m.Properties.Global_symbols)
+	}
+
+	wholeStaticLibs := m.GetWholeStaticLibs(ctx)
+	wholeStaticFlags := ""
+	if len(wholeStaticLibs) > 0 {
+		wholeStaticFlags = tc.getLinker().linkWholeArchives(wholeStaticLibs)
+	}
+
+	objectFiles, nonCompiledDeps := m.CompileObjs(ctx)
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:      relocatableObjectRule,
+			Outputs:   m.outputs(),
+			Inputs:    objectFiles,
+			Implicits: append(wholeStaticLibs, nonCompiledDeps...),
+			OrderOnly: buildWrapperDeps,
+			Optional:  true,
+			Args: map[string]string{
+				"build_wrapper":     buildWrapper,
+				"ldflags":           utils.Join(tc.getLinker().getFlags(), m.Properties.Ldflags),
+				"linker":            tc.getLinker().getTool(),
+				"localize_symbols":  localize,
+				"whole_static_libs": wholeStaticFlags,
+			},
+		})
+
+	g.addPostBuild(ctx, &m.library)
+	installDeps := append(g.install(m, ctx), m.postBuildOuts...)
+	addPhony(m, ctx, installDeps, !isBuiltByDefault(m))
+}
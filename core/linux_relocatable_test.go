@@ -0,0 +1,40 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_localizeSymbolsCommand(t *testing.T) {
+	assert.Equal(t, "", localizeSymbolsCommand("objcopy", "out/foo.o", []string{}))
+	assert.Equal(t,
+		"&& objcopy --keep-global-symbol=foo_init --keep-global-symbol=foo_run out/foo.o",
+		localizeSymbolsCommand("objcopy", "out/foo.o", []string{"foo_init", "foo_run", "foo_init"}))
+}
+
+func Test_staticLibrary_outputFileName_relocatable(t *testing.T) {
+	m := &staticLibrary{relocatable: true}
+	m.SimpleName.Properties.Name = "foo_component"
+	assert.Equal(t, "foo_component.o", m.outputFileName())
+
+	m.relocatable = false
+	assert.Equal(t, "foo_component.a", m.outputFileName())
+}
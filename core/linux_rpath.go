@@ -0,0 +1,95 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// originRpath returns the run-time search path of a directory relative
+// to the directory the module is installed in
+func originRpath(rel string) string {
+	if rel == "." {
+		return "$ORIGIN"
+	}
+	return "$ORIGIN/" + rel
+}
+
+// propertyErrorReporter is the part of blueprint.ModuleContext used by
+// getRpaths, so that its checks can be tested without a module context.
+type propertyErrorReporter interface {
+	PropertyErrorf(property, format string, args ...interface{})
+}
+
+// getRpaths returns the run-time search paths of a binary or shared
+// library, quoted for the shell and escaped for Ninja. These are the
+// paths from rpath, and, with use_origin_rpath or add_lib_dirs_to_rpath,
+// the install directories of the shared libraries it links with,
+// relative to its own. 'libPaths' are the install directories of the
+// shared libraries, and 'uninstalled' the shared libraries which are
+// not installed.
+func (l *library) getRpaths(ctx propertyErrorReporter, libPaths, uninstalled []string) []string {
+	props := &l.Properties.Build
+	useOrigin := proptools.Bool(props.Use_origin_rpath)
+	installPath, installed := l.Properties.InstallableProps.getInstallPath()
+
+	rpaths := []string{}
+	for _, rpath := range props.Rpath {
+		if strings.HasPrefix(rpath, "$ORIGIN") {
+			ctx.PropertyErrorf("rpath", "%s: use a path relative to the install directory, "+
+				"which is made relative to $ORIGIN", rpath)
+		} else if filepath.IsAbs(rpath) {
+			rpaths = utils.AppendIfUnique(rpaths, rpath)
+		} else if !installed {
+			ctx.PropertyErrorf("rpath", "%s is relative to the install directory, "+
+				"but the module is not installed", rpath)
+		} else {
+			rpaths = utils.AppendIfUnique(rpaths, originRpath(filepath.Clean(rpath)))
+		}
+	}
+
+	if useOrigin && !installed {
+		ctx.PropertyErrorf("use_origin_rpath", "requires the module to be installed")
+	} else if installed && (useOrigin || props.isRpathWanted()) {
+		// add_lib_dirs_to_rpath skips the libraries which are not
+		// installed, but with use_origin_rpath they couldn't be found
+		if useOrigin {
+			for _, lib := range uninstalled {
+				ctx.PropertyErrorf("use_origin_rpath", "%s is not installed, "+
+					"so can't be found relative to the module", lib)
+			}
+		}
+		for _, path := range libPaths {
+			rel, err := filepath.Rel(installPath, path)
+			if err != nil {
+				utils.Die("Could not find relative path for: %s due to: %s", path, err)
+			}
+			rpaths = utils.AppendIfUnique(rpaths, originRpath(rel))
+		}
+	}
+
+	for i := range rpaths {
+		rpaths[i] = "'" + strings.Replace(rpaths[i], "$", "$$", -1) + "'"
+	}
+	return rpaths
+}
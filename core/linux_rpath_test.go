@@ -0,0 +1,104 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_originRpath(t *testing.T) {
+	assert.Equal(t, "$ORIGIN", originRpath("."))
+	assert.Equal(t, "$ORIGIN/../lib", originRpath("../lib"))
+}
+
+type propertyErrorRecorder struct {
+	errors map[string][]string
+}
+
+func (r *propertyErrorRecorder) PropertyErrorf(property, format string, args ...interface{}) {
+	if r.errors == nil {
+		r.errors = map[string][]string{}
+	}
+	r.errors[property] = append(r.errors[property], fmt.Sprintf(format, args...))
+}
+
+func Test_getRpaths(t *testing.T) {
+	l := &library{}
+	l.Properties.InstallableProps.InstallGroupPath = proptools.StringPtr("install/bin")
+	l.Properties.Build.Rpath = []string{"/opt/lib", "../lib", "../lib/"}
+
+	ctx := &propertyErrorRecorder{}
+	assert.Equal(t, []string{"'/opt/lib'", "'$$ORIGIN/../lib'"},
+		l.getRpaths(ctx, []string{"install/lib"}, []string{}))
+	assert.Empty(t, ctx.errors)
+
+	l.Properties.Build.Use_origin_rpath = proptools.BoolPtr(true)
+	l.Properties.Build.Rpath = []string{}
+	ctx = &propertyErrorRecorder{}
+	assert.Equal(t, []string{"'$$ORIGIN'", "'$$ORIGIN/../lib'"},
+		l.getRpaths(ctx, []string{"install/bin", "install/lib"}, []string{}))
+	assert.Empty(t, ctx.errors)
+}
+
+func Test_getRpathsAddLibDirs(t *testing.T) {
+	l := &library{}
+	l.Properties.Build.Add_lib_dirs_to_rpath = proptools.BoolPtr(true)
+
+	// Not installed, so there is nothing to be relative to
+	ctx := &propertyErrorRecorder{}
+	assert.Equal(t, []string{}, l.getRpaths(ctx, []string{"install/lib"}, []string{}))
+	assert.Empty(t, ctx.errors)
+
+	// Uninstalled libraries are skipped without use_origin_rpath
+	l.Properties.InstallableProps.InstallGroupPath = proptools.StringPtr("install/bin")
+	ctx = &propertyErrorRecorder{}
+	assert.Equal(t, []string{"'$$ORIGIN/../lib'"},
+		l.getRpaths(ctx, []string{"install/lib"}, []string{"libfoo"}))
+	assert.Empty(t, ctx.errors)
+}
+
+func Test_getRpathsErrors(t *testing.T) {
+	l := &library{}
+	l.Properties.Build.Rpath = []string{"$ORIGIN/../lib", "../lib", "/opt/lib"}
+
+	// $ORIGIN is rejected whether or not the module is installed, and
+	// relative paths need an install directory
+	ctx := &propertyErrorRecorder{}
+	assert.Equal(t, []string{"'/opt/lib'"}, l.getRpaths(ctx, []string{}, []string{}))
+	assert.Len(t, ctx.errors["rpath"], 2)
+	assert.Contains(t, ctx.errors["rpath"][0], "$ORIGIN/../lib")
+	assert.Contains(t, ctx.errors["rpath"][1], "not installed")
+
+	l.Properties.Build.Rpath = []string{}
+	l.Properties.Build.Use_origin_rpath = proptools.BoolPtr(true)
+	ctx = &propertyErrorRecorder{}
+	l.getRpaths(ctx, []string{}, []string{})
+	assert.Equal(t, []string{"requires the module to be installed"}, ctx.errors["use_origin_rpath"])
+
+	// Each uninstalled shared library is reported
+	l.Properties.InstallableProps.InstallGroupPath = proptools.StringPtr("install/bin")
+	ctx = &propertyErrorRecorder{}
+	l.getRpaths(ctx, []string{"install/lib"}, []string{"libfoo", "libbar"})
+	assert.Len(t, ctx.errors["use_origin_rpath"], 2)
+	assert.Contains(t, ctx.errors["use_origin_rpath"][0], "libfoo")
+	assert.Contains(t, ctx.errors["use_origin_rpath"][1], "libbar")
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+var _ = pctx.StaticVariable("shared_lib_stubs_tool", "${BobScriptsDir}/shared_lib_stubs.py")
+
+var stubSourceRule = pctx.StaticRule("shared_lib_stub_source",
+	blueprint.RuleParams{
+		Command:     "$shared_lib_stubs_tool $in -o $out --versions=$versions --version=$version",
+		CommandDeps: []string{"$shared_lib_stubs_tool"},
+		Description: "$out",
+	}, "version", "versions")
+
+var stubLibraryRule = pctx.StaticRule("shared_lib_stub",
+	blueprint.RuleParams{
+		Command: "$ccompiler $cflags -fPIC -shared -nostdlib -Wl,-soname,$soname " +
+			"$ldflags $in -o $out",
+		Description: "$out",
+	}, "ccompiler", "cflags", "ldflags", "soname")
+
+// stubVersions returns the API versions stubs are built for, oldest
+// first. Without versions, a single stub holds the whole symbol file.
+func (m *sharedLibrary) stubVersions() []string {
+	if len(m.Properties.Stubs.Versions) > 0 {
+		return m.Properties.Stubs.Versions
+	}
+	return []string{"current"}
+}
+
+func (m *sharedLibrary) hasStubs() bool {
+	return m.Properties.Stubs.Symbol_file != nil
+}
+
+func sharedLibStubsDir(m *sharedLibrary, version string) string {
+	return filepath.Join("${BuildDir}", string(m.getTarget()), "stubs", m.Name(), version)
+}
+
+// Path of the stub users of a library link against, which is the one
+// of its latest API version.
+func sharedLibStubPath(m *sharedLibrary) string {
+	versions := m.stubVersions()
+	return filepath.Join(sharedLibStubsDir(m, versions[len(versions)-1]), m.getLinkName())
+}
+
+// addSharedLibStubs builds a stub of the library for each API version.
+// The stubs have the SONAME of the library, so modules linked against
+// them load the real library at runtime.
+func (g *linuxGenerator) addSharedLibStubs(ctx blueprint.ModuleContext, m *sharedLibrary) []string {
+	if !m.hasStubs() {
+		return []string{}
+	}
+
+	tc := g.getToolchain(m.Properties.TargetType)
+	ccompiler, cflags := tc.getCCompiler()
+	symbolFile := getBackendPathInSourceDir(g, *m.Properties.Stubs.Symbol_file)
+
+	stubs := []string{}
+	for _, version := range m.stubVersions() {
+		dir := sharedLibStubsDir(m, version)
+		source := filepath.Join(dir, m.outputName()+"_stub.c")
+		stub := filepath.Join(dir, m.getLinkName())
+
+		ctx.Build(pctx,
+			blueprint.BuildParams{
+				Rule:     stubSourceRule,
+				Outputs:  []string{source},
+				Inputs:   []string{symbolFile},
+				Optional: true,
+				Args: map[string]string{
+					"version":  version,
+					"versions": strings.Join(m.Properties.Stubs.Versions, ","),
+				},
+			})
+		ctx.Build(pctx,
+			blueprint.BuildParams{
+				Rule:     stubLibraryRule,
+				Outputs:  []string{stub},
+				Inputs:   []string{source},
+				Optional: true,
+				Args: map[string]string{
+					"ccompiler": ccompiler,
+					"cflags":    utils.Join(cflags),
+					"ldflags":   utils.Join(tc.getLinker().getFlags()),
+					"soname":    m.getSoname(),
+				},
+			})
+		stubs = append(stubs, stub)
+	}
+	return stubs
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sharedLibStubPath(t *testing.T) {
+	m := &sharedLibrary{fileNameExtension: ".so"}
+	m.SimpleName.Properties.Name = "libfoo"
+	m.Properties.TargetType = tgtTypeTarget
+	symbolFile := "foo/libfoo.map.txt"
+	m.Properties.Stubs.Symbol_file = &symbolFile
+
+	assert.True(t, m.hasStubs())
+	assert.Equal(t, []string{"current"}, m.stubVersions())
+	assert.Equal(t, "${BuildDir}/target/stubs/libfoo/current/libfoo.so", sharedLibStubPath(m))
+
+	m.Properties.Stubs.Versions = []string{"1", "2"}
+	m.Properties.Library_version = "2.1"
+	assert.Equal(t, "${BuildDir}/target/stubs/libfoo/2/libfoo.so", sharedLibStubPath(m))
+	assert.Equal(t, "libfoo.so.2", m.getSoname())
+}
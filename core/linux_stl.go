@@ -0,0 +1,104 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// The values the Linux backend accepts for stl. Which of them can be
+// used depends on the toolchain.
+var linuxStls = []string{"libc++", "libstdc++", "none", "static"}
+
+// stlLibrary returns the C++ standard library a value of stl selects,
+// or "" when it leaves the choice to the toolchain.
+func stlLibrary(stl string) string {
+	if stl == "libc++" || stl == "libstdc++" {
+		return stl
+	}
+	return ""
+}
+
+// stlMutator checks the stl property on the Linux backend, and that
+// the modules linked together don't select different C++ standard
+// libraries, which would fail to link, or crash at runtime when both
+// are loaded into the same process.
+func stlMutator(mctx blueprint.TopDownMutatorContext) {
+	if !getConfig(mctx).Properties.GetBool("builder_ninja") {
+		return
+	}
+	l, ok := getLibrary(mctx.Module())
+	if !ok || !isEnabled(l) {
+		return
+	}
+
+	if l.Properties.Stl != nil && !utils.Contains(linuxStls, *l.Properties.Stl) {
+		mctx.PropertyErrorf("stl", "must be one of %s, not %s",
+			strings.Join(linuxStls, ", "), *l.Properties.Stl)
+		return
+	}
+
+	if _, ok := getBinaryOrSharedLib(mctx.Module()); !ok {
+		return
+	}
+
+	linked := getLinkableModules(mctx)
+	mctx.VisitDirectDepsIf(
+		func(dep blueprint.Module) bool { return mctx.OtherModuleDependencyTag(dep) == sharedDepTag },
+		func(dep blueprint.Module) { linked[dep] = true })
+
+	deps := []*library{}
+	for dep := range linked {
+		if depLib, ok := getLibrary(dep); ok {
+			deps = append(deps, depLib)
+		}
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Name() < deps[j].Name() })
+
+	// Compare against the first module selecting a library, starting
+	// with this one
+	stl, user := stlLibrary(proptools.String(l.Properties.Stl)), l.Name()
+	for _, depLib := range deps {
+		depStl := stlLibrary(proptools.String(depLib.Properties.Stl))
+		if depStl == "" {
+			continue
+		}
+		if stl == "" {
+			stl, user = depStl, depLib.Name()
+		} else if depStl != stl {
+			mctx.ModuleErrorf("links modules using different C++ standard libraries: "+
+				"%s uses %s, but %s uses %s", user, stl, depLib.Name(), depStl)
+			return
+		}
+	}
+}
+
+// stlFlags returns the C++ compiler and linker flags of the module's
+// stl property.
+func (l *library) stlFlags(tc toolchain) ([]string, []string, error) {
+	if l.Properties.Stl == nil {
+		return []string{}, []string{}, nil
+	}
+	return tc.getStlFlags(proptools.String(l.Properties.Stl))
+}
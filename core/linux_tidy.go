@@ -0,0 +1,124 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+// The phony target which runs clang-tidy on all modules
+const analyzeTargetName = "analyze"
+
+// clang-tidy does not write anything on success, so touch a stamp file to
+// record that the source has been checked with the current flags.
+var tidyRule = pctx.StaticRule("clang_tidy",
+	blueprint.RuleParams{
+		Command:     "$tidy -quiet $tidyflags $in -- $cflags $langflags && touch $out",
+		Description: "tidy $in",
+	}, "tidy", "tidyflags", "cflags", "langflags")
+
+func tidyEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["clang_tidy"]
+	return ok && props.GetBool("clang_tidy")
+}
+
+// tidyFlags returns the clang-tidy options for a module, selecting the
+// checks configured for the project followed by the module's own.
+func (l *library) tidyFlags(ctx blueprint.ModuleContext) string {
+	checks := []string{}
+	if s := getConfig(ctx).Properties.GetString("clang_tidy_checks"); s != "" {
+		checks = append(checks, s)
+	}
+	checks = append(checks, l.Properties.Tidy_checks...)
+
+	if len(checks) == 0 {
+		// Let clang-tidy pick up the .clang-tidy files in the source tree
+		return ""
+	}
+	return "'-checks=" + strings.Join(checks, ",") + "'"
+}
+
+// addTidyAction runs clang-tidy on a C or C++ source of the library, using
+// the same flags as the compile which produced 'object'. The object is an
+// implicit dependency, so that the check is repeated whenever a header
+// included by the source changes.
+func (l *library) addTidyAction(ctx blueprint.ModuleContext, source, object, langflags string,
+	orderOnly []string) {
+
+	if !tidyEnabled(getConfig(ctx)) || proptools.Bool(l.Properties.Tidy_disabled) {
+		return
+	}
+
+	output := strings.TrimSuffix(object, ".o") + ".tidy"
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:      tidyRule,
+			Outputs:   []string{output},
+			Inputs:    []string{source},
+			Implicits: []string{object},
+			OrderOnly: orderOnly,
+			Args: map[string]string{
+				"tidy":      getConfig(ctx).Properties.GetString("clang_tidy_binary"),
+				"tidyflags": l.tidyFlags(ctx),
+				"cflags":    "$cflags",
+				"langflags": langflags,
+			},
+			Optional: true,
+		})
+
+	l.tidyOuts = append(l.tidyOuts, output)
+}
+
+type tidyProducer interface {
+	tidyOutputs() []string
+}
+
+func (l *library) tidyOutputs() []string {
+	return l.tidyOuts
+}
+
+type analyzeSingleton struct{}
+
+// GenerateBuildActions collects the clang-tidy runs of every module under
+// the analyze target.
+func (s *analyzeSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	outputs := []string{}
+
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if t, ok := m.(tidyProducer); ok {
+			outputs = append(outputs, t.tidyOutputs()...)
+		}
+	})
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:     blueprint.Phony,
+			Inputs:   outputs,
+			Outputs:  []string{analyzeTargetName},
+			Optional: true,
+		})
+}
+
+func analyzeSingletonFactory() blueprint.Singleton {
+	return &analyzeSingleton{}
+}
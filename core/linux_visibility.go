@@ -0,0 +1,166 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+var _ = pctx.StaticVariable("export_header_tool", "${BobScriptsDir}/export_header.py")
+var _ = pctx.StaticVariable("check_exports_tool", "${BobScriptsDir}/check_exports.py")
+
+var exportHeaderRule = pctx.StaticRule("export_header",
+	blueprint.RuleParams{
+		Command:     "$export_header_tool --macro $macro -o $out",
+		CommandDeps: []string{"$export_header_tool"},
+		Description: "$out",
+	}, "macro")
+
+var checkExportsRule = pctx.StaticRule("check_exports",
+	blueprint.RuleParams{
+		Command:     "$check_exports_tool $in -o $out $args",
+		CommandDeps: []string{"$check_exports_tool"},
+		Description: "check exports of $lib",
+	}, "args", "lib")
+
+func hiddenVisibilityEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["hidden_visibility"]
+	return ok && props.GetBool("hidden_visibility")
+}
+
+// hiddenVisibilityMutator decides whether each shared library is
+// compiled with hidden symbol visibility, so that the directory of its
+// export header can be exported to the modules using it.
+func hiddenVisibilityMutator(mctx blueprint.BottomUpMutatorContext) {
+	l, ok := getLibrary(mctx.Module())
+	if !ok || !isEnabled(l) {
+		return
+	}
+
+	if _, ok := mctx.Module().(*sharedLibrary); !ok {
+		if l.Properties.Hidden_visibility != nil {
+			mctx.PropertyErrorf("hidden_visibility", "is only supported by shared libraries")
+		}
+		return
+	}
+
+	config := getConfig(mctx)
+	if l.Properties.Hidden_visibility == nil {
+		l.Properties.Hidden_visibility = proptools.BoolPtr(hiddenVisibilityEnabled(config))
+	}
+	if *l.Properties.Hidden_visibility && !config.Properties.GetBool("builder_ninja") {
+		mctx.PropertyErrorf("hidden_visibility", "is only supported by the Linux backend")
+	}
+}
+
+func (l *library) hiddenVisibility() bool {
+	return proptools.Bool(l.Properties.Hidden_visibility)
+}
+
+// exportMacro returns the name of the macro annotating the symbols a
+// library exports, derived from the library name so that libraries
+// using each other don't define the same macro.
+func (l *library) exportMacro() string {
+	name := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return '_'
+		}
+		return unicode.ToUpper(r)
+	}, l.Name())
+	return name + "_EXPORT"
+}
+
+func (l *library) exportHeaderDir() string {
+	return filepath.Join("${BuildDir}", string(l.Properties.TargetType), "export_headers", l.Name())
+}
+
+// exportHeaders returns the header defining the export macro of a
+// library compiled with hidden visibility
+func (l *library) exportHeaders() []string {
+	if !l.hiddenVisibility() {
+		return []string{}
+	}
+	return []string{filepath.Join(l.exportHeaderDir(), l.Name()+"_export.h")}
+}
+
+// exportHeaderIncludeDirs returns the include directory of the export
+// header, which is exported to users of the library.
+func (l *library) exportHeaderIncludeDirs() []string {
+	if !l.hiddenVisibility() {
+		return []string{}
+	}
+	return []string{l.exportHeaderDir()}
+}
+
+func (l *library) visibilityCflags() []string {
+	if !l.hiddenVisibility() {
+		return []string{}
+	}
+	return []string{"-fvisibility=hidden"}
+}
+
+// addExportHeader writes the header defining the export macro of the
+// library, when it is compiled with hidden visibility.
+func (g *linuxGenerator) addExportHeader(ctx blueprint.ModuleContext, l *library) {
+	for _, header := range l.exportHeaders() {
+		ctx.Build(pctx,
+			blueprint.BuildParams{
+				Rule:     exportHeaderRule,
+				Outputs:  []string{header},
+				Args:     map[string]string{"macro": l.exportMacro()},
+				Optional: true,
+			})
+	}
+}
+
+// addExportCheck checks that a shared library compiled with hidden
+// visibility only exports the symbols given default visibility by its
+// objects and whole static libraries, returning the stamp written by
+// the check so that it runs whenever the library is built.
+func (g *linuxGenerator) addExportCheck(ctx blueprint.ModuleContext, l *library,
+	soFile string, objectFiles []string) []string {
+
+	if !l.hiddenVisibility() {
+		return []string{}
+	}
+
+	tc := g.getToolchain(l.Properties.TargetType)
+	stamp := filepath.Join(l.exportHeaderDir(), l.outputName()+".exports_checked")
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:    checkExportsRule,
+			Inputs:  utils.NewStringSlice([]string{soFile}, objectFiles, l.GetWholeStaticLibs(ctx)),
+			Outputs: []string{stamp},
+			Args: map[string]string{
+				"args": utils.Join(tc.getLibraryTocFlags()),
+				"lib":  soFile,
+			},
+			Optional: true,
+		})
+
+	return []string{stamp}
+}
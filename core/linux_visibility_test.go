@@ -0,0 +1,50 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_exportMacro(t *testing.T) {
+	l := &library{}
+	l.SimpleName.Properties.Name = "libfoo"
+	assert.Equal(t, "LIBFOO_EXPORT", l.exportMacro())
+
+	l.SimpleName.Properties.Name = "libgles-v2.1"
+	assert.Equal(t, "LIBGLES_V2_1_EXPORT", l.exportMacro())
+}
+
+func Test_exportHeaders(t *testing.T) {
+	l := &library{}
+	l.SimpleName.Properties.Name = "libfoo"
+	l.Properties.TargetType = tgtTypeTarget
+
+	assert.Equal(t, []string{}, l.exportHeaders())
+	assert.Equal(t, []string{}, l.exportHeaderIncludeDirs())
+	assert.Equal(t, []string{}, l.visibilityCflags())
+
+	l.Properties.Hidden_visibility = proptools.BoolPtr(true)
+	dir := "${BuildDir}/target/export_headers/libfoo"
+	assert.Equal(t, []string{dir + "/libfoo_export.h"}, l.exportHeaders())
+	assert.Equal(t, []string{dir}, l.exportHeaderIncludeDirs())
+	assert.Equal(t, []string{"-fvisibility=hidden"}, l.visibilityCflags())
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/google/blueprint"
+)
+
+var _ = pctx.StaticVariable("warning_log_tool", "${BobScriptsDir}/warning_log.py")
+var _ = pctx.StaticVariable("warning_budget_tool", "${BobScriptsDir}/warning_budget.py")
+
+var warningBudgetRule = pctx.StaticRule("warning_budget",
+	blueprint.RuleParams{
+		Command:        "$warning_budget_tool --name $budget --max $max_warnings --logs $out.rsp && touch $out",
+		CommandDeps:    []string{"$warning_budget_tool"},
+		Rspfile:        "$out.rsp",
+		RspfileContent: "$in",
+		Description:    "warning budget $budget",
+	}, "budget", "max_warnings")
+
+// warningBudgetHandler records the directory of each bob_warning_budget,
+// so that compilation can log the warnings of the modules it covers.
+type warningBudgetHandler struct {
+	lock sync.Mutex
+	// Name of the budget module defined in each directory
+	budgets map[string]string
+}
+
+func newWarningBudgetHandler() *warningBudgetHandler {
+	return &warningBudgetHandler{budgets: map[string]string{}}
+}
+
+func (h *warningBudgetHandler) warningBudgetMutator(mctx blueprint.BottomUpMutatorContext) {
+	if _, ok := mctx.Module().(*warningBudget); !ok {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	dir := mctx.ModuleDir()
+	if prev, ok := h.budgets[dir]; ok {
+		mctx.ModuleErrorf("%s already has the warning budget %s", dir, prev)
+		return
+	}
+	h.budgets[dir] = mctx.ModuleName()
+}
+
+// budgetFor returns the budget covering modules defined in a directory,
+// which is the one in the closest directory above it.
+func (h *warningBudgetHandler) budgetFor(dir string) (string, bool) {
+	if h == nil {
+		return "", false
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for {
+		if name, ok := h.budgets[dir]; ok {
+			return name, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// warningLogWrapper returns the command prefix which records the
+// warnings of a compilation in a file next to the object, and the name
+// of that file. Both are empty when no budget covers the module.
+func warningLogWrapper(ctx blueprint.ModuleContext, output string) (string, string) {
+	g, ok := unwrapBackend(getBackend(ctx)).(*linuxGenerator)
+	if !ok {
+		return "", ""
+	}
+	if _, ok := g.warningBudgets.budgetFor(ctx.ModuleDir()); !ok {
+		return "", ""
+	}
+
+	log := output + ".warnings"
+	return "$warning_log_tool --log " + log + " --", log
+}
+
+type warningLogProducer interface {
+	warningLogs() []string
+}
+
+func (l *library) warningLogs() []string {
+	return l.warningLogOuts
+}
+
+type warningBudgetSingleton struct {
+	handler *warningBudgetHandler
+}
+
+// GenerateBuildActions adds a step checking each budget against the
+// warnings logged by the modules it covers. The steps are built by
+// default, so that exceeding a budget fails the build.
+func (s *warningBudgetSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	logs := map[string][]string{}
+	budgets := map[string]*warningBudget{}
+
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if b, ok := m.(*warningBudget); ok {
+			budgets[ctx.ModuleName(m)] = b
+			return
+		}
+		p, ok := m.(warningLogProducer)
+		if !ok || len(p.warningLogs()) == 0 {
+			return
+		}
+		if budget, ok := s.handler.budgetFor(ctx.ModuleDir(m)); ok {
+			logs[budget] = append(logs[budget], p.warningLogs()...)
+		}
+	})
+
+	names := []string{}
+	for name := range budgets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		limit := budgets[name].Properties.Max_warnings
+		if limit == nil {
+			continue
+		}
+		stamp := filepath.Join("${BuildDir}", "warning_budgets", name+".stamp")
+		sort.Strings(logs[name])
+		ctx.Build(pctx,
+			blueprint.BuildParams{
+				Rule:    warningBudgetRule,
+				Inputs:  logs[name],
+				Outputs: []string{stamp},
+				Args: map[string]string{
+					"budget":       name,
+					"max_warnings": strconv.FormatInt(*limit, 10),
+				},
+			})
+		ctx.Build(pctx,
+			blueprint.BuildParams{
+				Rule:     blueprint.Phony,
+				Inputs:   []string{stamp},
+				Outputs:  []string{name},
+				Optional: true,
+			})
+	}
+}
+
+func (h *warningBudgetHandler) warningBudgetSingletonFactory() blueprint.Singleton {
+	return &warningBudgetSingleton{h}
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_warningBudgetFor(t *testing.T) {
+	h := newWarningBudgetHandler()
+	h.budgets["."] = "project_budget"
+	h.budgets["legacy"] = "legacy_budget"
+	h.budgets["legacy/clean"] = "clean_budget"
+
+	for dir, expected := range map[string]string{
+		".":                   "project_budget",
+		"src":                 "project_budget",
+		"legacy":              "legacy_budget",
+		"legacy/drivers/uart": "legacy_budget",
+		"legacy/clean/lib":    "clean_budget",
+		"legacyish":           "project_budget",
+	} {
+		budget, ok := h.budgetFor(dir)
+		assert.True(t, ok, dir)
+		assert.Equal(t, expected, budget, dir)
+	}
+
+	delete(h.budgets, ".")
+	_, ok := h.budgetFor("src")
+	assert.False(t, ok)
+
+	var none *warningBudgetHandler
+	_, ok = none.budgetFor("src")
+	assert.False(t, ok)
+}
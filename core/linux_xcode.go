@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+var _ = pctx.StaticVariable("xcconfig_tool", "${BobScriptsDir}/xcconfig.py")
+
+var xcconfigRule = pctx.StaticRule("xcconfig",
+	blueprint.RuleParams{
+		Command: "$xcconfig_tool -o $out --name $name @cflags $cflags @conlyflags $conlyflags " +
+			"@cxxflags $cxxflags @ldflags $ldflags",
+		CommandDeps: []string{"$xcconfig_tool"},
+		Description: "$out",
+	}, "name", "cflags", "conlyflags", "cxxflags", "ldflags")
+
+func xcodeConfigEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["xcode_config_files"]
+	return ok && props.GetBool("xcode_config_files")
+}
+
+// addXcodeConfig writes the flags used to build a host module to an
+// Xcode configuration file, so that developers can build and debug host
+// tools in an Xcode project. 'linkArgs' are the arguments of the link
+// rule, and are nil for static libraries. Must be called after
+// CompileObjs(), which sets the compiler flags for the module.
+func (g *linuxGenerator) addXcodeConfig(ctx blueprint.ModuleContext, l *library,
+	linkArgs map[string]string) []string {
+
+	if l.Properties.TargetType != tgtTypeHost || !xcodeConfigEnabled(getConfig(ctx)) {
+		return []string{}
+	}
+
+	output := filepath.Join("${BuildDir}", "xcode", l.outputName()+".xcconfig")
+
+	ldflags := ""
+	if linkArgs != nil {
+		ldflags = utils.Join([]string{
+			linkArgs["ldflags"],
+			linkArgs["static_libs"],
+			"-L" + linkArgs["shared_libs_dir"],
+			linkArgs["shared_libs_flags"],
+			linkArgs["ldlibs"],
+		})
+	}
+
+	ctx.Build(pctx,
+		blueprint.BuildParams{
+			Rule:    xcconfigRule,
+			Outputs: []string{output},
+			Args: map[string]string{
+				"name":       ctx.ModuleName(),
+				"cflags":     "$cflags",
+				"conlyflags": "$conlyflags",
+				"cxxflags":   "$cxxflags",
+				"ldflags":    ldflags,
+			},
+			Optional: true,
+		})
+
+	return []string{output}
+}
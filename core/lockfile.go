@@ -0,0 +1,196 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+var (
+	lockfileOut   string
+	lockfileCheck bool
+)
+
+func init() {
+	flag.StringVar(&lockfileOut, "lockfile", "",
+		"File recording the direct dependencies of each module. No build files are written")
+	flag.BoolVar(&lockfileCheck, "lockfile-check", false,
+		"Fail if the module graph differs from the lockfile, instead of writing it")
+}
+
+const lockfileHeader = "# Direct dependencies of each module. Regenerate with bob_lock.\n"
+
+// lockfileHandler records the direct dependencies of every enabled
+// module, so that the module graph can be compared with the one
+// recorded in the lockfile.
+type lockfileHandler struct {
+	path  string
+	check bool
+
+	lock  sync.Mutex
+	lines []string
+}
+
+func initLockfileHandler() *lockfileHandler {
+	if lockfileOut == "" {
+		if lockfileCheck {
+			utils.Die("--lockfile-check requires --lockfile")
+		}
+		return nil
+	}
+	return &lockfileHandler{path: lockfileOut, check: lockfileCheck}
+}
+
+func lockfileModuleName(name string, m blueprint.Module) string {
+	if s, ok := m.(splittable); ok {
+		return name + " (" + string(s.getTarget()) + ")"
+	}
+	return name
+}
+
+// lockfileMutator records a line for each module, followed by a line
+// for each of its dependencies. Defaults are not recorded, as they
+// only share properties, and do not appear in the build.
+func (h *lockfileHandler) lockfileMutator(mctx blueprint.BottomUpMutatorContext) {
+	m := mctx.Module()
+	if _, ok := m.(*defaults); ok {
+		return
+	}
+	if e, ok := m.(enableable); ok && !isEnabled(e) {
+		return
+	}
+
+	name := lockfileModuleName(mctx.ModuleName(), m)
+	lines := []string{name}
+	mctx.VisitDirectDeps(func(dep blueprint.Module) {
+		kind := "other"
+		if tag, ok := mctx.OtherModuleDependencyTag(dep).(dependencyTag); ok {
+			kind = tag.name
+		}
+		if kind == defaultDepTag.name {
+			return
+		}
+		depName := lockfileModuleName(mctx.OtherModuleName(dep), dep)
+		lines = append(lines, name+" -> "+kind+" "+depName)
+	})
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.lines = append(h.lines, lines...)
+}
+
+// content returns the lockfile, with the lines sorted so that it does
+// not depend on the order modules are visited in.
+func (h *lockfileHandler) content() string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	lines := map[string]bool{}
+	for _, line := range h.lines {
+		lines[line] = true
+	}
+	return lockfileHeader + strings.Join(utils.SortedKeysBoolMap(lines), "\n") + "\n"
+}
+
+// lockfileDiff returns the lines removed from and added to the old
+// lockfile content. Comments are ignored.
+func lockfileDiff(old, new string) (removed, added []string) {
+	parse := func(content string) map[string]bool {
+		lines := map[string]bool{}
+		for _, line := range strings.Split(content, "\n") {
+			if line != "" && !strings.HasPrefix(line, "#") {
+				lines[line] = true
+			}
+		}
+		return lines
+	}
+	oldLines := parse(old)
+	newLines := parse(new)
+
+	for line := range oldLines {
+		if !newLines[line] {
+			removed = append(removed, line)
+		}
+	}
+	for line := range newLines {
+		if !oldLines[line] {
+			added = append(added, line)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+	return
+}
+
+func (h *lockfileHandler) verify(content string) {
+	old, err := ioutil.ReadFile(h.path)
+	if err != nil {
+		utils.Die("%v", err)
+	}
+
+	removed, added := lockfileDiff(string(old), content)
+	if len(removed) == 0 && len(added) == 0 {
+		fmt.Printf("Module graph matches %s\n", h.path)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Module graph differs from %s:\n", h.path)
+	for _, line := range removed {
+		fmt.Fprintf(os.Stderr, "- %s\n", line)
+	}
+	for _, line := range added {
+		fmt.Fprintf(os.Stderr, "+ %s\n", line)
+	}
+	utils.Die("Run bob_lock to update %s if these changes are expected", h.path)
+}
+
+type lockfileSingleton struct {
+	handler *lockfileHandler
+}
+
+func (s *lockfileSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	h := s.handler
+	content := h.content()
+
+	if h.check {
+		h.verify(content)
+	} else {
+		err := ioutil.WriteFile(h.path, []byte(content), 0644)
+		if err != nil {
+			utils.Die("%v", err)
+		}
+		fmt.Printf("Wrote module graph to %s\n", h.path)
+	}
+
+	// As with the statistics, don't overwrite the build files
+	os.Exit(0)
+}
+
+func (h *lockfileHandler) lockfileSingletonFactory() blueprint.Singleton {
+	return &lockfileSingleton{h}
+}
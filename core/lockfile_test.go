@@ -0,0 +1,55 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_lockfileHandler_content_is_sorted_and_unique(t *testing.T) {
+	h := &lockfileHandler{}
+	h.lines = []string{
+		"libfoo (target)",
+		"libfoo (target) -> static libbar (target)",
+		"bin (host)",
+		"libfoo (target) -> static libbar (target)",
+	}
+	assert.Equal(t, lockfileHeader+
+		"bin (host)\n"+
+		"libfoo (target)\n"+
+		"libfoo (target) -> static libbar (target)\n", h.content())
+}
+
+func Test_lockfileDiff(t *testing.T) {
+	old := lockfileHeader +
+		"libfoo (target)\n" +
+		"libfoo (target) -> shared libbar (target)\n"
+	new := "libbaz (target)\n" +
+		"libfoo (target)\n" +
+		"libfoo (target) -> static libbar (target)\n"
+
+	removed, added := lockfileDiff(old, new)
+	assert.Equal(t, []string{"libfoo (target) -> shared libbar (target)"}, removed)
+	assert.Equal(t, []string{"libbaz (target)", "libfoo (target) -> static libbar (target)"}, added)
+
+	removed, added = lockfileDiff(old, old)
+	assert.Empty(t, removed)
+	assert.Empty(t, added)
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// The number of logs of earlier generation runs which are kept
+const generationLogsKept = 5
+
+// initGenerationLog starts the generation log in the build directory.
+// Its verbosity is set by BOB_LOG_LEVEL, defaulting to info.
+func initGenerationLog() {
+	level := utils.LogInfo
+	if name, present := os.LookupEnv("BOB_LOG_LEVEL"); present && name != "" {
+		var err error
+		level, err = utils.ParseLogLevel(name)
+		if err != nil {
+			utils.Die("BOB_LOG_LEVEL: %v", err)
+		}
+	}
+
+	filename := getPathInBuildDir("generation.log")
+	if err := utils.OpenLog(filename, level, generationLogsKept); err != nil {
+		utils.Warnf("", "Unable to write generation log %s: %v", filename, err)
+	}
+}
+
+// logBottomUpMutator wraps a mutator to record each module it is run on,
+// when debug messages are logged.
+func logBottomUpMutator(name string, mutator blueprint.BottomUpMutator) blueprint.BottomUpMutator {
+	if !utils.LogEnabled(utils.LogDebug) {
+		return mutator
+	}
+	return func(mctx blueprint.BottomUpMutatorContext) {
+		utils.Logf(utils.LogDebug, mctx.ModuleName(), "%s (%s)", name, mctx.BlueprintsFile())
+		mutator(mctx)
+	}
+}
+
+// logTopDownMutator is the top down equivalent of logBottomUpMutator
+func logTopDownMutator(name string, mutator blueprint.TopDownMutator) blueprint.TopDownMutator {
+	if !utils.LogEnabled(utils.LogDebug) {
+		return mutator
+	}
+	return func(mctx blueprint.TopDownMutatorContext) {
+		utils.Logf(utils.LogDebug, mctx.ModuleName(), "%s (%s)", name, mctx.BlueprintsFile())
+		mutator(mctx)
+	}
+}
@@ -67,6 +67,18 @@ func (m *simpleOutputProducer) implicitOutputs() []string {
 	return m.implicitOuts
 }
 
+// outputSetter is implemented by modules whose outputs can be set by
+// backends outside Bob, which can't access simpleOutputProducer.
+type outputSetter interface {
+	setOutputs(outputDir string, outs, implicitOuts []string)
+}
+
+func (m *simpleOutputProducer) setOutputs(outputDir string, outs, implicitOuts []string) {
+	m.outputdir = outputDir
+	m.outs = outs
+	m.implicitOuts = implicitOuts
+}
+
 // Modules that produce headers in the build output directory that may
 // be referenced by other modules must implement the genIncludeDirs()
 // function. This structure supplies a basic version of this function,
@@ -107,6 +107,9 @@ func defaultApplierMutator(mctx blueprint.BottomUpMutatorContext) {
 		return
 	}
 
+	// Properties marked final, and the defaults marking them
+	final := map[string]string{}
+
 	// Accumulate properties from direct dependencies into an empty defaults
 	accumulatedDef := defaults{}
 	accumulatedProps := accumulatedDef.defaultableProperties()
@@ -118,11 +121,25 @@ func defaultApplierMutator(mctx blueprint.BottomUpMutatorContext) {
 					dep.Name(), mctx.ModuleName())
 			}
 
+			// The defaults are in the order they are applied, so
+			// later defaults can't set properties made final by
+			// earlier ones.
+			for _, name := range finalPropertiesSet(def.defaultableProperties(), final) {
+				mctx.ModuleErrorf("%s is final in defaults %s (%s), but set by defaults %s (%s)",
+					name, final[name], defaultsChain(mctx.ModuleName(), final[name]),
+					dep.Name(), defaultsChain(mctx.ModuleName(), dep.Name()))
+			}
+			for _, name := range def.Properties.Final {
+				final[name] = dep.Name()
+			}
+
 			// Append defaults at the same level to maintain cflag order
 			err := appendDefaults(accumulatedProps, def.defaultableProperties())
 			if err != nil {
 				if propertyErr, ok := err.(*proptools.ExtendPropertyError); ok {
-					mctx.PropertyErrorf(propertyErr.Property, "%s", propertyErr.Err.Error())
+					mctx.PropertyErrorf(propertyErr.Property, "%s in defaults %s (%s)",
+						propertyErr.Err.Error(), dep.Name(),
+						defaultsChain(mctx.ModuleName(), dep.Name()))
 				} else {
 					utils.Die("%s", err)
 				}
@@ -130,6 +147,11 @@ func defaultApplierMutator(mctx blueprint.BottomUpMutatorContext) {
 		}
 	})
 
+	for _, name := range finalPropertiesSet(defaultableProps, final) {
+		mctx.PropertyErrorf(name, "is final in defaults %s (%s), so can't be set",
+			final[name], defaultsChain(mctx.ModuleName(), final[name]))
+	}
+
 	// Now apply the defaults to the core module
 	// Defaults are more generic, so we prepend to the
 	// core module properties.
@@ -222,15 +244,13 @@ func templateApplierMutator(mctx blueprint.TopDownMutatorContext) {
 		props := append([]interface{}{}, m.featurableProperties()...)
 
 		if ts, ok := module.(targetSpecificLibrary); ok {
-			host := ts.getTargetSpecific(tgtTypeHost)
-			target := ts.getTargetSpecific(tgtTypeTarget)
-
-			props = append(props, host.getTargetSpecificProps())
-			props = append(props, target.getTargetSpecificProps())
+			for _, tgt := range targetSpecificTypes {
+				props = append(props, ts.getTargetSpecific(tgt).getTargetSpecificProps())
+			}
 		}
 
 		for _, p := range props {
-			ApplyTemplate(p, cfgProps)
+			mctx.AddNinjaFileDeps(ApplyTemplate(p, cfgProps)...)
 		}
 	}
 }
@@ -257,15 +277,11 @@ func featureApplierMutator(mctx blueprint.TopDownMutatorContext) {
 		// Apply features in target-specific properties.
 		// This should happen for all modules which support host:{} and target:{}
 		if ts, ok := module.(targetSpecificLibrary); ok {
-			host := ts.getTargetSpecific(tgtTypeHost)
-			target := ts.getTargetSpecific(tgtTypeTarget)
-
-			var tgtprops = []propmap{
-				propmap{[]interface{}{host.getTargetSpecificProps()}, &host.Features},
-				propmap{[]interface{}{target.getTargetSpecificProps()}, &target.Features},
+			for _, tgt := range targetSpecificTypes {
+				tgtSpecific := ts.getTargetSpecific(tgt)
+				props = append(props, propmap{
+					[]interface{}{tgtSpecific.getTargetSpecificProps()}, &tgtSpecific.Features})
 			}
-			props = append(props, tgtprops...)
-
 		}
 
 		for _, prop := range props {
@@ -282,5 +298,6 @@ func featureApplierMutator(mctx blueprint.TopDownMutatorContext) {
 				}
 			}
 		}
+		recordDisablingFeature(m, cfgProps)
 	}
 }
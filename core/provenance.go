@@ -0,0 +1,94 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/google/blueprint"
+)
+
+// Matches the line holding the name of a module in a build file
+var moduleNameRegexp = regexp.MustCompile(`^\s*name\s*:\s*"([^"]+)"`)
+
+// buildFileLines maps each module name in a build file to the line it is
+// named on. Build files are only scanned the first time they are needed.
+type buildFileLines struct {
+	lock  sync.Mutex
+	files map[string]map[string]int
+}
+
+var provenanceLines = buildFileLines{files: map[string]map[string]int{}}
+
+func scanBuildFile(filename string) map[string]int {
+	lines := map[string]int{}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return lines
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		m := moduleNameRegexp.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		if _, ok := lines[m[1]]; !ok {
+			lines[m[1]] = lineNo
+		}
+	}
+
+	return lines
+}
+
+func (b *buildFileLines) lineOf(filename, module string) (int, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	lines, ok := b.files[filename]
+	if !ok {
+		lines = scanBuildFile(filename)
+		b.files[filename] = lines
+	}
+
+	line, ok := lines[module]
+	return line, ok
+}
+
+func provenanceEnabled(config *bobConfig) bool {
+	props := config.Properties
+	_, ok := props.properties["build_file_provenance"]
+	return ok && props.GetBool("build_file_provenance")
+}
+
+// provenanceComment returns a comment naming the build file, and the line
+// within it, that the current module was defined on.
+func provenanceComment(ctx blueprint.ModuleContext) string {
+	location := ctx.BlueprintsFile()
+	if line, ok := provenanceLines.lineOf(location, ctx.ModuleName()); ok {
+		location = fmt.Sprintf("%s:%d", location, line)
+	}
+
+	return fmt.Sprintf("# Module %s (%s), defined in %s\n", ctx.ModuleName(), ctx.ModuleType(), location)
+}
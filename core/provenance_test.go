@@ -0,0 +1,52 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_scanBuildFile_finds_module_names(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bob_provenance")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "build.bp")
+	content := `bob_binary {
+    name: "bin",
+    srcs: ["main.c"],
+}
+
+bob_static_library {
+    name : "libfoo",
+    cflags: ["-DNAME=\"name\""],
+}
+`
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(content), 0644))
+
+	assert.Equal(t, map[string]int{"bin": 2, "libfoo": 7}, scanBuildFile(filename))
+}
+
+func Test_scanBuildFile_ignores_missing_file(t *testing.T) {
+	assert.Equal(t, map[string]int{}, scanBuildFile("does/not/exist.bp"))
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+// The ways a bob_python_binary can be packaged
+const (
+	pythonPackagingZipapp = "zipapp"
+	pythonPackagingVenv   = "venv"
+)
+
+// PythonBinaryProps describes the properties of the bob_python_binary module
+type PythonBinaryProps struct {
+	SourceProps
+	AliasableProps
+	EnableableProps
+
+	// The entry point of the tool, either a module run as `__main__`, or
+	// `module:function`
+	Main *string
+	// A pip requirements file, relative to the build.bp, listing the
+	// packages the tool uses
+	Requirements *string
+	// How the tool is packaged, either `zipapp` (the default) or `venv`
+	Packaging *string
+}
+
+// Type representing each bob_python_binary module. The module packages
+// a Python tool, with the packages it needs, so that it can be used as
+// the host_bin of generator modules.
+type pythonBinary struct {
+	moduleBase
+	simpleOutputProducer
+	Properties struct {
+		PythonBinaryProps
+		Features
+	}
+}
+
+var _ featurable = (*pythonBinary)(nil)
+var _ enableable = (*pythonBinary)(nil)
+var _ aliasable = (*pythonBinary)(nil)
+var _ pathProcessor = (*pythonBinary)(nil)
+
+func (m *pythonBinary) features() *Features {
+	return &m.Properties.Features
+}
+
+func (m *pythonBinary) featurableProperties() []interface{} {
+	return []interface{}{&m.Properties.PythonBinaryProps}
+}
+
+func (m *pythonBinary) getEnableableProps() *EnableableProps {
+	return &m.Properties.EnableableProps
+}
+
+func (m *pythonBinary) getAliasList() []string {
+	return m.Properties.getAliasList()
+}
+
+func (m *pythonBinary) shortName() string {
+	return m.Name()
+}
+
+func (m *pythonBinary) altName() string {
+	return m.Name()
+}
+
+func (m *pythonBinary) altShortName() string {
+	return m.shortName()
+}
+
+func (m *pythonBinary) processPaths(ctx blueprint.BaseModuleContext, g generatorBackend) {
+	m.Properties.SourceProps.processPaths(ctx, g)
+	if m.Properties.Requirements != nil {
+		m.Properties.Requirements = proptools.StringPtr(
+			filepath.Join(projectModuleDir(ctx), *m.Properties.Requirements))
+	}
+}
+
+// packaging returns how the tool is packaged, reporting an error for
+// unknown values.
+func (m *pythonBinary) packaging(ctx blueprint.ModuleContext) string {
+	packaging := proptools.StringDefault(m.Properties.Packaging, pythonPackagingZipapp)
+	if packaging != pythonPackagingZipapp && packaging != pythonPackagingVenv {
+		ctx.PropertyErrorf("packaging", "must be %s or %s, not %s",
+			pythonPackagingZipapp, pythonPackagingVenv, packaging)
+	}
+	return packaging
+}
+
+// pythonInterpreter returns the interpreter the tools are packaged for
+func pythonInterpreter(config *bobConfig) string {
+	if _, ok := config.Properties.properties["python_binary"]; ok {
+		if python := config.Properties.GetString("python_binary"); python != "" {
+			return python
+		}
+	}
+	return "python3"
+}
+
+// Called by Blueprint to generate the rules associated with the module.
+// This is forwarded to the backend to handle.
+func (m *pythonBinary) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
+	if isEnabled(m) {
+		if m.Properties.Main == nil {
+			ctx.PropertyErrorf("main", "is required")
+			return
+		}
+		getBackend(ctx).pythonBinaryActions(m, ctx)
+	}
+}
+
+// Create the structure representing the bob_python_binary
+func pythonBinaryFactory(config *bobConfig) (blueprint.Module, []interface{}) {
+	module := &pythonBinary{}
+	module.Properties.Features.Init(&config.Properties, PythonBinaryProps{})
+	return module, []interface{}{&module.Properties, &module.SimpleName.Properties}
+}
@@ -0,0 +1,36 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_pythonInterpreter(t *testing.T) {
+	config := &bobConfig{}
+	config.Properties.properties = map[string]interface{}{}
+	assert.Equal(t, "python3", pythonInterpreter(config))
+
+	config.Properties.properties["python_binary"] = ""
+	assert.Equal(t, "python3", pythonInterpreter(config))
+
+	config.Properties.properties["python_binary"] = "/opt/python/bin/python3.9"
+	assert.Equal(t, "/opt/python/bin/python3.9", pythonInterpreter(config))
+}
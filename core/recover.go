@@ -0,0 +1,72 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"runtime/debug"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// propertyPanic is raised by code which finds an error in a property,
+// but has no access to the module context to report it.
+type propertyPanic struct {
+	property string
+	err      error
+}
+
+func panicInProperty(property string, err error) {
+	panic(propertyPanic{property, err})
+}
+
+// recoverModulePanic turns a panic while processing a module into an
+// error reported against the module, or against the property when this
+// is known, instead of a bare stack trace. The stack trace is written
+// to the generation log. It must be called with defer.
+func recoverModulePanic(ctx blueprint.BaseModuleContext, phase string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	utils.Logf(utils.LogError, ctx.ModuleName(), "panic in %s: %v\n%s", phase, r, debug.Stack())
+
+	if p, ok := r.(propertyPanic); ok {
+		ctx.PropertyErrorf(p.property, "%v", p.err)
+	} else {
+		ctx.ModuleErrorf("internal error in %s: %v", phase, r)
+	}
+}
+
+// recoverBottomUpMutator wraps a mutator so that panics are reported
+// as errors in the module being mutated.
+func recoverBottomUpMutator(name string, mutator blueprint.BottomUpMutator) blueprint.BottomUpMutator {
+	return func(mctx blueprint.BottomUpMutatorContext) {
+		defer recoverModulePanic(mctx, name+" mutator")
+		mutator(mctx)
+	}
+}
+
+// recoverTopDownMutator is the top down equivalent of recoverBottomUpMutator
+func recoverTopDownMutator(name string, mutator blueprint.TopDownMutator) blueprint.TopDownMutator {
+	return func(mctx blueprint.TopDownMutatorContext) {
+		defer recoverModulePanic(mctx, name+" mutator")
+		mutator(mctx)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// What happens to a module whose required_config is not met
+const (
+	requiredConfigError   = "error"
+	requiredConfigDisable = "disable"
+)
+
+// checkRequiredConfig returns why the configuration doesn't meet 'req',
+// which is either `OPTION`, for a boolean option which must be enabled,
+// or `OPTION=value`. An empty string means the requirement is met.
+func checkRequiredConfig(props *configProperties, req string) (string, error) {
+	name, expected := req, "y"
+	if i := strings.Index(req, "="); i != -1 {
+		name, expected = req[:i], req[i+1:]
+	}
+
+	value, ok := props.properties[strings.ToLower(name)]
+	if name == "" || !ok {
+		return "", fmt.Errorf("%s is not a configuration option", name)
+	}
+
+	switch v := value.(type) {
+	case bool:
+		var want bool
+		switch strings.ToLower(expected) {
+		case "y", "true", "1":
+			want = true
+		case "n", "false", "0":
+			want = false
+		default:
+			return "", fmt.Errorf("%s is a boolean option, so can't be %q", name, expected)
+		}
+		if v != want {
+			if want {
+				return fmt.Sprintf("%s is not enabled", name), nil
+			}
+			return fmt.Sprintf("%s is enabled", name), nil
+		}
+	default:
+		actual := fmt.Sprint(v)
+		if actual != expected {
+			return fmt.Sprintf("%s is %q, not %q", name, actual, expected), nil
+		}
+	}
+	return "", nil
+}
+
+// requiredConfigMutator checks the required_config of each module, once
+// features and defaults have been applied. Depending on
+// required_config_action, a module whose requirements are not met
+// either fails generation, or is disabled. Modules depending on it are
+// then disabled by checkDisabledMutator, as for any disabled module.
+func requiredConfigMutator(mctx blueprint.BottomUpMutatorContext) {
+	if _, ok := mctx.Module().(*defaults); ok {
+		return
+	}
+	e, ok := mctx.Module().(enableable)
+	if !ok || !isEnabled(e) {
+		return
+	}
+	props := e.getEnableableProps()
+	if len(props.Required_config) == 0 {
+		return
+	}
+
+	action := proptools.StringDefault(props.Required_config_action, requiredConfigError)
+	if action != requiredConfigError && action != requiredConfigDisable {
+		mctx.PropertyErrorf("required_config_action", "must be %s or %s, not %s",
+			requiredConfigError, requiredConfigDisable, action)
+		return
+	}
+
+	unmet := []string{}
+	config := &getConfig(mctx).Properties
+	for _, req := range props.Required_config {
+		reason, err := checkRequiredConfig(config, req)
+		if err != nil {
+			mctx.PropertyErrorf("required_config", "%v", err)
+		} else if reason != "" {
+			unmet = append(unmet, reason)
+		}
+	}
+	if len(unmet) == 0 {
+		return
+	}
+
+	if action == requiredConfigError {
+		mctx.PropertyErrorf("required_config", "is not met by the configuration: %s",
+			strings.Join(unmet, ", "))
+		return
+	}
+
+	props.Enabled = proptools.BoolPtr(false)
+	props.Disabled_reason = "has unmet required_config: " + strings.Join(unmet, ", ")
+	utils.Logf(utils.LogInfo, mctx.ModuleName(), "disabled, as it %s", props.Disabled_reason)
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkRequiredConfig(t *testing.T) {
+	props := &configProperties{
+		properties: map[string]interface{}{
+			"vulkan":       true,
+			"opencl":       false,
+			"gpu_arch":     "valhall",
+			"shader_cores": json.Number("4"),
+		},
+	}
+
+	met := []string{"VULKAN", "VULKAN=y", "OPENCL=n", "GPU_ARCH=valhall", "SHADER_CORES=4"}
+	for _, req := range met {
+		reason, err := checkRequiredConfig(props, req)
+		assert.NoError(t, err, req)
+		assert.Equal(t, "", reason, req)
+	}
+
+	reason, err := checkRequiredConfig(props, "OPENCL")
+	assert.NoError(t, err)
+	assert.Equal(t, "OPENCL is not enabled", reason)
+
+	reason, err = checkRequiredConfig(props, "VULKAN=n")
+	assert.NoError(t, err)
+	assert.Equal(t, "VULKAN is enabled", reason)
+
+	reason, err = checkRequiredConfig(props, "GPU_ARCH=bifrost")
+	assert.NoError(t, err)
+	assert.Equal(t, `GPU_ARCH is "valhall", not "bifrost"`, reason)
+
+	reason, err = checkRequiredConfig(props, "SHADER_CORES=8")
+	assert.NoError(t, err)
+	assert.Equal(t, `SHADER_CORES is "4", not "8"`, reason)
+
+	_, err = checkRequiredConfig(props, "GPU_ARHC=valhall")
+	assert.EqualError(t, err, "GPU_ARHC is not a configuration option")
+
+	_, err = checkRequiredConfig(props, "VULKAN=maybe")
+	assert.EqualError(t, err, `VULKAN is a boolean option, so can't be "maybe"`)
+}
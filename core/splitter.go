@@ -30,6 +30,9 @@ import (
 type SplittableProps struct {
 	Host_supported   *bool
 	Target_supported *bool
+	// Build the module with the host cross toolchain, for a host OS
+	// other than the one running the build
+	Host_cross_supported *bool
 }
 
 // If a Module implements this interface, then it will be split into
@@ -110,6 +113,20 @@ func supportedVariantsMutator(mctx blueprint.BottomUpMutatorContext) {
 			utils.Die("%v", err)
 		}
 	}
+
+	for _, tgt := range sp.supportedVariants() {
+		if tgt != tgtTypeHostCross {
+			continue
+		}
+		if !hostCrossEnabled(&getConfig(mctx).Properties) {
+			mctx.ModuleErrorf("is built for host_cross, but HOST_CROSS_TOOLCHAIN is not enabled")
+		}
+		// Shared libraries are named and linked as ELF objects,
+		// which the host cross OS may not use.
+		if _, ok := mctx.Module().(*sharedLibrary); ok {
+			mctx.PropertyErrorf("host_cross_supported", "is not supported by bob_shared_library")
+		}
+	}
 }
 
 func tgtToString(tgts []tgtType) []string {
@@ -24,6 +24,7 @@ package core
 
 import (
 	"os"
+	"time"
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/bootstrap"
@@ -38,6 +39,8 @@ var (
 	configOpts     = os.Getenv("BOB_CONFIG_OPTS")
 	srcdir         = os.Getenv("SRCDIR")
 	configJSONFile = os.Getenv("CONFIG_JSON")
+	// Set by the --config-overrides option of bootstrap
+	configOverridesFile = os.Getenv("BOB_CONFIG_OVERRIDES")
 )
 
 type moduleBase struct {
@@ -77,21 +80,83 @@ func getBobDir() string {
 // It loads the configuration from .bob.config.json, registers the module type
 // and mutators, initializes the backend, and finally calls into Blueprint.
 func Main() {
+	start := time.Now()
+	initGenerationLog()
+	tools := &generationTools{
+		stats:    initStatsHandler(start),
+		trace:    initTraceHandler(start),
+		events:   initEventLog(start),
+		lockfile: initLockfileHandler(),
+		explain:  initExplainHandler(),
+		graphviz: initGrapvizHandler(),
+		vscode:   vscodeOut != "",
+	}
+
 	// Load the config first. This is needed because some of the module
 	// types' definitions contain a struct-per-feature, and features are
 	// specified in the config.
 	config := &bobConfig{}
-	err := config.Properties.LoadConfig(configJSONFile)
+	err := config.Properties.LoadConfig(configJSONFile, configOverridesFile)
 	if err != nil {
 		utils.Die("%v", err)
 	}
+	tools.stats.setConfigLoaded()
+	tools.trace.setConfigLoaded()
+	tools.events.setConfigLoaded()
+	utils.Logf(utils.LogInfo, "", "Loaded configuration %s", configJSONFile)
+
+	// Depend on the config file
+	pctx.AddNinjaFileDeps(configJSONFile, getPathInBuildDir(".env.hash"))
+	if configOverridesFile != "" {
+		utils.Logf(utils.LogInfo, "", "Applied configuration overrides %s", configOverridesFile)
+		pctx.AddNinjaFileDeps(configOverridesFile)
+	}
+
+	ctx := newContext(config, tools)
+	identifyCompilers(config)
+	bootstrap.Main(ctx, config)
+
+	if extraAndroidBpEnabled(config) {
+		generateExtraAndroidBp(config)
+	}
+
+	saveFlagCache()
+	tools.trace.writeTrace()
+	tools.events.finish()
+
+	utils.Logf(utils.LogInfo, "", "Generation finished in %v", time.Since(start))
+	utils.CloseLog()
+}
+
+// generationTools holds the handlers of the tools which report on the
+// module graph instead of writing build files. Each is nil when the
+// tool is not used.
+type generationTools struct {
+	stats    *statsHandler
+	trace    *traceHandler
+	events   *eventLogHandler
+	lockfile *lockfileHandler
+	explain  *explainHandler
+	graphviz *graphvizHandler
+	vscode   bool
+	// Set for the context writing the Android.bp alongside build.ninja,
+	// which must not overwrite the reports of the Ninja generation.
+	secondary bool
+}
+
+// newContext registers the module types, mutators and singletons, and
+// initializes the backend selected by the config.
+func newContext(config *bobConfig, tools *generationTools) *blueprint.Context {
+	stats := tools.stats
+	trace := tools.trace
+	lockfile := tools.lockfile
+	explain := tools.explain
 
 	builder_ninja := config.Properties.GetBool("builder_ninja")
 	builder_android_bp := config.Properties.GetBool("builder_android_bp")
 	builder_android_make := config.Properties.GetBool("builder_android_make")
-
-	// Depend on the config file
-	pctx.AddNinjaFileDeps(configJSONFile, getPathInBuildDir(".env.hash"))
+	_, builder_plugin := config.Properties.properties["builder_plugin"]
+	builder_plugin = builder_plugin && config.Properties.GetBool("builder_plugin")
 
 	var ctx = blueprint.NewContext()
 
@@ -104,6 +169,23 @@ func Main() {
 		ctx.RegisterModuleType(name, factory)
 	})
 
+	// Register mutators through these, so that the time spent in each
+	// can be reported when statistics or a trace are requested, and
+	// property values can be explained, and logged at debug level.
+	// Panics are reported as errors in the module being mutated.
+	registerBottomUpMutator := func(name string, m blueprint.BottomUpMutator) blueprint.MutatorHandle {
+		m = recoverBottomUpMutator(name, m)
+		m = explain.explainBottomUpMutator(name, logBottomUpMutator(name, m))
+		return ctx.RegisterBottomUpMutator(name,
+			trace.traceBottomUpMutator(name, stats.timeBottomUpMutator(name, m)))
+	}
+	registerTopDownMutator := func(name string, m blueprint.TopDownMutator) blueprint.MutatorHandle {
+		m = recoverTopDownMutator(name, m)
+		m = explain.explainTopDownMutator(name, logTopDownMutator(name, m))
+		return ctx.RegisterTopDownMutator(name,
+			trace.traceTopDownMutator(name, stats.timeTopDownMutator(name, m)))
+	}
+
 	// Note that the order of mutators is important, since the
 	// contents of each module will be rewritten. The following
 	// describes the required orderring of mutators dealing with
@@ -168,54 +250,114 @@ func Main() {
 	// The depender mutator adds the dependencies between binaries and libraries.
 	//
 	// The generated depender mutator add dependencies to generated source modules.
-	ctx.RegisterBottomUpMutator("default_deps1", defaultDepsStage1Mutator).Parallel()
-	ctx.RegisterBottomUpMutator("default_deps2", defaultDepsStage2Mutator).Parallel()
-	ctx.RegisterTopDownMutator("features_applier", featureApplierMutator).Parallel()
-	ctx.RegisterTopDownMutator("template_applier", templateApplierMutator).Parallel()
-	ctx.RegisterBottomUpMutator("check_lib_fields", checkLibraryFieldsMutator).Parallel()
-	ctx.RegisterBottomUpMutator("strip_empty_components", stripEmptyComponentsMutator).Parallel()
-	ctx.RegisterBottomUpMutator("supported_variants", supportedVariantsMutator).Parallel()
-	ctx.RegisterBottomUpMutator(splitterMutatorName, splitterMutator).Parallel()
-	ctx.RegisterTopDownMutator("target", targetMutator).Parallel()
-	ctx.RegisterBottomUpMutator("process_paths", pathMutator).Parallel()
-	ctx.RegisterBottomUpMutator("default_applier", defaultApplierMutator).Parallel()
-	ctx.RegisterBottomUpMutator("depender", dependerMutator).Parallel()
-	ctx.RegisterBottomUpMutator("alias", aliasMutator).Parallel()
-	ctx.RegisterBottomUpMutator("generated", generatedDependerMutator).Parallel()
-
-	if handler := initGrapvizHandler(); handler != nil {
+	registerBottomUpMutator("default_deps1", defaultDepsStage1Mutator).Parallel()
+	registerBottomUpMutator("default_deps2", defaultDepsStage2Mutator).Parallel()
+	registerTopDownMutator("features_applier", featureApplierMutator).Parallel()
+	registerTopDownMutator("template_applier", templateApplierMutator).Parallel()
+	registerBottomUpMutator("check_lib_fields", checkLibraryFieldsMutator).Parallel()
+	registerBottomUpMutator("strip_empty_components", stripEmptyComponentsMutator).Parallel()
+	registerBottomUpMutator("supported_variants", supportedVariantsMutator).Parallel()
+	registerBottomUpMutator(splitterMutatorName, splitterMutator).Parallel()
+	registerTopDownMutator("target", targetMutator).Parallel()
+	registerTopDownMutator("strict_paths", strictPathsMutator).Parallel()
+	registerBottomUpMutator("process_paths", pathMutator).Parallel()
+	registerBottomUpMutator("default_applier", defaultApplierMutator).Parallel()
+	registerBottomUpMutator("required_config", requiredConfigMutator).Parallel()
+	// Find dependency cycles before blueprint does, so they can be
+	// reported with the properties which create them.
+	cycles := newDependencyCycleHandler()
+	registerBottomUpMutator("collect_dependencies", cycles.collectDependenciesMutator).Parallel()
+	registerBottomUpMutator("check_dependency_cycles", cycles.checkCyclesMutator).Parallel()
+	registerBottomUpMutator("depender", dependerMutator).Parallel()
+	registerBottomUpMutator("alias", aliasMutator).Parallel()
+	registerBottomUpMutator("generated", generatedDependerMutator).Parallel()
+	registerBottomUpMutator("parser_sources", parserSourcesMutator).Parallel()
+	registerBottomUpMutator("hidden_visibility", hiddenVisibilityMutator).Parallel()
+	registerBottomUpMutator("android_aux", androidAuxMutator).Parallel()
+
+	if handler := tools.graphviz; handler != nil {
 		ctx.RegisterBottomUpMutator("graphviz_output", handler.graphvizMutator)
 		// Singleton for stop tool and don't overwrite build.bp
-		ctx.RegisterSingletonType("quit_singleton", handler.quitSingletonFactory)
+		registerSingletonType(ctx, "quit_singleton", handler.quitSingletonFactory)
 	} else {
 
-		ctx.RegisterTopDownMutator("export_lib_flags", exportLibFlagsMutator).Parallel()
+		registerTopDownMutator("export_lib_flags", exportLibFlagsMutator).Parallel()
+		registerTopDownMutator("gc_sections", gcSectionsMutator).Parallel()
+		registerTopDownMutator("stl", stlMutator).Parallel()
+		registerTopDownMutator("version_script_gen", versionScriptGenMutator).Parallel()
 		dependencyGraphHandler := graphMutatorHandler{
 			map[tgtType]graph.Graph{
-				tgtTypeHost:   graph.NewGraph("All"),
-				tgtTypeTarget: graph.NewGraph("All"),
+				tgtTypeHost:      graph.NewGraph("All"),
+				tgtTypeTarget:    graph.NewGraph("All"),
+				tgtTypeHostCross: graph.NewGraph("All"),
 			},
 		}
-		ctx.RegisterBottomUpMutator("sort_resolved_static_libs",
+		registerBottomUpMutator("sort_resolved_static_libs",
 			dependencyGraphHandler.ResolveDependencySortMutator) // This can't be parallel
-		ctx.RegisterTopDownMutator("find_required_modules",
+		registerTopDownMutator("find_required_modules",
 			findRequiredModulesMutator).Parallel()
-		ctx.RegisterBottomUpMutator("check_disabled_modules",
+		registerBottomUpMutator("check_disabled_modules",
 			checkDisabledMutator).Parallel()
-		ctx.RegisterTopDownMutator("check_reexport_libs",
+		registerBottomUpMutator("check_expected_enabled",
+			checkExpectedEnabledMutator).Parallel()
+		registerBottomUpMutator("check_cxx_features",
+			checkCxxFeaturesMutator).Parallel()
+		registerBottomUpMutator("check_allocator",
+			checkAllocatorMutator).Parallel()
+		registerBottomUpMutator("check_static_executable",
+			checkStaticExecutableMutator).Parallel()
+		registerTopDownMutator("check_reexport_libs",
 			checkReexportLibsMutator).Parallel()
-		ctx.RegisterTopDownMutator("collect_reexport_lib_dependencies",
+		registerTopDownMutator("collect_reexport_lib_dependencies",
 			collectReexportLibsDependenciesMutator).Parallel()
-		ctx.RegisterBottomUpMutator("apply_reexport_lib_dependencies",
+		registerBottomUpMutator("apply_reexport_lib_dependencies",
 			applyReexportLibsDependenciesMutator).Parallel()
-		ctx.RegisterTopDownMutator("install_group_mutator", installGroupMutator).Parallel()
-		ctx.RegisterTopDownMutator("debug_info_mutator", debugInfoMutator).Parallel()
+		registerTopDownMutator("install_group_mutator", installGroupMutator).Parallel()
+		registerTopDownMutator("debug_info_mutator", debugInfoMutator).Parallel()
 		if !builder_android_bp {
 			// The android_bp backend's escape function is a no-op,
 			// so optimize by skipping the mutator
-			ctx.RegisterTopDownMutator("escape_mutator", escapeMutator).Parallel()
+			registerTopDownMutator("escape_mutator", escapeMutator).Parallel()
+		}
+		registerTopDownMutator("late_template_mutator", lateTemplateMutator).Parallel()
+		// Must follow all mutators adding dependencies or changing
+		// exported properties.
+		registerBottomUpMutator("exported_variables", exportedVariablesMutator).Parallel()
+		registerBottomUpMutator("check_defines", checkDefinesMutator).Parallel()
+		if events := tools.events; events != nil {
+			// After all the mutators which may disable modules
+			ctx.RegisterBottomUpMutator("collect_events", events.eventLogMutator).Parallel()
+		}
+
+		if stats != nil {
+			ctx.RegisterBottomUpMutator("collect_stats", stats.statsMutator).Parallel()
+			// Registered before the backend singletons, so the
+			// statistics are written before any build files.
+			registerSingletonType(ctx, "stats_singleton", stats.statsSingletonFactory)
+		}
+		if tools.vscode {
+			// Also registered before the backend singletons, as
+			// this does not write build files either.
+			registerSingletonType(ctx, "vscode_singleton", vscodeSingletonFactory)
+		}
+		if lockfile != nil {
+			ctx.RegisterBottomUpMutator("collect_lockfile", lockfile.lockfileMutator).Parallel()
+			registerSingletonType(ctx, "lockfile_singleton", lockfile.lockfileSingletonFactory)
+		}
+		if explain != nil {
+			registerSingletonType(ctx, "explain_singleton", explain.explainSingletonFactory)
+		}
+		if lintEnabled(config) && !tools.secondary {
+			registerSingletonType(ctx, "lint_singleton", lintSingletonFactory)
+		}
+		if configExportEnabled(config) && !tools.secondary {
+			registerSingletonType(ctx, "config_export_singleton", configExportSingletonFactory)
+		}
+		if flagAuditEnabled(config) && !tools.secondary {
+			// Module build actions are generated before any
+			// singleton runs, so every dropped flag is recorded.
+			registerSingletonType(ctx, "flag_audit_singleton", flagAuditSingletonFactory)
 		}
-		ctx.RegisterTopDownMutator("late_template_mutator", lateTemplateMutator).Parallel()
 	}
 
 	if builder_ninja {
@@ -224,10 +366,21 @@ func Main() {
 		config.Generator = &androidBpGenerator{}
 	} else if builder_android_make {
 		config.Generator = &androidMkGenerator{}
+	} else if builder_plugin {
+		plugin, err := newPluginBackend(config)
+		if err != nil {
+			utils.Die("%v", err)
+		}
+		config.Generator = toBackendV1(plugin)
 	} else {
 		utils.Die("Unknown builder backend")
 	}
 
+	apiVersion := backendAPIVersion(&config.Properties)
+	config.Generator = selectBackendAPI(apiVersion, config.Generator)
 	config.Generator.init(ctx, config)
-	bootstrap.Main(ctx, config)
+	utils.Logf(utils.LogInfo, "", "Using the %T backend with backend API version %d",
+		unwrapBackend(config.Generator), apiVersion)
+
+	return ctx
 }
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+// staticLinkProperty returns the property which links a module
+// statically, or "" if it is linked dynamically
+func (l *library) staticLinkProperty() string {
+	if proptools.Bool(l.Properties.Static_pie) {
+		return "static_pie"
+	} else if proptools.Bool(l.Properties.Static_executable) {
+		return "static_executable"
+	}
+	return ""
+}
+
+// staticExecutableFlags returns the compiler and linker flags which
+// link a binary statically
+func (l *library) staticExecutableFlags(tc toolchain) ([]string, []string, error) {
+	if l.staticLinkProperty() == "" {
+		return []string{}, []string{}, nil
+	}
+	return tc.getStaticExecutableFlags(proptools.Bool(l.Properties.Static_pie))
+}
+
+// checkStaticExecutableMutator checks that statically linked binaries
+// don't use shared libraries. This runs after the shared_libs of static
+// libraries have been propagated to the binaries which use them, so
+// that shared libraries pulled in through a static library are found.
+func checkStaticExecutableMutator(mctx blueprint.BottomUpMutatorContext) {
+	b, ok := mctx.Module().(*binary)
+	if !ok || !isEnabled(b) {
+		return
+	}
+
+	property := b.staticLinkProperty()
+	if property == "" {
+		return
+	}
+
+	if proptools.Bool(b.Properties.Static_pie) {
+		if proptools.Bool(b.Properties.Static_executable) {
+			mctx.PropertyErrorf("static_pie", "can't be used with static_executable, "+
+				"as it already links statically")
+		}
+		if !getConfig(mctx).Properties.GetBool("builder_ninja") {
+			mctx.PropertyErrorf("static_pie", "is only supported by the Linux backend")
+		}
+	}
+
+	if len(b.Properties.Shared_libs) > 0 {
+		mctx.PropertyErrorf(property, "the binary can't link with shared libraries, "+
+			"but links with %s. Use static libraries instead, and check the "+
+			"shared_libs of the static libraries it uses",
+			strings.Join(b.Properties.Shared_libs, ", "))
+	}
+}
@@ -0,0 +1,270 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+var (
+	statsOut        string
+	statsLargestNum int
+)
+
+func init() {
+	flag.StringVar(&statsOut, "stats-out", "",
+		"Output file name for module and generation statistics. No build files are written")
+	flag.IntVar(&statsLargestNum, "stats-largest", 20,
+		"Number of modules to list when reporting the largest modules by source count")
+}
+
+// moduleSize records how many sources a module variant has
+type moduleSize struct {
+	Name    string `json:"name"`
+	Variant string `json:"variant,omitempty"`
+	Type    string `json:"type"`
+	Sources int    `json:"sources"`
+}
+
+// phaseTime records how long a phase of generation took
+type phaseTime struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// statsReport is the content of the statistics file
+type statsReport struct {
+	Modules         int            `json:"modules"`
+	EnabledModules  int            `json:"enabled_modules"`
+	ModulesByType   map[string]int `json:"modules_by_type"`
+	EdgesByKind     map[string]int `json:"edges_by_kind"`
+	LargestModules  []moduleSize   `json:"largest_modules"`
+	GenerationTimes []phaseTime    `json:"generation_times"`
+
+	// Time spent in each mutator, summed over all modules. Mutators
+	// running in parallel may therefore exceed the elapsed time.
+	MutatorTimes []phaseTime `json:"mutator_times"`
+}
+
+// statsHandler gathers statistics while Bob generates the build, and
+// writes them out once all modules have been processed.
+type statsHandler struct {
+	out   string
+	start time.Time
+
+	lock          sync.Mutex
+	report        statsReport
+	sizes         []moduleSize
+	mutatorOrder  []string
+	mutatorTimes  map[string]time.Duration
+	configLoaded  time.Time
+	mutatorsStart time.Time
+	mutatorsEnd   time.Time
+}
+
+func initStatsHandler(start time.Time) *statsHandler {
+	if statsOut == "" {
+		return nil
+	}
+
+	return &statsHandler{
+		out:   statsOut,
+		start: start,
+		report: statsReport{
+			ModulesByType: map[string]int{},
+			EdgesByKind:   map[string]int{},
+		},
+		mutatorTimes: map[string]time.Duration{},
+	}
+}
+
+func (h *statsHandler) setConfigLoaded() {
+	if h != nil {
+		h.configLoaded = time.Now()
+	}
+}
+
+func (h *statsHandler) addMutatorTime(name string, start time.Time) {
+	end := time.Now()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.mutatorsStart.IsZero() || start.Before(h.mutatorsStart) {
+		h.mutatorsStart = start
+	}
+	if end.After(h.mutatorsEnd) {
+		h.mutatorsEnd = end
+	}
+	h.mutatorTimes[name] += end.Sub(start)
+}
+
+func (h *statsHandler) registerMutatorName(name string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.mutatorOrder = append(h.mutatorOrder, name)
+}
+
+// timeBottomUpMutator wraps a mutator so that the time spent in it is
+// recorded. When statistics are not enabled, the mutator is returned as is.
+func (h *statsHandler) timeBottomUpMutator(name string,
+	mutator blueprint.BottomUpMutator) blueprint.BottomUpMutator {
+	if h == nil {
+		return mutator
+	}
+	h.registerMutatorName(name)
+	return func(mctx blueprint.BottomUpMutatorContext) {
+		defer h.addMutatorTime(name, time.Now())
+		mutator(mctx)
+	}
+}
+
+// timeTopDownMutator is the top down equivalent of timeBottomUpMutator
+func (h *statsHandler) timeTopDownMutator(name string,
+	mutator blueprint.TopDownMutator) blueprint.TopDownMutator {
+	if h == nil {
+		return mutator
+	}
+	h.registerMutatorName(name)
+	return func(mctx blueprint.TopDownMutatorContext) {
+		defer h.addMutatorTime(name, time.Now())
+		mutator(mctx)
+	}
+}
+
+// statsMutator records the type, dependencies and size of each module
+func (h *statsHandler) statsMutator(mctx blueprint.BottomUpMutatorContext) {
+	m := mctx.Module()
+
+	enabled := true
+	if e, ok := m.(enableable); ok {
+		enabled = isEnabled(e)
+	}
+
+	edges := map[string]int{}
+	mctx.VisitDirectDeps(func(dep blueprint.Module) {
+		kind := "other"
+		if tag, ok := mctx.OtherModuleDependencyTag(dep).(dependencyTag); ok {
+			kind = tag.name
+		}
+		edges[kind]++
+	})
+
+	size := moduleSize{Name: mctx.ModuleName(), Type: mctx.ModuleType(), Sources: -1}
+	if s, ok := m.(splittable); ok {
+		size.Variant = string(s.getTarget())
+	}
+	if s, ok := m.(matchSourceInterface); ok {
+		if _, isDefaults := m.(*defaults); !isDefaults {
+			size.Sources = len(s.getSourceProperties().getSources(mctx))
+		}
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.report.Modules++
+	if enabled {
+		h.report.EnabledModules++
+	}
+	h.report.ModulesByType[size.Type]++
+	for kind, count := range edges {
+		h.report.EdgesByKind[kind] += count
+	}
+	if size.Sources >= 0 {
+		h.sizes = append(h.sizes, size)
+	}
+}
+
+func secondsSince(start, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start).Seconds()
+}
+
+func (h *statsHandler) writeReport() {
+	now := time.Now()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	sort.SliceStable(h.sizes, func(i, j int) bool {
+		if h.sizes[i].Sources != h.sizes[j].Sources {
+			return h.sizes[i].Sources > h.sizes[j].Sources
+		}
+		if h.sizes[i].Name != h.sizes[j].Name {
+			return h.sizes[i].Name < h.sizes[j].Name
+		}
+		return h.sizes[i].Variant < h.sizes[j].Variant
+	})
+	if len(h.sizes) > statsLargestNum {
+		h.sizes = h.sizes[:statsLargestNum]
+	}
+	h.report.LargestModules = h.sizes
+
+	h.report.GenerationTimes = []phaseTime{
+		{"load_config", secondsSince(h.start, h.configLoaded)},
+		{"parse_build_files", secondsSince(h.configLoaded, h.mutatorsStart)},
+		{"mutators", secondsSince(h.mutatorsStart, h.mutatorsEnd)},
+		{"build_actions", secondsSince(h.mutatorsEnd, now)},
+		{"total", secondsSince(h.start, now)},
+	}
+
+	h.report.MutatorTimes = []phaseTime{}
+	for _, name := range h.mutatorOrder {
+		h.report.MutatorTimes = append(h.report.MutatorTimes,
+			phaseTime{name, h.mutatorTimes[name].Seconds()})
+	}
+
+	content, err := json.MarshalIndent(h.report, "", "  ")
+	if err != nil {
+		utils.Die("%v", err)
+	}
+	err = ioutil.WriteFile(h.out, append(content, '\n'), 0644)
+	if err != nil {
+		utils.Die("%v", err)
+	}
+
+	fmt.Printf("Wrote statistics for %d modules to %s\n", h.report.Modules, h.out)
+}
+
+type statsSingleton struct {
+	handler *statsHandler
+}
+
+func (s *statsSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	s.handler.writeReport()
+	// As with the graphviz output, don't overwrite the build files
+	os.Exit(0)
+}
+
+func (h *statsHandler) statsSingletonFactory() blueprint.Singleton {
+	return &statsSingleton{h}
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// The properties STRICT_PATHS checks for absolute paths
+var strictPathProperties = []string{
+	"Srcs",
+	"Include_dirs",
+	"Export_include_dirs",
+	"Ldflags",
+	"Export_ldflags",
+}
+
+func strictPathsEnabled(props *configProperties) bool {
+	_, ok := props.properties["strict_paths"]
+	return ok && props.GetBool("strict_paths")
+}
+
+// strictPathsAllowed returns whether STRICT_PATHS_ALLOWLIST lets a
+// module use absolute paths
+func strictPathsAllowed(props *configProperties, name string) bool {
+	value, ok := props.properties["strict_paths_allowlist"]
+	if !ok {
+		return false
+	}
+	return utils.Contains(strings.Fields(value.(string)), name)
+}
+
+// absolutePathIn returns the absolute path a property value refers to,
+// if any. The value may be a path, or a flag containing one, such as
+// -L/usr/lib or -Wl,--version-script=/tmp/lib.map.
+func absolutePathIn(value string) (string, bool) {
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '=' })
+	for _, part := range parts {
+		for _, prefix := range []string{"-isystem", "-I", "-L"} {
+			if strings.HasPrefix(part, prefix) {
+				part = strings.TrimPrefix(part, prefix)
+				break
+			}
+		}
+		if filepath.IsAbs(part) {
+			return part, true
+		}
+	}
+	return "", false
+}
+
+// findAbsolutePaths calls 'report' for each absolute path in the
+// properties listed in strictPathProperties, looking inside embedded
+// property structures
+func findAbsolutePaths(propsVal reflect.Value, report func(property, path string)) {
+	propsType := propsVal.Type()
+	for i := 0; i < propsVal.NumField(); i++ {
+		field := propsVal.Field(i)
+		fieldType := propsType.Field(i)
+
+		if fieldType.Anonymous && field.Kind() == reflect.Struct {
+			findAbsolutePaths(field, report)
+		} else if field.Kind() == reflect.Slice &&
+			utils.Contains(strictPathProperties, fieldType.Name) {
+			for j := 0; j < field.Len(); j++ {
+				if path, ok := absolutePathIn(field.Index(j).String()); ok {
+					report(strings.ToLower(fieldType.Name), path)
+				}
+			}
+		}
+	}
+}
+
+// strictPathsMutator rejects absolute paths with STRICT_PATHS, so that
+// paths specific to one host can't leak into build definitions shared
+// between hosts. It runs after templates and target specific properties
+// are applied, so that paths from the configuration are checked too.
+func strictPathsMutator(mctx blueprint.TopDownMutatorContext) {
+	props := &getConfig(mctx).Properties
+	if !strictPathsEnabled(props) || strictPathsAllowed(props, mctx.ModuleName()) {
+		return
+	}
+
+	module := mctx.Module()
+	if _, ok := module.(*defaults); ok {
+		return
+	}
+	if e, ok := module.(enableable); ok && !isEnabled(e) {
+		return
+	}
+	m, ok := module.(featurable)
+	if !ok {
+		return
+	}
+
+	for _, p := range m.featurableProperties() {
+		findAbsolutePaths(reflect.Indirect(reflect.ValueOf(p)), func(property, path string) {
+			mctx.PropertyErrorf(property, "absolute path %s is not allowed with STRICT_PATHS. "+
+				"Add %s to STRICT_PATHS_ALLOWLIST if it is needed", path, mctx.ModuleName())
+		})
+	}
+}
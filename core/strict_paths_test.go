@@ -0,0 +1,73 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_absolutePathIn(t *testing.T) {
+	tests := map[string]string{
+		"src/main.c":                        "",
+		"/usr/include":                      "/usr/include",
+		"-L/opt/lib":                        "/opt/lib",
+		"-lfoo":                             "",
+		"-isystem/opt/include":              "/opt/include",
+		"-Wl,--version-script=/tmp/lib.map": "/tmp/lib.map",
+		"-Wl,-rpath,/opt/lib":               "/opt/lib",
+		"-Wl,--as-needed":                   "",
+		"{{.srcdir}}/include":               "",
+	}
+
+	for value, expected := range tests {
+		path, ok := absolutePathIn(value)
+		assert.Equal(t, expected != "", ok, value)
+		assert.Equal(t, expected, path, value)
+	}
+}
+
+func Test_findAbsolutePaths(t *testing.T) {
+	type embedded struct {
+		Srcs []string
+	}
+	props := struct {
+		embedded
+		Include_dirs []string
+		Cflags       []string
+		Ldflags      []string
+	}{
+		embedded:     embedded{Srcs: []string{"main.c", "/home/user/extra.c"}},
+		Include_dirs: []string{"include", "/usr/local/include"},
+		Cflags:       []string{"-I/not/checked"},
+		Ldflags:      []string{"-L/opt/lib", "-lfoo"},
+	}
+
+	found := map[string][]string{}
+	findAbsolutePaths(reflect.ValueOf(props), func(property, path string) {
+		found[property] = append(found[property], path)
+	})
+
+	assert.Equal(t, map[string][]string{
+		"srcs":         {"/home/user/extra.c"},
+		"include_dirs": {"/usr/local/include"},
+		"ldflags":      {"/opt/lib"},
+	}, found)
+}
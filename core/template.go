@@ -19,8 +19,11 @@ package core
 
 import (
 	"bytes"
+	"fmt"
+	"os"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -28,12 +31,20 @@ import (
 )
 
 func applyTemplateString(elem reflect.Value, stringvalues map[string]string, funcmap map[string]interface{}) {
+	applyTemplateStringMissingKey(elem, stringvalues, funcmap, "error")
+}
+
+// applyTemplateStringMissingKey applies a template, using the text/template
+// missingkey option to choose what happens when a configuration value is
+// referenced which does not exist.
+func applyTemplateStringMissingKey(elem reflect.Value, stringvalues map[string]string,
+	funcmap map[string]interface{}, missingkey string) {
 	if elem.Kind() != reflect.String {
 		utils.Die("elem is not a string")
 	}
 
 	t := template.New("TemplateProps")
-	t.Option("missingkey=error")
+	t.Option("missingkey=" + missingkey)
 	t.Funcs(funcmap)
 
 	tmpl, err := t.Parse(elem.String())
@@ -48,35 +59,70 @@ func applyTemplateString(elem reflect.Value, stringvalues map[string]string, fun
 	elem.SetString(buf.String())
 }
 
-func applyTemplateRecursive(propsVal reflect.Value,
-	stringvalues map[string]string, funcmap map[string]interface{}) {
+func applyTemplateRecursive(propsVal reflect.Value, stringvalues map[string]string,
+	funcmap map[string]interface{}, missingkeys map[string]string) {
 
 	for i := 0; i < propsVal.NumField(); i++ {
 		field := propsVal.Field(i)
+		missingkey, ok := missingkeys[propsVal.Type().Field(i).Name]
+		if !ok {
+			missingkey = "error"
+		}
 
 		switch field.Kind() {
 		case reflect.String:
-			applyTemplateString(field, stringvalues, funcmap)
+			applyTemplateStringMissingKey(field, stringvalues, funcmap, missingkey)
 
 		case reflect.Slice:
 			// Array of strings
 			for j := 0; j < field.Len(); j++ {
 				elem := field.Index(j)
 				if elem.Kind() == reflect.String {
-					applyTemplateString(elem, stringvalues, funcmap)
+					applyTemplateStringMissingKey(elem, stringvalues, funcmap, missingkey)
 				}
 			}
 
 		case reflect.Ptr:
 			tgtField := reflect.Indirect(field)
 			if tgtField.Kind() == reflect.String {
-				applyTemplateString(tgtField, stringvalues, funcmap)
+				applyTemplateStringMissingKey(tgtField, stringvalues, funcmap, missingkey)
 			}
 
 		case reflect.Struct:
-			applyTemplateRecursive(field, stringvalues, funcmap)
+			applyTemplateRecursive(field, stringvalues, funcmap, missingkeys)
+		}
+	}
+}
+
+// templateMissingKeys reads TEMPLATE_MISSINGKEY, which lists properties
+// as property=behaviour, where the behaviour is error (the default) or
+// zero, to expand missing configuration values to an empty string. The
+// result is keyed by field name.
+func templateMissingKeys(properties *configProperties) map[string]string {
+	missingkeys := map[string]string{}
+	value, ok := properties.properties["template_missingkey"]
+	if !ok {
+		return missingkeys
+	}
+	for _, entry := range strings.Fields(value.(string)) {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || (parts[1] != "error" && parts[1] != "zero") {
+			utils.Die("Invalid TEMPLATE_MISSINGKEY entry '%s', expected property=error or property=zero",
+				entry)
 		}
+		missingkeys[featurePropertyName(parts[0])] = parts[1]
+	}
+	return missingkeys
+}
+
+// templateEnvAllowed returns whether TEMPLATE_ENV_ALLOWLIST lets
+// templates read an environment variable.
+func templateEnvAllowed(properties *configProperties, name string) bool {
+	value, ok := properties.properties["template_env_allowlist"]
+	if !ok {
+		return false
 	}
+	return utils.Contains(strings.Fields(value.(string)), name)
 }
 
 func regMatch(rule string, input string) bool {
@@ -89,17 +135,48 @@ func regReplace(rule string, input string, replace string) string {
 	return re.ReplaceAllString(input, replace)
 }
 
-func matchSrcs(input string) string {
-	return "{{match_srcs \"" + input + "\"}}"
+func matchSrcs(input string, opts ...string) string {
+	args := []string{strconv.Quote(input)}
+	for _, opt := range opts {
+		args = append(args, strconv.Quote(opt))
+	}
+	return "{{match_srcs " + strings.Join(args, " ") + "}}"
 }
 
 func filter_compiler_flags(flag string) string {
 	return "{{add_if_supported \"" + flag + "\"}}"
 }
 
+// filterOut removes the space separated words in 'input' which match one
+// of the space separated patterns. As in Make, a pattern may contain a
+// '%', which matches any part of a word.
+func filterOut(patterns string, input string) string {
+	words := []string{}
+	for _, word := range strings.Fields(input) {
+		matched := false
+		for _, pattern := range strings.Fields(patterns) {
+			if parts := strings.SplitN(pattern, "%", 2); len(parts) == 2 {
+				matched = len(word) >= len(parts[0])+len(parts[1]) &&
+					strings.HasPrefix(word, parts[0]) && strings.HasSuffix(word, parts[1])
+			} else {
+				matched = word == pattern
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
 // ApplyTemplate writes configuration values (from properties) into the string
-// properties in props. This is done recursively.
-func ApplyTemplate(props interface{}, properties *configProperties) {
+// properties in props. This is done recursively. The files the build must be
+// regenerated on, when the templates read values from outside the
+// configuration, are returned.
+func ApplyTemplate(props interface{}, properties *configProperties) (deps []string) {
 	stringvalues := properties.StringMap()
 	funcmap := make(map[string]interface{})
 	funcmap["to_upper"] = strings.ToUpper
@@ -109,7 +186,42 @@ func ApplyTemplate(props interface{}, properties *configProperties) {
 	funcmap["reg_replace"] = regReplace
 	funcmap["match_srcs"] = matchSrcs
 	funcmap["add_if_supported"] = filter_compiler_flags
+	funcmap["dep_outputs"] = depOutputsTemplate
+	funcmap["join"] = strings.Join
+	funcmap["filter_out"] = filterOut
+	funcmap["default"] = func(key, fallback string) string {
+		if value := stringvalues[key]; value != "" {
+			return value
+		}
+		return fallback
+	}
+	funcmap["env"] = func(name string) (string, error) {
+		if !templateEnvAllowed(properties, name) {
+			return "", fmt.Errorf("environment variable %s is not in TEMPLATE_ENV_ALLOWLIST", name)
+		}
+		return os.Getenv(name), nil
+	}
+	git := func() (*gitMetadata, error) {
+		g, err := sourceGitMetadata.get()
+		if err == nil {
+			deps = g.deps
+		}
+		return g, err
+	}
+	funcmap["git_describe"] = func() (string, error) {
+		g, err := git()
+		return g.describe, err
+	}
+	funcmap["git_commit"] = func() (string, error) {
+		g, err := git()
+		return g.commit, err
+	}
+	funcmap["git_dirty"] = func() (bool, error) {
+		g, err := git()
+		return g.dirty, err
+	}
 	propsVal := reflect.Indirect(reflect.ValueOf(props))
 
-	applyTemplateRecursive(propsVal, stringvalues, funcmap)
+	applyTemplateRecursive(propsVal, stringvalues, funcmap, templateMissingKeys(properties))
+	return
 }
@@ -18,6 +18,7 @@
 package core
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -175,3 +176,80 @@ func TestApplyTemplateNested(t *testing.T) {
 	assert.Equalf(t, "alpha1", refA, "refA incorrect")
 	assert.Equalf(t, "beta0", refB, "refB incorrect")
 }
+
+type testFuncProperties struct {
+	Cflags  []string
+	Ldflags []string
+	Out     *string
+}
+
+// Check the functions computing strings from configuration values
+func TestApplyTemplateFunctions(t *testing.T) {
+	config := setupTestConfig(map[string]string{
+		"opt_flags": "-O2 -g -fno-omit-frame-pointer -Werror",
+		"empty":     "",
+		"dirs":      "a,b,c",
+	})
+	config.properties = map[string]interface{}{
+		"template_env_allowlist": "BOB_TEMPLATE_TEST_ENV",
+		"template_missingkey":    "ldflags=zero",
+	}
+	os.Setenv("BOB_TEMPLATE_TEST_ENV", "from_env")
+	defer os.Unsetenv("BOB_TEMPLATE_TEST_ENV")
+
+	out := `{{default "out_name" "libfoo"}}`
+	props := testFuncProperties{
+		Cflags: []string{
+			`{{filter_out "-Werror -f%" .opt_flags}}`,
+			`{{default "empty" "-DFALLBACK"}}`,
+			`-I{{join (split .dirs ",") " -I"}}`,
+			`-DVALUE={{env "BOB_TEMPLATE_TEST_ENV"}}`,
+		},
+		Ldflags: []string{"{{.missing}}"},
+		Out:     &out,
+	}
+	ApplyTemplate(&props, config)
+
+	assert.Equal(t, []string{"-O2 -g", "-DFALLBACK", "-Ia -Ib -Ic", "-DVALUE=from_env"}, props.Cflags)
+	assert.Equal(t, []string{""}, props.Ldflags)
+	assert.Equal(t, "libfoo", out)
+}
+
+func TestTemplateEnvAllowed(t *testing.T) {
+	config := setupTestConfig(map[string]string{})
+	assert.False(t, templateEnvAllowed(config, "HOME"))
+
+	config.properties = map[string]interface{}{"template_env_allowlist": "PATH HOME"}
+	assert.True(t, templateEnvAllowed(config, "HOME"))
+	assert.False(t, templateEnvAllowed(config, "USER"))
+}
+
+func TestFilterOut(t *testing.T) {
+	assert.Equal(t, "b d", filterOut("a c", "a b c d"))
+	assert.Equal(t, "-O2", filterOut("-W%", "-Wall -O2 -Wextra"))
+	assert.Equal(t, "-W", filterOut("-W%all", "-W -Wall"))
+}
+
+func TestApplyTemplateDepOutputs(t *testing.T) {
+	config := setupTestConfig(map[string]string{"tool": "gen_tool"})
+
+	// dep_outputs is kept for the late template
+	props := testFuncProperties{
+		Ldflags: []string{`-Wl,--script={{dep_outputs .tool}}`},
+	}
+	ApplyTemplate(&props, config)
+
+	assert.Equal(t, []string{`-Wl,--script={{dep_outputs "gen_tool"}}`}, props.Ldflags)
+}
+
+func TestApplyTemplateMatchSrcsOptions(t *testing.T) {
+	config := setupTestConfig(map[string]string{})
+
+	// match_srcs is kept for the late template, with its options
+	props := testFuncProperties{
+		Ldflags: []string{`{{match_srcs "{a,b}.lds *.ld" "optional" "root=ld"}}`},
+	}
+	ApplyTemplate(&props, config)
+
+	assert.Equal(t, []string{`{{match_srcs "{a,b}.lds *.ld" "optional" "root=ld"}}`}, props.Ldflags)
+}
@@ -25,6 +25,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ARM-software/bob-build/internal/utils"
 )
@@ -37,17 +38,42 @@ type linker interface {
 	dropUnusedDependencies() string
 	setRpathLink(string) string
 	setVersionScript(string) string
+	setMapFile(string) string
+	gcSections() string
+	keepSymbol(string) string
 	setRpath([]string) string
 	linkWholeArchives([]string) string
+	linkGroup([]string) string
+	setLtoCacheDir(string) string
 	keepSharedLibraryTransitivity() string
 	dropSharedLibraryTransitivity() string
 	getForwardingLibFlags() string
 }
 
+// The linkers which the compiler can be told to use with -fuse-ld
+var selectableLinkers = []string{"bfd", "gold", "lld", "mold"}
+
+// selectedLinker returns the linker selected by LINKER for a target
+// type, or "" to use the compiler's default.
+func selectedLinker(config *bobConfig, tgt tgtType) string {
+	props := config.Properties
+	if _, ok := props.properties["linker"]; !ok {
+		return ""
+	}
+	ld := props.GetTargetString(tgt, "linker")
+	if ld != "" && !utils.Contains(selectableLinkers, ld) {
+		utils.Die("%s_LINKER: unknown linker '%s', expected one of %s",
+			strings.ToUpper(string(tgt)), ld, strings.Join(selectableLinkers, ", "))
+	}
+	return ld
+}
+
 type defaultLinker struct {
 	tool  string
 	flags []string
 	libs  []string
+	// The linker passed to -fuse-ld, if any
+	ld string
 }
 
 func (l defaultLinker) getTool() string {
@@ -55,7 +81,10 @@ func (l defaultLinker) getTool() string {
 }
 
 func (l defaultLinker) getFlags() []string {
-	return l.flags
+	if l.ld == "" {
+		return l.flags
+	}
+	return utils.NewStringSlice(l.flags, []string{"-fuse-ld=" + l.ld})
 }
 
 func (l defaultLinker) getLibs() []string {
@@ -78,7 +107,12 @@ func (l defaultLinker) dropSharedLibraryTransitivity() string {
 	return "-Wl,--no-copy-dt-needed-entries"
 }
 
+// Only bfd supports --copy-dt-needed-entries, so it is used to link
+// against forwarding libraries, whichever linker is selected.
 func (l defaultLinker) getForwardingLibFlags() string {
+	if l.ld == "bfd" {
+		return ""
+	}
 	return "-fuse-ld=bfd"
 }
 
@@ -90,6 +124,18 @@ func (l defaultLinker) setVersionScript(path string) string {
 	return "-Wl,--version-script," + path
 }
 
+func (l defaultLinker) setMapFile(path string) string {
+	return "-Wl,-Map=" + path
+}
+
+func (l defaultLinker) gcSections() string {
+	return "-Wl,--gc-sections"
+}
+
+func (l defaultLinker) keepSymbol(symbol string) string {
+	return "-Wl,--undefined=" + symbol
+}
+
 func (l defaultLinker) setRpath(paths []string) string {
 	if len(paths) == 0 {
 		return ""
@@ -109,8 +155,21 @@ func (l defaultLinker) linkWholeArchives(libs []string) string {
 	return fmt.Sprintf("-Wl,--whole-archive %s -Wl,--no-whole-archive", utils.Join(libs))
 }
 
-func newDefaultLinker(tool string, flags, libs []string) (linker defaultLinker) {
+// Only ThinLTO has a cache, so GCC and armlink have no option for it
+func (l defaultLinker) setLtoCacheDir(dir string) string {
+	return ""
+}
+
+func (l defaultLinker) linkGroup(libs []string) string {
+	if len(libs) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("-Wl,--start-group %s -Wl,--end-group", utils.Join(libs))
+}
+
+func newDefaultLinker(tool, ld string, flags, libs []string) (linker defaultLinker) {
 	linker.tool = tool
+	linker.ld = ld
 	linker.flags = flags
 	linker.libs = libs
 	return
@@ -118,12 +177,27 @@ func newDefaultLinker(tool string, flags, libs []string) (linker defaultLinker)
 
 type toolchain interface {
 	getArchiver() (tool string, flags []string)
+	// The modifiers of the archiver which write thin and
+	// deterministic archives. Empty when the archiver has none.
+	getArchiverModifiers() (thin, deterministic string)
 	getAssembler() (tool string, flags []string)
 	getCCompiler() (tool string, flags []string)
 	getCXXCompiler() (tool string, flags []string)
 	getLinker() linker
 	getStripFlags() []string
 	getLibraryTocFlags() []string
+	// The objcopy used to convert binaries to other formats. Empty
+	// when the toolchain has none.
+	getObjcopy() string
+	// The nm and size used for the code size report. Empty when the
+	// toolchain has none.
+	getSizeTools() (nm, size string)
+	// The C++ compiler and linker flags selecting the C++ standard
+	// library named by a module's stl property
+	getStlFlags(stl string) (cxxflags, ldflags []string, err error)
+	// The compiler and linker flags of a statically linked
+	// executable, which is position independent when pie is set
+	getStaticExecutableFlags(pie bool) (cflags, ldflags []string, err error)
 	checkFlagIsSupported(language, flag string) bool
 }
 
@@ -261,6 +335,19 @@ func (cache *flagSupportedCache) checkFlag(tc toolchain, language, flag string)
 		return supported
 	}
 
+	// Check whether a previous generation has already probed the flag.
+	// The compiler flags are part of the key here, as the toolchains
+	// sharing the on-disk cache may pass different flags to the same
+	// compiler.
+	persistentKey := strings.Join(append([]string{flag, language}, flags...), "/")
+	persistentCache := getPersistentFlagCache()
+	if supported, ok := persistentCache.lookup(compiler, persistentKey); ok {
+		cache.lock.Lock()
+		cache.m[key] = supported
+		cache.lock.Unlock()
+		return supported
+	}
+
 	// We have not seen the flag before, check it by running the compiler with the flag
 	// Add a '-Werror' to make sure that the compiler exits with an error code if the
 	// flag is unknown. If the flag starts with '-Wno-' remove the 'no-' part so that
@@ -270,11 +357,14 @@ func (cache *flagSupportedCache) checkFlag(tc toolchain, language, flag string)
 	testFlags := utils.NewStringSlice(flags, []string{"-x", language, "-c", os.DevNull, "-o", os.DevNull, "-Werror", saneFlag})
 	testFlags = utils.Remove(testFlags, "")
 	cmd := exec.Command(compiler, testFlags...)
+	start := time.Now()
 	_, err := cmd.CombinedOutput()
+	generationTrace.addFlagProbe(compiler, language, flag, start, err == nil)
 	if err == nil {
 		cache.lock.Lock()
 		cache.m[key] = true
 		cache.lock.Unlock()
+		persistentCache.store(compiler, persistentKey, true)
 		return true
 	}
 
@@ -282,6 +372,7 @@ func (cache *flagSupportedCache) checkFlag(tc toolchain, language, flag string)
 	cache.lock.Lock()
 	cache.m[key] = false
 	cache.lock.Unlock()
+	persistentCache.store(compiler, persistentKey, false)
 
 	return false
 }
@@ -298,6 +389,8 @@ type toolchainGnuCommon struct {
 	asBinary      string
 	objcopyBinary string
 	objdumpBinary string
+	nmBinary      string
+	sizeBinary    string
 	gccBinary     string
 	gxxBinary     string
 	linker        linker
@@ -320,6 +413,10 @@ func (tc toolchainGnuCommon) getArchiver() (string, []string) {
 	return tc.arBinary, []string{}
 }
 
+func (tc toolchainGnuCommon) getArchiverModifiers() (string, string) {
+	return "T", "D"
+}
+
 func (tc toolchainGnuCommon) getAssembler() (string, []string) {
 	return tc.asBinary, []string{}
 }
@@ -343,6 +440,14 @@ func (tc toolchainGnuCommon) getStripFlags() []string {
 	}
 }
 
+func (tc toolchainGnuCommon) getObjcopy() string {
+	return tc.objcopyBinary
+}
+
+func (tc toolchainGnuCommon) getSizeTools() (string, string) {
+	return tc.nmBinary, tc.sizeBinary
+}
+
 func (tc toolchainGnuCommon) getLibraryTocFlags() []string {
 	return []string{
 		"--format", "elf",
@@ -358,6 +463,27 @@ func (tc toolchainGnuCommon) checkFlagIsSupported(language, flag string) bool {
 	return tc.flagCache.checkFlag(tc, language, flag)
 }
 
+// GCC only ships libstdc++. Disabling it at link time requires GCC 13.
+func (tc toolchainGnuCommon) getStlFlags(stl string) ([]string, []string, error) {
+	switch stl {
+	case "libstdc++":
+		return []string{}, []string{}, nil
+	case "static":
+		return []string{}, []string{"-static-libstdc++"}, nil
+	case "none":
+		return []string{"-nostdinc++"}, []string{"-nostdlib++"}, nil
+	}
+	return nil, nil, fmt.Errorf("%s is not supported by GCC", stl)
+}
+
+// -static-pie requires GCC 8, and a C library with support for it
+func (tc toolchainGnuCommon) getStaticExecutableFlags(pie bool) ([]string, []string, error) {
+	if pie {
+		return []string{"-fPIE"}, []string{"-static-pie"}, nil
+	}
+	return []string{}, []string{"-static"}, nil
+}
+
 // The libstdc++ headers shipped with GCC toolchains are stored, relative to
 // the `prefix-gcc` binary's location, in `../$ARCH/include/c++/$VERSION` and
 // `../$ARCH/include/c++/$VERSION/$ARCH`. This function returns $ARCH. This is
@@ -438,10 +564,12 @@ func newToolchainGnuCommon(config *bobConfig, tgt tgtType) (tc toolchainGnuCommo
 	props := config.Properties
 	tc.prefix = props.GetString(string(tgt) + "_gnu_prefix")
 	tc.arBinary = props.GetString(string(tgt) + "_ar_binary")
-	tc.asBinary = tc.prefix + props.GetString("as_binary")
+	tc.asBinary = tc.prefix + props.GetTargetString(tgt, "as_binary")
 
 	tc.objcopyBinary = props.GetString(string(tgt) + "_objcopy_binary")
 	tc.objdumpBinary = props.GetString(string(tgt) + "_objdump_binary")
+	tc.nmBinary = props.GetString(string(tgt) + "_nm_binary")
+	tc.sizeBinary = props.GetString(string(tgt) + "_size_binary")
 
 	tc.gccBinary = tc.prefix + props.GetString(string(tgt)+"_gnu_cc_binary")
 	tc.gxxBinary = tc.prefix + props.GetString(string(tgt)+"_gnu_cxx_binary")
@@ -457,7 +585,7 @@ func newToolchainGnuCommon(config *bobConfig, tgt tgtType) (tc toolchainGnuCommo
 	tc.cflags = append(tc.cflags, flags...)
 	tc.ldflags = append(tc.ldflags, flags...)
 
-	tc.linker = newDefaultLinker(tc.gxxBinary, tc.ldflags, []string{})
+	tc.linker = newDefaultLinker(tc.gxxBinary, selectedLinker(config, tgt), tc.ldflags, []string{})
 	tc.flagCache = newFlagCache()
 
 	return
@@ -473,15 +601,55 @@ func newToolchainGnuCross(config *bobConfig) (tc toolchainGnuCross) {
 	return
 }
 
+// hostCrossLinker is the linker of the host cross toolchain. The host
+// OS it builds for need not use ELF, so the rpath options are dropped.
+type hostCrossLinker struct {
+	defaultLinker
+}
+
+func (l hostCrossLinker) setRpathLink(path string) string {
+	return ""
+}
+
+func (l hostCrossLinker) setRpath(paths []string) string {
+	return ""
+}
+
+// The host cross toolchain is always a GNU cross compiler, e.g. MinGW.
+// Other compilers can be selected with its toolchain file.
+func newToolchainGnuHostCross(config *bobConfig) (tc toolchainGnuCross) {
+	tc.toolchainGnuCommon = newToolchainGnuCommon(config, tgtTypeHostCross)
+	tc.linker = hostCrossLinker{tc.linker.(defaultLinker)}
+	return
+}
+
+// clangLinker is the linker used by Clang, which can cache the code
+// generated by ThinLTO.
+type clangLinker struct {
+	defaultLinker
+}
+
+// lld has its own option for the ThinLTO cache, while the other
+// linkers pass it to the LLVM gold plugin.
+func (l clangLinker) setLtoCacheDir(dir string) string {
+	if l.ld == "lld" {
+		return "-Wl,--thinlto-cache-dir=" + dir
+	}
+	return "-Wl,-plugin-opt,cache-dir=" + dir
+}
+
 type toolchainClangCommon struct {
 	// Options read from the config:
 	arBinary       string
 	asBinary       string
 	objcopyBinary  string
 	objdumpBinary  string
+	nmBinary       string
+	sizeBinary     string
 	clangBinary    string
 	clangxxBinary  string
 	linker         linker
+	ld             string
 	prefix         string
 	useGnuBinutils bool
 
@@ -514,6 +682,11 @@ func (tc toolchainClangCommon) getArchiver() (string, []string) {
 	return tc.arBinary, []string{}
 }
 
+// llvm-ar uses the same modifiers as GNU ar
+func (tc toolchainClangCommon) getArchiverModifiers() (string, string) {
+	return "T", "D"
+}
+
 func (tc toolchainClangCommon) getAssembler() (string, []string) {
 	if tc.useGnuBinutils {
 		return tc.gnu.getAssembler()
@@ -530,7 +703,7 @@ func (tc toolchainClangCommon) getCXXCompiler() (string, []string) {
 }
 
 func (tc toolchainClangCommon) getLinker() linker {
-	return newDefaultLinker(tc.clangxxBinary, tc.ldflags, tc.ldlibs)
+	return clangLinker{newDefaultLinker(tc.clangxxBinary, tc.ld, tc.ldflags, tc.ldlibs)}
 }
 
 func (tc toolchainClangCommon) getStripFlags() []string {
@@ -540,6 +713,14 @@ func (tc toolchainClangCommon) getStripFlags() []string {
 	}
 }
 
+func (tc toolchainClangCommon) getObjcopy() string {
+	return tc.objcopyBinary
+}
+
+func (tc toolchainClangCommon) getSizeTools() (string, string) {
+	return tc.nmBinary, tc.sizeBinary
+}
+
 func (tc toolchainClangCommon) getLibraryTocFlags() []string {
 	return []string{
 		"--format", "elf",
@@ -551,6 +732,28 @@ func (tc toolchainClangCommon) checkFlagIsSupported(language, flag string) bool
 	return tc.flagCache.checkFlag(tc, language, flag)
 }
 
+// Selecting a library overrides the one from clang_stl_library, as the
+// module's flags come later on the command line.
+func (tc toolchainClangCommon) getStlFlags(stl string) ([]string, []string, error) {
+	switch stl {
+	case "libc++", "libstdc++":
+		return []string{"--stdlib=" + stl}, []string{"--stdlib=" + stl}, nil
+	case "static":
+		return []string{}, []string{"-static-libstdc++"}, nil
+	case "none":
+		return []string{"-nostdinc++"}, []string{"-nostdlib++"}, nil
+	}
+	return nil, nil, fmt.Errorf("%s is not supported by Clang", stl)
+}
+
+// -static-pie requires Clang 12
+func (tc toolchainClangCommon) getStaticExecutableFlags(pie bool) ([]string, []string, error) {
+	if pie {
+		return []string{"-fPIE"}, []string{"-static-pie"}, nil
+	}
+	return []string{}, []string{"-static"}, nil
+}
+
 func newToolchainClangCommon(config *bobConfig, tgt tgtType) (tc toolchainClangCommon) {
 	props := config.Properties
 	tc.prefix = props.GetString(string(tgt) + "_clang_prefix")
@@ -558,10 +761,12 @@ func newToolchainClangCommon(config *bobConfig, tgt tgtType) (tc toolchainClangC
 	// This assumes arBinary and asBinary are either in the path, or the same directory as clang.
 	// This is not necessarily the case. This will need to be updated when we support clang on linux without a GNU toolchain.
 	tc.arBinary = props.GetString(string(tgt) + "_ar_binary")
-	tc.asBinary = tc.prefix + props.GetString("as_binary")
+	tc.asBinary = tc.prefix + props.GetTargetString(tgt, "as_binary")
 
 	tc.objcopyBinary = props.GetString(string(tgt) + "_objcopy_binary")
 	tc.objdumpBinary = props.GetString(string(tgt) + "_objdump_binary")
+	tc.nmBinary = props.GetString(string(tgt) + "_nm_binary")
+	tc.sizeBinary = props.GetString(string(tgt) + "_size_binary")
 
 	tc.clangBinary = tc.prefix + props.GetString(string(tgt)+"_clang_cc_binary")
 	tc.clangxxBinary = tc.prefix + props.GetString(string(tgt)+"_clang_cxx_binary")
@@ -659,7 +864,8 @@ func newToolchainClangCommon(config *bobConfig, tgt tgtType) (tc toolchainClangC
 	// every call to getCXXCompiler().
 	tc.cxxflags = append(tc.cxxflags, tc.cflags...)
 
-	tc.linker = newDefaultLinker(tc.clangxxBinary, tc.cflags, []string{})
+	tc.ld = selectedLinker(config, tgt)
+	tc.linker = clangLinker{newDefaultLinker(tc.clangxxBinary, tc.ld, tc.cflags, []string{})}
 	tc.flagCache = newFlagCache()
 
 	return
@@ -680,6 +886,8 @@ type toolchainArmClang struct {
 	asBinary      string
 	objcopyBinary string
 	objdumpBinary string
+	nmBinary      string
+	sizeBinary    string
 	ccBinary      string
 	cxxBinary     string
 	linker        linker
@@ -700,6 +908,10 @@ func (tc toolchainArmClang) getArchiver() (string, []string) {
 	return tc.arBinary, []string{}
 }
 
+func (tc toolchainArmClang) getArchiverModifiers() (string, string) {
+	return "", ""
+}
+
 func (tc toolchainArmClang) getAssembler() (string, []string) {
 	return tc.asBinary, []string{}
 }
@@ -723,6 +935,14 @@ func (tc toolchainArmClang) getStripFlags() []string {
 	}
 }
 
+func (tc toolchainArmClang) getObjcopy() string {
+	return tc.objcopyBinary
+}
+
+func (tc toolchainArmClang) getSizeTools() (string, string) {
+	return tc.nmBinary, tc.sizeBinary
+}
+
 func (tc toolchainArmClang) getLibraryTocFlags() []string {
 	return []string{
 		"--format", "elf",
@@ -734,16 +954,33 @@ func (tc toolchainArmClang) checkFlagIsSupported(language, flag string) bool {
 	return tc.flagCache.checkFlag(tc, language, flag)
 }
 
+// armclang always uses the Arm C++ library, selected by armlink
+func (tc toolchainArmClang) getStlFlags(stl string) ([]string, []string, error) {
+	return nil, nil, fmt.Errorf("stl is not supported by armclang")
+}
+
+// armlink always links images statically, and has no self-relocating
+// executables
+func (tc toolchainArmClang) getStaticExecutableFlags(pie bool) ([]string, []string, error) {
+	if pie {
+		return nil, nil, fmt.Errorf("static_pie is not supported by armclang")
+	}
+	return []string{}, []string{}, nil
+}
+
 func newToolchainArmClangCommon(config *bobConfig, tgt tgtType) (tc toolchainArmClang) {
 	props := config.Properties
 	tc.prefix = props.GetString(string(tgt) + "_gnu_prefix")
-	tc.arBinary = tc.prefix + props.GetString("armclang_ar_binary")
-	tc.asBinary = tc.prefix + props.GetString("armclang_as_binary")
+	tc.arBinary = tc.prefix + props.GetTargetString(tgt, "armclang_ar_binary")
+	tc.asBinary = tc.prefix + props.GetTargetString(tgt, "armclang_as_binary")
 	tc.objcopyBinary = props.GetString(string(tgt) + "_objcopy_binary")
 	tc.objdumpBinary = props.GetString(string(tgt) + "_objdump_binary")
+	tc.nmBinary = props.GetString(string(tgt) + "_nm_binary")
+	tc.sizeBinary = props.GetString(string(tgt) + "_size_binary")
 	tc.ccBinary = tc.prefix + props.GetString(string(tgt)+"_armclang_cc_binary")
 	tc.cxxBinary = tc.prefix + props.GetString(string(tgt)+"_armclang_cxx_binary")
-	tc.linker = newDefaultLinker(tc.cxxBinary, []string{}, []string{})
+	// armclang links with armlink, so LINKER doesn't apply
+	tc.linker = newDefaultLinker(tc.cxxBinary, "", []string{}, []string{})
 
 	tc.cflags = strings.Split(config.Properties.GetString(string(tgt)+"_armclang_flags"), " ")
 	tc.flagCache = newFlagCache()
@@ -795,6 +1032,19 @@ func (l xcodeLinker) setVersionScript(path string) string {
 	return ""
 }
 
+func (l xcodeLinker) setMapFile(path string) string {
+	return "-Wl,-map," + path
+}
+
+func (l xcodeLinker) gcSections() string {
+	return "-Wl,-dead_strip"
+}
+
+// Mach-O symbol names have a leading underscore
+func (l xcodeLinker) keepSymbol(symbol string) string {
+	return "-Wl,-u,_" + symbol
+}
+
 func (l xcodeLinker) setRpath(path []string) string {
 	return ""
 }
@@ -803,6 +1053,16 @@ func (l xcodeLinker) linkWholeArchives(libs []string) string {
 	return utils.Join(libs)
 }
 
+// ld64 has its own option for the ThinLTO cache
+func (l xcodeLinker) setLtoCacheDir(dir string) string {
+	return "-Wl,-cache_path_lto," + dir
+}
+
+// ld64 searches static libraries repeatedly, so doesn't need groups
+func (l xcodeLinker) linkGroup(libs []string) string {
+	return utils.Join(libs)
+}
+
 func (l xcodeLinker) keepSharedLibraryTransitivity() string {
 	return ""
 }
@@ -852,6 +1112,10 @@ func (tc toolchainXcode) getArchiver() (string, []string) {
 	return tc.arBinary, []string{}
 }
 
+func (tc toolchainXcode) getArchiverModifiers() (string, string) {
+	return "", ""
+}
+
 func (tc toolchainXcode) getAssembler() (string, []string) {
 	return tc.asBinary, []string{}
 }
@@ -876,6 +1140,16 @@ func (tc toolchainXcode) getStripFlags() []string {
 	}
 }
 
+// Mach-O files are not converted to other formats
+func (tc toolchainXcode) getObjcopy() string {
+	return ""
+}
+
+// ld64 link maps are not understood by the code size report
+func (tc toolchainXcode) getSizeTools() (string, string) {
+	return "", ""
+}
+
 func (tc toolchainXcode) getLibraryTocFlags() []string {
 	return []string{
 		"--format", "macho",
@@ -888,11 +1162,28 @@ func (tc toolchainXcode) checkFlagIsSupported(language, flag string) bool {
 	return tc.flagCache.checkFlag(tc, language, flag)
 }
 
+// Xcode no longer ships libstdc++, and libc++ is part of the system,
+// so can't be linked statically.
+func (tc toolchainXcode) getStlFlags(stl string) ([]string, []string, error) {
+	switch stl {
+	case "libc++":
+		return []string{"--stdlib=libc++"}, []string{"--stdlib=libc++"}, nil
+	case "none":
+		return []string{"-nostdinc++"}, []string{"-nostdlib++"}, nil
+	}
+	return nil, nil, fmt.Errorf("%s is not supported by Xcode", stl)
+}
+
+// macOS only supports executables linked dynamically with libSystem
+func (tc toolchainXcode) getStaticExecutableFlags(pie bool) ([]string, []string, error) {
+	return nil, nil, fmt.Errorf("static executables are not supported by Xcode")
+}
+
 func newToolchainXcodeCommon(config *bobConfig, tgt tgtType) (tc toolchainXcode) {
 	props := config.Properties
 	tc.prefix = props.GetString(string(tgt) + "_xcode_prefix")
 	tc.arBinary = props.GetString(string(tgt) + "_ar_binary")
-	tc.asBinary = tc.prefix + props.GetString("as_binary")
+	tc.asBinary = tc.prefix + props.GetTargetString(tgt, "as_binary")
 	tc.dsymBinary = props.GetString(string(tgt) + "_dsymutil_binary")
 	tc.stripBinary = props.GetString(string(tgt) + "_strip_binary")
 	tc.otoolBinary = props.GetString(string(tgt) + "_otool_binary")
@@ -927,39 +1218,55 @@ func newToolchainXcodeCross(config *bobConfig) (tc toolchainXcodeCross) {
 type toolchainSet struct {
 	host   toolchain
 	target toolchain
+	// Only set when HOST_CROSS_TOOLCHAIN is enabled
+	hostCross toolchain
 }
 
 func (tcs *toolchainSet) getToolchain(tgt tgtType) toolchain {
 	if tgt == tgtTypeHost {
 		return tcs.host
+	} else if tgt == tgtTypeHostCross {
+		if tcs.hostCross == nil {
+			utils.Die("no host cross toolchain configured")
+		}
+		return tcs.hostCross
 	}
 	return tcs.target
 }
 
 func (tcs *toolchainSet) parseConfig(config *bobConfig) {
 	props := config.Properties
+	targetConfig, targetFile := toolchainConfig(config, tgtTypeTarget)
+	hostConfig, hostFile := toolchainConfig(config, tgtTypeHost)
 
 	if props.GetBool("target_toolchain_clang") {
-		tcs.target = newToolchainClangCross(config)
+		tcs.target = newToolchainClangCross(targetConfig)
 	} else if props.GetBool("target_toolchain_gnu") {
-		tcs.target = newToolchainGnuCross(config)
+		tcs.target = newToolchainGnuCross(targetConfig)
 	} else if props.GetBool("target_toolchain_armclang") {
-		tcs.target = newToolchainArmClangCross(config)
+		tcs.target = newToolchainArmClangCross(targetConfig)
 	} else if props.GetBool("target_toolchain_xcode") {
-		tcs.target = newToolchainXcodeCross(config)
+		tcs.target = newToolchainXcodeCross(targetConfig)
 	} else {
 		panic(errors.New("no usable target compiler toolchain configured"))
 	}
+	tcs.target = withToolchainFileFlags(tcs.target, targetFile)
 
 	if props.GetBool("host_toolchain_clang") {
-		tcs.host = newToolchainClangNative(config)
+		tcs.host = newToolchainClangNative(hostConfig)
 	} else if props.GetBool("host_toolchain_gnu") {
-		tcs.host = newToolchainGnuNative(config)
+		tcs.host = newToolchainGnuNative(hostConfig)
 	} else if props.GetBool("host_toolchain_armclang") {
-		tcs.host = newToolchainArmClangNative(config)
+		tcs.host = newToolchainArmClangNative(hostConfig)
 	} else if props.GetBool("host_toolchain_xcode") {
-		tcs.host = newToolchainXcodeNative(config)
+		tcs.host = newToolchainXcodeNative(hostConfig)
 	} else {
 		panic(errors.New("no usable host compiler toolchain configured"))
 	}
+	tcs.host = withToolchainFileFlags(tcs.host, hostFile)
+
+	if hostCrossEnabled(&props) {
+		hostCrossConfig, hostCrossFile := toolchainConfig(config, tgtTypeHostCross)
+		tcs.hostCross = withToolchainFileFlags(newToolchainGnuHostCross(hostCrossConfig), hostCrossFile)
+	}
 }
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// toolchainFile is the content of a toolchain file, which describes the
+// toolchain for one target type in a single JSON file. Any value set in
+// the file overrides the corresponding configuration options.
+type toolchainFile struct {
+	// Prefix of all the tools, e.g. "aarch64-linux-gnu-"
+	Prefix *string `json:"prefix"`
+
+	Cc       *string `json:"cc"`
+	Cxx      *string `json:"cxx"`
+	Ar       *string `json:"ar"`
+	As       *string `json:"as"`
+	Objcopy  *string `json:"objcopy"`
+	Objdump  *string `json:"objdump"`
+	Nm       *string `json:"nm"`
+	Size     *string `json:"size"`
+	Strip    *string `json:"strip"`
+	Otool    *string `json:"otool"`
+	Dsymutil *string `json:"dsymutil"`
+
+	Sysroot *string `json:"sysroot"`
+	// Target triple passed to Clang
+	Triple *string `json:"triple"`
+	// Clang configuration file, passed with --config
+	Clang_config *string `json:"clang_config"`
+
+	// Extra flags for C and C++ compilation
+	Cflags []string `json:"cflags"`
+	// Extra flags for linking
+	Ldflags []string `json:"ldflags"`
+}
+
+func loadToolchainFile(filename string) (*toolchainFile, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(content))
+	// Catch misspelt entries, which would otherwise be silently ignored
+	d.DisallowUnknownFields()
+
+	tf := &toolchainFile{}
+	if err = d.Decode(tf); err != nil {
+		return nil, err
+	}
+
+	// Paths to files are relative to the toolchain file. Tools are
+	// not, as they may be looked up in PATH.
+	dir := filepath.Dir(filename)
+	for _, path := range []*string{tf.Sysroot, tf.Clang_config} {
+		if path != nil && *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+
+	return tf, nil
+}
+
+// configKeys returns the configuration options overridden by the
+// toolchain file for the target type 'tgt'. The same value applies to
+// the options of each kind of toolchain, so that the file does not need
+// to know which kind is selected.
+func (tf *toolchainFile) configKeys(tgt tgtType) map[string]string {
+	keys := map[string]string{}
+	set := func(value *string, names ...string) {
+		if value == nil {
+			return
+		}
+		for _, name := range names {
+			keys[name] = *value
+		}
+	}
+	t := string(tgt) + "_"
+
+	set(tf.Prefix, t+"gnu_prefix", t+"clang_prefix", t+"xcode_prefix")
+	set(tf.Cc, t+"gnu_cc_binary", t+"clang_cc_binary", t+"armclang_cc_binary")
+	set(tf.Cxx, t+"gnu_cxx_binary", t+"clang_cxx_binary", t+"armclang_cxx_binary")
+	// The assembler and the armclang archiver are shared between target
+	// types, so are set in the namespace of this target type, which the
+	// toolchain reads first.
+	set(tf.Ar, t+"ar_binary", "armclang_ar_binary", t+"armclang_ar_binary")
+	set(tf.As, "as_binary", t+"as_binary", "armclang_as_binary", t+"armclang_as_binary")
+	set(tf.Objcopy, t+"objcopy_binary")
+	set(tf.Objdump, t+"objdump_binary")
+	set(tf.Nm, t+"nm_binary")
+	set(tf.Size, t+"size_binary")
+	set(tf.Strip, t+"strip_binary")
+	set(tf.Otool, t+"otool_binary")
+	set(tf.Dsymutil, t+"dsymutil_binary")
+	set(tf.Sysroot, t+"sysroot")
+	set(tf.Triple, t+"clang_triple", t+"xcode_triple")
+
+	return keys
+}
+
+func (tf *toolchainFile) cflags() []string {
+	flags := tf.Cflags
+	if tf.Clang_config != nil {
+		flags = append([]string{"--config=" + *tf.Clang_config}, flags...)
+	}
+	return flags
+}
+
+func (tf *toolchainFile) ldflags() []string {
+	flags := tf.Ldflags
+	if tf.Clang_config != nil {
+		flags = append([]string{"--config=" + *tf.Clang_config}, flags...)
+	}
+	return flags
+}
+
+// toolchainConfig returns the configuration to create the toolchain
+// for 'tgt' from, along with its toolchain file, if one is set.
+func toolchainConfig(config *bobConfig, tgt tgtType) (*bobConfig, *toolchainFile) {
+	key := string(tgt) + "_toolchain_file"
+	if _, ok := config.Properties.properties[key]; !ok {
+		return config, nil
+	}
+	filename := config.Properties.GetString(key)
+	if filename == "" {
+		return config, nil
+	}
+
+	tf, err := loadToolchainFile(filename)
+	if err != nil {
+		utils.Die("Unable to load %s toolchain file %s: %v", tgt, filename, err)
+	}
+
+	// Copy the properties, so that overriding the options shared
+	// between target types does not affect the other toolchain.
+	tgtConfig := *config
+	tgtConfig.Properties.properties = map[string]interface{}{}
+	for k, v := range config.Properties.properties {
+		tgtConfig.Properties.properties[k] = v
+	}
+	for k, v := range tf.configKeys(tgt) {
+		tgtConfig.Properties.properties[k] = v
+	}
+
+	return &tgtConfig, tf
+}
+
+// toolchainWithFlags adds the flags from a toolchain file to the
+// flags of another toolchain.
+type toolchainWithFlags struct {
+	toolchain
+	cflags    []string
+	ldflags   []string
+	flagCache *flagSupportedCache
+}
+
+func (tc toolchainWithFlags) getCCompiler() (string, []string) {
+	tool, flags := tc.toolchain.getCCompiler()
+	return tool, utils.NewStringSlice(flags, tc.cflags)
+}
+
+func (tc toolchainWithFlags) getCXXCompiler() (string, []string) {
+	tool, flags := tc.toolchain.getCXXCompiler()
+	return tool, utils.NewStringSlice(flags, tc.cflags)
+}
+
+// Flags must be checked with the extra flags, as they may select a
+// different target.
+func (tc toolchainWithFlags) checkFlagIsSupported(language, flag string) bool {
+	return tc.flagCache.checkFlag(tc, language, flag)
+}
+
+func (tc toolchainWithFlags) getLinker() linker {
+	return linkerWithFlags{tc.toolchain.getLinker(), tc.ldflags}
+}
+
+type linkerWithFlags struct {
+	linker
+	flags []string
+}
+
+func (l linkerWithFlags) getFlags() []string {
+	return utils.NewStringSlice(l.linker.getFlags(), l.flags)
+}
+
+// withToolchainFileFlags returns the toolchain to use, given the
+// toolchain created from the configuration and the toolchain file.
+func withToolchainFileFlags(tc toolchain, tf *toolchainFile) toolchain {
+	if tf == nil || (len(tf.cflags()) == 0 && len(tf.ldflags()) == 0) {
+		return tc
+	}
+	return toolchainWithFlags{tc, tf.cflags(), tf.ldflags(), newFlagCache()}
+}
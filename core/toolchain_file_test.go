@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeToolchainFile(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "bob_toolchain_file")
+	assert.Nil(t, err)
+
+	filename := filepath.Join(dir, "aarch64.json")
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(content), 0644))
+
+	return filename, func() { os.RemoveAll(dir) }
+}
+
+func Test_loadToolchainFile_resolves_paths(t *testing.T) {
+	filename, cleanup := writeToolchainFile(t, `{
+    "prefix": "aarch64-linux-gnu-",
+    "cc": "clang",
+    "sysroot": "sysroot",
+    "clang_config": "/opt/aarch64.cfg",
+    "cflags": ["-mcpu=cortex-a76"]
+}`)
+	defer cleanup()
+
+	tf, err := loadToolchainFile(filename)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "clang", *tf.Cc)
+	assert.Equal(t, filepath.Join(filepath.Dir(filename), "sysroot"), *tf.Sysroot)
+	assert.Equal(t, []string{"--config=/opt/aarch64.cfg", "-mcpu=cortex-a76"}, tf.cflags())
+	assert.Equal(t, []string{"--config=/opt/aarch64.cfg"}, tf.ldflags())
+}
+
+func Test_loadToolchainFile_rejects_unknown_entries(t *testing.T) {
+	filename, cleanup := writeToolchainFile(t, `{"sysrot": "/opt/sysroot"}`)
+	defer cleanup()
+
+	_, err := loadToolchainFile(filename)
+	assert.NotNil(t, err)
+}
+
+func Test_toolchainConfig_overrides_one_target(t *testing.T) {
+	filename, cleanup := writeToolchainFile(t, `{"as": "aarch64-as", "triple": "aarch64-linux-gnu"}`)
+	defer cleanup()
+
+	config := &bobConfig{}
+	config.Properties.properties = map[string]interface{}{
+		"target_toolchain_file": filename,
+		"as_binary":             "as",
+		"target_clang_triple":   "",
+	}
+
+	tgtConfig, tf := toolchainConfig(config, tgtTypeTarget)
+	assert.NotNil(t, tf)
+	assert.Equal(t, "aarch64-as", tgtConfig.Properties.GetString("as_binary"))
+	assert.Equal(t, "aarch64-as", tgtConfig.Properties.GetTargetString(tgtTypeTarget, "as_binary"))
+	assert.Equal(t, "aarch64-linux-gnu", tgtConfig.Properties.GetString("target_clang_triple"))
+
+	// The host toolchain still sees the original value
+	assert.Equal(t, "as", config.Properties.GetString("as_binary"))
+
+	hostConfig, tf := toolchainConfig(config, tgtTypeHost)
+	assert.Nil(t, tf)
+	assert.Equal(t, config, hostConfig)
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_selectedLinker(t *testing.T) {
+	config := &bobConfig{}
+	config.Properties.properties = map[string]interface{}{}
+	assert.Equal(t, "", selectedLinker(config, tgtTypeTarget))
+
+	config.Properties.properties["linker"] = "lld"
+	assert.Equal(t, "lld", selectedLinker(config, tgtTypeTarget))
+
+	// The target can use a different linker than the host
+	config.Properties.properties["host_linker"] = "gold"
+	assert.Equal(t, "gold", selectedLinker(config, tgtTypeHost))
+	assert.Equal(t, "lld", selectedLinker(config, tgtTypeTarget))
+}
+
+func Test_defaultLinker_selected(t *testing.T) {
+	flags := []string{"--sysroot=/sysroot"}
+
+	l := newDefaultLinker("g++", "", flags, []string{})
+	assert.Equal(t, flags, l.getFlags())
+	assert.Equal(t, "-fuse-ld=bfd", l.getForwardingLibFlags())
+
+	l = newDefaultLinker("clang++", "lld", flags, []string{})
+	assert.Equal(t, []string{"--sysroot=/sysroot", "-fuse-ld=lld"}, l.getFlags())
+	assert.Equal(t, "-fuse-ld=bfd", l.getForwardingLibFlags())
+
+	// Forwarding libraries already use the selected linker
+	l = newDefaultLinker("g++", "bfd", flags, []string{})
+	assert.Equal(t, "", l.getForwardingLibFlags())
+
+	// The toolchain's flags are not modified
+	assert.Equal(t, []string{"--sysroot=/sysroot"}, flags)
+}
+
+func Test_clangLinker_setLtoCacheDir(t *testing.T) {
+	l := clangLinker{newDefaultLinker("clang++", "", []string{}, []string{})}
+	assert.Equal(t, "-Wl,-plugin-opt,cache-dir=out/lto", l.setLtoCacheDir("out/lto"))
+
+	l = clangLinker{newDefaultLinker("clang++", "lld", []string{}, []string{})}
+	assert.Equal(t, "-Wl,--thinlto-cache-dir=out/lto", l.setLtoCacheDir("out/lto"))
+}
+
+func Test_toolchainGnu_setLtoCacheDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bob_gnu_lto")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	prefix := filepath.Join(dir, "aarch64-linux-gnu-")
+	assert.Nil(t, ioutil.WriteFile(prefix+"gcc", []byte{}, 0755))
+
+	config := &bobConfig{}
+	config.Properties.properties = map[string]interface{}{
+		"as_binary":             "as",
+		"linker":                "lld",
+		"target_gnu_prefix":     prefix,
+		"target_gnu_cc_binary":  "gcc",
+		"target_gnu_cxx_binary": "g++",
+		"target_gnu_flags":      "",
+		"target_sysroot":        "",
+		"target_ar_binary":      "aarch64-linux-gnu-ar",
+		"target_objcopy_binary": "aarch64-linux-gnu-objcopy",
+		"target_objdump_binary": "aarch64-linux-gnu-objdump",
+		"target_nm_binary":      "aarch64-linux-gnu-nm",
+		"target_size_binary":    "aarch64-linux-gnu-size",
+	}
+
+	// The GCC LTO plugin has no cache, whichever linker is used
+	tc := newToolchainGnuCross(config)
+	assert.Equal(t, "", tc.getLinker().setLtoCacheDir("out/lto"))
+}
+
+func Test_usesLto(t *testing.T) {
+	assert.False(t, usesLto([]string{}))
+	assert.False(t, usesLto([]string{"-O2", "-flto-partition=one"}))
+	assert.True(t, usesLto([]string{"-flto"}))
+	assert.True(t, usesLto([]string{"-O2", "-flto=thin"}))
+	assert.False(t, usesLto([]string{"-flto=thin", "-fno-lto"}))
+	assert.True(t, usesLto([]string{"-fno-lto", "-flto=full"}))
+}
+
+func Test_defaultLinker_linkGroup(t *testing.T) {
+	l := newDefaultLinker("g++", "", []string{}, []string{})
+	assert.Equal(t, "", l.linkGroup([]string{}))
+	assert.Equal(t, "-Wl,--start-group a.a b.a -Wl,--end-group", l.linkGroup([]string{"a.a", "b.a"}))
+}
+
+func Test_getStlFlags(t *testing.T) {
+	gnu := toolchainGnuCommon{}
+	cxxflags, ldflags, err := gnu.getStlFlags("static")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, cxxflags)
+	assert.Equal(t, []string{"-static-libstdc++"}, ldflags)
+	_, _, err = gnu.getStlFlags("libc++")
+	assert.Error(t, err)
+
+	clang := toolchainClangCommon{}
+	cxxflags, ldflags, err = clang.getStlFlags("libc++")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--stdlib=libc++"}, cxxflags)
+	assert.Equal(t, []string{"--stdlib=libc++"}, ldflags)
+
+	xcode := toolchainXcode{}
+	_, _, err = xcode.getStlFlags("libstdc++")
+	assert.Error(t, err)
+}
+
+func Test_stlLibrary(t *testing.T) {
+	assert.Equal(t, "libc++", stlLibrary("libc++"))
+	assert.Equal(t, "libstdc++", stlLibrary("libstdc++"))
+	// These use the toolchain's library
+	assert.Equal(t, "", stlLibrary("static"))
+	assert.Equal(t, "", stlLibrary("none"))
+	assert.Equal(t, "", stlLibrary(""))
+}
+
+func Test_getStaticExecutableFlags(t *testing.T) {
+	gnu := toolchainGnuCommon{}
+	cflags, ldflags, err := gnu.getStaticExecutableFlags(false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, cflags)
+	assert.Equal(t, []string{"-static"}, ldflags)
+
+	clang := toolchainClangCommon{}
+	cflags, ldflags, err = clang.getStaticExecutableFlags(true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-fPIE"}, cflags)
+	assert.Equal(t, []string{"-static-pie"}, ldflags)
+
+	armclang := toolchainArmClang{}
+	_, _, err = armclang.getStaticExecutableFlags(true)
+	assert.Error(t, err)
+
+	xcode := toolchainXcode{}
+	_, _, err = xcode.getStaticExecutableFlags(false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,355 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/fileutils"
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// Thread IDs used to lay out the trace. Flag probes and file writes
+// can overlap, so they are spread over as many threads as needed,
+// starting at these IDs.
+const (
+	traceTidPhases    = 1
+	traceTidFileWrite = 10
+	traceTidFlagProbe = 100
+)
+
+// traceEvent is an event in the Chrome trace event format. Times are in
+// microseconds since generation started.
+type traceEvent struct {
+	Name string                 `json:"name"`
+	Cat  string                 `json:"cat,omitempty"`
+	Ph   string                 `json:"ph"`
+	Ts   int64                  `json:"ts"`
+	Dur  int64                  `json:"dur"`
+	Pid  int                    `json:"pid"`
+	Tid  int                    `json:"tid"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// tracePass records a mutator or singleton pass. Parallel mutators are
+// called once per module from many goroutines, so the pass lasts from
+// the first call starting to the last one ending.
+type tracePass struct {
+	name    string
+	cat     string
+	start   time.Time
+	end     time.Time
+	modules int
+}
+
+// traceHandler records what Bob spends its time on during generation,
+// and writes it as a Chrome trace, which can be loaded in
+// chrome://tracing or https://ui.perfetto.dev.
+type traceHandler struct {
+	out   string
+	start time.Time
+
+	lock         sync.Mutex
+	configLoaded time.Time
+	passes       []*tracePass
+	mutators     map[string]*tracePass
+	probes       []traceEvent
+	writes       []traceEvent
+}
+
+// generationTrace is used by the code probing flags and writing files,
+// which has no access to the context. It is nil when tracing is disabled.
+var generationTrace *traceHandler
+
+func initTraceHandler(start time.Time) *traceHandler {
+	out, present := os.LookupEnv("BOB_TRACE")
+	if !present || out == "" {
+		return nil
+	}
+
+	generationTrace = &traceHandler{
+		out:      out,
+		start:    start,
+		mutators: map[string]*tracePass{},
+	}
+	return generationTrace
+}
+
+func (h *traceHandler) since(t time.Time) int64 {
+	return t.Sub(h.start).Nanoseconds() / 1000
+}
+
+func (h *traceHandler) setConfigLoaded() {
+	if h != nil {
+		h.configLoaded = time.Now()
+	}
+}
+
+func (h *traceHandler) addMutatorCall(name string, start time.Time) {
+	end := time.Now()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	pass := h.mutators[name]
+	if pass.start.IsZero() || start.Before(pass.start) {
+		pass.start = start
+	}
+	if end.After(pass.end) {
+		pass.end = end
+	}
+	pass.modules++
+}
+
+func (h *traceHandler) registerMutator(name string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	pass := &tracePass{name: name, cat: "mutator"}
+	h.mutators[name] = pass
+	h.passes = append(h.passes, pass)
+}
+
+// traceBottomUpMutator wraps a mutator so that its pass is traced. When
+// tracing is not enabled, the mutator is returned as is.
+func (h *traceHandler) traceBottomUpMutator(name string,
+	mutator blueprint.BottomUpMutator) blueprint.BottomUpMutator {
+	if h == nil {
+		return mutator
+	}
+	h.registerMutator(name)
+	return func(mctx blueprint.BottomUpMutatorContext) {
+		defer h.addMutatorCall(name, time.Now())
+		mutator(mctx)
+	}
+}
+
+// traceTopDownMutator is the top down equivalent of traceBottomUpMutator
+func (h *traceHandler) traceTopDownMutator(name string,
+	mutator blueprint.TopDownMutator) blueprint.TopDownMutator {
+	if h == nil {
+		return mutator
+	}
+	h.registerMutator(name)
+	return func(mctx blueprint.TopDownMutatorContext) {
+		defer h.addMutatorCall(name, time.Now())
+		mutator(mctx)
+	}
+}
+
+type tracedSingleton struct {
+	handler   *traceHandler
+	name      string
+	singleton blueprint.Singleton
+}
+
+func (s *tracedSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	start := time.Now()
+	s.singleton.GenerateBuildActions(ctx)
+
+	s.handler.lock.Lock()
+	defer s.handler.lock.Unlock()
+	s.handler.passes = append(s.handler.passes,
+		&tracePass{name: s.name, cat: "singleton", start: start, end: time.Now()})
+}
+
+// registerSingletonType registers a singleton, tracing its pass when
+// tracing is enabled.
+func registerSingletonType(ctx *blueprint.Context, name string, factory blueprint.SingletonFactory) {
+	h := generationTrace
+	if h == nil {
+		ctx.RegisterSingletonType(name, factory)
+		return
+	}
+	ctx.RegisterSingletonType(name, func() blueprint.Singleton {
+		return &tracedSingleton{h, name, factory()}
+	})
+}
+
+func (h *traceHandler) addFlagProbe(compiler, language, flag string, start time.Time,
+	supported bool) {
+	if h == nil {
+		return
+	}
+	end := time.Now()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.probes = append(h.probes, traceEvent{
+		Name: flag,
+		Cat:  "flag_probe",
+		Ts:   h.since(start),
+		Dur:  h.since(end) - h.since(start),
+		Args: map[string]interface{}{
+			"compiler":  compiler,
+			"language":  language,
+			"supported": supported,
+		},
+	})
+}
+
+func (h *traceHandler) addFileWrite(filename string, start time.Time) {
+	if h == nil {
+		return
+	}
+	end := time.Now()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.writes = append(h.writes, traceEvent{
+		Name: filename,
+		Cat:  "file_write",
+		Ts:   h.since(start),
+		Dur:  h.since(end) - h.since(start),
+	})
+}
+
+// writeFileIfChanged is fileutils.WriteIfChanged, recording the time
+// taken in the trace.
+func writeFileIfChanged(filename string, sb *strings.Builder) error {
+	defer generationTrace.addFileWrite(filename, time.Now())
+	return fileutils.WriteIfChanged(filename, sb)
+}
+
+// assignThreads places overlapping events on different threads, so
+// that each thread has a sequence of non-overlapping events.
+func assignThreads(events []traceEvent, firstTid int) []traceEvent {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Ts < events[j].Ts })
+
+	// The end time of the last event on each thread
+	ends := []int64{}
+	for i := range events {
+		thread := 0
+		for thread < len(ends) && ends[thread] > events[i].Ts {
+			thread++
+		}
+		if thread == len(ends) {
+			ends = append(ends, 0)
+		}
+		ends[thread] = events[i].Ts + events[i].Dur
+		events[i].Tid = firstTid + thread
+	}
+	return events
+}
+
+func (h *traceHandler) phase(name string, start, end time.Time) traceEvent {
+	return traceEvent{
+		Name: name,
+		Cat:  "phase",
+		Ts:   h.since(start),
+		Dur:  h.since(end) - h.since(start),
+		Tid:  traceTidPhases,
+	}
+}
+
+func threadName(tid int, name string) traceEvent {
+	return traceEvent{
+		Name: "thread_name",
+		Ph:   "M",
+		Tid:  tid,
+		Args: map[string]interface{}{"name": name},
+	}
+}
+
+// events returns the trace events, once generation is complete
+func (h *traceHandler) events(end time.Time) []traceEvent {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	events := []traceEvent{threadName(traceTidPhases, "Generation")}
+
+	// Mutator passes are listed in registration order, but a
+	// mutator which no module ran has no time.
+	passes := []*tracePass{}
+	for _, pass := range h.passes {
+		if !pass.start.IsZero() {
+			passes = append(passes, pass)
+		}
+	}
+
+	// The phases between the passes are handled by Blueprint
+	events = append(events, h.phase("load_config", h.start, h.configLoaded))
+	if len(passes) > 0 {
+		events = append(events,
+			h.phase("parse_build_files", h.configLoaded, passes[0].start))
+	}
+
+	var prev *tracePass
+	for _, pass := range passes {
+		if prev != nil && prev.cat == "mutator" && pass.cat == "singleton" {
+			events = append(events, h.phase("generate_build_actions", prev.end, pass.start))
+		}
+		event := h.phase(pass.name, pass.start, pass.end)
+		event.Cat = pass.cat
+		if pass.cat == "mutator" {
+			event.Args = map[string]interface{}{"modules": pass.modules}
+		}
+		events = append(events, event)
+		prev = pass
+	}
+	if prev != nil {
+		events = append(events, h.phase("write_build_files", prev.end, end))
+	}
+
+	if len(h.writes) > 0 {
+		events = append(events, threadName(traceTidFileWrite, "File writes"))
+		events = append(events, assignThreads(h.writes, traceTidFileWrite)...)
+	}
+	if len(h.probes) > 0 {
+		events = append(events, threadName(traceTidFlagProbe, "Flag probes"))
+		events = append(events, assignThreads(h.probes, traceTidFlagProbe)...)
+	}
+
+	for i := range events {
+		if events[i].Ph == "" {
+			events[i].Ph = "X"
+		}
+		events[i].Pid = 1
+	}
+	return events
+}
+
+// writeTrace writes the trace file. This is called once Blueprint has
+// written the build files.
+func (h *traceHandler) writeTrace() {
+	if h == nil {
+		return
+	}
+
+	content, err := json.Marshal(map[string]interface{}{
+		"traceEvents":     h.events(time.Now()),
+		"displayTimeUnit": "ms",
+	})
+	if err != nil {
+		utils.Die("%v", err)
+	}
+	err = ioutil.WriteFile(h.out, append(content, '\n'), 0644)
+	if err != nil {
+		utils.Die("%v", err)
+	}
+
+	fmt.Printf("Wrote generation trace to %s\n", h.out)
+}
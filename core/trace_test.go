@@ -0,0 +1,66 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_assignThreads_separates_overlapping_events(t *testing.T) {
+	events := []traceEvent{
+		{Name: "d", Ts: 10, Dur: 5},
+		{Name: "a", Ts: 0, Dur: 10},
+		{Name: "b", Ts: 5, Dur: 10},
+		{Name: "c", Ts: 8, Dur: 4},
+	}
+	tids := map[string]int{}
+	for _, e := range assignThreads(events, 100) {
+		tids[e.Name] = e.Tid
+	}
+	assert.Equal(t, map[string]int{"a": 100, "b": 101, "c": 102, "d": 100}, tids)
+}
+
+func Test_traceHandler_events_lists_phases_in_order(t *testing.T) {
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	h := &traceHandler{start: start, configLoaded: at(1), mutators: map[string]*tracePass{}}
+	h.registerMutator("unused")
+	h.registerMutator("deps")
+	h.mutators["deps"].start = at(2)
+	h.mutators["deps"].end = at(4)
+	h.mutators["deps"].modules = 3
+	h.passes = append(h.passes, &tracePass{name: "dist", cat: "singleton", start: at(6), end: at(7)})
+
+	names := []string{}
+	for _, e := range h.events(at(9)) {
+		if e.Tid == traceTidPhases && e.Ph == "X" {
+			names = append(names, e.Name)
+		}
+		if e.Name == "deps" {
+			assert.Equal(t, int64(2000), e.Ts)
+			assert.Equal(t, int64(2000), e.Dur)
+			assert.Equal(t, 3, e.Args["modules"])
+		}
+	}
+	assert.Equal(t, []string{"load_config", "parse_build_files", "deps",
+		"generate_build_actions", "dist", "write_build_files"}, names)
+}
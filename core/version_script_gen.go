@@ -0,0 +1,211 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// versionNode is a version node of a version script, with the symbols
+// it exports
+type versionNode struct {
+	name    string
+	symbols []string
+}
+
+func (b *BuildProps) versionScriptGenerated() bool {
+	from := &b.Version_script_from
+	return len(from.Headers) > 0 || from.Symbol_list != nil
+}
+
+var cIdentifierRegexp = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// annotatedSymbols returns the names declared by the declarations in a
+// header which are annotated with 'macro'. The name of a function is
+// the identifier before its parameters, and the name of a variable is
+// the last identifier before its initializer, dimensions or the end of
+// the declaration.
+func annotatedSymbols(header string, macro string) []string {
+	symbols := []string{}
+	lines := []string{}
+	for _, line := range strings.Split(header, "\n") {
+		// Skip preprocessor directives, such as the macro's definition
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			lines = append(lines, line)
+		}
+	}
+	text := strings.Join(lines, "\n")
+
+	for _, loc := range regexp.MustCompile(`\b`+regexp.QuoteMeta(macro)+`\b`).FindAllStringIndex(text, -1) {
+		decl := text[loc[1]:]
+		if end := strings.IndexAny(decl, ";{"); end >= 0 {
+			decl = decl[:end]
+		}
+		if paren := strings.Index(decl, "("); paren >= 0 {
+			decl = decl[:paren]
+		} else if end := strings.IndexAny(decl, "=["); end >= 0 {
+			decl = decl[:end]
+		}
+		if names := cIdentifierRegexp.FindAllString(decl, -1); len(names) > 0 {
+			symbols = utils.AppendIfUnique(symbols, names[len(names)-1])
+		}
+	}
+	return symbols
+}
+
+// parseSymbolList reads a symbol list, which has one symbol per line.
+// A [NODE] line starts a version node, and symbols before the first
+// one are in 'defaultNode'. Comments start with #.
+func parseSymbolList(list io.Reader, defaultNode string) ([]versionNode, error) {
+	nodes := []versionNode{{name: defaultNode}}
+	scanner := bufio.NewScanner(list)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(strings.SplitN(scanner.Text(), "#", 2)[0])
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			nodes = append(nodes, versionNode{name: strings.TrimSpace(line[1 : len(line)-1])})
+		} else if cIdentifierRegexp.FindString(line) == line {
+			nodes[len(nodes)-1].symbols = append(nodes[len(nodes)-1].symbols, line)
+		} else {
+			return nil, fmt.Errorf("line %d: expected a symbol or [NODE], not %s", number, line)
+		}
+	}
+	return nodes, scanner.Err()
+}
+
+// writeVersionScript returns a version script exporting the symbols of
+// each node. Each node inherits the previous one, and all other symbols
+// are local. Empty nodes are left out, except the first when it is the
+// only one.
+func writeVersionScript(nodes []versionNode) string {
+	sb := &strings.Builder{}
+	previous := ""
+	for i, node := range nodes {
+		if len(node.symbols) == 0 && (i > 0 || len(nodes) > 1) {
+			continue
+		}
+		sb.WriteString(node.name + " {\n")
+		if len(node.symbols) > 0 {
+			sb.WriteString("  global:\n")
+			for _, symbol := range node.symbols {
+				sb.WriteString("    " + symbol + ";\n")
+			}
+		}
+		if previous == "" {
+			sb.WriteString("  local:\n    *;\n")
+			sb.WriteString("};\n")
+		} else {
+			sb.WriteString("} " + previous + ";\n")
+		}
+		previous = node.name
+	}
+	return sb.String()
+}
+
+// versionNodes collects the exported symbols of a library from its
+// annotated headers and symbol list. A symbol listed in the symbol list
+// is only in the node it is listed in.
+func (l *library) versionNodes(ctx blueprint.BaseModuleContext) ([]versionNode, error) {
+	from := &l.Properties.Version_script_from
+	macro := proptools.StringDefault(from.Macro, l.exportMacro())
+	defaultNode := proptools.StringDefault(from.Node, strings.TrimSuffix(l.exportMacro(), "_EXPORT"))
+
+	nodes := []versionNode{{name: defaultNode}}
+	if from.Symbol_list != nil {
+		path := getPathInSourceDir(*from.Symbol_list)
+		ctx.AddNinjaFileDeps(path)
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		if nodes, err = parseSymbolList(file, defaultNode); err != nil {
+			return nil, fmt.Errorf("%s: %s", *from.Symbol_list, err)
+		}
+	}
+
+	listed := []string{}
+	for _, node := range nodes {
+		listed = append(listed, node.symbols...)
+	}
+	for _, header := range from.Headers {
+		path := getPathInSourceDir(header)
+		ctx.AddNinjaFileDeps(path)
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, symbol := range annotatedSymbols(string(content), macro) {
+			if !utils.Contains(listed, symbol) {
+				listed = append(listed, symbol)
+				nodes[0].symbols = append(nodes[0].symbols, symbol)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// versionScriptGenMutator writes the version script of each binary and
+// shared library using version_script_from. It is written when the build
+// is generated, which is rerun when the headers or symbol list change,
+// so that the Linux and Android.mk backends can use it like a
+// hand-written one.
+func versionScriptGenMutator(mctx blueprint.TopDownMutatorContext) {
+	l, ok := getBinaryOrSharedLib(mctx.Module())
+	if !ok || !isEnabled(l) || !l.Properties.versionScriptGenerated() {
+		return
+	}
+
+	if l.Properties.Version_script != nil {
+		mctx.PropertyErrorf("version_script_from", "can't be used with version_script")
+		return
+	}
+	if getConfig(mctx).Properties.GetBool("builder_android_bp") {
+		mctx.PropertyErrorf("version_script_from", "is not supported by the Android.bp backend")
+		return
+	}
+
+	nodes, err := l.versionNodes(mctx)
+	if err != nil {
+		mctx.PropertyErrorf("version_script_from", "%s", err)
+		return
+	}
+
+	path := filepath.Join(getBuildDir(), string(l.Properties.TargetType),
+		"version_scripts", l.Name()+".map")
+	sb := &strings.Builder{}
+	sb.WriteString(writeVersionScript(nodes))
+	if err := getBackendOutput(getConfig(mctx)).writeFile(path, sb); err != nil {
+		utils.Die("%v", err.Error())
+	}
+	l.Properties.GeneratedVersionScript = &path
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_annotatedSymbols(t *testing.T) {
+	header := `#ifndef FOO_H
+#define FOO_H
+#include <foo_export.h>
+
+LIBFOO_EXPORT int foo_open(const char *path);
+LIBFOO_EXPORT void
+foo_close(int fd);
+extern LIBFOO_EXPORT const char *foo_names[4];
+LIBFOO_EXPORT extern int foo_count;
+int foo_internal(void);
+LIBFOO_EXPORT_EXTRA int foo_other(void);
+
+#endif
+`
+	assert.Equal(t, []string{"foo_open", "foo_close", "foo_names", "foo_count"},
+		annotatedSymbols(header, "LIBFOO_EXPORT"))
+}
+
+func Test_parseSymbolList(t *testing.T) {
+	list := strings.Join([]string{
+		"# Symbols of the first release",
+		"foo_open",
+		"foo_close  # closes a file",
+		"",
+		"[LIBFOO_2]",
+		"foo_reset",
+	}, "\n")
+
+	nodes, err := parseSymbolList(strings.NewReader(list), "LIBFOO")
+	assert.NoError(t, err)
+	assert.Equal(t, []versionNode{
+		{name: "LIBFOO", symbols: []string{"foo_open", "foo_close"}},
+		{name: "LIBFOO_2", symbols: []string{"foo_reset"}},
+	}, nodes)
+
+	_, err = parseSymbolList(strings.NewReader("foo_open;\n"), "LIBFOO")
+	assert.Error(t, err)
+}
+
+func Test_writeVersionScript(t *testing.T) {
+	script := writeVersionScript([]versionNode{
+		{name: "LIBFOO", symbols: []string{"foo_open", "foo_close"}},
+		{name: "LIBFOO_1.1"},
+		{name: "LIBFOO_2", symbols: []string{"foo_reset"}},
+	})
+	assert.Equal(t, `LIBFOO {
+  global:
+    foo_open;
+    foo_close;
+  local:
+    *;
+};
+LIBFOO_2 {
+  global:
+    foo_reset;
+} LIBFOO;
+`, script)
+
+	assert.Equal(t, "LIBFOO {\n  local:\n    *;\n};\n",
+		writeVersionScript([]versionNode{{name: "LIBFOO"}}))
+}
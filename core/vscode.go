@@ -0,0 +1,229 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+var vscodeOut string
+
+func init() {
+	flag.StringVar(&vscodeOut, "vscode-out", "",
+		"Directory to write Visual Studio Code configuration to. No build files are written")
+}
+
+// The name of the configuration combining all modules
+const vscodeAllModules = "All modules"
+
+// vscodeConfiguration is a configuration of the C/C++ extension. The
+// user chooses which configuration is used for IntelliSense.
+type vscodeConfiguration struct {
+	Name         string   `json:"name"`
+	IncludePath  []string `json:"includePath"`
+	Defines      []string `json:"defines"`
+	CompilerPath string   `json:"compilerPath,omitempty"`
+	CompilerArgs []string `json:"compilerArgs,omitempty"`
+}
+
+// vscodeProperties is the content of c_cpp_properties.json
+type vscodeProperties struct {
+	Configurations []vscodeConfiguration `json:"configurations"`
+	Version        int                   `json:"version"`
+}
+
+// vscodeTask is a task building one target with the bob script
+type vscodeTask struct {
+	Label          string      `json:"label"`
+	Type           string      `json:"type"`
+	Command        string      `json:"command"`
+	Args           []string    `json:"args,omitempty"`
+	Group          interface{} `json:"group"`
+	ProblemMatcher []string    `json:"problemMatcher"`
+}
+
+// vscodeTasks is the content of tasks.json
+type vscodeTasks struct {
+	Version string       `json:"version"`
+	Tasks   []vscodeTask `json:"tasks"`
+}
+
+// vscodeDefines returns the macros defined by -D flags, in the form
+// expected by the C/C++ extension.
+func vscodeDefines(flags []string) []string {
+	defines := []string{}
+	for _, flag := range flags {
+		if strings.HasPrefix(flag, "-D") && len(flag) > 2 {
+			defines = append(defines, flag[2:])
+		}
+	}
+	return defines
+}
+
+func isCxxSource(s string) bool {
+	ext := filepath.Ext(s)
+	return ext == ".cpp" || ext == ".cc"
+}
+
+// vscodeModuleConfiguration describes how a library or binary is
+// compiled, using the same include directories and flags as the
+// Linux backend.
+func vscodeModuleConfiguration(g generatorBackend, name string, l *library) vscodeConfiguration {
+	gendirs := []string{}
+	for _, m := range l.exported.generatedHeaderModules {
+		gs, _ := getGenerateCommon(m)
+		gendirs = append(gendirs, gs.genIncludeDirs()...)
+	}
+
+	includePath := []string{}
+	for _, dir := range l.compileIncludeDirs(gendirs) {
+		includePath = append(includePath, absolutePath(dir))
+	}
+
+	cflags := utils.MergeFlags(l.Properties.Cflags, l.Properties.Export_cflags, l.exported.cflags)
+
+	tc := g.getToolchain(l.Properties.TargetType)
+	compiler, targetFlags := tc.getCCompiler()
+	if len(utils.Filter(isCxxSource, l.Properties.Srcs)) > 0 {
+		compiler, targetFlags = tc.getCXXCompiler()
+	}
+	if path, err := exec.LookPath(compiler); err == nil {
+		compiler = path
+	}
+
+	return vscodeConfiguration{
+		Name:         name,
+		IncludePath:  includePath,
+		Defines:      vscodeDefines(cflags),
+		CompilerPath: compiler,
+		CompilerArgs: targetFlags,
+	}
+}
+
+func writeVscodeFile(dir, name string, content interface{}) {
+	data, err := json.MarshalIndent(content, "", "    ")
+	if err != nil {
+		utils.Die("%v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, name), append(data, '\n'), 0644)
+	if err != nil {
+		utils.Die("%v", err)
+	}
+}
+
+type vscodeSingleton struct{}
+
+// GenerateBuildActions writes c_cpp_properties.json, with a
+// configuration for each library and binary, and tasks.json, with a
+// build task for each alias.
+func (s *vscodeSingleton) GenerateBuildActions(ctx blueprint.SingletonContext) {
+	g := getConfig(ctx).Generator
+	if _, ok := unwrapBackend(g).(*linuxGenerator); !ok {
+		utils.Die("--vscode-out is only supported by the Linux backend")
+	}
+
+	configs := map[string]vscodeConfiguration{}
+	aliases := []string{}
+
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if _, ok := m.(*alias); ok {
+			aliases = append(aliases, ctx.ModuleName(m))
+			return
+		}
+
+		l, ok := getLibrary(m)
+		if !ok || !isEnabled(l) {
+			return
+		}
+		name := fmt.Sprintf("%s (%s)", ctx.ModuleName(m), l.Properties.TargetType)
+		configs[name] = vscodeModuleConfiguration(g, name, l)
+	})
+
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.Strings(aliases)
+
+	// The combined configuration is listed first, so that it is
+	// used until the user picks a module.
+	all := vscodeConfiguration{
+		Name:        vscodeAllModules,
+		IncludePath: []string{},
+		Defines:     []string{},
+	}
+	properties := vscodeProperties{Version: 4}
+	for _, name := range names {
+		all.IncludePath = utils.MergeUnique(all.IncludePath, configs[name].IncludePath)
+		all.Defines = utils.MergeUnique(all.Defines, configs[name].Defines)
+	}
+	properties.Configurations = append(properties.Configurations, all)
+	for _, name := range names {
+		properties.Configurations = append(properties.Configurations, configs[name])
+	}
+
+	bob := absolutePath(filepath.Join(getBuildDir(), "bob"))
+	tasks := vscodeTasks{Version: "2.0.0"}
+	tasks.Tasks = append(tasks.Tasks, vscodeTask{
+		Label:          "bob: build",
+		Type:           "shell",
+		Command:        bob,
+		Group:          map[string]interface{}{"kind": "build", "isDefault": true},
+		ProblemMatcher: []string{"$gcc"},
+	})
+	for _, name := range aliases {
+		tasks.Tasks = append(tasks.Tasks, vscodeTask{
+			Label:          "bob: " + name,
+			Type:           "shell",
+			Command:        bob,
+			Args:           []string{name},
+			Group:          "build",
+			ProblemMatcher: []string{"$gcc"},
+		})
+	}
+
+	err := os.MkdirAll(vscodeOut, 0755)
+	if err != nil {
+		utils.Die("%v", err)
+	}
+	writeVscodeFile(vscodeOut, "c_cpp_properties.json", properties)
+	writeVscodeFile(vscodeOut, "tasks.json", tasks)
+
+	fmt.Printf("Wrote Visual Studio Code configuration for %d modules and %d aliases to %s\n",
+		len(names), len(aliases), vscodeOut)
+
+	// As with the statistics, don't overwrite the build files
+	os.Exit(0)
+}
+
+func vscodeSingletonFactory() blueprint.Singleton {
+	return &vscodeSingleton{}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/google/blueprint"
+)
+
+// WarningBudgetProps describes the properties of the bob_warning_budget module
+type WarningBudgetProps struct {
+	// The number of compiler warnings allowed in the C and C++ modules
+	// defined in this directory and its subdirectories
+	Max_warnings *int64
+}
+
+// Type representing each bob_warning_budget module. A budget covers the
+// modules of its directory, except in subdirectories with their own
+// budget.
+type warningBudget struct {
+	moduleBase
+	Properties struct {
+		WarningBudgetProps
+		Features
+	}
+}
+
+func (m *warningBudget) features() *Features {
+	return &m.Properties.Features
+}
+
+func (m *warningBudget) featurableProperties() []interface{} {
+	return []interface{}{&m.Properties.WarningBudgetProps}
+}
+
+// The budget is checked once the objects of every module it covers are
+// known, by the backend's singleton, so there is nothing to do here.
+func (m *warningBudget) GenerateBuildActions(ctx blueprint.ModuleContext) {
+	defer recoverModulePanic(ctx, "build actions")
+
+	if m.Properties.Max_warnings == nil {
+		ctx.PropertyErrorf("max_warnings", "is required")
+	} else if *m.Properties.Max_warnings < 0 {
+		ctx.PropertyErrorf("max_warnings", "must not be negative")
+	}
+}
+
+// Create the structure representing the bob_warning_budget
+func warningBudgetFactory(config *bobConfig) (blueprint.Module, []interface{}) {
+	module := &warningBudget{}
+	module.Properties.Features.Init(&config.Properties, WarningBudgetProps{})
+	return module, []interface{}{&module.Properties, &module.SimpleName.Properties}
+}
@@ -44,22 +44,85 @@ func armFlag(s string) bool {
 	return s == "-marm" || s == "-mno-thumb"
 }
 
-// Identify whether a compilation flag should be used on android
+// AndroidCompileFlagDropReason returns why a compilation flag is not
+// used on android, or "" if it is.
 //
 // The Android build system should set machine specific flags (so it
 // can do multi-arch builds) and compiler standard, so filter these
 // out from module properties.
+func AndroidCompileFlagDropReason(s string) string {
+	if thumbFlag(s) || armFlag(s) {
+		return "the instruction set is passed to Android as the module's arm mode"
+	} else if machineSpecificFlag(s) {
+		return "machine specific flags are set by Android"
+	} else if compilerStandard(s) {
+		return "the standard is passed to Android as the module's language standard"
+	}
+	return ""
+}
+
+// Identify whether a compilation flag should be used on android
 func AndroidCompileFlags(s string) bool {
-	return !(machineSpecificFlag(s) || compilerStandard(s))
+	return AndroidCompileFlagDropReason(s) == ""
 }
 
-// Identify whether a link flag should be used on android
+// AndroidLinkFlagDropReason returns why a link flag is not used on
+// android, or "" if it is.
 //
 // The Android build system should set machine specific flags (so it
 // can do multi-arch builds), so filter these out from module
 // properties.
+func AndroidLinkFlagDropReason(s string) string {
+	if machineSpecificFlag(s) {
+		return "machine specific flags are set by Android"
+	}
+	return ""
+}
+
+// Identify whether a link flag should be used on android
 func AndroidLinkFlags(s string) bool {
-	return !machineSpecificFlag(s)
+	return AndroidLinkFlagDropReason(s) == ""
+}
+
+// DroppedFlag is a flag removed from a property of a module
+type DroppedFlag struct {
+	Property string `json:"property"`
+	Flag     string `json:"flag"`
+	Reason   string `json:"reason"`
+}
+
+// Audit records the flags removed from the properties of one module,
+// so that users can find out why a flag they set has no effect. A nil
+// Audit records nothing.
+type Audit struct {
+	Dropped []DroppedFlag
+}
+
+// Drop records that 'flag' was removed from 'property'
+func (a *Audit) Drop(property, flag, reason string) {
+	if a == nil {
+		return
+	}
+	for _, d := range a.Dropped {
+		if d.Property == property && d.Flag == flag {
+			return
+		}
+	}
+	a.Dropped = append(a.Dropped, DroppedFlag{property, flag, reason})
+}
+
+// Filter returns the flags for which dropReason returns "", and records
+// the others as dropped from 'property'.
+func (a *Audit) Filter(property string, dropReason func(string) string, flags []string) []string {
+	var kept []string
+	for _, flag := range flags {
+		if reason := dropReason(flag); reason != "" {
+			a.Drop(property, flag, reason)
+		} else {
+			kept = append(kept, flag)
+		}
+	}
+	return kept
 }
 
 func GetCompilerStandard(flags ...[]string) (std string) {
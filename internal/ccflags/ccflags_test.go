@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ccflags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ARM-software/bob-build/internal/utils"
+)
+
+// A representative set of module flags, as seen on Android
+var benchmarkFlags = []string{
+	"-Wall", "-Werror", "-Wno-unused-parameter", "-DLOG_TAG=\"bob\"",
+	"-DNDEBUG", "-march=armv8-a", "-mcpu=cortex-a76", "-std=c++17",
+	"-fno-exceptions", "-fvisibility=hidden", "-O2", "-g",
+	"-Iexternal/project/include", "-isystem", "prebuilts/include",
+}
+
+func Test_AndroidCompileFlags(t *testing.T) {
+	assert.Equal(t,
+		[]string{"-Wall", "-DFOO=1", "-fno-rtti", "-O2"},
+		utils.Filter(AndroidCompileFlags,
+			[]string{"-Wall", "-mthumb", "-DFOO=1", "-std=c99", "-fno-rtti", "-march=armv7-a", "-O2"}))
+}
+
+func Test_AndroidLinkFlags(t *testing.T) {
+	assert.Equal(t,
+		[]string{"-Wl,--no-undefined", "-std=c99"},
+		utils.Filter(AndroidLinkFlags, []string{"-m32", "-Wl,--no-undefined", "-std=c99"}))
+}
+
+func Test_Audit_Filter(t *testing.T) {
+	audit := &Audit{}
+	assert.Equal(t,
+		[]string{"-Wall", "-O2"},
+		audit.Filter("cflags", AndroidCompileFlagDropReason,
+			[]string{"-Wall", "-march=armv8-a", "-std=c99", "-O2", "-march=armv8-a"}))
+	assert.Equal(t, []DroppedFlag{
+		{"cflags", "-march=armv8-a", "machine specific flags are set by Android"},
+		{"cflags", "-std=c99", "the standard is passed to Android as the module's language standard"},
+	}, audit.Dropped)
+
+	// A nil audit only filters
+	var none *Audit
+	assert.Equal(t, []string{"-Wl,--no-undefined"},
+		none.Filter("ldflags", AndroidLinkFlagDropReason, []string{"-m32", "-Wl,--no-undefined"}))
+}
+
+func Test_GetCompilerStandard(t *testing.T) {
+	assert.Equal(t, "c++17", GetCompilerStandard([]string{"-std=c++11", "-Wall"}, []string{"-std=c++17"}))
+	assert.Equal(t, "", GetCompilerStandard([]string{"-Wall"}))
+}
+
+func Test_GetArmMode(t *testing.T) {
+	mode, err := GetArmMode([]string{"-mthumb"})
+	assert.Nil(t, err)
+	assert.Equal(t, "thumb", mode)
+
+	mode, err = GetArmMode([]string{"-Wall"}, []string{"-mno-thumb"})
+	assert.Nil(t, err)
+	assert.Equal(t, "arm", mode)
+
+	_, err = GetArmMode([]string{"-mthumb"}, []string{"-marm"})
+	assert.NotNil(t, err)
+}
+
+// The classifications are simple prefix checks. Caching their results in
+// a map is an order of magnitude slower than re-evaluating them, so these
+// benchmarks exist to catch any change that makes them more expensive.
+func BenchmarkAndroidCompileFlags(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		utils.Filter(AndroidCompileFlags, benchmarkFlags)
+	}
+}
+
+func BenchmarkAndroidLinkFlags(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		utils.Filter(AndroidLinkFlags, benchmarkFlags)
+	}
+}
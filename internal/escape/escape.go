@@ -18,6 +18,7 @@
 package escape
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/google/blueprint/proptools"
@@ -107,3 +108,34 @@ func EscapeTemplatedStringList(list []string, escapeFn func(string) string) []st
 	}
 	return list
 }
+
+// Find the characters in a string which are unlikely to be intended in
+// a build definition: control characters, and shell command
+// substitution. Escaping makes them harmless, but they usually come
+// from a quoting mistake or a value leaking in from the environment.
+//
+// A list describing each suspicious character or sequence once is
+// returned.
+func Suspicious(s string) []string {
+	found := []string{}
+	add := func(desc string) {
+		for _, f := range found {
+			if f == desc {
+				return
+			}
+		}
+		found = append(found, desc)
+	}
+
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			add(fmt.Sprintf("%q", r))
+		} else if r == '`' {
+			add("`")
+		}
+	}
+	if strings.Contains(s, "$(") {
+		add("$(")
+	}
+	return found
+}
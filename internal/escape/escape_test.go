@@ -115,3 +115,10 @@ func TestEscapeTemplatedString(t *testing.T) {
 			testcase.name)
 	}
 }
+
+func TestSuspicious(t *testing.T) {
+	assert.Empty(t, Suspicious(`-DVERSION="1.0" -I$SRCDIR/include`))
+	assert.Equal(t, []string{`'\n'`}, Suspicious("-DFOO\n"))
+	assert.Equal(t, []string{`'\x00'`, "`"}, Suspicious("a\x00`date`"))
+	assert.Equal(t, []string{"$("}, Suspicious("-DHOST=$(hostname)"))
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"strings"
+)
+
+// Flags whose value is the next element of the flag list. The value is
+// never treated as a flag in its own right.
+var flagsWithSeparateValue = map[string]bool{
+	"-D":             true,
+	"-I":             true,
+	"-U":             true,
+	"-Xclang":        true,
+	"-Xpreprocessor": true,
+	"-idirafter":     true,
+	"-imacros":       true,
+	"-include":       true,
+	"-iquote":        true,
+	"-isystem":       true,
+	"-x":             true,
+}
+
+// MergeUnique concatenates the input lists, keeping only the first
+// occurrence of each element. The order of the remaining elements is
+// preserved, so lists given earlier take priority over later ones.
+func MergeUnique(lists ...[]string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, list := range lists {
+		for _, s := range list {
+			if !seen[s] {
+				seen[s] = true
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// macroName returns the name of the macro set by a -D or -U flag
+func macroName(flag string) string {
+	return strings.SplitN(flag[2:], "=", 2)[0]
+}
+
+// MergeFlags concatenates lists of compiler flags, dropping -I, -D and
+// -U flags which have no effect because they repeat an earlier flag.
+//
+// A repeated -I<dir> is always dropped, as the compiler only searches
+// the first occurrence of a directory. A -D<macro> or -U<macro> is only
+// dropped when it matches the last flag for the same macro, so that
+// redefinitions keep their meaning. All other flags, and flags whose
+// value is given separately, are kept in place.
+func MergeFlags(lists ...[]string) []string {
+	includes := map[string]bool{}
+	macros := map[string]string{}
+	out := []string{}
+	valueFollows := false
+
+	for _, list := range lists {
+		for _, flag := range list {
+			if valueFollows {
+				valueFollows = false
+			} else if flagsWithSeparateValue[flag] {
+				valueFollows = true
+			} else if strings.HasPrefix(flag, "-I") {
+				if includes[flag] {
+					continue
+				}
+				includes[flag] = true
+			} else if strings.HasPrefix(flag, "-D") || strings.HasPrefix(flag, "-U") {
+				name := macroName(flag)
+				if macros[name] == flag {
+					continue
+				}
+				macros[name] = flag
+			}
+			out = append(out, flag)
+		}
+	}
+	return out
+}
+
+// MacroDefinitions returns the value of each macro defined by a list of
+// compiler flags, once all the -D and -U flags have been applied in
+// order. As with the compiler, a macro defined without a value is 1.
+// Macros which end up undefined are not included.
+func MacroDefinitions(flags []string) map[string]string {
+	macros := map[string]string{}
+	for i := 0; i < len(flags); i++ {
+		flag := flags[i]
+		if flagsWithSeparateValue[flag] {
+			if i+1 == len(flags) {
+				break
+			}
+			i++
+			if flag != "-D" && flag != "-U" {
+				continue
+			}
+			flag += flags[i]
+		}
+
+		if strings.HasPrefix(flag, "-D") {
+			parts := strings.SplitN(flag[2:], "=", 2)
+			if len(parts) == 1 {
+				macros[parts[0]] = "1"
+			} else {
+				macros[parts[0]] = parts[1]
+			}
+		} else if strings.HasPrefix(flag, "-U") {
+			delete(macros, macroName(flag))
+		}
+	}
+	return macros
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MergeUnique(t *testing.T) {
+	assert.Equal(t, []string{}, MergeUnique())
+	assert.Equal(t,
+		[]string{"parent", "child", "grandchild"},
+		MergeUnique([]string{"parent", "child"},
+			[]string{"child", "grandchild"},
+			[]string{"grandchild", "parent"}))
+}
+
+func Test_MergeUniqueDoesNotModifyInput(t *testing.T) {
+	first := make([]string, 1, 4)
+	first[0] = "a"
+	out := MergeUnique(first, []string{"b"})
+	out[0] = "c"
+	assert.Equal(t, []string{"a"}, first)
+}
+
+func Test_MergeFlagsDropsRepeatedIncludes(t *testing.T) {
+	assert.Equal(t,
+		[]string{"-Ia", "-O2", "-Ib", "-O2"},
+		MergeFlags([]string{"-Ia", "-O2", "-Ib"},
+			[]string{"-Ib", "-O2", "-Ia"}))
+}
+
+func Test_MergeFlagsDropsRepeatedDefines(t *testing.T) {
+	assert.Equal(t,
+		[]string{"-DA", "-DB=1"},
+		MergeFlags([]string{"-DA", "-DB=1"}, []string{"-DB=1", "-DA"}))
+}
+
+func Test_MergeFlagsKeepsRedefinitions(t *testing.T) {
+	// Each of these changes the value of FOO, so all must be kept
+	flags := []string{"-DFOO=1", "-DFOO=2", "-DFOO=1", "-UFOO", "-DFOO=1"}
+	assert.Equal(t, flags, MergeFlags(flags))
+
+	assert.Equal(t,
+		[]string{"-DFOO", "-UFOO"},
+		MergeFlags([]string{"-DFOO", "-UFOO"}, []string{"-UFOO"}))
+}
+
+func Test_MergeFlagsKeepsSeparateValues(t *testing.T) {
+	assert.Equal(t,
+		[]string{"-Ia", "-include", "a.h", "-include", "a.h",
+			"-Xclang", "-DX", "-Xclang", "-DX", "-I", "b", "-I", "b"},
+		MergeFlags([]string{"-Ia", "-include", "a.h", "-include", "a.h"},
+			[]string{"-Xclang", "-DX", "-Xclang", "-DX"},
+			[]string{"-I", "b", "-I", "b", "-Ia"}))
+}
+
+func Test_MacroDefinitions(t *testing.T) {
+	assert.Equal(t,
+		map[string]string{"A": "1", "B": "2", "D": "x=y", "E": ""},
+		MacroDefinitions([]string{"-DA", "-DB=1", "-O2", "-D", "B=2", "-DC",
+			"-UC", "-DD=x=y", "-DE=", "-Xclang", "-DF"}))
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevel is the verbosity of a message written to the generation log
+type LogLevel int
+
+const (
+	LogError LogLevel = iota
+	LogWarning
+	LogInfo
+	LogDebug
+)
+
+var logLevelNames = []string{"ERROR", "WARNING", "INFO", "DEBUG"}
+
+func (level LogLevel) String() string {
+	return logLevelNames[level]
+}
+
+// ParseLogLevel returns the level with the given name, ignoring case
+func ParseLogLevel(name string) (LogLevel, error) {
+	for i, levelName := range logLevelNames {
+		if strings.EqualFold(name, levelName) {
+			return LogLevel(i), nil
+		}
+	}
+	return LogError, fmt.Errorf("unknown log level '%s', expected one of %s",
+		name, strings.ToLower(strings.Join(logLevelNames, ", ")))
+}
+
+// LogObserver is called with each error and warning, whether or not
+// the generation log is written.
+type LogObserver func(level LogLevel, context string, msg string)
+
+type generationLog struct {
+	lock     sync.Mutex
+	file     *os.File
+	level    LogLevel
+	observer LogObserver
+}
+
+var genLog generationLog
+
+// rotateLogs renames the logs of earlier runs, so that filename.1 is
+// the most recent, keeping at most 'keep' of them.
+func rotateLogs(filename string, keep int) {
+	os.Remove(fmt.Sprintf("%s.%d", filename, keep))
+	for i := keep - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", filename, i), fmt.Sprintf("%s.%d", filename, i+1))
+	}
+	if keep > 0 {
+		os.Rename(filename, filename+".1")
+	}
+}
+
+// OpenLog starts writing the messages at or below 'level' to a new log
+// file, after rotating the logs of earlier runs.
+func OpenLog(filename string, level LogLevel, keep int) error {
+	genLog.lock.Lock()
+	defer genLog.lock.Unlock()
+
+	rotateLogs(filename, keep)
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	genLog.file = file
+	genLog.level = level
+	return nil
+}
+
+// CloseLog stops writing the generation log
+func CloseLog() {
+	genLog.lock.Lock()
+	defer genLog.lock.Unlock()
+
+	if genLog.file != nil {
+		genLog.file.Close()
+		genLog.file = nil
+	}
+}
+
+// ObserveLog sets the function called with each error and warning
+func ObserveLog(observer LogObserver) {
+	genLog.lock.Lock()
+	defer genLog.lock.Unlock()
+	genLog.observer = observer
+}
+
+// LogEnabled returns whether messages at a level are written to the log
+func LogEnabled(level LogLevel) bool {
+	genLog.lock.Lock()
+	defer genLog.lock.Unlock()
+	return genLog.file != nil && level <= genLog.level
+}
+
+// Logf writes a message to the generation log, with a timestamp and its
+// level. The context, e.g. the name of the module the message is about,
+// may be empty.
+func Logf(level LogLevel, context string, format string, a ...interface{}) {
+	genLog.lock.Lock()
+	observer := genLog.observer
+	genLog.lock.Unlock()
+	// Called without the lock, so that the observer can log
+	if observer != nil && level <= LogWarning {
+		observer(level, context, fmt.Sprintf(format, a...))
+	}
+
+	genLog.lock.Lock()
+	defer genLog.lock.Unlock()
+
+	if genLog.file == nil || level > genLog.level {
+		return
+	}
+	if context != "" {
+		context = " [" + context + "]"
+	}
+	fmt.Fprintf(genLog.file, "%s %s%s %s\n", time.Now().Format("2006-01-02T15:04:05.000Z07:00"),
+		level, context, fmt.Sprintf(format, a...))
+}
+
+// Warnf prints a warning, prefixed by its context when there is one,
+// and writes it to the generation log.
+func Warnf(context string, format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	if context != "" {
+		fmt.Fprintf(os.Stderr, "WARNING: %s: %s\n", context, msg)
+	} else {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", msg)
+	}
+	Logf(LogWarning, context, "%s", msg)
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021 Arm Limited.
+ * SPDX-License-Identifier: Apache-2.0
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseLogLevel(t *testing.T) {
+	level, err := ParseLogLevel("debug")
+	assert.Nil(t, err)
+	assert.Equal(t, LogDebug, level)
+
+	level, err = ParseLogLevel("WARNING")
+	assert.Nil(t, err)
+	assert.Equal(t, LogWarning, level)
+
+	_, err = ParseLogLevel("verbose")
+	assert.NotNil(t, err)
+}
+
+func Test_Logf_levels_and_rotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bob_log")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "generation.log")
+
+	for run := 1; run <= 3; run++ {
+		assert.Nil(t, OpenLog(filename, LogInfo, 2))
+		Logf(LogInfo, "libfoo", "run %d", run)
+		Logf(LogDebug, "libfoo", "not written")
+		assert.True(t, LogEnabled(LogWarning))
+		assert.False(t, LogEnabled(LogDebug))
+		CloseLog()
+	}
+	assert.False(t, LogEnabled(LogError))
+
+	content, err := ioutil.ReadFile(filename)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Equal(t, 1, len(lines))
+	assert.True(t, strings.HasSuffix(lines[0], " INFO [libfoo] run 3"), lines[0])
+
+	// Only two earlier logs are kept
+	previous, err := ioutil.ReadFile(filename + ".1")
+	assert.Nil(t, err)
+	assert.Contains(t, string(previous), "run 2")
+	previous, err = ioutil.ReadFile(filename + ".2")
+	assert.Nil(t, err)
+	assert.Contains(t, string(previous), "run 1")
+	_, err = os.Stat(filename + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_ObserveLog(t *testing.T) {
+	observed := []string{}
+	ObserveLog(func(level LogLevel, context string, msg string) {
+		observed = append(observed, level.String()+" "+context+" "+msg)
+	})
+	defer ObserveLog(nil)
+
+	// Observed even though no log is open
+	Logf(LogWarning, "libfoo", "glob %s matched no files", "*.S")
+	Logf(LogInfo, "", "not observed")
+	Logf(LogError, "", "failed")
+
+	assert.Equal(t, []string{
+		"WARNING libfoo glob *.S matched no files",
+		"ERROR  failed",
+	}, observed)
+}
@@ -22,8 +22,10 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 )
 
 var (
@@ -290,6 +292,10 @@ func NewStringSlice(lists ...[]string) []string {
 // Deferred functions will not execute.
 func Exit(exitCode int, err string, a ...interface{}) {
 	fmt.Fprintf(os.Stderr, err+"\n", a...)
+	if exitCode != 0 {
+		Logf(LogError, "", err, a...)
+	}
+	CloseLog()
 	os.Exit(exitCode)
 }
 
@@ -373,3 +379,101 @@ func SplitPath(path string) (components []string) {
 
 	return append(SplitPath(dir), file)
 }
+
+// ExpandBraces expands shell-style brace expressions in a pattern, so
+// that "a.{c,cpp}" gives "a.c" and "a.cpp". Braces may be nested. A
+// pattern without a complete brace expression is returned as is.
+func ExpandBraces(pattern string) []string {
+	start := strings.Index(pattern, "{")
+	if start < 0 {
+		return []string{pattern}
+	}
+
+	// Find the matching close brace, and the commas at its level
+	depth := 0
+	commas := []int{}
+	end := -1
+	for i := start; i < len(pattern) && end < 0; i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		}
+	}
+	if end < 0 {
+		return []string{pattern}
+	}
+
+	prefix, suffix := pattern[:start], pattern[end+1:]
+	bounds := append(append([]int{start}, commas...), end)
+
+	expanded := []string{}
+	for i := 0; i < len(bounds)-1; i++ {
+		alternative := pattern[bounds[i]+1 : bounds[i+1]]
+		expanded = append(expanded, ExpandBraces(prefix+alternative+suffix)...)
+	}
+	return expanded
+}
+
+// TransformPath replaces the matches of re in path with template, as
+// regexp.ReplaceAllString does. In addition to the capture groups of
+// re, the template can use ${dir}, ${stem} and ${ext}, which are the
+// directory of path, and its file name without and with only the
+// extension (without the dot). Named capture groups take precedence.
+func TransformPath(re *regexp.Regexp, path string, template string) string {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	placeholders := map[string]string{
+		"dir":  filepath.Dir(path),
+		"stem": strings.TrimSuffix(base, ext),
+		"ext":  strings.TrimPrefix(ext, "."),
+	}
+	for _, name := range re.SubexpNames() {
+		delete(placeholders, name)
+	}
+
+	template = Expand(template, func(s string) string {
+		if value, ok := placeholders[s]; ok {
+			// The value must not be expanded again by the regexp
+			return strings.Replace(value, "$", "$$", -1)
+		}
+		return "${" + s + "}"
+	})
+	return re.ReplaceAllString(path, template)
+}
+
+// ParallelFor calls work for each index from 0 to n-1, on a pool of
+// one worker per CPU. It returns once every call has completed. Calls
+// run concurrently, so work must guard any state it shares.
+func ParallelFor(n int, work func(i int)) {
+	workers := runtime.NumCPU()
+	if workers > n {
+		workers = n
+	}
+
+	indices := make(chan int)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				work(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+}
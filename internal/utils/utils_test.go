@@ -19,6 +19,8 @@ package utils
 
 import (
 	"fmt"
+	"regexp"
+	"sync"
 	"testing"
 	"unicode"
 
@@ -310,3 +312,46 @@ func Test_SplitPath(t *testing.T) {
 	assert.Equal(t, []string{"a", "rel", "path"}, SplitPath("a/rel/path"))
 	assert.Equal(t, []string{"a", "rel", "path"}, SplitPath("a/rel/path/"))
 }
+
+func Test_ExpandBraces(t *testing.T) {
+	assert.Equal(t, []string{"a.c"}, ExpandBraces("a.c"))
+	assert.Equal(t, []string{"a.c", "a.cpp"}, ExpandBraces("a.{c,cpp}"))
+	assert.Equal(t, []string{"x/a.lds", "x/b.lds", "y/a.lds", "y/b.lds"},
+		ExpandBraces("{x,y}/{a,b}.lds"))
+	assert.Equal(t, []string{"a1", "a2", "b"}, ExpandBraces("{a{1,2},b}"))
+	assert.Equal(t, []string{"a{b"}, ExpandBraces("a{b"))
+}
+
+func Test_TransformPath(t *testing.T) {
+	re := regexp.MustCompile(`(.*)\.proto$`)
+	assert.Equal(t, "foo/bar.pb.c", TransformPath(re, "foo/bar.proto", "$1.pb.c"))
+	assert.Equal(t, "gen/foo/bar_pb.c", TransformPath(re, "foo/bar.proto", "gen/${dir}/${stem}_pb.c"))
+	assert.Equal(t, "bar.proto.txt", TransformPath(re, "foo/bar.proto", "${stem}.${ext}.txt"))
+
+	// Named groups are used in preference to the placeholders
+	named := regexp.MustCompile(`(?P<dir>[^/]+)/(?P<file>.*)\.proto$`)
+	assert.Equal(t, "foo_bar.c", TransformPath(named, "foo/bar.proto", "${dir}_${file}.c"))
+	assert.Equal(t, "foo/bar.c", TransformPath(named, "foo/bar.proto", "${1}/${2}.c"))
+
+	// Placeholders are not expanded twice
+	assert.Equal(t, "a$1/b.c", TransformPath(re, "a$1/b.proto", "${dir}/${stem}.c"))
+}
+
+func Test_ParallelFor(t *testing.T) {
+	lock := sync.Mutex{}
+	seen := map[int]int{}
+	ParallelFor(100, func(i int) {
+		lock.Lock()
+		defer lock.Unlock()
+		seen[i]++
+	})
+
+	assert.Equal(t, 100, len(seen))
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, 1, seen[i], "index %d", i)
+	}
+
+	called := false
+	ParallelFor(0, func(i int) { called = true })
+	assert.False(t, called)
+}
@@ -72,6 +72,7 @@ type gensrcsProps struct {
 		Replace       []string
 		Implicit_srcs []string
 		Implicit_outs []string
+		File_args     []string
 	}
 }
 
@@ -83,6 +84,7 @@ type soongInout struct {
 	implicitSrcs android.Paths
 	implicitOuts android.WritablePaths
 	rspfile      android.WritablePath
+	fileArgs     []string
 }
 
 // helper interface to distinguish genrulebob/gensrcsbob module from other soong modules
@@ -344,6 +346,11 @@ func (m *genrulebobCommon) writeNinjaRules(ctx android.ModuleContext, args map[s
 		ruleparams.RspfileContent = *m.Properties.Rsp_content
 	}
 
+	fileArgs := utils.ContainsArg(m.Properties.Cmd, "file_args")
+	if fileArgs {
+		args["file_args"] = ""
+	}
+
 	// no need to keep depfile in args, as the same named argument will be provided by ninja BuildParams below,
 	// we need it however to check existence of cmd argument
 	keys := utils.SortedKeys(args)
@@ -361,6 +368,9 @@ func (m *genrulebobCommon) writeNinjaRules(ctx android.ModuleContext, args map[s
 		if m.Properties.Rsp_content != nil {
 			args["rspfile"] = io.rspfile.String()
 		}
+		if fileArgs {
+			args["file_args"] = strings.Join(io.fileArgs, " ")
+		}
 
 		ctx.Build(pctx,
 			android.BuildParams{
@@ -438,7 +448,7 @@ func (m *gensrcsbob) inoutForSrc(ctx android.ModuleContext, re *regexp.Regexp,
 	// helper to replace source path
 	replaceSource := func(props []string) (newProps []string) {
 		for _, prop := range props {
-			newProps = append(newProps, re.ReplaceAllString(source.Rel(), prop))
+			newProps = append(newProps, utils.TransformPath(re, source.Rel(), prop))
 		}
 		return
 	}
@@ -448,6 +458,7 @@ func (m *gensrcsbob) inoutForSrc(ctx android.ModuleContext, re *regexp.Regexp,
 	io.implicitSrcs = append(pathsForImplicitSrcs(ctx, source, replaceSource(m.Properties.Out.Implicit_srcs)),
 		commonImplicits...)
 	io.implicitOuts = pathsForModuleGen(ctx, replaceSource(m.Properties.Out.Implicit_outs))
+	io.fileArgs = replaceSource(m.Properties.Out.File_args)
 
 	if m.genrulebobCommon.Properties.Depfile {
 		io.depfile = pathForModuleGen(ctx, getDepfileName(source.Rel()))
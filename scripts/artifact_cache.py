@@ -0,0 +1,469 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Run a command, reusing its outputs from a cache when the same command
+has already been run on the same inputs.
+
+The cache key is made from the command line, with the build directory
+replaced so that build directories can share the cache, the content of
+every file named on the command line except the outputs, and the
+content of each tool given with --tool. Files named on the command line
+include shared libraries found with -L and -l.
+
+With --preprocess, the command is a C or C++ compile, and the key uses
+the preprocessed source rather than the files named on the command
+line, so that included headers are part of the key. The preprocessor
+also writes the dependency file, which ninja needs whether or not the
+compile runs.
+
+On a hit, the outputs are copied from the cache and the command is not
+run. Otherwise the command is run, and its outputs are stored in the
+cache if it succeeds.
+
+The cache is a local directory, an HTTP server accepting GET and PUT,
+or both, in which case the server is only used when the directory
+misses.
+
+For commands which can't be wrapped, --restore copies the outputs from
+the cache, failing on a miss, and --store adds them once the command
+has run. The key is then made from --action-key and the content of
+each --input.
+"""
+
+import argparse
+import hashlib
+import os
+import shutil
+import subprocess
+import sys
+import tempfile
+
+try:
+    from urllib.request import Request, urlopen
+except ImportError:
+    from urllib2 import Request, urlopen
+
+
+# Increase when the key calculation changes, to ignore older entries
+KEY_VERSION = b"2"
+
+# Seconds to wait for the cache server before treating it as a miss
+URL_TIMEOUT = 30
+
+
+def file_digest(path):
+    h = hashlib.sha256()
+    with open(path, "rb") as fp:
+        for chunk in iter(lambda: fp.read(1 << 16), b""):
+            h.update(chunk)
+    return h.digest()
+
+
+def which(tool):
+    if os.sep in tool:
+        return tool
+    if hasattr(shutil, "which"):
+        return shutil.which(tool)
+    for d in os.environ.get("PATH", "").split(os.pathsep):
+        path = os.path.join(d, tool)
+        if os.path.isfile(path) and os.access(path, os.X_OK):
+            return path
+    return None
+
+
+def tool_digest(tool):
+    """Identify a tool by its content, or by its name if it is not found"""
+    path = which(tool)
+    if path and os.path.isfile(path):
+        return file_digest(path)
+    return tool.encode("utf-8")
+
+
+def word_paths(word):
+    """Return the possible paths in a command line word, e.g. in -Wl,--flag=path"""
+    paths = []
+    for part in word.split(","):
+        paths.append(part)
+        if "=" in part:
+            paths.append(part.split("=", 1)[1])
+    return paths
+
+
+def find_library(name, lib_dirs):
+    for lib_dir in lib_dirs:
+        for ext in (".so", ".a"):
+            path = os.path.join(lib_dir, "lib" + name + ext)
+            if os.path.isfile(path):
+                return path
+    return None
+
+
+def preprocess_command(command, obj):
+    """Turn a compile command into one writing the preprocessed source
+    to stdout. The dependency file is still written, naming the object."""
+    pp = []
+    words = iter(command)
+    for word in words:
+        if word == "-c":
+            pp.append("-E")
+        elif word == "-o":
+            next(words, None)
+        else:
+            pp.append(word)
+    return pp + ["-MQ", obj]
+
+
+def preprocess(command, build_dir, outputs):
+    """Return the preprocessed source, or None if it can't be produced"""
+    with open(os.devnull, "wb") as devnull:
+        proc = subprocess.Popen(preprocess_command(command, outputs[0]),
+                                stdout=subprocess.PIPE, stderr=devnull)
+        out = proc.communicate()[0]
+    if proc.returncode != 0:
+        return None
+    return out.replace(build_dir.encode("utf-8"), b"@BUILDDIR@")
+
+
+def cache_key(command, build_dir, outputs, tools=(), preprocessed=None):
+    """Calculate the key of a command, which must not depend on the build directory"""
+    outputs = set(os.path.abspath(o) for o in outputs)
+    h = hashlib.sha256(KEY_VERSION)
+    lib_dirs = []
+
+    def add_file(path):
+        if os.path.isfile(path) and os.path.abspath(path) not in outputs:
+            h.update(file_digest(path))
+
+    for tool in tools:
+        h.update(tool_digest(tool))
+
+    if preprocessed is not None:
+        h.update(hashlib.sha256(preprocessed).digest())
+
+    for word in command:
+        h.update(word.replace(build_dir, "@BUILDDIR@").encode("utf-8") + b"\0")
+
+        if preprocessed is not None:
+            # The preprocessed source covers the files the compile reads
+            continue
+
+        if word.startswith("-L"):
+            lib_dirs.append(word[2:])
+        elif word.startswith("-l"):
+            path = find_library(word[2:], lib_dirs)
+            if path:
+                add_file(path)
+        else:
+            for path in word_paths(word):
+                add_file(path)
+
+    return h.hexdigest()
+
+
+def action_key(key, inputs):
+    """Calculate the key of an action described by the build system"""
+    h = hashlib.sha256(KEY_VERSION)
+    h.update(key.encode("utf-8") + b"\0")
+    for path in inputs:
+        h.update(file_digest(path) if os.path.isfile(path) else b"\0")
+    return h.hexdigest()
+
+
+def copy(src, dst):
+    shutil.copyfile(src, dst)
+    shutil.copymode(src, dst)
+
+
+class LocalCache(object):
+    def __init__(self, cache_dir):
+        self.cache_dir = cache_dir
+
+    def entry(self, key):
+        return os.path.join(self.cache_dir, key[:2], key)
+
+    def restore(self, key, outputs):
+        """Copy the outputs from a cache entry, returning False if it is missing or incomplete"""
+        cached = [os.path.join(self.entry(key), str(i)) for i in range(len(outputs))]
+        if not all(os.path.isfile(c) for c in cached):
+            return False
+        for src, dst in zip(cached, outputs):
+            copy(src, dst)
+        return True
+
+    def store(self, key, outputs):
+        """Add the outputs to the cache. The entry appears atomically, so
+        that concurrent builds never see a partial entry."""
+        entry = self.entry(key)
+        if os.path.isdir(entry):
+            return
+        try:
+            if not os.path.isdir(self.cache_dir):
+                os.makedirs(self.cache_dir)
+            tmp = tempfile.mkdtemp(prefix=".tmp", dir=self.cache_dir)
+        except OSError:
+            return
+        try:
+            for i, output in enumerate(outputs):
+                copy(output, os.path.join(tmp, str(i)))
+            parent = os.path.dirname(entry)
+            if not os.path.isdir(parent):
+                os.makedirs(parent)
+            os.rename(tmp, entry)
+        except OSError:
+            # Another build stored the same entry first, or the cache is
+            # not writable. Neither stops the build.
+            shutil.rmtree(tmp, ignore_errors=True)
+
+
+class RemoteCache(object):
+    """A cache on an HTTP server. Each entry is a file per output, and a
+    manifest holding the mode of each output, which is uploaded last so
+    that an entry is only used once it is complete."""
+
+    def __init__(self, url):
+        self.url = url.rstrip("/")
+
+    def get(self, path):
+        try:
+            return urlopen(self.url + "/" + path, timeout=URL_TIMEOUT).read()
+        except (IOError, OSError, ValueError):
+            return None
+
+    def put(self, path, data):
+        req = Request(self.url + "/" + path, data=data)
+        req.get_method = lambda: "PUT"
+        try:
+            urlopen(req, timeout=URL_TIMEOUT).read()
+            return True
+        except (IOError, OSError, ValueError):
+            return False
+
+    def restore(self, key, outputs):
+        manifest = self.get(key + "/manifest")
+        if manifest is None:
+            return False
+        try:
+            modes = [int(m, 8) for m in manifest.decode("utf-8").split()]
+        except ValueError:
+            return False
+        if len(modes) != len(outputs):
+            return False
+
+        contents = []
+        for i in range(len(outputs)):
+            data = self.get("{0}/{1}".format(key, i))
+            if data is None:
+                return False
+            contents.append(data)
+
+        for output, data, mode in zip(outputs, contents, modes):
+            with open(output, "wb") as fp:
+                fp.write(data)
+            os.chmod(output, mode)
+        return True
+
+    def store(self, key, outputs):
+        modes = []
+        for i, output in enumerate(outputs):
+            with open(output, "rb") as fp:
+                if not self.put("{0}/{1}".format(key, i), fp.read()):
+                    return
+            modes.append("{0:o}".format(os.stat(output).st_mode & 0o777))
+        self.put(key + "/manifest", "\n".join(modes).encode("utf-8"))
+
+
+def restore(caches, key, outputs):
+    """Restore the outputs from the first cache holding them, adding them
+    to the caches which missed"""
+    for i, cache in enumerate(caches):
+        if cache.restore(key, outputs):
+            for missed in caches[:i]:
+                missed.store(key, outputs)
+            return True
+    return False
+
+
+def store(caches, key, outputs):
+    if not all(os.path.isfile(o) for o in outputs):
+        return
+    for cache in caches:
+        cache.store(key, outputs)
+
+
+def run(caches, build_dir, outputs, command, tools=(), preprocessed=False):
+    pp = None
+    if preprocessed:
+        pp = preprocess(command, build_dir, outputs)
+        if pp is None:
+            # Let the compiler report the error
+            return subprocess.call(command)
+
+    key = cache_key(command, build_dir, outputs, tools, pp)
+    if restore(caches, key, outputs):
+        return 0
+
+    ret = subprocess.call(command)
+    if ret == 0:
+        store(caches, key, outputs)
+    return ret
+
+
+def parse_args():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("--cache-dir", help="Directory holding the cache")
+    parser.add_argument("--url", help="URL of an HTTP server holding the cache")
+    parser.add_argument("--build-dir", required=True, help="Build directory of the command")
+    parser.add_argument("--output", action="append", default=[], required=True,
+                        help="Output of the command. May be repeated")
+    parser.add_argument("--tool", action="append", default=[],
+                        help="Tool run by the command, identifying the toolchain. May be repeated")
+    parser.add_argument("--preprocess", action="store_true",
+                        help="Key a C or C++ compile by its preprocessed source")
+    group = parser.add_mutually_exclusive_group()
+    group.add_argument("--restore", action="store_true",
+                       help="Restore the outputs of --action-key, failing on a miss")
+    group.add_argument("--store", action="store_true",
+                       help="Store the outputs of --action-key")
+    parser.add_argument("--action-key", help="Key of the action, with --restore or --store")
+    parser.add_argument("--input", action="append", default=[],
+                        help="Input of the action, with --restore or --store. May be repeated")
+    parser.add_argument("command", nargs=argparse.REMAINDER,
+                        help="Command to run, following --")
+    args = parser.parse_args()
+
+    if not args.cache_dir and not args.url:
+        parser.error("--cache-dir or --url is required")
+    if args.command and args.command[0] == "--":
+        args.command = args.command[1:]
+    if args.restore or args.store:
+        if not args.action_key:
+            parser.error("--action-key is required with --restore and --store")
+        if args.command:
+            parser.error("no command is run with --restore and --store")
+    elif not args.command:
+        parser.error("no command given")
+    return args
+
+
+def write(path, content):
+    with open(path, "wt") as fp:
+        fp.write(content)
+
+
+def test_key_ignores_build_dir(tmp_path):
+    for name in ("release", "debug"):
+        os.makedirs(str(tmp_path / name))
+        write(str(tmp_path / name / "a.o"), "object")
+    keys = [cache_key(["ar", "-rcs", str(tmp_path / d / "a.a"), str(tmp_path / d / "a.o")],
+                      str(tmp_path / d), [str(tmp_path / d / "a.a")])
+            for d in ("release", "debug")]
+    assert keys[0] == keys[1]
+
+
+def test_key_depends_on_inputs_not_outputs(tmp_path):
+    tmp = str(tmp_path)
+    obj = os.path.join(tmp, "a.o")
+    out = os.path.join(tmp, "a.so")
+    lib = os.path.join(tmp, "libb.so")
+    for path in (obj, out, lib):
+        write(path, "1")
+    command = ["cc", "-shared", obj, "-o", out, "-Wl,-Map=" + out, "-L" + tmp, "-lb"]
+
+    key = cache_key(command, tmp, [out])
+    write(out, "2")
+    assert cache_key(command, tmp, [out]) == key
+
+    for path in (obj, lib):
+        write(path, "2")
+        new_key = cache_key(command, tmp, [out])
+        assert new_key != key
+        key = new_key
+
+
+def test_key_depends_on_tools(tmp_path):
+    tool = str(tmp_path / "cc")
+    write(tool, "1")
+    key = cache_key(["cc", "a.c"], str(tmp_path), ["a.o"], tools=[tool])
+    write(tool, "2")
+    assert cache_key(["cc", "a.c"], str(tmp_path), ["a.o"], tools=[tool]) != key
+
+
+def test_preprocess_command():
+    command = ["ccache", "gcc", "-c", "-O2", "-MMD", "-MF", "a.o.d", "a.c", "-o", "a.o"]
+    assert preprocess_command(command, "a.o") == [
+        "ccache", "gcc", "-E", "-O2", "-MMD", "-MF", "a.o.d", "a.c", "-MQ", "a.o"]
+
+
+def test_action_key(tmp_path):
+    src = str(tmp_path / "in.txt")
+    write(src, "1")
+    key = action_key("abc", [src])
+    assert action_key("abd", [src]) != key
+    write(src, "2")
+    assert action_key("abc", [src]) != key
+
+
+def test_run_reuses_outputs(tmp_path):
+    cache = LocalCache(str(tmp_path / "cache"))
+    out = str(tmp_path / "out")
+    counter = str(tmp_path / "counter")
+    command = ["sh", "-c", "echo x >> {0}; echo built > {1}".format(counter, out)]
+
+    assert run([cache], str(tmp_path), [out], command) == 0
+    os.remove(out)
+    assert run([cache], str(tmp_path), [out], command) == 0
+    with open(out, "rt") as fp:
+        assert fp.read() == "built\n"
+    with open(counter, "rt") as fp:
+        assert fp.read() == "x\n"
+
+
+def test_restore_fills_earlier_caches(tmp_path):
+    first = LocalCache(str(tmp_path / "first"))
+    second = LocalCache(str(tmp_path / "second"))
+    out = str(tmp_path / "out")
+    write(out, "built")
+    second.store("abcd", [out])
+    os.remove(out)
+
+    assert restore([first, second], "abcd", [out])
+    assert os.path.isdir(first.entry("abcd"))
+    assert not restore([first, second], "abce", [out])
+
+
+def main():
+    args = parse_args()
+    caches = []
+    if args.cache_dir:
+        caches.append(LocalCache(args.cache_dir))
+    if args.url:
+        caches.append(RemoteCache(args.url))
+
+    if args.restore or args.store:
+        key = action_key(args.action_key, args.input)
+        if args.store:
+            store(caches, key, args.output)
+            return 0
+        return 0 if restore(caches, key, args.output) else 1
+
+    return run(caches, args.build_dir, args.output, args.command, args.tool, args.preprocess)
+
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -0,0 +1,109 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Check that a shared library compiled with hidden symbol visibility only
+exports the symbols its objects give default visibility, which are the
+symbols annotated with the library's export macro. Symbols leaking from
+static libraries compiled without hidden visibility are reported.
+"""
+
+import argparse
+import logging
+import os
+import re
+import subprocess
+import sys
+
+
+logger = logging.getLogger(__name__)
+
+# Environment to use for processes we parse output from.
+# Force the C locale.
+child_env = os.environ.copy()
+child_env['LC_ALL'] = "C"
+
+# Symbols the linker defines in every shared library
+LINKER_SYMBOLS = {"_init", "_fini", "_edata", "_end", "__bss_start"}
+
+SYMBOL_RE = re.compile(r"^[0-9a-fA-F]+ (?P<flags>.{7}) (?P<section>\S+)\s+[0-9a-fA-F]+\s+(?P<rest>.*)$")
+
+
+def parse_args():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("input", help="Shared library to check")
+    parser.add_argument("objects", nargs="*", help="Objects compiled into the library")
+    parser.add_argument("-o", "--output", required=True, help="Stamp file to write")
+    parser.add_argument("--format", action="store",
+                        choices=["elf", "macho"], default="elf",
+                        help="Library format")
+    parser.add_argument("--objdump-tool", default="objdump",
+                        help="Tool used to read the symbol tables of Elf files")
+    parser.add_argument("--otool-tool", default="otool", help=argparse.SUPPRESS)
+    parser.add_argument("--nm-tool", default="nm", help=argparse.SUPPRESS)
+    return parser.parse_args()
+
+
+def global_symbols(objdump, flag, path):
+    """
+    Return the defined global and weak symbols of a file, mapped to
+    whether they have hidden visibility.
+    """
+    output = subprocess.check_output([objdump, flag, path], env=child_env)
+    symbols = {}
+    for line in output.decode("utf-8").splitlines():
+        match = SYMBOL_RE.match(line)
+        if not match:
+            continue
+        flags = match.group("flags")
+        if flags[0] not in "gu" and flags[1] != "w":
+            continue
+        if match.group("section") == "*UND*":
+            continue
+        fields = match.group("rest").split()
+        if not fields:
+            continue
+        hidden = ".hidden" in fields or ".internal" in fields
+        symbols[fields[-1]] = hidden
+    return symbols
+
+
+def main():
+    args = parse_args()
+    logging.basicConfig(format="%(levelname)s: %(message)s")
+
+    # Only Elf symbol tables record the visibility of each symbol
+    if args.format == "elf":
+        annotated = set(LINKER_SYMBOLS)
+        for obj in args.objects:
+            symbols = global_symbols(args.objdump_tool, "-t", obj)
+            annotated.update(name for name, hidden in symbols.items() if not hidden)
+
+        exported = global_symbols(args.objdump_tool, "-T", args.input)
+        leaked = sorted(name for name in exported if name not in annotated)
+        if leaked:
+            logger.error("%s exports symbols which are not annotated for export:\n    %s",
+                         args.input, "\n    ".join(leaked))
+            sys.exit(1)
+
+    with open(args.output, "w"):
+        pass
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,124 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Write a manifest of the SHA-256 checksums of a list of files.
+
+The manifest has the format of `sha256sum`, with one line per file, so
+it can be checked with `sha256sum --check` from the directory the paths
+are relative to. When a key is given, the manifest is signed with
+`openssl dgst -sha256 -sign`, and the signature can be verified with
+`openssl dgst -sha256 -verify <public key> -signature <signature>`.
+"""
+
+import argparse
+import hashlib
+import io
+import logging
+import subprocess
+import sys
+
+
+logger = logging.getLogger(__name__)
+
+
+def parse_entries(text):
+    """Return the (path, file) pairs in the list of entries, which are
+    whitespace separated path=file pairs, sorted by path."""
+    entries = []
+    for pair in text.split():
+        path, _, filename = pair.partition("=")
+        if not path or not filename:
+            raise ValueError("Invalid entry '{}', expected path=file".format(pair))
+        entries.append((path, filename))
+    return sorted(entries)
+
+
+def sha256_file(filename):
+    digest = hashlib.sha256()
+    with open(filename, "rb") as fp:
+        for block in iter(lambda: fp.read(1 << 16), b""):
+            digest.update(block)
+    return digest.hexdigest()
+
+
+def manifest_lines(entries):
+    return ["{}  {}\n".format(sha256_file(filename), path) for path, filename in entries]
+
+
+def sign(manifest, key, signature):
+    subprocess.check_call(["openssl", "dgst", "-sha256", "-sign", key,
+                           "-out", signature, manifest])
+
+
+def test_parse_entries():
+    assert parse_entries("lib/libfoo.so=out/libfoo.so\n bin/foo=out/foo ") == \
+        [("bin/foo", "out/foo"), ("lib/libfoo.so", "out/libfoo.so")]
+
+    try:
+        parse_entries("bin/foo")
+        assert False, "Expected a ValueError"
+    except ValueError:
+        pass
+
+
+def test_manifest_lines(tmp_path):
+    src = tmp_path / "foo"
+    src.write_text(u"foo\n")
+
+    assert manifest_lines([("bin/foo", str(src))]) == [
+        "b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c  bin/foo\n"]
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("-o", "--output", required=True, help="Manifest to write")
+    parser.add_argument("--entries", required=True,
+                        help="File listing the files to checksum, as path=file pairs "
+                             "separated by whitespace")
+    parser.add_argument("--key", help="Private key to sign the manifest with")
+    parser.add_argument("--signature", help="Signature to write")
+    args = parser.parse_args()
+
+    logging.basicConfig(format="%(levelname)s: %(message)s", level=logging.WARNING)
+
+    if bool(args.key) != bool(args.signature):
+        logger.error("--key and --signature must be used together")
+        return 1
+
+    with io.open(args.entries, "rt") as fp:
+        try:
+            entries = parse_entries(fp.read())
+        except ValueError as e:
+            logger.error("%s", str(e))
+            return 1
+
+    with io.open(args.output, "wt") as fp:
+        fp.writelines(manifest_lines(entries))
+
+    if args.key:
+        try:
+            sign(args.output, args.key, args.signature)
+        except (OSError, subprocess.CalledProcessError) as e:
+            logger.error("Signing %s failed: %s", args.output, str(e))
+            return 1
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
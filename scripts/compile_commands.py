@@ -0,0 +1,168 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+"""
+Write and merge compilation databases (compile_commands.json) for the
+objects of out-of-tree kernel module builds.
+
+Kbuild records the command used for each object in a `.<object>.cmd`
+file next to it. `kmod_build.py` collects these commands after a build,
+mapping the copies of the sources it gave to Kbuild back to the
+originals, so that tools such as clangd find them when editing the
+module's sources. When run as a script, the databases of several modules
+are merged into one.
+"""
+
+import argparse
+import io
+import json
+import logging
+import os
+import re
+import sys
+
+
+logger = logging.getLogger(__name__)
+
+# The command line of an object in a Kbuild .cmd file. Newer kernels name
+# the variable savedcmd_<object>.
+CMD_LINE = re.compile(r"^(saved)?cmd_[^ ]*\.o := (?P<prefix>.* )(?P<file>[^ ]*\.[cS]) *(;|$)")
+
+
+def kbuild_entries(kdir, module_dir, sources):
+    """Return the compile_commands.json entries for the objects Kbuild
+    built in module_dir. Kbuild runs the commands in kdir. sources maps
+    the absolute path of each copied source to the original one."""
+    entries = []
+    for dirpath, dirnames, filenames in os.walk(module_dir):
+        dirnames.sort()
+        for name in sorted(filenames):
+            if not (name.startswith(".") and name.endswith(".o.cmd")):
+                continue
+            with io.open(os.path.join(dirpath, name), "rt") as fp:
+                for line in fp:
+                    match = CMD_LINE.match(line.rstrip("\n"))
+                    if not match:
+                        continue
+                    src = os.path.normpath(os.path.join(kdir, match.group("file")))
+                    src = sources.get(src, src)
+                    entries.append({
+                        "directory": kdir,
+                        "file": src,
+                        "command": match.group("prefix") + src,
+                    })
+                    break
+    return entries
+
+
+def merge(existing, databases):
+    """Merge the entries of several databases into an existing one. The
+    entries of the databases replace any existing entries for the same
+    file."""
+    files = set(entry["file"] for database in databases for entry in database)
+    merged = [entry for entry in existing if entry["file"] not in files]
+    for database in databases:
+        merged.extend(database)
+    return sorted(merged, key=lambda entry: entry["file"])
+
+
+def read(filename):
+    with io.open(filename, "rt") as fp:
+        return json.load(fp)
+
+
+def write(filename, entries):
+    with open(filename, "w") as fp:
+        fp.write(json.dumps(entries, indent=2, sort_keys=True))
+        fp.write("\n")
+
+
+def test_kbuild_entries(tmp_path):
+    kdir = str(tmp_path / "kernel")
+    module_dir = tmp_path / "out" / "module"
+    module_dir.mkdir(parents=True)
+    copy = str(module_dir / "driver.c")
+    (module_dir / ".driver.o.cmd").write_text(
+        u"cmd_{0}/driver.o := gcc -DFOO=1 -c -o {0}/driver.o {0}/driver.c\n"
+        u"\n"
+        u"source_{0}/driver.o := {0}/driver.c\n".format(str(module_dir)))
+    (module_dir / ".driver.mod.o.cmd").write_text(
+        u"savedcmd_driver.mod.o := gcc -c -o driver.mod.o ../out/module/driver.mod.c\n")
+    (module_dir / "driver.o.d").write_text(u"cmd_ignored.o := gcc -c ignored.c\n")
+
+    entries = kbuild_entries(kdir, str(module_dir), {copy: "/src/driver.c"})
+    assert entries == [
+        {
+            "directory": kdir,
+            "file": os.path.join(str(tmp_path), "out", "module", "driver.mod.c"),
+            "command": "gcc -c -o driver.mod.o " +
+                       os.path.join(str(tmp_path), "out", "module", "driver.mod.c"),
+        },
+        {
+            "directory": kdir,
+            "file": "/src/driver.c",
+            "command": "gcc -DFOO=1 -c -o {0}/driver.o /src/driver.c".format(str(module_dir)),
+        },
+    ]
+
+
+def test_merge():
+    existing = [
+        {"directory": "/build", "file": "/src/main.c", "command": "cc -c /src/main.c"},
+        {"directory": "/kernel", "file": "/src/driver.c", "command": "old"},
+    ]
+    database = [{"directory": "/kernel", "file": "/src/driver.c", "command": "new"}]
+    other = [{"directory": "/kernel", "file": "/src/bus.c", "command": "cc -c /src/bus.c"}]
+
+    assert merge(existing, [database, other]) == [
+        other[0],
+        database[0],
+        existing[0],
+    ]
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("-o", "--output", required=True,
+                        help="Compilation database to merge into")
+    parser.add_argument("databases", nargs="*", metavar="DATABASE",
+                        help="Compilation databases to merge")
+    args = parser.parse_args()
+
+    logging.basicConfig(format="%(levelname)s: %(message)s", level=logging.WARNING)
+
+    # Keep the entries of other tools, such as `ninja -t compdb`, which
+    # may have written the existing database.
+    existing = []
+    if os.path.exists(args.output):
+        try:
+            existing = read(args.output)
+        except ValueError as e:
+            logger.warning("Replacing %s, which can't be read: %s", args.output, str(e))
+
+    try:
+        databases = [read(database) for database in args.databases]
+    except (IOError, ValueError) as e:
+        logger.error("%s", str(e))
+        return 1
+
+    write(args.output, merge(existing, databases))
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -0,0 +1,169 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Create a distribution package from a list of files.
+
+All files are placed in a top level directory named after the package.
+Timestamps and ownership are not recorded, so the package only changes
+when the packaged files do.
+"""
+
+import argparse
+import gzip
+import io
+import logging
+import os
+import stat
+import sys
+import tarfile
+import zipfile
+
+
+logger = logging.getLogger(__name__)
+
+# Zip files cannot represent times before 1980
+ZIP_DATE_TIME = (1980, 1, 1, 0, 0, 0)
+
+
+def parse_entries(text):
+    """Return the (archive path, file) pairs in the list of entries, which
+    are whitespace separated archive_path=file pairs."""
+    entries = []
+    for pair in text.split():
+        path, _, filename = pair.partition("=")
+        if not path or not filename:
+            raise ValueError("Invalid entry '{}', expected archive_path=file".format(pair))
+        entries.append((path, filename))
+    return sorted(entries)
+
+
+def file_mode(filename):
+    """Return the permissions to record for a file. Only the executable
+    bits of the source are kept."""
+    if os.stat(filename).st_mode & stat.S_IXUSR:
+        return 0o755
+    return 0o644
+
+
+def write_tar(output, root, entries):
+    # Don't let gzip record the time either
+    with open(output, "wb") as raw, \
+            gzip.GzipFile(filename="", fileobj=raw, mode="wb", mtime=0) as gz, \
+            tarfile.open(fileobj=gz, mode="w") as tar:
+        for path, filename in entries:
+            info = tarfile.TarInfo(os.path.join(root, path))
+            info.uid = info.gid = 0
+            info.uname = info.gname = ""
+            info.mtime = 0
+            if os.path.islink(filename):
+                info.type = tarfile.SYMTYPE
+                info.linkname = os.readlink(filename)
+                info.mode = 0o777
+                tar.addfile(info)
+                continue
+            info.size = os.path.getsize(filename)
+            info.mode = file_mode(filename)
+            with open(filename, "rb") as fp:
+                tar.addfile(info, fp)
+
+
+def write_zip(output, root, entries):
+    """Write a zip file. Symlinks cannot be stored portably, so the
+    files they point to are stored in their place."""
+    with zipfile.ZipFile(output, "w", zipfile.ZIP_DEFLATED) as zf:
+        for path, filename in entries:
+            info = zipfile.ZipInfo(os.path.join(root, path), ZIP_DATE_TIME)
+            info.compress_type = zipfile.ZIP_DEFLATED
+            info.external_attr = file_mode(filename) << 16
+            with open(filename, "rb") as fp:
+                zf.writestr(info, fp.read())
+
+
+def test_parse_entries():
+    assert parse_entries("lib/libfoo.so=out/libfoo.so\n bin/foo=out/foo ") == \
+        [("bin/foo", "out/foo"), ("lib/libfoo.so", "out/libfoo.so")]
+
+    try:
+        parse_entries("bin/foo")
+        assert False, "Expected a ValueError"
+    except ValueError:
+        pass
+
+
+def test_write_tar(tmp_path):
+    src = tmp_path / "foo"
+    src.write_text(u"foo\n")
+    os.chmod(str(src), 0o700)
+    os.symlink("foo", str(tmp_path / "foo_link"))
+    out = str(tmp_path / "pkg.tar.gz")
+
+    write_tar(out, "pkg-1.0", [("bin/foo", str(src)),
+                               ("bin/foo2", str(tmp_path / "foo_link"))])
+
+    with tarfile.open(out) as tar:
+        foo = tar.getmember("pkg-1.0/bin/foo")
+        assert foo.mode == 0o755 and foo.mtime == 0 and foo.uid == 0
+        assert tar.extractfile(foo).read() == b"foo\n"
+        link = tar.getmember("pkg-1.0/bin/foo2")
+        assert link.issym() and link.linkname == "foo"
+
+
+def test_write_zip(tmp_path):
+    src = tmp_path / "foo"
+    src.write_text(u"foo\n")
+    out = str(tmp_path / "pkg.zip")
+
+    write_zip(out, "pkg", [("data/foo", str(src))])
+
+    with zipfile.ZipFile(out) as zf:
+        assert zf.namelist() == ["pkg/data/foo"]
+        assert zf.read("pkg/data/foo") == b"foo\n"
+        assert zf.getinfo("pkg/data/foo").date_time == ZIP_DATE_TIME
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("-o", "--output", required=True, help="Package to create")
+    parser.add_argument("--root", required=True,
+                        help="Name of the top level directory in the package")
+    parser.add_argument("--format", choices=["tar.gz", "zip"], default="tar.gz",
+                        help="Package format")
+    parser.add_argument("--entries", required=True,
+                        help="File listing the files to package, as archive_path=file "
+                             "pairs separated by whitespace")
+    args = parser.parse_args()
+
+    logging.basicConfig(format="%(levelname)s: %(message)s", level=logging.WARNING)
+
+    with io.open(args.entries, "rt") as fp:
+        try:
+            entries = parse_entries(fp.read())
+        except ValueError as e:
+            logger.error("%s", str(e))
+            return 1
+
+    if args.format == "zip":
+        write_zip(args.output, args.root, entries)
+    else:
+        write_tar(args.output, args.root, entries)
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
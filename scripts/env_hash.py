@@ -17,6 +17,7 @@
 
 import argparse
 import hashlib
+import json
 import os
 import sys
 
@@ -29,7 +30,19 @@ sys.path.append(CFG_DIR)
 import config_system.utils as utils  # nopep8: E402 module level import not at top of file
 
 
-def hash_env():
+def template_env_allowlist(config_json):
+    """Return the environment variables templates may read, as listed in
+    TEMPLATE_ENV_ALLOWLIST in the JSON configuration"""
+    if config_json is None or not os.path.exists(config_json):
+        return []
+    with open(config_json) as fp:
+        option = json.load(fp).get("template_env_allowlist", {})
+    if option.get("ignore", True):
+        return []
+    return option.get("value", "").split()
+
+
+def hash_env(extra_env=None):
     """Hash only relevant environment options"""
 
     # List of relevant options which might influence the generation of
@@ -48,10 +61,14 @@ def hash_env():
         "BOB_ALWAYS_LINK_SHARED_LIBS",
         "BOB_BOOTSTRAP_VERSION",
         "BOB_CONFIG_OPTS",
+        "BOB_CONFIG_OVERRIDES",
         "BOB_CONFIG_PLUGIN_OPTS",
         "BOB_CPUPROFILE",
         "BOB_DIR",
+        "BOB_EVENT_LOG",
         "BOB_LINK_PARALLELISM",
+        "BOB_LOG_LEVEL",
+        "BOB_TRACE",
         "BOB_VERSION",
         "BUILDDIR",
         "CONFIG_FILE",
@@ -96,6 +113,8 @@ def hash_env():
         "ARMROOT"
     ]
 
+    relevant_env += extra_env or []
+
     m = hashlib.sha256()
     for k in sorted(os.environ.keys()):
         if k in relevant_env:
@@ -108,10 +127,10 @@ def hash_env():
     return m.hexdigest()
 
 
-def write_env_hash(filename):
+def write_env_hash(filename, extra_env=None):
     """Write a hash of the current environment to the named file."""
     with utils.open_and_write_if_changed(filename) as fp:
-        fp.write(hash_env())
+        fp.write(hash_env(extra_env))
 
 
 def test_hash_env_relevant():
@@ -153,13 +172,33 @@ def test_hash_env_irrelevant():
     assert org_hash == hash_env()
 
 
+def test_hash_env_allowlisted():
+    """Test if variables read by templates change the hash"""
+    os.environ['BOB_TEST_TEMPLATE_ENV'] = "a"
+    org_hash = hash_env(["BOB_TEST_TEMPLATE_ENV"])
+    os.environ['BOB_TEST_TEMPLATE_ENV'] = "b"
+    assert org_hash != hash_env(["BOB_TEST_TEMPLATE_ENV"])
+    assert hash_env() == hash_env([])
+    del os.environ['BOB_TEST_TEMPLATE_ENV']
+
+
+def test_template_env_allowlist(tmpdir):
+    config_json = str(tmpdir.join("config.json"))
+    with open(config_json, "w") as fp:
+        json.dump({"template_env_allowlist": {"ignore": False, "value": "FOO BAR"}}, fp)
+    assert template_env_allowlist(config_json) == ["FOO", "BAR"]
+    assert template_env_allowlist(str(tmpdir.join("missing.json"))) == []
+
+
 def main():
     parser = argparse.ArgumentParser()
     parser.add_argument("output",
                         help="Output file to write containing environment hash")
+    parser.add_argument("--config-json", default=None,
+                        help="JSON configuration, listing the variables templates may read")
     args = parser.parse_args()
 
-    write_env_hash(args.output)
+    write_env_hash(args.output, template_env_allowlist(args.config_json))
 
 
 if __name__ == "__main__":
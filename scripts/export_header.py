@@ -0,0 +1,56 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Write the header defining the export macro of a shared library compiled
+with hidden symbol visibility. Declarations annotated with the macro are
+exported from the library; everything else stays hidden.
+"""
+
+import argparse
+
+
+HEADER = """\
+/* Generated by Bob. Do not edit. */
+
+#ifndef {guard}
+#define {guard}
+
+#if defined(__GNUC__) || defined(__clang__)
+#define {macro} __attribute__((visibility("default")))
+#else
+#define {macro}
+#endif
+
+#endif /* {guard} */
+"""
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("-o", "--output", required=True, help="Header to write")
+    parser.add_argument("--macro", required=True, help="Name of the export macro")
+    args = parser.parse_args()
+
+    content = HEADER.format(guard=args.macro + "_H", macro=args.macro)
+    with open(args.output, "w") as fp:
+        fp.write(content)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,168 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Check that the sources of modules are formatted with clang-format.
+
+The `check` command runs clang-format on the sources of one module, and
+records the files which are not formatted in a JSON result file. It
+succeeds whatever the result, so that every module is checked.
+
+The `summary` command reads the result files of all modules, reports the
+unformatted files grouped by module, and fails if there are any.
+"""
+
+import argparse
+import json
+import subprocess
+import sys
+
+
+def check_file(clang_format, src):
+    """Return the clang-format diagnostics for a file, or None if it is formatted"""
+    proc = subprocess.Popen([clang_format, "--dry-run", "--Werror", src],
+                            stdout=subprocess.PIPE, stderr=subprocess.STDOUT,
+                            universal_newlines=True)
+    output, _ = proc.communicate()
+    if proc.returncode == 0:
+        return None
+    return output
+
+
+def check_module(module, srcs, check=check_file):
+    """Return the result of checking the sources of a module"""
+    failures = []
+    for src in srcs:
+        output = check(src)
+        if output is not None:
+            failures.append({"file": src, "output": output})
+    return {"module": module, "files": len(srcs), "failures": failures}
+
+
+def summarize(results):
+    """Return the report of a list of module results, and whether all files are formatted"""
+    report = ""
+    modules = []
+    files = 0
+    for result in sorted(results, key=lambda r: r["module"]):
+        if not result["failures"]:
+            continue
+        modules.append(result["module"])
+        report += "==== {} ====\n".format(result["module"])
+        for failure in result["failures"]:
+            files += 1
+            report += failure["output"] or "{}: not formatted\n".format(failure["file"])
+        report += "\n"
+
+    if not modules:
+        return report, True
+
+    report += "{} file(s) in {} module(s) need formatting:\n".format(files, len(modules))
+    for module in modules:
+        report += "    {}\n".format(module)
+    return report, False
+
+
+def parse_args():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    subparsers = parser.add_subparsers(dest="command")
+
+    check = subparsers.add_parser("check", help="Check the sources of one module")
+    check.add_argument("-o", "--output", required=True, help="Result file to write")
+    check.add_argument("--module", required=True, help="Name of the module")
+    check.add_argument("--clang-format", default="clang-format",
+                       help="clang-format executable")
+    check.add_argument("srcs", nargs="*", help="Sources to check")
+
+    summary = subparsers.add_parser("summary", help="Report the results of all modules")
+    summary.add_argument("-o", "--output", required=True,
+                         help="File to create when all sources are formatted")
+    summary.add_argument("--results", required=True,
+                         help="File listing the result files, separated by whitespace")
+
+    args = parser.parse_args()
+    if args.command is None:
+        parser.error("a command is required")
+    return args
+
+
+def test_check_module():
+    outputs = {"a.c": None, "b.c": "b.c:1:1: error: code should be clang-formatted\n"}
+    result = check_module("libfoo", ["a.c", "b.c"], check=outputs.get)
+    assert result == {
+        "module": "libfoo",
+        "files": 2,
+        "failures": [{"file": "b.c", "output": outputs["b.c"]}],
+    }
+
+
+def test_summarize_formatted():
+    assert summarize([{"module": "libfoo", "files": 2, "failures": []}]) == ("", True)
+
+
+def test_summarize_failures():
+    results = [
+        {"module": "libzoo", "files": 1, "failures": [{"file": "z.c", "output": ""}]},
+        {"module": "libbar", "files": 1, "failures": []},
+        {"module": "libfoo", "files": 1, "failures": [{"file": "f.c", "output": "f.c: bad\n"}]},
+    ]
+    assert summarize(results) == (
+        "==== libfoo ====\n"
+        "f.c: bad\n"
+        "\n"
+        "==== libzoo ====\n"
+        "z.c: not formatted\n"
+        "\n"
+        "2 file(s) in 2 module(s) need formatting:\n"
+        "    libfoo\n"
+        "    libzoo\n", False)
+
+
+def main():
+    args = parse_args()
+
+    if args.command == "check":
+        try:
+            result = check_module(args.module, args.srcs,
+                                  check=lambda src: check_file(args.clang_format, src))
+        except OSError as e:
+            sys.stderr.write("error: could not run {}: {}\n".format(args.clang_format, e))
+            return 1
+        with open(args.output, "wt") as fp:
+            json.dump(result, fp, indent=2, sort_keys=True)
+        return 0
+
+    with open(args.results, "rt") as fp:
+        result_files = fp.read().split()
+    results = []
+    for result_file in result_files:
+        with open(result_file, "rt") as fp:
+            results.append(json.load(fp))
+
+    report, ok = summarize(results)
+    sys.stdout.write(report)
+    if not ok:
+        return 1
+
+    with open(args.output, "wt"):
+        pass
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -0,0 +1,108 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Build a Go host tool with the go command.
+
+The module cache (GOMODCACHE, or GOPATH for older Go versions) and the
+build cache are kept in the build directory, so that tools are built
+the same way on every machine, and share compiled packages.
+"""
+
+import argparse
+import logging
+import os
+import subprocess
+import sys
+
+
+logger = logging.getLogger(__name__)
+
+
+def go_environment(env, cache_dir, goflags):
+    """Return the environment to run the go command in. Modules are
+    always enabled, as tools are found through their go.mod."""
+    env = dict(env)
+    cache_dir = os.path.abspath(cache_dir)
+    env["GOPATH"] = os.path.join(cache_dir, "path")
+    env["GOMODCACHE"] = os.path.join(cache_dir, "path", "pkg", "mod")
+    env["GOCACHE"] = os.path.join(cache_dir, "cache")
+    env["GO111MODULE"] = "on"
+    if goflags:
+        env["GOFLAGS"] = goflags
+    else:
+        env.pop("GOFLAGS", None)
+    return env
+
+
+def build_command(go, package, output, tags, ldflags):
+    cmd = [go, "build", "-trimpath", "-o", os.path.abspath(output)]
+    if tags:
+        cmd += ["-tags", tags]
+    if ldflags:
+        cmd += ["-ldflags", ldflags]
+    return cmd + [package]
+
+
+def test_go_environment():
+    env = go_environment({"HOME": "/home/user", "GOFLAGS": "-v"}, "/build/go", "")
+    assert env["HOME"] == "/home/user"
+    assert env["GOPATH"] == "/build/go/path"
+    assert env["GOMODCACHE"] == "/build/go/path/pkg/mod"
+    assert env["GOCACHE"] == "/build/go/cache"
+    assert "GOFLAGS" not in env
+
+    env = go_environment({}, "/build/go", "-mod=vendor")
+    assert env["GOFLAGS"] == "-mod=vendor"
+
+
+def test_build_command():
+    assert build_command("go", "./cmd/gen", "/build/gen", "", "") == \
+        ["go", "build", "-trimpath", "-o", "/build/gen", "./cmd/gen"]
+    assert build_command("go", ".", "/build/gen", "netgo,osusergo", "-s -w") == \
+        ["go", "build", "-trimpath", "-o", "/build/gen",
+         "-tags", "netgo,osusergo", "-ldflags", "-s -w", "."]
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("--go", default="go", help="The go command")
+    parser.add_argument("--goflags", default="", help="Value of GOFLAGS")
+    parser.add_argument("--cache-dir", required=True,
+                        help="Directory holding the module and build caches")
+    parser.add_argument("--dir", required=True, help="Directory to run the go command in")
+    parser.add_argument("--package", default=".", help="Package to build, relative to --dir")
+    parser.add_argument("--tags", default="", help="Comma separated build tags")
+    parser.add_argument("--ldflags", default="", help="Flags passed to the Go linker")
+    parser.add_argument("-o", "--output", required=True, help="Binary to write")
+    args = parser.parse_args()
+
+    logging.basicConfig(format="%(levelname)s: %(message)s", level=logging.WARNING)
+
+    env = go_environment(os.environ, args.cache_dir, args.goflags)
+    cmd = build_command(args.go, args.package, args.output, args.tags, args.ldflags)
+    try:
+        subprocess.check_call(cmd, cwd=args.dir, env=env)
+    except (OSError, subprocess.CalledProcessError) as e:
+        logger.error("%s", str(e))
+        return 1
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -23,6 +23,7 @@ import subprocess
 import sys
 import shutil
 
+import compile_commands
 import copy_with_deps
 import kernel_config_parser
 
@@ -209,6 +210,8 @@ def parse_args():
                         help="Allow N jobs at once")
     parser.add_argument("--make-command", "-M", default="make",
                         help="Path to `make` command")
+    parser.add_argument("--compile-commands", metavar="FILE", default=None,
+                        help="Compilation database of the module's sources to write")
 
     group = parser.add_argument_group("Kernel options")
     group.add_argument("--kernel", "-k", metavar="KDIR", required=True,
@@ -290,6 +293,7 @@ def main():
         logger.warning(msg, autoconf_file)
 
     deps = []
+    copied_sources = dict()
 
     # Add commonly needed search paths for copy_with_deps
     search_path.extend([str.format(d, kdir=abs_kdir, arch=arch) for d in kernel_search_paths])
@@ -303,6 +307,7 @@ def main():
             sys.exit(1)
 
         dest = os.path.join(output_dir, src_rel)
+        copied_sources[os.path.abspath(dest)] = os.path.abspath(src)
         deps.extend(copy_with_deps.copy_with_deps(src, dest, search_path, [kconfig]))
 
     deps = sorted(set(deps))
@@ -358,6 +363,10 @@ def main():
     build_module(output_dir, module_ko, abs_kdir, abs_module_dir,
                  make_command, make_args, extra_cflags)
 
+    if args.compile_commands:
+        entries = compile_commands.kbuild_entries(abs_kdir, abs_module_dir, copied_sources)
+        compile_commands.write(args.compile_commands, entries)
+
 
 if __name__ == "__main__":
     main()
@@ -0,0 +1,233 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Describe the interface a library exports to its users in a JSON file.
+
+The description contains the include directories and preprocessor
+definitions exported to users of the library, the SONAME of shared
+libraries, and the symbols the library defines. Packaging and ABI
+tooling can read this instead of the build files.
+"""
+
+import argparse
+import json
+import logging
+import os
+import re
+import subprocess
+import sys
+
+
+logger = logging.getLogger(__name__)
+
+# Version of the file format. Increase this when removing or changing the
+# meaning of fields; adding fields is compatible.
+FORMAT_VERSION = 1
+
+# Environment to use for processes we parse output from.
+# Force the C locale.
+child_env = os.environ.copy()
+child_env['LC_ALL'] = "C"
+
+
+def parse_args():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("input", help="Library to describe")
+    parser.add_argument("-o", "--output", required=True, help="Interface file to create")
+    parser.add_argument("--name", required=True, help="Name of the library module")
+    parser.add_argument("--type", choices=["static", "shared"], required=True,
+                        help="Type of library")
+    parser.add_argument("--target", required=True, help="Target type, host or target")
+    parser.add_argument("--soname", default=None, help="SONAME of a shared library")
+    parser.add_argument("--export-include-dir", action="append", default=[],
+                        help="Include directory exported to users of the library")
+    parser.add_argument("--export-cflag", action="append", default=[],
+                        help="Compiler flag exported to users of the library. "
+                             "Use --export-cflag=FLAG for flags starting with '-'")
+    parser.add_argument("--format", action="store",
+                        choices=["elf", "macho"], default="elf",
+                        help="Library format")
+    parser.add_argument("--objdump-tool", default="objdump",
+                        help="Tool used to read the symbols of Elf libraries. "
+                             "This is expected to be objdump on Linux platforms")
+    parser.add_argument("--otool-tool", default="otool",
+                        help="Not used, but accepted so that the flags used to run "
+                             "library_toc.py can be passed")
+    parser.add_argument("--nm-tool", default="nm",
+                        help="Tool used to read the symbols of Mach-O libraries. "
+                             "This is expected to be nm on OSX")
+
+    return parser.parse_args()
+
+
+def run(cmd):
+    try:
+        result = subprocess.check_output(cmd, env=child_env)
+    except subprocess.CalledProcessError as e:
+        logger.error("Command failed: %s", str(e.cmd))
+        sys.exit(e.returncode)
+    return result.decode(sys.getdefaultencoding()).split("\n")
+
+
+OBJDUMP_SYMBOL_RE = re.compile(r"^[\da-fA-F]+ (.{7}) (\S+)\s+[\da-fA-F]+\s+(\S.*)$")
+
+
+def parse_objdump_symbols(lines):
+    """
+    Return the global symbols defined in `objdump -t` or `objdump -T`
+    output.
+
+    Each symbol line has the address, 7 flag characters, the section,
+    the size or alignment, an optional version, and the name. Undefined
+    symbols are in the *UND* section, and global symbols have the 'g'
+    or 'u' flag, or 'w' for weak definitions.
+    """
+    symbols = set()
+    for line in lines:
+        match = OBJDUMP_SYMBOL_RE.match(line)
+        if not match:
+            continue
+        flags, section, rest = match.groups()
+        name = rest.split()[-1]
+        if section in ("*UND*", "*COM*"):
+            continue
+        if flags[0] not in "gu!" and flags[1] != "w":
+            continue
+        # Skip section, file and debugging symbols
+        if flags[5] == "d":
+            continue
+        symbols.add(name)
+    return sorted(symbols)
+
+
+def parse_nm_symbols(lines):
+    """
+    Return the global symbols defined in `nm -gP` output, which has the
+    symbol name, type, value and size on each line.
+    """
+    symbols = set()
+    for line in lines:
+        fields = line.split()
+        if len(fields) < 2 or fields[0].endswith(":"):
+            # Archive member headers end with a colon
+            continue
+        if fields[1] in ("U", "u"):
+            continue
+        symbols.add(fields[0])
+    return sorted(symbols)
+
+
+def get_symbols(args):
+    if args.format == "macho":
+        return parse_nm_symbols(run([args.nm_tool, "-gP", args.input]))
+    if args.type == "shared":
+        # Users can only see the dynamic symbol table
+        return parse_objdump_symbols(run([args.objdump_tool, "-T", args.input]))
+    return parse_objdump_symbols(run([args.objdump_tool, "-t", args.input]))
+
+
+def split_cflags(cflags):
+    """Separate the preprocessor definitions from other exported flags"""
+    defines = []
+    others = []
+    for flag in cflags:
+        if flag.startswith("-D") and len(flag) > 2:
+            defines.append(flag[2:])
+        else:
+            others.append(flag)
+    return defines, others
+
+
+def describe(args, symbols):
+    defines, cflags = split_cflags(args.export_cflag)
+    interface = {
+        "format_version": FORMAT_VERSION,
+        "name": args.name,
+        "type": args.type,
+        "target": args.target,
+        "file": os.path.basename(args.input),
+        "export_include_dirs": args.export_include_dir,
+        "export_defines": defines,
+        "export_cflags": cflags,
+        "symbols": symbols,
+    }
+    if args.type == "shared":
+        interface["soname"] = args.soname
+    return interface
+
+
+def test_parse_objdump_symbols():
+    lines = """
+libfoo.so:     file format elf64-x86-64
+
+DYNAMIC SYMBOL TABLE:
+0000000000000000  w   D  *UND*	0000000000000000              __gmon_start__
+0000000000000000      DF *UND*	0000000000000000  GLIBC_2.2.5 malloc
+0000000000001109 g    DF .text	000000000000000b  Base        foo
+0000000000004010 g    DO .data	0000000000000004  Base        foo_count
+0000000000001120  w   DF .text	0000000000000005  Base        foo_weak
+""".split("\n")
+    assert parse_objdump_symbols(lines) == ["foo", "foo_count", "foo_weak"]
+
+    lines = """
+In archive libfoo.a:
+
+foo.o:     file format elf64-x86-64
+
+SYMBOL TABLE:
+0000000000000000 l    df *ABS*	0000000000000000 foo.c
+0000000000000000 l    d  .text	0000000000000000 .text
+0000000000000000 l     F .text	0000000000000007 helper
+0000000000000007 g     F .text	000000000000000b foo
+0000000000000000         *UND*	0000000000000000 bar
+0000000000000004       O *COM*	0000000000000004 common_var
+""".split("\n")
+    assert parse_objdump_symbols(lines) == ["foo"]
+
+
+def test_parse_nm_symbols():
+    lines = """
+libfoo.a[foo.o]:
+_bar U
+_foo T 0 b
+_foo_count D 10 4
+""".split("\n")
+    assert parse_nm_symbols(lines) == ["_foo", "_foo_count"]
+
+
+def test_split_cflags():
+    assert split_cflags(["-DFOO=1", "-Wno-unused", "-DBAR", "-D"]) == \
+        (["FOO=1", "BAR"], ["-Wno-unused", "-D"])
+
+
+def main():
+    args = parse_args()
+    logging.basicConfig(format="%(levelname)s: %(message)s", level=logging.WARNING)
+
+    interface = describe(args, get_symbols(args))
+
+    with open(args.output, "wt") as fp:
+        json.dump(interface, fp, sort_keys=True, indent=4, separators=(",", ": "))
+        fp.write("\n")
+
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -0,0 +1,145 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+"""
+Prepend a license header to the files written by a generator module, or
+check that they start with it.
+
+The header is a plain text file, which is written as a comment in the
+syntax of each output. Outputs in languages this script doesn't know,
+such as object files and libraries, are left alone. A `#!` line stays
+at the start of the file, above the header.
+"""
+
+import argparse
+import logging
+import os
+import sys
+
+
+logger = logging.getLogger(__name__)
+
+C_STYLE = (".c", ".cc", ".cpp", ".cxx", ".h", ".hh", ".hpp", ".hxx", ".inl",
+           ".s", ".S", ".java", ".go", ".rs")
+HASH_STYLE = (".py", ".sh", ".bash", ".mk", ".cmake", ".pl", ".rb")
+
+
+def comment(header, filename):
+    """Return the header as a comment in the syntax of 'filename', or None
+    if the syntax isn't known."""
+    lines = [line.rstrip() for line in header.decode("utf-8").strip("\n").split("\n")]
+    ext = os.path.splitext(filename)[1]
+    if ext in C_STYLE:
+        body = [(" * " + line).rstrip() for line in lines]
+        text = "\n".join(["/*"] + body + [" */"])
+    elif ext in HASH_STYLE:
+        text = "\n".join([("# " + line).rstrip() for line in lines])
+    else:
+        return None
+    return (text + "\n\n").encode("utf-8")
+
+
+def split_shebang(content):
+    """Split the `#!` line from the rest of the content"""
+    if content.startswith(b"#!"):
+        end = content.find(b"\n") + 1
+        if end == 0:
+            return content + b"\n", b""
+        return content[:end], content[end:]
+    return b"", content
+
+
+def has_header(content, header_comment):
+    _, body = split_shebang(content)
+    return body.startswith(header_comment)
+
+
+def add_header(content, header_comment):
+    shebang, body = split_shebang(content)
+    if body.startswith(header_comment):
+        return content
+    return shebang + header_comment + body
+
+
+def test_comment():
+    header = b"Copyright (C) Example Ltd.\n\nLicensed under MIT\n"
+    assert comment(header, "gen.h") == \
+        b"/*\n * Copyright (C) Example Ltd.\n *\n * Licensed under MIT\n */\n\n"
+    assert comment(header, "gen.py") == \
+        b"# Copyright (C) Example Ltd.\n#\n# Licensed under MIT\n\n"
+    assert comment(header, "libgen.a") is None
+
+
+def test_add_header():
+    header_comment = b"# Licensed under MIT\n\n"
+    assert add_header(b"print(1)\n", header_comment) == b"# Licensed under MIT\n\nprint(1)\n"
+    assert add_header(b"#!/usr/bin/env python\nprint(1)\n", header_comment) == \
+        b"#!/usr/bin/env python\n# Licensed under MIT\n\nprint(1)\n"
+
+    # The header is only added once
+    once = add_header(b"print(1)\n", header_comment)
+    assert add_header(once, header_comment) == once
+    assert has_header(once, header_comment)
+    assert not has_header(b"print(1)\n", header_comment)
+
+
+def process(filename, header, check):
+    """Add the header to a file, or check it is there. Returns False if the
+    check fails."""
+    header_comment = comment(header, filename)
+    if header_comment is None:
+        return True
+
+    with open(filename, "rb") as fp:
+        content = fp.read()
+
+    if check:
+        if not has_header(content, header_comment):
+            logger.error("%s does not start with the license header", filename)
+            return False
+        return True
+
+    new_content = add_header(content, header_comment)
+    if new_content != content:
+        # Only write when the header is missing, so the output keeps
+        # its timestamp when the generator didn't update it.
+        with open(filename, "wb") as fp:
+            fp.write(new_content)
+    return True
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("--header", required=True, help="File holding the license header")
+    parser.add_argument("--check", action="store_true",
+                        help="Check the files start with the header, rather than adding it")
+    parser.add_argument("files", nargs="*", help="Files written by the generator")
+    args = parser.parse_args()
+
+    logging.basicConfig(format="%(levelname)s: %(message)s", level=logging.WARNING)
+
+    with open(args.header, "rb") as fp:
+        header = fp.read()
+
+    ok = True
+    for filename in args.files:
+        ok = process(filename, header, args.check) and ok
+    return 0 if ok else 1
+
+
+if __name__ == "__main__":
+    sys.exit(main())
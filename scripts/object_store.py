@@ -0,0 +1,170 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Run a command, then store its outputs in a content-addressed directory,
+replacing them with hard links to the stored files.
+
+Outputs with the same contents, e.g. objects compiled for several
+variants of a module, end up as links to a single file, so that they
+only use disk space once.
+"""
+
+import argparse
+import hashlib
+import os
+import shutil
+import subprocess
+import sys
+import tempfile
+
+
+def file_digest(path):
+    h = hashlib.sha256()
+    with open(path, "rb") as fp:
+        for chunk in iter(lambda: fp.read(1 << 16), b""):
+            h.update(chunk)
+    return h.hexdigest()
+
+
+def replace_with_link(src, dst):
+    """Atomically replace dst with a hard link to src"""
+    fd, tmp = tempfile.mkstemp(prefix=".tmp", dir=os.path.dirname(dst) or ".")
+    os.close(fd)
+    os.remove(tmp)
+    try:
+        os.link(src, tmp)
+        os.rename(tmp, dst)
+    except OSError:
+        if os.path.exists(tmp):
+            os.remove(tmp)
+        raise
+
+
+def store(store_dir, output):
+    """Move an output into the store, unless the store already has the same
+    contents, and link the output to the stored file."""
+    digest = file_digest(output)
+    entry = os.path.join(store_dir, digest[:2], digest)
+
+    try:
+        if os.path.isfile(entry):
+            if not os.path.samefile(entry, output):
+                replace_with_link(entry, output)
+        else:
+            parent = os.path.dirname(entry)
+            if not os.path.isdir(parent):
+                os.makedirs(parent)
+            replace_with_link(output, entry)
+    except OSError:
+        # The store is on another file system, or a concurrent build
+        # created the same entry. The output is still usable.
+        return
+
+    # The stored file may be older than the inputs of this output, which
+    # would make ninja build it again.
+    os.utime(output, None)
+
+
+def remove_outputs(outputs):
+    """Remove the links to stored files before running the command, as
+    tools may write to an existing output in place, which would change
+    the stored file and every output linked to it."""
+    for output in outputs:
+        if os.path.lexists(output):
+            os.remove(output)
+
+
+def parse_args():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("--store", required=True, help="Directory holding the stored files")
+    parser.add_argument("--output", action="append", default=[], required=True,
+                        help="Output of the command. May be repeated")
+    parser.add_argument("command", nargs=argparse.REMAINDER,
+                        help="Command to run, following --")
+    args = parser.parse_args()
+
+    if args.command and args.command[0] == "--":
+        args.command = args.command[1:]
+    if not args.command:
+        parser.error("no command given")
+    return args
+
+
+def write(path, content):
+    with open(path, "wt") as fp:
+        fp.write(content)
+
+
+def test_store_links_identical_outputs():
+    tmp = tempfile.mkdtemp()
+    try:
+        store_dir = os.path.join(tmp, "store")
+        outputs = [os.path.join(tmp, name) for name in ("host.o", "target.o", "other.o")]
+        write(outputs[0], "object")
+        write(outputs[1], "object")
+        write(outputs[2], "other object")
+
+        for output in outputs:
+            store(store_dir, output)
+        assert os.path.samefile(outputs[0], outputs[1])
+        assert not os.path.samefile(outputs[0], outputs[2])
+        assert os.stat(outputs[0]).st_nlink == 3
+
+        # Storing again must not break the links
+        store(store_dir, outputs[0])
+        assert os.path.samefile(outputs[0], outputs[1])
+    finally:
+        shutil.rmtree(tmp)
+
+
+def test_store_rebuilt_output():
+    tmp = tempfile.mkdtemp()
+    try:
+        store_dir = os.path.join(tmp, "store")
+        outputs = [os.path.join(tmp, name) for name in ("a.o", "b.o")]
+        for output in outputs:
+            write(output, "object")
+            store(store_dir, output)
+
+        # The output is removed before the command runs again, so the
+        # other output keeps the original contents.
+        remove_outputs([outputs[0]])
+        write(outputs[0], "changed")
+        store(store_dir, outputs[0])
+        with open(outputs[1], "rt") as fp:
+            assert fp.read() == "object"
+        assert not os.path.samefile(outputs[0], outputs[1])
+    finally:
+        shutil.rmtree(tmp)
+
+
+def main():
+    args = parse_args()
+    remove_outputs(args.output)
+    ret = subprocess.call(args.command)
+    if ret != 0:
+        return ret
+    for output in args.output:
+        if os.path.isfile(output):
+            store(args.store, output)
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -0,0 +1,192 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Package a Python tool, with the packages listed in its requirements
+file, so that it runs the same way on every machine.
+
+A zipapp is a single executable archive holding the sources and the
+packages. Packages with compiled extensions can't be loaded from an
+archive, so those tools should use a venv, which is a virtual
+environment next to a launcher script written at the output path.
+"""
+
+import argparse
+import logging
+import os
+import shutil
+import stat
+import subprocess
+import sys
+
+
+logger = logging.getLogger(__name__)
+
+
+def entry_point_code(main):
+    """Return the code which runs the entry point. The entry point is
+    either a module to run as __main__, or module:function."""
+    module, _, function = main.partition(":")
+    if function:
+        return ("import sys\n"
+                "from {} import {}\n"
+                "sys.exit({}())\n").format(module, function, function)
+    return ("import runpy\n"
+            "runpy.run_module({!r}, run_name='__main__', alter_sys=True)\n").format(module)
+
+
+def shebang_interpreter(python):
+    """Return the interpreter to name in a #! line. Interpreters which
+    are not given as a path are found on PATH."""
+    if os.path.isabs(python):
+        return python
+    return "/usr/bin/env " + python
+
+
+def copy_sources(root, sources, dest):
+    """Copy the sources into dest, keeping their path relative to the
+    module directory."""
+    for src in sources:
+        rel = os.path.relpath(src, root)
+        if rel.startswith(os.pardir):
+            raise ValueError("{} is not in {}".format(src, root))
+        path = os.path.join(dest, rel)
+        if not os.path.isdir(os.path.dirname(path)):
+            os.makedirs(os.path.dirname(path))
+        shutil.copyfile(src, path)
+
+
+def pip_install(python, requirements, extra_args):
+    """Install the requirements. Pip is not allowed to prompt, and uses
+    the hashes in the requirements file when there are any."""
+    cmd = [python, "-m", "pip", "install", "--no-input",
+           "--disable-pip-version-check", "-r", requirements] + extra_args
+    subprocess.check_call(cmd)
+
+
+def remove(path):
+    if os.path.isdir(path) and not os.path.islink(path):
+        shutil.rmtree(path)
+    elif os.path.lexists(path):
+        os.remove(path)
+
+
+def write_zipapp(args):
+    staging = args.output + ".staging"
+    remove(staging)
+    copy_sources(args.root, args.sources, staging)
+    if args.requirements:
+        pip_install(args.python, args.requirements, ["--target", staging])
+    with open(os.path.join(staging, "__main__.py"), "w") as fp:
+        fp.write(entry_point_code(args.main))
+
+    remove(args.output)
+    subprocess.check_call([args.python, "-m", "zipapp", staging,
+                           "--output", args.output,
+                           "--python", shebang_interpreter(args.python)])
+    shutil.rmtree(staging)
+
+
+def launcher_script():
+    """Return the script which runs the entry point using the venv next
+    to it."""
+    return ("#!/bin/sh\n"
+            "dir=$(cd \"$(dirname \"$0\")\" && pwd)\n"
+            "PYTHONPATH=\"$dir/src${PYTHONPATH:+:$PYTHONPATH}\" "
+            "exec \"$dir/venv/bin/python\" \"$dir/src/__main__.py\" \"$@\"\n")
+
+
+def write_venv(args):
+    outdir = os.path.dirname(args.output)
+    venv = os.path.join(outdir, "venv")
+    src = os.path.join(outdir, "src")
+    remove(venv)
+    remove(src)
+
+    subprocess.check_call([args.python, "-m", "venv", venv])
+    if args.requirements:
+        pip_install(os.path.join(venv, "bin", "python"), args.requirements, [])
+    copy_sources(args.root, args.sources, src)
+    with open(os.path.join(src, "__main__.py"), "w") as fp:
+        fp.write(entry_point_code(args.main))
+
+    with open(args.output, "w") as fp:
+        fp.write(launcher_script())
+    mode = os.stat(args.output).st_mode
+    os.chmod(args.output, mode | stat.S_IXUSR | stat.S_IXGRP | stat.S_IXOTH)
+
+
+def test_entry_point_code():
+    assert entry_point_code("tool.cli:run") == \
+        "import sys\nfrom tool.cli import run\nsys.exit(run())\n"
+    assert entry_point_code("tool") == \
+        "import runpy\nrunpy.run_module('tool', run_name='__main__', alter_sys=True)\n"
+
+
+def test_shebang_interpreter():
+    assert shebang_interpreter("python3") == "/usr/bin/env python3"
+    assert shebang_interpreter("/opt/python/bin/python3") == "/opt/python/bin/python3"
+
+
+def test_copy_sources(tmp_path):
+    root = tmp_path / "module"
+    (root / "tool").mkdir(parents=True)
+    (root / "tool" / "cli.py").write_text(u"x = 1\n")
+    dest = tmp_path / "out"
+
+    copy_sources(str(root), [str(root / "tool" / "cli.py")], str(dest))
+    assert (dest / "tool" / "cli.py").read_text() == u"x = 1\n"
+
+    try:
+        copy_sources(str(root / "tool"), [str(root / "other.py")], str(dest))
+        assert False, "Expected a ValueError"
+    except ValueError:
+        pass
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("--packaging", choices=["zipapp", "venv"], default="zipapp",
+                        help="How the tool is packaged")
+    parser.add_argument("--python", default="python3",
+                        help="Interpreter the tool is packaged for")
+    parser.add_argument("--main", required=True,
+                        help="Entry point, as a module or module:function")
+    parser.add_argument("--root", required=True,
+                        help="Directory the source paths are relative to in the package")
+    parser.add_argument("--requirements", help="Pip requirements file")
+    parser.add_argument("-o", "--output", required=True,
+                        help="The zipapp, or the launcher script of the venv")
+    parser.add_argument("sources", nargs="*", help="Python sources of the tool")
+    args = parser.parse_args()
+
+    logging.basicConfig(format="%(levelname)s: %(message)s", level=logging.WARNING)
+
+    try:
+        if args.packaging == "venv":
+            write_venv(args)
+        else:
+            write_zipapp(args)
+    except (ValueError, subprocess.CalledProcessError) as e:
+        logger.error("%s", str(e))
+        return 1
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -0,0 +1,198 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Generate the source of a stub shared library from a symbol file.
+
+The symbol file has the format of a linker version script. Each symbol
+is followed by a comment which may contain tags:
+
+    LIBFOO {
+      global:
+        foo_open;
+        foo_table; # var
+        foo_close; # introduced=2
+      local:
+        *;
+    };
+
+`introduced=<version>` on a symbol or a version node leaves the symbols
+out of the stubs for earlier versions. The stub for the `current`
+version contains every symbol. `var` marks a data symbol, which
+is defined as a variable instead of a function.
+
+The stub defines every symbol of the requested version with an empty
+body, so linking against it only succeeds when a module uses the API
+the library declares.
+"""
+
+import argparse
+import re
+import sys
+
+
+class SymbolFileError(Exception):
+    pass
+
+
+NODE_START_RE = re.compile(r"^([A-Za-z0-9_.]*)\s*\{$")
+NODE_END_RE = re.compile(r"^\}\s*[A-Za-z0-9_.]*\s*;$")
+SYMBOL_RE = re.compile(r"^([A-Za-z_][A-Za-z0-9_]*)\s*;$")
+
+
+def parse_tags(comment):
+    """Return the tags in a comment as a dictionary. Tags without a value
+    map to True."""
+    tags = {}
+    for word in comment.split():
+        if "=" in word:
+            key, value = word.split("=", 1)
+            tags[key] = value
+        else:
+            tags[word] = True
+    return tags
+
+
+def parse_symbol_file(lines):
+    """Return a list of (name, tags) tuples, one for each global symbol.
+    The tags of a version node apply to all the symbols in it."""
+    symbols = []
+    node_tags = None
+    in_global = False
+
+    for number, line in enumerate(lines, 1):
+        code, _, comment = line.partition("#")
+        code = code.strip()
+        tags = parse_tags(comment)
+        if not code:
+            continue
+
+        if node_tags is None:
+            if not NODE_START_RE.match(code):
+                raise SymbolFileError("line {}: expected a version node".format(number))
+            node_tags = tags
+            in_global = True
+        elif NODE_END_RE.match(code):
+            node_tags = None
+        elif code == "global:":
+            in_global = True
+        elif code == "local:":
+            in_global = False
+        elif not in_global:
+            continue
+        else:
+            match = SYMBOL_RE.match(code)
+            if not match:
+                raise SymbolFileError("line {}: unsupported symbol '{}'".format(number, code))
+            symbol_tags = dict(node_tags)
+            symbol_tags.update(tags)
+            symbols.append((match.group(1), symbol_tags))
+
+    if node_tags is not None:
+        raise SymbolFileError("unterminated version node")
+    return symbols
+
+
+def symbols_for_version(symbols, versions, version):
+    """Return the symbols present in 'version', given the list of API
+    versions, oldest first."""
+    if version == "current":
+        return list(symbols)
+    if version not in versions:
+        raise SymbolFileError("unknown version '{}'".format(version))
+    limit = versions.index(version)
+
+    selected = []
+    for name, tags in symbols:
+        introduced = tags.get("introduced")
+        if introduced is not None:
+            if introduced not in versions:
+                raise SymbolFileError("{} is introduced in unknown version '{}'".format(
+                    name, introduced))
+            if versions.index(introduced) > limit:
+                continue
+        selected.append((name, tags))
+    return selected
+
+
+def stub_source(symbols):
+    lines = ["/* Generated by shared_lib_stubs.py. Do not edit. */"]
+    for name, tags in symbols:
+        if tags.get("var"):
+            lines.append("int {} = 0;".format(name))
+        else:
+            lines.append("void {}(void) {{}}".format(name))
+    return "\n".join(lines) + "\n"
+
+
+def test_parse_symbol_file():
+    lines = [
+        "LIBFOO { # introduced=1",
+        "  global:",
+        "    foo_open;",
+        "    foo_table; # var",
+        "    foo_close; # introduced=2",
+        "  local:",
+        "    *;",
+        "};",
+    ]
+    assert parse_symbol_file(lines) == [
+        ("foo_open", {"introduced": "1"}),
+        ("foo_table", {"introduced": "1", "var": True}),
+        ("foo_close", {"introduced": "2"}),
+    ]
+
+
+def test_symbols_for_version():
+    symbols = [("foo_open", {}), ("foo_close", {"introduced": "2"})]
+    assert symbols_for_version(symbols, ["1", "2"], "1") == [("foo_open", {})]
+    assert len(symbols_for_version(symbols, ["1", "2"], "2")) == 2
+    assert len(symbols_for_version(symbols, [], "current")) == 2
+
+
+def test_stub_source():
+    source = stub_source([("foo_open", {}), ("foo_table", {"var": True})])
+    assert "void foo_open(void) {}" in source
+    assert "int foo_table = 0;" in source
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("input", help="Symbol file")
+    parser.add_argument("-o", "--output", required=True, help="Stub source file to create")
+    parser.add_argument("--versions", default="",
+                        help="Comma separated list of API versions, oldest first")
+    parser.add_argument("--version", default="current",
+                        help="API version to generate the stub for")
+    args = parser.parse_args()
+
+    try:
+        with open(args.input) as fp:
+            symbols = parse_symbol_file(fp.read().splitlines())
+        versions = [v for v in args.versions.split(",") if v]
+        symbols = symbols_for_version(symbols, versions, args.version)
+    except SymbolFileError as e:
+        sys.stderr.write("{}: {}\n".format(args.input, e))
+        sys.exit(1)
+
+    with open(args.output, "w") as fp:
+        fp.write(stub_source(symbols))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,471 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Summarize the link map files of binaries and shared libraries into a
+code size report.
+
+For each module, the sizes of the output sections are combined into text,
+data and bss totals, in the same way as the `size` utility does, and the
+input files contributing the most to the module are listed. When the
+output of nm and size on the linked file is given, the largest symbols
+and the totals reported by size are added.
+
+Link maps written by GNU ld, gold and LLD are understood.
+"""
+
+import argparse
+import csv
+import json
+import logging
+import os
+import re
+import sys
+
+
+logger = logging.getLogger(__name__)
+
+# Number of input files to list for each module
+TOP_INPUTS = 10
+
+# Number of symbols to list for each module
+TOP_SYMBOLS = 10
+
+# Output sections which are counted, keyed by the prefix of their name.
+# Longer prefixes take precedence. Anything else (debug information,
+# symbol tables, comments) is not loaded at runtime, so is ignored.
+SECTION_KINDS = {
+    ".text": "text",
+    ".rodata": "text",
+    ".init": "text",
+    ".fini": "text",
+    ".plt": "text",
+    ".iplt": "text",
+    ".eh_frame": "text",
+    ".gcc_except_table": "text",
+    ".ARM.exidx": "text",
+    ".ARM.extab": "text",
+    ".interp": "text",
+    ".note": "text",
+    ".hash": "text",
+    ".gnu.hash": "text",
+    ".dynsym": "text",
+    ".dynstr": "text",
+    ".gnu.version": "text",
+    ".rela": "text",
+    ".rel.": "text",
+    ".relr": "text",
+    ".data": "data",
+    ".sdata": "data",
+    ".ldata": "data",
+    ".tm_clone_table": "data",
+    ".data.rel.ro": "data",
+    ".tdata": "data",
+    ".got": "data",
+    ".dynamic": "data",
+    ".init_array": "data",
+    ".fini_array": "data",
+    ".preinit_array": "data",
+    ".ctors": "data",
+    ".dtors": "data",
+    ".bss": "bss",
+    ".sbss": "bss",
+    ".lbss": "bss",
+    ".tbss": "bss",
+    "COMMON": "bss",
+}
+
+
+def section_kind(name):
+    """Return whether an output section is counted as text, data or bss,
+    or None if it does not take space at runtime."""
+    best = None
+    for prefix in SECTION_KINDS:
+        if name == prefix or name.startswith(prefix + ".") or \
+                name.startswith(prefix + "_") or \
+                (prefix.endswith(".") and name.startswith(prefix)):
+            if best is None or len(prefix) > len(best):
+                best = prefix
+    return SECTION_KINDS[best] if best else None
+
+
+GNU_SECTION_RE = re.compile(r"^(\S+)\s+0x([0-9a-fA-F]+)\s+0x([0-9a-fA-F]+)\s*$")
+GNU_INPUT_RE = re.compile(r"^ (\S+)\s+0x([0-9a-fA-F]+)\s+0x([0-9a-fA-F]+)\s+(.+)$")
+GNU_NAME_ONLY_RE = re.compile(r"^ ?(\S+)\s*$")
+GNU_CONTINUATION_RE = re.compile(r"^\s+0x([0-9a-fA-F]+)\s+0x([0-9a-fA-F]+)(?:\s+(.+))?$")
+
+
+def parse_gnu_map(lines):
+    """Parse a GNU ld or gold map, returning the output section sizes and
+    the (output section, input file, size) contributions."""
+    sections = {}
+    inputs = []
+
+    in_map = False
+    current = None
+    pending = None  # Section or input name wrapped onto the next line
+
+    for line in lines:
+        line = line.rstrip("\n")
+        if not in_map:
+            if line.startswith("Linker script and memory map") or \
+                    line.startswith("Memory map"):
+                in_map = True
+            continue
+
+        if pending is not None:
+            m = GNU_CONTINUATION_RE.match(line)
+            name, is_input = pending
+            pending = None
+            if m:
+                size = int(m.group(2), 16)
+                if is_input:
+                    if m.group(3) and current is not None:
+                        inputs.append((current, m.group(3).strip(), size))
+                else:
+                    current = name
+                    sections[name] = sections.get(name, 0) + size
+                continue
+
+        if not line or line.startswith("LOAD ") or line.startswith("OUTPUT("):
+            continue
+
+        if not line[0].isspace():
+            m = GNU_SECTION_RE.match(line)
+            if m:
+                current = m.group(1)
+                sections[current] = sections.get(current, 0) + int(m.group(3), 16)
+                continue
+            m = GNU_NAME_ONLY_RE.match(line)
+            if m:
+                current = None
+                pending = (m.group(1), False)
+            continue
+
+        if line.startswith(" *"):
+            # Input section descriptions and *fill* entries
+            continue
+
+        m = GNU_INPUT_RE.match(line)
+        if m:
+            if current is not None:
+                inputs.append((current, m.group(4).strip(), int(m.group(3), 16)))
+            continue
+
+        if line.startswith(" ") and not line.startswith("  "):
+            m = GNU_NAME_ONLY_RE.match(line)
+            if m:
+                pending = (m.group(1), True)
+
+    return sections, inputs
+
+
+def parse_lld_map(lines):
+    """Parse an LLD map, returning the output section sizes and the
+    (output section, input file, size) contributions."""
+    sections = {}
+    inputs = []
+
+    lines = iter(lines)
+    header = next(lines, "")
+    in_col = header.find(" In ") + 1
+    sym_col = header.find(" Symbol")
+    sym_col = sym_col + 1 if sym_col >= 0 else len(header) + 1000
+
+    current = None
+    for line in lines:
+        line = line.rstrip("\n")
+        fields = line.split()
+        if len(fields) < 5:
+            continue
+        try:
+            size = int(fields[2], 16)
+        except ValueError:
+            continue
+
+        # Find where the name starts, after the four numeric columns
+        pos = 0
+        for field in fields[:4]:
+            pos = line.index(field, pos) + len(field)
+        col = len(line) - len(line[pos:].lstrip())
+        name = line[col:].strip()
+
+        if col < in_col:
+            current = name
+            sections[current] = sections.get(current, 0) + size
+        elif col < sym_col:
+            if current is not None:
+                # Inputs are shown as file:(section)
+                inputs.append((current, re.sub(r":\(.*\)$", "", name), size))
+
+    return sections, inputs
+
+
+def parse_map(filename):
+    with open(filename, "rt") as fp:
+        lines = fp.readlines()
+    if lines and "VMA" in lines[0] and " Out " in lines[0]:
+        return parse_lld_map(lines)
+    return parse_gnu_map(lines)
+
+
+def parse_nm(lines):
+    """Parse the output of `nm --print-size`, returning the
+    (symbol, size) of each symbol which has a size."""
+    symbols = []
+    for line in lines:
+        fields = line.split(None, 3)
+        if len(fields) != 4:
+            continue
+        try:
+            size = int(fields[1], 16)
+        except ValueError:
+            continue
+        symbols.append((fields[3].strip(), size))
+    return symbols
+
+
+def parse_size(lines):
+    """Parse the Berkeley format output of `size` for a single file,
+    returning its text, data and bss totals."""
+    for line in lines:
+        fields = line.split()
+        if len(fields) < 3:
+            continue
+        try:
+            text, data, bss = [int(f) for f in fields[:3]]
+        except ValueError:
+            # The header
+            continue
+        return {"text": text, "data": data, "bss": bss}
+    return None
+
+
+def read_lines(filename):
+    with open(filename, "rt") as fp:
+        return fp.readlines()
+
+
+def add_size_tool_output(entry, symbols, totals):
+    """Add the largest symbols listed by nm, and the totals reported by
+    size, to a report entry"""
+    top = sorted(symbols, key=lambda s: (-s[1], s[0]))[:TOP_SYMBOLS]
+    entry["largest_symbols"] = [{"symbol": n, "size": s} for n, s in top if s > 0]
+    if totals is not None:
+        entry["size_utility"] = totals
+
+
+def summarize(sections, inputs):
+    """Combine the parsed map into the totals of a report entry"""
+    totals = {"text": 0, "data": 0, "bss": 0}
+    for name, size in sections.items():
+        kind = section_kind(name)
+        if kind:
+            totals[kind] += size
+
+    by_input = {}
+    for section, input_file, size in inputs:
+        if section_kind(section):
+            by_input[input_file] = by_input.get(input_file, 0) + size
+    top = sorted(by_input.items(), key=lambda i: (-i[1], i[0]))[:TOP_INPUTS]
+
+    entry = dict(totals)
+    entry["total"] = sum(totals.values())
+    entry["largest_inputs"] = [{"file": f, "size": s} for f, s in top if s > 0]
+    return entry
+
+
+def write_report(entries, json_file, csv_file):
+    with open(json_file, "wt") as fp:
+        json.dump(entries, fp, sort_keys=True, indent=4, separators=(",", ": "))
+        fp.write("\n")
+
+    with open(csv_file, "wt") as fp:
+        writer = csv.writer(fp, lineterminator="\n")
+        writer.writerow(["module", "text", "data", "bss", "total"])
+        for module in sorted(entries):
+            e = entries[module]
+            writer.writerow([module, e["text"], e["data"], e["bss"], e["total"]])
+
+
+def test_section_kind():
+    assert section_kind(".text") == "text"
+    assert section_kind(".text.startup") == "text"
+    assert section_kind(".rela.dyn") == "text"
+    assert section_kind(".rel.plt") == "text"
+    assert section_kind(".eh_frame_hdr") == "text"
+    assert section_kind(".gnu.version_r") == "text"
+    assert section_kind(".data.rel.ro") == "data"
+    assert section_kind(".init_array") == "data"
+    assert section_kind(".bss") == "bss"
+    assert section_kind(".debug_info") is None
+    assert section_kind(".comment") is None
+    assert section_kind(".textual") is None
+
+
+def test_parse_nm():
+    lines = """\
+0000000000004010 0000000000000004 B counter
+0000000000001139 0000000000000017 T main
+0000000000001150 0000000000000120 t helper
+                 U printf
+""".splitlines(True)
+
+    symbols = parse_nm(lines)
+    assert symbols == [("counter", 4), ("main", 0x17), ("helper", 0x120)]
+
+    entry = {}
+    add_size_tool_output(entry, symbols, {"text": 1, "data": 2, "bss": 3})
+    assert entry["largest_symbols"] == [
+        {"symbol": "helper", "size": 0x120},
+        {"symbol": "main", "size": 0x17},
+        {"symbol": "counter", "size": 4},
+    ]
+    assert entry["size_utility"] == {"text": 1, "data": 2, "bss": 3}
+
+
+def test_parse_size():
+    lines = """\
+   text	   data	    bss	    dec	    hex	filename
+   1418	    544	      8	   1970	    7b2	main
+""".splitlines(True)
+
+    assert parse_size(lines) == {"text": 1418, "data": 544, "bss": 8}
+    assert parse_size([]) is None
+
+
+def test_parse_gnu_map():
+    lines = """
+Archive member included to satisfy reference by file (symbol)
+
+Linker script and memory map
+
+LOAD main.o
+.interp         0x0000000000000318       0x1c
+ *(.interp)
+ .interp        0x0000000000000318       0x1c crt1.o
+
+.note.gnu.property
+                0x0000000000000338       0x20
+ .note.gnu.property
+                0x0000000000000338       0x20 crt1.o
+
+.text           0x0000000000001040      0x120
+ *(.text .stub .text.*)
+ .text          0x0000000000001040       0x26 crt1.o
+                0x0000000000001040                _start
+ *fill*         0x0000000000001066        0xa
+ .text          0x0000000000001070       0xf0 main.o
+                0x0000000000001070                main
+
+.data           0x0000000000004000        0x8
+ .data          0x0000000000004000        0x8 libfoo.a(foo.o)
+
+.bss            0x0000000000004010      0x1a0
+ COMMON         0x0000000000004010      0x1a0 main.o
+
+.debug_info     0x0000000000000000      0x100
+ .debug_info    0x0000000000000000      0x100 main.o
+""".splitlines(True)
+
+    sections, inputs = parse_gnu_map(lines)
+    entry = summarize(sections, inputs)
+
+    assert entry["text"] == 0x1c + 0x20 + 0x120
+    assert entry["data"] == 0x8
+    assert entry["bss"] == 0x1a0
+    assert entry["total"] == entry["text"] + entry["data"] + entry["bss"]
+    assert entry["largest_inputs"] == [
+        {"file": "main.o", "size": 0xf0 + 0x1a0},
+        {"file": "crt1.o", "size": 0x1c + 0x20 + 0x26},
+        {"file": "libfoo.a(foo.o)", "size": 0x8},
+    ]
+
+
+def test_parse_lld_map():
+    lines = """\
+             VMA              LMA     Size Align Out     In      Symbol
+          2001c8           2001c8       15     1 .rodata
+          2001c8           2001c8       15     1         main.o:(.rodata.str1.1)
+          201000           201000       2e    16 .text
+          201000           201000       2e    16         main.o:(.text)
+          201000           201000        0     1                 main
+          202000           202000        8     8 .data
+          202000           202000        8     8         libfoo.a(foo.o):(.data)
+          202010           202010       40    16 .bss
+          202010           202010       40    16         main.o:(.bss)
+               0                0       63     1 .comment
+               0                0       63     1         <internal>:(.comment)
+""".splitlines(True)
+
+    sections, inputs = parse_lld_map(lines)
+    entry = summarize(sections, inputs)
+
+    assert entry["text"] == 0x15 + 0x2e
+    assert entry["data"] == 0x8
+    assert entry["bss"] == 0x40
+    assert entry["largest_inputs"] == [
+        {"file": "main.o", "size": 0x15 + 0x2e + 0x40},
+        {"file": "libfoo.a(foo.o)", "size": 0x8},
+    ]
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("--json", required=True, help="JSON report to write")
+    parser.add_argument("--csv", required=True, help="CSV report to write")
+    parser.add_argument("--entries", required=True,
+                        help="File listing the maps to read, as module=map_file pairs "
+                             "separated by whitespace. The output of nm and size on "
+                             "the linked file can follow the map, as "
+                             "module=map_file,nm_file,size_file")
+    args = parser.parse_args()
+
+    logging.basicConfig(format="%(levelname)s: %(message)s", level=logging.WARNING)
+
+    with open(args.entries, "rt") as fp:
+        pairs = fp.read().split()
+
+    entries = {}
+    for pair in pairs:
+        module, _, files = pair.partition("=")
+        files = files.split(",")
+        if not files[0] or len(files) not in (1, 3):
+            logger.error("Invalid entry '%s', expected module=map_file[,nm_file,size_file]",
+                         pair)
+            return 1
+        map_file = files[0]
+        if not os.path.exists(map_file):
+            logger.warning("%s: link map %s does not exist", module, map_file)
+            continue
+        entries[module] = summarize(*parse_map(map_file))
+
+        if len(files) == 3:
+            nm_file, size_file = files[1:]
+            if not os.path.exists(nm_file) or not os.path.exists(size_file):
+                logger.warning("%s: output of nm or size does not exist", module)
+                continue
+            add_size_tool_output(entries[module], parse_nm(read_lines(nm_file)),
+                                 parse_size(read_lines(size_file)))
+
+    write_report(entries, args.json, args.csv)
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
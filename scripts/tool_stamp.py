@@ -0,0 +1,133 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Write a stamp holding the hash of the tool run by a generator module.
+
+For Python tools, the modules the tool imports from the source tree are
+hashed too, and listed in a depfile, so that editing them updates the
+stamp even though they aren't listed in the module. The stamp is only
+rewritten when the hash changes, so rebuilding the tool with the same
+contents doesn't make its users run again.
+"""
+
+import argparse
+import hashlib
+import modulefinder
+import os
+import sys
+
+
+def is_python(path):
+    """Return whether a file is a Python script"""
+    if path.endswith(".py"):
+        return True
+    try:
+        with open(path, "rb") as fp:
+            first_line = fp.readline(128)
+    except IOError:
+        return False
+    return first_line.startswith(b"#!") and b"python" in first_line
+
+
+def is_under(path, root):
+    path = os.path.abspath(path)
+    root = os.path.abspath(root)
+    return path == root or path.startswith(root + os.sep)
+
+
+def python_imports(tool, root):
+    """Return the files of the modules imported by a Python tool which are
+    inside 'root'. Modules of the standard library and installed packages
+    are outside the source tree, so they are not returned."""
+    finder = modulefinder.ModuleFinder(path=[os.path.dirname(tool)] + sys.path)
+    try:
+        finder.run_script(tool)
+    except (SyntaxError, ImportError):
+        # The tool will report this itself when it is run
+        return []
+
+    files = []
+    for module in finder.modules.values():
+        path = module.__file__
+        if path is None or module.__name__ == "__main__":
+            continue
+        if is_under(path, root):
+            files.append(os.path.relpath(path))
+    return sorted(set(files))
+
+
+def hash_files(files, root):
+    """Hash the contents of files, which are given in a fixed order. Files
+    are named relative to 'root', so the hash is the same in every build
+    directory."""
+    sha = hashlib.sha256()
+    for path in files:
+        name = os.path.relpath(path, root)
+        sha.update(name.encode("utf-8") + b"\0")
+        with open(path, "rb") as fp:
+            sha.update(fp.read())
+        sha.update(b"\0")
+    return sha.hexdigest()
+
+
+def write_if_changed(path, content):
+    try:
+        with open(path) as fp:
+            if fp.read() == content:
+                return
+    except IOError:
+        pass
+    with open(path, "w") as fp:
+        fp.write(content)
+
+
+def escape_dep(path):
+    return path.replace(" ", "\\ ")
+
+
+def test_is_under():
+    assert is_under("src/tools/gen.py", "src")
+    assert not is_under("srcs/gen.py", "src")
+    assert not is_under("/usr/lib/python3/os.py", "src")
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("--output", required=True, help="Stamp file to write")
+    parser.add_argument("--depfile", required=True,
+                        help="Depfile listing the files which were hashed")
+    parser.add_argument("--root", required=True,
+                        help="Root of the source tree. Imported modules outside it aren't hashed")
+    parser.add_argument("tools", nargs="+", help="Tools to hash")
+    args = parser.parse_args()
+
+    files = []
+    for tool in args.tools:
+        files.append(tool)
+        if is_python(tool):
+            files.extend(python_imports(tool, args.root))
+
+    write_if_changed(args.output, hash_files(files, args.root) + "\n")
+
+    with open(args.depfile, "w") as fp:
+        fp.write(escape_dep(args.output) + ": " + " ".join(escape_dep(f) for f in files) + "\n")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,109 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Check the warnings logged for the modules covered by a warning budget.
+
+The check fails when there are more warnings than the budget allows.
+When there are fewer, it suggests lowering the budget, so that fixed
+warnings can't come back.
+"""
+
+import argparse
+import io
+import logging
+import sys
+
+
+logger = logging.getLogger(__name__)
+
+# How many of the files with the most warnings are listed
+WORST_FILES = 10
+
+
+def count_warnings(logs):
+    """Return the number of warnings in each log"""
+    counts = {}
+    for log in logs:
+        with io.open(log, "rt", encoding="utf-8", errors="replace") as fp:
+            counts[log] = sum(1 for line in fp if line.strip())
+    return counts
+
+
+def source_name(log):
+    """Return the object a log was written for"""
+    return log[:-len(".warnings")] if log.endswith(".warnings") else log
+
+
+def check_budget(name, max_warnings, counts):
+    """Return whether the warnings are within the budget, reporting
+    the result."""
+    total = sum(counts.values())
+    if total > max_warnings:
+        logger.error("%d warnings exceed the budget of %d set by %s",
+                     total, max_warnings, name)
+        worst = sorted(counts.items(), key=lambda item: (-item[1], item[0]))
+        for log, count in worst[:WORST_FILES]:
+            if count > 0:
+                logger.error("  %5d %s", count, source_name(log))
+        return False
+    if total < max_warnings:
+        logger.warning("%s allows %d warnings, but there are %d. "
+                       "Lower max_warnings to %d to keep them fixed.",
+                       name, max_warnings, total, total)
+    return True
+
+
+def test_check_budget(tmp_path):
+    logs = []
+    for name, lines in (("a.o.warnings", 2), ("b.o.warnings", 0), ("c.o.warnings", 1)):
+        log = tmp_path / name
+        log.write_text(u"w\n" * lines)
+        logs.append(str(log))
+
+    counts = count_warnings(logs)
+    assert sum(counts.values()) == 3
+    assert check_budget("budget", 3, counts)
+    assert check_budget("budget", 4, counts)
+    assert not check_budget("budget", 2, counts)
+
+
+def test_source_name():
+    assert source_name("build/objects/foo/foo.c.o.warnings") == "build/objects/foo/foo.c.o"
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("--name", required=True, help="Name of the budget")
+    parser.add_argument("--max", type=int, required=True, help="Number of warnings allowed")
+    parser.add_argument("--logs", required=True,
+                        help="File listing the warning logs, separated by whitespace")
+    args = parser.parse_args()
+
+    logging.basicConfig(format="%(levelname)s: %(message)s", level=logging.WARNING)
+
+    with io.open(args.logs, "rt") as fp:
+        logs = fp.read().split()
+
+    if check_budget(args.name, args.max, count_warnings(logs)):
+        return 0
+    return 1
+
+
+if __name__ == "__main__":
+    sys.exit(main())
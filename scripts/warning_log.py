@@ -0,0 +1,80 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Run a compiler command, recording the warnings it prints in a log file.
+
+The compiler's output is passed through unchanged. The log is written
+even when the command fails, and holds one line per warning.
+"""
+
+import argparse
+import re
+import subprocess
+import sys
+
+
+# GCC and Clang report warnings as "file:line:col: warning: message"
+WARNING_RE = re.compile(r"^\S.*?: warning: ")
+
+
+def find_warnings(output):
+    """Return the warning lines in compiler output"""
+    return [line for line in output.splitlines() if WARNING_RE.match(line)]
+
+
+def test_find_warnings():
+    output = "\n".join([
+        "foo.c: In function 'main':",
+        "foo.c:3:9: warning: unused variable 'x' [-Wunused-variable]",
+        "    3 |     int x;",
+        "foo.c:4:1: error: expected ';' before '}' token",
+        "In file included from foo.c:1:",
+        "foo.h:2:5: warning: 'y' defined but not used",
+    ])
+    assert find_warnings(output) == [
+        "foo.c:3:9: warning: unused variable 'x' [-Wunused-variable]",
+        "foo.h:2:5: warning: 'y' defined but not used",
+    ]
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("--log", required=True, help="File to record the warnings in")
+    parser.add_argument("command", nargs=argparse.REMAINDER,
+                        help="Command to run, following --")
+    args = parser.parse_args()
+
+    if args.command and args.command[0] == "--":
+        args.command = args.command[1:]
+    if not args.command:
+        parser.error("no command given")
+
+    proc = subprocess.Popen(args.command, stderr=subprocess.PIPE)
+    _, err = proc.communicate()
+    err = err.decode("utf-8", "replace")
+    sys.stderr.write(err)
+
+    with open(args.log, "wt") as fp:
+        for line in find_warnings(err):
+            fp.write(line + "\n")
+    return proc.returncode
+
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -20,12 +20,36 @@ from collections import defaultdict
 
 import argparse
 import os
+import shlex
 import shutil
 import subprocess
 import sys
 import tempfile
 
 
+THIN_ARCHIVE_MAGIC = b"!<thin>\n"
+
+
+def is_thin_archive(archive):
+    with open(archive, "rb") as fp:
+        return fp.read(len(THIN_ARCHIVE_MAGIC)) == THIN_ARCHIVE_MAGIC
+
+
+def thin_archive_members(archive):
+    """Return the objects referenced by a thin archive. The archive stores
+    paths relative to its directory. GNU ar lists them with the archive's
+    directory prepended, while other archivers may list them as stored."""
+    dirname = os.path.dirname(archive)
+    members = []
+    for m in list_archive_contents(archive):
+        if os.path.splitext(m)[1] != ".o":
+            continue
+        if dirname and not os.path.isabs(m) and not m.startswith(dirname + os.sep):
+            m = os.path.join(dirname, m)
+        members.append(os.path.normpath(m))
+    return members
+
+
 def list_archive_contents(archive):
     cmd = ["ar", "t", archive]
     out = subprocess.check_output(cmd)
@@ -64,6 +88,10 @@ def extract_archives(ar, dest, archives):
     extracted_objects = []
 
     for a in archives:
+        # The objects of a thin archive are used where they are
+        if is_thin_archive(a):
+            extracted_objects += thin_archive_members(a)
+            continue
         this_dest_name = os.path.splitext(os.path.basename(a))[0]
         this_dest = os.path.join(dest, this_dest_name)
         os.mkdir(this_dest)
@@ -77,6 +105,8 @@ def parse_args():
 
     ap.add_argument("--build-wrapper", required=False)
     ap.add_argument("--ar", required=True)
+    ap.add_argument("--modifiers", default="rcs",
+                    help="Modifiers of the archiver's operation, including T for a thin archive")
     ap.add_argument("--out", required=True)
     ap.add_argument("inputs", nargs="+")
 
@@ -110,17 +140,26 @@ def main():
                 "Error: %s is not an object file or archive.\n" % i)
             sys.exit(1)
 
+    # A thin archive refers to the extracted objects, so they are kept
+    # in a directory next to it rather than removed once it is written.
+    thin = "T" in args.modifiers
     args_out_dirname, args_out_basename = os.path.split(args.out)
-    tmpdir = tempfile.mkdtemp(dir=args_out_dirname, prefix=args_out_basename + ".",
-                              suffix=".tmp.d")
+    if thin:
+        tmpdir = args.out + ".objects"
+        if os.path.isdir(tmpdir):
+            shutil.rmtree(tmpdir)
+        os.mkdir(tmpdir)
+    else:
+        tmpdir = tempfile.mkdtemp(dir=args_out_dirname, prefix=args_out_basename + ".",
+                                  suffix=".tmp.d")
 
     try:
         extracted_objects = extract_archives(args.ar, tmpdir, archives)
-        cmd = [args.ar, "-rcs", args.out] + objects + extracted_objects
+        cmd = [args.ar, "-" + args.modifiers, args.out] + objects + extracted_objects
         # prepend with build wrapper
         # note: we need to split as it can contain wrapper args as well
         if args.build_wrapper is not None:
-            cmd = args.build_wrapper.split() + cmd
+            cmd = shlex.split(args.build_wrapper) + cmd
         subprocess.call(cmd)
     except subprocess.CalledProcessError as e:
         sys.stderr.write("Error: Command '%s' failed.\n" % e.cmd)
@@ -131,7 +170,27 @@ def main():
                 (' '.join(cmd), e.strerror))
         sys.exit(1)
     finally:
-        shutil.rmtree(tmpdir)
+        if not thin:
+            shutil.rmtree(tmpdir)
+
+
+def test_is_thin_archive(tmp_path):
+    thin = tmp_path / "libthin.a"
+    thin.write_bytes(THIN_ARCHIVE_MAGIC + b"/              0         0")
+    regular = tmp_path / "libfull.a"
+    regular.write_bytes(b"!<arch>\n")
+    assert is_thin_archive(str(thin))
+    assert not is_thin_archive(str(regular))
+
+
+def test_thin_archive_members(monkeypatch):
+    listing = ["out/lib/a.o", "b.o", "/abs/c.o", "__.SYMDEF"]
+    monkeypatch.setattr(sys.modules[__name__], "list_archive_contents", lambda archive: listing)
+    assert thin_archive_members(os.path.join("out", "lib", "libx.a")) == [
+        os.path.join("out", "lib", "a.o"),
+        os.path.join("out", "lib", "b.o"),
+        "/abs/c.o",
+    ]
 
 
 if __name__ == "__main__":
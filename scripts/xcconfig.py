@@ -0,0 +1,184 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""
+Write the flags used to build a module as an Xcode configuration file.
+
+The flags are given after the options, each group introduced by one of
+the words @cflags, @conlyflags, @cxxflags or @ldflags. They are passed
+exactly as they are on the compiler and linker command lines, so that
+the shell handles any quoting in the same way.
+
+Paths in the flags are made absolute, so that the file can be used
+from an Xcode project outside the build directory.
+"""
+
+import argparse
+import os
+import sys
+
+
+GROUPS = ("@cflags", "@conlyflags", "@cxxflags", "@ldflags")
+
+# Flags whose value is a path, either joined to the flag or following it
+PATH_FLAGS = ("-I", "-isystem", "-iquote", "-L", "-include")
+
+
+def parse_args():
+    parser = argparse.ArgumentParser(description=__doc__,
+                                     formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("-o", "--output", required=True, help="Configuration file to create")
+    parser.add_argument("--name", required=True, help="Name of the module")
+    parser.add_argument("flags", nargs=argparse.REMAINDER,
+                        help="Groups of flags, each starting with " + ", ".join(GROUPS))
+
+    return parser.parse_args()
+
+
+def split_groups(words):
+    """Return a dictionary of the flags in each group"""
+    groups = {group: [] for group in GROUPS}
+    current = None
+    for word in words:
+        if word in groups:
+            current = groups[word]
+        elif current is None:
+            raise ValueError("Flag {} is not in a group".format(word))
+        else:
+            current.append(word)
+    return groups
+
+
+def absolute_paths(flags):
+    """Make the paths in a list of flags absolute"""
+    result = []
+    path_follows = False
+    for flag in flags:
+        if path_follows:
+            result.append(os.path.abspath(flag))
+            path_follows = False
+            continue
+        for prefix in PATH_FLAGS:
+            if flag == prefix:
+                path_follows = True
+                break
+            if flag.startswith(prefix) and prefix in ("-I", "-L"):
+                flag = prefix + os.path.abspath(flag[len(prefix):])
+                break
+        else:
+            if not flag.startswith("-") and os.path.exists(flag):
+                # Libraries are linked by path
+                flag = os.path.abspath(flag)
+        result.append(flag)
+    return result
+
+
+def split_search_paths(flags):
+    """Separate the -I include directories from other flags"""
+    dirs = []
+    others = []
+    include_follows = False
+    for flag in flags:
+        if include_follows:
+            dirs.append(flag)
+            include_follows = False
+        elif flag == "-I":
+            include_follows = True
+        elif flag.startswith("-I"):
+            dirs.append(flag[2:])
+        else:
+            others.append(flag)
+    return dirs, others
+
+
+def quote(value):
+    """Quote a value in a list setting if it contains spaces or quotes"""
+    if any(c in value for c in ' \t"'):
+        return '"' + value.replace("\\", "\\\\").replace('"', '\\"') + '"'
+    return value
+
+
+def setting(name, values):
+    return "{} = {}\n".format(name, " ".join(quote(v) for v in values))
+
+
+def xcconfig(name, groups):
+    cflags = absolute_paths(groups["@cflags"])
+    search_paths, cflags = split_search_paths(cflags)
+
+    content = "// Build settings for {}, written by Bob\n".format(name)
+    content += setting("HEADER_SEARCH_PATHS", ["$(inherited)"] + search_paths)
+    content += setting("OTHER_CFLAGS",
+                       ["$(inherited)"] + cflags + absolute_paths(groups["@conlyflags"]))
+    content += setting("OTHER_CPLUSPLUSFLAGS",
+                       ["$(inherited)"] + cflags + absolute_paths(groups["@cxxflags"]))
+    if groups["@ldflags"]:
+        content += setting("OTHER_LDFLAGS",
+                           ["$(inherited)"] + absolute_paths(groups["@ldflags"]))
+    return content
+
+
+def test_split_groups():
+    groups = split_groups(["@cflags", "-O2", "-DFOO", "@cxxflags", "-std=c++11", "@ldflags"])
+    assert groups == {
+        "@cflags": ["-O2", "-DFOO"],
+        "@conlyflags": [],
+        "@cxxflags": ["-std=c++11"],
+        "@ldflags": [],
+    }
+
+
+def test_absolute_paths():
+    cwd = os.getcwd()
+    assert absolute_paths(["-Iinclude", "-isystem", "sys", "-O2", "-L", "lib"]) == [
+        "-I" + os.path.join(cwd, "include"),
+        "-isystem", os.path.join(cwd, "sys"),
+        "-O2",
+        "-L", os.path.join(cwd, "lib"),
+    ]
+
+
+def test_split_search_paths():
+    assert split_search_paths(["-I/a", "-DFOO", "-I", "/b"]) == (["/a", "/b"], ["-DFOO"])
+
+
+def test_xcconfig():
+    groups = split_groups(["@cflags", "-I/inc", "-DNAME=\"a b\"", "@cxxflags", "-std=c++11"])
+    assert xcconfig("foo", groups) == \
+        "// Build settings for foo, written by Bob\n" \
+        "HEADER_SEARCH_PATHS = $(inherited) /inc\n" \
+        "OTHER_CFLAGS = $(inherited) \"-DNAME=\\\"a b\\\"\"\n" \
+        "OTHER_CPLUSPLUSFLAGS = $(inherited) \"-DNAME=\\\"a b\\\"\" -std=c++11\n"
+
+
+def main():
+    args = parse_args()
+
+    try:
+        groups = split_groups(args.flags)
+    except ValueError as e:
+        sys.stderr.write("error: {}\n".format(e))
+        return 1
+
+    with open(args.output, "wt") as fp:
+        fp.write(xcconfig(args.name, groups))
+
+    return 0
+
+
+if __name__ == "__main__":
+    sys.exit(main())
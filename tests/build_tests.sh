@@ -63,6 +63,7 @@ function check_build_output() {
     check_installed "${DIR}/install/bin/bob_test_install_deps_binary"
     check_installed "${DIR}/data/resources/bob_test_install_deps_resource.txt"
     check_installed "${DIR}/install/bin/bob_test_install_deps"
+    check_installed "${DIR}/install/bin/widgetc"
     if [ "$OS" != "OSX" ] ; then
         check_installed "${DIR}/lib/modules/test_module1.ko"
         check_installed "${DIR}/lib/modules/test_module2.ko"
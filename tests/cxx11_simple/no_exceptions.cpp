@@ -0,0 +1,10 @@
+#if defined(__EXCEPTIONS) || defined(__cpp_exceptions)
+    #error "Exceptions were not disabled"
+#endif
+#if defined(__GXX_RTTI) || defined(__cpp_rtti)
+    #error "RTTI was not disabled"
+#endif
+
+int main() {
+    return 0;
+}
@@ -0,0 +1 @@
+#define BOARD_I2C_FREQ 400000
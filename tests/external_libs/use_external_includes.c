@@ -0,0 +1,13 @@
+#include "external_header.h"
+
+#if EXTERNAL_HEADER != 31415926
+#error "Wrong external_header.h included"
+#endif
+
+#if !defined(EXTERNAL_TARGET) || defined(EXTERNAL_HOST)
+#error "Target specific export_cflags of the external library not applied"
+#endif
+
+int main(void) {
+    return 0;
+}
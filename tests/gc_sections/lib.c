@@ -0,0 +1,14 @@
+int gc_sections_used(void)
+{
+	return 0;
+}
+
+int gc_sections_unused(void)
+{
+	return 1;
+}
+
+int gc_sections_plugin_entry(void)
+{
+	return 0;
+}
@@ -0,0 +1,6 @@
+int gc_sections_used(void);
+
+int main(void)
+{
+	return gc_sections_used();
+}
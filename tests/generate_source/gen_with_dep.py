@@ -22,7 +22,7 @@ import os
 
 parser = argparse.ArgumentParser(description="Test generator outputing depfile")
 parser.add_argument("input", default=[], action="append", help="Input file(s)")
-parser.add_argument("-o", "--output", help="Output file")
+parser.add_argument("-o", "--output", nargs="+", help="Output file(s)")
 parser.add_argument("-d", "--depfile", help="Dependency file")
 parser.add_argument("--gen-implicit-out", action="store_true",
                     help="Flag to generate implicit output file")
@@ -35,22 +35,26 @@ implicit_ins = [os.path.join(base, "depgen2.in"),
 
 args.input += implicit_ins
 
-with open(args.output, "w") as out:
-    for input_file in args.input:
-        if not os.path.exists(input_file):
-            print("Input file doesn't exist: " + input_file)
-            exit(-1)
+content = ""
+for input_file in args.input:
+    if not os.path.exists(input_file):
+        print("Input file doesn't exist: " + input_file)
+        exit(-1)
 
-        with open(input_file, "r") as f:
-            out.write(f.read())
+    with open(input_file, "r") as f:
+        content += f.read()
+
+for output in args.output:
+    with open(output, "w") as out:
+        out.write(content)
 
 template = "{target}: {deps}\n"
 dep_str = " \\\n\t".join(args.input)
 with open(args.depfile, "w") as depfile:
-    depfile.write(template.format(target=args.output, deps=dep_str))
+    depfile.write(template.format(target=" ".join(args.output), deps=dep_str))
 
 # create empty file for test purposes, in the same folder as out file
 if args.gen_implicit_out:
-    outdir = os.path.dirname(args.output)
+    outdir = os.path.dirname(args.output[0])
     with open(os.path.join(outdir, "out.h"), "w") as implicit_out:
         implicit_out.write("")
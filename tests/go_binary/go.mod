@@ -0,0 +1,3 @@
+module github.com/ARM-software/bob-build/tests/go_binary
+
+go 1.13
@@ -0,0 +1,12 @@
+#ifndef BOB_TEST_HEADER_LIB_H
+#define BOB_TEST_HEADER_LIB_H
+
+#ifndef BOB_TEST_HEADER_LIB_VALUE
+#error "Flags exported by the header library are missing"
+#endif
+
+static inline int bob_test_header_lib_value(void) {
+    return BOB_TEST_HEADER_LIB_VALUE;
+}
+
+#endif /* BOB_TEST_HEADER_LIB_H */
@@ -0,0 +1,5 @@
+#include "bob_test_header_lib.h"
+
+int bob_test_header_libs_reexport(void) {
+    return bob_test_header_lib_value();
+}
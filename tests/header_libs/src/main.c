@@ -0,0 +1,5 @@
+#include "bob_test_header_lib.h"
+
+int main() {
+    return bob_test_header_lib_value() == 3 ? 0 : 1;
+}
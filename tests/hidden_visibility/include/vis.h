@@ -0,0 +1,8 @@
+#ifndef VIS_H
+#define VIS_H
+
+#include <libvis_export.h>
+
+LIBVIS_EXPORT int vis_api(int x);
+
+#endif /* VIS_H */
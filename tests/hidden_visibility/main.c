@@ -0,0 +1,6 @@
+#include <vis.h>
+
+int main(void)
+{
+	return vis_api(1) == 3 ? 0 : 1;
+}
@@ -0,0 +1,12 @@
+#include "vis.h"
+
+/* Not annotated, so hidden even though it is not static */
+int vis_internal(int x)
+{
+	return x * 2;
+}
+
+int vis_api(int x)
+{
+	return vis_internal(x) + 1;
+}
@@ -0,0 +1,10 @@
+/*
+ * Copyright 2021 Example Ltd.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+int license_header_checked(void)
+{
+    return 1;
+}
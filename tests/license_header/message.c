@@ -0,0 +1,4 @@
+const char *license_header_message(void)
+{
+    return "generated";
+}
@@ -0,0 +1,6 @@
+int ping(int x);
+
+int main(void)
+{
+    return ping(3) == 3 ? 0 : 1;
+}
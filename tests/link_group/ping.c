@@ -0,0 +1,6 @@
+int pong(int x);
+
+int ping(int x)
+{
+    return x <= 0 ? 0 : pong(x - 1) + 1;
+}
@@ -0,0 +1,4 @@
+int ping_helper(int x)
+{
+    return x - 1;
+}
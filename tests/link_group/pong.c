@@ -0,0 +1,7 @@
+int ping(int x);
+int ping_helper(int x);
+
+int pong(int x)
+{
+    return x <= 0 ? 0 : ping(ping_helper(x)) + 1;
+}
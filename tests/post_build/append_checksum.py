@@ -0,0 +1,40 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""Copy a binary, appending the SHA-256 digest of its content"""
+
+import argparse
+import hashlib
+import shutil
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__)
+    parser.add_argument("input", help="Linked binary")
+    parser.add_argument("-o", "--output", required=True, help="Binary to write")
+    args = parser.parse_args()
+
+    with open(args.input, "rb") as fp:
+        content = fp.read()
+    with open(args.output, "wb") as fp:
+        fp.write(content)
+        fp.write(hashlib.sha256(content).digest())
+    shutil.copymode(args.input, args.output)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,39 @@
+#!/usr/bin/env python
+
+# Copyright 2021 Arm Limited.
+# SPDX-License-Identifier: Apache-2.0
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""Write an index of installed files, with the SHA-256 digest of each"""
+
+import argparse
+import hashlib
+import os
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__)
+    parser.add_argument("inputs", nargs="+", help="Installed files")
+    parser.add_argument("-o", "--output", required=True, help="Index to write")
+    args = parser.parse_args()
+
+    with open(args.output, "w") as out:
+        for path in sorted(args.inputs):
+            with open(path, "rb") as fp:
+                digest = hashlib.sha256(fp.read()).hexdigest()
+            out.write("{} {}\n".format(digest, os.path.basename(path)))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,11 @@
+int rel_extra(void);
+
+int rel_internal(void)
+{
+	return rel_extra() + 1;
+}
+
+int rel_api(void)
+{
+	return rel_internal() + 1;
+}
@@ -0,0 +1,4 @@
+int rel_extra(void)
+{
+	return 1;
+}
@@ -0,0 +1,6 @@
+int rel_api(void);
+
+int main(void)
+{
+	return rel_api() == 3 ? 0 : 1;
+}
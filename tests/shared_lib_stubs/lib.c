@@ -0,0 +1,13 @@
+int stubs_api_value = 3;
+
+int stubs_api_open(void) {
+    return stubs_api_value;
+}
+
+void stubs_api_close(void) {
+}
+
+/* Not part of the API, so users can't link against it */
+int stubs_api_internal(void) {
+    return 0;
+}
@@ -0,0 +1,10 @@
+extern int stubs_api_value;
+int stubs_api_open(void);
+void stubs_api_close(void);
+
+int main()
+{
+	int ret = stubs_api_open() == stubs_api_value ? 0 : 1;
+	stubs_api_close();
+	return ret;
+}
@@ -0,0 +1,18 @@
+#include <stdio.h>
+
+int sharedtest_indirect(void);
+
+int main(int argc, char **argv) {
+    if (argc > 1) {
+        FILE *fp = fopen(argv[1], "wt");
+        fprintf(fp, "int main(void) { return 0; }\n");
+        fclose(fp);
+    }
+
+    if (sharedtest_indirect() == 12345) {
+        return 0;
+    } else {
+        fprintf(stderr, "%s: Library function did not return correct value\n", argv[0]);
+        return 1;
+    }
+}
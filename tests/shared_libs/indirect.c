@@ -0,0 +1,5 @@
+int sharedtest_not_installed(void);
+
+int FUNC_NAME(void) {
+    return sharedtest_not_installed();
+}
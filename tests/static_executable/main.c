@@ -0,0 +1,5 @@
+int message_length(void);
+
+int main(void) {
+    return message_length() == 6 ? 0 : 1;
+}
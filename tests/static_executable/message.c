@@ -0,0 +1,3 @@
+int message_length(void) {
+    return 6;
+}
@@ -0,0 +1,6 @@
+#include <string>
+
+int main() {
+    std::string s("static");
+    return s.size() == 6 ? 0 : 1;
+}